@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ScanResult результат антивирусной проверки файла
+type ScanResult struct {
+	Infected bool
+	// Название найденной сигнатуры
+	Signature string
+}
+
+// FileScanner проверяет загружаемые файлы на вредоносное содержимое
+type FileScanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// NewFileScanner создает антивирусный сканер. Без провайдера проверка отключена.
+func NewFileScanner(cfg AntivirusConfig) (FileScanner, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "clamav":
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("ANTIVIRUS_ADDRESS is required for clamav provider")
+		}
+		return &ClamAVScanner{address: cfg.Address, timeout: cfg.Timeout}, nil
+	default:
+		return nil, fmt.Errorf("unknown antivirus provider: %s", cfg.Provider)
+	}
+}
+
+// clamavChunkSize размер блока данных в команде INSTREAM
+const clamavChunkSize = 64 << 10
+
+// ClamAVScanner проверка через демон clamd по TCP (команда INSTREAM)
+type ClamAVScanner struct {
+	address string
+	timeout time.Duration
+}
+
+func (c *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (ScanResult, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return ScanResult{}, err
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	// Данные передаются блоками с длиной в 4 байта, нулевая длина завершает поток
+	chunk := make([]byte, 4+clamavChunkSize)
+	for {
+		n, err := io.ReadFull(r, chunk[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(chunk, uint32(n))
+			if _, err := conn.Write(chunk[:4+n]); err != nil {
+				return ScanResult{}, fmt.Errorf("failed to stream file to clamd: %w", err)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return ScanResult{}, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return ScanResult{}, fmt.Errorf("failed to finish clamd stream: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return ScanResult{}, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamAVReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamAVReply разбирает ответ clamd: "stream: OK", "stream: <сигнатура> FOUND"
+// или "<описание> ERROR"
+func parseClamAVReply(reply string) (ScanResult, error) {
+	status := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case status == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(status, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(status, " FOUND")}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd error: %s", reply)
+	}
+}
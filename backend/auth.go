@@ -1,8 +1,13 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,6 +19,15 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// issuedAt возвращает время выпуска токена. Токен без iat считается выпущенным в начале эпохи,
+// чтобы его отклоняла любая отметка tokens_valid_after.
+func (c *Claims) issuedAt() time.Time {
+	if c.IssuedAt == nil {
+		return time.Unix(0, 0)
+	}
+	return c.IssuedAt.Time
+}
+
 // GenerateToken генерирует JWT токен для пользователя
 func GenerateToken(cfg *Config, userID int64, role string, isRefresh bool) (string, error) {
 	expiry := cfg.JWTAccessExpiry
@@ -21,10 +35,16 @@ func GenerateToken(cfg *Config, userID int64, role string, isRefresh bool) (stri
 		expiry = cfg.JWTRefreshExpiry
 	}
 
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	claims := Claims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -73,3 +93,95 @@ func ExtractTokenFromHeader(authHeader string) (string, error) {
 	return authHeader[len(bearerPrefix):], nil
 }
 
+// GenerateRefreshToken генерирует непрозрачный refresh токен
+func GenerateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// generateTokenID генерирует уникальный идентификатор (jti) JWT токена
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sha256Hex возвращает SHA-256 хеш строки в hex. Так в БД хранятся refresh, API токены,
+// токены календаря и push токены устройств: по хешу ищется запись, сам токен не сохраняется.
+func sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Персональные API токены начинаются с префикса, чтобы отличать их от JWT
+const APITokenPrefix = "hbt_"
+
+// MaxAPITokensPerUser ограничивает число персональных API токенов пользователя
+const MaxAPITokensPerUser = 10
+
+// Области действия персональных API токенов
+const (
+	ScopeDonationsRead = "donations:read"
+	ScopePostsWrite    = "posts:write"
+)
+
+// GenerateAPIToken генерирует персональный API токен
+func GenerateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	return APITokenPrefix + hex.EncodeToString(b), nil
+}
+
+// GenerateOTPCode генерирует числовой одноразовый код заданной длины
+func GenerateOTPCode(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate otp code: %w", err)
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// inviteCodeAlphabet символы кодов приглашения без похожих 0/O и 1/I
+const inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// InviteCodeLength длина кода приглашения
+const InviteCodeLength = 8
+
+// GenerateInviteCode генерирует код приглашения
+func GenerateInviteCode() (string, error) {
+	code := make([]byte, InviteCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(inviteCodeAlphabet))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate invite code: %w", err)
+		}
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// GenerateReferralCode генерирует реферальный код пользователя (в том же формате, что и код приглашения)
+func GenerateReferralCode() (string, error) {
+	return GenerateInviteCode()
+}
+
+// NormalizeInviteCode приводит введенный код к виду, в котором он хранится
+func NormalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// HashOTPCode возвращает SHA-256 хеш одноразового кода, привязанный к телефону
+func HashOTPCode(phone, code string) string {
+	return sha256Hex(phone + ":" + code)
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// Матрица доступа к маршрутам API. Каждый маршрут вызывается от имени анонима, пользователя
+// (жертвователь, собеседник автора, автор комментария и вопроса), автора поста, постороннего
+// пользователя, модератора и администратора. Политика описана в README: «Роли и права»
+// и «Доступ к объектам».
+const (
+	asAnonymous = iota
+	asUser
+	asAuthor
+	asOther
+	asModerator
+	asAdmin
+	identityCount
+)
+
+var identityNames = [identityCount]string{"anonymous", "user", "author", "other", "moderator", "admin"}
+
+// allowed доступ разрешен, а код ответа зависит от данных запроса: подходит любой, кроме 401 и 403
+const allowed = -1
+
+// access ожидаемые коды ответа по пользователям
+type access [identityCount]int
+
+// public маршрут без авторизации: всем один код
+func public(code int) access {
+	return access{code, code, code, code, code, code}
+}
+
+// signedIn маршрут для любого пользователя с токеном
+func signedIn(code int) access {
+	return access{http.StatusUnauthorized, code, code, code, code, code}
+}
+
+// moderatorPermissions права роли moderator после инициализации схемы
+var moderatorPermissions = map[string]bool{
+	PermVerificationsReview: true,
+	PermPostsModerate:       true,
+}
+
+// withPerm маршрут для ролей с правом permission
+func withPerm(permission string, code int) access {
+	moderator := http.StatusForbidden
+	if moderatorPermissions[permission] {
+		moderator = code
+	}
+	return access{http.StatusUnauthorized, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, moderator, code}
+}
+
+// authzCase запрос матрицы. В пути и теле подставляются ID объектов authzFixture:
+// {user}, {author}, {post}, {donation}, {chat}, {message}, {comment}, {question}, {import},
+// {verification}, {n} - уникальный суффикс.
+type authzCase struct {
+	method, path string
+	body         interface{}
+	want         access
+	// Поток событий: проверяется только код ответа
+	stream bool
+}
+
+var authzCases = []authzCase{
+	// Служебные и публичные маршруты
+	{method: "GET", path: "/health", want: public(allowed)},
+	{method: "GET", path: "/health?deep=true", want: withPerm(PermSystemView, allowed)},
+	{method: "GET", path: "/readyz", want: public(allowed)},
+	{method: "GET", path: "/metrics", want: public(http.StatusOK)},
+	{method: "GET", path: "/swagger/index.html", want: public(http.StatusOK)},
+	{method: "GET", path: "/api/v1/verifications/document-types", want: public(http.StatusOK)},
+	{method: "GET", path: "/api/v1/config/money", want: public(http.StatusOK)},
+	{method: "GET", path: "/api/v1/calendar/" + strings.Repeat("0", 64) + ".ics", want: public(http.StatusNotFound)},
+	{method: "POST", path: "/api/v1/payments/webhook", body: json.RawMessage(`{}`), want: public(allowed)},
+
+	// Аутентификация: учетные данные передаются в теле запроса
+	{method: "POST", path: "/api/v1/auth/register", body: json.RawMessage(`{}`), want: public(http.StatusBadRequest)},
+	{method: "POST", path: "/api/v1/auth/login", body: json.RawMessage(`{}`), want: public(http.StatusBadRequest)},
+	{method: "POST", path: "/api/v1/auth/refresh", body: json.RawMessage(`{}`), want: public(http.StatusBadRequest)},
+	{method: "POST", path: "/api/v1/auth/request-otp", body: json.RawMessage(`{}`), want: public(http.StatusBadRequest)},
+	{method: "POST", path: "/api/v1/auth/verify-otp", body: json.RawMessage(`{}`), want: public(http.StatusBadRequest)},
+	{method: "POST", path: "/api/v1/auth/forgot-password", body: json.RawMessage(`{}`), want: public(http.StatusBadRequest)},
+	{method: "POST", path: "/api/v1/auth/reset-password", body: json.RawMessage(`{}`), want: public(http.StatusBadRequest)},
+	{method: "GET", path: "/api/v1/auth/oauth/providers", want: public(http.StatusOK)},
+	// Провайдеры входа в тестах не настроены
+	{method: "GET", path: "/api/v1/auth/oauth/vk", want: public(http.StatusNotFound)},
+	{method: "GET", path: "/api/v1/auth/oauth/vk/callback?code=c&state=s", want: public(http.StatusNotFound)},
+	{method: "POST", path: "/api/v1/auth/logout", want: signedIn(allowed)},
+
+	// Профиль: объекты выбираются по ID текущего пользователя
+	{method: "GET", path: "/api/v1/users/me", want: signedIn(http.StatusOK)},
+	{method: "PATCH", path: "/api/v1/users/me", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "POST", path: "/api/v1/users/me/photo", want: signedIn(allowed)},
+	{method: "POST", path: "/api/v1/users/me/change-password", body: json.RawMessage(`{}`), want: signedIn(http.StatusBadRequest)},
+	{method: "PUT", path: "/api/v1/users/me/away", body: json.RawMessage(`{"message": "В отпуске"}`), want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/away", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/quota", want: signedIn(http.StatusOK)},
+	{method: "GET", path: "/api/v1/users/me/donations", want: signedIn(http.StatusOK)},
+	{method: "GET", path: "/api/v1/users/me/referrals", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/collaborations", want: signedIn(http.StatusOK)},
+	{method: "PATCH", path: "/api/v1/users/me/collaborations/{post}", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/favorites", want: signedIn(http.StatusOK)},
+	{method: "GET", path: "/api/v1/users/me/hidden-posts", want: signedIn(http.StatusOK)},
+	{method: "POST", path: "/api/v1/users/me/hidden-posts", body: json.RawMessage(`{"post_id": {post}}`), want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/hidden-posts/{post}", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/muted-authors", want: signedIn(http.StatusOK)},
+	{method: "POST", path: "/api/v1/users/me/muted-authors", body: json.RawMessage(`{"author_id": {author}}`), want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/muted-authors/{author}", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/post-limit", want: signedIn(allowed)},
+	{method: "POST", path: "/api/v1/users/me/post-limit/requests", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "POST", path: "/api/v1/users/me/devices", body: json.RawMessage(`{"token": "device-{n}", "platform": "android"}`), want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/devices/device-{n}", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/notification-preferences", want: signedIn(http.StatusOK)},
+	{method: "PUT", path: "/api/v1/users/me/notification-preferences", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "POST", path: "/api/v1/users/me/identities/vk", want: signedIn(http.StatusNotFound)},
+	{method: "DELETE", path: "/api/v1/users/me/identities/vk", want: signedIn(http.StatusNotFound)},
+	{method: "GET", path: "/api/v1/users/me/tokens", want: signedIn(http.StatusOK)},
+	{method: "POST", path: "/api/v1/users/me/tokens", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/tokens/0", want: signedIn(allowed)},
+	{method: "POST", path: "/api/v1/users/me/calendar", want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/calendar", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/templates", want: signedIn(http.StatusOK)},
+	{method: "POST", path: "/api/v1/users/me/templates", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "PUT", path: "/api/v1/users/me/templates/0", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/templates/0", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/subscriptions", want: signedIn(http.StatusOK)},
+	{method: "POST", path: "/api/v1/users/me/subscriptions", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "PATCH", path: "/api/v1/users/me/subscriptions/0", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/subscriptions/0", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/users/me/integrations", want: signedIn(http.StatusOK)},
+	{method: "PUT", path: "/api/v1/users/me/integrations", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "DELETE", path: "/api/v1/users/me/integrations/telegram", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/notifications", want: signedIn(http.StatusOK)},
+	{method: "PATCH", path: "/api/v1/notifications/read", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/ratings", want: public(http.StatusOK)},
+	{method: "GET", path: "/api/v1/ratings/me", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/ratings/me/history", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/points-events", want: public(http.StatusOK)},
+
+	// Верификация
+	{method: "POST", path: "/api/v1/verifications", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/verifications/me", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/verifications/organizations/7707083893", want: signedIn(allowed)},
+	{method: "GET", path: "/api/v1/verifications", want: withPerm(PermVerificationsReview, http.StatusOK)},
+	{method: "GET", path: "/api/v1/verifications/{verification}", want: withPerm(PermVerificationsReview, http.StatusOK)},
+	{method: "PATCH", path: "/api/v1/verifications/{verification}", body: json.RawMessage(`{"status": "approved"}`), want: withPerm(PermVerificationsReview, http.StatusOK)},
+	{method: "GET", path: "/api/v1/verifications/{verification}/documents", want: withPerm(PermVerificationDocumentsView, allowed)},
+
+	// Администрирование
+	{method: "GET", path: "/api/v1/admin/posts/moderation", want: withPerm(PermPostsModerate, http.StatusOK)},
+	{method: "GET", path: "/api/v1/admin/posts/moderation/urgent", want: withPerm(PermPostsModerate, http.StatusOK)},
+	{method: "PATCH", path: "/api/v1/admin/posts/{post}/moderation", body: json.RawMessage(`{"status": "active"}`), want: withPerm(PermPostsModerate, allowed)},
+	{method: "GET", path: "/api/v1/admin/users", want: withPerm(PermUsersView, http.StatusOK)},
+	{method: "GET", path: "/api/v1/admin/users/{user}", want: withPerm(PermUsersView, http.StatusOK)},
+	{method: "PATCH", path: "/api/v1/admin/users/{user}", body: json.RawMessage(`{}`), want: withPerm(PermUsersBlock, allowed)},
+	{method: "POST", path: "/api/v1/admin/users/{user}/block", body: json.RawMessage(`{"reason": "Проверка доступа"}`), want: withPerm(PermUsersBlock, allowed)},
+	{method: "DELETE", path: "/api/v1/admin/users/{user}/block", body: json.RawMessage(`{"reason": "Проверка доступа"}`), want: withPerm(PermUsersBlock, allowed)},
+	{method: "GET", path: "/api/v1/admin/backups/status", want: withPerm(PermBackupsView, http.StatusOK)},
+	{method: "GET", path: "/api/v1/admin/audit-log", want: withPerm(PermAuditView, http.StatusOK)},
+	{method: "GET", path: "/api/v1/admin/digests", want: withPerm(PermAuditView, http.StatusOK)},
+	{method: "GET", path: "/api/v1/admin/digests/0", want: withPerm(PermAuditView, allowed)},
+	{method: "GET", path: "/api/v1/admin/selftest", want: withPerm(PermSystemView, allowed)},
+	{method: "POST", path: "/api/v1/admin/selftest", want: withPerm(PermSystemView, allowed)},
+	{method: "GET", path: "/api/v1/admin/ops", want: withPerm(PermSystemView, allowed)},
+	{method: "GET", path: "/api/v1/admin/dead-letters", want: withPerm(PermSystemView, http.StatusOK)},
+	{method: "GET", path: "/api/v1/admin/retention/report", want: withPerm(PermSystemView, allowed)},
+	{method: "POST", path: "/api/v1/admin/dead-letters/0/retry", want: withPerm(PermJobsManage, allowed)},
+	{method: "POST", path: "/api/v1/admin/dead-letters/0/discard", want: withPerm(PermJobsManage, allowed)},
+	{method: "GET", path: "/api/v1/admin/roles", want: withPerm(PermRolesManage, http.StatusOK)},
+	{method: "PUT", path: "/api/v1/admin/roles/authz-{n}", body: json.RawMessage(`{"permissions": []}`), want: withPerm(PermRolesManage, allowed)},
+	{method: "PATCH", path: "/api/v1/admin/users/{user}/role", body: json.RawMessage(`{"role": "user"}`), want: withPerm(PermRolesManage, allowed)},
+	{method: "GET", path: "/api/v1/admin/risk-tiers", want: withPerm(PermLimitsManage, http.StatusOK)},
+	{method: "PUT", path: "/api/v1/admin/risk-tiers/authz-{n}", body: json.RawMessage(`{}`), want: withPerm(PermLimitsManage, allowed)},
+	{method: "PATCH", path: "/api/v1/admin/users/{user}/risk-tier", body: json.RawMessage(`{}`), want: withPerm(PermLimitsManage, allowed)},
+	{method: "GET", path: "/api/v1/admin/post-limit-requests", want: withPerm(PermLimitsManage, http.StatusOK)},
+	{method: "PATCH", path: "/api/v1/admin/post-limit-requests/0", body: json.RawMessage(`{}`), want: withPerm(PermLimitsManage, allowed)},
+	{method: "POST", path: "/api/v1/admin/invites", body: json.RawMessage(`{}`), want: withPerm(PermInvitesManage, allowed)},
+	{method: "GET", path: "/api/v1/admin/invites", want: withPerm(PermInvitesManage, http.StatusOK)},
+	{method: "GET", path: "/api/v1/admin/invites/0/users", want: withPerm(PermInvitesManage, allowed)},
+	// Пустое тело: акция с множителем повлияла бы на баллы в других тестах
+	{method: "POST", path: "/api/v1/admin/points-events", body: json.RawMessage(`{}`), want: withPerm(PermPromotionsManage, allowed)},
+	{method: "GET", path: "/api/v1/admin/points-events", want: withPerm(PermPromotionsManage, http.StatusOK)},
+	{method: "DELETE", path: "/api/v1/admin/points-events/0", want: withPerm(PermPromotionsManage, allowed)},
+	{method: "POST", path: "/api/v1/admin/webhooks", body: json.RawMessage(`{}`), want: withPerm(PermWebhooksManage, allowed)},
+	{method: "GET", path: "/api/v1/admin/webhooks", want: withPerm(PermWebhooksManage, http.StatusOK)},
+	{method: "PATCH", path: "/api/v1/admin/webhooks/0", body: json.RawMessage(`{}`), want: withPerm(PermWebhooksManage, allowed)},
+	{method: "DELETE", path: "/api/v1/admin/webhooks/0", want: withPerm(PermWebhooksManage, allowed)},
+	{method: "GET", path: "/api/v1/admin/webhooks/0/deliveries", want: withPerm(PermWebhooksManage, allowed)},
+
+	// Посты
+	{method: "GET", path: "/api/v1/posts", want: public(http.StatusOK)},
+	{method: "GET", path: "/api/v1/posts/{post}", want: public(http.StatusOK)},
+	{method: "GET", path: "/api/v1/posts/{post}/events", want: public(http.StatusOK), stream: true},
+	{method: "GET", path: "/api/v1/posts/{post}/share-image", want: public(allowed)},
+	{method: "GET", path: "/api/v1/posts/{post}/flyer", want: public(allowed)},
+	{method: "POST", path: "/api/v1/posts", body: json.RawMessage(`{}`), want: signedIn(allowed)},
+	{method: "PATCH", path: "/api/v1/posts/{post}", body: json.RawMessage(`{"title": "Новое название сбора"}`),
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "DELETE", path: "/api/v1/posts/{post}",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusNoContent, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "POST", path: "/api/v1/posts/{post}/media",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "POST", path: "/api/v1/posts/{post}/media/bulk",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "DELETE", path: "/api/v1/posts/{post}/media/0",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "GET", path: "/api/v1/posts/{post}/collaborators",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "POST", path: "/api/v1/posts/{post}/collaborators", body: json.RawMessage(`{}`),
+		want: access{http.StatusUnauthorized, http.StatusForbidden, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "PATCH", path: "/api/v1/posts/{post}/collaborators/{user}", body: json.RawMessage(`{"permissions": ["edit"]}`),
+		want: access{http.StatusUnauthorized, http.StatusForbidden, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	// Соавтор может выйти из поста сам
+	{method: "DELETE", path: "/api/v1/posts/{post}/collaborators/{user}",
+		want: access{http.StatusUnauthorized, allowed, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "POST", path: "/api/v1/posts/{post}/favorite", want: signedIn(http.StatusNoContent)},
+	{method: "DELETE", path: "/api/v1/posts/{post}/favorite", want: signedIn(http.StatusNoContent)},
+
+	// Комментарии и вопросы
+	{method: "GET", path: "/api/v1/posts/{post}/comments", want: public(http.StatusOK)},
+	{method: "POST", path: "/api/v1/posts/{post}/comments", body: json.RawMessage(`{"text": "Комментарий"}`), want: signedIn(http.StatusCreated)},
+	{method: "DELETE", path: "/api/v1/posts/{post}/comments/{comment}",
+		want: access{http.StatusUnauthorized, http.StatusNoContent, http.StatusNoContent, http.StatusForbidden, http.StatusNoContent, http.StatusNoContent}},
+	{method: "GET", path: "/api/v1/posts/{post}/questions", want: public(http.StatusOK)},
+	{method: "GET", path: "/api/v1/posts/{post}/questions?unanswered=true",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusOK, http.StatusForbidden, http.StatusOK, http.StatusOK}},
+	{method: "POST", path: "/api/v1/posts/{post}/questions", body: json.RawMessage(`{"question": "Вопрос"}`),
+		want: access{http.StatusUnauthorized, http.StatusCreated, http.StatusUnprocessableEntity, http.StatusCreated, http.StatusCreated, http.StatusCreated}},
+	{method: "PUT", path: "/api/v1/posts/{post}/questions/{question}/answer", body: json.RawMessage(`{"answer": "Ответ"}`),
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "DELETE", path: "/api/v1/posts/{post}/questions/{question}",
+		want: access{http.StatusUnauthorized, http.StatusNoContent, http.StatusNoContent, http.StatusForbidden, http.StatusNoContent, http.StatusNoContent}},
+
+	// Пожертвования
+	{method: "GET", path: "/api/v1/donations", want: public(http.StatusOK)},
+	{method: "GET", path: "/api/v1/donations/{donation}", want: public(http.StatusOK)},
+	{method: "POST", path: "/api/v1/donations", body: testForm{"post_id": "{post}", "amount": "100"}, want: signedIn(allowed)},
+	{method: "PATCH", path: "/api/v1/donations/{donation}", body: json.RawMessage(`{"status": "confirmed"}`),
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusOK}},
+	{method: "PATCH", path: "/api/v1/donations/{donation}", body: json.RawMessage(`{"status": "refunded"}`),
+		want: access{http.StatusUnauthorized, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusOK}},
+	{method: "DELETE", path: "/api/v1/donations/{donation}",
+		want: access{http.StatusUnauthorized, http.StatusNoContent, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusNoContent}},
+	{method: "GET", path: "/api/v1/donations/{donation}/history",
+		want: access{http.StatusUnauthorized, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusOK}},
+	// Квитанция выдается только после подтверждения
+	{method: "GET", path: "/api/v1/donations/{donation}/receipt",
+		want: access{http.StatusUnauthorized, http.StatusUnprocessableEntity, http.StatusUnprocessableEntity, http.StatusForbidden, http.StatusForbidden, http.StatusUnprocessableEntity}},
+	{method: "POST", path: "/api/v1/donations/{donation}/pay",
+		want: access{http.StatusUnauthorized, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "GET", path: "/api/v1/posts/{post}/donations/stats",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusOK}},
+	{method: "POST", path: "/api/v1/posts/{post}/statement-imports",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, allowed, http.StatusForbidden, http.StatusForbidden, allowed}},
+	{method: "GET", path: "/api/v1/statement-imports/{import}",
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusOK}},
+	{method: "POST", path: "/api/v1/statement-imports/{import}/confirm", body: json.RawMessage(`{"min_confidence": 0.9}`),
+		want: access{http.StatusUnauthorized, http.StatusForbidden, allowed, http.StatusForbidden, http.StatusForbidden, allowed}},
+
+	// Чаты: пользователь - помогающий в чате по посту автора
+	{method: "GET", path: "/api/v1/chats", want: signedIn(http.StatusOK)},
+	{method: "POST", path: "/api/v1/chats", body: json.RawMessage(`{"post_id": {post}}`),
+		want: access{http.StatusUnauthorized, http.StatusConflict, http.StatusBadRequest, http.StatusCreated, http.StatusCreated, http.StatusCreated}},
+	{method: "GET", path: "/api/v1/chats/{chat}/events", stream: true,
+		want: access{http.StatusUnauthorized, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "GET", path: "/api/v1/chats/{chat}/draft",
+		want: access{http.StatusUnauthorized, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "PUT", path: "/api/v1/chats/{chat}/draft", body: json.RawMessage(`{"text": "Черновик"}`),
+		want: access{http.StatusUnauthorized, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "GET", path: "/api/v1/chats/{chat}/messages",
+		want: access{http.StatusUnauthorized, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "POST", path: "/api/v1/chats/{chat}/messages", body: testForm{"text": "Сообщение"},
+		want: access{http.StatusUnauthorized, http.StatusCreated, http.StatusCreated, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "PATCH", path: "/api/v1/chats/{chat}/messages/read",
+		want: access{http.StatusUnauthorized, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "PATCH", path: "/api/v1/chats/{chat}/messages/{message}", body: json.RawMessage(`{"text": "Исправлено"}`),
+		want: access{http.StatusUnauthorized, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "DELETE", path: "/api/v1/chats/{chat}/messages/{message}",
+		want: access{http.StatusUnauthorized, http.StatusNoContent, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+
+	// Файлы: вложения чата доступны его участникам, сканы верификации - владельцу и праву verifications.documents, MinIO в тестах нет
+	{method: "POST", path: "/api/v1/upload/presigned-url", body: json.RawMessage(`{}`), want: signedIn(http.StatusBadRequest)},
+	{method: "POST", path: "/api/v1/files/presigned-url", body: json.RawMessage(`{"bucket": "chat-attachments", "object_key": "chats/{chat}/file.png"}`),
+		want: access{http.StatusUnauthorized, allowed, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "GET", path: "/files/chat-attachments/chats/{chat}/file.png",
+		want: access{http.StatusUnauthorized, allowed, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "POST", path: "/api/v1/files/presigned-url", body: json.RawMessage(`{"bucket": "verification-docs", "object_key": "verifications/{verification}/passport.png"}`),
+		want: access{http.StatusUnauthorized, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+	{method: "GET", path: "/api/v1/files/verification-docs/verifications/{verification}/passport.png",
+		want: access{http.StatusUnauthorized, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, allowed}},
+}
+
+// authzFixture пользователи и объекты одного запроса матрицы
+type authzFixture struct {
+	users    [identityCount]testUser
+	replacer *strings.Replacer
+}
+
+var authzSeq int64
+
+// authzPlaceholder подстановка ID в пути запроса матрицы
+var authzPlaceholder = regexp.MustCompile(`\{[a-z]+\}`)
+
+// newAuthzFixture создает пользователей и объекты: пост автора с пожертвованием,
+// комментарием и вопросом пользователя, чат пользователя с автором, выписку и верификацию
+func (s *testServer) newAuthzFixture() *authzFixture {
+	s.t.Helper()
+	f := &authzFixture{}
+	f.users[asUser] = s.createUser("user")
+	f.users[asAuthor] = s.createUser("user")
+	f.users[asOther] = s.createUser("user")
+	f.users[asModerator] = s.createUser(RoleModerator)
+	f.users[asAdmin] = s.createUser(RoleAdmin)
+	user, author := f.users[asUser], f.users[asAuthor]
+
+	post := s.createPost(author, "active")
+	donation := &Donation{PostID: post.ID, DonorID: user.ID, Amount: 500}
+	if err := s.db.CreateDonation(donation); err != nil {
+		s.t.Fatalf("create donation: %v", err)
+	}
+	chat, err := s.db.CreateChat(post.ID, user.ID, author.ID)
+	if err != nil {
+		s.t.Fatalf("create chat: %v", err)
+	}
+	text := "Здравствуйте"
+	message := &Message{ChatID: chat.ID, SenderID: user.ID, Text: &text}
+	if err := s.db.CreateMessage(message); err != nil {
+		s.t.Fatalf("create message: %v", err)
+	}
+	comment, err := s.db.CreatePostComment(post.ID, user.ID, "Комментарий")
+	if err != nil {
+		s.t.Fatalf("create comment: %v", err)
+	}
+	question, err := s.db.CreatePostQuestion(post.ID, user.ID, "Вопрос")
+	if err != nil {
+		s.t.Fatalf("create question: %v", err)
+	}
+	imp := &StatementImport{PostID: post.ID, UploadedBy: author.ID, FileName: "statement.csv"}
+	if err := s.db.CreateStatementImport(imp, nil); err != nil {
+		s.t.Fatalf("create statement import: %v", err)
+	}
+	verification := s.createVerification(user)
+
+	authzSeq++
+	id := func(v int64) string { return strconv.FormatInt(v, 10) }
+	f.replacer = strings.NewReplacer(
+		"{user}", id(user.ID),
+		"{author}", id(author.ID),
+		"{post}", id(post.ID),
+		"{donation}", id(donation.ID),
+		"{chat}", id(chat.ID),
+		"{message}", id(message.ID),
+		"{comment}", id(comment.ID),
+		"{question}", id(question.ID),
+		"{import}", id(imp.ID),
+		"{verification}", id(verification.ID),
+		"{n}", fmt.Sprintf("%d-%d", testPhoneSeq, authzSeq),
+	)
+	return f
+}
+
+// expand подставляет ID объектов в путь и тело запроса
+func (f *authzFixture) expand(path string, body interface{}) (string, interface{}) {
+	switch b := body.(type) {
+	case json.RawMessage:
+		body = json.RawMessage(f.replacer.Replace(string(b)))
+	case testForm:
+		form := testForm{}
+		for name, value := range b {
+			form[name] = f.replacer.Replace(value)
+		}
+		body = form
+	}
+	return f.replacer.Replace(path), body
+}
+
+func TestAuthorizationMatrix(t *testing.T) {
+	s := newTestServer(t)
+	covered := map[string]bool{}
+
+	for _, tc := range authzCases {
+		name := tc.method + " " + tc.path
+		covered[s.routeKey(tc.method, tc.path)] = true
+
+		t.Run(name, func(t *testing.T) {
+			for identity, want := range tc.want {
+				// Свои объекты для каждого пользователя: удаление или изменение
+				// не влияет на ответ следующему
+				f := s.newAuthzFixture()
+				path, body := f.expand(tc.path, tc.body)
+				user := f.users[identity]
+
+				var code int
+				var responseBody string
+				if tc.stream {
+					code = s.stream(user, path)
+				} else {
+					rec := s.request(user, tc.method, path, body)
+					code, responseBody = rec.Code, rec.Body.String()
+				}
+
+				switch {
+				case want == allowed && (code == http.StatusUnauthorized || code == http.StatusForbidden):
+					t.Errorf("%s: status %d, want access, body: %s", identityNames[identity], code, responseBody)
+				case want != allowed && code != want:
+					t.Errorf("%s: status %d, want %d, body: %s", identityNames[identity], code, want, responseBody)
+				}
+			}
+		})
+	}
+
+	// Каждый маршрут роутера должен быть в матрице
+	var missing []string
+	err := s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Маршруты без метода - префиксы подмаршрутизаторов и Swagger UI
+			if route.GetHandler() != nil && !covered["* "+template] {
+				missing = append(missing, template)
+			}
+			return nil
+		}
+		for _, method := range methods {
+			if !covered[method+" "+template] {
+				missing = append(missing, method+" "+template)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		t.Errorf("routes missing from the authorization matrix:\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+// routeKey возвращает метод и шаблон маршрута, который обслуживает запрос. Для маршрутов
+// без метода (префиксов) метод - "*".
+func (s *testServer) routeKey(method, path string) string {
+	s.t.Helper()
+	req := httptest.NewRequest(method, authzPlaceholder.ReplaceAllString(path, "1"), nil)
+	var match mux.RouteMatch
+	if !s.router.Match(req, &match) || match.Route == nil {
+		s.t.Fatalf("%s %s: no route", method, path)
+	}
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if _, err := match.Route.GetMethods(); err != nil {
+		return "* " + template
+	}
+	return method + " " + template
+}
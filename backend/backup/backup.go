@@ -0,0 +1,256 @@
+// Package backup создает и восстанавливает резервные копии: дамп PostgreSQL
+// (pg_dump) и манифест объектов MinIO.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	dumpFileName     = "database.dump"
+	manifestFileName = "manifest.json"
+	statusFileName   = "last_backup.json"
+	timestampLayout  = "20060102T150405Z"
+)
+
+// Config настройки резервного копирования
+type Config struct {
+	DatabaseURL   string
+	Dir           string
+	RetentionDays int
+	Buckets       []string
+}
+
+// Status результат последнего запуска резервного копирования
+type Status struct {
+	Success      bool      `json:"success"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Path         string    `json:"path,omitempty"`
+	DumpSize     int64     `json:"dump_size"`
+	ObjectsCount int       `json:"objects_count"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// ManifestObject описание объекта MinIO в манифесте
+type ManifestObject struct {
+	Bucket       string    `json:"bucket"`
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	VersionID    string    `json:"version_id,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Manifest список объектов MinIO на момент резервного копирования
+type Manifest struct {
+	CreatedAt time.Time        `json:"created_at"`
+	Objects   []ManifestObject `json:"objects"`
+}
+
+// Run создает резервную копию в отдельном каталоге внутри cfg.Dir и
+// сохраняет статус запуска. Дамп делается pg_dump в одной транзакции,
+// поэтому он согласован на момент начала копирования.
+func Run(ctx context.Context, cfg Config, client *minio.Client) (*Status, error) {
+	status := &Status{StartedAt: time.Now().UTC()}
+
+	err := run(ctx, cfg, client, status)
+	status.FinishedAt = time.Now().UTC()
+	status.Success = err == nil
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	if writeErr := writeJSON(filepath.Join(cfg.Dir, statusFileName), status); writeErr != nil && err == nil {
+		err = fmt.Errorf("failed to write backup status: %w", writeErr)
+	}
+	return status, err
+}
+
+func run(ctx context.Context, cfg Config, client *minio.Client, status *Status) error {
+	path := filepath.Join(cfg.Dir, status.StartedAt.Format(timestampLayout))
+	if err := os.MkdirAll(path, 0o750); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	status.Path = path
+
+	dumpPath := filepath.Join(path, dumpFileName)
+	cmd := pgCommand(ctx, cfg.DatabaseURL, "pg_dump", "--format=custom", "--no-owner", "--file", dumpPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pg_dump failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	info, err := os.Stat(dumpPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat database dump: %w", err)
+	}
+	status.DumpSize = info.Size()
+
+	manifest := Manifest{CreatedAt: time.Now().UTC()}
+	for _, bucket := range cfg.Buckets {
+		for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Recursive: true}) {
+			if obj.Err != nil {
+				return fmt.Errorf("failed to list objects in %s: %w", bucket, obj.Err)
+			}
+			manifest.Objects = append(manifest.Objects, ManifestObject{
+				Bucket:       bucket,
+				Key:          obj.Key,
+				Size:         obj.Size,
+				ETag:         obj.ETag,
+				VersionID:    obj.VersionID,
+				LastModified: obj.LastModified,
+			})
+		}
+	}
+	status.ObjectsCount = len(manifest.Objects)
+
+	if err := writeJSON(filepath.Join(path, manifestFileName), manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Prune удаляет резервные копии старше cfg.RetentionDays.
+// Самая свежая копия сохраняется всегда.
+func Prune(cfg Config) ([]string, error) {
+	if cfg.RetentionDays <= 0 {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []time.Time
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if t, err := time.Parse(timestampLayout, entry.Name()); err == nil {
+			backups = append(backups, t)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].After(backups[j]) })
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -cfg.RetentionDays)
+	var removed []string
+	for i, t := range backups {
+		if i == 0 || !t.Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(cfg.Dir, t.Format(timestampLayout))
+		if err := os.RemoveAll(path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// pgCommand готовит запуск pg_dump или pg_restore с подключением к databaseURL. Пароль из URL
+// передается в окружении процесса (PGPASSWORD), а не в аргументах, которые видны в ps и /proc.
+func pgCommand(ctx context.Context, databaseURL, name string, args ...string) *exec.Cmd {
+	conn, password := splitPassword(databaseURL)
+	cmd := exec.CommandContext(ctx, name, append(args, "--dbname", conn)...)
+	if password != "" {
+		cmd.Env = append(os.Environ(), "PGPASSWORD="+password)
+	}
+	return cmd
+}
+
+// splitPassword убирает пароль из URL подключения и возвращает его отдельно. Строка
+// подключения в формате key=value возвращается без изменений.
+func splitPassword(databaseURL string) (string, string) {
+	u, err := url.Parse(databaseURL)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") || u.User == nil {
+		return databaseURL, ""
+	}
+	password, ok := u.User.Password()
+	if !ok {
+		return databaseURL, ""
+	}
+	u.User = url.User(u.User.Username())
+	return u.String(), password
+}
+
+// Restore восстанавливает базу данных из резервной копии.
+// Существующие объекты схемы пересоздаются.
+func Restore(ctx context.Context, databaseURL, path string) error {
+	dumpPath := filepath.Join(path, dumpFileName)
+	if _, err := os.Stat(dumpPath); err != nil {
+		return fmt.Errorf("database dump not found: %w", err)
+	}
+
+	cmd := pgCommand(ctx, databaseURL, "pg_restore", "--clean", "--if-exists", "--no-owner", "--single-transaction", dumpPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pg_restore failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Verify сверяет манифест резервной копии с объектами в MinIO и
+// возвращает объекты, которые отсутствуют или изменились.
+func Verify(ctx context.Context, client *minio.Client, path string) ([]ManifestObject, error) {
+	data, err := os.ReadFile(filepath.Join(path, manifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	var missing []ManifestObject
+	for _, obj := range manifest.Objects {
+		info, err := client.StatObject(ctx, obj.Bucket, obj.Key, minio.StatObjectOptions{VersionID: obj.VersionID})
+		if err != nil || info.ETag != obj.ETag {
+			missing = append(missing, obj)
+		}
+	}
+	return missing, nil
+}
+
+// LastStatus возвращает статус последнего резервного копирования.
+// Если копирование еще не выполнялось, возвращает nil без ошибки.
+func LastStatus(dir string) (*Status, error) {
+	data, err := os.ReadFile(filepath.Join(dir, statusFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup status: %w", err)
+	}
+
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse backup status: %w", err)
+	}
+	return &status, nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Пишем через временный файл, чтобы не оставить поврежденный JSON
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,22 @@
+package backup
+
+import "testing"
+
+func TestSplitPassword(t *testing.T) {
+	tests := []struct {
+		url, conn, password string
+	}{
+		{"postgres://app:s3cr%40t@db:5432/app?sslmode=disable", "postgres://app@db:5432/app?sslmode=disable", "s3cr@t"},
+		{"postgresql://app@db/app", "postgresql://app@db/app", ""},
+		{"host=db user=app password=secret", "host=db user=app password=secret", ""},
+	}
+	for _, tc := range tests {
+		conn, password := splitPassword(tc.url)
+		if conn != tc.conn || password != tc.password {
+			t.Errorf("splitPassword(%q) = %q, %q, want %q, %q", tc.url, conn, password, tc.conn, tc.password)
+		}
+	}
+	if cmd := pgCommand(t.Context(), tests[0].url, "pg_dump", "--file", "x"); cmd.Args[len(cmd.Args)-1] != tests[0].conn {
+		t.Errorf("pg_dump args = %v, want connection without password", cmd.Args)
+	}
+}
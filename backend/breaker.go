@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrCircuitOpen возвращается, когда breaker разомкнут и вызовы не выполняются
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState состояние circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker размыкается после threshold последовательных ошибок и
+// отклоняет вызовы в течение cooldown. Затем пропускает один пробный вызов:
+// при успехе замыкается, при ошибке снова размыкается.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	critical  bool
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+	trips    int64
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   []*CircuitBreaker
+)
+
+// NewCircuitBreaker создает breaker и регистрирует его для /readyz и метрик
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return newCircuitBreaker(name, threshold, cooldown, true)
+}
+
+// NewIntegrationBreaker создает breaker внешней интеграции. Он виден в метриках и
+// диагностике, но не переводит сервер в неготовность: без SMS или OCR API работает.
+func NewIntegrationBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return newCircuitBreaker(name, threshold, cooldown, false)
+}
+
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration, critical bool) *CircuitBreaker {
+	b := &CircuitBreaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		critical:  critical,
+	}
+
+	breakersMu.Lock()
+	breakers = append(breakers, b)
+	breakersMu.Unlock()
+
+	return b
+}
+
+// CircuitBreakers возвращает все зарегистрированные breakers
+func CircuitBreakers() []*CircuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	return append([]*CircuitBreaker(nil), breakers...)
+}
+
+// Name возвращает имя breaker
+func (b *CircuitBreaker) Name() string {
+	return b.name
+}
+
+// Critical сообщает, что без зависимости сервер не готов принимать трафик
+func (b *CircuitBreaker) Critical() bool {
+	return b.critical
+}
+
+// Allow проверяет, можно ли выполнить вызов
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success отмечает успешный вызов
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure отмечает неудачный вызов
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+		b.trips++
+	}
+}
+
+// Release завершает вызов, результат которого не говорит о доступности
+// сервиса (например, запрос отменен клиентом)
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State возвращает текущее состояние
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Trips возвращает число размыканий с момента запуска
+func (b *CircuitBreaker) Trips() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.trips
+}
+
+// failoverConnector подключается к первому доступному серверу PostgreSQL
+// из списка. Ошибки подключения ко всем серверам учитываются breaker.
+type failoverConnector struct {
+	connectors []driver.Connector
+	breaker    *CircuitBreaker
+}
+
+func newFailoverConnector(urls []string, breaker *CircuitBreaker) (*failoverConnector, error) {
+	c := &failoverConnector{breaker: breaker}
+	for _, url := range urls {
+		connector, err := pq.NewConnector(url)
+		if err != nil {
+			return nil, err
+		}
+		c.connectors = append(c.connectors, connector)
+	}
+	return c, nil
+}
+
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, connector := range c.connectors {
+		conn, err := connector.Connect(ctx)
+		if err == nil {
+			c.breaker.Success()
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	// Отмена запроса клиентом не говорит о недоступности базы
+	if ctx.Err() == nil {
+		c.breaker.Failure()
+	} else {
+		c.breaker.Release()
+	}
+	return nil, lastErr
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// breakerTransport пропускает HTTP запросы к MinIO через breaker
+type breakerTransport struct {
+	base    http.RoundTripper
+	breaker *CircuitBreaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		t.breaker.Release()
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		t.breaker.Failure()
+	default:
+		t.breaker.Success()
+	}
+	return resp, err
+}
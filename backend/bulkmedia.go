@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxBulkMediaFiles сколько файлов можно загрузить одним запросом
+	maxBulkMediaFiles = 50
+	// maxBulkMediaFileSize ограничение на каждый файл, как в POST /posts/{id}/media
+	maxBulkMediaFileSize = 10 << 20
+)
+
+// bulkMediaTypes допустимые расширения и типы содержимого медиа постов, как в ValidateMediaFile
+var bulkMediaTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+}
+
+// bulkMediaFile файл из формы или запись zip архива
+type bulkMediaFile struct {
+	name string
+	// Размер, заявленный в форме или в заголовке записи архива
+	size int64
+	open func() (io.ReadCloser, error)
+}
+
+// collectBulkMediaFiles собирает файлы из полей media и архивов из поля archive.
+// Архивы остаются открытыми до вызова возвращенной функции closeArchives.
+func collectBulkMediaFiles(form *multipart.Form) ([]bulkMediaFile, func(), error) {
+	var files []bulkMediaFile
+	var archives []multipart.File
+	closeArchives := func() {
+		for _, archive := range archives {
+			archive.Close()
+		}
+	}
+
+	for _, header := range form.File["media"] {
+		header := header
+		files = append(files, bulkMediaFile{
+			name: header.Filename,
+			size: header.Size,
+			open: func() (io.ReadCloser, error) { return header.Open() },
+		})
+	}
+	for _, header := range form.File["archive"] {
+		archive, err := header.Open()
+		if err != nil {
+			closeArchives()
+			return nil, nil, NewInternalError("Ошибка чтения архива")
+		}
+		archives = append(archives, archive)
+
+		entries, err := zipMediaFiles(archive, header)
+		if err != nil {
+			closeArchives()
+			return nil, nil, err
+		}
+		files = append(files, entries...)
+	}
+	return files, closeArchives, nil
+}
+
+// zipMediaFiles возвращает файлы zip архива, пропуская каталоги и служебные файлы macOS
+func zipMediaFiles(file multipart.File, header *multipart.FileHeader) ([]bulkMediaFile, error) {
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		return nil, NewValidationError("Не удалось прочитать zip архив", map[string]interface{}{"field": "archive", "file": header.Filename})
+	}
+
+	var files []bulkMediaFile
+	for _, entry := range archive.File {
+		name := path.Base(entry.Name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(entry.Name, "__MACOSX/") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		entry := entry
+		files = append(files, bulkMediaFile{
+			name: entry.Name,
+			size: int64(entry.UncompressedSize64),
+			open: func() (io.ReadCloser, error) { return entry.Open() },
+		})
+	}
+	return files, nil
+}
+
+// readBulkMediaFile читает и проверяет файл: расширение, размер и настоящий тип содержимого.
+// Изображения пережимаются без метаданных. Возвращает содержимое, Content-Type и тип медиа.
+func readBulkMediaFile(file bulkMediaFile) ([]byte, string, string, error) {
+	message := "Разрешенные форматы медиа: JPEG, PNG, WebP, MP4, WebM"
+	if _, ok := bulkMediaTypes[strings.ToLower(filepath.Ext(file.name))]; !ok {
+		return nil, "", "", NewUnsupportedMediaError(message)
+	}
+	if file.size > maxBulkMediaFileSize {
+		return nil, "", "", NewFileTooLargeError("10MB")
+	}
+
+	reader, err := file.open()
+	if err != nil {
+		return nil, "", "", NewInternalError("Ошибка чтения файла")
+	}
+	defer reader.Close()
+
+	// Размер в заголовке zip записи может не совпадать с содержимым
+	data, err := io.ReadAll(io.LimitReader(reader, maxBulkMediaFileSize+1))
+	if err != nil {
+		return nil, "", "", NewUnsupportedMediaError("Не удалось прочитать файл")
+	}
+	if len(data) > maxBulkMediaFileSize {
+		return nil, "", "", NewFileTooLargeError("10MB")
+	}
+
+	contentType := http.DetectContentType(data)
+	allowed := false
+	for _, allowedType := range bulkMediaTypes {
+		if contentType == allowedType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, "", "", NewUnsupportedMediaError(message)
+	}
+	if strings.HasPrefix(contentType, "video/") {
+		return data, contentType, "video", nil
+	}
+
+	data, contentType, err = readImageUpload(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", "", err
+	}
+	return data, contentType, "image", nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type cachedResponse struct {
+	header   http.Header
+	body     []byte
+	storedAt time.Time
+}
+
+// DegradedCache хранит последние успешные ответы публичных GET эндпоинтов и
+// отдает их, когда хранилище недоступно (ответ 5xx при разомкнутом breaker).
+type DegradedCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.RWMutex
+	entries map[string]*cachedResponse
+}
+
+func NewDegradedCache(ttl time.Duration, maxEntries int) *DegradedCache {
+	return &DegradedCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*cachedResponse),
+	}
+}
+
+// Wrap оборачивает обработчик чтения. Ответы с разными токенами хранятся отдельно:
+// лента авторизованного пользователя учитывает его скрытые посты и авторов.
+func (c *DegradedCache) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.RequestURI()
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			key += "\x00" + auth
+		}
+		rec := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		next(rec, r)
+
+		if rec.status == http.StatusOK {
+			c.store(key, rec)
+		} else if rec.status >= http.StatusInternalServerError && anyBreakerOpen() {
+			if cached := c.load(key); cached != nil {
+				metrics.Inc("degraded_responses_total", "Responses served from the degraded cache", map[string]string{"path": r.URL.Path})
+				for k, v := range cached.header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("X-Degraded", "true")
+				w.Header().Set("X-Cached-At", cached.storedAt.UTC().Format(time.RFC3339))
+				w.WriteHeader(http.StatusOK)
+				w.Write(cached.body)
+				return
+			}
+		}
+
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	}
+}
+
+func (c *DegradedCache) store(key string, rec *responseRecorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		c.evictOldest()
+	}
+	c.entries[key] = &cachedResponse{
+		header:   rec.header.Clone(),
+		body:     append([]byte(nil), rec.body.Bytes()...),
+		storedAt: time.Now(),
+	}
+}
+
+func (c *DegradedCache) load(key string) *cachedResponse {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.storedAt) > c.ttl {
+		return nil
+	}
+	return entry
+}
+
+func (c *DegradedCache) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if oldestKey == "" || entry.storedAt.Before(oldest) {
+			oldestKey = key
+			oldest = entry.storedAt
+		}
+	}
+	delete(c.entries, oldestKey)
+}
+
+// responseRecorder буферизует ответ обработчика
+type responseRecorder struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
+
+func anyBreakerOpen() bool {
+	for _, b := range CircuitBreakers() {
+		if b.Critical() && b.State() != BreakerClosed {
+			return true
+		}
+	}
+	return false
+}
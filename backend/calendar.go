@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// calendarLineLimit максимальная длина строки iCalendar в байтах (RFC 5545, 3.1)
+const calendarLineLimit = 75
+
+// GenerateCalendarToken генерирует токен ссылки на календарь сроков автора
+func GenerateCalendarToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CalendarFeedURL ссылка на календарь по шаблону CALENDAR_FEED_URL
+func CalendarFeedURL(cfg *Config, token string) string {
+	return strings.ReplaceAll(cfg.Calendar.FeedURL, "{token}", token)
+}
+
+// RenderDeadlineCalendar создает календарь iCalendar со сроками сборов автора: событие
+// на момент окончания срока и напоминание за сутки для активных сборов. postURL - шаблон
+// ссылки на пост, {id} заменяется на ID поста.
+func RenderDeadlineCalendar(deadlines []PostDeadline, postURL string) []byte {
+	var buf bytes.Buffer
+	writeCalendarLine(&buf, "BEGIN:VCALENDAR")
+	writeCalendarLine(&buf, "VERSION:2.0")
+	writeCalendarLine(&buf, "PRODID:-//Hackathon Bot//Post deadlines//RU")
+	writeCalendarLine(&buf, "CALSCALE:GREGORIAN")
+	writeCalendarLine(&buf, "METHOD:PUBLISH")
+	writeCalendarLine(&buf, "X-WR-CALNAME:"+escapeCalendarText("Сроки сборов"))
+
+	for _, d := range deadlines {
+		at := d.Deadline.UTC().Format("20060102T150405Z")
+		writeCalendarLine(&buf, "BEGIN:VEVENT")
+		writeCalendarLine(&buf, fmt.Sprintf("UID:post-%d-deadline@hackathon-bot", d.PostID))
+		writeCalendarLine(&buf, "DTSTAMP:"+d.UpdatedAt.UTC().Format("20060102T150405Z"))
+		writeCalendarLine(&buf, "DTSTART:"+at)
+		writeCalendarLine(&buf, "DTEND:"+at)
+		writeCalendarLine(&buf, "SUMMARY:"+escapeCalendarText("Окончание сбора: "+d.Title))
+		if postURL != "" {
+			writeCalendarLine(&buf, "URL:"+strings.ReplaceAll(postURL, "{id}", strconv.FormatInt(d.PostID, 10)))
+		}
+		if d.Status == "active" {
+			writeCalendarLine(&buf, "BEGIN:VALARM")
+			writeCalendarLine(&buf, "ACTION:DISPLAY")
+			writeCalendarLine(&buf, "DESCRIPTION:"+escapeCalendarText("Завтра заканчивается сбор: "+d.Title))
+			writeCalendarLine(&buf, "TRIGGER:-P1D")
+			writeCalendarLine(&buf, "END:VALARM")
+		}
+		writeCalendarLine(&buf, "END:VEVENT")
+	}
+
+	writeCalendarLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// escapeCalendarText экранирует значение TEXT: обратную косую черту, запятую, точку с запятой и переводы строк
+func escapeCalendarText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// writeCalendarLine записывает строку с CRLF, перенося ее по calendarLineLimit байт
+// без разрыва символов UTF-8. Строки продолжения начинаются с пробела.
+func writeCalendarLine(buf *bytes.Buffer, line string) {
+	limit := calendarLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		limit = calendarLineLimit - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
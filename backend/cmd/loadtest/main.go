@@ -0,0 +1,278 @@
+// Команда loadtest выполняет нагрузочный тест ленты, чатов и пожертвований
+// с постоянной интенсивностью запросов и сверяет результаты с бюджетами
+// производительности из loadtest/budgets.json.
+//
+// Использование:
+//
+//	loadtest -base-url http://localhost:8080 -phone +79990000000 -password secret \
+//	         -post-id 1 -chat-id 1 -rate 20 -duration 30s
+//
+// Завершается с кодом 1, если хотя бы один сценарий вышел за бюджет.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budget допустимые задержки и доля ошибок сценария
+type Budget struct {
+	P95Ms        float64 `json:"p95_ms"`
+	P99Ms        float64 `json:"p99_ms"`
+	MaxErrorRate float64 `json:"max_error_rate"`
+}
+
+type scenario struct {
+	name    string
+	auth    bool
+	request func() (*http.Request, error)
+}
+
+type result struct {
+	latencies []time.Duration
+	errors    int
+}
+
+func main() {
+	baseURL := flag.String("base-url", "http://localhost:8080", "адрес сервера")
+	budgetsPath := flag.String("budgets", "loadtest/budgets.json", "файл с бюджетами производительности")
+	rate := flag.Int("rate", 10, "запросов в секунду на каждый сценарий")
+	duration := flag.Duration("duration", 30*time.Second, "длительность теста")
+	token := flag.String("token", "", "JWT токен для защищенных эндпоинтов")
+	phone := flag.String("phone", "", "телефон для входа, если токен не задан")
+	password := flag.String("password", "", "пароль для входа, если токен не задан")
+	postID := flag.Int64("post-id", 0, "ID поста для сценариев post и donations")
+	chatID := flag.Int64("chat-id", 0, "ID чата для сценариев messages и send_message")
+	write := flag.Bool("write", false, "включить сценарии с записью (send_message)")
+	only := flag.String("scenarios", "", "список сценариев через запятую (по умолчанию все доступные)")
+	flag.Parse()
+
+	budgets, err := loadBudgets(*budgetsPath)
+	if err != nil {
+		log.Fatalf("Failed to load budgets: %v", err)
+	}
+
+	api := strings.TrimRight(*baseURL, "/") + "/api/v1"
+	if *token == "" && *phone != "" {
+		*token, err = login(api, *phone, *password)
+		if err != nil {
+			log.Fatalf("Login failed: %v", err)
+		}
+	}
+
+	scenarios := buildScenarios(api, *postID, *chatID, *write)
+	scenarios = filterScenarios(scenarios, *token != "", *only)
+	if len(scenarios) == 0 {
+		log.Fatal("No scenarios to run")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	results := make(map[string]*result)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	log.Printf("Running %d scenarios at %d req/s each for %s", len(scenarios), *rate, *duration)
+	for _, sc := range scenarios {
+		res := &result{}
+		results[sc.name] = res
+
+		wg.Add(1)
+		go func(sc scenario) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(time.Second / time.Duration(*rate))
+			defer ticker.Stop()
+			deadline := time.After(*duration)
+
+			var inflight sync.WaitGroup
+			for {
+				select {
+				case <-deadline:
+					inflight.Wait()
+					return
+				case <-ticker.C:
+					inflight.Add(1)
+					go func() {
+						defer inflight.Done()
+						latency, ok := do(client, sc, *token)
+						mu.Lock()
+						res.latencies = append(res.latencies, latency)
+						if !ok {
+							res.errors++
+						}
+						mu.Unlock()
+					}()
+				}
+			}
+		}(sc)
+	}
+	wg.Wait()
+
+	failed := false
+	fmt.Printf("\n%-14s %8s %8s %8s %8s %8s  %s\n", "scenario", "requests", "errors", "p50 ms", "p95 ms", "p99 ms", "budget")
+	for _, sc := range scenarios {
+		res := results[sc.name]
+		p50, p95, p99 := percentile(res.latencies, 50), percentile(res.latencies, 95), percentile(res.latencies, 99)
+		errorRate := 0.0
+		if len(res.latencies) > 0 {
+			errorRate = float64(res.errors) / float64(len(res.latencies))
+		}
+
+		verdict := "n/a"
+		if budget, ok := budgets[sc.name]; ok {
+			verdict = "ok"
+			var violations []string
+			if budget.P95Ms > 0 && p95 > budget.P95Ms {
+				violations = append(violations, fmt.Sprintf("p95 > %.0f", budget.P95Ms))
+			}
+			if budget.P99Ms > 0 && p99 > budget.P99Ms {
+				violations = append(violations, fmt.Sprintf("p99 > %.0f", budget.P99Ms))
+			}
+			if errorRate > budget.MaxErrorRate {
+				violations = append(violations, fmt.Sprintf("errors %.2f%% > %.2f%%", errorRate*100, budget.MaxErrorRate*100))
+			}
+			if len(violations) > 0 {
+				verdict = "FAIL: " + strings.Join(violations, ", ")
+				failed = true
+			}
+		}
+
+		fmt.Printf("%-14s %8d %8d %8.1f %8.1f %8.1f  %s\n", sc.name, len(res.latencies), res.errors, p50, p95, p99, verdict)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func buildScenarios(api string, postID, chatID int64, write bool) []scenario {
+	get := func(url string) func() (*http.Request, error) {
+		return func() (*http.Request, error) { return http.NewRequest(http.MethodGet, url, nil) }
+	}
+
+	scenarios := []scenario{
+		{name: "feed", request: get(api + "/posts?status=active&page=1&limit=20")},
+		{name: "chats", auth: true, request: get(api + "/chats")},
+	}
+	if postID > 0 {
+		scenarios = append(scenarios,
+			scenario{name: "post", request: get(fmt.Sprintf("%s/posts/%d", api, postID))},
+			scenario{name: "donations", request: get(fmt.Sprintf("%s/donations?post_id=%d&page=1&limit=20", api, postID))},
+		)
+	}
+	if chatID > 0 {
+		scenarios = append(scenarios, scenario{name: "messages", auth: true, request: get(fmt.Sprintf("%s/chats/%d/messages?page=1&limit=50", api, chatID))})
+		if write {
+			scenarios = append(scenarios, scenario{name: "send_message", auth: true, request: func() (*http.Request, error) {
+				var body bytes.Buffer
+				mw := multipart.NewWriter(&body)
+				mw.WriteField("text", "loadtest "+time.Now().Format(time.RFC3339Nano))
+				mw.Close()
+				req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/chats/%d/messages", api, chatID), &body)
+				if err != nil {
+					return nil, err
+				}
+				req.Header.Set("Content-Type", mw.FormDataContentType())
+				return req, nil
+			}})
+		}
+	}
+	return scenarios
+}
+
+func filterScenarios(scenarios []scenario, authenticated bool, only string) []scenario {
+	selected := make(map[string]bool)
+	for _, name := range strings.Split(only, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			selected[name] = true
+		}
+	}
+
+	var result []scenario
+	for _, sc := range scenarios {
+		if len(selected) > 0 && !selected[sc.name] {
+			continue
+		}
+		if sc.auth && !authenticated {
+			log.Printf("Skipping %s: no token", sc.name)
+			continue
+		}
+		result = append(result, sc)
+	}
+	return result
+}
+
+func do(client *http.Client, sc scenario, token string) (time.Duration, bool) {
+	req, err := sc.request()
+	if err != nil {
+		return 0, false
+	}
+	if sc.auth {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Since(start), false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return time.Since(start), resp.StatusCode < http.StatusBadRequest
+}
+
+func login(api, phone, password string) (string, error) {
+	body, _ := json.Marshal(map[string]string{"phone": phone, "password": password})
+	resp, err := http.Post(api+"/auth/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.Token, nil
+}
+
+func loadBudgets(path string) (map[string]Budget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var budgets map[string]Budget
+	if err := json.Unmarshal(data, &budgets); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+// percentile возвращает перцентиль задержки в миллисекундах
+func percentile(latencies []time.Duration, p float64) float64 {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(float64(len(sorted)-1) * p / 100)
+	return float64(sorted[idx].Microseconds()) / 1000
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/singleflight"
+)
+
+// RequestCoalescer объединяет одновременные одинаковые запросы к публичным GET
+// эндпоинтам: обработчик выполняется один раз, остальные запросы с тем же URL
+// ждут его и получают копию ответа. Всплеск запросов одной ленты или поста
+// превращается в один набор запросов к PostgreSQL. Результат не кэшируется:
+// запрос, пришедший после завершения обработчика, выполняется заново.
+type RequestCoalescer struct {
+	group singleflight.Group
+}
+
+func NewRequestCoalescer() *RequestCoalescer {
+	return &RequestCoalescer{}
+}
+
+// Wrap оборачивает обработчик чтения. Ответ не должен зависеть от заголовков
+// запроса, кроме Authorization: запросы с разными токенами не объединяются.
+func (c *RequestCoalescer) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.RequestURI()
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			key += "\x00" + auth
+		}
+
+		result, _, shared := c.group.Do(key, func() (interface{}, error) {
+			rec := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+			// Ответ нужен всем ожидающим, поэтому отключение первого клиента его не отменяет
+			next(rec, r.WithContext(context.WithoutCancel(r.Context())))
+			return rec, nil
+		})
+		rec := result.(*responseRecorder)
+
+		if shared {
+			path := r.URL.Path
+			if route := mux.CurrentRoute(r); route != nil {
+				if tmpl, err := route.GetPathTemplate(); err == nil {
+					path = tmpl
+				}
+			}
+			metrics.Inc("coalesced_requests_total", "Requests that shared a handler run with identical concurrent requests", map[string]string{"path": path})
+		}
+
+		for k, v := range rec.header.Clone() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// Соответствие API спецификации Swagger (docs/swagger.json): маршруты роутера совпадают
+// с описанными путями, коды и тела ответов - с описанными схемами. Спецификация обновляется
+// через make swagger.
+
+// specRootPaths описанные пути, которые обслуживаются вне basePath
+var specRootPaths = map[string]string{
+	"/health": "Служебный маршрут для балансировщика, обслуживается без /api/v1",
+	"/readyz": "Служебный маршрут для балансировщика, обслуживается без /api/v1",
+}
+
+// specUndocumented маршруты, которые намеренно не описаны
+var specUndocumented = map[string]string{
+	"GET /metrics":                    "Метрики Prometheus, не часть API",
+	"GET /files/{bucket}/{objectKey}": "Тот же обработчик, что GET /api/v1/files/{bucket}/{objectKey}, для старых ссылок",
+}
+
+// swaggerSpec часть спецификации Swagger 2.0, нужная для проверки
+type swaggerSpec struct {
+	BasePath    string                                 `json:"basePath"`
+	Paths       map[string]map[string]swaggerOperation `json:"paths"`
+	Definitions map[string]*swaggerSchema              `json:"definitions"`
+}
+
+type swaggerOperation struct {
+	Responses map[string]struct{ Schema *swaggerSchema } `json:"responses"`
+}
+
+type swaggerSchema struct {
+	Ref                  string                    `json:"$ref"`
+	Type                 string                    `json:"type"`
+	Properties           map[string]*swaggerSchema `json:"properties"`
+	AdditionalProperties json.RawMessage           `json:"additionalProperties"`
+	Items                *swaggerSchema            `json:"items"`
+	Required             []string                  `json:"required"`
+	AllOf                []*swaggerSchema          `json:"allOf"`
+	Enum                 []interface{}             `json:"enum"`
+}
+
+func loadSwaggerSpec(t *testing.T) *swaggerSpec {
+	t.Helper()
+	data, err := os.ReadFile("docs/swagger.json")
+	if err != nil {
+		t.Fatalf("read spec: %v", err)
+	}
+	var spec swaggerSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	return &spec
+}
+
+// operations возвращает описанные операции по ключу "GET /api/v1/posts/{id}"
+func (spec *swaggerSpec) operations() map[string]swaggerOperation {
+	ops := make(map[string]swaggerOperation)
+	for path, methods := range spec.Paths {
+		if _, ok := specRootPaths[path]; !ok {
+			path = spec.BasePath + path
+		}
+		for method, op := range methods {
+			ops[strings.ToUpper(method)+" "+path] = op
+		}
+	}
+	return ops
+}
+
+// routeParamPattern регулярное выражение параметра в шаблоне маршрута: {token:[0-9a-f]{64}}
+var routeParamPattern = regexp.MustCompile(`\{(\w+):(?:[^{}]|\{[^{}]*\})*\}`)
+
+// specRouteKey ключ маршрута в виде ключа описанной операции
+func specRouteKey(method, template string) string {
+	return method + " " + routeParamPattern.ReplaceAllString(template, "{$1}")
+}
+
+// Маршруты роутера совпадают с описанными путями. Роутер собирается без базы:
+// обработчики при обходе не вызываются.
+func TestRoutesMatchSpec(t *testing.T) {
+	spec := loadSwaggerSpec(t)
+	router := NewRouter(NewConfig(), nil, &Handlers{}, nil, nil)
+
+	registered := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// Маршруты без методов - префиксы подроутеров и Swagger UI
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			registered[specRouteKey(method, template)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+
+	documented := spec.operations()
+	for key := range registered {
+		if _, ok := specUndocumented[key]; !ok {
+			if _, ok := documented[key]; !ok {
+				t.Errorf("%s: route is not documented", key)
+			}
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			t.Errorf("%s: documented route is not registered", key)
+		}
+	}
+}
+
+// Ответы на запросы матрицы доступа соответствуют спецификации: код ответа описан,
+// тело - описанной схеме. GET запросы выполняются от имени всех пользователей матрицы,
+// остальные - без токена, если маршрут его требует: запрос отклоняется до обработчика.
+func TestResponsesMatchSpec(t *testing.T) {
+	s := newTestServer(t)
+	spec := loadSwaggerSpec(t)
+	documented := spec.operations()
+
+	for _, tc := range authzCases {
+		if tc.stream {
+			continue
+		}
+		method, template, _ := strings.Cut(s.routeKey(tc.method, tc.path), " ")
+		op, ok := documented[specRouteKey(method, template)]
+		if !ok {
+			continue
+		}
+
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			f := s.newAuthzFixture()
+			path, body := f.expand(tc.path, tc.body)
+			for identity, want := range tc.want {
+				if tc.method != http.MethodGet && (identity != asAnonymous || want != http.StatusUnauthorized) {
+					continue
+				}
+				rec := s.request(f.users[identity], tc.method, path, body)
+				for _, e := range spec.checkResponse(op, rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes()) {
+					t.Errorf("%s: status %d: %s", identityNames[identity], rec.Code, e)
+				}
+			}
+		})
+	}
+}
+
+// checkResponse сверяет ответ с описанием операции. Тело проверяется только у JSON ответов.
+func (spec *swaggerSpec) checkResponse(op swaggerOperation, status int, contentType string, body []byte) []string {
+	response, ok := op.Responses[fmt.Sprint(status)]
+	if !ok {
+		response, ok = op.Responses["default"]
+	}
+	if !ok {
+		return []string{"status is not documented"}
+	}
+	if response.Schema == nil || !strings.HasPrefix(contentType, "application/json") {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("response is not JSON: %v", err)}
+	}
+	var errs []string
+	spec.validate(value, response.Schema, "$", &errs)
+	return errs
+}
+
+// resolve раскрывает $ref и объединяет allOf в одну схему
+func (spec *swaggerSpec) resolve(schema *swaggerSchema) *swaggerSchema {
+	for schema.Ref != "" {
+		def, ok := spec.Definitions[strings.TrimPrefix(schema.Ref, "#/definitions/")]
+		if !ok {
+			return &swaggerSchema{}
+		}
+		schema = def
+	}
+	if len(schema.AllOf) == 0 {
+		return schema
+	}
+
+	merged := &swaggerSchema{Type: "object", Properties: make(map[string]*swaggerSchema)}
+	for _, part := range schema.AllOf {
+		part = spec.resolve(part)
+		for name, prop := range part.Properties {
+			merged.Properties[name] = prop
+		}
+		merged.Required = append(merged.Required, part.Required...)
+		if part.Type != "" && part.Type != "object" {
+			merged.Type = part.Type
+		}
+	}
+	return merged
+}
+
+// validate сверяет значение со схемой. null допускается везде: в Go ему
+// соответствуют nil указатели, срезы и карты.
+func (spec *swaggerSpec) validate(value interface{}, schema *swaggerSchema, at string, errs *[]string) {
+	if value == nil {
+		return
+	}
+	schema = spec.resolve(schema)
+	mismatch := func(expected string) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %T", at, expected, value))
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch("object")
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, fmt.Sprintf("%s: missing required field %q", at, name))
+			}
+		}
+		additional := spec.additional(schema)
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := schema.Properties[name]; ok {
+				spec.validate(obj[name], prop, at+"."+name, errs)
+			} else if additional != nil {
+				spec.validate(obj[name], additional, at+"."+name, errs)
+			} else if len(schema.Properties) > 0 {
+				*errs = append(*errs, fmt.Sprintf("%s: field %q is not documented", at, name))
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			mismatch("array")
+			return
+		}
+		if schema.Items != nil {
+			for i, item := range items {
+				spec.validate(item, schema.Items, fmt.Sprintf("%s[%d]", at, i), errs)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			mismatch("string")
+			return
+		}
+		if len(schema.Enum) > 0 && !specEnumContains(schema.Enum, s) {
+			*errs = append(*errs, fmt.Sprintf("%s: value %q is not in enum", at, s))
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			mismatch("integer")
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			mismatch("number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			mismatch("boolean")
+		}
+	}
+}
+
+// additional возвращает схему значений карты (additionalProperties), nil - карты нет
+func (spec *swaggerSpec) additional(schema *swaggerSchema) *swaggerSchema {
+	if len(schema.AdditionalProperties) == 0 {
+		return nil
+	}
+	var permitted bool
+	if err := json.Unmarshal(schema.AdditionalProperties, &permitted); err == nil {
+		if permitted {
+			return &swaggerSchema{}
+		}
+		return nil
+	}
+	var additional swaggerSchema
+	if err := json.Unmarshal(schema.AdditionalProperties, &additional); err != nil {
+		return &swaggerSchema{}
+	}
+	return &additional
+}
+
+func specEnumContains(enum []interface{}, value string) bool {
+	for _, item := range enum {
+		if fmt.Sprint(item) == value {
+			return true
+		}
+	}
+	return false
+}
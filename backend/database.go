@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"log"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -11,23 +16,168 @@ import (
 
 type DB struct {
 	*sql.DB
+	slowQueryThreshold time.Duration
+	legacyTimezone     string
+	// Шифрование персональных данных верификации, nil - данные хранятся открыто
+	pii *PIICipher
+	// Начисление баллов за подтвержденные пожертвования
+	money MoneyConfig
 }
 
-func NewDB(databaseURL string) (*DB, error) {
-	db, err := sql.Open("postgres", databaseURL)
+// NewDB подключается к PostgreSQL. При недоступности основного сервера
+// подключения идут к резервным из cfg.DatabaseFailover по порядку.
+func NewDB(cfg *Config, breaker *CircuitBreaker) (*DB, error) {
+	var urls []string
+	for _, dsn := range append([]string{cfg.DatabaseURL}, cfg.DatabaseFailover...) {
+		urls = append(urls, withSessionSettings(dsn, cfg.DatabaseStatementTimeout))
+	}
+
+	pii, err := NewPIICipher(cfg.PII)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := newFailoverConnector(urls, breaker)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db := sql.OpenDB(connector)
 
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{db}, nil
+	return &DB{DB: db, slowQueryThreshold: cfg.DatabaseSlowQuery, legacyTimezone: cfg.DatabaseLegacyTimezone, pii: pii, money: cfg.Money}, nil
+}
+
+// withSessionSettings добавляет в параметры подключения statement_timeout, чтобы
+// PostgreSQL прерывал зависшие запросы, и часовой пояс UTC, чтобы время в запросах
+// и ответах не зависело от настроек сервера. Заданные в DSN значения не меняются.
+func withSessionSettings(dsn string, timeout time.Duration) string {
+	settings := map[string]string{"timezone": "UTC"}
+	if timeout > 0 {
+		settings["statement_timeout"] = fmt.Sprintf("%d", timeout.Milliseconds())
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		for key, value := range settings {
+			if q.Get(key) == "" {
+				q.Set(key, value)
+			}
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	for _, key := range []string{"timezone", "statement_timeout"} {
+		if value, ok := settings[key]; ok && !strings.Contains(dsn, key+"=") {
+			dsn += " " + key + "=" + value
+		}
+	}
+	return dsn
+}
+
+// Query выполняет запрос с учетом медленных запросов
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext выполняет запрос с учетом медленных запросов
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	db.observeQuery(query, len(args), start, err)
+	return rows, err
+}
+
+// QueryRow выполняет запрос одной строки с учетом медленных запросов
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext выполняет запрос одной строки с учетом медленных запросов
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	db.observeQuery(query, len(args), start, row.Err())
+	return row
+}
+
+// Exec выполняет команду с учетом медленных запросов
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext выполняет команду с учетом медленных запросов
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	db.observeQuery(query, len(args), start, err)
+	return result, err
+}
+
+// WithTx выполняет fn в одной транзакции: фиксирует ее, если fn вернула nil,
+// иначе откатывает. Ошибка fn возвращается без изменений, чтобы AppError
+// (NotFound, Conflict и т.п.) доходили до обработчика.
+func (db *DB) WithTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+var whitespaceRe = regexp.MustCompile(`\s+`)
+
+// observeQuery пишет метрики запроса и логирует медленные запросы.
+// Значения параметров не логируются, только их количество.
+func (db *DB) observeQuery(query string, argCount int, start time.Time, err error) {
+	duration := time.Since(start)
+	query = strings.TrimSpace(whitespaceRe.ReplaceAllString(query, " "))
+
+	operation := "OTHER"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	labels := map[string]string{"operation": operation}
+
+	metrics.Inc("db_queries_total", "Database queries executed", labels)
+	metrics.Add("db_query_duration_seconds_total", "Total time spent in database queries", labels, duration.Seconds())
+	if err != nil && err != sql.ErrNoRows {
+		metrics.Inc("db_query_errors_total", "Database queries that returned an error", labels)
+	}
+
+	if db.slowQueryThreshold > 0 && duration >= db.slowQueryThreshold {
+		metrics.Inc("db_slow_queries_total", "Database queries slower than the configured threshold", labels)
+		log.Printf("Slow query (%s, %d args redacted): %s", duration.Round(time.Millisecond), argCount, query)
+	}
 }
 
 // InitSchema создает все таблицы базы данных
 func (db *DB) InitSchema() error {
+	// Столбцы TIMESTAMP, созданные до перехода на TIMESTAMPTZ, переводим с учетом часового пояса
+	if err := db.migrateTimestampColumns(); err != nil {
+		return err
+	}
+
+	// Таблицы, созданные до секционирования, переводим в секционированные
+	if err := db.migratePartitionedTables(); err != nil {
+		return err
+	}
+
 	queries := []string{
 		// Таблица users
 		`CREATE TABLE IF NOT EXISTS users (
@@ -39,12 +189,106 @@ func (db *DB) InitSchema() error {
 			photo_url VARCHAR(500),
 			role VARCHAR(20) DEFAULT 'user' CHECK (role IN ('user', 'helper', 'needy', 'admin')),
 			helper_name VARCHAR(100),
-			created_at TIMESTAMP DEFAULT NOW(),
-			updated_at TIMESTAMP DEFAULT NOW(),
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
 			is_active BOOLEAN DEFAULT true
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_phone ON users(phone)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_role ON users(role)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified BOOLEAN DEFAULT false`,
+		// Коды приглашения выпускаются партиями (batch) с ограничением числа регистраций
+		`CREATE TABLE IF NOT EXISTS invite_codes (
+			id BIGSERIAL PRIMARY KEY,
+			code VARCHAR(32) UNIQUE NOT NULL,
+			batch VARCHAR(100) NOT NULL,
+			max_uses INTEGER NOT NULL CHECK (max_uses > 0),
+			uses INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMPTZ,
+			created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_invite_codes_batch ON invite_codes(batch)`,
+		// Код, по которому зарегистрировался пользователь (для учета приглашений)
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS invite_code_id BIGINT REFERENCES invite_codes(id) ON DELETE SET NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_users_invite_code_id ON users(invite_code_id)`,
+		// Реферальная программа: код пользователя создается при первом запросе ссылки
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_code VARCHAR(32) UNIQUE`,
+		`CREATE TABLE IF NOT EXISTS referrals (
+			id BIGSERIAL PRIMARY KEY,
+			referrer_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			referee_id BIGINT UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			reject_reason VARCHAR(20),
+			ip_address VARCHAR(45),
+			bonus_points INTEGER NOT NULL DEFAULT 0,
+			donation_id BIGINT,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			rewarded_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_referrals_referrer_id ON referrals(referrer_id, created_at DESC)`,
+		// Часовой пояс пользователя (IANA) для дат в уведомлениях и документах
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '` + DefaultTimezone + `'`,
+		// Режим "нет на месте": текст автоответа (NULL - выключен) и время окончания (NULL - до отключения)
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS photo_variants JSONB`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS away_message TEXT`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS away_until TIMESTAMPTZ`,
+		// Последний вход или обновление сессии (для сроков хранения) и время обезличивания аккаунта.
+		// Аккаунтам, созданным раньше, время входа восстанавливается по refresh токенам.
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_active_at TIMESTAMPTZ`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ`,
+		// Access токены, выпущенные раньше этого времени, недействительны (смена и сброс пароля)
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ`,
+		// Хеш токена ссылки на календарь сроков сборов (NULL - календарь выключен)
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_token_hash VARCHAR(64) UNIQUE`,
+
+		// Таблицы roles и role_permissions (матрица прав ролей)
+		`CREATE TABLE IF NOT EXISTS roles (
+			name VARCHAR(20) PRIMARY KEY,
+			description VARCHAR(200) NOT NULL DEFAULT ''
+		)`,
+		`INSERT INTO roles (name, description) VALUES
+			('user', 'Пользователь'),
+			('helper', 'Помогающий'),
+			('needy', 'Нуждающийся'),
+			('admin', 'Администратор')
+		ON CONFLICT (name) DO NOTHING`,
+		`CREATE TABLE IF NOT EXISTS role_permissions (
+			role VARCHAR(20) NOT NULL REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+			permission VARCHAR(50) NOT NULL,
+			PRIMARY KEY (role, permission)
+		)`,
+		// Роль moderator: проверка верификаций и модерация постов.
+		// Права выдаются только при создании роли, чтобы не перезаписывать изменения администратора.
+		`WITH created AS (
+			INSERT INTO roles (name, description) VALUES ('moderator', 'Модератор')
+			ON CONFLICT (name) DO NOTHING
+			RETURNING name
+		)
+		INSERT INTO role_permissions (role, permission)
+		SELECT created.name, p FROM created, unnest(ARRAY['verifications.review', 'posts.moderate']) AS p`,
+		// Список ролей теперь хранится в таблице roles
+		`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check`,
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_role_fkey') THEN
+				ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
+			END IF;
+		END $$`,
+
+		// Таблица risk_tiers: уровни доверия авторов и максимальная целевая сумма поста.
+		// Уровни с min_completed_posts назначаются автоматически по числу завершенных сборов,
+		// остальные (organization) назначает администратор в users.risk_tier.
+		`CREATE TABLE IF NOT EXISTS risk_tiers (
+			name VARCHAR(20) PRIMARY KEY,
+			description VARCHAR(200) NOT NULL DEFAULT '',
+			max_post_amount DECIMAL(15,2) NOT NULL CHECK (max_post_amount > 0),
+			min_completed_posts INTEGER CHECK (min_completed_posts >= 0)
+		)`,
+		`INSERT INTO risk_tiers (name, description, max_post_amount, min_completed_posts) VALUES
+			('new', 'Новый автор', 100000, 0),
+			('trusted', 'Автор с завершенными сборами', 500000, 2),
+			('organization', 'Организация', 5000000, NULL)
+		ON CONFLICT (name) DO NOTHING`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS risk_tier VARCHAR(20) REFERENCES risk_tiers(name) ON UPDATE CASCADE ON DELETE SET NULL`,
 
 		// Таблица verifications
 		`CREATE TABLE IF NOT EXISTS verifications (
@@ -67,13 +311,41 @@ func (db *DB) InitSchema() error {
 			consent2 BOOLEAN DEFAULT false,
 			consent3 BOOLEAN DEFAULT false,
 			status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
-			submitted_at TIMESTAMP DEFAULT NOW(),
-			reviewed_at TIMESTAMP,
+			submitted_at TIMESTAMPTZ DEFAULT NOW(),
+			reviewed_at TIMESTAMPTZ,
 			reviewed_by BIGINT REFERENCES users(id),
 			rejection_reason TEXT
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_verifications_user_id ON verifications(user_id)`,
+		// Персональные данные хранятся зашифрованными (PIICipher), шифротекст длиннее исходных значений
+		`ALTER TABLE verifications
+			ALTER COLUMN passport_series TYPE TEXT,
+			ALTER COLUMN passport_number TYPE TEXT,
+			ALTER COLUMN inn TYPE TEXT,
+			ALTER COLUMN snils TYPE TEXT`,
 		`CREATE INDEX IF NOT EXISTS idx_verifications_status ON verifications(status)`,
+		// Документы других стран (справочник DocumentCountries): дополнительный документ
+		// проверяется по справочнику, поля без своего столбца хранятся в document_data
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS country VARCHAR(2) NOT NULL DEFAULT 'RU'`,
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS document_type VARCHAR(50) NOT NULL DEFAULT 'ru_passport'`,
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS document_data JSONB`,
+		`ALTER TABLE verifications DROP CONSTRAINT IF EXISTS verifications_doc_type_check`,
+		`ALTER TABLE verifications ALTER COLUMN doc_type TYPE VARCHAR(50)`,
+		// Организация заявителя и результат ее сверки с реестром НКО
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS organization_inn VARCHAR(10)`,
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS organization_ogrn VARCHAR(13)`,
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS organization_name VARCHAR(500)`,
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS organization_check VARCHAR(20)`,
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS organization_mismatches TEXT[]`,
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS organization_registry JSONB`,
+		`ALTER TABLE verifications ADD COLUMN IF NOT EXISTS organization_checked_at TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_verifications_organization_pending ON verifications(submitted_at) WHERE organization_check = 'pending'`,
+		// Кэш ответов реестра организаций по ИНН; data NULL - организации в реестре нет
+		`CREATE TABLE IF NOT EXISTS registry_organizations (
+			inn VARCHAR(10) PRIMARY KEY,
+			data JSONB,
+			fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
 
 		// Таблица posts
 		`CREATE TABLE IF NOT EXISTS posts (
@@ -87,13 +359,29 @@ func (db *DB) InitSchema() error {
 			bank VARCHAR(100) NOT NULL,
 			phone VARCHAR(20) NOT NULL,
 			status VARCHAR(20) DEFAULT 'active' CHECK (status IN ('active', 'completed', 'closed', 'moderated')),
-			created_at TIMESTAMP DEFAULT NOW(),
-			updated_at TIMESTAMP DEFAULT NOW(),
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
 			is_editable BOOLEAN DEFAULT true
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_posts_user_id ON posts(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_posts_status ON posts(status)`,
 		`CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at DESC)`,
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS beneficiary_is_minor BOOLEAN DEFAULT false`,
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS guardian_document_url VARCHAR(500)`,
+		// Картинка для соцсетей: отпечаток данных и собранная сумма на момент отрисовки
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS share_image_hash VARCHAR(64)`,
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS share_image_collected DECIMAL(15,2)`,
+		// Язык поста: NULL - еще не определен, пустая строка - определить не удалось
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS language VARCHAR(8)`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_language ON posts(language)`,
+		// Полнотекстовый поиск по заголовку и описанию, триграммы - для опечаток
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+		) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN(search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_title_trgm ON posts USING GIN(title gin_trgm_ops)`,
 
 		// Таблица post_media
 		`CREATE TABLE IF NOT EXISTS post_media (
@@ -102,27 +390,248 @@ func (db *DB) InitSchema() error {
 			media_url VARCHAR(500) NOT NULL,
 			media_type VARCHAR(20) NOT NULL CHECK (media_type IN ('image', 'video')),
 			order_index INTEGER DEFAULT 0,
-			created_at TIMESTAMP DEFAULT NOW()
+			created_at TIMESTAMPTZ DEFAULT NOW()
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_post_media_post_id ON post_media(post_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_post_media_order ON post_media(post_id, order_index)`,
+		// Уменьшенные копии изображений (small/medium/large), см. thumbnails.go
+		`ALTER TABLE post_media ADD COLUMN IF NOT EXISTS variants JSONB`,
+		// Размер загруженного файла для квоты хранилища (без уменьшенных копий)
+		`ALTER TABLE post_media ADD COLUMN IF NOT EXISTS size BIGINT NOT NULL DEFAULT 0`,
 
-		// Таблица donations
-		`CREATE TABLE IF NOT EXISTS donations (
+		// Таблица post_line_items: статьи расходов, из которых складывается целевая сумма поста
+		`CREATE TABLE IF NOT EXISTS post_line_items (
+			id BIGSERIAL PRIMARY KEY,
+			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			title VARCHAR(200) NOT NULL,
+			amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+			order_index INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_post_line_items_post ON post_line_items(post_id, order_index)`,
+
+		// Таблица post_limit_requests: запросы на сбор сверх лимита уровня доверия.
+		// Одобренный запрос действует на один пост (used_at, post_id).
+		`CREATE TABLE IF NOT EXISTS post_limit_requests (
 			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+			reason TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+			comment VARCHAR(500),
+			reviewed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			reviewed_at TIMESTAMPTZ,
+			post_id BIGINT REFERENCES posts(id) ON DELETE SET NULL,
+			used_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_post_limit_requests_user ON post_limit_requests(user_id, status)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_post_limit_requests_pending ON post_limit_requests(user_id) WHERE status = 'pending'`,
+
+		// Таблица donations (секционирована по месяцам, см. partitions.go)
+		`CREATE TABLE IF NOT EXISTS donations (
+			id BIGSERIAL,
 			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
 			donor_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 			amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
 			receipt_url VARCHAR(500),
-			status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'rejected')),
-			confirmed_at TIMESTAMP,
+			status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'rejected', 'refunded')),
+			confirmed_at TIMESTAMPTZ,
 			confirmed_by BIGINT REFERENCES users(id),
-			created_at TIMESTAMP DEFAULT NOW()
-		)`,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (id, created_at)
+		) PARTITION BY RANGE (created_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_donations_post_id ON donations(post_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_donations_donor_id ON donations(donor_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_donations_status ON donations(status)`,
 		`CREATE INDEX IF NOT EXISTS idx_donations_created_at ON donations(created_at DESC)`,
+		// Статус refunded: пожертвование отменено донором или возвращено администратором
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint
+			               WHERE conrelid = 'donations'::regclass AND conname = 'donations_status_check'
+			                 AND pg_get_constraintdef(oid) LIKE '%refunded%') THEN
+				ALTER TABLE donations DROP CONSTRAINT IF EXISTS donations_status_check;
+				ALTER TABLE donations ADD CONSTRAINT donations_status_check
+					CHECK (status IN ('pending', 'confirmed', 'rejected', 'refunded'));
+			END IF;
+		END $$`,
+		// Сверка чека распознаванием: сумма и дата из чека, результат для подтверждающего
+		`ALTER TABLE donations ADD COLUMN IF NOT EXISTS receipt_check VARCHAR(20)`,
+		`ALTER TABLE donations ADD COLUMN IF NOT EXISTS receipt_amount DECIMAL(15,2)`,
+		`ALTER TABLE donations ADD COLUMN IF NOT EXISTS receipt_date DATE`,
+		`ALTER TABLE donations ADD COLUMN IF NOT EXISTS receipt_checked_at TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_donations_receipt_pending ON donations(created_at) WHERE receipt_check = 'pending'`,
+		// Рейтинг за неделю, месяц и год считается по подтвержденным пожертвованиям периода
+		`CREATE INDEX IF NOT EXISTS idx_donations_confirmed_at ON donations(confirmed_at, donor_id) WHERE status = 'confirmed'`,
+		`ALTER TABLE donations ADD COLUMN IF NOT EXISTS is_anonymous BOOLEAN NOT NULL DEFAULT false`,
+		// События пожертвований: история изменений для аудита, записи только добавляются.
+		// Внешних ключей нет: у секционированной donations ключ включает created_at,
+		// а история должна сохраниться после удаления поста или пользователя.
+		`CREATE TABLE IF NOT EXISTS donation_events (
+			id BIGSERIAL PRIMARY KEY,
+			donation_id BIGINT NOT NULL,
+			event_type VARCHAR(30) NOT NULL CHECK (event_type IN ('created', 'receipt_attached', 'confirmed', 'rejected', 'reverted')),
+			actor_id BIGINT,
+			metadata JSONB,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_donation_events_donation_id ON donation_events(donation_id, id)`,
+		// Акции с повышенным начислением баллов: множитель действует для пожертвований,
+		// созданных в период акции. Начисленные баллы сохраняются, чтобы отмена
+		// подтверждения списала ровно их.
+		`CREATE TABLE IF NOT EXISTS points_events (
+			id BIGSERIAL PRIMARY KEY,
+			title VARCHAR(100) NOT NULL,
+			multiplier DECIMAL(4,2) NOT NULL CHECK (multiplier > 1),
+			starts_at TIMESTAMPTZ NOT NULL,
+			ends_at TIMESTAMPTZ NOT NULL,
+			created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			CHECK (ends_at > starts_at)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_points_events_period ON points_events(starts_at, ends_at)`,
+		`ALTER TABLE donations ADD COLUMN IF NOT EXISTS rating_points INTEGER`,
+		`CREATE OR REPLACE FUNCTION donation_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'donation_events is append-only';
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE TRIGGER donation_events_append_only BEFORE UPDATE OR DELETE ON donation_events
+			FOR EACH ROW EXECUTE FUNCTION donation_events_append_only()`,
+		// Пожертвования, созданные до появления событий, получают историю из своих полей
+		`INSERT INTO donation_events (donation_id, event_type, actor_id, metadata, created_at)
+		SELECT d.id, e.event_type, e.actor_id, e.metadata, e.created_at
+		FROM donations d
+		CROSS JOIN LATERAL (VALUES
+			('created', d.donor_id, jsonb_build_object('post_id', d.post_id, 'amount', d.amount, 'backfilled', true), d.created_at),
+			(CASE WHEN d.receipt_url IS NOT NULL THEN 'receipt_attached' END, d.donor_id,
+			 jsonb_build_object('receipt_url', d.receipt_url, 'backfilled', true), d.created_at),
+			(NULLIF(d.status, 'pending'), d.confirmed_by, jsonb_build_object('backfilled', true), COALESCE(d.confirmed_at, d.created_at))
+		) AS e(event_type, actor_id, metadata, created_at)
+		WHERE e.event_type IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM donation_events de WHERE de.donation_id = d.id)`,
+
+		// Соавторы постов: приглашение, ответ и права (edit, chats, donations)
+		`CREATE TABLE IF NOT EXISTS post_collaborators (
+			id BIGSERIAL PRIMARY KEY,
+			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			invited_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			permissions TEXT[] NOT NULL DEFAULT '{}',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			responded_at TIMESTAMPTZ,
+			UNIQUE (post_id, user_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_post_collaborators_user_id ON post_collaborators(user_id, status)`,
+
+		// Публичные комментарии к постам
+		`CREATE TABLE IF NOT EXISTS post_comments (
+			id BIGSERIAL PRIMARY KEY,
+			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			text VARCHAR(2000) NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_post_comments_post_id ON post_comments(post_id, created_at DESC)`,
+
+		// Вопросы к автору поста: без ответа видны только автору, с ответом публичны
+		`CREATE TABLE IF NOT EXISTS post_questions (
+			id BIGSERIAL PRIMARY KEY,
+			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			question VARCHAR(1000) NOT NULL,
+			answer VARCHAR(4000),
+			answered_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			answered_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			CHECK ((answer IS NULL) = (answered_at IS NULL))
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_post_questions_answered ON post_questions(post_id, answered_at DESC) WHERE answer IS NOT NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_post_questions_unanswered ON post_questions(post_id, created_at) WHERE answer IS NULL`,
+
+		// Избранные посты пользователей
+		`CREATE TABLE IF NOT EXISTS favorites (
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, post_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_favorites_post_id ON favorites(post_id)`,
+
+		// Посты и авторы, скрытые пользователем из ленты
+		`CREATE TABLE IF NOT EXISTS hidden_posts (
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, post_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_hidden_posts_post_id ON hidden_posts(post_id)`,
+		`CREATE TABLE IF NOT EXISTS muted_authors (
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			author_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, author_id),
+			CHECK (user_id <> author_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_muted_authors_author_id ON muted_authors(author_id)`,
+
+		// Срочные сборы: рассматриваются вне очереди, после публикации до urgent_until показываются первыми
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS urgent BOOLEAN NOT NULL DEFAULT false`,
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS urgent_until TIMESTAMPTZ`,
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS urgent_document_url VARCHAR(500)`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_urgent ON posts(created_at) WHERE urgent`,
+
+		// Срок сбора, после которого активный пост закрывается
+		`ALTER TABLE posts ADD COLUMN IF NOT EXISTS deadline TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_deadline ON posts(deadline) WHERE deadline IS NOT NULL`,
+
+		// Индексы под порядки ленты postSortOrders
+		`CREATE INDEX IF NOT EXISTS idx_posts_collected ON posts(collected DESC, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_goal_progress ON posts((collected >= amount), (collected / amount) DESC, created_at DESC)`,
+
+		// Выписки банка для сверки пожертвований: каждый входящий перевод и предложенное пожертвование
+		`CREATE TABLE IF NOT EXISTS statement_imports (
+			id BIGSERIAL PRIMARY KEY,
+			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			uploaded_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			file_name VARCHAR(255) NOT NULL,
+			rows_skipped INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_statement_imports_post_id ON statement_imports(post_id, created_at DESC)`,
+		`CREATE TABLE IF NOT EXISTS statement_rows (
+			id BIGSERIAL PRIMARY KEY,
+			import_id BIGINT NOT NULL REFERENCES statement_imports(id) ON DELETE CASCADE,
+			row_number INTEGER NOT NULL,
+			tx_date TIMESTAMPTZ NOT NULL,
+			amount DECIMAL(15,2) NOT NULL,
+			payer TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			donation_id BIGINT,
+			confidence NUMERIC(3,2) NOT NULL DEFAULT 0,
+			reasons TEXT[] NOT NULL DEFAULT '{}',
+			status VARCHAR(20) NOT NULL CHECK (status IN ('unmatched', 'proposed', 'confirmed', 'skipped')),
+			reviewed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			reviewed_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_statement_rows_import_id ON statement_rows(import_id, row_number)`,
+
+		// Оплата пожертвований через платежный шлюз. donations секционирована, поэтому без внешнего ключа.
+		// Незавершенный платеж у пожертвования один: повторный запрос оплаты возвращает его.
+		`CREATE TABLE IF NOT EXISTS payments (
+			id BIGSERIAL PRIMARY KEY,
+			donation_id BIGINT NOT NULL,
+			provider VARCHAR(20) NOT NULL,
+			provider_payment_id VARCHAR(100),
+			amount DECIMAL(15,2) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'new' CHECK (status IN ('new', 'pending', 'succeeded', 'canceled')),
+			confirmation_url TEXT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (provider, provider_payment_id)
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_open ON payments(donation_id) WHERE status IN ('new', 'pending')`,
+		`CREATE INDEX IF NOT EXISTS idx_payments_donation_id ON payments(donation_id)`,
 
 		// Таблица chats
 		`CREATE TABLE IF NOT EXISTS chats (
@@ -130,30 +639,54 @@ func (db *DB) InitSchema() error {
 			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
 			helper_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 			needy_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-			created_at TIMESTAMP DEFAULT NOW(),
-			updated_at TIMESTAMP DEFAULT NOW(),
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
 			UNIQUE(post_id, helper_id)
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_chats_post_id ON chats(post_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_chats_helper_id ON chats(helper_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_chats_needy_id ON chats(needy_id)`,
+		// Автоответ отсутствующего участника отправляется в чат один раз
+		`ALTER TABLE chats ADD COLUMN IF NOT EXISTS away_replied BOOLEAN DEFAULT false`,
+
+		// Таблица author_response_stats: медиана времени первого ответа автора поста в чатах
+		`CREATE TABLE IF NOT EXISTS author_response_stats (
+			user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			median_seconds INTEGER NOT NULL,
+			sample_size INTEGER NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
 
-		// Таблица messages
+		// Таблица messages (секционирована по месяцам, см. partitions.go)
 		`CREATE TABLE IF NOT EXISTS messages (
-			id BIGSERIAL PRIMARY KEY,
+			id BIGSERIAL,
 			chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
 			sender_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 			text TEXT,
 			attachment_url VARCHAR(500),
 			is_read BOOLEAN DEFAULT false,
 			is_edited BOOLEAN DEFAULT false,
-			created_at TIMESTAMP DEFAULT NOW(),
-			updated_at TIMESTAMP DEFAULT NOW(),
-			CHECK (text IS NOT NULL OR attachment_url IS NOT NULL)
-		)`,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			CHECK (text IS NOT NULL OR attachment_url IS NOT NULL),
+			PRIMARY KEY (id, created_at)
+		) PARTITION BY RANGE (created_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_chat_id ON messages(chat_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_sender_id ON messages(sender_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(chat_id, created_at DESC)`,
+		// Курсорная пагинация сообщений по id
+		`CREATE INDEX IF NOT EXISTS idx_messages_chat_cursor ON messages(chat_id, id)`,
+		// Подсчет непрочитанных для списка чатов
+		`CREATE INDEX IF NOT EXISTS idx_messages_unread ON messages(chat_id) WHERE is_read = false`,
+
+		// Таблица chat_drafts: неотправленный текст сообщения, общий для всех устройств пользователя
+		`CREATE TABLE IF NOT EXISTS chat_drafts (
+			chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			text TEXT NOT NULL,
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (chat_id, user_id)
+		)`,
 
 		// Таблица ratings
 		`CREATE TABLE IF NOT EXISTS ratings (
@@ -162,11 +695,319 @@ func (db *DB) InitSchema() error {
 			points INTEGER DEFAULT 0 CHECK (points >= 0),
 			total_donated DECIMAL(15,2) DEFAULT 0 CHECK (total_donated >= 0),
 			status VARCHAR(50),
-			updated_at TIMESTAMP DEFAULT NOW()
+			updated_at TIMESTAMPTZ DEFAULT NOW()
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_ratings_user_id ON ratings(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_ratings_points ON ratings(points DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_ratings_total_donated ON ratings(total_donated DESC)`,
+		// Журнал баллов рейтинга: points и total_donated в ratings - суммы по журналу.
+		// Записи не изменяются и удаляются только вместе с пользователем.
+		`CREATE TABLE IF NOT EXISTS rating_events (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			source VARCHAR(30) NOT NULL CHECK (source IN ('donation', 'donation_reversal', 'referral', 'referral_reversal', 'opening_balance')),
+			donation_id BIGINT,
+			referral_id BIGINT,
+			points_delta INTEGER NOT NULL,
+			amount_delta DECIMAL(15,2) NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rating_events_user_id ON rating_events(user_id, id)`,
+		`ALTER TABLE rating_events DROP CONSTRAINT IF EXISTS rating_events_source_check`,
+		`ALTER TABLE rating_events ADD CONSTRAINT rating_events_source_check
+			CHECK (source IN ('donation', 'donation_reversal', 'referral', 'referral_reversal', 'opening_balance'))`,
+		`CREATE OR REPLACE FUNCTION rating_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'rating_events is append-only';
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE TRIGGER rating_events_append_only BEFORE UPDATE ON rating_events
+			FOR EACH ROW EXECUTE FUNCTION rating_events_append_only()`,
+		// Баллы, накопленные до появления журнала, переносятся в него одной записью
+		`INSERT INTO rating_events (user_id, source, points_delta, amount_delta, created_at)
+		SELECT r.user_id, 'opening_balance', r.points, r.total_donated, COALESCE(r.updated_at, NOW())
+		FROM ratings r
+		WHERE (r.points <> 0 OR r.total_donated <> 0)
+		  AND NOT EXISTS (SELECT 1 FROM rating_events e WHERE e.user_id = r.user_id)`,
+
+		// Таблица refresh_tokens
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			revoked_at TIMESTAMPTZ,
+			replaced_by BIGINT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+			user_agent VARCHAR(500),
+			ip_address VARCHAR(64)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id)`,
+		`UPDATE users u SET last_active_at = COALESCE((SELECT MAX(r.created_at) FROM refresh_tokens r WHERE r.user_id = u.id), u.created_at)
+		 WHERE u.last_active_at IS NULL`,
+
+		// Таблица revoked_tokens (отозванные access токены до истечения срока)
+		`CREATE TABLE IF NOT EXISTS revoked_tokens (
+			jti VARCHAR(64) PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at TIMESTAMPTZ NOT NULL,
+			revoked_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at)`,
+
+		// Таблица login_events (история входов для модерации)
+		`CREATE TABLE IF NOT EXISTS login_events (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			success BOOLEAN NOT NULL,
+			ip_address VARCHAR(45),
+			user_agent TEXT,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_login_events_user_id ON login_events(user_id, created_at DESC)`,
+
+		// Таблица admin_actions (журнал действий администраторов и модераторов с причинами)
+		`CREATE TABLE IF NOT EXISTS admin_actions (
+			id BIGSERIAL PRIMARY KEY,
+			actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			action VARCHAR(50) NOT NULL,
+			target_type VARCHAR(20) NOT NULL,
+			target_id BIGINT,
+			target_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_admin_actions_target_user ON admin_actions(target_user_id, created_at DESC)`,
+		// Журнал аудита: объекты без числового ID (роли, уровни доверия) задаются именем,
+		// значения до и после изменения хранятся в JSON
+		`ALTER TABLE admin_actions ALTER COLUMN target_id DROP NOT NULL`,
+		`ALTER TABLE admin_actions ADD COLUMN IF NOT EXISTS target_name VARCHAR(100)`,
+		`ALTER TABLE admin_actions ADD COLUMN IF NOT EXISTS old_value JSONB`,
+		`ALTER TABLE admin_actions ADD COLUMN IF NOT EXISTS new_value JSONB`,
+		// Записи журнала сохраняются после удаления пользователя
+		`DO $$ BEGIN
+			IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'admin_actions_target_user_id_fkey' AND confdeltype = 'c') THEN
+				ALTER TABLE admin_actions DROP CONSTRAINT admin_actions_target_user_id_fkey;
+				ALTER TABLE admin_actions ADD CONSTRAINT admin_actions_target_user_id_fkey
+					FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE SET NULL;
+			END IF;
+		END $$`,
+		`CREATE INDEX IF NOT EXISTS idx_admin_actions_created_at ON admin_actions(created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_admin_actions_actor ON admin_actions(actor_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_admin_actions_action ON admin_actions(action, created_at DESC)`,
+
+		// Таблица user_identities (учетные записи VK ID, Яндекс, Google, привязанные к аккаунту)
+		`CREATE TABLE IF NOT EXISTS user_identities (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider VARCHAR(20) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			email VARCHAR(255),
+			name VARCHAR(255),
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			UNIQUE(provider, subject),
+			UNIQUE(user_id, provider)
+		)`,
+
+		// Таблица oauth_states (одноразовые state и PKCE verifier незавершенных входов)
+		`CREATE TABLE IF NOT EXISTS oauth_states (
+			state VARCHAR(64) PRIMARY KEY,
+			provider VARCHAR(20) NOT NULL,
+			user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+			code_verifier VARCHAR(128) NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+
+		// Таблица otp_codes (одноразовые коды подтверждения по SMS)
+		`CREATE TABLE IF NOT EXISTS otp_codes (
+			id BIGSERIAL PRIMARY KEY,
+			phone VARCHAR(20) NOT NULL,
+			purpose VARCHAR(30) NOT NULL,
+			code_hash VARCHAR(64) NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			attempts INT DEFAULT 0,
+			consumed_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_otp_codes_phone_purpose ON otp_codes(phone, purpose)`,
+
+		// Таблица notifications
+		`CREATE TABLE IF NOT EXISTS notifications (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			type VARCHAR(50) NOT NULL,
+			title VARCHAR(200) NOT NULL,
+			body TEXT NOT NULL,
+			post_id BIGINT REFERENCES posts(id) ON DELETE CASCADE,
+			is_read BOOLEAN DEFAULT false,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, created_at DESC)`,
+		// Каналы доставки уведомлений; для типов без строки действует DefaultNotificationPreference
+		`CREATE TABLE IF NOT EXISTS notification_preferences (
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			type VARCHAR(50) NOT NULL,
+			push BOOLEAN NOT NULL,
+			sms BOOLEAN NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, type)
+		)`,
+
+		// Таблица payment_note_templates (шаблоны назначения платежа для ручных переводов)
+		`CREATE TABLE IF NOT EXISTS payment_note_templates (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(50) NOT NULL,
+			text VARCHAR(210) NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			UNIQUE(user_id, name)
+		)`,
+
+		// Таблица subscriptions (регулярные пожертвования). Пожертвования партиционированы,
+		// поэтому last_donation_id без внешнего ключа.
+		`CREATE TABLE IF NOT EXISTS subscriptions (
+			id BIGSERIAL PRIMARY KEY,
+			post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+			donor_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+			period VARCHAR(10) NOT NULL CHECK (period IN ('weekly', 'monthly')),
+			is_anonymous BOOLEAN NOT NULL DEFAULT false,
+			is_active BOOLEAN NOT NULL DEFAULT true,
+			next_run_at TIMESTAMPTZ NOT NULL,
+			last_run_at TIMESTAMPTZ,
+			last_donation_id BIGINT,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			UNIQUE(donor_id, post_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_subscriptions_due ON subscriptions(next_run_at) WHERE is_active = true`,
+
+		// Таблица user_integrations (личный webhook или Telegram чат автора)
+		`CREATE TABLE IF NOT EXISTS user_integrations (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			type VARCHAR(20) NOT NULL,
+			target VARCHAR(500) NOT NULL,
+			secret VARCHAR(64) NOT NULL,
+			is_active BOOLEAN DEFAULT true,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			UNIQUE(user_id, type)
+		)`,
+
+		// Таблица webhooks (вебхуки партнеров, подписанные на события платформы)
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			url VARCHAR(500) NOT NULL,
+			secret VARCHAR(64) NOT NULL,
+			events TEXT[] NOT NULL,
+			is_active BOOLEAN NOT NULL DEFAULT true,
+			created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+
+		// Таблица webhook_deliveries (очередь доставки событий с повторами)
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			integration_id BIGINT NOT NULL REFERENCES user_integrations(id) ON DELETE CASCADE,
+			event VARCHAR(50) NOT NULL,
+			payload JSONB NOT NULL,
+			summary TEXT NOT NULL,
+			status VARCHAR(20) DEFAULT 'pending',
+			attempts INT DEFAULT 0,
+			last_error TEXT,
+			next_attempt_at TIMESTAMPTZ DEFAULT NOW(),
+			delivered_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending'`,
+		// Событие адресовано интеграции пользователя или вебхуку партнера
+		`ALTER TABLE webhook_deliveries ALTER COLUMN integration_id DROP NOT NULL`,
+		`ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS webhook_id BIGINT REFERENCES webhooks(id) ON DELETE CASCADE`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC) WHERE webhook_id IS NOT NULL`,
+
+		// Таблица dead_letters (фоновые задания, которые не удалось выполнить)
+		`CREATE TABLE IF NOT EXISTS dead_letters (
+			id BIGSERIAL PRIMARY KEY,
+			kind VARCHAR(20) NOT NULL,
+			reference_id BIGINT,
+			payload JSONB NOT NULL,
+			error TEXT NOT NULL,
+			attempts INT NOT NULL DEFAULT 1,
+			status VARCHAR(20) NOT NULL DEFAULT 'failed',
+			retries INT NOT NULL DEFAULT 0,
+			next_retry_at TIMESTAMPTZ,
+			last_retried_at TIMESTAMPTZ,
+			last_retried_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		// Одно задание на событие webhook_deliveries: повторный сбой обновляет его
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_dead_letters_reference ON dead_letters(kind, reference_id) WHERE reference_id IS NOT NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_dead_letters_status ON dead_letters(status, created_at DESC)`,
+
+		// Еженедельные сводки для администраторов, одна на неделю
+		`CREATE TABLE IF NOT EXISTS digests (
+			id BIGSERIAL PRIMARY KEY,
+			period_start TIMESTAMPTZ UNIQUE NOT NULL,
+			period_end TIMESTAMPTZ NOT NULL,
+			report JSONB NOT NULL,
+			text TEXT NOT NULL,
+			deliveries JSONB NOT NULL DEFAULT '[]',
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+
+		// Таблица idempotency_keys (ответы на запросы с Idempotency-Key).
+		// status_code NULL, пока запрос выполняется.
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			key VARCHAR(255) NOT NULL,
+			request_hash VARCHAR(64) NOT NULL,
+			status_code INT,
+			content_type VARCHAR(255) NOT NULL DEFAULT '',
+			response_body BYTEA,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			completed_at TIMESTAMPTZ,
+			PRIMARY KEY (user_id, key)
+		)`,
+
+		// Таблица devices (FCM токены устройств для push уведомлений)
+		`CREATE TABLE IF NOT EXISTS devices (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token VARCHAR(512) UNIQUE NOT NULL,
+			platform VARCHAR(20) NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			last_seen_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_devices_user_id ON devices(user_id)`,
+		// Все пользователи, регистрировавшие устройство (в devices остается только последний).
+		// Нужна для защиты реферальной программы от приглашения самого себя.
+		`CREATE TABLE IF NOT EXISTS device_users (
+			token_hash VARCHAR(64) NOT NULL,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			last_seen_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (token_hash, user_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_device_users_user_id ON device_users(user_id)`,
+
+		// Таблица api_tokens (персональные токены для ботов и автоматизации, хранится только хеш)
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			token_prefix VARCHAR(16) NOT NULL,
+			scopes TEXT[] NOT NULL,
+			expires_at TIMESTAMPTZ,
+			last_used_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id)`,
 
 		// Старая таблица files (оставляем для совместимости)
 		`CREATE TABLE IF NOT EXISTS files (
@@ -175,30 +1016,74 @@ func (db *DB) InitSchema() error {
 		content_type VARCHAR(100),
 		size BIGINT,
 		minio_object_name VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		)`,
 	}
 
+	// Создание индексов на больших таблицах может идти дольше statement_timeout
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SET statement_timeout = 0`); err != nil {
+		return fmt.Errorf("failed to disable statement timeout: %w", err)
+	}
 	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
 			return fmt.Errorf("failed to execute schema query: %w", err)
 		}
 	}
+	if _, err := conn.ExecContext(ctx, `RESET statement_timeout`); err != nil {
+		return fmt.Errorf("failed to reset statement timeout: %w", err)
+	}
 
-	return nil
+	if err := db.grantAdminPermissions(); err != nil {
+		return err
+	}
+
+	if err := db.encryptStoredVerificationPII(); err != nil {
+		return fmt.Errorf("failed to encrypt verification data: %w", err)
+	}
+
+	return db.EnsurePartitions(partitionMonthsAhead)
 }
 
 // ========== User functions ==========
 
-// CreateUser создает нового пользователя
-func (db *DB) CreateUser(phone, passwordHash, firstName, lastName string) (*User, error) {
+// CreateUser создает нового пользователя. Непустой inviteCode расходует одно использование
+// кода приглашения в той же транзакции: если пользователь не создан, использование не учитывается.
+func (db *DB) CreateUser(phone, passwordHash, firstName, lastName, inviteCode string) (*User, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var inviteCodeID *int64
+	if inviteCode != "" {
+		var id int64
+		err := tx.QueryRow(`UPDATE invite_codes SET uses = uses + 1
+		                    WHERE code = $1 AND uses < max_uses AND (expires_at IS NULL OR expires_at > NOW())
+		                    RETURNING id`, inviteCode).Scan(&id)
+		if err == sql.ErrNoRows {
+			return nil, NewValidationError("Код приглашения недействителен или уже использован", map[string]interface{}{"field": "invite_code"})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to use invite code: %w", err)
+		}
+		inviteCodeID = &id
+	}
+
 	var user User
-	query := `INSERT INTO users (phone, password_hash, first_name, last_name) 
-	          VALUES ($1, $2, $3, $4) 
-	          RETURNING id, phone, first_name, last_name, photo_url, role, helper_name, created_at, updated_at, is_active`
-	err := db.QueryRow(query, phone, passwordHash, firstName, lastName).Scan(
+	query := `INSERT INTO users (phone, password_hash, first_name, last_name, invite_code_id) 
+	          VALUES ($1, $2, $3, $4, $5) 
+	          RETURNING id, phone, first_name, last_name, photo_url, role, helper_name, created_at, updated_at, is_active, phone_verified`
+	err = tx.QueryRow(query, phone, passwordHash, firstName, lastName, inviteCodeID).Scan(
 		&user.ID, &user.Phone, &user.FirstName, &user.LastName,
-		&user.PhotoURL, &user.Role, &user.HelperName, &user.CreatedAt, &user.UpdatedAt, &user.IsActive,
+		&user.PhotoURL, &user.Role, &user.HelperName, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.PhoneVerified,
 	)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
@@ -206,17 +1091,24 @@ func (db *DB) CreateUser(phone, passwordHash, firstName, lastName string) (*User
 		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
 // GetUserByPhone получает пользователя по телефону
 func (db *DB) GetUserByPhone(phone string) (*User, error) {
 	var user User
-	query := `SELECT id, phone, password_hash, first_name, last_name, photo_url, role, helper_name, created_at, updated_at, is_active 
+	var awayMessage *string
+	var awayUntil *time.Time
+	query := `SELECT id, phone, password_hash, first_name, last_name, photo_url, role, helper_name, created_at, updated_at, is_active, phone_verified,
+	                 photo_variants, ` + awayColumns("users") + `
 	          FROM users WHERE phone = $1`
 	err := db.QueryRow(query, phone).Scan(
 		&user.ID, &user.Phone, &user.PasswordHash, &user.FirstName, &user.LastName,
-		&user.PhotoURL, &user.Role, &user.HelperName, &user.CreatedAt, &user.UpdatedAt, &user.IsActive,
+		&user.PhotoURL, &user.Role, &user.HelperName, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.PhoneVerified,
+		&user.PhotoVariants, &awayMessage, &awayUntil,
 	)
 	if err == sql.ErrNoRows {
 		return nil, NewNotFoundError("Пользователь")
@@ -224,17 +1116,22 @@ func (db *DB) GetUserByPhone(phone string) (*User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	user.Away = newAwayStatus(awayMessage, awayUntil)
 	return &user, nil
 }
 
 // GetUserByID получает пользователя по ID
 func (db *DB) GetUserByID(id int64) (*User, error) {
 	var user User
-	query := `SELECT id, phone, password_hash, first_name, last_name, photo_url, role, helper_name, created_at, updated_at, is_active 
+	var awayMessage *string
+	var awayUntil *time.Time
+	query := `SELECT id, phone, password_hash, first_name, last_name, photo_url, role, helper_name, created_at, updated_at, is_active, phone_verified,
+	                 photo_variants, ` + awayColumns("users") + `, timezone
 	          FROM users WHERE id = $1`
 	err := db.QueryRow(query, id).Scan(
 		&user.ID, &user.Phone, &user.PasswordHash, &user.FirstName, &user.LastName,
-		&user.PhotoURL, &user.Role, &user.HelperName, &user.CreatedAt, &user.UpdatedAt, &user.IsActive,
+		&user.PhotoURL, &user.Role, &user.HelperName, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.PhoneVerified,
+		&user.PhotoVariants, &awayMessage, &awayUntil, &user.Timezone,
 	)
 	if err == sql.ErrNoRows {
 		return nil, NewNotFoundError("Пользователь")
@@ -242,11 +1139,12 @@ func (db *DB) GetUserByID(id int64) (*User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	user.Away = newAwayStatus(awayMessage, awayUntil)
 	return &user, nil
 }
 
 // UpdateUser обновляет данные пользователя
-func (db *DB) UpdateUser(id int64, firstName, lastName *string, helperName *string, photoURL *string) error {
+func (db *DB) UpdateUser(id int64, firstName, lastName *string, helperName *string, photoURL *string, timezone *string) error {
 	updates := []string{}
 	args := []interface{}{}
 	argPos := 1
@@ -271,6 +1169,11 @@ func (db *DB) UpdateUser(id int64, firstName, lastName *string, helperName *stri
 		args = append(args, *photoURL)
 		argPos++
 	}
+	if timezone != nil {
+		updates = append(updates, fmt.Sprintf("timezone = $%d", argPos))
+		args = append(args, *timezone)
+		argPos++
+	}
 
 	if len(updates) == 0 {
 		return nil
@@ -293,413 +1196,4717 @@ func (db *DB) UpdateUserPassword(id int64, passwordHash string) error {
 	return err
 }
 
-// ========== Verification functions ==========
+// UpdateUserPhotoVariants сохраняет уменьшенные копии фото пользователя
+func (db *DB) UpdateUserPhotoVariants(id int64, variants *ImageVariants) error {
+	query := `UPDATE users SET photo_variants = $1, updated_at = NOW() WHERE id = $2`
+	_, err := db.Exec(query, variants, id)
+	return err
+}
 
-// CreateVerification создает заявку на верификацию
-func (db *DB) CreateVerification(v *Verification) error {
-	query := `INSERT INTO verifications 
-	          (user_id, user_photo_url, last_name, first_name, middle_name, birth_date, 
-	           passport_series, passport_number, passport_issuer, passport_date, 
-	           doc_type, inn, snils, passport_scans_urls, consent1, consent2, consent3)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
-	          RETURNING id, status, submitted_at`
-	var scansArray pq.StringArray
-	if len(v.PassportScansURLs) > 0 {
-		scansArray = pq.StringArray(v.PassportScansURLs)
+// SetUserAway включает режим "нет на месте". Пустой message выключает его.
+func (db *DB) SetUserAway(id int64, message *string, until *time.Time) error {
+	query := `UPDATE users SET away_message = $1, away_until = $2, updated_at = NOW() WHERE id = $3`
+	_, err := db.Exec(query, message, until, id)
+	return err
+}
+
+// awayColumns выбирает текст автоответа, если режим "нет на месте" еще действует, и время его окончания
+func awayColumns(alias string) string {
+	return fmt.Sprintf("CASE WHEN %[1]s.away_until IS NULL OR %[1]s.away_until > NOW() THEN %[1]s.away_message END, %[1]s.away_until", alias)
+}
+
+func newAwayStatus(message *string, until *time.Time) *AwayStatus {
+	if message == nil {
+		return nil
 	}
-	err := db.QueryRow(query,
-		v.UserID, v.UserPhotoURL, v.LastName, v.FirstName, v.MiddleName, v.BirthDate,
-		v.PassportSeries, v.PassportNumber, v.PassportIssuer, v.PassportDate,
-		v.DocType, v.INN, v.SNILS, scansArray, v.Consent1, v.Consent2, v.Consent3,
-	).Scan(&v.ID, &v.Status, &v.SubmittedAt)
+	return &AwayStatus{Message: *message, Until: until}
+}
+
+// SetUserPhoneVerified отмечает телефон пользователя как подтвержденный
+func (db *DB) SetUserPhoneVerified(id int64) error {
+	query := `UPDATE users SET phone_verified = true, updated_at = NOW() WHERE id = $1`
+	_, err := db.Exec(query, id)
 	return err
 }
 
-// GetVerificationByUserID получает верификацию по user_id
-func (db *DB) GetVerificationByUserID(userID int64) (*Verification, error) {
-	var v Verification
-	var scansArray pq.StringArray
-	query := `SELECT id, user_id, user_photo_url, last_name, first_name, middle_name, birth_date,
-	                 passport_series, passport_number, passport_issuer, passport_date,
-	                 doc_type, inn, snils, passport_scans_urls, consent1, consent2, consent3,
-	                 status, submitted_at, reviewed_at, reviewed_by, rejection_reason
-	          FROM verifications WHERE user_id = $1`
-	err := db.QueryRow(query, userID).Scan(
-		&v.ID, &v.UserID, &v.UserPhotoURL, &v.LastName, &v.FirstName, &v.MiddleName, &v.BirthDate,
-		&v.PassportSeries, &v.PassportNumber, &v.PassportIssuer, &v.PassportDate,
-		&v.DocType, &v.INN, &v.SNILS, &scansArray, &v.Consent1, &v.Consent2, &v.Consent3,
-		&v.Status, &v.SubmittedAt, &v.ReviewedAt, &v.ReviewedBy, &v.RejectionReason,
+// ========== Refresh token functions ==========
+
+// CreateRefreshToken сохраняет новый refresh токен
+func (db *DB) CreateRefreshToken(userID int64, tokenHash string, expiresAt time.Time, userAgent, ipAddress *string) (*RefreshToken, error) {
+	var rt RefreshToken
+	query := `WITH active AS (UPDATE users SET last_active_at = NOW() WHERE id = $1)
+	          INSERT INTO refresh_tokens (user_id, token_hash, expires_at, user_agent, ip_address)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING id, user_id, token_hash, expires_at, created_at, revoked_at, replaced_by, user_agent, ip_address`
+	err := db.QueryRow(query, userID, tokenHash, expiresAt, userAgent, ipAddress).Scan(
+		&rt.ID, &rt.UserID, &rt.TokenHash, &rt.ExpiresAt, &rt.CreatedAt,
+		&rt.RevokedAt, &rt.ReplacedBy, &rt.UserAgent, &rt.IPAddress,
 	)
-	if err == sql.ErrNoRows {
-		return nil, NewNotFoundError("Верификация")
-	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return &rt, nil
+}
+
+// GetRefreshTokenByHash получает refresh токен по хешу
+func (db *DB) GetRefreshTokenByHash(tokenHash string) (*RefreshToken, error) {
+	var rt RefreshToken
+	query := `SELECT id, user_id, token_hash, expires_at, created_at, revoked_at, replaced_by, user_agent, ip_address
+	          FROM refresh_tokens WHERE token_hash = $1`
+	err := db.QueryRow(query, tokenHash).Scan(
+		&rt.ID, &rt.UserID, &rt.TokenHash, &rt.ExpiresAt, &rt.CreatedAt,
+		&rt.RevokedAt, &rt.ReplacedBy, &rt.UserAgent, &rt.IPAddress,
+	)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Refresh токен")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return &rt, nil
+}
+
+// RotateRefreshToken отзывает старый refresh токен и сохраняет новый вместо него.
+// Возвращает false, если старый токен уже был отозван параллельным запросом.
+func (db *DB) RotateRefreshToken(oldID int64, newTokenHash string, expiresAt time.Time, userAgent, ipAddress *string) (bool, error) {
+	rotated := false
+	err := db.WithTx(func(tx *sql.Tx) error {
+		var userID int64
+		query := `UPDATE refresh_tokens SET revoked_at = NOW()
+		          WHERE id = $1 AND revoked_at IS NULL
+		          RETURNING user_id`
+		err := tx.QueryRow(query, oldID).Scan(&userID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to revoke refresh token: %w", err)
+		}
+
+		var newID int64
+		query = `INSERT INTO refresh_tokens (user_id, token_hash, expires_at, user_agent, ip_address)
+		         VALUES ($1, $2, $3, $4, $5) RETURNING id`
+		if err := tx.QueryRow(query, userID, newTokenHash, expiresAt, userAgent, ipAddress).Scan(&newID); err != nil {
+			return fmt.Errorf("failed to create refresh token: %w", err)
+		}
+
+		if _, err := tx.Exec(`UPDATE refresh_tokens SET replaced_by = $1 WHERE id = $2`, newID, oldID); err != nil {
+			return fmt.Errorf("failed to link refresh tokens: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE users SET last_active_at = NOW() WHERE id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to update user activity: %w", err)
+		}
+		rotated = true
+		return nil
+	})
+	return rotated, err
+}
+
+// RevokeUserRefreshTokens отзывает все активные refresh токены пользователя
+func (db *DB) RevokeUserRefreshTokens(userID int64) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := db.Exec(query, userID)
+	return err
+}
+
+// RevokeUserTokens завершает все сессии пользователя: отзывает refresh токены и делает
+// недействительными уже выданные access токены. Время обрезается до секунд, как iat в JWT,
+// поэтому токен, выпущенный в ту же секунду, что и отзыв, остается действительным.
+func (db *DB) RevokeUserTokens(userID int64) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`UPDATE users SET tokens_valid_after = date_trunc('second', NOW()) WHERE id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to invalidate access tokens: %w", err)
+		}
+		query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+		if _, err := tx.Exec(query, userID); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		return nil
+	})
+}
+
+// RevokeRefreshToken отзывает refresh токен пользователя
+func (db *DB) RevokeRefreshToken(userID int64, tokenHash string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW()
+	          WHERE user_id = $1 AND token_hash = $2 AND revoked_at IS NULL`
+	_, err := db.Exec(query, userID, tokenHash)
+	return err
+}
+
+// GetActiveRefreshTokens получает действующие сессии пользователя
+func (db *DB) GetActiveRefreshTokens(userID int64) ([]RefreshToken, error) {
+	query := `SELECT id, user_id, token_hash, expires_at, created_at, revoked_at, replaced_by, user_agent, ip_address
+	          FROM refresh_tokens WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	          ORDER BY created_at DESC`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []RefreshToken
+	for rows.Next() {
+		var rt RefreshToken
+		err := rows.Scan(&rt.ID, &rt.UserID, &rt.TokenHash, &rt.ExpiresAt, &rt.CreatedAt,
+			&rt.RevokedAt, &rt.ReplacedBy, &rt.UserAgent, &rt.IPAddress)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, rt)
+	}
+	return tokens, rows.Err()
+}
+
+// ========== Login history functions ==========
+
+// RecordLoginEvent сохраняет попытку входа пользователя
+func (db *DB) RecordLoginEvent(userID int64, success bool, ipAddress, userAgent *string) error {
+	query := `INSERT INTO login_events (user_id, success, ip_address, user_agent) VALUES ($1, $2, $3, $4)`
+	_, err := db.Exec(query, userID, success, ipAddress, userAgent)
+	return err
+}
+
+// GetLoginHistory получает последние попытки входа пользователя
+func (db *DB) GetLoginHistory(userID int64, limit int) ([]LoginEvent, error) {
+	query := `SELECT id, user_id, success, ip_address, user_agent, created_at
+	          FROM login_events WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+	rows, err := db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []LoginEvent
+	for rows.Next() {
+		var e LoginEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Success, &e.IPAddress, &e.UserAgent, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ========== Admin action functions ==========
+
+// RecordAdminAction сохраняет действие администратора в журнал
+func (db *DB) RecordAdminAction(rec AdminActionRecord) error {
+	oldValue, err := auditValue(rec.OldValue)
+	if err != nil {
+		return err
+	}
+	newValue, err := auditValue(rec.NewValue)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO admin_actions (actor_id, action, target_type, target_id, target_name, target_user_id, reason, old_value, new_value)
+	          VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, ''), NULLIF($6, 0), $7, $8, $9)`
+	_, err = db.Exec(query, rec.ActorID, rec.Action, rec.TargetType, rec.TargetID, rec.TargetName, rec.TargetUserID,
+		rec.Reason, oldValue, newValue)
+	return err
+}
+
+// auditValue сериализует значение для журнала, nil сохраняется как NULL
+func auditValue(value interface{}) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit value: %w", err)
+	}
+	s := string(data)
+	return &s, nil
+}
+
+const adminActionColumns = `id, actor_id, action, target_type, target_id, target_name, target_user_id, reason, old_value, new_value, created_at`
+
+func scanAdminActions(rows *sql.Rows) ([]AdminActionEntry, error) {
+	defer rows.Close()
+
+	actions := []AdminActionEntry{}
+	for rows.Next() {
+		var a AdminActionEntry
+		var oldValue, newValue []byte
+		if err := rows.Scan(&a.ID, &a.ActorID, &a.Action, &a.TargetType, &a.TargetID, &a.TargetName, &a.TargetUserID,
+			&a.Reason, &oldValue, &newValue, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if oldValue != nil {
+			a.OldValue = json.RawMessage(oldValue)
+		}
+		if newValue != nil {
+			a.NewValue = json.RawMessage(newValue)
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
+
+// GetAdminActions получает последние действия администраторов в отношении пользователя
+func (db *DB) GetAdminActions(targetUserID int64, limit int) ([]AdminActionEntry, error) {
+	query := `SELECT ` + adminActionColumns + `
+	          FROM admin_actions WHERE target_user_id = $1 ORDER BY created_at DESC LIMIT $2`
+	rows, err := db.Query(query, targetUserID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAdminActions(rows)
+}
+
+// GetAuditLog получает страницу журнала действий администраторов, новые первыми, и общее число записей
+func (db *DB) GetAuditLog(filter AuditLogFilter, page, limit int) ([]AdminActionEntry, int, error) {
+	where := "1=1"
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		where += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.ActorID != 0 {
+		add("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if filter.TargetUserID != 0 {
+		add("target_user_id = $%d", filter.TargetUserID)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM admin_actions WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM admin_actions WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+		adminActionColumns, where, len(args)+1, len(args)+2)
+	rows, err := db.Query(query, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	actions, err := scanAdminActions(rows)
+	return actions, total, err
+}
+
+// GetAdminUsers получает пользователей по фильтрам для администратора, новые первыми
+func (db *DB) GetAdminUsers(filter AdminUserFilter, page, limit int) ([]AdminUserListItem, int, error) {
+	where := "1=1"
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		where += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.Query != "" {
+		// Телефон ищется по цифрам без +, пробелов и скобок, имя - по подстроке в любом порядке
+		digits := strings.TrimPrefix(FormatPhone(filter.Query), "+")
+		if digits != "" && strings.Trim(digits, "0123456789") == "" {
+			add("u.phone LIKE $%d", "%"+digits+"%")
+		} else {
+			pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.Query) + "%"
+			add("(u.first_name || ' ' || u.last_name ILIKE $%[1]d OR u.last_name || ' ' || u.first_name ILIKE $%[1]d)", pattern)
+		}
+	}
+	if filter.Role != "" {
+		add("u.role = $%d", filter.Role)
+	}
+	if filter.VerificationStatus == "none" {
+		where += " AND v.id IS NULL"
+	} else if filter.VerificationStatus != "" {
+		add("v.status = $%d", filter.VerificationStatus)
+	}
+	if filter.IsActive != nil {
+		add("u.is_active = $%d", *filter.IsActive)
+	}
+
+	from := `FROM users u LEFT JOIN verifications v ON v.user_id = u.id WHERE ` + where
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) `+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`SELECT u.id, u.phone, u.first_name, u.last_name, u.role, u.is_active, u.phone_verified, v.status, u.created_at,
+	                             (SELECT MAX(created_at) FROM login_events WHERE user_id = u.id AND success)
+	                      %s ORDER BY u.created_at DESC, u.id DESC LIMIT $%d OFFSET $%d`, from, len(args)+1, len(args)+2)
+	rows, err := db.Query(query, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []AdminUserListItem{}
+	for rows.Next() {
+		var u AdminUserListItem
+		if err := rows.Scan(&u.ID, &u.Phone, &u.FirstName, &u.LastName, &u.Role, &u.IsActive, &u.PhoneVerified,
+			&u.VerificationStatus, &u.CreatedAt, &u.LastLoginAt); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// SetUserActive блокирует или разблокирует пользователя. Возвращает прежнее значение.
+func (db *DB) SetUserActive(userID int64, active bool) (bool, error) {
+	var wasActive bool
+	query := `UPDATE users u SET is_active = $1, updated_at = NOW()
+	          FROM users old WHERE u.id = $2 AND old.id = u.id
+	          RETURNING old.is_active`
+	err := db.QueryRow(query, active, userID).Scan(&wasActive)
+	if err == sql.ErrNoRows {
+		return false, NewNotFoundError("Пользователь")
+	}
+	return wasActive, err
+}
+
+// ========== Identity functions ==========
+
+// CreateOAuthState сохраняет state незавершенного входа через провайдера.
+// userID задан, если пользователь привязывает провайдера к своему аккаунту.
+func (db *DB) CreateOAuthState(state, provider string, userID *int64, codeVerifier string, expiresAt time.Time) error {
+	// Заодно удаляем state брошенных входов
+	if _, err := db.Exec(`DELETE FROM oauth_states WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("failed to clean up oauth states: %w", err)
+	}
+
+	query := `INSERT INTO oauth_states (state, provider, user_id, code_verifier, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := db.Exec(query, state, provider, userID, codeVerifier, expiresAt); err != nil {
+		return fmt.Errorf("failed to create oauth state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeOAuthState получает и удаляет state, чтобы его нельзя было использовать повторно
+func (db *DB) ConsumeOAuthState(state, provider string) (*OAuthState, error) {
+	var s OAuthState
+	query := `DELETE FROM oauth_states WHERE state = $1 AND provider = $2 AND expires_at > NOW()
+	          RETURNING state, provider, user_id, code_verifier, expires_at`
+	err := db.QueryRow(query, state, provider).Scan(&s.State, &s.Provider, &s.UserID, &s.CodeVerifier, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Состояние входа")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume oauth state: %w", err)
+	}
+	return &s, nil
+}
+
+// GetUserIdentity получает привязку по учетной записи провайдера
+func (db *DB) GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	var i UserIdentity
+	query := `SELECT id, user_id, provider, subject, email, name, created_at
+	          FROM user_identities WHERE provider = $1 AND subject = $2`
+	err := db.QueryRow(query, provider, subject).Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.Name, &i.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Привязанный аккаунт")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+	return &i, nil
+}
+
+// GetUserIdentities получает провайдеров, привязанных к аккаунту
+func (db *DB) GetUserIdentities(userID int64) ([]UserIdentity, error) {
+	query := `SELECT id, user_id, provider, subject, email, name, created_at
+	          FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []UserIdentity
+	for rows.Next() {
+		var i UserIdentity
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.Name, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+// LinkUserIdentity привязывает учетную запись провайдера к аккаунту.
+// Повторная привязка того же провайдера заменяет прежнюю учетную запись.
+func (db *DB) LinkUserIdentity(userID int64, provider string, user *OAuthUser) (*UserIdentity, error) {
+	var i UserIdentity
+	query := `INSERT INTO user_identities (user_id, provider, subject, email, name)
+	          VALUES ($1, $2, $3, $4, $5)
+	          ON CONFLICT (user_id, provider) DO UPDATE
+	          SET subject = EXCLUDED.subject, email = EXCLUDED.email, name = EXCLUDED.name, created_at = NOW()
+	          RETURNING id, user_id, provider, subject, email, name, created_at`
+	err := db.QueryRow(query, userID, provider, user.Subject, user.Email, user.Name).Scan(
+		&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.Name, &i.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link user identity: %w", err)
+	}
+	return &i, nil
+}
+
+// DeleteUserIdentity отвязывает провайдера от аккаунта. Возвращает false, если привязки не было.
+func (db *DB) DeleteUserIdentity(userID int64, provider string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userID, provider)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ========== Revoked token functions ==========
+
+// RevokeAccessToken добавляет access токен в список отозванных
+func (db *DB) RevokeAccessToken(jti string, userID int64, expiresAt time.Time) error {
+	query := `INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3)
+	          ON CONFLICT (jti) DO NOTHING`
+	if _, err := db.Exec(query, jti, userID, expiresAt); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	// Истекшие токены больше не нужно хранить
+	_, err := db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < NOW()`)
+	return err
+}
+
+// CheckAccessToken проверяет, отозван ли access токен и активен ли аккаунт его владельца,
+// и возвращает текущую роль пользователя. Пустой jti (API токены) на отзыв не проверяется.
+// Токен, выпущенный (issuedAt) раньше tokens_valid_after, считается отозванным; нулевой
+// issuedAt эту проверку пропускает. Удаленный пользователь считается неактивным.
+func (db *DB) CheckAccessToken(jti string, userID int64, issuedAt time.Time) (revoked, active bool, role string, err error) {
+	var issued interface{}
+	if !issuedAt.IsZero() {
+		issued = issuedAt
+	}
+	query := `SELECT ($1 <> '' AND EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1))
+	                 OR COALESCE($3::timestamptz < u.tokens_valid_after, false),
+	                 COALESCE(u.is_active, false), COALESCE(u.role, '')
+	          FROM (SELECT 1) one LEFT JOIN users u ON u.id = $2`
+	if err := db.QueryRow(query, jti, userID, issued).Scan(&revoked, &active, &role); err != nil {
+		return false, false, "", fmt.Errorf("failed to check access token: %w", err)
+	}
+	return revoked, active, role, nil
+}
+
+// ========== OTP functions ==========
+
+// CreateOTPCode сохраняет новый одноразовый код. Ранее выданные неиспользованные
+// коды для того же телефона и назначения удаляются.
+func (db *DB) CreateOTPCode(phone, purpose, codeHash string, expiresAt time.Time) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM otp_codes WHERE phone = $1 AND purpose = $2 AND consumed_at IS NULL`, phone, purpose); err != nil {
+			return fmt.Errorf("failed to delete old otp codes: %w", err)
+		}
+
+		query := `INSERT INTO otp_codes (phone, purpose, code_hash, expires_at) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(query, phone, purpose, codeHash, expiresAt); err != nil {
+			return fmt.Errorf("failed to create otp code: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetActiveOTPCode получает последний неиспользованный код для телефона и назначения
+func (db *DB) GetActiveOTPCode(phone, purpose string) (*OTPCode, error) {
+	var code OTPCode
+	query := `SELECT id, phone, purpose, code_hash, expires_at, attempts, consumed_at, created_at
+	          FROM otp_codes WHERE phone = $1 AND purpose = $2 AND consumed_at IS NULL
+	          ORDER BY created_at DESC LIMIT 1`
+	err := db.QueryRow(query, phone, purpose).Scan(
+		&code.ID, &code.Phone, &code.Purpose, &code.CodeHash, &code.ExpiresAt,
+		&code.Attempts, &code.ConsumedAt, &code.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Код подтверждения")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get otp code: %w", err)
+	}
+	return &code, nil
+}
+
+// ClaimOTPAttempt атомарно засчитывает попытку ввода кода и возвращает его хеш для сравнения.
+// Возвращает false, если попытки исчерпаны: параллельные запросы не могут проверить код
+// больше maxAttempts раз.
+func (db *DB) ClaimOTPAttempt(id int64, maxAttempts int) (string, bool, error) {
+	var codeHash string
+	query := `UPDATE otp_codes SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2 RETURNING code_hash`
+	err := db.QueryRow(query, id, maxAttempts).Scan(&codeHash)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to claim otp attempt: %w", err)
+	}
+	return codeHash, true, nil
+}
+
+// ConsumeOTPCode отмечает код использованным. Возвращает false, если код уже использован.
+func (db *DB) ConsumeOTPCode(id int64) (bool, error) {
+	result, err := db.Exec(`UPDATE otp_codes SET consumed_at = NOW() WHERE id = $1 AND consumed_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume otp code: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ========== Retention functions ==========
+
+// retentionStaff аккаунты администраторов и ролей с правами не обезличиваются и не удаляются
+const retentionStaff = `(u.role = '` + RoleAdmin + `' OR EXISTS (SELECT 1 FROM role_permissions rp WHERE rp.role = u.role))`
+
+// inactiveUsersWhere аккаунты без входа с $1, кроме авторов активных сборов
+const inactiveUsersWhere = `u.anonymized_at IS NULL AND COALESCE(u.last_active_at, u.created_at) < $1 AND NOT ` + retentionStaff + `
+	AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.user_id = u.id AND p.status = 'active')`
+
+// unverifiedUsersWhere регистрации без подтвержденного телефона и входа с $1. Удаляются
+// только аккаунты без постов, пожертвований и сообщений: они удалились бы каскадно.
+const unverifiedUsersWhere = `u.anonymized_at IS NULL AND NOT COALESCE(u.phone_verified, false)
+	AND COALESCE(u.last_active_at, u.created_at) < $1 AND NOT ` + retentionStaff + `
+	AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.user_id = u.id)
+	AND NOT EXISTS (SELECT 1 FROM donations d WHERE d.donor_id = u.id)
+	AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.sender_id = u.id)`
+
+// CountRetention считает записи, которые затронет применение сроков хранения.
+// Нулевая граница не учитывается.
+func (db *DB) CountRetention(ctx context.Context, report *RetentionReport) error {
+	count := func(query string, before *time.Time, dest *int) error {
+		if before == nil {
+			return nil
+		}
+		return db.QueryRowContext(ctx, query, *before).Scan(dest)
+	}
+	if err := count(`SELECT COUNT(*) FROM users u WHERE `+inactiveUsersWhere, report.InactiveBefore, &report.InactiveAccounts); err != nil {
+		return fmt.Errorf("failed to count inactive users: %w", err)
+	}
+	if err := count(`SELECT COUNT(*) FROM users u WHERE `+unverifiedUsersWhere, report.UnverifiedBefore, &report.UnverifiedAccounts); err != nil {
+		return fmt.Errorf("failed to count unverified users: %w", err)
+	}
+	if err := count(`SELECT COUNT(*) FROM otp_codes WHERE expires_at < $1`, report.OTPExpiredBefore, &report.ExpiredOTPCodes); err != nil {
+		return fmt.Errorf("failed to count expired otp codes: %w", err)
+	}
+	if err := count(`SELECT COUNT(*) FROM messages WHERE created_at < $1 AND attachment_url IS NOT NULL`,
+		report.ChatAttachmentsBefore, &report.ChatAttachments); err != nil {
+		return fmt.Errorf("failed to count chat attachments: %w", err)
+	}
+	if report.InactiveBefore == nil {
+		return nil
+	}
+	// Сканы заявок, загруженные после VerificationDocsRetainedAfter, еще под блокировкой хранилища
+	query := `SELECT COALESCE(SUM(docs) FILTER (WHERE NOT locked), 0), COALESCE(SUM(docs) FILTER (WHERE locked), 0)
+	          FROM (SELECT COALESCE(cardinality(v.passport_scans_urls), 0) + (v.user_photo_url IS NOT NULL)::int AS docs,
+	                       COALESCE(v.submitted_at >= $2, false) AS locked
+	                FROM verifications v JOIN users u ON u.id = v.user_id WHERE ` + inactiveUsersWhere + `) s`
+	if err := db.QueryRowContext(ctx, query, *report.InactiveBefore, report.VerificationDocsRetainedAfter).
+		Scan(&report.VerificationDocs, &report.RetainedVerificationDocs); err != nil {
+		return fmt.Errorf("failed to count verification docs: %w", err)
+	}
+	if err := count(`SELECT COUNT(p.guardian_document_url) + COUNT(p.urgent_document_url)
+	                 FROM posts p JOIN users u ON u.id = p.user_id WHERE `+inactiveUsersWhere,
+		report.InactiveBefore, &report.RetainedPostDocuments); err != nil {
+		return fmt.Errorf("failed to count post documents: %w", err)
+	}
+	return nil
+}
+
+// GetInactiveUserIDs возвращает ID аккаунтов без входа с before, больших afterID
+func (db *DB) GetInactiveUserIDs(before time.Time, afterID int64, limit int) ([]int64, error) {
+	return db.queryUserIDs(`SELECT u.id FROM users u WHERE `+inactiveUsersWhere+` AND u.id > $2 ORDER BY u.id LIMIT $3`,
+		before, afterID, limit)
+}
+
+// GetUnverifiedUserIDs возвращает ID неподтвержденных регистраций без входа с before, больших afterID
+func (db *DB) GetUnverifiedUserIDs(before time.Time, afterID int64, limit int) ([]int64, error) {
+	return db.queryUserIDs(`SELECT u.id FROM users u WHERE `+unverifiedUsersWhere+` AND u.id > $2 ORDER BY u.id LIMIT $3`,
+		before, afterID, limit)
+}
+
+func (db *DB) queryUserIDs(query string, args ...interface{}) ([]int64, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AnonymizeUser обезличивает аккаунт без входа с before: заменяет имя и телефон, удаляет
+// фото, сессии, устройства, привязки, личные настройки и заявку на верификацию с паспортными
+// данными. Посты, пожертвования, комментарии и сообщения остаются. Возвращает URL фото и
+// сканов заявки для удаления; false - аккаунт за это время стал активным.
+func (db *DB) AnonymizeUser(id int64, before time.Time) ([]string, []string, bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var phone string
+	var photoURL *string
+	var variants *ImageVariants
+	query := `SELECT u.phone, u.photo_url, u.photo_variants FROM users u WHERE u.id = $2 AND ` + inactiveUsersWhere + ` FOR UPDATE`
+	err = tx.QueryRow(query, before, id).Scan(&phone, &photoURL, &variants)
+	if err == sql.ErrNoRows {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	query = `UPDATE users SET phone = 'anon-' || id, password_hash = '', first_name = 'Удаленный', last_name = 'пользователь',
+	             photo_url = NULL, photo_variants = NULL, helper_name = NULL, away_message = NULL, away_until = NULL,
+	             referral_code = NULL, calendar_token_hash = NULL, phone_verified = false, is_active = false,
+	             anonymized_at = NOW(), updated_at = NOW()
+	         WHERE id = $1`
+	if _, err := tx.Exec(query, id); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	for _, table := range []string{"refresh_tokens", "login_events", "user_identities", "devices", "device_users", "api_tokens",
+		"user_integrations", "payment_note_templates", "notifications", "notification_preferences",
+		"chat_drafts", "favorites", "hidden_posts", "muted_authors"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = $1`, id); err != nil {
+			return nil, nil, false, fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM subscriptions WHERE donor_id = $1`, id); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to delete subscriptions: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE referrals SET ip_address = NULL WHERE referee_id = $1`, id); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to clear referral: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM otp_codes WHERE phone = $1`, phone); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to delete otp codes: %w", err)
+	}
+
+	docs, err := deleteUserVerification(tx, id)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, false, err
+	}
+	return photoURLs(photoURL, variants), docs, true, nil
+}
+
+// deleteUserVerification удаляет заявку на верификацию пользователя и возвращает URL ее
+// сканов. Файлы, на которые ссылаются заявки других пользователей, не возвращаются.
+func deleteUserVerification(tx *sql.Tx, userID int64) ([]string, error) {
+	var photoURL *string
+	var scans pq.StringArray
+	err := tx.QueryRow(`DELETE FROM verifications WHERE user_id = $1 RETURNING user_photo_url, passport_scans_urls`, userID).
+		Scan(&photoURL, &scans)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete verification: %w", err)
+	}
+
+	urls := []string(scans)
+	if photoURL != nil {
+		urls = append(urls, *photoURL)
+	}
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	rows, err := tx.Query(`SELECT url FROM unnest($1::text[]) url
+	                       WHERE NOT EXISTS (SELECT 1 FROM verifications v WHERE v.user_photo_url = url OR url = ANY(v.passport_scans_urls))`,
+		pq.Array(urls))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check verification docs: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		docs = append(docs, url)
+	}
+	return docs, rows.Err()
+}
+
+// photoURLs собирает URL фото и его уменьшенных копий
+func photoURLs(photoURL *string, variants *ImageVariants) []string {
+	var urls []string
+	if photoURL != nil {
+		urls = append(urls, *photoURL)
+	}
+	if variants != nil {
+		for _, url := range []string{variants.Small, variants.Medium, variants.Large} {
+			if url != "" {
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls
+}
+
+// DeleteUnverifiedUser удаляет неподтвержденную регистрацию без входа с before.
+// Возвращает URL фото для удаления; false - аккаунт за это время изменился.
+func (db *DB) DeleteUnverifiedUser(id int64, before time.Time) ([]string, bool, error) {
+	var photoURL *string
+	var variants *ImageVariants
+	query := `DELETE FROM users u WHERE u.id = $2 AND ` + unverifiedUsersWhere + ` RETURNING u.photo_url, u.photo_variants`
+	err := db.QueryRow(query, before, id).Scan(&photoURL, &variants)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to delete unverified user: %w", err)
+	}
+	return photoURLs(photoURL, variants), true, nil
+}
+
+// DeleteExpiredOTPCodes удаляет коды подтверждения, истекшие до before
+func (db *DB) DeleteExpiredOTPCodes(before time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM otp_codes WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired otp codes: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetOldChatAttachments возвращает вложения сообщений, отправленных до before, старые первыми
+func (db *DB) GetOldChatAttachments(before time.Time, limit int) ([]ChatAttachmentRef, error) {
+	query := `SELECT id, created_at, attachment_url FROM messages
+	          WHERE created_at < $1 AND attachment_url IS NOT NULL
+	          ORDER BY created_at LIMIT $2`
+	rows, err := db.Query(query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []ChatAttachmentRef
+	for rows.Next() {
+		var ref ChatAttachmentRef
+		if err := rows.Scan(&ref.MessageID, &ref.CreatedAt, &ref.URL); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// ClearChatAttachment убирает вложение из сообщения. Сообщение без текста получает
+// отметку об удалении вложения.
+func (db *DB) ClearChatAttachment(messageID int64, createdAt time.Time) error {
+	query := `UPDATE messages SET attachment_url = NULL, text = COALESCE(text, 'Вложение удалено по сроку хранения')
+	          WHERE id = $1 AND created_at = $2`
+	_, err := db.Exec(query, messageID, createdAt)
+	return err
+}
+
+// ========== Invite functions ==========
+
+const inviteCodeColumns = `id, code, batch, max_uses, uses, expires_at, created_by, created_at`
+
+func scanInviteCodes(rows *sql.Rows) ([]InviteCode, error) {
+	defer rows.Close()
+
+	var codes []InviteCode
+	for rows.Next() {
+		var c InviteCode
+		if err := rows.Scan(&c.ID, &c.Code, &c.Batch, &c.MaxUses, &c.Uses, &c.ExpiresAt, &c.CreatedBy, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// CreateInviteCodes сохраняет партию кодов приглашения
+func (db *DB) CreateInviteCodes(batch string, codes []string, maxUses int, expiresAt *time.Time, createdBy int64) ([]InviteCode, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO invite_codes (code, batch, max_uses, expires_at, created_by)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING ` + inviteCodeColumns
+	created := make([]InviteCode, 0, len(codes))
+	for _, code := range codes {
+		var c InviteCode
+		err := tx.QueryRow(query, code, batch, maxUses, expiresAt, createdBy).Scan(
+			&c.ID, &c.Code, &c.Batch, &c.MaxUses, &c.Uses, &c.ExpiresAt, &c.CreatedBy, &c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create invite code: %w", err)
+		}
+		created = append(created, c)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// GetInviteCodes получает коды приглашения, новые первыми. Пустой batch - все партии.
+func (db *DB) GetInviteCodes(batch string, page, limit int) ([]InviteCode, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM invite_codes WHERE $1 = '' OR batch = $1`, batch).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + inviteCodeColumns + `
+	          FROM invite_codes WHERE $1 = '' OR batch = $1
+	          ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`
+	rows, err := db.Query(query, batch, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	codes, err := scanInviteCodes(rows)
+	return codes, total, err
+}
+
+// GetInvitedUsers получает пользователей, зарегистрированных по коду приглашения
+func (db *DB) GetInvitedUsers(codeID int64) ([]InvitedUser, error) {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM invite_codes WHERE id = $1)`, codeID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, NewNotFoundError("Код приглашения")
+	}
+
+	rows, err := db.Query(`SELECT id, first_name, last_name, created_at FROM users
+	                       WHERE invite_code_id = $1 ORDER BY created_at`, codeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []InvitedUser{}
+	for rows.Next() {
+		var u InvitedUser
+		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// ========== Referral functions ==========
+
+// GetUserIDByReferralCode находит пользователя по реферальному коду
+func (db *DB) GetUserIDByReferralCode(code string) (int64, error) {
+	var userID int64
+	err := db.QueryRow(`SELECT id FROM users WHERE referral_code = $1 AND is_active = true`, code).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, NewValidationError("Реферальный код не найден", map[string]interface{}{"field": "referral_code"})
+	}
+	return userID, err
+}
+
+// GetOrCreateReferralCode возвращает реферальный код пользователя, при первом запросе создает его
+func (db *DB) GetOrCreateReferralCode(userID int64) (string, error) {
+	var code *string
+	if err := db.QueryRow(`SELECT referral_code FROM users WHERE id = $1`, userID).Scan(&code); err != nil {
+		if err == sql.ErrNoRows {
+			return "", NewNotFoundError("Пользователь")
+		}
+		return "", err
+	}
+	if code != nil {
+		return *code, nil
+	}
+
+	// Коды случайные, совпадение маловероятно, но возможно
+	for attempt := 0; attempt < 5; attempt++ {
+		newCode, err := GenerateReferralCode()
+		if err != nil {
+			return "", err
+		}
+		query := `UPDATE users SET referral_code = COALESCE(referral_code, $2) WHERE id = $1 RETURNING referral_code`
+		err = db.QueryRow(query, userID, newCode).Scan(&code)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to set referral code: %w", err)
+		}
+		return *code, nil
+	}
+	return "", fmt.Errorf("failed to generate unique referral code")
+}
+
+// CreateReferral сохраняет приглашение нового пользователя и IP адрес, с которого он зарегистрировался
+func (db *DB) CreateReferral(referrerID, refereeID int64, ipAddress *string) error {
+	query := `INSERT INTO referrals (referrer_id, referee_id, ip_address) VALUES ($1, $2, $3)
+	          ON CONFLICT (referee_id) DO NOTHING`
+	_, err := db.Exec(query, referrerID, refereeID, ipAddress)
+	return err
+}
+
+// rewardReferral начисляет по bonus баллов рейтинга приглашенному донору и пригласившему
+// его пользователю, если приглашение еще ожидает первого пожертвования. Бонус не
+// начисляется, если оба входили с одного IP адреса (или приглашенный зарегистрировался
+// с IP адреса пригласившего) или регистрировали одно устройство: приглашение отклоняется.
+// Возвращает приглашение, если бонус начислен.
+func rewardReferral(tx *sql.Tx, refereeID, donationID int64, bonus int) (*Referral, error) {
+	var r Referral
+	query := `SELECT id, referrer_id, referee_id FROM referrals WHERE referee_id = $1 AND status = $2 FOR UPDATE`
+	err := tx.QueryRow(query, refereeID, ReferralPending).Scan(&r.ID, &r.ReferrerID, &r.RefereeID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referral: %w", err)
+	}
+
+	var sameIP, sameDevice bool
+	query = `SELECT
+	             EXISTS (SELECT 1 FROM login_events l
+	                     WHERE l.user_id = r.referrer_id AND l.ip_address IS NOT NULL
+	                       AND (l.ip_address = r.ip_address
+	                            OR l.ip_address IN (SELECT ip_address FROM login_events WHERE user_id = r.referee_id))),
+	             EXISTS (SELECT 1 FROM device_users a JOIN device_users b ON b.token_hash = a.token_hash
+	                     WHERE a.user_id = r.referrer_id AND b.user_id = r.referee_id)
+	         FROM referrals r WHERE r.id = $1`
+	if err := tx.QueryRow(query, r.ID).Scan(&sameIP, &sameDevice); err != nil {
+		return nil, fmt.Errorf("failed to check referral: %w", err)
+	}
+
+	var rejectReason *string
+	switch {
+	case sameIP:
+		rejectReason = getStringPtr(ReferralRejectSameIP)
+	case sameDevice:
+		rejectReason = getStringPtr(ReferralRejectSameDevice)
+	}
+	if rejectReason != nil {
+		query = `UPDATE referrals SET status = $2, reject_reason = $3, donation_id = $4 WHERE id = $1`
+		if _, err := tx.Exec(query, r.ID, ReferralRejected, *rejectReason, donationID); err != nil {
+			return nil, fmt.Errorf("failed to reject referral: %w", err)
+		}
+		return nil, nil
+	}
+
+	query = `UPDATE referrals SET status = $2, bonus_points = $3, donation_id = $4, rewarded_at = NOW()
+	         WHERE id = $1 RETURNING status, bonus_points, created_at, rewarded_at`
+	if err := tx.QueryRow(query, r.ID, ReferralRewarded, bonus, donationID).Scan(&r.Status, &r.BonusPoints, &r.CreatedAt, &r.RewardedAt); err != nil {
+		return nil, fmt.Errorf("failed to reward referral: %w", err)
+	}
+	if bonus > 0 {
+		for _, userID := range []int64{r.ReferrerID, r.RefereeID} {
+			if err := addRatingEvent(tx, RatingEvent{UserID: userID, Source: RatingEventReferral, ReferralID: &r.ID, Points: bonus}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &r, nil
+}
+
+// GetReferrals получает пользователей, приглашенных пользователем, новые первыми
+func (db *DB) GetReferrals(referrerID int64) ([]Referral, error) {
+	query := `SELECT r.id, r.referrer_id, r.referee_id, u.first_name, r.status, r.reject_reason,
+	                 r.bonus_points, r.created_at, r.rewarded_at
+	          FROM referrals r JOIN users u ON u.id = r.referee_id
+	          WHERE r.referrer_id = $1 ORDER BY r.created_at DESC`
+	rows, err := db.Query(query, referrerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referrals := []Referral{}
+	for rows.Next() {
+		var r Referral
+		if err := rows.Scan(&r.ID, &r.ReferrerID, &r.RefereeID, &r.FirstName, &r.Status, &r.RejectReason,
+			&r.BonusPoints, &r.CreatedAt, &r.RewardedAt); err != nil {
+			return nil, err
+		}
+		referrals = append(referrals, r)
+	}
+	return referrals, rows.Err()
+}
+
+// ========== Role functions ==========
+
+// grantAdminPermissions выдает роли admin все известные права
+func (db *DB) grantAdminPermissions() error {
+	query := `INSERT INTO role_permissions (role, permission)
+	          SELECT $1, unnest($2::text[])
+	          ON CONFLICT DO NOTHING`
+	if _, err := db.Exec(query, RoleAdmin, pq.Array(AllPermissions)); err != nil {
+		return fmt.Errorf("failed to grant admin permissions: %w", err)
+	}
+	return nil
+}
+
+// GetRolePermissions получает матрицу прав: роль -> набор прав
+func (db *DB) GetRolePermissions() (map[string]map[string]bool, error) {
+	rows, err := db.Query(`SELECT role, permission FROM role_permissions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byRole := make(map[string]map[string]bool)
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, err
+		}
+		if byRole[role] == nil {
+			byRole[role] = make(map[string]bool)
+		}
+		byRole[role][permission] = true
+	}
+	return byRole, rows.Err()
+}
+
+// GetRoles получает роли вместе с их правами
+func (db *DB) GetRoles() ([]Role, error) {
+	query := `SELECT r.name, r.description, COALESCE(array_agg(rp.permission ORDER BY rp.permission)
+	                 FILTER (WHERE rp.permission IS NOT NULL), '{}')
+	          FROM roles r LEFT JOIN role_permissions rp ON rp.role = r.name
+	          GROUP BY r.name, r.description ORDER BY r.name`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		var role Role
+		var permissions pq.StringArray
+		if err := rows.Scan(&role.Name, &role.Description, &permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = []string(permissions)
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SaveRole создает роль или заменяет ее описание и набор прав
+func (db *DB) SaveRole(name, description string, permissions []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO roles (name, description) VALUES ($1, $2)
+	          ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description`
+	if _, err := tx.Exec(query, name, description); err != nil {
+		return fmt.Errorf("failed to save role: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role = $1`, name); err != nil {
+		return fmt.Errorf("failed to reset role permissions: %w", err)
+	}
+
+	query = `INSERT INTO role_permissions (role, permission) SELECT $1, unnest($2::text[])`
+	if _, err := tx.Exec(query, name, pq.Array(permissions)); err != nil {
+		return fmt.Errorf("failed to save role permissions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RoleExists проверяет, что роль заведена в таблице roles
+func (db *DB) RoleExists(name string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1)`, name).Scan(&exists)
+	return exists, err
+}
+
+// UpdateUserRole меняет роль пользователя. Возвращает прежнюю роль.
+func (db *DB) UpdateUserRole(userID int64, role string) (string, error) {
+	var oldRole string
+	query := `UPDATE users u SET role = $1, updated_at = NOW()
+	          FROM users old WHERE u.id = $2 AND old.id = u.id
+	          RETURNING old.role`
+	err := db.QueryRow(query, role, userID).Scan(&oldRole)
+	if err == sql.ErrNoRows {
+		return "", NewNotFoundError("Пользователь")
+	}
+	return oldRole, err
+}
+
+// ========== Post limit functions ==========
+
+// GetRiskTiers получает уровни доверия авторов по возрастанию лимита
+func (db *DB) GetRiskTiers() ([]RiskTier, error) {
+	query := `SELECT name, description, max_post_amount, min_completed_posts
+	          FROM risk_tiers ORDER BY max_post_amount`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []RiskTier
+	for rows.Next() {
+		var tier RiskTier
+		if err := rows.Scan(&tier.Name, &tier.Description, &tier.MaxPostAmount, &tier.MinCompletedPosts); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, rows.Err()
+}
+
+// SaveRiskTier создает или изменяет уровень доверия
+func (db *DB) SaveRiskTier(tier RiskTier) error {
+	query := `INSERT INTO risk_tiers (name, description, max_post_amount, min_completed_posts)
+	          VALUES ($1, $2, $3, $4)
+	          ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description,
+	              max_post_amount = EXCLUDED.max_post_amount, min_completed_posts = EXCLUDED.min_completed_posts`
+	if _, err := db.Exec(query, tier.Name, tier.Description, tier.MaxPostAmount, tier.MinCompletedPosts); err != nil {
+		return fmt.Errorf("failed to save risk tier: %w", err)
+	}
+	return nil
+}
+
+// RiskTierExists проверяет, что уровень доверия заведен в таблице risk_tiers
+func (db *DB) RiskTierExists(name string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM risk_tiers WHERE name = $1)`, name).Scan(&exists)
+	return exists, err
+}
+
+// SetUserRiskTier назначает пользователю уровень доверия. nil возвращает автоматический выбор.
+// Возвращает прежний назначенный уровень.
+func (db *DB) SetUserRiskTier(userID int64, tier *string) (*string, error) {
+	var oldTier *string
+	query := `UPDATE users u SET risk_tier = $1, updated_at = NOW()
+	          FROM users old WHERE u.id = $2 AND old.id = u.id
+	          RETURNING old.risk_tier`
+	err := db.QueryRow(query, tier, userID).Scan(&oldTier)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Пользователь")
+	}
+	return oldTier, err
+}
+
+// GetPostLimit определяет уровень доверия автора и его лимит целевой суммы: назначенный
+// администратором или наибольший из автоматических по числу завершенных сборов.
+// Возвращает nil, если подходящих уровней нет (лимит не действует).
+func (db *DB) GetPostLimit(userID int64) (*PostLimit, error) {
+	query := `WITH author AS (
+	              SELECT u.risk_tier,
+	                     (SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id AND p.status = 'completed') AS completed
+	              FROM users u WHERE u.id = $1
+	          )
+	          SELECT t.name, t.max_post_amount, author.risk_tier IS NOT NULL
+	          FROM risk_tiers t, author
+	          WHERE t.name = author.risk_tier
+	             OR (author.risk_tier IS NULL AND t.min_completed_posts <= author.completed)
+	          ORDER BY t.max_post_amount DESC
+	          LIMIT 1`
+	var limit PostLimit
+	err := db.QueryRow(query, userID).Scan(&limit.Tier, &limit.MaxAmount, &limit.Assigned)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post limit: %w", err)
+	}
+	return &limit, nil
+}
+
+// CreatePostLimitRequest создает запрос на исключение из лимита
+func (db *DB) CreatePostLimitRequest(req *PostLimitRequest) error {
+	query := `INSERT INTO post_limit_requests (user_id, amount, reason)
+	          VALUES ($1, $2, $3)
+	          RETURNING id, status, created_at`
+	err := db.QueryRow(query, req.UserID, req.Amount, req.Reason).Scan(&req.ID, &req.Status, &req.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return NewConflictError("Предыдущий запрос еще рассматривается")
+		}
+		return fmt.Errorf("failed to create post limit request: %w", err)
+	}
+	return nil
+}
+
+const postLimitRequestColumns = `id, user_id, amount, reason, status, comment, reviewed_by, reviewed_at, post_id, used_at, created_at`
+
+func scanPostLimitRequests(rows *sql.Rows) ([]PostLimitRequest, error) {
+	defer rows.Close()
+
+	var requests []PostLimitRequest
+	for rows.Next() {
+		var req PostLimitRequest
+		err := rows.Scan(&req.ID, &req.UserID, &req.Amount, &req.Reason, &req.Status, &req.Comment,
+			&req.ReviewedBy, &req.ReviewedAt, &req.PostID, &req.UsedAt, &req.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// GetUserPostLimitRequests получает запросы пользователя на исключение, новые первыми
+func (db *DB) GetUserPostLimitRequests(userID int64) ([]PostLimitRequest, error) {
+	query := `SELECT ` + postLimitRequestColumns + `
+	          FROM post_limit_requests WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanPostLimitRequests(rows)
+}
+
+// GetPostLimitRequests получает страницу запросов на исключение по статусу (старые первыми) и их общее число
+func (db *DB) GetPostLimitRequests(status string, page, limit int) ([]PostLimitRequest, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM post_limit_requests WHERE status = $1`, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + postLimitRequestColumns + `
+	          FROM post_limit_requests WHERE status = $1
+	          ORDER BY created_at LIMIT $2 OFFSET $3`
+	rows, err := db.Query(query, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	requests, err := scanPostLimitRequests(rows)
+	return requests, total, err
+}
+
+// ReviewPostLimitRequest сохраняет решение по запросу, ожидающему рассмотрения
+func (db *DB) ReviewPostLimitRequest(id, reviewerID int64, status string, comment *string) (*PostLimitRequest, error) {
+	query := `UPDATE post_limit_requests
+	          SET status = $2, comment = $3, reviewed_by = $4, reviewed_at = NOW()
+	          WHERE id = $1 AND status = 'pending'
+	          RETURNING ` + postLimitRequestColumns
+	rows, err := db.Query(query, id, status, comment, reviewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review post limit request: %w", err)
+	}
+	requests, err := scanPostLimitRequests(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		var exists bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM post_limit_requests WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, NewNotFoundError("Запрос")
+		}
+		return nil, NewConflictError("Запрос уже рассмотрен")
+	}
+	return &requests[0], nil
+}
+
+// FindPostLimitException ищет одобренное неиспользованное исключение, покрывающее сумму
+func (db *DB) FindPostLimitException(userID int64, amount float64) (*int64, error) {
+	query := `SELECT id FROM post_limit_requests
+	          WHERE user_id = $1 AND status = 'approved' AND used_at IS NULL AND amount >= $2
+	          ORDER BY amount LIMIT 1`
+	var id int64
+	err := db.QueryRow(query, userID, amount).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// UsePostLimitException отмечает исключение использованным для поста
+func (db *DB) UsePostLimitException(id, postID int64) error {
+	_, err := db.Exec(`UPDATE post_limit_requests SET used_at = NOW(), post_id = $2 WHERE id = $1 AND used_at IS NULL`, id, postID)
+	return err
+}
+
+// ========== Verification functions ==========
+
+// CreateVerification создает заявку на верификацию. Серия и номер паспорта, ИНН, СНИЛС
+// и поля документа в document_data шифруются.
+func (db *DB) CreateVerification(v *Verification) error {
+	series, number, inn, snils, err := db.pii.encryptVerificationPII(v)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt verification data: %w", err)
+	}
+	documentData, err := db.pii.encryptDocumentData(v.DocumentData)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt verification data: %w", err)
+	}
+
+	query := `INSERT INTO verifications 
+	          (user_id, user_photo_url, last_name, first_name, middle_name, birth_date, 
+	           passport_series, passport_number, passport_issuer, passport_date, 
+	           doc_type, inn, snils, passport_scans_urls, consent1, consent2, consent3,
+	           country, document_type, document_data,
+	           organization_inn, organization_ogrn, organization_name, organization_check)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+	                  $21, $22, $23, $24)
+	          RETURNING id, status, submitted_at`
+	var scansArray pq.StringArray
+	if len(v.PassportScansURLs) > 0 {
+		scansArray = pq.StringArray(v.PassportScansURLs)
+	}
+	err = db.QueryRow(query,
+		v.UserID, v.UserPhotoURL, v.LastName, v.FirstName, v.MiddleName, v.BirthDate,
+		series, number, v.PassportIssuer, v.PassportDate,
+		v.DocType, inn, snils, scansArray, v.Consent1, v.Consent2, v.Consent3,
+		v.Country, v.DocumentType, documentData,
+		v.OrganizationINN, v.OrganizationOGRN, v.OrganizationName, v.OrganizationCheck,
+	).Scan(&v.ID, &v.Status, &v.SubmittedAt)
+	return err
+}
+
+// GetVerificationByUserID получает верификацию по user_id
+func (db *DB) GetVerificationByUserID(userID int64) (*Verification, error) {
+	return db.getVerification("user_id", userID)
+}
+
+// GetVerificationByID получает верификацию по ID
+func (db *DB) GetVerificationByID(id int64) (*Verification, error) {
+	return db.getVerification("id", id)
+}
+
+func (db *DB) getVerification(column string, value int64) (*Verification, error) {
+	var v Verification
+	var scansArray pq.StringArray
+	var documentData, registry []byte
+	var mismatches pq.StringArray
+	query := `SELECT id, user_id, user_photo_url, last_name, first_name, middle_name, birth_date,
+	                 passport_series, passport_number, passport_issuer, passport_date,
+	                 doc_type, inn, snils, passport_scans_urls, consent1, consent2, consent3,
+	                 status, submitted_at, reviewed_at, reviewed_by, rejection_reason,
+	                 country, document_type, document_data,
+	                 organization_inn, organization_ogrn, organization_name, organization_check,
+	                 organization_mismatches, organization_registry
+	          FROM verifications WHERE ` + column + ` = $1`
+	err := db.QueryRow(query, value).Scan(
+		&v.ID, &v.UserID, &v.UserPhotoURL, &v.LastName, &v.FirstName, &v.MiddleName, &v.BirthDate,
+		&v.PassportSeries, &v.PassportNumber, &v.PassportIssuer, &v.PassportDate,
+		&v.DocType, &v.INN, &v.SNILS, &scansArray, &v.Consent1, &v.Consent2, &v.Consent3,
+		&v.Status, &v.SubmittedAt, &v.ReviewedAt, &v.ReviewedBy, &v.RejectionReason,
+		&v.Country, &v.DocumentType, &documentData,
+		&v.OrganizationINN, &v.OrganizationOGRN, &v.OrganizationName, &v.OrganizationCheck,
+		&mismatches, &registry,
+	)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Верификация")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get verification: %w", err)
 	}
-	if len(scansArray) > 0 {
-		v.PassportScansURLs = []string(scansArray)
+	if len(scansArray) > 0 {
+		v.PassportScansURLs = []string(scansArray)
+	}
+	if documentData != nil {
+		if err := json.Unmarshal(documentData, &v.DocumentData); err != nil {
+			return nil, fmt.Errorf("failed to decode verification %d document data: %w", v.ID, err)
+		}
+	}
+	v.OrganizationMismatches = []string(mismatches)
+	if registry != nil {
+		if err := json.Unmarshal(registry, &v.OrganizationRegistry); err != nil {
+			return nil, fmt.Errorf("failed to decode verification %d registry data: %w", v.ID, err)
+		}
+	}
+	if err := db.pii.decryptVerificationPII(&v); err != nil {
+		return nil, fmt.Errorf("failed to decrypt verification %d: %w", v.ID, err)
+	}
+	return &v, nil
+}
+
+// GetVerifications получает список верификаций с фильтрацией
+func (db *DB) GetVerifications(status string, page, limit int) ([]Verification, int, error) {
+	where := "1=1"
+	args := []interface{}{}
+	argPos := 1
+
+	if status != "" {
+		where += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, status)
+		argPos++
+	}
+
+	// Подсчет общего количества
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM verifications WHERE %s", where)
+	err := db.QueryRow(countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Получение данных
+	offset := (page - 1) * limit
+	query := fmt.Sprintf(`SELECT id, user_id, first_name, last_name, status, submitted_at, organization_inn, organization_check
+	                     FROM verifications WHERE %s ORDER BY submitted_at DESC LIMIT $%d OFFSET $%d`,
+		where, argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var verifications []Verification
+	for rows.Next() {
+		var v Verification
+		err := rows.Scan(&v.ID, &v.UserID, &v.FirstName, &v.LastName, &v.Status, &v.SubmittedAt, &v.OrganizationINN, &v.OrganizationCheck)
+		if err != nil {
+			return nil, 0, err
+		}
+		verifications = append(verifications, v)
+	}
+
+	return verifications, total, nil
+}
+
+// UpdateVerificationStatus обновляет статус верификации
+func (db *DB) UpdateVerificationStatus(id int64, status string, reviewedBy int64, rejectionReason *string) error {
+	query := `UPDATE verifications 
+	          SET status = $1, reviewed_at = NOW(), reviewed_by = $2, rejection_reason = $3 
+	          WHERE id = $4`
+	_, err := db.Exec(query, status, reviewedBy, rejectionReason, id)
+	return err
+}
+
+// GetVerificationsPendingOrganizationCheck возвращает заявки, организация которых еще не сверена с реестром
+func (db *DB) GetVerificationsPendingOrganizationCheck(limit int) ([]Verification, error) {
+	query := `SELECT id, organization_inn, organization_ogrn, organization_name FROM verifications
+	          WHERE organization_check = 'pending' ORDER BY submitted_at LIMIT $1`
+	rows, err := db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var verifications []Verification
+	for rows.Next() {
+		var v Verification
+		if err := rows.Scan(&v.ID, &v.OrganizationINN, &v.OrganizationOGRN, &v.OrganizationName); err != nil {
+			return nil, err
+		}
+		verifications = append(verifications, v)
+	}
+	return verifications, rows.Err()
+}
+
+// SaveOrganizationCheck сохраняет результат сверки организации с реестром. Пустые в заявке
+// ОГРН и название заполняются из реестра.
+func (db *DB) SaveOrganizationCheck(id int64, result OrganizationCheckResult) error {
+	var registry []byte
+	var ogrn, name *string
+	if result.Registry != nil {
+		var err error
+		if registry, err = json.Marshal(result.Registry); err != nil {
+			return err
+		}
+		ogrn, name = &result.Registry.OGRN, &result.Registry.Name
+	}
+	query := `UPDATE verifications
+	          SET organization_check = $2, organization_mismatches = $3, organization_registry = $4,
+	              organization_ogrn = COALESCE(organization_ogrn, $5), organization_name = COALESCE(organization_name, $6),
+	              organization_checked_at = NOW()
+	          WHERE id = $1`
+	_, err := db.Exec(query, id, result.Status, pq.Array(result.Mismatches), registry, ogrn, name)
+	return err
+}
+
+// GetCachedOrganization возвращает ответ реестра по ИНН, полученный после since.
+// found=false - в кэше нет свежего ответа; org=nil при found=true - организации нет в реестре.
+func (db *DB) GetCachedOrganization(inn string, since time.Time) (org *RegistryOrganization, found bool, err error) {
+	var data []byte
+	err = db.QueryRow(`SELECT data FROM registry_organizations WHERE inn = $1 AND fetched_at > $2`, inn, since).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if data == nil {
+		return nil, true, nil
+	}
+	if err := json.Unmarshal(data, &org); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cached organization %s: %w", inn, err)
+	}
+	return org, true, nil
+}
+
+// CacheOrganization сохраняет ответ реестра по ИНН; org=nil - организации нет в реестре
+func (db *DB) CacheOrganization(inn string, org *RegistryOrganization) error {
+	var data []byte
+	if org != nil {
+		var err error
+		if data, err = json.Marshal(org); err != nil {
+			return err
+		}
+	}
+	query := `INSERT INTO registry_organizations (inn, data, fetched_at) VALUES ($1, $2, NOW())
+	          ON CONFLICT (inn) DO UPDATE SET data = EXCLUDED.data, fetched_at = EXCLUDED.fetched_at`
+	_, err := db.Exec(query, inn, data)
+	return err
+}
+
+// IsUserVerified проверяет, верифицирован ли пользователь
+func (db *DB) IsUserVerified(userID int64) bool {
+	var count int
+	query := `SELECT COUNT(*) FROM verifications WHERE user_id = $1 AND status = 'approved'`
+	db.QueryRow(query, userID).Scan(&count)
+	return count > 0
+}
+
+// ========== Post functions ==========
+
+// CreatePost создает новый пост
+func (db *DB) CreatePost(p *Post) error {
+	if p.Status == "" {
+		p.Status = "active"
+	}
+	query := `INSERT INTO posts (user_id, title, description, amount, recipient, bank, phone, status, beneficiary_is_minor, language, urgent, deadline)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	          RETURNING id, collected, status, created_at, updated_at, is_editable`
+	err := db.QueryRow(query, p.UserID, p.Title, p.Description, p.Amount, p.Recipient, p.Bank, p.Phone, p.Status, p.BeneficiaryIsMinor, p.Language, p.Urgent, p.Deadline).Scan(
+		&p.ID, &p.Collected, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable,
+	)
+	return err
+}
+
+// GetPostByID получает пост по ID
+func (db *DB) GetPostByID(id int64) (*Post, error) {
+	var p Post
+	query := `SELECT id, user_id, title, description, amount, collected, recipient, bank, phone, 
+	                 status, created_at, updated_at, is_editable, beneficiary_is_minor, guardian_document_url,
+	                 COALESCE(language, ''), deadline, urgent, urgent_until, urgent_document_url
+	          FROM posts WHERE id = $1`
+	err := db.QueryRow(query, id).Scan(
+		&p.ID, &p.UserID, &p.Title, &p.Description, &p.Amount, &p.Collected,
+		&p.Recipient, &p.Bank, &p.Phone, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable,
+		&p.BeneficiaryIsMinor, &p.GuardianDocumentURL, &p.Language, &p.Deadline, &p.Urgent, &p.UrgentUntil, &p.UrgentDocumentURL,
+	)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Пост")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	return &p, nil
+}
+
+// GetShareImageState получает отпечаток и собранную сумму последней отрисованной картинки поста
+func (db *DB) GetShareImageState(postID int64) (*string, *float64, error) {
+	var hash *string
+	var collected *float64
+	query := `SELECT share_image_hash, share_image_collected FROM posts WHERE id = $1`
+	if err := db.QueryRow(query, postID).Scan(&hash, &collected); err != nil {
+		return nil, nil, fmt.Errorf("failed to get share image state: %w", err)
+	}
+	return hash, collected, nil
+}
+
+// SaveShareImageState сохраняет отпечаток и собранную сумму отрисованной картинки поста
+func (db *DB) SaveShareImageState(postID int64, hash string, collected float64) error {
+	query := `UPDATE posts SET share_image_hash = $1, share_image_collected = $2 WHERE id = $3`
+	_, err := db.Exec(query, hash, collected, postID)
+	return err
+}
+
+// GetPosts получает список постов с фильтрацией и пагинацией.
+// Если задан searchQuery, посты ищутся по словам заголовка и описания
+// (с учетом морфологии) либо по похожести заголовка, и сортируются по релевантности.
+func (db *DB) GetPosts(status string, userID *int64, searchQuery string, page, limit int) ([]Post, int, error) {
+	where, orderBy, args := postsFilter(PostFilter{Status: status, UserID: userID, Search: searchQuery})
+	argPos := len(args) + 1
+
+	// Подсчет общего количества
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM posts p WHERE %s", where)
+	err := db.QueryRow(countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Получение данных
+	offset := (page - 1) * limit
+	query := fmt.Sprintf(`SELECT p.id, p.user_id, p.title, p.description, p.amount, p.collected, p.recipient, p.bank, p.phone,
+	                             p.status, p.created_at, p.updated_at, p.is_editable, p.beneficiary_is_minor, p.guardian_document_url,
+	                             COALESCE(p.language, ''), p.deadline, p.urgent, p.urgent_until, p.urgent_document_url
+	                      FROM posts p WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		where, orderBy, argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var posts []Post
+	for rows.Next() {
+		var p Post
+		err := rows.Scan(
+			&p.ID, &p.UserID, &p.Title, &p.Description, &p.Amount, &p.Collected,
+			&p.Recipient, &p.Bank, &p.Phone, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable,
+			&p.BeneficiaryIsMinor, &p.GuardianDocumentURL, &p.Language, &p.Deadline, &p.Urgent, &p.UrgentUntil, &p.UrgentDocumentURL,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, total, nil
+}
+
+// GetPostsWithDetails получает страницу постов вместе с авторами и медиа.
+// Выполняет постоянное число запросов независимо от размера страницы.
+func (db *DB) GetPostsWithDetails(filter PostFilter, page, limit int) ([]PostWithDetails, int, error) {
+	where, orderBy, args := postsFilter(filter)
+	argPos := len(args) + 1
+
+	// Подсчет общего количества
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM posts p WHERE %s", where)
+	if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// Посты вместе с авторами
+	offset := (page - 1) * limit
+	query := fmt.Sprintf(`SELECT `+postDetailsColumns+` FROM posts p `+postDetailsJoins+`
+	                      WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		where, orderBy, argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	posts, err := db.queryPostsWithDetails(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// Столбцы и соединения поста с автором и временем ответа для queryPostsWithDetails
+var postDetailsColumns = `p.id, p.user_id, p.title, p.description, p.amount, p.collected, p.recipient, p.bank, p.phone,
+	p.status, p.created_at, p.updated_at, p.is_editable, p.beneficiary_is_minor, p.guardian_document_url,
+	COALESCE(p.language, ''), p.deadline, p.urgent, p.urgent_until, p.urgent_document_url,
+	u.id, u.first_name, u.last_name, COALESCE(u.photo_variants->>'small', u.photo_url), ` + awayColumns("u") + `,
+	rs.median_seconds, (SELECT COUNT(*) FROM post_questions q WHERE q.post_id = p.id AND q.answer IS NOT NULL)`
+
+const postDetailsJoins = `LEFT JOIN users u ON u.id = p.user_id
+	LEFT JOIN author_response_stats rs ON rs.user_id = p.user_id`
+
+// queryPostsWithDetails выполняет запрос со столбцами postDetailsColumns и загружает
+// медиа всех постов одним запросом
+func (db *DB) queryPostsWithDetails(query string, args ...interface{}) ([]PostWithDetails, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []PostWithDetails
+	var postIDs []int64
+	for rows.Next() {
+		var p PostWithDetails
+		var authorID sql.NullInt64
+		var firstName, lastName sql.NullString
+		var photoURL, awayMessage *string
+		var awayUntil *time.Time
+		var responseSeconds sql.NullInt64
+		err := rows.Scan(
+			&p.ID, &p.UserID, &p.Title, &p.Description, &p.Amount, &p.Collected,
+			&p.Recipient, &p.Bank, &p.Phone, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable,
+			&p.BeneficiaryIsMinor, &p.GuardianDocumentURL, &p.Language, &p.Deadline, &p.Urgent, &p.UrgentUntil, &p.UrgentDocumentURL,
+			&authorID, &firstName, &lastName, &photoURL, &awayMessage, &awayUntil, &responseSeconds, &p.AnsweredQuestions,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if authorID.Valid {
+			p.Author = &UserInfo{
+				ID:     authorID.Int64,
+				Name:   fmt.Sprintf("%s %s", firstName.String, lastName.String),
+				Avatar: photoURL,
+				Away:   newAwayStatus(awayMessage, awayUntil),
+			}
+			if responseSeconds.Valid {
+				p.Author.ResponseTime = NewResponseTime(int(responseSeconds.Int64))
+			}
+		}
+		posts = append(posts, p)
+		postIDs = append(postIDs, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Медиа всех постов страницы одним запросом
+	media, err := db.GetPostsMedia(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].Media = media[posts[i].ID]
+	}
+
+	return posts, nil
+}
+
+// postSortOrders сортировки ленты. Кроме urgent, каждая совпадает с индексом
+// (idx_posts_created_at, idx_posts_collected, idx_posts_goal_progress, idx_posts_deadline).
+var postSortOrders = map[string]string{
+	PostSortNewest: "p.created_at DESC",
+	PostSortUrgent: "(p.urgent AND p.urgent_until > NOW()) IS TRUE DESC, p.created_at DESC",
+	// Больше всего собрано
+	PostSortMostCollected: "p.collected DESC, p.created_at DESC",
+	// Меньше всего собрано, из равных - дольше ждущие
+	PostSortLeastDonated: "p.collected ASC, p.created_at ASC",
+	// Ближе всего к цели по доле собранного, достигшие цели в конце
+	PostSortClosestToGoal: "p.collected >= p.amount, p.collected / p.amount DESC, p.created_at DESC",
+	// Ближайший срок первым, посты без срока в конце
+	PostSortDeadline: "p.deadline ASC NULLS LAST, p.created_at DESC",
+}
+
+// postsFilter собирает условие и сортировку списка постов (таблица posts с псевдонимом p).
+// Если задан ViewerID, исключаются посты, которые он скрыл, и посты скрытых им авторов,
+// кроме запроса постов конкретного автора (UserID). Без поиска и сортировки срочные сборы
+// идут первыми, заданная сортировка важнее релевантности поиска.
+func postsFilter(filter PostFilter) (string, string, []interface{}) {
+	where := "1=1"
+	args := []interface{}{}
+	argPos := 1
+	orderBy := postSortOrders[PostSortUrgent]
+
+	if filter.Status != "" {
+		where += fmt.Sprintf(" AND p.status = $%d", argPos)
+		args = append(args, filter.Status)
+		argPos++
+	}
+	if filter.UserID != nil {
+		where += fmt.Sprintf(" AND p.user_id = $%d", argPos)
+		args = append(args, *filter.UserID)
+		argPos++
+	}
+	if filter.Language != "" {
+		where += fmt.Sprintf(" AND p.language = $%d", argPos)
+		args = append(args, filter.Language)
+		argPos++
+	}
+	if filter.ViewerID != 0 {
+		where += fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM hidden_posts hp WHERE hp.user_id = $%d AND hp.post_id = p.id)", argPos)
+		if filter.UserID == nil {
+			where += fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM muted_authors ma WHERE ma.user_id = $%d AND ma.author_id = p.user_id)", argPos)
+		}
+		args = append(args, filter.ViewerID)
+		argPos++
+	}
+	if filter.DeadlineBefore != nil {
+		where += fmt.Sprintf(" AND p.deadline <= $%d", argPos)
+		args = append(args, *filter.DeadlineBefore)
+		argPos++
+	}
+	if filter.Search != "" {
+		where += fmt.Sprintf(" AND (p.search_vector @@ websearch_to_tsquery('russian', $%d) OR $%d <%% p.title)", argPos, argPos)
+		orderBy = fmt.Sprintf("ts_rank(p.search_vector, websearch_to_tsquery('russian', $%d)) + word_similarity($%d, p.title) DESC, p.created_at DESC", argPos, argPos)
+		args = append(args, filter.Search)
+	}
+	if order, ok := postSortOrders[filter.Sort]; ok {
+		orderBy = order
+	}
+
+	return where, orderBy, args
+}
+
+// UpdatePost обновляет пост
+func (db *DB) UpdatePost(id int64, title, description *string, amount *float64, recipient, bank, phone, language *string) error {
+	updates := []string{}
+	args := []interface{}{}
+	argPos := 1
+
+	if title != nil {
+		updates = append(updates, fmt.Sprintf("title = $%d", argPos))
+		args = append(args, *title)
+		argPos++
+	}
+	if description != nil {
+		updates = append(updates, fmt.Sprintf("description = $%d", argPos))
+		args = append(args, *description)
+		argPos++
+	}
+	if amount != nil {
+		updates = append(updates, fmt.Sprintf("amount = $%d", argPos))
+		args = append(args, *amount)
+		argPos++
+	}
+	if recipient != nil {
+		updates = append(updates, fmt.Sprintf("recipient = $%d", argPos))
+		args = append(args, *recipient)
+		argPos++
+	}
+	if bank != nil {
+		updates = append(updates, fmt.Sprintf("bank = $%d", argPos))
+		args = append(args, *bank)
+		argPos++
+	}
+	if phone != nil {
+		updates = append(updates, fmt.Sprintf("phone = $%d", argPos))
+		args = append(args, *phone)
+		argPos++
+	}
+	if language != nil {
+		updates = append(updates, fmt.Sprintf("language = $%d", argPos))
+		args = append(args, *language)
+		argPos++
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	updates = append(updates, fmt.Sprintf("updated_at = $%d", argPos))
+	args = append(args, time.Now())
+	argPos++
+
+	args = append(args, id)
+	query := fmt.Sprintf("UPDATE posts SET %s WHERE id = $%d", strings.Join(updates, ", "), argPos)
+	_, err := db.Exec(query, args...)
+	return err
+}
+
+// DetectMissingPostLanguages определяет язык постов, созданных до появления
+// определения языка. Возвращает число обработанных постов.
+func (db *DB) DetectMissingPostLanguages(limit int) (int, error) {
+	rows, err := db.Query(`SELECT id, title, description FROM posts WHERE language IS NULL ORDER BY id LIMIT $1`, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	languages := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var title, description string
+		if err := rows.Scan(&id, &title, &description); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		languages[id] = DetectLanguage(title + "\n" + description)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, language := range languages {
+		if _, err := db.Exec(`UPDATE posts SET language = $1 WHERE id = $2`, language, id); err != nil {
+			return 0, fmt.Errorf("failed to set post language: %w", err)
+		}
+	}
+	return len(languages), nil
+}
+
+// SetPostGuardianDocument сохраняет документ законного представителя
+func (db *DB) SetPostGuardianDocument(id int64, documentURL string) error {
+	_, err := db.Exec(`UPDATE posts SET guardian_document_url = $1, updated_at = NOW() WHERE id = $2`, documentURL, id)
+	return err
+}
+
+// SetPostDeadline меняет срок сбора, nil убирает срок
+func (db *DB) SetPostDeadline(id int64, deadline *time.Time) error {
+	_, err := db.Exec(`UPDATE posts SET deadline = $1, updated_at = NOW() WHERE id = $2`, deadline, id)
+	return err
+}
+
+// CloseExpiredPosts закрывает активные посты, срок которых истек
+func (db *DB) CloseExpiredPosts() ([]ExpiredPost, error) {
+	query := `UPDATE posts p SET status = 'closed', updated_at = NOW()
+	          FROM users u
+	          WHERE u.id = p.user_id AND p.status = 'active' AND p.deadline <= NOW()
+	          RETURNING p.id, p.user_id, p.title, p.deadline, u.timezone`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []ExpiredPost
+	for rows.Next() {
+		var p ExpiredPost
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Deadline, &p.Timezone); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// SetCalendarTokenHash сохраняет хеш токена календаря сроков пользователя, nil выключает календарь
+func (db *DB) SetCalendarTokenHash(userID int64, tokenHash *string) error {
+	_, err := db.Exec(`UPDATE users SET calendar_token_hash = $1, updated_at = NOW() WHERE id = $2`, tokenHash, userID)
+	return err
+}
+
+// GetCalendarDeadlines получает сроки сборов автора по хешу токена календаря: посты со сроком,
+// кроме снятых модератором. Аккаунт должен быть активен.
+func (db *DB) GetCalendarDeadlines(tokenHash string) ([]PostDeadline, error) {
+	var userID int64
+	err := db.QueryRow(`SELECT id FROM users WHERE calendar_token_hash = $1 AND is_active = true`, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Календарь")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar owner: %w", err)
+	}
+
+	query := `SELECT id, title, status, deadline, updated_at FROM posts
+	          WHERE user_id = $1 AND deadline IS NOT NULL AND status <> 'moderated'
+	          ORDER BY deadline`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadlines := []PostDeadline{}
+	for rows.Next() {
+		var d PostDeadline
+		if err := rows.Scan(&d.PostID, &d.Title, &d.Status, &d.Deadline, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		deadlines = append(deadlines, d)
+	}
+	return deadlines, rows.Err()
+}
+
+// SetPostUrgentDocument сохраняет документ, подтверждающий срочность сбора
+func (db *DB) SetPostUrgentDocument(id int64, documentURL string) error {
+	_, err := db.Exec(`UPDATE posts SET urgent_document_url = $1, updated_at = NOW() WHERE id = $2`, documentURL, id)
+	return err
+}
+
+// PublishUrgentPost публикует срочный сбор с модерации: до until он показывается первым в ленте
+func (db *DB) PublishUrgentPost(id int64, until time.Time) error {
+	query := `UPDATE posts SET status = 'active', urgent_until = $1, updated_at = NOW() WHERE id = $2 AND urgent`
+	result, err := db.Exec(query, until, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return NewNotFoundError("Пост")
+	}
+	return nil
+}
+
+// GetUrgentModerationPosts получает срочные сборы на модерации, самые старые первыми
+func (db *DB) GetUrgentModerationPosts(page, limit int) ([]Post, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM posts WHERE urgent AND status = 'moderated'`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, user_id, title, description, amount, collected, recipient, bank, phone,
+	                 status, created_at, updated_at, is_editable, beneficiary_is_minor, guardian_document_url,
+	                 COALESCE(language, ''), deadline, urgent, urgent_until, urgent_document_url
+	          FROM posts WHERE urgent AND status = 'moderated'
+	          ORDER BY created_at, id LIMIT $1 OFFSET $2`
+	rows, err := db.Query(query, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		err := rows.Scan(
+			&p.ID, &p.UserID, &p.Title, &p.Description, &p.Amount, &p.Collected,
+			&p.Recipient, &p.Bank, &p.Phone, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable,
+			&p.BeneficiaryIsMinor, &p.GuardianDocumentURL, &p.Language, &p.Deadline, &p.Urgent, &p.UrgentUntil, &p.UrgentDocumentURL,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, total, rows.Err()
+}
+
+// ExpireUrgentPosts снимает отметку срочности с опубликованных сборов, срок которых
+// истек, и со сборов, закрытых или завершенных до истечения срока
+func (db *DB) ExpireUrgentPosts() (int64, error) {
+	query := `UPDATE posts SET urgent = false, updated_at = NOW()
+	          WHERE urgent AND (urgent_until <= NOW() OR status IN ('closed', 'completed'))`
+	result, err := db.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CountOverdueUrgentReviews считает срочные сборы, ожидающие модерации дольше sla
+func (db *DB) CountOverdueUrgentReviews(sla time.Duration) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM posts WHERE urgent AND status = 'moderated' AND created_at <= $1`
+	err := db.QueryRow(query, time.Now().Add(-sla)).Scan(&count)
+	return count, err
+}
+
+// UpdatePostStatus меняет статус поста
+func (db *DB) UpdatePostStatus(id int64, status string) error {
+	result, err := db.Exec(`UPDATE posts SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return NewNotFoundError("Пост")
+	}
+	return nil
+}
+
+// DeletePost удаляет пост
+func (db *DB) DeletePost(id int64) error {
+	query := `DELETE FROM posts WHERE id = $1`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// GetPostObjectURLs возвращает URL файлов, которые удаляются из базы вместе с постом:
+// медиа и их уменьшенные копии, чеки пожертвований и вложения сообщений чатов поста.
+// Результат сгруппирован по bucket.
+func (db *DB) GetPostObjectURLs(postID int64) (map[string][]string, error) {
+	query := `SELECT $2::text, media_url FROM post_media WHERE post_id = $1
+	          UNION ALL
+	          SELECT $2::text, v.url FROM post_media pm,
+	                 LATERAL (VALUES (pm.variants->>'small'), (pm.variants->>'medium'), (pm.variants->>'large')) AS v(url)
+	          WHERE pm.post_id = $1 AND v.url IS NOT NULL AND v.url <> ''
+	          UNION ALL
+	          SELECT $3::text, receipt_url FROM donations WHERE post_id = $1 AND receipt_url IS NOT NULL
+	          UNION ALL
+	          SELECT $4::text, m.attachment_url FROM messages m
+	          JOIN chats c ON c.id = m.chat_id
+	          WHERE c.post_id = $1 AND m.attachment_url IS NOT NULL`
+	rows, err := db.Query(query, postID, BucketPostMedia, BucketDonationReceipts, BucketChatAttachments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post object urls: %w", err)
+	}
+	defer rows.Close()
+
+	urls := make(map[string][]string)
+	for rows.Next() {
+		var bucket, url string
+		if err := rows.Scan(&bucket, &url); err != nil {
+			return nil, err
+		}
+		urls[bucket] = append(urls[bucket], url)
+	}
+	return urls, rows.Err()
+}
+
+// UpdatePostCollected обновляет собранную сумму поста
+func (db *DB) UpdatePostCollected(postID int64, amount float64) error {
+	query := `UPDATE posts SET collected = collected + $1, updated_at = NOW() WHERE id = $2`
+	_, err := db.Exec(query, amount, postID)
+	return err
+}
+
+// RecalculatePostsCollected пересчитывает собранные суммы постов по
+// подтвержденным пожертвованиям и возвращает число исправленных постов
+func (db *DB) RecalculatePostsCollected() (int, error) {
+	query := `UPDATE posts p SET collected = c.total, updated_at = NOW()
+	          FROM (
+	              SELECT p2.id, COALESCE(SUM(d.amount), 0) AS total
+	              FROM posts p2
+	              LEFT JOIN donations d ON d.post_id = p2.id AND d.status = 'confirmed'
+	              GROUP BY p2.id
+	          ) c
+	          WHERE c.id = p.id AND p.collected IS DISTINCT FROM c.total`
+	result, err := db.Exec(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recalculate collected amounts: %w", err)
+	}
+	fixed, err := result.RowsAffected()
+	return int(fixed), err
+}
+
+// ========== Collaborator functions ==========
+
+const collaboratorColumns = `c.id, c.post_id, p.title, u.id, u.first_name, u.last_name, COALESCE(u.photo_variants->>'small', u.photo_url),
+	c.invited_by, c.permissions, c.status, c.created_at, c.responded_at`
+
+func scanCollaborators(rows *sql.Rows) ([]PostCollaborator, error) {
+	defer rows.Close()
+	collaborators := []PostCollaborator{}
+	for rows.Next() {
+		var c PostCollaborator
+		var firstName, lastName string
+		if err := rows.Scan(&c.ID, &c.PostID, &c.PostTitle, &c.User.ID, &firstName, &lastName, &c.User.Avatar,
+			&c.InvitedBy, pq.Array(&c.Permissions), &c.Status, &c.CreatedAt, &c.RespondedAt); err != nil {
+			return nil, err
+		}
+		c.User.Name = strings.TrimSpace(firstName + " " + lastName)
+		collaborators = append(collaborators, c)
+	}
+	return collaborators, rows.Err()
+}
+
+// InviteCollaborator приглашает пользователя в соавторы поста. Отклонившего приглашение
+// можно пригласить снова, приглашенного или принявшего - нет.
+func (db *DB) InviteCollaborator(postID, userID, invitedBy int64, permissions []string) (int64, error) {
+	var id int64
+	query := `INSERT INTO post_collaborators (post_id, user_id, invited_by, permissions) VALUES ($1, $2, $3, $4)
+	          ON CONFLICT (post_id, user_id) DO UPDATE
+	          SET invited_by = EXCLUDED.invited_by, permissions = EXCLUDED.permissions, status = 'pending',
+	              created_at = NOW(), responded_at = NULL
+	          WHERE post_collaborators.status = 'declined'
+	          RETURNING id`
+	err := db.QueryRow(query, postID, userID, invitedBy, pq.Array(permissions)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, NewConflictError("Пользователь уже приглашен в соавторы")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to invite collaborator: %w", err)
+	}
+	return id, nil
+}
+
+// GetPostCollaborators получает соавторов поста и приглашения
+func (db *DB) GetPostCollaborators(postID int64) ([]PostCollaborator, error) {
+	query := `SELECT ` + collaboratorColumns + `
+	          FROM post_collaborators c JOIN posts p ON p.id = c.post_id JOIN users u ON u.id = c.user_id
+	          WHERE c.post_id = $1 ORDER BY c.created_at`
+	rows, err := db.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	return scanCollaborators(rows)
+}
+
+// GetUserCollaborations получает приглашения пользователя и посты, соавтором которых он является
+func (db *DB) GetUserCollaborations(userID int64) ([]PostCollaborator, error) {
+	query := `SELECT ` + collaboratorColumns + `
+	          FROM post_collaborators c JOIN posts p ON p.id = c.post_id JOIN users u ON u.id = c.user_id
+	          WHERE c.user_id = $1 AND c.status <> 'declined' ORDER BY c.created_at DESC`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanCollaborators(rows)
+}
+
+// GetCollaboratorPermissions получает права принявшего приглашение соавтора. Для остальных - nil.
+func (db *DB) GetCollaboratorPermissions(postID, userID int64) ([]string, error) {
+	var permissions []string
+	query := `SELECT permissions FROM post_collaborators WHERE post_id = $1 AND user_id = $2 AND status = 'accepted'`
+	err := db.QueryRow(query, postID, userID).Scan(pq.Array(&permissions))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collaborator permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// GetCollaboratorIDs получает принявших приглашение соавторов поста с правом permission
+func (db *DB) GetCollaboratorIDs(postID int64, permission string) ([]int64, error) {
+	query := `SELECT user_id FROM post_collaborators WHERE post_id = $1 AND status = 'accepted' AND $2 = ANY(permissions)`
+	rows, err := db.Query(query, postID, permission)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UpdateCollaboratorPermissions меняет права соавтора. Возвращает прежние права.
+func (db *DB) UpdateCollaboratorPermissions(postID, userID int64, permissions []string) ([]string, error) {
+	var old []string
+	query := `UPDATE post_collaborators c SET permissions = $3
+	          FROM post_collaborators old WHERE c.post_id = $1 AND c.user_id = $2 AND old.id = c.id
+	          RETURNING old.permissions`
+	err := db.QueryRow(query, postID, userID, pq.Array(permissions)).Scan(pq.Array(&old))
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Соавтор")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update collaborator: %w", err)
+	}
+	return old, nil
+}
+
+// DeleteCollaborator удаляет соавтора или приглашение. Возвращает false, если его не было.
+func (db *DB) DeleteCollaborator(postID, userID int64) (bool, error) {
+	result, err := db.Exec(`DELETE FROM post_collaborators WHERE post_id = $1 AND user_id = $2`, postID, userID)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// RespondCollaboration принимает или отклоняет приглашение пользователя. Возвращает ID поста.
+func (db *DB) RespondCollaboration(id, userID int64, status string) (int64, error) {
+	var postID int64
+	query := `UPDATE post_collaborators SET status = $3, responded_at = NOW()
+	          WHERE id = $1 AND user_id = $2 AND status = 'pending'
+	          RETURNING post_id`
+	err := db.QueryRow(query, id, userID, status).Scan(&postID)
+	if err == sql.ErrNoRows {
+		return 0, NewNotFoundError("Приглашение")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to respond to collaboration: %w", err)
+	}
+	return postID, nil
+}
+
+// ========== PostMedia functions ==========
+
+// CreatePostMedia создает медиа файл для поста
+func (db *DB) CreatePostMedia(postID int64, mediaURL, mediaType string, orderIndex int, size int64, variants *ImageVariants) (*PostMedia, error) {
+	var pm PostMedia
+	query := `INSERT INTO post_media (post_id, media_url, media_type, order_index, size, variants)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING id, post_id, media_url, media_type, order_index, variants, created_at`
+	err := db.QueryRow(query, postID, mediaURL, mediaType, orderIndex, size, variants).Scan(
+		&pm.ID, &pm.PostID, &pm.MediaURL, &pm.MediaType, &pm.OrderIndex, &pm.Variants, &pm.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create post media: %w", err)
+	}
+	return &pm, nil
+}
+
+// GetPostMedia получает все медиа файлы поста
+func (db *DB) GetPostMedia(postID int64) ([]PostMedia, error) {
+	query := `SELECT id, post_id, media_url, media_type, order_index, variants, created_at
+	          FROM post_media WHERE post_id = $1 ORDER BY order_index`
+	rows, err := db.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var media []PostMedia
+	for rows.Next() {
+		var pm PostMedia
+		err := rows.Scan(&pm.ID, &pm.PostID, &pm.MediaURL, &pm.MediaType, &pm.OrderIndex, &pm.Variants, &pm.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, pm)
+	}
+	return media, nil
+}
+
+// GetUserStorageUsed возвращает суммарный размер медиа постов пользователя в байтах
+func (db *DB) GetUserStorageUsed(userID int64) (int64, error) {
+	var used int64
+	query := `SELECT COALESCE(SUM(pm.size), 0)
+	          FROM post_media pm
+	          JOIN posts p ON p.id = pm.post_id
+	          WHERE p.user_id = $1`
+	if err := db.QueryRow(query, userID).Scan(&used); err != nil {
+		return 0, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	return used, nil
+}
+
+// GetPostsMedia получает медиа нескольких постов одним запросом
+func (db *DB) GetPostsMedia(postIDs []int64) (map[int64][]PostMedia, error) {
+	media := make(map[int64][]PostMedia)
+	if len(postIDs) == 0 {
+		return media, nil
+	}
+
+	query := `SELECT id, post_id, media_url, media_type, order_index, variants, created_at
+	          FROM post_media WHERE post_id = ANY($1) ORDER BY post_id, order_index`
+	rows, err := db.Query(query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pm PostMedia
+		err := rows.Scan(&pm.ID, &pm.PostID, &pm.MediaURL, &pm.MediaType, &pm.OrderIndex, &pm.Variants, &pm.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		media[pm.PostID] = append(media[pm.PostID], pm)
+	}
+	return media, rows.Err()
+}
+
+// DeletePostMedia удаляет медиа файл поста и возвращает удаленную запись
+func (db *DB) DeletePostMedia(postID, mediaID int64) (*PostMedia, error) {
+	query := `DELETE FROM post_media WHERE id = $1 AND post_id = $2
+	          RETURNING id, post_id, media_url, media_type, order_index, variants, created_at`
+	var pm PostMedia
+	err := db.QueryRow(query, mediaID, postID).Scan(&pm.ID, &pm.PostID, &pm.MediaURL, &pm.MediaType, &pm.OrderIndex, &pm.Variants, &pm.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Медиа")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete post media: %w", err)
+	}
+	return &pm, nil
+}
+
+// GetPostLineItems получает статьи расходов поста по порядку
+func (db *DB) GetPostLineItems(postID int64) ([]PostLineItem, error) {
+	query := `SELECT id, post_id, title, amount, order_index
+	          FROM post_line_items WHERE post_id = $1 ORDER BY order_index`
+	rows, err := db.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []PostLineItem
+	for rows.Next() {
+		var item PostLineItem
+		if err := rows.Scan(&item.ID, &item.PostID, &item.Title, &item.Amount, &item.OrderIndex); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ReplacePostLineItems заменяет статьи расходов поста. Пустой список удаляет разбивку.
+func (db *DB) ReplacePostLineItems(postID int64, items []LineItemRequest) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM post_line_items WHERE post_id = $1`, postID); err != nil {
+		return fmt.Errorf("failed to reset post line items: %w", err)
+	}
+
+	query := `INSERT INTO post_line_items (post_id, title, amount, order_index) VALUES ($1, $2, $3, $4)`
+	for i, item := range items {
+		if _, err := tx.Exec(query, postID, item.Title, item.Amount, i); err != nil {
+			return fmt.Errorf("failed to save post line item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ========== Comment functions ==========
+
+// Автор комментария показывается так же, как жертвователь: имя помощника, если задано
+const commentColumns = `c.id, c.post_id, u.id, COALESCE(NULLIF(u.helper_name, ''), u.first_name || ' ' || u.last_name),
+	COALESCE(u.photo_variants->>'small', u.photo_url), c.text, c.created_at`
+
+func scanComment(row interface{ Scan(...interface{}) error }, c *PostComment) error {
+	return row.Scan(&c.ID, &c.PostID, &c.Author.ID, &c.Author.Name, &c.Author.Avatar, &c.Text, &c.CreatedAt)
+}
+
+// CreatePostComment создает комментарий к посту
+func (db *DB) CreatePostComment(postID, userID int64, text string) (*PostComment, error) {
+	var id int64
+	query := `INSERT INTO post_comments (post_id, user_id, text) VALUES ($1, $2, $3) RETURNING id`
+	if err := db.QueryRow(query, postID, userID, text).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+	return db.GetPostComment(id)
+}
+
+// GetPostComment получает комментарий с автором
+func (db *DB) GetPostComment(id int64) (*PostComment, error) {
+	var c PostComment
+	query := `SELECT ` + commentColumns + ` FROM post_comments c JOIN users u ON u.id = c.user_id WHERE c.id = $1`
+	err := scanComment(db.QueryRow(query, id), &c)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Комментарий")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return &c, nil
+}
+
+// GetPostComments получает комментарии поста с авторами, новые первыми
+func (db *DB) GetPostComments(postID int64, page, limit int) ([]PostComment, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM post_comments WHERE post_id = $1`, postID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + commentColumns + ` FROM post_comments c JOIN users u ON u.id = c.user_id
+	          WHERE c.post_id = $1 ORDER BY c.created_at DESC, c.id DESC LIMIT $2 OFFSET $3`
+	rows, err := db.Query(query, postID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	comments := []PostComment{}
+	for rows.Next() {
+		var c PostComment
+		if err := scanComment(rows, &c); err != nil {
+			return nil, 0, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, total, rows.Err()
+}
+
+// DeletePostComment удаляет комментарий
+func (db *DB) DeletePostComment(id int64) error {
+	_, err := db.Exec(`DELETE FROM post_comments WHERE id = $1`, id)
+	return err
+}
+
+// ========== Question functions ==========
+
+// Автор вопроса показывается так же, как автор комментария
+const questionColumns = `q.id, q.post_id, u.id, COALESCE(NULLIF(u.helper_name, ''), u.first_name || ' ' || u.last_name),
+	COALESCE(u.photo_variants->>'small', u.photo_url), q.question, q.answer, q.answered_at, q.created_at`
+
+func scanQuestion(row interface{ Scan(...interface{}) error }, q *PostQuestion) error {
+	return row.Scan(&q.ID, &q.PostID, &q.Author.ID, &q.Author.Name, &q.Author.Avatar, &q.Question, &q.Answer, &q.AnsweredAt, &q.CreatedAt)
+}
+
+// CreatePostQuestion сохраняет вопрос к автору поста
+func (db *DB) CreatePostQuestion(postID, userID int64, question string) (*PostQuestion, error) {
+	var id int64
+	query := `INSERT INTO post_questions (post_id, user_id, question) VALUES ($1, $2, $3) RETURNING id`
+	if err := db.QueryRow(query, postID, userID, question).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create question: %w", err)
+	}
+	return db.GetPostQuestion(id)
+}
+
+// GetPostQuestion получает вопрос с автором
+func (db *DB) GetPostQuestion(id int64) (*PostQuestion, error) {
+	var q PostQuestion
+	query := `SELECT ` + questionColumns + ` FROM post_questions q JOIN users u ON u.id = q.user_id WHERE q.id = $1`
+	err := scanQuestion(db.QueryRow(query, id), &q)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Вопрос")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question: %w", err)
+	}
+	return &q, nil
+}
+
+// GetPostQuestions получает вопросы поста: с ответом - последние отвеченные первыми,
+// без ответа - в порядке поступления
+func (db *DB) GetPostQuestions(postID int64, answered bool, page, limit int) ([]PostQuestion, int, error) {
+	where, orderBy := "q.answer IS NOT NULL", "q.answered_at DESC, q.id DESC"
+	if !answered {
+		where, orderBy = "q.answer IS NULL", "q.created_at, q.id"
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM post_questions q WHERE q.post_id = $1 AND ` + where
+	if err := db.QueryRow(countQuery, postID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + questionColumns + ` FROM post_questions q JOIN users u ON u.id = q.user_id
+	          WHERE q.post_id = $1 AND ` + where + ` ORDER BY ` + orderBy + ` LIMIT $2 OFFSET $3`
+	rows, err := db.Query(query, postID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	questions := []PostQuestion{}
+	for rows.Next() {
+		var q PostQuestion
+		if err := scanQuestion(rows, &q); err != nil {
+			return nil, 0, err
+		}
+		questions = append(questions, q)
+	}
+	return questions, total, rows.Err()
+}
+
+// CountPostQuestions считает вопросы поста с ответом и без
+func (db *DB) CountPostQuestions(postID int64) (answered, unanswered int, err error) {
+	query := `SELECT COUNT(*) FILTER (WHERE answer IS NOT NULL), COUNT(*) FILTER (WHERE answer IS NULL)
+	          FROM post_questions WHERE post_id = $1`
+	err = db.QueryRow(query, postID).Scan(&answered, &unanswered)
+	return answered, unanswered, err
+}
+
+// AnswerPostQuestion сохраняет ответ на вопрос; повторный ответ заменяет прежний,
+// время ответа не меняется
+func (db *DB) AnswerPostQuestion(id, answeredBy int64, answer string) (*PostQuestion, error) {
+	query := `UPDATE post_questions SET answer = $2, answered_by = $3, answered_at = COALESCE(answered_at, NOW()) WHERE id = $1`
+	if _, err := db.Exec(query, id, answer, answeredBy); err != nil {
+		return nil, fmt.Errorf("failed to answer question: %w", err)
+	}
+	return db.GetPostQuestion(id)
+}
+
+// DeletePostQuestion удаляет вопрос вместе с ответом
+func (db *DB) DeletePostQuestion(id int64) error {
+	_, err := db.Exec(`DELETE FROM post_questions WHERE id = $1`, id)
+	return err
+}
+
+// ========== Favorite functions ==========
+
+// AddFavorite добавляет пост в избранное пользователя, повторное добавление ничего не меняет
+func (db *DB) AddFavorite(userID, postID int64) error {
+	query := `INSERT INTO favorites (user_id, post_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	_, err := db.Exec(query, userID, postID)
+	return err
+}
+
+// RemoveFavorite убирает пост из избранного пользователя
+func (db *DB) RemoveFavorite(userID, postID int64) error {
+	_, err := db.Exec(`DELETE FROM favorites WHERE user_id = $1 AND post_id = $2`, userID, postID)
+	return err
+}
+
+// GetFavoritePosts получает избранные посты пользователя с авторами и медиа,
+// последние добавленные первыми
+func (db *DB) GetFavoritePosts(userID int64, page, limit int) ([]PostWithDetails, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM favorites WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + postDetailsColumns + ` FROM favorites f JOIN posts p ON p.id = f.post_id ` + postDetailsJoins + `
+	          WHERE f.user_id = $1 ORDER BY f.created_at DESC, p.id DESC LIMIT $2 OFFSET $3`
+	posts, err := db.queryPostsWithDetails(query, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// ========== Feed preference functions ==========
+
+// HidePost скрывает пост из ленты пользователя, повторное скрытие ничего не меняет
+func (db *DB) HidePost(userID, postID int64) error {
+	_, err := db.Exec(`INSERT INTO hidden_posts (user_id, post_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, postID)
+	return err
+}
+
+// UnhidePost возвращает пост в ленту пользователя
+func (db *DB) UnhidePost(userID, postID int64) error {
+	_, err := db.Exec(`DELETE FROM hidden_posts WHERE user_id = $1 AND post_id = $2`, userID, postID)
+	return err
+}
+
+// GetHiddenPosts получает скрытые пользователем посты, последние первыми
+func (db *DB) GetHiddenPosts(userID int64) ([]HiddenPost, error) {
+	query := `SELECT p.id, p.title, hp.created_at FROM hidden_posts hp JOIN posts p ON p.id = hp.post_id
+	          WHERE hp.user_id = $1 ORDER BY hp.created_at DESC`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []HiddenPost{}
+	for rows.Next() {
+		var p HiddenPost
+		if err := rows.Scan(&p.PostID, &p.Title, &p.HiddenAt); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// MuteAuthor скрывает посты автора из ленты пользователя, повторное скрытие ничего не меняет
+func (db *DB) MuteAuthor(userID, authorID int64) error {
+	_, err := db.Exec(`INSERT INTO muted_authors (user_id, author_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, authorID)
+	return err
+}
+
+// UnmuteAuthor возвращает посты автора в ленту пользователя
+func (db *DB) UnmuteAuthor(userID, authorID int64) error {
+	_, err := db.Exec(`DELETE FROM muted_authors WHERE user_id = $1 AND author_id = $2`, userID, authorID)
+	return err
+}
+
+// GetMutedAuthors получает скрытых пользователем авторов, последних первыми
+func (db *DB) GetMutedAuthors(userID int64) ([]MutedAuthor, error) {
+	query := `SELECT u.id, u.first_name, u.last_name, COALESCE(u.photo_variants->>'small', u.photo_url), ma.created_at
+	          FROM muted_authors ma JOIN users u ON u.id = ma.author_id
+	          WHERE ma.user_id = $1 ORDER BY ma.created_at DESC`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authors := []MutedAuthor{}
+	for rows.Next() {
+		var m MutedAuthor
+		var firstName, lastName string
+		if err := rows.Scan(&m.Author.ID, &firstName, &lastName, &m.Author.Avatar, &m.MutedAt); err != nil {
+			return nil, err
+		}
+		m.Author.Name = strings.TrimSpace(firstName + " " + lastName)
+		authors = append(authors, m)
+	}
+	return authors, rows.Err()
+}
+
+// ========== Donation functions ==========
+
+// CreateDonation создает пожертвование и событие created
+func (db *DB) CreateDonation(d *Donation) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		return createDonation(tx, d)
+	})
+}
+
+// CreateDonationWithReceipt создает пожертвование вместе с чеком: upload загружает чек
+// под ID нового пожертвования и возвращает ссылку на него. Если загрузка не удалась,
+// пожертвование не создается, и повтор запроса с тем же ключом идемпотентности не
+// оставляет дубликата.
+func (db *DB) CreateDonationWithReceipt(d *Donation, upload func(donationID int64) (string, error)) error {
+	return db.WithTx(func(tx *sql.Tx) error {
+		if err := createDonation(tx, d); err != nil {
+			return err
+		}
+		receiptURL, err := upload(d.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE donations SET receipt_url = $1 WHERE id = $2`, receiptURL, d.ID); err != nil {
+			return err
+		}
+		d.ReceiptURL = &receiptURL
+		metadata := map[string]interface{}{"receipt_url": receiptURL}
+		return insertDonationEvent(tx, d.ID, DonationEventReceiptAttached, d.DonorID, metadata)
+	})
+}
+
+// createDonation выполняет создание пожертвования внутри транзакции tx
+func createDonation(tx *sql.Tx, d *Donation) error {
+	query := `INSERT INTO donations (post_id, donor_id, amount, receipt_url, is_anonymous)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING id, status, created_at`
+	err := tx.QueryRow(query, d.PostID, d.DonorID, d.Amount, d.ReceiptURL, d.IsAnonymous).Scan(
+		&d.ID, &d.Status, &d.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{"post_id": d.PostID, "amount": d.Amount}
+	if d.IsAnonymous {
+		metadata["is_anonymous"] = true
+	}
+	return insertDonationEvent(tx, d.ID, DonationEventCreated, d.DonorID, metadata)
+}
+
+// GetDonationByID получает пожертвование по ID
+func (db *DB) GetDonationByID(id int64) (*Donation, error) {
+	var d Donation
+	query := `SELECT id, post_id, donor_id, amount, receipt_url, status, confirmed_at, confirmed_by, created_at,
+	                 receipt_check, receipt_amount, receipt_date, receipt_checked_at, is_anonymous
+	          FROM donations WHERE id = $1`
+	err := db.QueryRow(query, id).Scan(
+		&d.ID, &d.PostID, &d.DonorID, &d.Amount, &d.ReceiptURL,
+		&d.Status, &d.ConfirmedAt, &d.ConfirmedBy, &d.CreatedAt,
+		&d.ReceiptCheck, &d.ReceiptAmount, &d.ReceiptDate, &d.ReceiptCheckedAt, &d.IsAnonymous,
+	)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Пожертвование")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get donation: %w", err)
+	}
+	return &d, nil
+}
+
+// GetDonations получает список пожертвований с фильтрацией. excludeAnonymous убирает
+// анонимные пожертвования: иначе фильтр по донору раскрывал бы их автора.
+func (db *DB) GetDonations(postID, donorID *int64, status string, excludeAnonymous bool, page, limit int) ([]Donation, int, error) {
+	where := "1=1"
+	args := []interface{}{}
+	argPos := 1
+
+	if postID != nil {
+		where += fmt.Sprintf(" AND post_id = $%d", argPos)
+		args = append(args, *postID)
+		argPos++
+	}
+	if donorID != nil {
+		where += fmt.Sprintf(" AND donor_id = $%d", argPos)
+		args = append(args, *donorID)
+		argPos++
+	}
+	if status != "" {
+		where += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, status)
+		argPos++
+	}
+	if excludeAnonymous {
+		where += " AND NOT is_anonymous"
+	}
+
+	// Подсчет общего количества
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM donations WHERE %s", where)
+	err := db.QueryRow(countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Получение данных
+	offset := (page - 1) * limit
+	query := fmt.Sprintf(`SELECT id, post_id, donor_id, amount, receipt_url, status, confirmed_at, confirmed_by, created_at,
+	                             receipt_check, receipt_amount, receipt_date, receipt_checked_at, is_anonymous
+	                      FROM donations WHERE %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`,
+		where, argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var donations []Donation
+	for rows.Next() {
+		var d Donation
+		err := rows.Scan(
+			&d.ID, &d.PostID, &d.DonorID, &d.Amount, &d.ReceiptURL,
+			&d.Status, &d.ConfirmedAt, &d.ConfirmedBy, &d.CreatedAt,
+			&d.ReceiptCheck, &d.ReceiptAmount, &d.ReceiptDate, &d.ReceiptCheckedAt, &d.IsAnonymous,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		donations = append(donations, d)
+	}
+
+	return donations, total, nil
+}
+
+// QueueReceiptCheck ставит загруженный чек в очередь на сверку
+func (db *DB) QueueReceiptCheck(id int64) error {
+	query := `UPDATE donations SET receipt_check = 'pending', receipt_checked_at = NULL WHERE id = $1`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// GetDonationsPendingReceiptCheck возвращает пожертвования, чеки которых ожидают сверки
+func (db *DB) GetDonationsPendingReceiptCheck(limit int) ([]Donation, error) {
+	query := `SELECT id, amount, created_at FROM donations
+	          WHERE receipt_check = 'pending' ORDER BY created_at LIMIT $1`
+	rows, err := db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var donations []Donation
+	for rows.Next() {
+		var d Donation
+		if err := rows.Scan(&d.ID, &d.Amount, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		donations = append(donations, d)
+	}
+	return donations, rows.Err()
+}
+
+// SaveReceiptCheck сохраняет результат сверки чека
+func (db *DB) SaveReceiptCheck(id int64, result ReceiptCheckResult) error {
+	query := `UPDATE donations
+	          SET receipt_check = $2, receipt_amount = $3, receipt_date = $4, receipt_checked_at = NOW()
+	          WHERE id = $1`
+	_, err := db.Exec(query, id, result.Status, result.Amount, result.Date)
+	return err
+}
+
+// RejectDonation отклоняет пожертвование. Отклонение подтвержденного пожертвования
+// (событие reverted) в той же транзакции уменьшает собранную сумму поста и рейтинг
+// донора, реферальный бонус не отзывается. Возвращает записанное событие или пустую
+// строку, если пожертвование уже было отклонено.
+func (db *DB) RejectDonation(id, rejectedBy int64) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var postID, donorID int64
+	var amount float64
+	var status string
+	var points sql.NullInt64
+	query := `SELECT post_id, donor_id, amount, status, rating_points FROM donations WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRow(query, id).Scan(&postID, &donorID, &amount, &status, &points)
+	if err == sql.ErrNoRows {
+		return "", NewNotFoundError("Пожертвование")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get donation: %w", err)
+	}
+	if status == "rejected" {
+		return "", nil
+	}
+	if status == "refunded" {
+		return "", NewConflictError("Пожертвование уже возвращено")
+	}
+
+	query = `UPDATE donations SET status = 'rejected', confirmed_at = NOW(), confirmed_by = $1 WHERE id = $2`
+	if _, err := tx.Exec(query, rejectedBy, id); err != nil {
+		return "", fmt.Errorf("failed to reject donation: %w", err)
+	}
+
+	event := DonationEventRejected
+	if status == "confirmed" {
+		event = DonationEventReverted
+		if err := revertConfirmedDonation(tx, id, postID, donorID, amount, points); err != nil {
+			return "", err
+		}
+	}
+
+	if err := insertDonationEvent(tx, id, event, rejectedBy, map[string]interface{}{"previous_status": status}); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
+
+// ConfirmDonation подтверждает пожертвование и в той же транзакции увеличивает
+// собранную сумму поста и рейтинг донора (с множителем акции, действовавшей при
+// создании пожертвования, см. pointsMultiplier), а за первое пожертвование приглашенного
+// пользователя начисляет реферальный бонус referralBonus (см. rewardReferral).
+// metadata сохраняется в событии confirmed. Возвращает false, если пожертвование
+// уже было подтверждено (повторно суммы не начисляются), и приглашение, если по
+// нему начислен бонус.
+func (db *DB) ConfirmDonation(id, confirmedBy int64, referralBonus int, metadata map[string]interface{}) (bool, *Referral, error) {
+	var confirmed bool
+	var referral *Referral
+	err := db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		confirmed, referral, err = db.confirmDonation(tx, id, confirmedBy, referralBonus, metadata)
+		return err
+	})
+	return confirmed, referral, err
+}
+
+// confirmDonation выполняет подтверждение внутри транзакции tx
+func (db *DB) confirmDonation(tx *sql.Tx, id, confirmedBy int64, referralBonus int, metadata map[string]interface{}) (bool, *Referral, error) {
+	var postID, donorID int64
+	var amount float64
+	var status string
+	var createdAt time.Time
+	query := `SELECT post_id, donor_id, amount, status, created_at FROM donations WHERE id = $1 FOR UPDATE`
+	err := tx.QueryRow(query, id).Scan(&postID, &donorID, &amount, &status, &createdAt)
+	if err == sql.ErrNoRows {
+		return false, nil, NewNotFoundError("Пожертвование")
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get donation: %w", err)
+	}
+	if status == "confirmed" {
+		return false, nil, nil
+	}
+	if status == "refunded" {
+		return false, nil, NewConflictError("Пожертвование возвращено")
+	}
+
+	// POINTS_PER_CURRENCY_UNIT баллов за рубль, во время акции - с ее множителем
+	multiplier, err := pointsMultiplier(tx, createdAt)
+	if err != nil {
+		return false, nil, err
+	}
+	points := db.money.Points(amount, multiplier)
+
+	query = `UPDATE donations SET status = 'confirmed', confirmed_at = NOW(), confirmed_by = NULLIF($1, 0), rating_points = $2 WHERE id = $3`
+	if _, err := tx.Exec(query, confirmedBy, points, id); err != nil {
+		return false, nil, fmt.Errorf("failed to confirm donation: %w", err)
+	}
+
+	query = `UPDATE posts SET collected = collected + $1, updated_at = NOW() WHERE id = $2`
+	if _, err := tx.Exec(query, amount, postID); err != nil {
+		return false, nil, fmt.Errorf("failed to update collected amount: %w", err)
+	}
+
+	if err := addRatingEvent(tx, RatingEvent{UserID: donorID, Source: RatingEventDonation, DonationID: &id, Points: points, Amount: amount}); err != nil {
+		return false, nil, err
+	}
+
+	referral, err := rewardReferral(tx, donorID, id, referralBonus)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["previous_status"] = status
+	metadata["rating_points"] = points
+	if multiplier > 1 {
+		metadata["points_multiplier"] = multiplier
+	}
+	if referral != nil {
+		metadata["referral_id"] = referral.ID
+	}
+	if err := insertDonationEvent(tx, id, DonationEventConfirmed, confirmedBy, metadata); err != nil {
+		return false, nil, err
+	}
+	return true, referral, nil
+}
+
+// RefundDonation отменяет пожертвование (статус refunded). Возврат подтвержденного
+// пожертвования в той же транзакции уменьшает собранную сумму поста и рейтинг донора,
+// как при отклонении, реферальный бонус не отзывается. Пожертвование с незавершенной или
+// успешной оплатой картой не возвращается. pendingOnly - отмена донором: только ожидающего
+// подтверждения пожертвования.
+// Возвращает прежний статус или пустую строку, если пожертвование уже возвращено.
+func (db *DB) RefundDonation(id, actorID int64, pendingOnly bool) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var postID, donorID int64
+	var amount float64
+	var status string
+	var points sql.NullInt64
+	query := `SELECT post_id, donor_id, amount, status, rating_points FROM donations WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRow(query, id).Scan(&postID, &donorID, &amount, &status, &points)
+	if err == sql.ErrNoRows {
+		return "", NewNotFoundError("Пожертвование")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get donation: %w", err)
+	}
+	if status == "refunded" {
+		return "", nil
+	}
+	if pendingOnly && status != "pending" {
+		return "", NewConflictError("Отменить можно только пожертвование, ожидающее подтверждения")
+	}
+
+	// Оплата, начатая до отмены, подтвердила бы уже отмененное пожертвование, а списанные
+	// картой деньги провайдеру не возвращаются: такой возврат отклоняется
+	var paying, paid bool
+	query = `SELECT COALESCE(bool_or(status IN ('new', 'pending')), false), COALESCE(bool_or(status = 'succeeded'), false)
+	         FROM payments WHERE donation_id = $1`
+	if err := tx.QueryRow(query, id).Scan(&paying, &paid); err != nil {
+		return "", fmt.Errorf("failed to check payments: %w", err)
+	}
+	if paying {
+		return "", NewConflictError("Пожертвование оплачивается картой, отменить его можно после завершения оплаты")
+	}
+	if paid {
+		return "", NewConflictError("Пожертвование оплачено картой, деньги возвращаются через платежного провайдера")
+	}
+
+	query = `UPDATE donations SET status = 'refunded', confirmed_at = NOW(), confirmed_by = $1 WHERE id = $2`
+	if _, err := tx.Exec(query, actorID, id); err != nil {
+		return "", fmt.Errorf("failed to refund donation: %w", err)
+	}
+	if status == "confirmed" {
+		if err := revertConfirmedDonation(tx, id, postID, donorID, amount, points); err != nil {
+			return "", err
+		}
+	}
+
+	if err := insertDonationEvent(tx, id, DonationEventRefunded, actorID, map[string]interface{}{"previous_status": status}); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return status, nil
+}
+
+// revertConfirmedDonation уменьшает собранную сумму поста и рейтинг донора на
+// подтвержденное пожертвование при его отклонении или возврате. Реферальный бонус,
+// начисленный за это пожертвование, тоже списывается.
+func revertConfirmedDonation(tx *sql.Tx, donationID, postID, donorID int64, amount float64, points sql.NullInt64) error {
+	query := `UPDATE posts SET collected = GREATEST(collected - $1, 0), updated_at = NOW() WHERE id = $2`
+	if _, err := tx.Exec(query, amount, postID); err != nil {
+		return fmt.Errorf("failed to update collected amount: %w", err)
+	}
+	// Пожертвования, подтвержденные до учета акций, принесли по баллу за рубль
+	if !points.Valid {
+		points.Int64 = int64(amount)
+	}
+	err := addRatingEvent(tx, RatingEvent{
+		UserID:     donorID,
+		Source:     RatingEventDonationReversal,
+		DonationID: &donationID,
+		Points:     -int(points.Int64),
+		Amount:     -amount,
+	})
+	if err != nil {
+		return err
+	}
+	return revertReferralReward(tx, donationID)
+}
+
+// revertReferralReward списывает реферальный бонус, начисленный за пожертвование, у
+// приглашенного и пригласившего. Приглашение снова ждет первого пожертвования.
+func revertReferralReward(tx *sql.Tx, donationID int64) error {
+	var r Referral
+	query := `UPDATE referrals SET status = $3, bonus_points = 0, donation_id = NULL, rewarded_at = NULL
+	          FROM (SELECT id, bonus_points FROM referrals WHERE donation_id = $1 AND status = $2 FOR UPDATE) old
+	          WHERE referrals.id = old.id
+	          RETURNING referrals.id, referrals.referrer_id, referrals.referee_id, old.bonus_points`
+	err := tx.QueryRow(query, donationID, ReferralRewarded, ReferralPending).Scan(&r.ID, &r.ReferrerID, &r.RefereeID, &r.BonusPoints)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revert referral: %w", err)
+	}
+	if r.BonusPoints == 0 {
+		return nil
+	}
+	for _, userID := range []int64{r.ReferrerID, r.RefereeID} {
+		err := addRatingEvent(tx, RatingEvent{UserID: userID, Source: RatingEventReferralReversal, ReferralID: &r.ID, Points: -r.BonusPoints})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRatingEvent записывает изменение баллов в журнал и пересчитывает рейтинг
+// пользователя по журналу. Строка рейтинга блокируется до записи, поэтому
+// параллельные изменения баллов одного пользователя выполняются по очереди и
+// каждое видит записи предыдущих.
+func addRatingEvent(tx *sql.Tx, e RatingEvent) error {
+	query := `INSERT INTO ratings (user_id) VALUES ($1)
+	          ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW()`
+	if _, err := tx.Exec(query, e.UserID); err != nil {
+		return fmt.Errorf("failed to lock rating: %w", err)
+	}
+
+	query = `INSERT INTO rating_events (user_id, source, donation_id, referral_id, points_delta, amount_delta)
+	         VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.Exec(query, e.UserID, e.Source, e.DonationID, e.ReferralID, e.Points, e.Amount); err != nil {
+		return fmt.Errorf("failed to add rating event: %w", err)
+	}
+
+	var total int
+	query = `UPDATE ratings r
+	         SET points = GREATEST(t.points, 0), total_donated = GREATEST(t.total_donated, 0), updated_at = NOW()
+	         FROM (SELECT COALESCE(SUM(points_delta), 0) AS points, COALESCE(SUM(amount_delta), 0) AS total_donated
+	               FROM rating_events WHERE user_id = $1) t
+	         WHERE r.user_id = $1
+	         RETURNING r.points`
+	if err := tx.QueryRow(query, e.UserID).Scan(&total); err != nil {
+		return fmt.Errorf("failed to update rating: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE ratings SET status = $1 WHERE user_id = $2`, ratingStatus(total), e.UserID); err != nil {
+		return fmt.Errorf("failed to update rating status: %w", err)
+	}
+	return nil
+}
+
+// pointsMultiplier возвращает множитель баллов для пожертвования, созданного в at:
+// наибольший среди акций, действовавших в этот момент, или 1
+func pointsMultiplier(tx *sql.Tx, at time.Time) (float64, error) {
+	var multiplier float64
+	query := `SELECT COALESCE(MAX(multiplier), 1) FROM points_events WHERE starts_at <= $1 AND ends_at > $1`
+	if err := tx.QueryRow(query, at).Scan(&multiplier); err != nil {
+		return 0, fmt.Errorf("failed to get points multiplier: %w", err)
+	}
+	return multiplier, nil
+}
+
+// insertDonationEvent записывает событие пожертвования в транзакции изменения
+func insertDonationEvent(tx *sql.Tx, donationID int64, eventType string, actorID int64, metadata map[string]interface{}) error {
+	var value *string
+	if len(metadata) > 0 {
+		var err error
+		if value, err = auditValue(metadata); err != nil {
+			return err
+		}
+	}
+	query := `INSERT INTO donation_events (donation_id, event_type, actor_id, metadata) VALUES ($1, $2, NULLIF($3, 0), $4)`
+	if _, err := tx.Exec(query, donationID, eventType, actorID, value); err != nil {
+		return fmt.Errorf("failed to record donation event: %w", err)
+	}
+	return nil
+}
+
+// GetDonationEvents получает события пожертвования в порядке записи
+func (db *DB) GetDonationEvents(donationID int64) ([]DonationEvent, error) {
+	query := `SELECT id, donation_id, event_type, actor_id, metadata, created_at
+	          FROM donation_events WHERE donation_id = $1 ORDER BY id`
+	rows, err := db.Query(query, donationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []DonationEvent{}
+	for rows.Next() {
+		var e DonationEvent
+		var metadata []byte
+		if err := rows.Scan(&e.ID, &e.DonationID, &e.Type, &e.ActorID, &metadata, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Metadata = metadata
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// donationStatusFromEvents восстанавливает статус пожертвования по его событиям.
+// Пустая строка - событий нет.
+func donationStatusFromEvents(events []DonationEvent) string {
+	status := ""
+	for _, e := range events {
+		switch e.Type {
+		case DonationEventCreated:
+			status = "pending"
+		case DonationEventConfirmed:
+			status = "confirmed"
+		case DonationEventRejected, DonationEventReverted:
+			status = "rejected"
+		case DonationEventRefunded:
+			status = "refunded"
+		}
+	}
+	return status
+}
+
+// GetPostDonorIDs возвращает ID всех доноров поста с подтвержденными пожертвованиями
+func (db *DB) GetPostDonorIDs(postID int64) ([]int64, error) {
+	query := `SELECT DISTINCT donor_id FROM donations WHERE post_id = $1 AND status = 'confirmed'`
+	rows, err := db.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetPostDonationStats считает статистику пожертвований поста: итоги по статусам, подтвержденные
+// суммы за последние days дней (дни без пожертвований - с нулями) и top доноров
+func (db *DB) GetPostDonationStats(post *Post, days, top int) (*DonationStatsResponse, error) {
+	stats := &DonationStatsResponse{
+		PostID:    post.ID,
+		Goal:      post.Amount,
+		Collected: post.Collected,
+		Daily:     []DonationDayStats{},
+		TopDonors: []TopDonor{},
+	}
+
+	if err := db.QueryRow(`SELECT timezone FROM users WHERE id = $1`, post.UserID).Scan(&stats.Timezone); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+		stats.Timezone = DefaultTimezone
+	}
+
+	t := &stats.Totals
+	query := `SELECT COALESCE(SUM(amount) FILTER (WHERE status = 'confirmed'), 0),
+	                 COUNT(*) FILTER (WHERE status = 'confirmed'),
+	                 COALESCE(SUM(amount) FILTER (WHERE status = 'pending'), 0),
+	                 COUNT(*) FILTER (WHERE status = 'pending'),
+	                 COUNT(*) FILTER (WHERE status = 'rejected'),
+	                 COUNT(*) FILTER (WHERE status = 'refunded'),
+	                 COUNT(DISTINCT donor_id) FILTER (WHERE status = 'confirmed'),
+	                 COALESCE(ROUND(AVG(amount) FILTER (WHERE status = 'confirmed'), 2), 0),
+	                 COALESCE(SUM(amount) FILTER (WHERE status = 'confirmed' AND is_anonymous), 0)
+	          FROM donations WHERE post_id = $1`
+	err := db.QueryRow(query, post.ID).Scan(&t.ConfirmedAmount, &t.ConfirmedCount, &t.PendingAmount, &t.PendingCount,
+		&t.RejectedCount, &t.RefundedCount, &t.UniqueDonors, &t.AverageAmount, &t.AnonymousAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get donation totals: %w", err)
+	}
+
+	query = `SELECT to_char(day, 'YYYY-MM-DD'), COALESCE(SUM(d.amount), 0), COUNT(d.id)
+	         FROM generate_series((NOW() AT TIME ZONE $2)::date - ($3::int - 1), (NOW() AT TIME ZONE $2)::date, INTERVAL '1 day') AS day
+	         LEFT JOIN donations d ON d.post_id = $1 AND d.status = 'confirmed'
+	              AND (COALESCE(d.confirmed_at, d.created_at) AT TIME ZONE $2)::date = day::date
+	         GROUP BY day ORDER BY day`
+	rows, err := db.Query(query, post.ID, stats.Timezone, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily donations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day DonationDayStats
+		if err := rows.Scan(&day.Date, &day.Amount, &day.Count); err != nil {
+			return nil, err
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT u.id, COALESCE(NULLIF(u.helper_name, ''), u.first_name || ' ' || u.last_name), u.photo_url,
+	                SUM(d.amount), COUNT(*)
+	         FROM donations d JOIN users u ON u.id = d.donor_id
+	         WHERE d.post_id = $1 AND d.status = 'confirmed' AND NOT d.is_anonymous
+	         GROUP BY u.id ORDER BY SUM(d.amount) DESC, MIN(d.created_at) LIMIT $2`
+	donorRows, err := db.Query(query, post.ID, top)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top donors: %w", err)
+	}
+	defer donorRows.Close()
+	for donorRows.Next() {
+		var donor TopDonor
+		err := donorRows.Scan(&donor.Donor.ID, &donor.Donor.Name, &donor.Donor.Avatar, &donor.Amount, &donor.Count)
+		if err != nil {
+			return nil, err
+		}
+		stats.TopDonors = append(stats.TopDonors, donor)
+	}
+	return stats, donorRows.Err()
+}
+
+// GetUserDonationSummary подсчитывает пожертвования, сделанные пользователем и полученные его постами
+func (db *DB) GetUserDonationSummary(userID int64) (given, received DonationSummary, err error) {
+	query := `SELECT COUNT(*), COUNT(*) FILTER (WHERE status = 'confirmed'),
+	                 COALESCE(SUM(amount) FILTER (WHERE status = 'confirmed'), 0)
+	          FROM donations WHERE donor_id = $1`
+	if err = db.QueryRow(query, userID).Scan(&given.Count, &given.ConfirmedCount, &given.ConfirmedAmount); err != nil {
+		return
+	}
+
+	query = `SELECT COUNT(*), COUNT(*) FILTER (WHERE d.status = 'confirmed'),
+	                COALESCE(SUM(d.amount) FILTER (WHERE d.status = 'confirmed'), 0)
+	         FROM donations d JOIN posts p ON p.id = d.post_id WHERE p.user_id = $1`
+	err = db.QueryRow(query, userID).Scan(&received.Count, &received.ConfirmedCount, &received.ConfirmedAmount)
+	return
+}
+
+// ========== Payment functions ==========
+
+const paymentColumns = `id, donation_id, provider, provider_payment_id, amount, status, confirmation_url, created_at, updated_at`
+
+func scanPayment(row interface{ Scan(...interface{}) error }) (*Payment, error) {
+	var p Payment
+	err := row.Scan(&p.ID, &p.DonationID, &p.Provider, &p.ProviderPaymentID, &p.Amount, &p.Status, &p.ConfirmationURL, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// OpenPayment возвращает незавершенный платеж пожертвования, создавая его при необходимости
+func (db *DB) OpenPayment(donationID int64, provider string, amount float64) (*Payment, error) {
+	query := `INSERT INTO payments (donation_id, provider, amount) VALUES ($1, $2, $3)
+	          ON CONFLICT (donation_id) WHERE status IN ('new', 'pending') DO NOTHING`
+	if _, err := db.Exec(query, donationID, provider, amount); err != nil {
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+	query = `SELECT ` + paymentColumns + ` FROM payments WHERE donation_id = $1 AND status IN ('new', 'pending')`
+	payment, err := scanPayment(db.QueryRow(query, donationID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// SetPaymentCreated сохраняет платеж, созданный у провайдера
+func (db *DB) SetPaymentCreated(id int64, providerPaymentID, status, confirmationURL string) (*Payment, error) {
+	query := `UPDATE payments SET provider_payment_id = $2, status = $3, confirmation_url = NULLIF($4, ''), updated_at = NOW()
+	          WHERE id = $1 RETURNING ` + paymentColumns
+	payment, err := scanPayment(db.QueryRow(query, id, providerPaymentID, status, confirmationURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+	return payment, nil
+}
+
+// UpdatePaymentStatus записывает статус из уведомления провайдера. Завершенный платеж
+// не меняется, поэтому повторное уведомление возвращает changed = false.
+func (db *DB) UpdatePaymentStatus(provider, providerPaymentID, status string) (*Payment, bool, error) {
+	query := `UPDATE payments SET status = $3, updated_at = NOW()
+	          WHERE provider = $1 AND provider_payment_id = $2 AND status NOT IN ('succeeded', 'canceled') AND status <> $3
+	          RETURNING ` + paymentColumns
+	payment, err := scanPayment(db.QueryRow(query, provider, providerPaymentID, status))
+	if err == nil {
+		return payment, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	query = `SELECT ` + paymentColumns + ` FROM payments WHERE provider = $1 AND provider_payment_id = $2`
+	payment, err = scanPayment(db.QueryRow(query, provider, providerPaymentID))
+	if err == sql.ErrNoRows {
+		return nil, false, NewNotFoundError("Платеж")
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, false, nil
+}
+
+// ========== Statement import functions ==========
+
+// GetStatementCandidates получает ожидающие подтверждения пожертвования поста с телефоном и именем жертвователя
+func (db *DB) GetStatementCandidates(postID int64) ([]StatementCandidate, error) {
+	query := `SELECT d.id, d.amount, d.created_at, u.phone, u.first_name, u.last_name
+	          FROM donations d JOIN users u ON u.id = d.donor_id
+	          WHERE d.post_id = $1 AND d.status = 'pending'`
+	rows, err := db.Query(query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []StatementCandidate
+	for rows.Next() {
+		var c StatementCandidate
+		if err := rows.Scan(&c.DonationID, &c.Amount, &c.CreatedAt, &c.DonorPhone, &c.FirstName, &c.LastName); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// CreateStatementImport сохраняет выписку и ее строки одной транзакцией
+func (db *DB) CreateStatementImport(imp *StatementImport, rows []StatementRow) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO statement_imports (post_id, uploaded_by, file_name, rows_skipped)
+	          VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	if err := tx.QueryRow(query, imp.PostID, imp.UploadedBy, imp.FileName, imp.RowsSkipped).Scan(&imp.ID, &imp.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create statement import: %w", err)
+	}
+
+	query = `INSERT INTO statement_rows (import_id, row_number, tx_date, amount, payer, description, donation_id, confidence, reasons, status)
+	         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	for i := range rows {
+		r := &rows[i]
+		err := tx.QueryRow(query, imp.ID, r.RowNumber, r.Date, r.Amount, r.Payer, r.Description,
+			r.DonationID, r.Confidence, pq.Array(r.Reasons), r.Status).Scan(&r.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create statement row %d: %w", r.RowNumber, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetStatementImport получает выписку по ID
+func (db *DB) GetStatementImport(id int64) (*StatementImport, error) {
+	var imp StatementImport
+	query := `SELECT id, post_id, uploaded_by, file_name, rows_skipped, created_at FROM statement_imports WHERE id = $1`
+	err := db.QueryRow(query, id).Scan(&imp.ID, &imp.PostID, &imp.UploadedBy, &imp.FileName, &imp.RowsSkipped, &imp.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Выписка")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// GetStatementRows получает строки выписки в порядке файла
+func (db *DB) GetStatementRows(importID int64) ([]StatementRow, error) {
+	query := `SELECT id, row_number, tx_date, amount, payer, description, donation_id, confidence, reasons, status, reviewed_by, reviewed_at
+	          FROM statement_rows WHERE import_id = $1 ORDER BY row_number`
+	rows, err := db.Query(query, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []StatementRow{}
+	for rows.Next() {
+		var r StatementRow
+		if err := rows.Scan(&r.ID, &r.RowNumber, &r.Date, &r.Amount, &r.Payer, &r.Description, &r.DonationID,
+			&r.Confidence, pq.Array(&r.Reasons), &r.Status, &r.ReviewedBy, &r.ReviewedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// SetStatementRowStatus отмечает результат подтверждения строки выписки
+func (db *DB) SetStatementRowStatus(id int64, status string, reviewedBy int64) error {
+	query := `UPDATE statement_rows SET status = $2, reviewed_by = $3, reviewed_at = NOW() WHERE id = $1`
+	_, err := db.Exec(query, id, status, reviewedBy)
+	return err
+}
+
+// ========== Notification functions ==========
+
+// CreateNotificationsBatch сохраняет уведомления одним запросом
+func (db *DB) CreateNotificationsBatch(notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(notifications))
+	args := make([]interface{}, 0, len(notifications)*5)
+	for i, n := range notifications {
+		pos := i * 5
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", pos+1, pos+2, pos+3, pos+4, pos+5))
+		args = append(args, n.UserID, n.Type, n.Title, n.Body, n.PostID)
+	}
+
+	query := `INSERT INTO notifications (user_id, type, title, body, post_id) VALUES ` + strings.Join(values, ", ")
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to create notifications: %w", err)
+	}
+	return nil
+}
+
+// GetNotifications получает уведомления пользователя с пагинацией
+func (db *DB) GetNotifications(userID int64, unreadOnly bool, page, limit int) ([]Notification, int, error) {
+	where := "user_id = $1"
+	if unreadOnly {
+		where += " AND is_read = false"
+	}
+
+	var total int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM notifications WHERE %s", where)
+	if err := db.QueryRow(countQuery, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	query := fmt.Sprintf(`SELECT id, user_id, type, title, body, post_id, is_read, created_at
+	                      FROM notifications WHERE %s ORDER BY created_at DESC LIMIT $2 OFFSET $3`, where)
+	rows, err := db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var notifications []Notification
+	for rows.Next() {
+		var n Notification
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.PostID, &n.IsRead, &n.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, total, rows.Err()
+}
+
+// MarkNotificationsRead отмечает уведомления прочитанными. Пустой список - все уведомления.
+func (db *DB) MarkNotificationsRead(userID int64, ids []int64) error {
+	if len(ids) == 0 {
+		_, err := db.Exec(`UPDATE notifications SET is_read = true WHERE user_id = $1 AND is_read = false`, userID)
+		return err
+	}
+	query := `UPDATE notifications SET is_read = true WHERE user_id = $1 AND id = ANY($2)`
+	_, err := db.Exec(query, userID, pq.Array(ids))
+	return err
+}
+
+// GetNotificationPreferences получает каналы всех типов уведомлений пользователя
+func (db *DB) GetNotificationPreferences(userID int64) ([]NotificationPreference, error) {
+	stored, err := db.notificationPreferences([]int64{userID}, NotificationTypes)
+	if err != nil {
+		return nil, err
+	}
+	preferences := make([]NotificationPreference, 0, len(NotificationTypes))
+	for _, notificationType := range NotificationTypes {
+		p, ok := stored[userID][notificationType]
+		if !ok {
+			p = DefaultNotificationPreference(notificationType)
+		}
+		preferences = append(preferences, p)
+	}
+	return preferences, nil
+}
+
+// SetNotificationPreferences сохраняет каналы перечисленных типов уведомлений
+func (db *DB) SetNotificationPreferences(userID int64, preferences []NotificationPreference) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO notification_preferences (user_id, type, push, sms) VALUES ($1, $2, $3, $4)
+	          ON CONFLICT (user_id, type) DO UPDATE SET push = EXCLUDED.push, sms = EXCLUDED.sms, updated_at = NOW()`
+	for _, p := range preferences {
+		if _, err := tx.Exec(query, userID, p.Type, p.Push, p.SMS); err != nil {
+			return fmt.Errorf("failed to save notification preferences: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// FilterNotificationChannel оставляет уведомления, получатели которых не выключили канал
+// для их типа
+func (db *DB) FilterNotificationChannel(channel string, notifications []Notification) ([]Notification, error) {
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+	userIDs := make([]int64, 0, len(notifications))
+	types := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		userIDs = append(userIDs, n.UserID)
+		types = append(types, n.Type)
+	}
+	stored, err := db.notificationPreferences(userIDs, types)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []Notification
+	for _, n := range notifications {
+		p, ok := stored[n.UserID][n.Type]
+		if !ok {
+			p = DefaultNotificationPreference(n.Type)
+		}
+		if p.Enabled(channel) {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered, nil
+}
+
+// notificationPreferences получает сохраненные каналы уведомлений по пользователю и типу
+func (db *DB) notificationPreferences(userIDs []int64, types []string) (map[int64]map[string]NotificationPreference, error) {
+	query := `SELECT user_id, type, push, sms FROM notification_preferences WHERE user_id = ANY($1) AND type = ANY($2)`
+	rows, err := db.Query(query, pq.Array(userIDs), pq.Array(types))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preferences := make(map[int64]map[string]NotificationPreference)
+	for rows.Next() {
+		var userID int64
+		var p NotificationPreference
+		if err := rows.Scan(&userID, &p.Type, &p.Push, &p.SMS); err != nil {
+			return nil, err
+		}
+		p.SMSAvailable = smsNotificationTypes[p.Type]
+		if preferences[userID] == nil {
+			preferences[userID] = make(map[string]NotificationPreference)
+		}
+		preferences[userID][p.Type] = p
+	}
+	return preferences, rows.Err()
+}
+
+// GetVerifiedPhones получает подтвержденные телефоны пользователей для SMS уведомлений
+func (db *DB) GetVerifiedPhones(userIDs []int64) (map[int64]string, error) {
+	rows, err := db.Query(`SELECT id, phone FROM users WHERE id = ANY($1) AND phone_verified AND is_active`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	phones := make(map[int64]string)
+	for rows.Next() {
+		var userID int64
+		var phone string
+		if err := rows.Scan(&userID, &phone); err != nil {
+			return nil, err
+		}
+		phones[userID] = phone
+	}
+	return phones, rows.Err()
+}
+
+// ========== Payment note template functions ==========
+
+// GetPaymentNoteTemplates получает шаблоны назначения платежа пользователя в порядке создания
+func (db *DB) GetPaymentNoteTemplates(userID int64) ([]PaymentNoteTemplate, error) {
+	query := `SELECT id, name, text, created_at, updated_at FROM payment_note_templates
+	          WHERE user_id = $1 ORDER BY created_at, id`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []PaymentNoteTemplate{}
+	for rows.Next() {
+		var t PaymentNoteTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.Text, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// CountPaymentNoteTemplates считает шаблоны назначения платежа пользователя
+func (db *DB) CountPaymentNoteTemplates(userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM payment_note_templates WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// CreatePaymentNoteTemplate сохраняет шаблон назначения платежа
+func (db *DB) CreatePaymentNoteTemplate(userID int64, name, text string) (*PaymentNoteTemplate, error) {
+	t := PaymentNoteTemplate{Name: name, Text: text}
+	query := `INSERT INTO payment_note_templates (user_id, name, text) VALUES ($1, $2, $3)
+	          RETURNING id, created_at, updated_at`
+	err := db.QueryRow(query, userID, name, text).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return nil, NewConflictError("Шаблон с таким названием уже есть")
+		}
+		return nil, fmt.Errorf("failed to create payment note template: %w", err)
+	}
+	return &t, nil
+}
+
+// UpdatePaymentNoteTemplate изменяет шаблон назначения платежа пользователя
+func (db *DB) UpdatePaymentNoteTemplate(userID, id int64, name, text string) (*PaymentNoteTemplate, error) {
+	t := PaymentNoteTemplate{ID: id, Name: name, Text: text}
+	query := `UPDATE payment_note_templates SET name = $3, text = $4, updated_at = NOW()
+	          WHERE id = $1 AND user_id = $2
+	          RETURNING created_at, updated_at`
+	err := db.QueryRow(query, id, userID, name, text).Scan(&t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Шаблон")
+	}
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return nil, NewConflictError("Шаблон с таким названием уже есть")
+		}
+		return nil, fmt.Errorf("failed to update payment note template: %w", err)
+	}
+	return &t, nil
+}
+
+// DeletePaymentNoteTemplate удаляет шаблон назначения платежа. Возвращает false, если его не было.
+func (db *DB) DeletePaymentNoteTemplate(userID, id int64) (bool, error) {
+	result, err := db.Exec(`DELETE FROM payment_note_templates WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ========== Subscription functions ==========
+
+const subscriptionColumns = `s.id, s.post_id, p.title, s.donor_id, s.amount, s.period, s.is_anonymous, s.is_active,
+	s.next_run_at, s.last_run_at, s.last_donation_id, s.created_at, s.updated_at`
+
+func scanSubscription(row interface{ Scan(...interface{}) error }) (*Subscription, error) {
+	var s Subscription
+	err := row.Scan(&s.ID, &s.PostID, &s.PostTitle, &s.DonorID, &s.Amount, &s.Interval, &s.IsAnonymous, &s.IsActive,
+		&s.NextRunAt, &s.LastRunAt, &s.LastDonationID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetSubscriptions получает подписки донора, ближайшие первыми
+func (db *DB) GetSubscriptions(donorID int64) ([]Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions s JOIN posts p ON p.id = s.post_id
+	          WHERE s.donor_id = $1 ORDER BY s.is_active DESC, s.next_run_at, s.id`
+	rows, err := db.Query(query, donorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []Subscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// GetSubscription получает подписку донора по ID
+func (db *DB) GetSubscription(donorID, id int64) (*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions s JOIN posts p ON p.id = s.post_id
+	          WHERE s.id = $1 AND s.donor_id = $2`
+	s, err := scanSubscription(db.QueryRow(query, id, donorID))
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Подписка")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+	return s, nil
+}
+
+// CreateSubscription сохраняет подписку. На один пост у донора одна подписка.
+func (db *DB) CreateSubscription(s *Subscription) error {
+	query := `INSERT INTO subscriptions (post_id, donor_id, amount, period, is_anonymous, next_run_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING id, is_active, created_at, updated_at`
+	err := db.QueryRow(query, s.PostID, s.DonorID, s.Amount, s.Interval, s.IsAnonymous, s.NextRunAt).Scan(
+		&s.ID, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return NewConflictError("Подписка на этот сбор уже есть")
+		}
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+	return nil
+}
+
+// UpdateSubscription сохраняет сумму, периодичность, анонимность, состояние и срок подписки
+func (db *DB) UpdateSubscription(s *Subscription) error {
+	query := `UPDATE subscriptions SET amount = $3, period = $4, is_anonymous = $5, is_active = $6,
+	          next_run_at = $7, updated_at = NOW()
+	          WHERE id = $1 AND donor_id = $2
+	          RETURNING updated_at`
+	err := db.QueryRow(query, s.ID, s.DonorID, s.Amount, s.Interval, s.IsAnonymous, s.IsActive, s.NextRunAt).Scan(&s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return NewNotFoundError("Подписка")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubscription удаляет подписку. Созданные ей пожертвования остаются. Возвращает false, если ее не было.
+func (db *DB) DeleteSubscription(donorID, id int64) (bool, error) {
+	result, err := db.Exec(`DELETE FROM subscriptions WHERE id = $1 AND donor_id = $2`, id, donorID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RunDueSubscriptions создает пожертвования по подпискам, срок которых наступил, и переносит
+// срок на следующий период. Подписки на неактивные сборы останавливаются. Подписки
+// блокируются до конца транзакции, поэтому параллельные экземпляры не создадут пожертвование дважды.
+func (db *DB) RunDueSubscriptions(limit int, now time.Time) ([]SubscriptionRun, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + subscriptionColumns + `, p.user_id, p.status
+	          FROM subscriptions s JOIN posts p ON p.id = s.post_id
+	          WHERE s.is_active = true AND s.next_run_at <= $2
+	          ORDER BY s.next_run_at LIMIT $1
+	          FOR UPDATE OF s SKIP LOCKED`
+	rows, err := tx.Query(query, limit, now)
+	if err != nil {
+		return nil, err
+	}
+	var runs []SubscriptionRun
+	var statuses []string
+	for rows.Next() {
+		var run SubscriptionRun
+		var status string
+		s := &run.Subscription
+		err := rows.Scan(&s.ID, &s.PostID, &s.PostTitle, &s.DonorID, &s.Amount, &s.Interval, &s.IsAnonymous, &s.IsActive,
+			&s.NextRunAt, &s.LastRunAt, &s.LastDonationID, &s.CreatedAt, &s.UpdatedAt, &run.PostUserID, &status)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		runs = append(runs, run)
+		statuses = append(statuses, status)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range runs {
+		s := &runs[i].Subscription
+		if statuses[i] != "active" {
+			s.IsActive = false
+			if _, err := tx.Exec(`UPDATE subscriptions SET is_active = false, updated_at = NOW() WHERE id = $1`, s.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		d := &Donation{PostID: s.PostID, DonorID: s.DonorID, Amount: s.Amount, IsAnonymous: s.IsAnonymous}
+		err := tx.QueryRow(`INSERT INTO donations (post_id, donor_id, amount, is_anonymous) VALUES ($1, $2, $3, $4)
+		                    RETURNING id, status, created_at`, d.PostID, d.DonorID, d.Amount, d.IsAnonymous).Scan(
+			&d.ID, &d.Status, &d.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create subscription donation: %w", err)
+		}
+		metadata := map[string]interface{}{"post_id": d.PostID, "amount": d.Amount, "subscription_id": s.ID}
+		if d.IsAnonymous {
+			metadata["is_anonymous"] = true
+		}
+		if err := insertDonationEvent(tx, d.ID, DonationEventCreated, d.DonorID, metadata); err != nil {
+			return nil, err
+		}
+
+		s.NextRunAt = NextSubscriptionRun(s.Interval, s.NextRunAt, now)
+		s.LastRunAt = &now
+		s.LastDonationID = &d.ID
+		_, err = tx.Exec(`UPDATE subscriptions SET next_run_at = $2, last_run_at = $3, last_donation_id = $4, updated_at = NOW()
+		                  WHERE id = $1`, s.ID, s.NextRunAt, now, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		runs[i].Donation = d
+	}
+	return runs, tx.Commit()
+}
+
+// ========== Integration functions ==========
+
+// UpsertIntegration подключает интеграцию или обновляет адрес существующей того же типа
+func (db *DB) UpsertIntegration(userID int64, integrationType, target, secret string) (*Integration, error) {
+	query := `INSERT INTO user_integrations (user_id, type, target, secret)
+	          VALUES ($1, $2, $3, $4)
+	          ON CONFLICT (user_id, type) DO UPDATE
+	          SET target = EXCLUDED.target, secret = EXCLUDED.secret, is_active = true, updated_at = NOW()
+	          RETURNING id, user_id, type, target, secret, is_active, created_at, updated_at`
+	var i Integration
+	err := db.QueryRow(query, userID, integrationType, target, secret).Scan(
+		&i.ID, &i.UserID, &i.Type, &i.Target, &i.Secret, &i.IsActive, &i.CreatedAt, &i.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save integration: %w", err)
+	}
+	return &i, nil
+}
+
+// GetIntegrations получает интеграции пользователя
+func (db *DB) GetIntegrations(userID int64) ([]Integration, error) {
+	query := `SELECT id, user_id, type, target, secret, is_active, created_at, updated_at
+	          FROM user_integrations WHERE user_id = $1 ORDER BY type`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var integrations []Integration
+	for rows.Next() {
+		var i Integration
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Type, &i.Target, &i.Secret, &i.IsActive, &i.CreatedAt, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		integrations = append(integrations, i)
+	}
+	return integrations, rows.Err()
+}
+
+// DeleteIntegration отключает интеграцию. Возвращает false, если ее не было.
+func (db *DB) DeleteIntegration(userID int64, integrationType string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM user_integrations WHERE user_id = $1 AND type = $2`, userID, integrationType)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ========== Device functions ==========
+
+// UpsertDevice регистрирует токен устройства. Токен, ранее принадлежавший
+// другому пользователю (смена аккаунта на устройстве), переходит к текущему.
+func (db *DB) UpsertDevice(userID int64, token, platform string) (*Device, error) {
+	query := `INSERT INTO devices (user_id, token, platform)
+	          VALUES ($1, $2, $3)
+	          ON CONFLICT (token) DO UPDATE
+	          SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, last_seen_at = NOW()
+	          RETURNING id, user_id, token, platform, created_at, last_seen_at`
+	var d Device
+	err := db.QueryRow(query, userID, token, platform).Scan(&d.ID, &d.UserID, &d.Token, &d.Platform, &d.CreatedAt, &d.LastSeenAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+
+	query = `INSERT INTO device_users (token_hash, user_id) VALUES ($1, $2)
+	         ON CONFLICT (token_hash, user_id) DO UPDATE SET last_seen_at = NOW()`
+	if _, err := db.Exec(query, sha256Hex(token), userID); err != nil {
+		return nil, fmt.Errorf("failed to record device user: %w", err)
+	}
+	return &d, nil
+}
+
+// DeleteDevice удаляет токен устройства пользователя. Возвращает false, если его не было.
+func (db *DB) DeleteDevice(userID int64, token string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM devices WHERE user_id = $1 AND token = $2`, userID, token)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetDeviceTokens получает токены устройств пользователей
+func (db *DB) GetDeviceTokens(userIDs []int64) (map[int64][]string, error) {
+	rows, err := db.Query(`SELECT user_id, token FROM devices WHERE user_id = ANY($1)`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make(map[int64][]string)
+	for rows.Next() {
+		var userID int64
+		var token string
+		if err := rows.Scan(&userID, &token); err != nil {
+			return nil, err
+		}
+		tokens[userID] = append(tokens[userID], token)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteDeviceTokens удаляет токены, которые FCM больше не принимает
+func (db *DB) DeleteDeviceTokens(tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	_, err := db.Exec(`DELETE FROM devices WHERE token = ANY($1)`, pq.Array(tokens))
+	return err
+}
+
+// ========== API token functions ==========
+
+const apiTokenColumns = `id, user_id, name, token_hash, token_prefix, scopes, expires_at, last_used_at, created_at`
+
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (*APIToken, error) {
+	var t APIToken
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.TokenHash, &t.Prefix, pq.Array(&t.Scopes), &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateAPIToken сохраняет персональный API токен
+func (db *DB) CreateAPIToken(userID int64, name, tokenHash, prefix string, scopes []string, expiresAt *time.Time) (*APIToken, error) {
+	query := `INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING ` + apiTokenColumns
+	token, err := scanAPIToken(db.QueryRow(query, userID, name, tokenHash, prefix, pq.Array(scopes), expiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api token: %w", err)
+	}
+	return token, nil
+}
+
+// GetAPITokens получает API токены пользователя
+func (db *DB) GetAPITokens(userID int64) ([]APIToken, error) {
+	rows, err := db.Query(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// CountAPITokens считает API токены пользователя
+func (db *DB) CountAPITokens(userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM api_tokens WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// GetAPITokenByHash получает API токен по хешу
+func (db *DB) GetAPITokenByHash(tokenHash string) (*APIToken, error) {
+	token, err := scanAPIToken(db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = $1`, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("API токен")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api token: %w", err)
+	}
+	return token, nil
+}
+
+// TouchAPIToken обновляет время последнего использования токена и активности его
+// владельца не чаще раза в минуту
+func (db *DB) TouchAPIToken(id int64) error {
+	query := `WITH touched AS (
+	              UPDATE api_tokens SET last_used_at = NOW()
+	              WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	              RETURNING user_id
+	          )
+	          UPDATE users SET last_active_at = NOW() WHERE id IN (SELECT user_id FROM touched)`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// DeleteAPIToken отзывает API токен пользователя. Возвращает false, если его не было.
+func (db *DB) DeleteAPIToken(userID, id int64) (bool, error) {
+	result, err := db.Exec(`DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ========== Webhook delivery functions ==========
+
+// EnqueueWebhookEvent ставит событие в очередь доставки по всем активным интеграциям пользователя
+func (db *DB) EnqueueWebhookEvent(userID int64, event string, payload []byte, summary string) error {
+	query := `INSERT INTO webhook_deliveries (integration_id, event, payload, summary)
+	          SELECT id, $2, $3, $4 FROM user_integrations WHERE user_id = $1 AND is_active = true`
+	if _, err := db.Exec(query, userID, event, payload, summary); err != nil {
+		return fmt.Errorf("failed to enqueue webhook event: %w", err)
+	}
+	return nil
+}
+
+// EnqueuePartnerWebhookEvent ставит событие в очередь доставки по активным вебхукам
+// партнеров, подписанным на него
+func (db *DB) EnqueuePartnerWebhookEvent(event string, payload []byte) error {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event, payload, summary)
+	          SELECT id, $1, $2, '' FROM webhooks WHERE is_active = true AND $1 = ANY(events)`
+	if _, err := db.Exec(query, event, payload); err != nil {
+		return fmt.Errorf("failed to enqueue partner webhook event: %w", err)
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries выбирает готовые к отправке события и откладывает
+// их на lease, чтобы параллельные экземпляры не отправили их повторно.
+// Вебхук партнера возвращается как интеграция типа webhook.
+func (db *DB) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	query := `WITH claimed AS (
+	              UPDATE webhook_deliveries
+	              SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+	              WHERE id IN (
+	                  SELECT id FROM webhook_deliveries
+	                  WHERE status = 'pending' AND next_attempt_at <= NOW()
+	                  ORDER BY next_attempt_at LIMIT $1
+	                  FOR UPDATE SKIP LOCKED
+	              )
+	              RETURNING id, integration_id, webhook_id, event, payload, summary, attempts
+	          )
+	          SELECT c.id, c.integration_id, c.webhook_id, c.event, c.payload, c.summary, c.attempts,
+	                 COALESCE(i.id, w.id), COALESCE(i.user_id, 0), COALESCE(i.type, '` + IntegrationWebhook + `'),
+	                 COALESCE(i.target, w.url), COALESCE(i.secret, w.secret), COALESCE(i.is_active, w.is_active)
+	          FROM claimed c
+	          LEFT JOIN user_integrations i ON i.id = c.integration_id
+	          LEFT JOIN webhooks w ON w.id = c.webhook_id`
+	rows, err := db.Query(query, limit, int(lease.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		err := rows.Scan(&d.ID, &d.IntegrationID, &d.WebhookID, &d.Event, &d.Payload, &d.Summary, &d.Attempts,
+			&d.Integration.ID, &d.Integration.UserID, &d.Integration.Type, &d.Integration.Target,
+			&d.Integration.Secret, &d.Integration.IsActive)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkWebhookDelivered отмечает событие доставленным
+func (db *DB) MarkWebhookDelivered(id int64) error {
+	query := `UPDATE webhook_deliveries SET status = 'delivered', attempts = attempts + 1,
+	          last_error = NULL, delivered_at = NOW() WHERE id = $1`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// MarkWebhookFailed сохраняет неудачную попытку. Без nextAttempt событие больше не отправляется.
+func (db *DB) MarkWebhookFailed(id int64, lastError string, nextAttempt *time.Time) error {
+	if nextAttempt == nil {
+		query := `UPDATE webhook_deliveries SET status = 'failed', attempts = attempts + 1, last_error = $2 WHERE id = $1`
+		_, err := db.Exec(query, id, lastError)
+		return err
+	}
+	query := `UPDATE webhook_deliveries SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`
+	_, err := db.Exec(query, id, lastError, *nextAttempt)
+	return err
+}
+
+// GetWebhookBacklog возвращает число недоставленных событий и время создания самого старого
+func (db *DB) GetWebhookBacklog(ctx context.Context) (int, *time.Time, error) {
+	var pending int
+	var oldest *time.Time
+	query := `SELECT COUNT(*), MIN(created_at) FROM webhook_deliveries WHERE status = 'pending'`
+	if err := db.QueryRowContext(ctx, query).Scan(&pending, &oldest); err != nil {
+		return 0, nil, fmt.Errorf("failed to get webhook backlog: %w", err)
+	}
+	return pending, oldest, nil
+}
+
+// GetFailedWebhookDeliveries возвращает число событий, доставка которых прекращена: всего и
+// среди созданных за последние сутки
+func (db *DB) GetFailedWebhookDeliveries(ctx context.Context) (total, lastDay int, err error) {
+	query := `SELECT COUNT(*), COUNT(*) FILTER (WHERE created_at > NOW() - INTERVAL '24 hours')
+	          FROM webhook_deliveries WHERE status = 'failed'`
+	if err = db.QueryRowContext(ctx, query).Scan(&total, &lastDay); err != nil {
+		return 0, 0, fmt.Errorf("failed to count failed webhook deliveries: %w", err)
+	}
+	return total, lastDay, nil
+}
+
+// GetStuckReceiptChecks возвращает число чеков, ожидающих сверки дольше olderThan после
+// загрузки, и время загрузки самого старого
+func (db *DB) GetStuckReceiptChecks(ctx context.Context, olderThan time.Duration) (int, *time.Time, error) {
+	var count int
+	var oldest *time.Time
+	query := `SELECT COUNT(*), MIN(uploaded_at) FROM (
+	              SELECT COALESCE((SELECT MAX(e.created_at) FROM donation_events e
+	                               WHERE e.donation_id = d.id AND e.event_type = 'receipt_attached'), d.created_at) AS uploaded_at
+	              FROM donations d WHERE d.receipt_check = 'pending'
+	          ) pending
+	          WHERE uploaded_at < NOW() - $1 * INTERVAL '1 second'`
+	if err := db.QueryRowContext(ctx, query, int(olderThan.Seconds())).Scan(&count, &oldest); err != nil {
+		return 0, nil, fmt.Errorf("failed to count stuck receipt checks: %w", err)
 	}
-	return &v, nil
+	return count, oldest, nil
 }
 
-// GetVerifications получает список верификаций с фильтрацией
-func (db *DB) GetVerifications(status string, page, limit int) ([]Verification, int, error) {
+// RequeueWebhookDelivery возвращает событие с прекращенной доставкой в очередь.
+// Счетчик попыток не сбрасывается, поэтому при новой ошибке событие сразу снова
+// попадает в очередь недоставленных. Возвращает false, если событие не найдено
+// или его доставка не прекращена.
+func (db *DB) RequeueWebhookDelivery(id int64) (bool, error) {
+	result, err := db.Exec(`UPDATE webhook_deliveries SET status = 'pending', next_attempt_at = NOW()
+	                        WHERE id = $1 AND status = 'failed'`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ========== Dead letter functions ==========
+
+const deadLetterColumns = `id, kind, reference_id, payload, error, attempts, status, retries,
+	next_retry_at, last_retried_at, last_retried_by, created_at, updated_at`
+
+func scanDeadLetter(row interface{ Scan(...interface{}) error }) (*DeadLetter, error) {
+	var d DeadLetter
+	err := row.Scan(&d.ID, &d.Kind, &d.ReferenceID, &d.Payload, &d.Error, &d.Attempts, &d.Status, &d.Retries,
+		&d.NextRetryAt, &d.LastRetriedAt, &d.LastRetriedBy, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// RecordDeadLetter сохраняет упавшее задание. Задание с ID (упавший повтор) обновляется
+// с новым payload и ошибкой; для задания с reference_id обновляется существующая запись.
+func (db *DB) RecordDeadLetter(d *DeadLetter) error {
+	if d.ID != 0 {
+		query := `UPDATE dead_letters SET payload = $2, error = $3, attempts = attempts + 1,
+		          status = 'failed', updated_at = NOW() WHERE id = $1`
+		if _, err := db.Exec(query, d.ID, d.Payload, d.Error); err != nil {
+			return fmt.Errorf("failed to update dead letter: %w", err)
+		}
+		return nil
+	}
+
+	query := `INSERT INTO dead_letters (kind, reference_id, payload, error, attempts)
+	          VALUES ($1, $2, $3, $4, $5)
+	          ON CONFLICT (kind, reference_id) WHERE reference_id IS NOT NULL DO UPDATE
+	          SET payload = EXCLUDED.payload, error = EXCLUDED.error, attempts = EXCLUDED.attempts,
+	              status = 'failed', updated_at = NOW()`
+	if _, err := db.Exec(query, d.Kind, d.ReferenceID, d.Payload, d.Error, d.Attempts); err != nil {
+		return fmt.Errorf("failed to record dead letter: %w", err)
+	}
+	return nil
+}
+
+// GetDeadLetter получает задание по ID
+func (db *DB) GetDeadLetter(id int64) (*DeadLetter, error) {
+	d, err := scanDeadLetter(db.QueryRow(`SELECT `+deadLetterColumns+` FROM dead_letters WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Задание")
+	}
+	return d, err
+}
+
+// GetDeadLetters получает задания по фильтрам, новые первыми
+func (db *DB) GetDeadLetters(filter DeadLetterFilter, page, limit int) ([]DeadLetter, int, error) {
 	where := "1=1"
 	args := []interface{}{}
-	argPos := 1
-
-	if status != "" {
-		where += fmt.Sprintf(" AND status = $%d", argPos)
-		args = append(args, status)
-		argPos++
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		where += fmt.Sprintf(" AND "+condition, len(args))
+	}
+	if filter.Kind != "" {
+		add("kind = $%d", filter.Kind)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
 	}
 
-	// Подсчет общего количества
 	var total int
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM verifications WHERE %s", where)
-	err := db.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
+	if err := db.QueryRow(`SELECT COUNT(*) FROM dead_letters WHERE `+where, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	// Получение данных
-	offset := (page - 1) * limit
-	query := fmt.Sprintf(`SELECT id, user_id, first_name, last_name, status, submitted_at 
-	                     FROM verifications WHERE %s ORDER BY submitted_at DESC LIMIT $%d OFFSET $%d`,
-		where, argPos, argPos+1)
-	args = append(args, limit, offset)
-
-	rows, err := db.Query(query, args...)
+	query := fmt.Sprintf(`SELECT %s FROM dead_letters WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+		deadLetterColumns, where, len(args)+1, len(args)+2)
+	rows, err := db.Query(query, append(args, limit, (page-1)*limit)...)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var verifications []Verification
+	letters := []DeadLetter{}
 	for rows.Next() {
-		var v Verification
-		err := rows.Scan(&v.ID, &v.UserID, &v.FirstName, &v.LastName, &v.Status, &v.SubmittedAt)
+		d, err := scanDeadLetter(rows)
 		if err != nil {
 			return nil, 0, err
 		}
-		verifications = append(verifications, v)
+		letters = append(letters, *d)
 	}
+	return letters, total, rows.Err()
+}
 
-	return verifications, total, nil
+// CountFailedDeadLetters возвращает число заданий, ожидающих решения администратора
+func (db *DB) CountFailedDeadLetters(ctx context.Context) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM dead_letters WHERE status = 'failed'`
+	if err := db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	return count, nil
 }
 
-// UpdateVerificationStatus обновляет статус верификации
-func (db *DB) UpdateVerificationStatus(id int64, status string, reviewedBy int64, rejectionReason *string) error {
-	query := `UPDATE verifications 
-	          SET status = $1, reviewed_at = NOW(), reviewed_by = $2, rejection_reason = $3 
-	          WHERE id = $4`
-	_, err := db.Exec(query, status, reviewedBy, rejectionReason, id)
+// MarkDeadLetterRetrying отмечает начало повтора. Следующий повтор разрешен не раньше nextRetryAt.
+// Возвращает false, если задание уже повторяется или закрыто.
+func (db *DB) MarkDeadLetterRetrying(id, userID int64, nextRetryAt time.Time) (bool, error) {
+	query := `UPDATE dead_letters SET status = 'retrying', retries = retries + 1, next_retry_at = $3,
+	          last_retried_at = NOW(), last_retried_by = $2, updated_at = NOW()
+	          WHERE id = $1 AND status = 'failed'`
+	result, err := db.Exec(query, id, userID, nextRetryAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ResolveDeadLetter отмечает повтор задания успешным
+func (db *DB) ResolveDeadLetter(id int64) error {
+	_, err := db.Exec(`UPDATE dead_letters SET status = 'resolved', updated_at = NOW()
+	                   WHERE id = $1 AND status = 'retrying'`, id)
 	return err
 }
 
-// IsUserVerified проверяет, верифицирован ли пользователь
-func (db *DB) IsUserVerified(userID int64) bool {
-	var count int
-	query := `SELECT COUNT(*) FROM verifications WHERE user_id = $1 AND status = 'approved'`
-	db.QueryRow(query, userID).Scan(&count)
-	return count > 0
+// ResolveWebhookDeadLetter отмечает успешным повтор события webhook_deliveries
+func (db *DB) ResolveWebhookDeadLetter(deliveryID int64) error {
+	_, err := db.Exec(`UPDATE dead_letters SET status = 'resolved', updated_at = NOW()
+	                   WHERE kind = $1 AND reference_id = $2 AND status = 'retrying'`, DeadLetterWebhook, deliveryID)
+	return err
 }
 
-// ========== Post functions ==========
+// DiscardDeadLetter закрывает задание без повтора. Возвращает false, если
+// задание уже повторяется или закрыто.
+func (db *DB) DiscardDeadLetter(id int64) (bool, error) {
+	result, err := db.Exec(`UPDATE dead_letters SET status = 'discarded', updated_at = NOW()
+	                        WHERE id = $1 AND status = 'failed'`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
 
-// CreatePost создает новый пост
-func (db *DB) CreatePost(p *Post) error {
-	query := `INSERT INTO posts (user_id, title, description, amount, recipient, bank, phone)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7)
-	          RETURNING id, collected, status, created_at, updated_at, is_editable`
-	err := db.QueryRow(query, p.UserID, p.Title, p.Description, p.Amount, p.Recipient, p.Bank, p.Phone).Scan(
-		&p.ID, &p.Collected, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable,
+// ========== Digest functions ==========
+
+// GetDigestReport собирает показатели сводки за период [start, end). Очереди
+// верификации и модерации считаются на момент вызова.
+func (db *DB) GetDigestReport(ctx context.Context, start, end time.Time) (*DigestReport, error) {
+	var report DigestReport
+	query := `SELECT
+	              (SELECT COUNT(*) FROM posts WHERE created_at >= $1 AND created_at < $2),
+	              (SELECT COUNT(*) FROM donations WHERE status = 'confirmed' AND confirmed_at >= $1 AND confirmed_at < $2),
+	              (SELECT COALESCE(SUM(amount), 0) FROM donations WHERE status = 'confirmed' AND confirmed_at >= $1 AND confirmed_at < $2),
+	              (SELECT COUNT(*) FROM verifications WHERE status = 'pending'),
+	              (SELECT MIN(submitted_at) FROM verifications WHERE status = 'pending'),
+	              (SELECT COUNT(*) FROM verifications WHERE reviewed_at >= $1 AND reviewed_at < $2),
+	              (SELECT COUNT(*) FROM posts WHERE status = 'moderated'),
+	              (SELECT COUNT(*) FROM post_limit_requests WHERE status = 'pending'),
+	              (SELECT COUNT(*) FROM donations WHERE receipt_check = 'mismatch' AND receipt_checked_at >= $1 AND receipt_checked_at < $2)`
+	err := db.QueryRowContext(ctx, query, start, end).Scan(
+		&report.NewPosts, &report.DonationsConfirmed, &report.AmountRaised,
+		&report.VerificationsPending, &report.VerificationsOldestAt, &report.VerificationsReviewed,
+		&report.PostsModeration, &report.LimitRequestsPending, &report.ReceiptMismatches,
 	)
-	return err
+	if err != nil {
+		return nil, fmt.Errorf("failed to build digest report: %w", err)
+	}
+	return &report, nil
 }
 
-// GetPostByID получает пост по ID
-func (db *DB) GetPostByID(id int64) (*Post, error) {
-	var p Post
-	query := `SELECT id, user_id, title, description, amount, collected, recipient, bank, phone, 
-	                 status, created_at, updated_at, is_editable
-	          FROM posts WHERE id = $1`
-	err := db.QueryRow(query, id).Scan(
-		&p.ID, &p.UserID, &p.Title, &p.Description, &p.Amount, &p.Collected,
-		&p.Recipient, &p.Bank, &p.Phone, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable,
-	)
+// DigestExists проверяет, сформирована ли сводка за неделю с началом periodStart
+func (db *DB) DigestExists(periodStart time.Time) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM digests WHERE period_start = $1)`, periodStart).Scan(&exists)
+	return exists, err
+}
+
+// CreateDigest сохраняет сводку. false - сводку за эту неделю уже сохранил другой экземпляр.
+func (db *DB) CreateDigest(d *Digest) (bool, error) {
+	report, err := json.Marshal(d.Report)
+	if err != nil {
+		return false, err
+	}
+	query := `INSERT INTO digests (period_start, period_end, report, text)
+	          VALUES ($1, $2, $3, $4)
+	          ON CONFLICT (period_start) DO NOTHING
+	          RETURNING id, created_at`
+	err = db.QueryRow(query, d.PeriodStart, d.PeriodEnd, report, d.Text).Scan(&d.ID, &d.CreatedAt)
 	if err == sql.ErrNoRows {
-		return nil, NewNotFoundError("Пост")
+		return false, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get post: %w", err)
+		return false, fmt.Errorf("failed to create digest: %w", err)
 	}
-	return &p, nil
+	return true, nil
 }
 
-// GetPosts получает список постов с фильтрацией и пагинацией
-func (db *DB) GetPosts(status string, userID *int64, page, limit int) ([]Post, int, error) {
-	where := "1=1"
-	args := []interface{}{}
-	argPos := 1
+// SetDigestDeliveries сохраняет результаты отправки сводки
+func (db *DB) SetDigestDeliveries(id int64, deliveries []DigestDelivery) error {
+	data, err := json.Marshal(deliveries)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE digests SET deliveries = $2 WHERE id = $1`, id, data)
+	return err
+}
 
-	if status != "" {
-		where += fmt.Sprintf(" AND status = $%d", argPos)
-		args = append(args, status)
-		argPos++
+const digestColumns = `id, period_start, period_end, report, text, deliveries, created_at`
+
+func scanDigest(row interface{ Scan(...interface{}) error }) (*Digest, error) {
+	var d Digest
+	var report, deliveries []byte
+	if err := row.Scan(&d.ID, &d.PeriodStart, &d.PeriodEnd, &report, &d.Text, &deliveries, &d.CreatedAt); err != nil {
+		return nil, err
 	}
-	if userID != nil {
-		where += fmt.Sprintf(" AND user_id = $%d", argPos)
-		args = append(args, *userID)
-		argPos++
+	if err := json.Unmarshal(report, &d.Report); err != nil {
+		return nil, fmt.Errorf("failed to decode digest report: %w", err)
 	}
+	if err := json.Unmarshal(deliveries, &d.Deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode digest deliveries: %w", err)
+	}
+	return &d, nil
+}
 
-	// Подсчет общего количества
+// GetDigests возвращает сводки, новые первыми
+func (db *DB) GetDigests(page, limit int) ([]Digest, int, error) {
 	var total int
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM posts WHERE %s", where)
-	err := db.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
+	if err := db.QueryRow(`SELECT COUNT(*) FROM digests`).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	// Получение данных
-	offset := (page - 1) * limit
-	query := fmt.Sprintf(`SELECT id, user_id, title, description, amount, collected, recipient, bank, phone,
-	                             status, created_at, updated_at, is_editable
-	                      FROM posts WHERE %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`,
-		where, argPos, argPos+1)
-	args = append(args, limit, offset)
-
-	rows, err := db.Query(query, args...)
+	query := `SELECT ` + digestColumns + ` FROM digests ORDER BY period_start DESC LIMIT $1 OFFSET $2`
+	rows, err := db.Query(query, limit, (page-1)*limit)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var posts []Post
+	digests := []Digest{}
 	for rows.Next() {
-		var p Post
-		err := rows.Scan(
-			&p.ID, &p.UserID, &p.Title, &p.Description, &p.Amount, &p.Collected,
-			&p.Recipient, &p.Bank, &p.Phone, &p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable,
-		)
+		d, err := scanDigest(rows)
 		if err != nil {
 			return nil, 0, err
 		}
-		posts = append(posts, p)
+		digests = append(digests, *d)
 	}
+	return digests, total, rows.Err()
+}
 
-	return posts, total, nil
+// GetDigest возвращает сводку по ID
+func (db *DB) GetDigest(id int64) (*Digest, error) {
+	d, err := scanDigest(db.QueryRow(`SELECT `+digestColumns+` FROM digests WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Сводка")
+	}
+	return d, err
 }
 
-// UpdatePost обновляет пост
-func (db *DB) UpdatePost(id int64, title, description *string, amount *float64, recipient, bank, phone *string) error {
-	updates := []string{}
-	args := []interface{}{}
-	argPos := 1
+// ========== Idempotency functions ==========
 
-	if title != nil {
-		updates = append(updates, fmt.Sprintf("title = $%d", argPos))
-		args = append(args, *title)
-		argPos++
-	}
-	if description != nil {
-		updates = append(updates, fmt.Sprintf("description = $%d", argPos))
-		args = append(args, *description)
-		argPos++
-	}
-	if amount != nil {
-		updates = append(updates, fmt.Sprintf("amount = $%d", argPos))
-		args = append(args, *amount)
-		argPos++
+// ClaimIdempotencyKey занимает ключ пользователя под запрос с хешем hash. Истекший
+// ключ (создан до expiredBefore) и ключ прерванного запроса (не завершен до
+// abandonedBefore) занимаются заново. Если ключ занят, возвращается его запись и false.
+func (db *DB) ClaimIdempotencyKey(userID int64, key, hash string, expiredBefore, abandonedBefore time.Time) (*IdempotencyRecord, bool, error) {
+	query := `INSERT INTO idempotency_keys (user_id, key, request_hash)
+	          VALUES ($1, $2, $3)
+	          ON CONFLICT (user_id, key) DO UPDATE SET
+	              request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = '',
+	              response_body = NULL, created_at = NOW(), completed_at = NULL
+	          WHERE idempotency_keys.created_at < $4
+	             OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < $5)`
+	result, err := db.Exec(query, userID, key, hash, expiredBefore, abandonedBefore)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
 	}
-	if recipient != nil {
-		updates = append(updates, fmt.Sprintf("recipient = $%d", argPos))
-		args = append(args, *recipient)
-		argPos++
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return nil, affected > 0, err
 	}
-	if bank != nil {
-		updates = append(updates, fmt.Sprintf("bank = $%d", argPos))
-		args = append(args, *bank)
-		argPos++
+
+	var record IdempotencyRecord
+	var status sql.NullInt64
+	err = db.QueryRow(`SELECT request_hash, status_code, content_type, response_body
+	                   FROM idempotency_keys WHERE user_id = $1 AND key = $2`, userID, key).
+		Scan(&record.RequestHash, &status, &record.ContentType, &record.Body)
+	if err == sql.ErrNoRows {
+		// Ключ освобожден между запросами, клиент может повторить
+		return nil, false, NewConflictError("Запрос с этим ключом идемпотентности еще выполняется")
 	}
-	if phone != nil {
-		updates = append(updates, fmt.Sprintf("phone = $%d", argPos))
-		args = append(args, *phone)
-		argPos++
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
 	}
-
-	if len(updates) == 0 {
-		return nil
+	if status.Valid {
+		code := int(status.Int64)
+		record.StatusCode = &code
 	}
+	return &record, false, nil
+}
 
-	updates = append(updates, fmt.Sprintf("updated_at = $%d", argPos))
-	args = append(args, time.Now())
-	argPos++
-
-	args = append(args, id)
-	query := fmt.Sprintf("UPDATE posts SET %s WHERE id = $%d", strings.Join(updates, ", "), argPos)
-	_, err := db.Exec(query, args...)
+// CompleteIdempotencyKey сохраняет ответ на запрос, занявший ключ
+func (db *DB) CompleteIdempotencyKey(userID int64, key string, status int, contentType string, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $3, content_type = $4, response_body = $5, completed_at = NOW()
+	          WHERE user_id = $1 AND key = $2`
+	_, err := db.Exec(query, userID, key, status, contentType, body)
 	return err
 }
 
-// DeletePost удаляет пост
-func (db *DB) DeletePost(id int64) error {
-	query := `DELETE FROM posts WHERE id = $1`
-	_, err := db.Exec(query, id)
+// ReleaseIdempotencyKey освобождает ключ незавершенного запроса
+func (db *DB) ReleaseIdempotencyKey(userID int64, key string) error {
+	_, err := db.Exec(`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`, userID, key)
 	return err
 }
 
-// UpdatePostCollected обновляет собранную сумму поста
-func (db *DB) UpdatePostCollected(postID int64, amount float64) error {
-	query := `UPDATE posts SET collected = collected + $1, updated_at = NOW() WHERE id = $2`
-	_, err := db.Exec(query, amount, postID)
-	return err
+// DeleteExpiredIdempotencyKeys удаляет ключи, созданные до before
+func (db *DB) DeleteExpiredIdempotencyKeys(before time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM idempotency_keys WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
 }
 
-// ========== PostMedia functions ==========
+// ========== Partner webhook functions ==========
 
-// CreatePostMedia создает медиа файл для поста
-func (db *DB) CreatePostMedia(postID int64, mediaURL, mediaType string, orderIndex int) (*PostMedia, error) {
-	var pm PostMedia
-	query := `INSERT INTO post_media (post_id, media_url, media_type, order_index)
-	          VALUES ($1, $2, $3, $4)
-	          RETURNING id, post_id, media_url, media_type, order_index, created_at`
-	err := db.QueryRow(query, postID, mediaURL, mediaType, orderIndex).Scan(
-		&pm.ID, &pm.PostID, &pm.MediaURL, &pm.MediaType, &pm.OrderIndex, &pm.CreatedAt,
-	)
+const partnerWebhookColumns = `id, name, url, secret, events, is_active, created_by, created_at, updated_at`
+
+func scanPartnerWebhook(row interface{ Scan(...interface{}) error }) (*PartnerWebhook, error) {
+	var w PartnerWebhook
+	err := row.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, pq.Array(&w.Events), &w.IsActive, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create post media: %w", err)
+		return nil, err
 	}
-	return &pm, nil
+	return &w, nil
 }
 
-// GetPostMedia получает все медиа файлы поста
-func (db *DB) GetPostMedia(postID int64) ([]PostMedia, error) {
-	query := `SELECT id, post_id, media_url, media_type, order_index, created_at
-	          FROM post_media WHERE post_id = $1 ORDER BY order_index`
-	rows, err := db.Query(query, postID)
+// CreatePartnerWebhook создает вебхук партнера
+func (db *DB) CreatePartnerWebhook(w *PartnerWebhook) error {
+	query := `INSERT INTO webhooks (name, url, secret, events, created_by)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING ` + partnerWebhookColumns
+	created, err := scanPartnerWebhook(db.QueryRow(query, w.Name, w.URL, w.Secret, pq.Array(w.Events), w.CreatedBy))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	*w = *created
+	return nil
+}
+
+// GetPartnerWebhooks получает все вебхуки партнеров
+func (db *DB) GetPartnerWebhooks() ([]PartnerWebhook, error) {
+	rows, err := db.Query(`SELECT ` + partnerWebhookColumns + ` FROM webhooks ORDER BY id`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var media []PostMedia
+	webhooks := []PartnerWebhook{}
 	for rows.Next() {
-		var pm PostMedia
-		err := rows.Scan(&pm.ID, &pm.PostID, &pm.MediaURL, &pm.MediaType, &pm.OrderIndex, &pm.CreatedAt)
+		w, err := scanPartnerWebhook(rows)
 		if err != nil {
 			return nil, err
 		}
-		media = append(media, pm)
+		webhooks = append(webhooks, *w)
 	}
-	return media, nil
-}
-
-// DeletePostMedia удаляет медиа файл
-func (db *DB) DeletePostMedia(mediaID int64) error {
-	query := `DELETE FROM post_media WHERE id = $1`
-	_, err := db.Exec(query, mediaID)
-	return err
-}
-
-// ========== Donation functions ==========
-
-// CreateDonation создает пожертвование
-func (db *DB) CreateDonation(d *Donation) error {
-	query := `INSERT INTO donations (post_id, donor_id, amount, receipt_url)
-	          VALUES ($1, $2, $3, $4)
-	          RETURNING id, status, created_at`
-	err := db.QueryRow(query, d.PostID, d.DonorID, d.Amount, d.ReceiptURL).Scan(
-		&d.ID, &d.Status, &d.CreatedAt,
-	)
-	return err
+	return webhooks, rows.Err()
 }
 
-// GetDonationByID получает пожертвование по ID
-func (db *DB) GetDonationByID(id int64) (*Donation, error) {
-	var d Donation
-	query := `SELECT id, post_id, donor_id, amount, receipt_url, status, confirmed_at, confirmed_by, created_at
-	          FROM donations WHERE id = $1`
-	err := db.QueryRow(query, id).Scan(
-		&d.ID, &d.PostID, &d.DonorID, &d.Amount, &d.ReceiptURL,
-		&d.Status, &d.ConfirmedAt, &d.ConfirmedBy, &d.CreatedAt,
-	)
+// GetPartnerWebhook получает вебхук партнера по ID
+func (db *DB) GetPartnerWebhook(id int64) (*PartnerWebhook, error) {
+	w, err := scanPartnerWebhook(db.QueryRow(`SELECT `+partnerWebhookColumns+` FROM webhooks WHERE id = $1`, id))
 	if err == sql.ErrNoRows {
-		return nil, NewNotFoundError("Пожертвование")
+		return nil, NewNotFoundError("Вебхук")
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get donation: %w", err)
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
 	}
-	return &d, nil
+	return w, nil
 }
 
-// GetDonations получает список пожертвований с фильтрацией
-func (db *DB) GetDonations(postID, donorID *int64, status string, page, limit int) ([]Donation, int, error) {
-	where := "1=1"
-	args := []interface{}{}
-	argPos := 1
+// UpdatePartnerWebhook сохраняет изменения вебхука партнера. При отключении
+// неотправленные события отменяются.
+func (db *DB) UpdatePartnerWebhook(w *PartnerWebhook) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	if postID != nil {
-		where += fmt.Sprintf(" AND post_id = $%d", argPos)
-		args = append(args, *postID)
-		argPos++
+	query := `UPDATE webhooks SET name = $2, url = $3, secret = $4, events = $5, is_active = $6, updated_at = NOW()
+	          WHERE id = $1
+	          RETURNING ` + partnerWebhookColumns
+	updated, err := scanPartnerWebhook(tx.QueryRow(query, w.ID, w.Name, w.URL, w.Secret, pq.Array(w.Events), w.IsActive))
+	if err == sql.ErrNoRows {
+		return NewNotFoundError("Вебхук")
 	}
-	if donorID != nil {
-		where += fmt.Sprintf(" AND donor_id = $%d", argPos)
-		args = append(args, *donorID)
-		argPos++
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
 	}
-	if status != "" {
-		where += fmt.Sprintf(" AND status = $%d", argPos)
-		args = append(args, status)
-		argPos++
+	if !updated.IsActive {
+		query := `UPDATE webhook_deliveries SET status = 'failed', last_error = 'webhook disabled'
+		          WHERE webhook_id = $1 AND status = 'pending'`
+		if _, err := tx.Exec(query, w.ID); err != nil {
+			return fmt.Errorf("failed to cancel webhook deliveries: %w", err)
+		}
 	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*w = *updated
+	return nil
+}
 
-	// Подсчет общего количества
-	var total int
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM donations WHERE %s", where)
-	err := db.QueryRow(countQuery, args...).Scan(&total)
+// DeletePartnerWebhook удаляет вебхук партнера вместе с очередью его событий.
+// Возвращает false, если его не было.
+func (db *DB) DeletePartnerWebhook(id int64) (bool, error) {
+	result, err := db.Exec(`DELETE FROM webhooks WHERE id = $1`, id)
 	if err != nil {
-		return nil, 0, err
+		return false, err
 	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
 
-	// Получение данных
-	offset := (page - 1) * limit
-	query := fmt.Sprintf(`SELECT id, post_id, donor_id, amount, receipt_url, status, confirmed_at, confirmed_by, created_at
-	                      FROM donations WHERE %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`,
-		where, argPos, argPos+1)
-	args = append(args, limit, offset)
+// GetPartnerWebhookDeliveries получает события вебхука партнера, новые первыми.
+// status фильтрует по состоянию доставки (pending, delivered, failed), пустой - все.
+func (db *DB) GetPartnerWebhookDeliveries(webhookID int64, status string, page, limit int) ([]WebhookDeliveryInfo, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1 AND ($2 = '' OR status = $2)`
+	if err := db.QueryRow(countQuery, webhookID, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
 
-	rows, err := db.Query(query, args...)
+	query := `SELECT id, event, status, attempts, last_error,
+	                 CASE WHEN status = 'pending' THEN next_attempt_at END, delivered_at, created_at
+	          FROM webhook_deliveries
+	          WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+	          ORDER BY created_at DESC, id DESC
+	          LIMIT $3 OFFSET $4`
+	rows, err := db.Query(query, webhookID, status, limit, (page-1)*limit)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var donations []Donation
+	deliveries := []WebhookDeliveryInfo{}
 	for rows.Next() {
-		var d Donation
-		err := rows.Scan(
-			&d.ID, &d.PostID, &d.DonorID, &d.Amount, &d.ReceiptURL,
-			&d.Status, &d.ConfirmedAt, &d.ConfirmedBy, &d.CreatedAt,
-		)
-		if err != nil {
+		var d WebhookDeliveryInfo
+		if err := rows.Scan(&d.ID, &d.Event, &d.Status, &d.Attempts, &d.LastError, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt); err != nil {
 			return nil, 0, err
 		}
-		donations = append(donations, d)
+		deliveries = append(deliveries, d)
 	}
-
-	return donations, total, nil
-}
-
-// UpdateDonationStatus обновляет статус пожертвования
-func (db *DB) UpdateDonationStatus(id int64, status string, confirmedBy int64) error {
-	query := `UPDATE donations 
-	          SET status = $1, confirmed_at = NOW(), confirmed_by = $2 
-	          WHERE id = $3`
-	_, err := db.Exec(query, status, confirmedBy, id)
-	return err
+	return deliveries, total, rows.Err()
 }
 
 // ========== Chat functions ==========
@@ -761,6 +5968,178 @@ func (db *DB) GetChatsByUserID(userID int64) ([]Chat, error) {
 	return chats, nil
 }
 
+// GetChatsWithDetails получает чаты пользователя вместе с постом, его автором,
+// собеседником, последним сообщением и числом непрочитанных одним запросом
+func (db *DB) GetChatsWithDetails(userID int64) ([]ChatWithDetails, error) {
+	query := `SELECT c.id, c.post_id, c.helper_id, c.needy_id, c.created_at, c.updated_at,
+	                 p.id, p.user_id, p.title, p.description, p.amount, p.collected, p.recipient, p.bank, p.phone,
+	                 p.status, p.created_at, p.updated_at, p.is_editable, p.beneficiary_is_minor, p.guardian_document_url,
+	                 COALESCE(p.language, ''), p.deadline, p.urgent, p.urgent_until, p.urgent_document_url,
+	                 pa.id, pa.first_name, pa.last_name, COALESCE(pa.photo_variants->>'small', pa.photo_url), ` + awayColumns("pa") + `,
+	                 iu.id, iu.first_name, iu.last_name, COALESCE(iu.photo_variants->>'small', iu.photo_url), ` + awayColumns("iu") + `,
+	                 lm.id, lm.chat_id, lm.sender_id, lm.text, lm.attachment_url, lm.is_read, lm.is_edited, lm.created_at, lm.updated_at,
+	                 uc.count
+	          FROM chats c
+	          JOIN posts p ON p.id = c.post_id
+	          JOIN users pa ON pa.id = p.user_id
+	          JOIN users iu ON iu.id = CASE WHEN c.helper_id = $1 THEN c.needy_id ELSE c.helper_id END
+	          LEFT JOIN LATERAL (
+	              SELECT id, chat_id, sender_id, text, attachment_url, is_read, is_edited, created_at, updated_at
+	              FROM messages WHERE chat_id = c.id ORDER BY created_at DESC LIMIT 1
+	          ) lm ON true
+	          CROSS JOIN LATERAL (
+	              SELECT COUNT(*) AS count FROM messages
+	              WHERE chat_id = c.id AND sender_id != $1 AND is_read = false
+	          ) uc
+	          WHERE c.helper_id = $1 OR c.needy_id = $1
+	             OR c.post_id IN (SELECT post_id FROM post_collaborators
+	                              WHERE user_id = $1 AND status = 'accepted' AND 'chats' = ANY(permissions))
+	          ORDER BY c.updated_at DESC`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []ChatWithDetails
+	for rows.Next() {
+		var c ChatWithDetails
+		var p PostWithDetails
+		var author, interlocutor UserInfo
+		var authorFirst, authorLast, interlocutorFirst, interlocutorLast string
+		var authorAway, interlocutorAway *string
+		var authorAwayUntil, interlocutorAwayUntil *time.Time
+		var lastID, lastChatID, lastSenderID sql.NullInt64
+		var lastIsRead, lastIsEdited sql.NullBool
+		var lastCreatedAt, lastUpdatedAt sql.NullTime
+		var last Message
+		err := rows.Scan(
+			&c.ID, &c.PostID, &c.HelperID, &c.NeedyID, &c.CreatedAt, &c.UpdatedAt,
+			&p.ID, &p.UserID, &p.Title, &p.Description, &p.Amount, &p.Collected, &p.Recipient, &p.Bank, &p.Phone,
+			&p.Status, &p.CreatedAt, &p.UpdatedAt, &p.IsEditable, &p.BeneficiaryIsMinor, &p.GuardianDocumentURL,
+			&p.Language, &p.Deadline, &p.Urgent, &p.UrgentUntil, &p.UrgentDocumentURL,
+			&author.ID, &authorFirst, &authorLast, &author.Avatar, &authorAway, &authorAwayUntil,
+			&interlocutor.ID, &interlocutorFirst, &interlocutorLast, &interlocutor.Avatar, &interlocutorAway, &interlocutorAwayUntil,
+			&lastID, &lastChatID, &lastSenderID, &last.Text, &last.AttachmentURL, &lastIsRead, &lastIsEdited, &lastCreatedAt, &lastUpdatedAt,
+			&c.UnreadCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		author.Name = fmt.Sprintf("%s %s", authorFirst, authorLast)
+		interlocutor.Name = fmt.Sprintf("%s %s", interlocutorFirst, interlocutorLast)
+		author.Away = newAwayStatus(authorAway, authorAwayUntil)
+		interlocutor.Away = newAwayStatus(interlocutorAway, interlocutorAwayUntil)
+		p.Author = &author
+		c.Post = &p
+		c.Interlocutor = &interlocutor
+
+		if lastID.Valid {
+			last.ID = lastID.Int64
+			last.ChatID = lastChatID.Int64
+			last.SenderID = lastSenderID.Int64
+			last.IsRead = lastIsRead.Bool
+			last.IsEdited = lastIsEdited.Bool
+			last.CreatedAt = lastCreatedAt.Time
+			last.UpdatedAt = lastUpdatedAt.Time
+			c.LastMessage = &last
+		}
+
+		chats = append(chats, c)
+	}
+	return chats, rows.Err()
+}
+
+// ClaimAwayReply отмечает, что в чат отправлен автоответ собеседника отправителя.
+// Возвращает собеседника и текст автоответа, если он отсутствует и автоответа в чате еще не было.
+func (db *DB) ClaimAwayReply(chatID, senderID int64) (int64, string, bool, error) {
+	var recipientID int64
+	var message string
+	query := `UPDATE chats c SET away_replied = true
+	          FROM users u
+	          WHERE c.id = $1 AND NOT c.away_replied
+	            AND u.id = CASE WHEN c.helper_id = $2 THEN c.needy_id ELSE c.helper_id END
+	            AND u.away_message IS NOT NULL AND (u.away_until IS NULL OR u.away_until > NOW())
+	          RETURNING u.id, u.away_message`
+	err := db.QueryRow(query, chatID, senderID).Scan(&recipientID, &message)
+	if err == sql.ErrNoRows {
+		return 0, "", false, nil
+	}
+	if err != nil {
+		return 0, "", false, err
+	}
+	return recipientID, message, true, nil
+}
+
+// RecalculateResponseStats пересчитывает медиану времени первого ответа авторов постов:
+// от первого сообщения помогающего до первого ответа автора после него.
+// Чаты с автоответом не учитываются, авторы с малым числом ответов исключаются.
+func (db *DB) RecalculateResponseStats() (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `WITH asked AS (
+	              SELECT c.id, c.needy_id,
+	                     (SELECT MIN(created_at) FROM messages WHERE chat_id = c.id AND sender_id = c.helper_id) AS asked_at
+	              FROM chats c
+	              WHERE NOT c.away_replied AND c.created_at > NOW() - $1 * INTERVAL '1 day'
+	          ), answered AS (
+	              SELECT a.needy_id,
+	                     EXTRACT(EPOCH FROM (
+	                         SELECT MIN(created_at) FROM messages
+	                         WHERE chat_id = a.id AND sender_id = a.needy_id AND created_at > a.asked_at
+	                     ) - a.asked_at) AS seconds
+	              FROM asked a WHERE a.asked_at IS NOT NULL
+	          )
+	          INSERT INTO author_response_stats (user_id, median_seconds, sample_size, updated_at)
+	          SELECT needy_id, percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds)::int, COUNT(*), NOW()
+	          FROM answered WHERE seconds IS NOT NULL
+	          GROUP BY needy_id HAVING COUNT(*) >= $2
+	          ON CONFLICT (user_id) DO UPDATE
+	          SET median_seconds = EXCLUDED.median_seconds, sample_size = EXCLUDED.sample_size, updated_at = EXCLUDED.updated_at`
+	result, err := tx.Exec(query, responseStatsWindow, minResponseSamples)
+	if err != nil {
+		return 0, err
+	}
+
+	// Авторы, у которых ответов за окно стало меньше минимума (NOW() в транзакции не меняется,
+	// поэтому у обновленных выше строк updated_at равен NOW())
+	if _, err := tx.Exec(`DELETE FROM author_response_stats WHERE updated_at < NOW()`); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	updated, _ := result.RowsAffected()
+	return int(updated), nil
+}
+
+// GetAuthorResponseTime получает время ответа автора. Без статистики возвращает nil.
+func (db *DB) GetAuthorResponseTime(userID int64) (*ResponseTime, error) {
+	var medianSeconds int
+	query := `SELECT median_seconds FROM author_response_stats WHERE user_id = $1`
+	err := db.QueryRow(query, userID).Scan(&medianSeconds)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewResponseTime(medianSeconds), nil
+}
+
+// CountUserChats подсчитывает чаты, в которых участвует пользователь
+func (db *DB) CountUserChats(userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM chats WHERE helper_id = $1 OR needy_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
 // UpdateChatUpdatedAt обновляет время последнего сообщения в чате
 func (db *DB) UpdateChatUpdatedAt(chatID int64) error {
 	query := `UPDATE chats SET updated_at = NOW() WHERE id = $1`
@@ -768,14 +6147,42 @@ func (db *DB) UpdateChatUpdatedAt(chatID int64) error {
 	return err
 }
 
+// GetChatByID получает чат по ID
+func (db *DB) GetChatByID(id int64) (*Chat, error) {
+	query := `SELECT id, post_id, helper_id, needy_id, created_at, updated_at FROM chats WHERE id = $1`
+	var chat Chat
+	err := db.QueryRow(query, id).Scan(&chat.ID, &chat.PostID, &chat.HelperID, &chat.NeedyID, &chat.CreatedAt, &chat.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Чат")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+	return &chat, nil
+}
+
 // ========== Message functions ==========
 
-// CreateMessage создает сообщение
+// NextMessageID резервирует ID сообщения, чтобы загрузить вложение до создания сообщения
+func (db *DB) NextMessageID() (int64, error) {
+	var id int64
+	query := `SELECT nextval(pg_get_serial_sequence('messages', 'id'))`
+	if err := db.QueryRow(query).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to reserve message id: %w", err)
+	}
+	return id, nil
+}
+
+// CreateMessage создает сообщение. Если m.ID задан, используется зарезервированный ID.
 func (db *DB) CreateMessage(m *Message) error {
-	query := `INSERT INTO messages (chat_id, sender_id, text, attachment_url)
-	          VALUES ($1, $2, $3, $4)
+	var id *int64
+	if m.ID != 0 {
+		id = &m.ID
+	}
+	query := `INSERT INTO messages (id, chat_id, sender_id, text, attachment_url)
+	          VALUES (COALESCE($1, nextval(pg_get_serial_sequence('messages', 'id'))), $2, $3, $4, $5)
 	          RETURNING id, is_read, is_edited, created_at, updated_at`
-	err := db.QueryRow(query, m.ChatID, m.SenderID, m.Text, m.AttachmentURL).Scan(
+	err := db.QueryRow(query, id, m.ChatID, m.SenderID, m.Text, m.AttachmentURL).Scan(
 		&m.ID, &m.IsRead, &m.IsEdited, &m.CreatedAt, &m.UpdatedAt,
 	)
 	if err != nil {
@@ -825,12 +6232,67 @@ func (db *DB) GetMessages(chatID int64, page, limit int) ([]Message, int, error)
 	return messages, total, nil
 }
 
-// MarkMessagesAsRead отмечает сообщения как прочитанные
-func (db *DB) MarkMessagesAsRead(chatID int64, messageIDs []int64) (int, error) {
+// GetMessagesByCursor получает сообщения чата относительно курсора: до beforeID
+// (более старые) или после afterID (более новые). Id сообщений растут со временем,
+// поэтому новые сообщения не сдвигают страницы, как при OFFSET.
+// Возвращает сообщения от старых к новым и признак, что за страницей есть еще сообщения.
+func (db *DB) GetMessagesByCursor(chatID int64, beforeID, afterID *int64, limit int) ([]Message, bool, error) {
+	var query string
+	var cursor int64
+	if afterID != nil {
+		cursor = *afterID
+		query = `SELECT id, chat_id, sender_id, text, attachment_url, is_read, is_edited, created_at, updated_at
+		         FROM messages WHERE chat_id = $1 AND id > $2 ORDER BY id ASC LIMIT $3`
+	} else {
+		cursor = *beforeID
+		query = `SELECT id, chat_id, sender_id, text, attachment_url, is_read, is_edited, created_at, updated_at
+		         FROM messages WHERE chat_id = $1 AND id < $2 ORDER BY id DESC LIMIT $3`
+	}
+
+	// Запрашиваем на одно сообщение больше, чтобы узнать, есть ли следующая страница
+	rows, err := db.Query(query, chatID, cursor, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		err := rows.Scan(
+			&m.ID, &m.ChatID, &m.SenderID, &m.Text, &m.AttachmentURL,
+			&m.IsRead, &m.IsEdited, &m.CreatedAt, &m.UpdatedAt,
+		)
+		if err != nil {
+			return nil, false, err
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Реверс для правильного порядка (от старых к новым)
+	if afterID == nil {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	return messages, hasMore, nil
+}
+
+// MarkMessagesAsRead отмечает прочитанными сообщения чата, отправленные не readerID
+func (db *DB) MarkMessagesAsRead(chatID, readerID int64, messageIDs []int64) (int, error) {
 	if len(messageIDs) == 0 {
 		// Отметить все сообщения в чате
-		query := `UPDATE messages SET is_read = true WHERE chat_id = $1 AND is_read = false`
-		result, err := db.Exec(query, chatID)
+		query := `UPDATE messages SET is_read = true WHERE chat_id = $1 AND sender_id <> $2 AND is_read = false`
+		result, err := db.Exec(query, chatID, readerID)
 		if err != nil {
 			return 0, err
 		}
@@ -839,8 +6301,8 @@ func (db *DB) MarkMessagesAsRead(chatID int64, messageIDs []int64) (int, error)
 	}
 
 	// Отметить конкретные сообщения
-	query := `UPDATE messages SET is_read = true WHERE chat_id = $1 AND id = ANY($2)`
-	result, err := db.Exec(query, chatID, pq.Array(messageIDs))
+	query := `UPDATE messages SET is_read = true WHERE chat_id = $1 AND sender_id <> $2 AND id = ANY($3)`
+	result, err := db.Exec(query, chatID, readerID, pq.Array(messageIDs))
 	if err != nil {
 		return 0, err
 	}
@@ -855,6 +6317,24 @@ func (db *DB) UpdateMessage(messageID int64, text string) error {
 	return err
 }
 
+// GetMessageByID получает сообщение по ID
+func (db *DB) GetMessageByID(messageID int64) (*Message, error) {
+	var m Message
+	query := `SELECT id, chat_id, sender_id, text, attachment_url, is_read, is_edited, created_at, updated_at
+	          FROM messages WHERE id = $1`
+	err := db.QueryRow(query, messageID).Scan(
+		&m.ID, &m.ChatID, &m.SenderID, &m.Text, &m.AttachmentURL,
+		&m.IsRead, &m.IsEdited, &m.CreatedAt, &m.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, NewNotFoundError("Сообщение")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return &m, nil
+}
+
 // DeleteMessage удаляет сообщение
 func (db *DB) DeleteMessage(messageID int64) error {
 	query := `DELETE FROM messages WHERE id = $1`
@@ -888,6 +6368,41 @@ func (db *DB) GetUnreadCount(chatID, userID int64) (int, error) {
 	return count, err
 }
 
+// ========== Draft functions ==========
+
+// GetChatDraft получает черновик пользователя в чате
+func (db *DB) GetChatDraft(chatID, userID int64) (*ChatDraft, error) {
+	d := ChatDraft{ChatID: chatID}
+	query := `SELECT text, updated_at FROM chat_drafts WHERE chat_id = $1 AND user_id = $2`
+	err := db.QueryRow(query, chatID, userID).Scan(&d.Text, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat draft: %w", err)
+	}
+	return &d, nil
+}
+
+// SaveChatDraft сохраняет черновик пользователя в чате
+func (db *DB) SaveChatDraft(chatID, userID int64, text string) (*ChatDraft, error) {
+	d := ChatDraft{ChatID: chatID, Text: text}
+	query := `INSERT INTO chat_drafts (chat_id, user_id, text) VALUES ($1, $2, $3)
+	          ON CONFLICT (chat_id, user_id) DO UPDATE SET text = EXCLUDED.text, updated_at = NOW()
+	          RETURNING updated_at`
+	if err := db.QueryRow(query, chatID, userID, text).Scan(&d.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save chat draft: %w", err)
+	}
+	return &d, nil
+}
+
+// DeleteChatDraft удаляет черновик пользователя в чате
+func (db *DB) DeleteChatDraft(chatID, userID int64) error {
+	query := `DELETE FROM chat_drafts WHERE chat_id = $1 AND user_id = $2`
+	_, err := db.Exec(query, chatID, userID)
+	return err
+}
+
 // ========== Rating functions ==========
 
 // GetOrCreateRating получает или создает рейтинг пользователя
@@ -915,9 +6430,52 @@ func (db *DB) GetOrCreateRating(userID int64) (*Rating, error) {
 	return &r, nil
 }
 
-// UpdateRating обновляет рейтинг пользователя
-func (db *DB) UpdateRating(userID int64, points int, totalDonated float64) error {
-	// Вычисляем статус на основе баллов
+// RecalculateRatings пересчитывает баллы, сумму пожертвований и статус по журналу
+// у рейтингов, разошедшихся с ним, и возвращает их количество
+func (db *DB) RecalculateRatings() (int, error) {
+	query := `WITH totals AS (
+	              SELECT r.user_id,
+	                     GREATEST(COALESCE(SUM(e.points_delta), 0), 0) AS points,
+	                     GREATEST(COALESCE(SUM(e.amount_delta), 0), 0) AS total_donated
+	              FROM ratings r LEFT JOIN rating_events e ON e.user_id = r.user_id
+	              GROUP BY r.user_id
+	          )
+	          UPDATE ratings r SET points = t.points, total_donated = t.total_donated, updated_at = NOW()
+	          FROM totals t
+	          WHERE r.user_id = t.user_id AND (r.points <> t.points OR r.total_donated <> t.total_donated)
+	          RETURNING r.user_id, r.points`
+	rows, err := db.Query(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recalculate ratings: %w", err)
+	}
+	type fixedRating struct {
+		userID int64
+		points int
+	}
+	var fixed []fixedRating
+	for rows.Next() {
+		var f fixedRating
+		if err := rows.Scan(&f.userID, &f.points); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		fixed = append(fixed, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, f := range fixed {
+		if _, err := db.Exec(`UPDATE ratings SET status = $1 WHERE user_id = $2`, ratingStatus(f.points), f.userID); err != nil {
+			return len(fixed), fmt.Errorf("failed to update rating status: %w", err)
+		}
+	}
+	return len(fixed), nil
+}
+
+// ratingStatus вычисляет статус на основе баллов
+func ratingStatus(points int) *string {
 	var status *string
 	if points >= 5501 {
 		s := "Пламенное Сердце"
@@ -932,12 +6490,7 @@ func (db *DB) UpdateRating(userID int64, points int, totalDonated float64) error
 		s := "Друг Платформы"
 		status = &s
 	}
-
-	query := `UPDATE ratings 
-	          SET points = $1, total_donated = $2, status = $3, updated_at = NOW() 
-	          WHERE user_id = $4`
-	_, err := db.Exec(query, points, totalDonated, status, userID)
-	return err
+	return status
 }
 
 // GetRatings получает рейтинг пользователей с пагинацией
@@ -973,6 +6526,82 @@ func (db *DB) GetRatings(page, limit int) ([]Rating, int, error) {
 	return ratings, total, nil
 }
 
+// GetPeriodRatings получает рейтинг по пожертвованиям, подтвержденным начиная с since:
+// баллы и сумма складываются из пожертвований периода, а не из накопленных в ratings.
+// Реферальные бонусы в рейтинг периода не входят. Status - текущий статус донора,
+// UpdatedAt - время последнего подтверждения в периоде.
+func (db *DB) GetPeriodRatings(since time.Time, page, limit int) ([]Rating, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(DISTINCT donor_id) FROM donations WHERE status = 'confirmed' AND confirmed_at >= $1`
+	if err := db.QueryRow(countQuery, since).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// Пожертвования, подтвержденные до учета акций, принесли по баллу за рубль
+	offset := (page - 1) * limit
+	query := `SELECT COALESCE(r.id, 0), d.donor_id, d.points, d.total_donated, r.status, d.updated_at
+	          FROM (SELECT donor_id,
+	                       SUM(COALESCE(rating_points, FLOOR(amount)::int)) AS points,
+	                       SUM(amount) AS total_donated,
+	                       MAX(confirmed_at) AS updated_at
+	                FROM donations WHERE status = 'confirmed' AND confirmed_at >= $1
+	                GROUP BY donor_id) d
+	          LEFT JOIN ratings r ON r.user_id = d.donor_id
+	          ORDER BY d.points DESC, d.total_donated DESC, d.donor_id
+	          LIMIT $2 OFFSET $3`
+	rows, err := db.Query(query, since, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var ratings []Rating
+	for rows.Next() {
+		var r Rating
+		if err := rows.Scan(&r.ID, &r.UserID, &r.Points, &r.TotalDonated, &r.Status, &r.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		ratings = append(ratings, r)
+	}
+	return ratings, total, rows.Err()
+}
+
+// GetRatingHistory получает журнал баллов пользователя, новые записи первыми.
+// Balance - баллы после записи: сумма журнала до нее включительно.
+func (db *DB) GetRatingHistory(userID int64, page, limit int) ([]RatingEvent, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM rating_events WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	query := `SELECT e.id, e.user_id, e.source, e.donation_id, d.post_id, p.title, e.referral_id,
+	                 e.points_delta, e.amount_delta, e.balance, e.created_at
+	          FROM (SELECT *, SUM(points_delta) OVER (ORDER BY id) AS balance
+	                FROM rating_events WHERE user_id = $1) e
+	          LEFT JOIN donations d ON d.id = e.donation_id
+	          LEFT JOIN posts p ON p.id = d.post_id
+	          ORDER BY e.id DESC
+	          LIMIT $2 OFFSET $3`
+	rows, err := db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []RatingEvent{}
+	for rows.Next() {
+		var e RatingEvent
+		err := rows.Scan(&e.ID, &e.UserID, &e.Source, &e.DonationID, &e.PostID, &e.PostTitle, &e.ReferralID,
+			&e.Points, &e.Amount, &e.Balance, &e.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+	return events, total, rows.Err()
+}
+
 // GetRatingPosition получает позицию пользователя в рейтинге
 func (db *DB) GetRatingPosition(userID int64) (int, error) {
 	var position int
@@ -980,3 +6609,98 @@ func (db *DB) GetRatingPosition(userID int64) (int, error) {
 	err := db.QueryRow(query, userID).Scan(&position)
 	return position, err
 }
+
+// ========== Points event functions ==========
+
+const pointsEventColumns = `id, title, multiplier, starts_at, ends_at, created_by, created_at`
+
+func scanPointsEvents(rows *sql.Rows) ([]PointsEvent, error) {
+	defer rows.Close()
+	events := []PointsEvent{}
+	for rows.Next() {
+		var e PointsEvent
+		if err := rows.Scan(&e.ID, &e.Title, &e.Multiplier, &e.StartsAt, &e.EndsAt, &e.CreatedBy, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// CreatePointsEvent создает акцию с множителем баллов
+func (db *DB) CreatePointsEvent(e *PointsEvent) error {
+	query := `INSERT INTO points_events (title, multiplier, starts_at, ends_at, created_by)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING id, created_at`
+	if err := db.QueryRow(query, e.Title, e.Multiplier, e.StartsAt, e.EndsAt, e.CreatedBy).Scan(&e.ID, &e.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create points event: %w", err)
+	}
+	return nil
+}
+
+// GetPointsEvents получает акции, последние по началу первыми
+func (db *DB) GetPointsEvents(page, limit int) ([]PointsEvent, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM points_events`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT ` + pointsEventColumns + ` FROM points_events ORDER BY starts_at DESC, id DESC LIMIT $1 OFFSET $2`
+	rows, err := db.Query(query, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	events, err := scanPointsEvents(rows)
+	return events, total, err
+}
+
+// GetCurrentPointsEvents получает идущие и будущие акции, ближайшие первыми
+func (db *DB) GetCurrentPointsEvents() ([]PointsEvent, error) {
+	query := `SELECT ` + pointsEventColumns + ` FROM points_events WHERE ends_at > NOW() ORDER BY starts_at, id`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	return scanPointsEvents(rows)
+}
+
+// CancelPointsEvent отменяет акцию: еще не начавшаяся удаляется, идущая завершается
+// сейчас, чтобы у уже созданных пожертвований сохранился множитель. Закончившуюся
+// акцию отменить нельзя.
+func (db *DB) CancelPointsEvent(id int64) (*PointsEvent, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT `+pointsEventColumns+` FROM points_events WHERE id = $1 FOR UPDATE`, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := scanPointsEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, NewNotFoundError("Акция")
+	}
+	event := &events[0]
+
+	now := time.Now()
+	switch {
+	case !event.EndsAt.After(now):
+		return nil, NewConflictError("Акция уже закончилась")
+	case !event.StartsAt.Before(now):
+		_, err = tx.Exec(`DELETE FROM points_events WHERE id = $1`, id)
+	default:
+		event.EndsAt = now
+		_, err = tx.Exec(`UPDATE points_events SET ends_at = $2 WHERE id = $1`, id, now)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel points event: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tmphackbackend/httpclient"
+)
+
+// DigestRunner формирует еженедельную сводку для администраторов и рассылает ее.
+// Задача запускается чаще раза в неделю: сводка за прошедшую неделю (с понедельника,
+// по московскому времени) формируется при первом запуске после ее окончания, в том
+// числе после перезапуска сервера. Уникальность недели в digests не дает экземплярам
+// отправить сводку дважды.
+type DigestRunner struct {
+	db       *DB
+	cfg      DigestConfig
+	botToken string
+	telegram *httpclient.Client
+}
+
+func NewDigestRunner(db *DB, cfg DigestConfig, botToken string, telegram *httpclient.Client) *DigestRunner {
+	return &DigestRunner{db: db, cfg: cfg, botToken: botToken, telegram: telegram}
+}
+
+// digestWeek возвращает границы последней завершенной недели
+func digestWeek(now time.Time) (time.Time, time.Time) {
+	if loc, err := LoadUserLocation(DefaultTimezone); err == nil {
+		now = now.In(loc)
+	}
+	offset := (int(now.Weekday()) + 6) % 7
+	end := time.Date(now.Year(), now.Month(), now.Day()-offset, 0, 0, 0, 0, now.Location())
+	return end.AddDate(0, 0, -7), end
+}
+
+// Run формирует и рассылает сводку за прошедшую неделю, если ее еще нет
+func (r *DigestRunner) Run(ctx context.Context) error {
+	start, end := digestWeek(time.Now())
+	exists, err := r.db.DigestExists(start)
+	if err != nil || exists {
+		return err
+	}
+
+	report, err := r.db.GetDigestReport(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	digest := &Digest{PeriodStart: start, PeriodEnd: end, Report: *report}
+	digest.Text = formatDigest(digest)
+
+	created, err := r.db.CreateDigest(digest)
+	if err != nil || !created {
+		return err
+	}
+
+	deliveries := r.deliver(ctx, digest)
+	if err := r.db.SetDigestDeliveries(digest.ID, deliveries); err != nil {
+		return err
+	}
+	log.Printf("Digest for week %s created, %d deliveries", start.Format("2006-01-02"), len(deliveries))
+	return nil
+}
+
+// deliver отправляет сводку каждому получателю. Ошибка одного получателя
+// сохраняется в результатах и не мешает остальным.
+func (r *DigestRunner) deliver(ctx context.Context, digest *Digest) []DigestDelivery {
+	subject := fmt.Sprintf("Сводка за неделю %s", digestPeriod(digest))
+	deliveries := []DigestDelivery{}
+	for _, email := range r.cfg.Emails {
+		delivery := DigestDelivery{Channel: DigestChannelEmail, Target: email, Sent: true}
+		if err := SendMail(r.cfg.SMTP, email, subject, digest.Text); err != nil {
+			delivery.Sent, delivery.Error = false, err.Error()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	for _, chatID := range r.cfg.TelegramChatIDs {
+		delivery := DigestDelivery{Channel: DigestChannelTelegram, Target: chatID, Sent: true}
+		if err := sendTelegramMessage(ctx, r.telegram, r.botToken, chatID, digest.Text); err != nil {
+			delivery.Sent, delivery.Error = false, err.Error()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	for _, delivery := range deliveries {
+		result := "sent"
+		if !delivery.Sent {
+			result = "failed"
+			log.Printf("Failed to send digest to %s %s: %s", delivery.Channel, delivery.Target, delivery.Error)
+		}
+		metrics.Inc("digest_deliveries_total", "Weekly digest deliveries by channel and result",
+			map[string]string{"channel": delivery.Channel, "result": result})
+	}
+	return deliveries
+}
+
+// digestPeriod возвращает неделю сводки в виде "дд.мм.гггг - дд.мм.гггг"
+func digestPeriod(digest *Digest) string {
+	start, end := digest.PeriodStart, digest.PeriodEnd
+	if loc, err := LoadUserLocation(DefaultTimezone); err == nil {
+		start, end = start.In(loc), end.In(loc)
+	}
+	return start.Format("02.01.2006") + " - " + end.AddDate(0, 0, -1).Format("02.01.2006")
+}
+
+// formatDigest формирует текст сводки для письма и Telegram
+func formatDigest(digest *Digest) string {
+	report := digest.Report
+	var b strings.Builder
+	fmt.Fprintf(&b, "Сводка за неделю %s\n\n", digestPeriod(digest))
+	fmt.Fprintf(&b, "Новых сборов: %d\n", report.NewPosts)
+	fmt.Fprintf(&b, "Подтверждено пожертвований: %d на %.2f ₽\n\n", report.DonationsConfirmed, report.AmountRaised)
+
+	fmt.Fprintf(&b, "Верификация: %d заявок в очереди, рассмотрено за неделю %d\n", report.VerificationsPending, report.VerificationsReviewed)
+	if report.VerificationsOldestAt != nil {
+		oldest := *report.VerificationsOldestAt
+		if loc, err := LoadUserLocation(DefaultTimezone); err == nil {
+			oldest = oldest.In(loc)
+		}
+		fmt.Fprintf(&b, "Самая старая заявка подана %s\n", oldest.Format("02.01.2006 15:04"))
+	}
+
+	b.WriteString("\nТребует внимания:\n")
+	fmt.Fprintf(&b, "Сборов на модерации: %d\n", report.PostsModeration)
+	fmt.Fprintf(&b, "Запросов на повышение лимита: %d\n", report.LimitRequestsPending)
+	fmt.Fprintf(&b, "Чеков с расхождением за неделю: %d\n", report.ReceiptMismatches)
+	return b.String()
+}
@@ -23,35 +23,101 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/login": {
-            "post": {
-                "description": "Аутентифицирует пользователя и возвращает JWT токен",
-                "consumes": [
-                    "application/json"
+        "/admin/audit-log": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Возвращает действия администраторов и модераторов (блокировки, решения по верификациям, постам,\nпожертвованиям и лимитам, смена ролей и прав) со значениями до и после изменения, новые первыми.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Аутентификация"
+                    "Пользователи"
                 ],
-                "summary": "Вход в систему",
+                "summary": "Журнал действий администраторов",
                 "parameters": [
                     {
-                        "description": "Данные входа",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.LoginRequest"
-                        }
+                        "type": "integer",
+                        "description": "Кто выполнил действие",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Действие, например user.block",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "user",
+                            "verification",
+                            "post",
+                            "donation",
+                            "post_limit_request",
+                            "role",
+                            "risk_tier",
+                            "invite_batch"
+                        ],
+                        "type": "string",
+                        "description": "Тип объекта",
+                        "name": "target_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID объекта",
+                        "name": "target_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Пользователь, которого касается действие",
+                        "name": "target_user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (не включая), RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.LoginResponse"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "401": {
@@ -59,33 +125,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/auth/refresh": {
-            "post": {
+        "/admin/backups/status": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Обновляет JWT токен пользователя",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает результат последнего резервного копирования (только для администраторов)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Аутентификация"
+                    "Утилиты"
                 ],
-                "summary": "Обновление токена",
+                "summary": "Статус резервного копирования",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.RefreshTokenResponse"
+                            "$ref": "#/definitions/main.BackupStatusResponse"
                         }
                     },
                     "401": {
@@ -93,39 +162,87 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Регистрирует нового пользователя в системе",
-                "consumes": [
-                    "application/json"
+        "/admin/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Возвращает фоновые задания, которые не удалось выполнить: рассылки уведомлений (не сохранены в базу\nили не поместились в очередь) и события интеграций и вебхуков партнеров после всех попыток доставки.\nДля каждого задания - ошибка, число попыток и ручных повторов, время, раньше которого повтор запрещен.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Аутентификация"
+                    "Утилиты"
                 ],
-                "summary": "Регистрация пользователя",
+                "summary": "Очередь недоставленных",
                 "parameters": [
                     {
-                        "description": "Данные регистрации",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.RegisterRequest"
-                        }
+                        "enum": [
+                            "notification",
+                            "webhook"
+                        ],
+                        "type": "string",
+                        "description": "Вид задания",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "failed",
+                            "retrying",
+                            "resolved",
+                            "discarded"
+                        ],
+                        "type": "string",
+                        "description": "Состояние",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (не включая), RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.RegisterResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -134,8 +251,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -143,29 +266,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/chats": {
-            "get": {
+        "/admin/dead-letters/{id}/discard": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает список всех чатов текущего пользователя",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Отмечает задание из очереди недоставленных как discarded: оно больше не повторяется\nи не учитывается в /admin/ops. Событие в webhook_deliveries остается в состоянии failed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Чаты"
+                    "Утилиты"
+                ],
+                "summary": "Закрыть задание",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID задания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Получить список чатов",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.ChatsListResponse"
+                            "$ref": "#/definitions/main.DeadLetter"
                         }
                     },
                     "401": {
@@ -173,52 +302,73 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/admin/dead-letters/{id}/retry": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Создает новый чат между помощником и нуждающимся по посту",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает задание в очередь: рассылку - в очередь уведомлений (только пользователям, которым она не дошла),\nсобытие - в webhook_deliveries для одной попытки доставки. Пока задание выполняется, оно в состоянии retrying;\nпри успехе переходит в resolved, при ошибке снова в failed. Следующий повтор разрешен не раньше next_retry_at\n(30с после первого повтора, затем пауза удваивается до 6 часов).",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Чаты"
+                    "Утилиты"
                 ],
-                "summary": "Создать чат",
+                "summary": "Повторить задание",
                 "parameters": [
                     {
-                        "description": "ID поста",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.CreateChatRequest"
-                        }
+                        "type": "integer",
+                        "description": "ID задания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.ChatResponse"
+                            "$ref": "#/definitions/main.DeadLetter"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -228,36 +378,44 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/chats/{id}/messages": {
+        "/admin/digests": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает список сообщений в чате с пагинацией",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает сводки для администраторов, новые первыми: новые сборы, подтвержденные пожертвования, очередь\nверификации, сборы на модерации и чеки с расхождением за неделю, текст в том виде, в каком он был отправлен,\nи результат отправки каждому получателю (почта и Telegram).",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Чаты"
+                    "Утилиты"
                 ],
-                "summary": "Получить сообщения чата",
+                "summary": "Еженедельные сводки",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID чата",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "default": 1,
@@ -267,8 +425,8 @@ const docTemplate = `{
                     },
                     {
                         "type": "integer",
-                        "default": 50,
-                        "description": "Количество сообщений",
+                        "default": 20,
+                        "description": "Количество на странице",
                         "name": "limit",
                         "in": "query"
                     }
@@ -277,7 +435,8 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.MessagesListResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "401": {
@@ -285,62 +444,60 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/digests/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Отправляет новое сообщение в чат (текст или вложение)",
-                "consumes": [
-                    "multipart/form-data"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Чаты"
+                    "Утилиты"
                 ],
-                "summary": "Отправить сообщение",
+                "summary": "Еженедельная сводка",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID чата",
+                        "description": "ID сводки",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Текст сообщения",
-                        "name": "text",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "file",
-                        "description": "Вложение (изображение, до 5MB)",
-                        "name": "attachment",
-                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.MessageResponse"
+                            "$ref": "#/definitions/main.Digest"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -348,46 +505,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/chats/{id}/messages/read": {
-            "patch": {
+        "/admin/invites": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Отмечает сообщения в чате как прочитанные",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает коды приглашения с числом регистраций по каждому, новые первыми",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Чаты"
+                    "Приглашения"
                 ],
-                "summary": "Отметить сообщения как прочитанные",
+                "summary": "Коды приглашения",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Партия",
+                        "name": "batch",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID чата",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
                     },
                     {
-                        "description": "ID сообщений (опционально, если пусто - все сообщения)",
-                        "name": "request",
-                        "in": "body",
-                        "schema": {
-                            "$ref": "#/definitions/main.MarkMessagesReadRequest"
-                        }
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.MarkMessagesReadResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "401": {
@@ -395,18 +555,22 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
-            }
-        },
-        "/chats/{id}/messages/{message_id}": {
-            "delete": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Удаляет сообщение из чата (только отправитель может удалить)",
+                "description": "Создает count кодов партии batch. По каждому коду можно зарегистрироваться max_uses раз до expires_at.",
                 "consumes": [
                     "application/json"
                 ],
@@ -414,28 +578,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Чаты"
+                    "Приглашения"
                 ],
-                "summary": "Удалить сообщение",
+                "summary": "Выпустить коды приглашения",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID чата",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "ID сообщения",
-                        "name": "message_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Параметры партии",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateInviteCodesRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Успешно удалено"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     },
                     "401": {
                         "description": "Unauthorized",
@@ -450,54 +619,40 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/admin/invites/{id}/users": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Редактирует текст сообщения (только отправитель может редактировать)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает пользователей, зарегистрированных по коду, в порядке регистрации",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Чаты"
+                    "Приглашения"
                 ],
-                "summary": "Редактировать сообщение",
+                "summary": "Пользователи по коду приглашения",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID чата",
+                        "description": "ID кода приглашения",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "ID сообщения",
-                        "name": "message_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Новый текст",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.UpdateMessageRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.MessageUpdateResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.InvitedUser"
+                            }
                         }
                     },
                     "400": {
@@ -517,47 +672,69 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/donations": {
+        "/admin/ops": {
             "get": {
-                "description": "Возвращает список пожертвований с фильтрацией и пагинацией",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Сводка для дежурного: очередь исходящих событий интеграций и вебхуков партнеров, очередь рассылки уведомлений,\nсобытия с прекращенной доставкой, чеки, сверка которых не завершилась за 30 минут, и последние запуски фоновых задач\n(stale - задача не завершалась успешно дольше трех интервалов), число заданий в очереди недоставленных.\nОчередь уведомлений и задачи - данные этого экземпляра.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Пожертвования"
+                    "Утилиты"
                 ],
-                "summary": "Получить список пожертвований",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Фильтр по посту",
-                        "name": "post_id",
-                        "in": "query"
+                "summary": "Очереди и фоновые задачи",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.OpsResponse"
+                        }
                     },
-                    {
-                        "type": "integer",
-                        "description": "Фильтр по донору",
-                        "name": "donor_id",
-                        "in": "query"
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/points-events": {
+            "get": {
+                "security": [
                     {
-                        "enum": [
-                            "pending",
-                            "confirmed",
-                            "rejected"
-                        ],
-                        "type": "string",
-                        "description": "Фильтр по статусу",
-                        "name": "status",
-                        "in": "query"
-                    },
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает прошедшие, идущие и запланированные акции, последние по началу первыми",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Рейтинг"
+                ],
+                "summary": "Акции с множителем баллов",
+                "parameters": [
                     {
                         "type": "integer",
                         "default": 1,
@@ -577,7 +754,20 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.DonationsListResponse"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     }
                 }
@@ -588,44 +778,33 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Создает новое пожертвование для поста",
+                "description": "Планирует акцию (например, «двойные баллы на выходных»): за пожертвования, созданные с starts_at до ends_at,\nпри подтверждении начисляется в multiplier раз больше баллов рейтинга. При пересечении акций действует наибольший множитель.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Пожертвования"
+                    "Рейтинг"
                 ],
-                "summary": "Создать пожертвование",
+                "summary": "Создать акцию с множителем баллов",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID поста",
-                        "name": "post_id",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "number",
-                        "description": "Сумма пожертвования",
-                        "name": "amount",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Чек/скриншот (JPEG, PNG, PDF, до 10MB)",
-                        "name": "receipt",
-                        "in": "formData"
+                        "description": "Название, множитель и период",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreatePointsEventRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.DonationResponse"
+                            "$ref": "#/definitions/main.PointsEvent"
                         }
                     },
                     "400": {
@@ -640,8 +819,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -649,23 +828,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/donations/{id}": {
-            "get": {
-                "description": "Возвращает детальную информацию о пожертвовании",
-                "consumes": [
-                    "application/json"
+        "/admin/points-events/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Запланированная акция удаляется, идущая завершается сейчас: пожертвования, созданные во время акции, сохраняют множитель.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Пожертвования"
+                    "Рейтинг"
                 ],
-                "summary": "Получить пожертвование",
+                "summary": "Отменить акцию",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID пожертвования",
+                        "description": "ID акции",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -675,7 +856,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.DonationWithDetails"
+                            "$ref": "#/definitions/main.PointsEvent"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "404": {
@@ -683,49 +876,65 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/admin/post-limit-requests": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Обновляет статус пожертвования (подтвердить или отклонить)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает запросы авторов на сбор сверх лимита, по умолчанию ожидающие рассмотрения",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Пожертвования"
+                    "Лимиты"
                 ],
-                "summary": "Подтвердить/отклонить пожертвование",
+                "summary": "Запросы на исключение из лимита",
                 "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "default": "pending",
+                        "description": "Статус запроса",
+                        "name": "status",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID пожертвования",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
                     },
                     {
-                        "description": "Статус",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.UpdateDonationRequest"
-                        }
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.DonationUpdateResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -749,14 +958,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/files/presigned-url": {
-            "post": {
+        "/admin/post-limit-requests/{id}": {
+            "patch": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Генерирует presigned URL для чтения (скачивания) файла из MinIO",
+                "description": "Одобряет или отклоняет запрос автора на сбор сверх лимита. Автор получает уведомление с комментарием.",
                 "consumes": [
                     "application/json"
                 ],
@@ -764,17 +973,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Утилиты"
+                    "Лимиты"
                 ],
-                "summary": "Получить presigned URL для чтения",
+                "summary": "Решение по запросу на исключение",
                 "parameters": [
                     {
-                        "description": "Параметры запроса",
+                        "type": "integer",
+                        "description": "ID запроса",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Решение",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.PresignedGetURLRequest"
+                            "$ref": "#/definitions/main.ReviewPostLimitRequestRequest"
                         }
                     }
                 ],
@@ -782,7 +998,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.PresignedGetURLResponse"
+                            "$ref": "#/definitions/main.PostLimitRequest"
                         }
                     },
                     "400": {
@@ -796,42 +1012,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/files/{bucket}/{objectKey}": {
-            "get": {
-                "description": "Получает файл из MinIO и отдает его клиенту (проксирование). Путь к файлу может содержать слэши, например: /files/user-photos/users/1/photo.jpg",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/octet-stream"
-                ],
-                "tags": [
-                    "Утилиты"
-                ],
-                "summary": "Получить файл",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Название bucket",
-                        "name": "bucket",
-                        "in": "path",
-                        "required": true
                     },
-                    {
-                        "type": "string",
-                        "description": "Ключ объекта (путь к файлу, может содержать слэши)",
-                        "name": "objectKey",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Файл"
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     },
                     "404": {
                         "description": "Not Found",
@@ -839,8 +1025,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -848,9 +1034,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
+        "/admin/posts/moderation": {
             "get": {
-                "description": "Проверяет состояние сервера, подключение к базе данных и MinIO",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает посты со статусом moderated вместе с документами законных представителей",
                 "consumes": [
                     "application/json"
                 ],
@@ -858,18 +1049,43 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Утилиты"
+                    "Посты"
+                ],
+                "summary": "Посты на модерации",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.HealthCheckResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.ModerationPostResponse"
+                            }
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -877,38 +1093,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts": {
+        "/admin/posts/moderation/urgent": {
             "get": {
-                "description": "Возвращает список постов с пагинацией и фильтрацией",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Отдельная очередь срочных сборов: самые старые первыми, со сроком рассмотрения review_deadline (URGENT_REVIEW_SLA_HOURS после создания) и отметкой overdue, если срок прошел. Срочные сборы есть и в общей очереди.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Посты"
                 ],
-                "summary": "Получить список постов",
+                "summary": "Срочные сборы на модерации",
                 "parameters": [
-                    {
-                        "enum": [
-                            "active",
-                            "completed",
-                            "closed",
-                            "moderated"
-                        ],
-                        "type": "string",
-                        "description": "Фильтр по статусу",
-                        "name": "status",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Фильтр по автору",
-                        "name": "user_id",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
                         "default": 1,
@@ -919,7 +1119,7 @@ const docTemplate = `{
                     {
                         "type": "integer",
                         "default": 20,
-                        "description": "Количество на странице",
+                        "description": "Количество на странице (до 100)",
                         "name": "limit",
                         "in": "query"
                     }
@@ -928,20 +1128,37 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.PostsListResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.ModerationPostResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/posts/{id}/moderation": {
+            "patch": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Создает новый пост о помощи",
+                "description": "Переводит пост в статус active (опубликовать) или closed (отклонить). При закрытии обязательна причина reason: она сохраняется в журнале и отправляется автору. Опубликованный срочный сбор показывается первым в ленте URGENT_DURATION_DAYS дней.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
@@ -949,62 +1166,30 @@ const docTemplate = `{
                 "tags": [
                     "Посты"
                 ],
-                "summary": "Создать пост",
+                "summary": "Решение по посту на модерации",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Заголовок",
-                        "name": "title",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Описание",
-                        "name": "description",
-                        "in": "formData",
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "number",
-                        "description": "Целевая сумма",
-                        "name": "amount",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Получатель средств",
-                        "name": "recipient",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Банк получателя",
-                        "name": "bank",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Телефон для связи",
-                        "name": "phone",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Медиа файлы (максимум 10, каждый до 10MB)",
-                        "name": "media",
-                        "in": "formData"
+                        "description": "Решение",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ModeratePostRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.PostResponse"
+                            "$ref": "#/definitions/main.SuccessResponse"
                         }
                     },
                     "400": {
@@ -1024,76 +1209,75 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/posts/{id}": {
+        "/admin/retention/report": {
             "get": {
-                "description": "Возвращает детальную информацию о посте",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Пробный запуск без изменений: число неактивных аккаунтов к обезличиванию, неподтвержденных регистраций\nи истекших кодов к удалению, вложений чатов старше срока хранения, и границы по времени для каждой очистки.\nЗаявки на верификацию обезличиваемых аккаунтов удаляются: verification_docs - сканы к удалению,\nretained_verification_docs - сканы под блокировкой хранилища, retained_post_documents - документы\nзаконного представителя и срочности, которые остаются вместе с постами.\nenabled=false - задача выключена (RETENTION_ENABLED), отчет показывает, что она сделала бы.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Посты"
-                ],
-                "summary": "Получить пост",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID поста",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "Утилиты"
                 ],
+                "summary": "Отчет по срокам хранения",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.PostWithDetails"
+                            "$ref": "#/definitions/main.RetentionReport"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/risk-tiers": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Удаляет пост (только автор может удалить)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает уровни доверия авторов с лимитами целевой суммы. Уровни с min_completed_posts назначаются автоматически\nпо числу завершенных сборов, уровни без него (organization) - только администратором.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Посты"
-                ],
-                "summary": "Удалить пост",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID поста",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "Лимиты"
                 ],
+                "summary": "Уровни доверия",
                 "responses": {
-                    "204": {
-                        "description": "Успешно удалено"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     },
                     "401": {
                         "description": "Unauthorized",
@@ -1106,22 +1290,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/main.ErrorResponse"
-                        }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/admin/risk-tiers/{name}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Обновляет данные поста (только автор может редактировать)",
+                "description": "Задает максимальную целевую сумму поста для уровня доверия и порог автоматического назначения",
                 "consumes": [
                     "application/json"
                 ],
@@ -1129,24 +1309,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Посты"
+                    "Лимиты"
                 ],
-                "summary": "Обновить пост",
+                "summary": "Создать или изменить уровень доверия",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID поста",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Название уровня",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Данные для обновления",
+                        "description": "Лимит и порог",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.UpdatePostRequest"
+                            "$ref": "#/definitions/main.SaveRiskTierRequest"
                         }
                     }
                 ],
@@ -1154,7 +1334,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.PostUpdateResponse"
+                            "$ref": "#/definitions/main.RiskTier"
                         }
                     },
                     "400": {
@@ -1174,61 +1354,34 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/main.ErrorResponse"
-                        }
                     }
                 }
             }
         },
-        "/posts/{id}/media": {
-            "post": {
+        "/admin/roles": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Добавляет медиа файл к существующему посту",
+                "description": "Возвращает роли с назначенными правами и список всех доступных прав",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Посты"
-                ],
-                "summary": "Добавить медиа к посту",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID поста",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Медиа файл (изображение/видео, до 10MB)",
-                        "name": "media",
-                        "in": "formData",
-                        "required": true
-                    }
+                    "Роли"
                 ],
+                "summary": "Получить роли",
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/main.PostMedia"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "401": {
@@ -1246,14 +1399,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/posts/{id}/media/{media_id}": {
-            "delete": {
+        "/admin/roles/{name}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Удаляет медиа файл из поста",
+                "description": "Создает роль или полностью заменяет ее набор прав. Роль admin всегда имеет все права и не изменяется, роли moderator нельзя выдать roles.manage, donations.manage, users.block и limits.manage.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1261,28 +1414,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Посты"
+                    "Роли"
                 ],
-                "summary": "Удалить медиа из поста",
+                "summary": "Создать или изменить роль",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID поста",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Имя роли",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "ID медиа",
-                        "name": "media_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Описание и права роли",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SaveRoleRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Успешно удалено"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Role"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     },
                     "401": {
                         "description": "Unauthorized",
@@ -1299,68 +1463,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/ratings": {
-            "get": {
-                "description": "Возвращает рейтинг пользователей с пагинацией",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Рейтинг"
-                ],
-                "summary": "Получить рейтинг пользователей",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Номер страницы",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 50,
-                        "description": "Количество на странице",
-                        "name": "limit",
-                        "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.RatingsListResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/ratings/me": {
+        "/admin/selftest": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает рейтинг текущего пользователя с позицией",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает результат проверки при запуске сервера: транзакция в PostgreSQL и запись, чтение и удаление пробного объекта в каждом bucket MinIO.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Рейтинг"
+                    "Утилиты"
                 ],
-                "summary": "Получить свой рейтинг",
+                "summary": "Результат самотестирования",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.RatingWithDetails"
+                            "$ref": "#/definitions/main.SelfTestReport"
                         }
                     },
                     "401": {
@@ -1369,6 +1491,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -1376,42 +1504,110 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/upload/presigned-url": {
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Генерирует presigned URL для прямой загрузки файла в MinIO",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Повторяет проверки самотестирования, например после исправления учетных данных. Код ответа 200 и при проваленных проверках.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Утилиты"
                 ],
-                "summary": "Получить presigned URL",
-                "parameters": [
-                    {
-                        "description": "Параметры загрузки",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                "summary": "Запустить самотестирование",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.PresignedURLRequest"
+                            "$ref": "#/definitions/main.SelfTestReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает пользователей с поиском и фильтрами, новые первыми. q из цифр ищет по телефону, иначе по имени и фамилии.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Список пользователей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Телефон или имя",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Роль",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "none",
+                            "pending",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Статус верификации",
+                        "name": "verification_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Активен ли аккаунт",
+                        "name": "is_active",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.PresignedURLResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -1425,18 +1621,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/users/me": {
+        "/admin/users/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает информацию о текущем пользователе",
+                "description": "Возвращает профиль, верификацию, последние посты, пожертвования, число чатов, активные сессии и историю входов пользователя",
                 "consumes": [
                     "application/json"
                 ],
@@ -1444,14 +1646,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Профиль"
+                    "Пользователи"
+                ],
+                "summary": "Сводка по пользователю",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Получить профиль",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.User"
+                            "$ref": "#/definitions/main.AdminUserDetailResponse"
                         }
                     },
                     "401": {
@@ -1459,6 +1670,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             },
@@ -1468,7 +1691,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Обновляет данные профиля текущего пользователя",
+                "description": "Меняет is_active аккаунта. Причина обязательна: она сохраняется в журнале и отправляется пользователю.\nПри деактивации сессии завершаются, а выданные access и API токены перестают приниматься сразу.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1476,17 +1699,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Профиль"
+                    "Пользователи"
                 ],
-                "summary": "Обновить профиль",
+                "summary": "Деактивировать или активировать пользователя",
                 "parameters": [
                     {
-                        "description": "Данные для обновления",
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новое состояние и причина",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.UpdateProfileRequest"
+                            "$ref": "#/definitions/main.UpdateAdminUserRequest"
                         }
                     }
                 ],
@@ -1508,18 +1738,30 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/users/me/change-password": {
+        "/admin/users/{id}/block": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Изменяет пароль текущего пользователя",
+                "description": "Деактивирует аккаунт и завершает его сессии. Причина обязательна: она сохраняется в журнале и отправляется пользователю.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1527,17 +1769,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Профиль"
+                    "Пользователи"
                 ],
-                "summary": "Изменить пароль",
+                "summary": "Заблокировать пользователя",
                 "parameters": [
                     {
-                        "description": "Старый и новый пароль",
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Причина блокировки",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.ChangePasswordRequest"
+                            "$ref": "#/definitions/main.BlockUserRequest"
                         }
                     }
                 ],
@@ -1559,34 +1808,44 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
-            }
-        },
-        "/users/me/photo": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Загружает фото профиля пользователя",
+                "description": "Снова активирует аккаунт. Действие сохраняется в журнале.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Профиль"
+                    "Пользователи"
                 ],
-                "summary": "Загрузить фото профиля",
+                "summary": "Разблокировать пользователя",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Фото профиля (JPEG, PNG, max 5MB)",
-                        "name": "photo",
-                        "in": "formData",
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -1594,23 +1853,23 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.PhotoUploadResponse"
+                            "$ref": "#/definitions/main.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
-                    "413": {
-                        "description": "Request Entity Too Large",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -1618,14 +1877,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/verifications": {
-            "get": {
+        "/admin/users/{id}/risk-tier": {
+            "patch": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает список всех заявок на верификацию с пагинацией",
+                "description": "Назначает пользователю уровень доверия (например, organization). tier = null возвращает автоматический выбор по завершенным сборам.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1633,41 +1892,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Верификация"
+                    "Лимиты"
                 ],
-                "summary": "Получить список заявок на верификацию",
+                "summary": "Назначить уровень доверия",
                 "parameters": [
-                    {
-                        "enum": [
-                            "pending",
-                            "approved",
-                            "rejected"
-                        ],
-                        "type": "string",
-                        "description": "Фильтр по статусу",
-                        "name": "status",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
-                        "default": 1,
-                        "description": "Номер страницы",
-                        "name": "page",
-                        "in": "query"
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 20,
-                        "description": "Количество на странице",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Уровень доверия",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateUserRiskTierRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.VerificationsListResponse"
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "401": {
@@ -1681,146 +1937,158 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/users/{id}/role": {
+            "patch": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Создает заявку на верификацию пользователя",
+                "description": "Назначает пользователю роль (admin, moderator, user или роль из /admin/roles). Новая роль действует со следующего запроса.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Верификация"
+                    "Роли"
                 ],
-                "summary": "Подать заявку на верификацию",
+                "summary": "Сменить роль пользователя",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Фото пользователя",
-                        "name": "user_photo",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Фамилия",
-                        "name": "last_name",
-                        "in": "formData",
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Имя",
-                        "name": "first_name",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Отчество",
-                        "name": "middle_name",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Дата рождения (YYYY-MM-DD)",
-                        "name": "birth_date",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Серия паспорта",
-                        "name": "passport_series",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Номер паспорта",
-                        "name": "passport_number",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Кем выдан",
-                        "name": "passport_issuer",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Дата выдачи (YYYY-MM-DD)",
-                        "name": "passport_date",
-                        "in": "formData",
-                        "required": true
+                        "description": "Новая роль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateUserRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
                     },
-                    {
-                        "enum": [
-                            "inn",
-                            "snils"
-                        ],
-                        "type": "string",
-                        "description": "Тип документа (inn или snils)",
-                        "name": "doc_type",
-                        "in": "formData",
-                        "required": true
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "ИНН",
-                        "name": "inn",
-                        "in": "formData"
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "СНИЛС",
-                        "name": "snils",
-                        "in": "formData"
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "security": [
                     {
-                        "type": "file",
-                        "description": "Сканы паспорта (минимум 2)",
-                        "name": "passport_scans",
-                        "in": "formData",
-                        "required": true
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает все вебхуки партнеров без секретов подписи",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Интеграции"
+                ],
+                "summary": "Вебхуки партнеров",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.PartnerWebhook"
+                            }
+                        }
                     },
-                    {
-                        "type": "boolean",
-                        "description": "Согласие 1",
-                        "name": "consent1",
-                        "in": "formData",
-                        "required": true
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
                     {
-                        "type": "boolean",
-                        "description": "Согласие 2",
-                        "name": "consent2",
-                        "in": "formData",
-                        "required": true
-                    },
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Подписывает URL партнера на события платформы: post.created, donation.created, donation.confirmed,\nverification.approved, verification.rejected. События доставляются POST запросом с повторами,\nтело подписывается HMAC-SHA256 (заголовок X-Webhook-Signature). Секрет подписи возвращается только в ответе.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Интеграции"
+                ],
+                "summary": "Создать вебхук партнера",
+                "parameters": [
                     {
-                        "type": "boolean",
-                        "description": "Согласие 3",
-                        "name": "consent3",
-                        "in": "formData",
-                        "required": true
+                        "description": "Название, URL и события",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateWebhookRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.VerificationResponse"
+                            "$ref": "#/definitions/main.WebhookResponse"
                         }
                     },
                     "400": {
@@ -1835,8 +2103,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -1844,29 +2112,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/verifications/me": {
-            "get": {
+        "/admin/webhooks/{id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает статус верификации текущего пользователя",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Удаляет вебхук вместе с очередью и историей его событий",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Верификация"
+                    "Интеграции"
+                ],
+                "summary": "Удалить вебхук партнера",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Получить статус верификации",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.VerificationResponse"
+                            "$ref": "#/definitions/main.SuccessResponse"
                         }
                     },
                     "401": {
@@ -1875,6 +2149,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -1882,16 +2162,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/verifications/{id}": {
+            },
             "patch": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Обновляет статус верификации (одобрить или отклонить)",
+                "description": "Меняет название, URL, события или включает и отключает вебхук. При отключении неотправленные события\nотменяются. С rotate_secret выдается новый секрет подписи, он возвращается только в ответе.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1899,24 +2177,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Верификация"
+                    "Интеграции"
                 ],
-                "summary": "Одобрить/отклонить верификацию",
+                "summary": "Изменить вебхук партнера",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID верификации",
+                        "description": "ID вебхука",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Статус и причина отклонения",
+                        "description": "Изменяемые поля",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.UpdateVerificationRequest"
+                            "$ref": "#/definitions/main.UpdateWebhookRequest"
                         }
                     }
                 ],
@@ -1924,7 +2202,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.VerificationResponse"
+                            "$ref": "#/definitions/main.WebhookResponse"
                         }
                     },
                     "400": {
@@ -1944,761 +2222,9998 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.ChangePasswordRequest": {
-            "type": "object",
-            "required": [
-                "new_password",
-                "old_password"
-            ],
-            "properties": {
-                "new_password": {
-                    "type": "string",
-                    "minLength": 6
-                },
-                "old_password": {
-                    "type": "string"
-                }
-            }
-        },
-        "main.ChatResponse": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "helper_id": {
-                    "type": "integer"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "needy_id": {
-                    "type": "integer"
-                },
-                "post_id": {
-                    "type": "integer"
-                }
-            }
         },
-        "main.ChatWithDetails": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "helper_id": {
-                    "type": "integer"
+        "/admin/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает события вебхука с состоянием доставки, числом попыток и последней ошибкой, новые первыми",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Интеграции"
+                ],
+                "summary": "События вебхука партнера",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Состояние доставки",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/forgot-password": {
+            "post": {
+                "description": "Отправляет SMS с одноразовым кодом для сброса пароля. Повторная отправка возможна не чаще одного раза в минуту",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Восстановление пароля",
+                "parameters": [
+                    {
+                        "description": "Телефон",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Аутентифицирует пользователя и возвращает JWT токен",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Вход в систему",
+                "parameters": [
+                    {
+                        "description": "Данные входа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.LoginResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает текущий access токен. Если передан refresh токен, он также отзывается; all_sessions завершает все сессии пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Выход из системы",
+                "parameters": [
+                    {
+                        "description": "Параметры выхода",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.LogoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oauth/providers": {
+            "get": {
+                "description": "Возвращает провайдеров (vk, yandex, google), через которых можно войти",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Провайдеры входа",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oauth/{provider}": {
+            "get": {
+                "description": "Возвращает ссылку на страницу входа VK ID, Яндекс или Google. Войти можно только в аккаунт, к которому провайдер уже привязан.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Вход через провайдера",
+                "parameters": [
+                    {
+                        "enum": [
+                            "vk",
+                            "yandex",
+                            "google"
+                        ],
+                        "type": "string",
+                        "description": "Провайдер",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.OAuthAuthorizeResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oauth/{provider}/callback": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Принимает пользователя, вернувшегося от провайдера. При входе выдает токены, при привязке сохраняет учетную запись провайдера в аккаунте. Привязка завершается только запросом с access токеном пользователя, который ее начал.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Callback провайдера входа",
+                "parameters": [
+                    {
+                        "enum": [
+                            "vk",
+                            "yandex",
+                            "google"
+                        ],
+                        "type": "string",
+                        "description": "Провайдер",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Код авторизации",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State из ссылки входа",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор устройства (VK ID)",
+                        "name": "device_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Выдает новый access токен по refresh токену. Refresh токен одноразовый: в ответе возвращается новый, старый отзывается",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Обновление токена",
+                "parameters": [
+                    {
+                        "description": "Refresh токен",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.RefreshTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Регистрирует нового пользователя в системе",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Регистрация пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные регистрации",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.RegisterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/request-otp": {
+            "post": {
+                "description": "Отправляет SMS с одноразовым кодом для подтверждения телефона. Повторная отправка возможна не чаще одного раза в минуту",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Запрос кода подтверждения",
+                "parameters": [
+                    {
+                        "description": "Телефон",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RequestOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reset-password": {
+            "post": {
+                "description": "Проверяет код из SMS и устанавливает новый пароль. Все сессии пользователя завершаются, выданные access токены перестают действовать",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Сброс пароля",
+                "parameters": [
+                    {
+                        "description": "Телефон, код и новый пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify-otp": {
+            "post": {
+                "description": "Проверяет код из SMS и отмечает телефон пользователя как подтвержденный",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Подтверждение телефона",
+                "parameters": [
+                    {
+                        "description": "Телефон и код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.VerifyOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/calendar/{token}.ics": {
+            "get": {
+                "description": "Календарь iCalendar со сроками сборов автора: событие на момент окончания срока и напоминание за сутки\nдля активных сборов. Доступен без входа по ссылке из POST /users/me/calendar.",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Календарь сроков сборов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен календаря",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает список всех чатов текущего пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Получить список чатов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ChatsListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает новый чат между помощником и нуждающимся по посту",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Создать чат",
+                "parameters": [
+                    {
+                        "description": "ID поста",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.ChatResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/{id}/draft": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает неотправленный текст сообщения текущего пользователя в чате. Если черновика нет, text пустой.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Получить черновик",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID чата",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ChatDraft"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет неотправленный текст сообщения (до 4000 символов), чтобы продолжить на другом устройстве.\nПустой текст удаляет черновик. Черновик также удаляется при отправке сообщения.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Сохранить черновик",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID чата",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Текст черновика",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SaveChatDraftRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ChatDraft"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/{id}/events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Server-Sent Events: событие message с каждым новым сообщением чата, включая автоответ.\nКаждые 25 секунд приходит комментарий keep-alive. Доступно только участникам чата.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Поток событий чата",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID чата",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Message"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/{id}/messages": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает сообщения чата от старых к новым. С before_id возвращаются сообщения старше указанного\n(next_cursor - следующий before_id для подгрузки истории), с after_id - новее указанного\n(next_cursor - следующий after_id для получения новых сообщений). Без курсора работает постраничная выдача page/limit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Получить сообщения чата",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID чата",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сообщения старше сообщения с этим ID",
+                        "name": "before_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сообщения новее сообщения с этим ID",
+                        "name": "after_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы (без курсора)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество сообщений",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.MessagesListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отправляет новое сообщение в чат (текст или вложение)",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Отправить сообщение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID чата",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Текст сообщения",
+                        "name": "text",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Вложение (изображение, до 5MB)",
+                        "name": "attachment",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/{id}/messages/read": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отмечает сообщения в чате как прочитанные",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Отметить сообщения как прочитанные",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID чата",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "ID сообщений (опционально, если пусто - все полученные сообщения)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.MarkMessagesReadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.MarkMessagesReadResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/chats/{id}/messages/{message_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет сообщение из чата (только отправитель может удалить)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Удалить сообщение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID чата",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID сообщения",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Успешно удалено"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Редактирует текст сообщения (только отправитель может редактировать)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Чаты"
+                ],
+                "summary": "Редактировать сообщение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID чата",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID сообщения",
+                        "name": "message_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новый текст",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.MessageUpdateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/config/money": {
+            "get": {
+                "description": "Возвращает валюту, округление, формат записи, границы суммы пожертвования и начисление баллов.\nСуммы округляются до fraction_digits знаков (half_up) и проверяются по donation.min и donation.max при создании пожертвования и подписки.\nБаллы за подтвержденное пожертвование: floor(сумма * points.per_unit * points.multiplier), multiplier - множитель идущей акции.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Утилиты"
+                ],
+                "summary": "Правила денежных сумм",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.MoneyConfigResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/donations": {
+            "get": {
+                "description": "Возвращает список пожертвований с фильтрацией и пагинацией. У анонимных пожертвований донор (donor_id, donor)\nвиден только ему самому и пользователям с правом donations.manage, чек - еще автору поста и соавторам с правом donations.\nС фильтром donor_id анонимные пожертвования видят только они же.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Получить список пожертвований",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по посту",
+                        "name": "post_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по донору",
+                        "name": "donor_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "confirmed",
+                            "rejected",
+                            "refunded"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по статусу",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.DonationsListResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает новое пожертвование для поста. Донора анонимного пожертвования видят только он сам и администраторы,\nбаллы рейтинга начисляются как обычно.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Создать пожертвование",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "post_id",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Сумма пожертвования",
+                        "name": "amount",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Скрыть донора",
+                        "name": "is_anonymous",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Чек/скриншот (JPEG, PNG, PDF, до 10MB)",
+                        "name": "receipt",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности (до 255 символов): повтор с тем же ключом возвращает сохраненный ответ с заголовком Idempotent-Replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.DonationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/donations/{id}": {
+            "get": {
+                "description": "Возвращает детальную информацию о пожертвовании. Донор анонимного пожертвования скрыт так же, как в списке.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Получить пожертвование",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пожертвования",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.DonationWithDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "То же, что PATCH /donations/{id} со статусом refunded: донор отменяет свое пожертвование, пока оно ожидает\nподтверждения, право donations.manage возвращает любое, кроме оплаченных или оплачиваемых картой. Пожертвование\nне удаляется, а получает статус refunded; возврат подтвержденного уменьшает собранную сумму поста и рейтинг донора.",
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Отменить пожертвование",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пожертвования",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Пожертвование отменено"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет статус пожертвования. confirmed и rejected - автор поста, соавтор с правом donations или право donations.manage.\nrefunded - возврат: право donations.manage для любого пожертвования или донор, пока пожертвование ожидает подтверждения.\nВозврат подтвержденного пожертвования уменьшает собранную сумму поста и рейтинг донора, возвращенное пожертвование больше не меняется.\nПожертвование с незавершенной или успешной оплатой картой не возвращается (409): деньги возвращает платежный провайдер.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Подтвердить/отклонить/вернуть пожертвование",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пожертвования",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Статус",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateDonationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.DonationUpdateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/donations/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает события пожертвования (created, receipt_attached, confirmed, rejected, reverted, refunded) с автором и данными изменения и статус, восстановленный по событиям. Доступно жертвователю, автору поста, соавтору с правом donations и пользователям с правом donations.manage. Донор анонимного пожертвования скрыт от автора и соавторов: actor_id его событий не возвращается.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "История пожертвования",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пожертвования",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.DonationHistoryResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/donations/{id}/pay": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает платеж у платежного шлюза (PAYMENT_PROVIDER) и возвращает confirmation_url страницы оплаты.\nПока платеж не завершен, повторный запрос возвращает его же. После оплаты пожертвование подтверждается\nавтоматически по уведомлению шлюза. Доступно жертвователю для ожидающего подтверждения пожертвования.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Оплатить пожертвование",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пожертвования",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/donations/{id}/receipt": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает PDF квитанцию: сбор, получатель, жертвователь, сумма и дата подтверждения в часовом поясе жертвователя.\nОткрывается по ссылке из уведомления (параметр token) без входа, иначе доступна жертвователю, автору поста,\nсоавтору с правом donations и пользователям с правом donations.manage.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Квитанция о пожертвовании",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пожертвования",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подпись ссылки из уведомления",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/presigned-url": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Генерирует presigned URL для чтения (скачивания) файла из MinIO. Доступ к файлу проверяется так же, как в GET /files.\nСсылки на файлы закрытых bucket действуют не дольше 15 минут. Ссылки на документы верификации здесь не выдаются:\nвладелец получает их через GET /files с JWT, проверяющие - через GET /verifications/{id}/documents.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Утилиты"
+                ],
+                "summary": "Получить presigned URL для чтения",
+                "parameters": [
+                    {
+                        "description": "Параметры запроса",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.PresignedGetURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PresignedGetURLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{bucket}/{objectKey}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получает файл из MinIO и отдает его клиенту (проксирование). Путь к файлу может содержать слэши, например: /files/user-photos/users/1/photo.jpg\nПоддерживает заголовок Range для частичной загрузки и перемотки видео.\nОтдает ETag и Last-Modified, на If-None-Match и If-Modified-Since отвечает 304 без загрузки файла из MinIO.\nФото профилей и медиа постов публичны. Документы верификации, чеки и вложения чатов требуют JWT владельца\n(для тегов img используйте POST /files/presigned-url).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Утилиты"
+                ],
+                "summary": "Получить файл",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Название bucket",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ объекта (путь к файлу, может содержать слэши)",
+                        "name": "objectKey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Диапазон байт, например bytes=0-1048575",
+                        "name": "Range",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag закэшированной копии",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл"
+                    },
+                    "206": {
+                        "description": "Часть файла"
+                    },
+                    "304": {
+                        "description": "Файл не изменился"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "416": {
+                        "description": "Диапазон за пределами файла"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Проверяет состояние сервера, подключение к базе данных и MinIO.\nС deep=true (нужен JWT с правом system.view) возвращает DeepHealthResponse: задержку запроса к базе,\nзапись и чтение пробного объекта в MinIO, очереди уведомлений и вебхуков, последние запуски фоновых задач.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Утилиты"
+                ],
+                "summary": "Health check",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Подробная диагностика",
+                        "name": "deep",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.HealthCheckResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает уведомления текущего пользователя, новые первыми",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Получить уведомления",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Только непрочитанные",
+                        "name": "unread",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.NotificationsListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/read": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отмечает указанные уведомления прочитанными. Если список пуст - все уведомления пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Отметить уведомления прочитанными",
+                "parameters": [
+                    {
+                        "description": "ID уведомлений",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.MarkNotificationsReadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/webhook": {
+            "post": {
+                "description": "Принимает уведомление о смене статуса платежа. Статус перечитывается у провайдера, повторные уведомления\nничего не меняют. Успешная оплата подтверждает пожертвование (событие confirmed с payment_id), отмена\nоставляет его ожидающим: жертвователь может оплатить снова или приложить чек.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Уведомление платежного шлюза",
+                "responses": {
+                    "200": {
+                        "description": "Уведомление обработано"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/points-events": {
+            "get": {
+                "description": "Возвращает идущие и запланированные акции, ближайшие первыми. active = true, если акция идет сейчас.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Рейтинг"
+                ],
+                "summary": "Текущие акции с множителем баллов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/posts": {
+            "get": {
+                "description": "Возвращает список постов с пагинацией и фильтрацией",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Получить список постов",
+                "parameters": [
+                    {
+                        "enum": [
+                            "active",
+                            "completed",
+                            "closed",
+                            "moderated"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по статусу",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по автору",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поиск по заголовку и описанию",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "ru",
+                            "uk",
+                            "be",
+                            "kk",
+                            "en",
+                            "de",
+                            "fr",
+                            "es"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по языку поста",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Посты со сроком не позже даты (RFC 3339)",
+                        "name": "deadline_before",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "newest",
+                            "urgent",
+                            "most_collected",
+                            "least_donated",
+                            "closest_to_goal",
+                            "deadline"
+                        ],
+                        "type": "string",
+                        "description": "Порядок: newest - новые, urgent - срочные и новые (по умолчанию без поиска), most_collected - больше всего собрано, least_donated - меньше всего собрано, closest_to_goal - ближе всего к цели, deadline - ближайший срок",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostsListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает новый пост о помощи. Автор должен быть не младше MIN_POSTING_AGE лет. Сбор в пользу несовершеннолетнего требует согласия и документа законного представителя и публикуется после модерации. Срочный сбор (urgent) доступен верифицированным авторам, требует документа, подтверждающего срочность, и рассматривается модератором вне очереди.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Создать пост",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Заголовок",
+                        "name": "title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Описание",
+                        "name": "description",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Целевая сумма",
+                        "name": "amount",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Получатель средств",
+                        "name": "recipient",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Банк получателя",
+                        "name": "bank",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Телефон для связи",
+                        "name": "phone",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Медиа файлы (максимум 10, каждый до 10MB)",
+                        "name": "media",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Сбор в пользу несовершеннолетнего",
+                        "name": "beneficiary_is_minor",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Согласие законного представителя",
+                        "name": "guardian_consent",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Документ законного представителя (PDF, JPEG, PNG до 10MB)",
+                        "name": "guardian_document",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Срочный сбор",
+                        "name": "urgent",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Срок сбора (RFC 3339), после него пост закрывается автоматически",
+                        "name": "deadline",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Документ, подтверждающий срочность: выписка, счет клиники (PDF, JPEG, PNG до 10MB), обязателен для срочного сбора",
+                        "name": "urgent_document",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Статьи расходов: JSON массив объектов с полями title и amount (до 20), сумма должна совпадать с amount",
+                        "name": "line_items",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности (до 255 символов): повтор с тем же ключом возвращает сохраненный ответ с заголовком Idempotent-Replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}": {
+            "get": {
+                "description": "Возвращает детальную информацию о посте",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Получить пост",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostWithDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет пост (только автор может удалить)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Удалить пост",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Успешно удалено"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет данные поста (автор или соавтор с правом edit). Доступно также по API токену с областью posts:write.\nСтатьи расходов line_items заменяются целиком, их сумма должна совпадать с целевой суммой.\nСрок deadline задается в RFC 3339, пустая строка убирает срок. Закрытый по сроку пост новым сроком не открывается.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Обновить пост",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные для обновления",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdatePostRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostUpdateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/collaborators": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает соавторов и приглашения поста с правами. Доступно автору и соавторам.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Соавторы поста",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.PostCollaborator"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Приглашает зарегистрированного пользователя по телефону помогать с постом. Права: edit - редактирование поста и медиа,\nchats - переписка в чатах поста, donations - подтверждение пожертвований. Права действуют после принятия приглашения\nв PATCH /users/me/collaborations/{id}. Только автор поста.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Пригласить соавтора",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Телефон и права",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.InviteCollaboratorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostCollaborator"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/collaborators/{user_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Автор удаляет соавтора или отзывает приглашение, соавтор может выйти из соавторов сам",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Удалить соавтора",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID соавтора",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Заменяет права соавтора или приглашенного. Только автор поста.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Изменить права соавтора",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID соавтора",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Права",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateCollaboratorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/comments": {
+            "get": {
+                "description": "Возвращает публичные комментарии поста с авторами, новые первыми",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Комментарии поста",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице (до 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostCommentsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Публикует комментарий к посту, например вопрос автору сбора. Автор поста получает уведомление.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Добавить комментарий",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Текст комментария",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateCommentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostComment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/comments/{comment_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет комментарий. Доступно автору комментария, автору поста, соавтору с правом edit и пользователям с правом posts.moderate.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Удалить комментарий",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID комментария",
+                        "name": "comment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Комментарий удален"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/donations/stats": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Для графиков сбора: суммы и количество пожертвований по статусам, уникальные доноры и средняя сумма,\nподтвержденные суммы по дням за последние days дней (дни в часовом поясе автора, дни без пожертвований - с нулями)\nи доноры с наибольшей суммой. Анонимные пожертвования входят в итоги (anonymous_amount), но не в список доноров.\nДоступна автору поста, соавторам с правом donations и пользователям с правом donations.manage.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Статистика пожертвований поста",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Число дней временного ряда, до 365",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Число доноров, до 50",
+                        "name": "top",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.DonationStatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/events": {
+            "get": {
+                "description": "Server-Sent Events: сразу и после каждого подтвержденного пожертвования приходит событие progress\nс собранной суммой, чтобы открытая страница поста обновляла прогресс без перезагрузки.\nКаждые 25 секунд приходит комментарий keep-alive. Поток открыт, пока клиент не отключится.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Поток прогресса сбора",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostProgressEvent"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/favorite": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет пост в избранное текущего пользователя. Повторное добавление не ошибка.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Добавить в избранное",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Пост в избранном"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Убирает пост из избранного текущего пользователя. Удаление отсутствующего поста не ошибка.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Убрать из избранного",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Пост убран из избранного"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/flyer": {
+            "get": {
+                "description": "Возвращает PDF листовку A4 с фото, описанием, прогрессом сбора, реквизитами для перевода и QR кодом\nдля сбора офлайн (в храмах, магазинах). Шаблоны: classic, tearoff (с отрывными полосками), mono (черно-белый).",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Листовка для печати",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "classic",
+                            "tearoff",
+                            "mono"
+                        ],
+                        "type": "string",
+                        "default": "classic",
+                        "description": "Шаблон листовки",
+                        "name": "template",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/media": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавляет медиа файл к существующему посту",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Добавить медиа к посту",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Медиа файл (изображение/видео, до 10MB)",
+                        "name": "media",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostMedia"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/media/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Принимает файлы в поле media и/или zip архивы в поле archive (не больше 50 файлов, каждый до 10MB).\nКаждый файл проверяется отдельно: ошибка одного файла не отменяет загрузку остальных, результат по каждому файлу возвращается в отчете.\nСуммарный размер проверяется по квоте хранилища до загрузки. Доступно также по API токену с областью posts:write.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Массовая загрузка медиа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Медиа файлы (можно несколько)",
+                        "name": "media",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Zip архив с медиа файлами",
+                        "name": "archive",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.BulkMediaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/media/{media_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет медиа файл из поста",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Удалить медиа из поста",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID медиа",
+                        "name": "media_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Успешно удалено"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/questions": {
+            "get": {
+                "description": "Возвращает вопросы с ответами автора, последние отвеченные первыми. С unanswered=true - вопросы без ответа\nв порядке поступления: доступно автору поста, соавтору с правом edit и пользователям с правом posts.moderate.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Вопросы и ответы поста",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Вопросы без ответа",
+                        "name": "unanswered",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице (до 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostQuestionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отправляет вопрос автору поста. Вопрос появится в GET /posts/{id}/questions после ответа автора, до этого\nего видят только автор, соавторы с правом edit и модераторы. Автор поста получает уведомление.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Задать вопрос",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Вопрос",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateQuestionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostQuestion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/questions/{question_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет вопрос вместе с ответом. Доступно автору вопроса, автору поста, соавтору с правом edit и пользователям\nс правом posts.moderate.",
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Удалить вопрос",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID вопроса",
+                        "name": "question_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Вопрос удален"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/questions/{question_id}/answer": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет ответ, после чего вопрос становится публичным. Повторный ответ заменяет прежний.\nДоступно автору поста и соавтору с правом edit. Автор вопроса получает уведомление о первом ответе.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Ответить на вопрос",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID вопроса",
+                        "name": "question_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ответ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.AnswerQuestionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostQuestion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/share-image": {
+            "get": {
+                "description": "Возвращает PNG 1200x630 с фото, заголовком, прогрессом сбора и QR кодом со ссылкой на пост.\nКартинка кэшируется и перерисовывается при изменении поста или заметном изменении собранной суммы.",
+                "produces": [
+                    "image/png"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Картинка для соцсетей",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/posts/{id}/statement-imports": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Принимает CSV выписку (до 5MB, разделитель ; , или табуляция, UTF-8 или Windows-1251) в поле file.\nСтолбцы находятся по заголовкам: дата, сумма, отправитель, назначение платежа. Каждый входящий перевод\nсопоставляется с ожидающим подтверждения пожертвованием поста: сумма до копейки, дата (не дальше 7 дней),\nтелефон и имя жертвователя в отправителе или назначении. Пожертвования не подтверждаются: предложения\nс уверенностью от 0 до 1 сохраняются для проверки и подтверждаются в POST /statement-imports/{id}/confirm.\nДоступно автору поста и роли с правом donations.manage.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Загрузить выписку банка",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "CSV выписка",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.StatementImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ratings": {
+            "get": {
+                "description": "Возвращает рейтинг пользователей с пагинацией. Без period - за все время по накопленным баллам.\nperiod=week|month|year - за текущую календарную неделю (с понедельника), месяц или год по московскому времени:\nбаллы и сумма считаются по пожертвованиям, подтвержденным в периоде, без реферальных бонусов.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Рейтинг"
+                ],
+                "summary": "Получить рейтинг пользователей",
+                "parameters": [
+                    {
+                        "enum": [
+                            "week",
+                            "month",
+                            "year"
+                        ],
+                        "type": "string",
+                        "description": "Период рейтинга",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.RatingsListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ratings/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает рейтинг текущего пользователя с позицией",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Рейтинг"
+                ],
+                "summary": "Получить свой рейтинг",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.RatingWithDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ratings/me/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает изменения баллов текущего пользователя, новые первыми: начисления за подтвержденные пожертвования (donation),\nсписания при отмене подтверждения или возврате (donation_reversal), реферальные бонусы (referral), их списание\nвместе с пожертвованием, за которое они начислены (referral_reversal), и баллы,\nнакопленные до появления журнала (opening_balance). balance - баллы после изменения.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Рейтинг"
+                ],
+                "summary": "История баллов рейтинга",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.RatingHistoryResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Возвращает 503, если база данных недоступна или разомкнут circuit breaker PostgreSQL или MinIO.\nBreakers внешних интеграций выводятся, но на готовность не влияют.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Утилиты"
+                ],
+                "summary": "Readiness check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ReadinessResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.ReadinessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/statement-imports/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает строки выписки с предложенными пожертвованиями, уверенностью и результатом подтверждения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Получить выписку банка",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID выписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.StatementImportResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/statement-imports/{id}/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Подтверждает пожертвования из строк со статусом proposed: перечисленных в row_ids и/или всех\nс уверенностью не ниже min_confidence. Подтверждение выполняется как PATCH /donations/{id}\n(собранная сумма, рейтинг, уведомления). Строка получает статус confirmed, либо skipped,\nесли пожертвование уже подтверждено или отклонено.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Подтвердить пожертвования по выписке",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID выписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Строки для подтверждения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ConfirmStatementRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.StatementImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/upload/presigned-url": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Генерирует presigned URL для прямой загрузки файла в MinIO. Доступно только для bucket user-photos (JPEG, PNG, WebP)\nи post-media (также MP4, WebM). Файл сохраняется под ключом uploads/{user_id}/{имя}, загружать нужно с тем же Content-Type.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Утилиты"
+                ],
+                "summary": "Получить presigned URL",
+                "parameters": [
+                    {
+                        "description": "Параметры загрузки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.PresignedURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PresignedURLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает информацию о текущем пользователе",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Получить профиль",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет данные профиля текущего пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Обновить профиль",
+                "parameters": [
+                    {
+                        "description": "Данные для обновления",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/away": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Включает автоответ: он один раз отправляется в каждый чат, где пользователю пишут, пока режим действует.\nСтатус показывается на постах пользователя и собеседникам в чатах. Без until режим действует до отключения.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Включить режим \"нет на месте\"",
+                "parameters": [
+                    {
+                        "description": "Текст автоответа и время окончания",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SetAwayRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.AwayStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выключает автоответ и статус на постах",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Выключить режим \"нет на месте\"",
+                "responses": {
+                    "204": {
+                        "description": "Режим выключен"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/calendar": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выпускает ссылку на календарь iCalendar со сроками сборов пользователя для подписки в календаре телефона.\nСсылка работает без входа и показывается только один раз; повторный вызов выпускает новую, старая перестает работать.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Ссылка на календарь сроков",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CalendarFeedResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает ссылку на календарь, после чего она возвращает 404",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Выключить календарь сроков",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/change-password": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Изменяет пароль текущего пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Изменить пароль",
+                "parameters": [
+                    {
+                        "description": "Старый и новый пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/collaborations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает ожидающие ответа приглашения (status pending) и принятые (status accepted)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Мои соавторства",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.PostCollaborator"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/collaborations/{id}": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Принимает (accepted) или отклоняет (declined) приглашение. Автор поста получает уведомление.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Ответить на приглашение в соавторы",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID приглашения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ответ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RespondCollaborationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/devices": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет FCM токен устройства. На него приходят push уведомления о новых сообщениях и подтвержденных пожертвованиях, даже когда приложение закрыто.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Зарегистрировать устройство",
+                "parameters": [
+                    {
+                        "description": "Токен устройства",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RegisterDeviceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Device"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/devices/{token}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет FCM токен устройства (например, при выходе из аккаунта)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Удалить устройство",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "FCM токен устройства",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/donations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает пожертвования текущего пользователя. Доступно также по API токену с областью donations:read.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Получить мои пожертвования",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "confirmed",
+                            "rejected",
+                            "refunded"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по статусу",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.DonationsListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/favorites": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает посты из избранного текущего пользователя с авторами и медиа, последние добавленные первыми",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Посты"
+                ],
+                "summary": "Избранные посты",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице (до 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostsListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/hidden-posts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает посты, скрытые текущим пользователем из ленты, последние скрытые первыми",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Скрытые посты",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.HiddenPost"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Убирает пост из ленты GET /posts текущего пользователя. Повторное скрытие не ошибка.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Скрыть пост",
+                "parameters": [
+                    {
+                        "description": "Пост",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.HidePostRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Пост скрыт"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/hidden-posts/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отменяет скрытие поста. Отмена для нескрытого поста не ошибка.",
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Вернуть пост в ленту",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID поста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Пост возвращен в ленту"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/identities/{provider}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает ссылку на страницу входа провайдера. После возврата от провайдера клиент вызывает callback с тем же access токеном, и учетная запись провайдера привязывается к текущему аккаунту.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Привязать провайдера входа",
+                "parameters": [
+                    {
+                        "enum": [
+                            "vk",
+                            "yandex",
+                            "google"
+                        ],
+                        "type": "string",
+                        "description": "Провайдер",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.OAuthAuthorizeResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет привязку VK ID, Яндекс или Google. Вход по телефону остается доступен.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Отвязать провайдера входа",
+                "parameters": [
+                    {
+                        "enum": [
+                            "vk",
+                            "yandex",
+                            "google"
+                        ],
+                        "type": "string",
+                        "description": "Провайдер",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/integrations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает подключенные webhook и Telegram чат для оповещений о пожертвованиях",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Интеграции"
+                ],
+                "summary": "Получить интеграции",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Integration"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Подключает личный webhook (target - URL) или Telegram чат (target - chat_id) для мгновенных оповещений о пожертвованиях на посты пользователя. Доступно только верифицированным авторам. Для webhook в ответе возвращается секрет подписи X-Webhook-Signature, повторно он не выдается.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Интеграции"
+                ],
+                "summary": "Подключить интеграцию",
+                "parameters": [
+                    {
+                        "description": "Параметры интеграции",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpsertIntegrationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.IntegrationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/integrations/{type}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отключает интеграцию указанного типа. Неотправленные события удаляются.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Интеграции"
+                ],
+                "summary": "Отключить интеграцию",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Тип интеграции (webhook, telegram)",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/muted-authors": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает авторов, посты которых текущий пользователь скрыл из ленты, последних скрытых первыми",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Скрытые авторы",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.MutedAuthor"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Убирает посты автора из ленты GET /posts текущего пользователя. Посты автора по-прежнему доступны по фильтру user_id. Повторное скрытие не ошибка.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Скрыть автора",
+                "parameters": [
+                    {
+                        "description": "Автор",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.MuteAuthorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Автор скрыт"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/muted-authors/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отменяет скрытие автора. Отмена для нескрытого автора не ошибка.",
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Вернуть автора в ленту",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID автора",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Автор возвращен в ленту"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/notification-preferences": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает для каждого типа уведомлений, включены ли push и SMS. Уведомление в приложении сохраняется всегда.\nПо умолчанию push включен, SMS выключены. SMS доступны только для типов с sms_available (квитанция о пожертвовании).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Каналы уведомлений",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.NotificationPreference"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет push и SMS для перечисленных типов, остальные типы не меняются. SMS отправляются на подтвержденный телефон.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Изменить каналы уведомлений",
+                "parameters": [
+                    {
+                        "description": "Каналы по типам уведомлений",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateNotificationPreferencesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.NotificationPreference"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/photo": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Загружает фото профиля пользователя",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Загрузить фото профиля",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Фото профиля (JPEG, PNG, max 5MB)",
+                        "name": "photo",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PhotoUploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/post-limit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает уровень доверия пользователя, максимальную целевую сумму поста и запросы на исключение.\nlimit равен null, если лимит не действует.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Лимиты"
+                ],
+                "summary": "Лимит целевой суммы",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/post-limit/requests": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает запрос на сбор с целевой суммой больше лимита уровня доверия. Одновременно может рассматриваться только один запрос.\nОдобренное исключение действует на один пост с суммой не больше запрошенной.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Лимиты"
+                ],
+                "summary": "Запросить исключение из лимита",
+                "parameters": [
+                    {
+                        "description": "Сумма и обоснование",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreatePostLimitRequestRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PostLimitRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/quota": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает состояние лимита запросов в текущем окне (по API токену - лимит этого токена) и место, занятое медиа постов.\nТе же значения лимита приходят в заголовках X-RateLimit-Limit, X-RateLimit-Remaining и X-RateLimit-Reset каждого ответа.\nДоступно также по API токену с любой областью действия.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Лимиты запросов и квота хранилища",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.QuotaResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/referrals": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает реферальный код и ссылку текущего пользователя (код создается при первом запросе)\nи приглашенных им пользователей. За первое подтвержденное пожертвование приглашенного\nоба получают бонусные баллы рейтинга. Бонус не начисляется, если пользователи входили\nс одного IP адреса или устройства (status rejected, reject_reason same_ip или same_device).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Реферальная программа",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ReferralDashboard"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/subscriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает подписки текущего пользователя: активные первыми по ближайшему сроку, затем приостановленные",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Регулярные пожертвования",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Subscription"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Раз в неделю или месяц планировщик создает пожертвование на сбор в статусе pending и присылает уведомление\nsubscription_due (donation_id в данных push), донор оплачивает его или прикладывает чек. Первое пожертвование\nсоздается в start_at или, без него, в течение 10 минут. На один сбор у донора одна подписка.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Оформить регулярное пожертвование",
+                "parameters": [
+                    {
+                        "description": "Сбор, сумма и периодичность",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/subscriptions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет подписку текущего пользователя. Уже созданные пожертвования остаются.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Отменить регулярное пожертвование",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Меняет сумму, периодичность или анонимность следующих пожертвований; is_active=false приостанавливает подписку.\nПосле возобновления срок остается прежним, а если он прошел - переносится на следующий период без пропущенных пожертвований.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пожертвования"
+                ],
+                "summary": "Изменить регулярное пожертвование",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изменяемые поля",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Subscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/templates": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает сохраненные тексты назначения платежа текущего пользователя в порядке создания.\nКлиент подставляет их в банковское приложение при ручном переводе; шаблоны общие для всех устройств.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Шаблоны назначения платежа",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.PaymentNoteTemplate"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сохраняет текст назначения платежа (до 210 символов) под уникальным названием. У пользователя может быть не больше 20 шаблонов.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Создать шаблон назначения платежа",
+                "parameters": [
+                    {
+                        "description": "Название и текст",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SavePaymentNoteTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.PaymentNoteTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/templates/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Заменяет название и текст шаблона. По updated_at клиенты на других устройствах узнают об изменении.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Изменить шаблон назначения платежа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID шаблона",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Название и текст",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SavePaymentNoteTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.PaymentNoteTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет шаблон текущего пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Удалить шаблон назначения платежа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID шаблона",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/tokens": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает персональные API токены текущего пользователя (без значений токенов)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API токены"
+                ],
+                "summary": "Получить API токены",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выпускает токен для ботов и автоматизации с ограниченными областями действия: donations:read (чтение своих пожертвований) и posts:write (обновление своих постов). Значение токена возвращается только один раз.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API токены"
+                ],
+                "summary": "Создать API токен",
+                "parameters": [
+                    {
+                        "description": "Название, области действия и срок жизни токена",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateAPITokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateAPITokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/tokens/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет API токен, после чего запросы с ним отклоняются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "API токены"
+                ],
+                "summary": "Отозвать API токен",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID токена",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/verifications": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает список всех заявок на верификацию с пагинацией. У заявок с организацией - ИНН и результат сверки с реестром.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Верификация"
+                ],
+                "summary": "Получить список заявок на верификацию",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по статусу",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Количество на странице",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.VerificationsListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает заявку на верификацию пользователя. Состав полей документа зависит от страны и типа документа,\nсм. GET /verifications/document-types: поля передаются под своими именами (passport_series, inn, iin...).",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Верификация"
+                ],
+                "summary": "Подать заявку на верификацию",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Фото пользователя",
+                        "name": "user_photo",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "last_name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "first_name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "middle_name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата рождения (YYYY-MM-DD)",
+                        "name": "birth_date",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "RU",
+                        "description": "Страна документа (ISO 3166-1 alpha-2)",
+                        "name": "country",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ru_passport",
+                        "description": "Удостоверение личности из справочника",
+                        "name": "document_type",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Серия паспорта",
+                        "name": "passport_series",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Номер документа",
+                        "name": "passport_number",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Кем выдан",
+                        "name": "passport_issuer",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата выдачи (YYYY-MM-DD)",
+                        "name": "passport_date",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дополнительный документ, если страна его требует (для RU - inn или snils)",
+                        "name": "doc_type",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ИНН",
+                        "name": "inn",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "СНИЛС",
+                        "name": "snils",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ИНН организации (10 цифр), от имени которой собирает пользователь",
+                        "name": "organization_inn",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ОГРН организации, без него заполняется из реестра",
+                        "name": "organization_ogrn",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название организации, без него заполняется из реестра",
+                        "name": "organization_name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Сканы документа (не меньше min_scans из справочника)",
+                        "name": "passport_scans",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Согласие 1",
+                        "name": "consent1",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Согласие 2",
+                        "name": "consent2",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Согласие 3",
+                        "name": "consent3",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.VerificationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/verifications/document-types": {
+            "get": {
+                "description": "Возвращает страны и документы, по которым принимаются заявки на верификацию, с полями формы: имя, подпись, тип (text, code, date), обязательность и формат номера. По справочнику строятся форма заявки и карточка заявки у проверяющего.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Верификация"
+                ],
+                "summary": "Документы для верификации",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.DocumentCountry"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/verifications/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает статус верификации текущего пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Верификация"
+                ],
+                "summary": "Получить статус верификации",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.VerificationResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/verifications/organizations/{inn}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает сведения реестра юридических лиц по ИНН организации (10 цифр) для заполнения формы верификации:\nОГРН, название, адрес, состояние и признак некоммерческой организации. Ответы реестра кэшируются.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Верификация"
+                ],
+                "summary": "Найти организацию в реестре",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ИНН организации",
+                        "name": "inn",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.RegistryOrganization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/verifications/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает заявку: паспортные данные и результат проверки возраста. Ссылок на сканы и фото в ответе нет:\nих выдает GET /verifications/{id}/documents с правом verifications.documents и записью в журнал.\norganization_check - результат сверки организации заявителя с реестром (pending, matched, mismatch, not_found),\norganization_mismatches - расхождения (name, ogrn, inactive, not_nonprofit), organization_registry - сведения реестра.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Верификация"
+                ],
+                "summary": "Получить заявку на верификацию",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID верификации",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Verification"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет статус верификации (одобрить или отклонить). При отклонении обязательна rejection_reason: она сохраняется в журнале и отправляется пользователю.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Верификация"
+                ],
+                "summary": "Одобрить/отклонить верификацию",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID верификации",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Статус и причина отклонения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateVerificationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.VerificationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/verifications/{id}/documents": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает presigned URL сканов паспорта и фото пользователя, действующие 5 минут.\nДоступно с правом verifications.documents (по умолчанию только admin). Каждый запрос записывается в журнал admin_actions.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Верификация"
+                ],
+                "summary": "Документы заявки на верификацию",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID верификации",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.VerificationDocumentsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "backup.Status": {
+            "type": "object",
+            "properties": {
+                "dump_size": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "objects_count": {
+                    "type": "integer"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.AdminActionEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new_value": {
+                    "type": "object"
+                },
+                "old_value": {
+                    "type": "object"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "target_id": {
+                    "type": "integer"
+                },
+                "target_name": {
+                    "type": "string"
+                },
+                "target_type": {
+                    "type": "string"
+                },
+                "target_user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.AdminUserDetailResponse": {
+            "type": "object",
+            "properties": {
+                "admin_actions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AdminActionEntry"
+                    }
+                },
+                "chats_count": {
+                    "type": "integer"
+                },
+                "donations_given": {
+                    "$ref": "#/definitions/main.DonationSummary"
+                },
+                "donations_received": {
+                    "$ref": "#/definitions/main.DonationSummary"
+                },
+                "login_history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.LoginEvent"
+                    }
+                },
+                "posts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Post"
+                    }
+                },
+                "posts_total": {
+                    "type": "integer"
+                },
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RefreshToken"
+                    }
+                },
+                "user": {
+                    "$ref": "#/definitions/main.User"
+                },
+                "verification": {
+                    "$ref": "#/definitions/main.Verification"
+                }
+            }
+        },
+        "main.AnswerQuestionRequest": {
+            "type": "object",
+            "required": [
+                "answer"
+            ],
+            "properties": {
+                "answer": {
+                    "type": "string",
+                    "maxLength": 4000
+                }
+            }
+        },
+        "main.AwayStatus": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "until": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.BackupStatusResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "last_backup": {
+                    "$ref": "#/definitions/backup.Status"
+                },
+                "stale": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.BlockUserRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "main.BulkMediaResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BulkMediaResult"
+                    }
+                },
+                "uploaded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.BulkMediaResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "media": {
+                    "$ref": "#/definitions/main.PostMedia"
+                },
+                "name": {
+                    "description": "Имя файла в форме или путь в zip архиве",
+                    "type": "string"
+                }
+            }
+        },
+        "main.CalendarFeedResponse": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "type": "string",
+                    "example": "https://api.example.com/api/v1/calendar/3f9a...c1.ics"
+                }
+            }
+        },
+        "main.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "old_password"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "old_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ChatDraft": {
+            "type": "object",
+            "properties": {
+                "chat_id": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ChatResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "helper_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "needy_id": {
+                    "type": "integer"
+                },
+                "post_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.ChatWithDetails": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "helper_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "interlocutor": {
+                    "$ref": "#/definitions/main.UserInfo"
+                },
+                "last_message": {
+                    "$ref": "#/definitions/main.Message"
+                },
+                "needy_id": {
+                    "type": "integer"
+                },
+                "post": {
+                    "$ref": "#/definitions/main.PostWithDetails"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "unread_count": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ChatsListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.ChatWithDetails"
+                    }
+                }
+            }
+        },
+        "main.ConfirmStatementRequest": {
+            "type": "object",
+            "properties": {
+                "min_confidence": {
+                    "type": "number",
+                    "maximum": 1
+                },
+                "row_ids": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "main.CreateAPITokenRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_in_days": {
+                    "type": "integer",
+                    "maximum": 365,
+                    "minimum": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.CreateAPITokenResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.CreateChatRequest": {
+            "type": "object",
+            "required": [
+                "post_id"
+            ],
+            "properties": {
+                "post_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.CreateCommentRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "text": {
+                    "type": "string",
+                    "maxLength": 2000
+                }
+            }
+        },
+        "main.CreateInviteCodesRequest": {
+            "type": "object",
+            "required": [
+                "batch",
+                "count",
+                "max_uses"
+            ],
+            "properties": {
+                "batch": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "count": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_uses": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "main.CreatePointsEventRequest": {
+            "type": "object",
+            "required": [
+                "ends_at",
+                "multiplier",
+                "starts_at",
+                "title"
+            ],
+            "properties": {
+                "ends_at": {
+                    "type": "string"
+                },
+                "multiplier": {
+                    "type": "number",
+                    "maximum": 10
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 100
+                }
+            }
+        },
+        "main.CreatePostLimitRequestRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "reason"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 2000,
+                    "minLength": 10
+                }
+            }
+        },
+        "main.CreateQuestionRequest": {
+            "type": "object",
+            "required": [
+                "question"
+            ],
+            "properties": {
+                "question": {
+                    "type": "string",
+                    "maxLength": 1000
+                }
+            }
+        },
+        "main.CreateSubscriptionRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "interval",
+                "post_id"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "interval": {
+                    "type": "string",
+                    "enum": [
+                        "weekly",
+                        "monthly"
+                    ]
+                },
+                "is_anonymous": {
+                    "type": "boolean"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "start_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "events",
+                "name",
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "url": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "main.DeadLetter": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "last_retried_at": {
+                    "type": "string"
+                },
+                "last_retried_by": {
+                    "type": "integer"
+                },
+                "next_retry_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Задание для повтора (FanoutJob) или описание события",
+                    "type": "object"
+                },
+                "reference_id": {
+                    "description": "ID события в webhook_deliveries для заданий webhook",
+                    "type": "integer"
+                },
+                "retries": {
+                    "description": "Ручные повторы",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Device": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.Digest": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DigestDelivery"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "report": {
+                    "$ref": "#/definitions/main.DigestReport"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DigestDelivery": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "sent": {
+                    "type": "boolean"
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DigestReport": {
+            "type": "object",
+            "properties": {
+                "amount_raised": {
+                    "type": "number"
+                },
+                "donations_confirmed": {
+                    "description": "Пожертвования, подтвержденные за неделю",
+                    "type": "integer"
+                },
+                "limit_requests_pending": {
+                    "type": "integer"
+                },
+                "new_posts": {
+                    "description": "Посты, созданные за неделю",
+                    "type": "integer"
+                },
+                "posts_moderation": {
+                    "description": "Требует внимания модераторов: посты на модерации, запросы на повышение лимита,\nчеки, сумма или дата которых не совпала с пожертвованием за неделю",
+                    "type": "integer"
+                },
+                "receipt_mismatches": {
+                    "type": "integer"
+                },
+                "verifications_oldest_at": {
+                    "type": "string"
+                },
+                "verifications_pending": {
+                    "description": "Очередь верификации на конец недели и заявки, рассмотренные за неделю",
+                    "type": "integer"
+                },
+                "verifications_reviewed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.DocumentCountry": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Код страны ISO 3166-1 alpha-2",
+                    "type": "string"
+                },
+                "identity_documents": {
+                    "description": "Удостоверение личности, одно на выбор",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DocumentType"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "tax_documents": {
+                    "description": "Дополнительный документ (doc_type), один на выбор; пустой список - не требуется",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DocumentType"
+                    }
+                }
+            }
+        },
+        "main.DocumentField": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Имя поля формы и ключ в document_data",
+                    "type": "string"
+                },
+                "pattern": {
+                    "description": "Регулярное выражение номера после нормализации, для подсказки в форме",
+                    "type": "string"
+                },
+                "required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.DocumentType": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DocumentField"
+                    }
+                },
+                "min_scans": {
+                    "description": "Сколько сканов страниц нужно приложить",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DonationDayStats": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2026-10-01"
+                }
+            }
+        },
+        "main.DonationEvent": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "donation_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DonationHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "consistent": {
+                    "type": "boolean"
+                },
+                "donation_id": {
+                    "type": "integer"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DonationEvent"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DonationResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "donor_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "receipt_check": {
+                    "type": "string"
+                },
+                "receipt_url": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DonationStatsResponse": {
+            "type": "object",
+            "properties": {
+                "collected": {
+                    "type": "number"
+                },
+                "daily": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DonationDayStats"
+                    }
+                },
+                "goal": {
+                    "type": "number"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "top_donors": {
+                    "description": "Доноры с наибольшей подтвержденной суммой, анонимные пожертвования не учитываются",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.TopDonor"
+                    }
+                },
+                "totals": {
+                    "$ref": "#/definitions/main.DonationTotals"
+                }
+            }
+        },
+        "main.DonationSummary": {
+            "type": "object",
+            "properties": {
+                "confirmed_amount": {
+                    "type": "number"
+                },
+                "confirmed_count": {
+                    "type": "integer"
+                },
+                "count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.DonationTotals": {
+            "type": "object",
+            "properties": {
+                "anonymous_amount": {
+                    "description": "Подтвержденная сумма анонимных пожертвований",
+                    "type": "number"
+                },
+                "average_amount": {
+                    "type": "number"
+                },
+                "confirmed_amount": {
+                    "type": "number"
+                },
+                "confirmed_count": {
+                    "type": "integer"
+                },
+                "pending_amount": {
+                    "type": "number"
+                },
+                "pending_count": {
+                    "type": "integer"
+                },
+                "refunded_count": {
+                    "type": "integer"
+                },
+                "rejected_count": {
+                    "type": "integer"
+                },
+                "unique_donors": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.DonationUpdateResponse": {
+            "type": "object",
+            "properties": {
+                "confirmed_at": {
+                    "type": "string"
+                },
+                "confirmed_by": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DonationWithDetails": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "confirmed_at": {
+                    "type": "string"
+                },
+                "confirmed_by": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "donor": {
+                    "$ref": "#/definitions/main.UserInfo"
+                },
+                "donor_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_anonymous": {
+                    "description": "Анонимное пожертвование: донор виден только ему самому и администраторам,\nбаллы рейтинга начисляются как обычно",
+                    "type": "boolean"
+                },
+                "post": {
+                    "$ref": "#/definitions/main.PostInfo"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "receipt_amount": {
+                    "type": "number"
+                },
+                "receipt_check": {
+                    "description": "Сверка чека: pending, matched, mismatch или unreadable",
+                    "type": "string"
+                },
+                "receipt_checked_at": {
+                    "type": "string"
+                },
+                "receipt_date": {
+                    "type": "string"
+                },
+                "receipt_url": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DonationsListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DonationWithDetails"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationResponse"
+                }
+            }
+        },
+        "main.ErrorDetail": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/main.ErrorDetail"
+                }
+            }
+        },
+        "main.ForgotPasswordRequest": {
+            "type": "object",
+            "required": [
+                "phone"
+            ],
+            "properties": {
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.HealthCheckResponse": {
+            "type": "object",
+            "properties": {
+                "backup": {
+                    "type": "string"
+                },
+                "database": {
+                    "type": "string"
+                },
+                "minio": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.HiddenPost": {
+            "type": "object",
+            "properties": {
+                "hidden_at": {
+                    "type": "string"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.HidePostRequest": {
+            "type": "object",
+            "required": [
+                "post_id"
+            ],
+            "properties": {
+                "post_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.ImageVariants": {
+            "type": "object",
+            "properties": {
+                "large": {
+                    "type": "string"
+                },
+                "medium": {
+                    "type": "string"
+                },
+                "small": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Integration": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.IntegrationResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.InviteCollaboratorRequest": {
+            "type": "object",
+            "required": [
+                "permissions",
+                "phone"
+            ],
+            "properties": {
+                "permissions": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.InvitedUser": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.JobStatus": {
+            "type": "object",
+            "properties": {
+                "interval": {
+                    "type": "string"
+                },
+                "last_duration_ms": {
+                    "type": "number"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_run_at": {
+                    "type": "string"
+                },
+                "last_success_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "stale": {
+                    "description": "Задача запускалась, но не завершалась успешно дольше jobStaleRuns интервалов",
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.LineItemRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "title"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "main.LoginEvent": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "phone"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/main.User"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.LogoutRequest": {
+            "type": "object",
+            "properties": {
+                "all_sessions": {
+                    "type": "boolean"
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MarkMessagesReadRequest": {
+            "type": "object",
+            "properties": {
+                "message_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "main.MarkMessagesReadResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "updated_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.MarkNotificationsReadRequest": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "main.Message": {
+            "type": "object",
+            "properties": {
+                "attachment_url": {
+                    "type": "string"
+                },
+                "chat_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_edited": {
+                    "type": "boolean"
+                },
+                "is_read": {
+                    "type": "boolean"
+                },
+                "sender_id": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MessageResponse": {
+            "type": "object",
+            "properties": {
+                "attachment_url": {
+                    "type": "string"
+                },
+                "chat_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_edited": {
+                    "type": "boolean"
+                },
+                "is_read": {
+                    "type": "boolean"
+                },
+                "sender_id": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MessageUpdateResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "is_edited": {
+                    "type": "boolean"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MessageWithDetails": {
+            "type": "object",
+            "properties": {
+                "attachment_url": {
+                    "type": "string"
+                },
+                "chat_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_edited": {
+                    "type": "boolean"
+                },
+                "is_read": {
+                    "type": "boolean"
+                },
+                "sender": {
+                    "$ref": "#/definitions/main.UserInfo"
+                },
+                "sender_id": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MessagesListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.MessageWithDetails"
+                    }
+                },
+                "has_more": {
+                    "type": "boolean"
+                },
+                "next_cursor": {
+                    "type": "integer"
+                },
+                "pagination": {
+                    "description": "Только при постраничной выдаче (без before_id/after_id)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.PaginationResponse"
+                        }
+                    ]
+                }
+            }
+        },
+        "main.ModeratePostRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "reason": {
+                    "description": "Обязательна при закрытии поста, передается автору в уведомлении",
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "closed"
+                    ]
+                }
+            }
+        },
+        "main.ModerationPostResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "bank": {
+                    "type": "string"
+                },
+                "beneficiary_is_minor": {
+                    "description": "Сбор в пользу несовершеннолетнего, создается родителем или опекуном",
+                    "type": "boolean"
+                },
+                "collected": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deadline": {
+                    "description": "Срок сбора: после него активный пост закрывается автоматически",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "guardian_document_url": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_editable": {
+                    "type": "boolean"
+                },
+                "language": {
+                    "description": "Язык текста поста (ISO 639-1), определяется автоматически",
+                    "type": "string"
+                },
+                "overdue": {
+                    "type": "boolean"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "review_deadline": {
+                    "description": "Срок рассмотрения срочного сбора и его нарушение",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "urgent": {
+                    "description": "Срочный сбор: рассматривается модератором вне очереди и после публикации\nдо UrgentUntil показывается первым в ленте",
+                    "type": "boolean"
+                },
+                "urgent_document_url": {
+                    "type": "string"
+                },
+                "urgent_until": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.MoneyConfigResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "currency_symbol": {
+                    "type": "string",
+                    "example": "₽"
+                },
+                "donation": {
+                    "$ref": "#/definitions/main.MoneyLimits"
+                },
+                "format": {
+                    "$ref": "#/definitions/main.MoneyFormat"
+                },
+                "fraction_digits": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "points": {
+                    "$ref": "#/definitions/main.PointsRates"
+                },
+                "rounding": {
+                    "$ref": "#/definitions/main.MoneyRounding"
+                },
+                "step": {
+                    "description": "Минимальный шаг суммы, 10^-fraction_digits",
+                    "type": "number",
+                    "example": 0.01
+                }
+            }
+        },
+        "main.MoneyFormat": {
+            "type": "object",
+            "properties": {
+                "decimal_separator": {
+                    "type": "string",
+                    "example": ","
+                },
+                "example": {
+                    "type": "string",
+                    "example": "1 500,50 ₽"
+                },
+                "group_separator": {
+                    "type": "string",
+                    "example": " "
+                },
+                "symbol_position": {
+                    "type": "string",
+                    "enum": [
+                        "before",
+                        "after"
+                    ],
+                    "example": "after"
+                }
+            }
+        },
+        "main.MoneyLimits": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number",
+                    "example": 1000000
+                },
+                "min": {
+                    "type": "number",
+                    "example": 1
+                }
+            }
+        },
+        "main.MoneyRounding": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "enum": [
+                        "half_up"
+                    ],
+                    "example": "half_up"
+                },
+                "points": {
+                    "type": "string",
+                    "enum": [
+                        "floor"
+                    ],
+                    "example": "floor"
+                }
+            }
+        },
+        "main.MuteAuthorRequest": {
+            "type": "object",
+            "required": [
+                "author_id"
+            ],
+            "properties": {
+                "author_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.MutedAuthor": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "$ref": "#/definitions/main.UserInfo"
+                },
+                "muted_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Notification": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_read": {
+                    "type": "boolean"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.NotificationPreference": {
+            "type": "object",
+            "required": [
+                "type"
+            ],
+            "properties": {
+                "push": {
+                    "type": "boolean"
+                },
+                "sms": {
+                    "type": "boolean"
+                },
+                "sms_available": {
+                    "description": "Можно ли включить SMS для этого типа",
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.NotificationsListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Notification"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationResponse"
+                }
+            }
+        },
+        "main.OAuthAuthorizeResponse": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.OpsBacklog": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "oldest_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.OpsResponse": {
+            "type": "object",
+            "properties": {
+                "dead_letters": {
+                    "description": "Задания в очереди недоставленных, ожидающие решения администратора",
+                    "type": "integer"
+                },
+                "jobs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.JobStatus"
+                    }
+                },
+                "notification_queue": {
+                    "description": "Рассылки уведомлений в памяти процесса",
+                    "type": "integer"
+                },
+                "notification_queue_capacity": {
+                    "type": "integer"
+                },
+                "outbox": {
+                    "description": "Исходящие события интеграций и вебхуков партнеров, ожидающие доставки",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.OpsBacklog"
+                        }
+                    ]
+                },
+                "stuck_uploads": {
+                    "description": "Загруженные чеки, сверка которых не завершилась за OpsStuckUploadAfter",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.OpsBacklog"
+                        }
+                    ]
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "webhook_failed": {
+                    "description": "События, доставка которых прекращена после всех попыток: всего и созданные за сутки",
+                    "type": "integer"
+                },
+                "webhook_failed_24h": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PaginationResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PartnerWebhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Payment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "confirmation_url": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "donation_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "provider_payment_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PaymentNoteTemplate": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PhotoUploadResponse": {
+            "type": "object",
+            "properties": {
+                "photo_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PointsEvent": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "multiplier": {
+                    "type": "number"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PointsRates": {
+            "type": "object",
+            "properties": {
+                "multiplier": {
+                    "description": "Множитель идущей акции, 1 без акции",
+                    "type": "number",
+                    "example": 1
+                },
+                "per_unit": {
+                    "type": "number",
+                    "example": 1
+                }
+            }
+        },
+        "main.Post": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "bank": {
+                    "type": "string"
+                },
+                "beneficiary_is_minor": {
+                    "description": "Сбор в пользу несовершеннолетнего, создается родителем или опекуном",
+                    "type": "boolean"
+                },
+                "collected": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deadline": {
+                    "description": "Срок сбора: после него активный пост закрывается автоматически",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_editable": {
+                    "type": "boolean"
+                },
+                "language": {
+                    "description": "Язык текста поста (ISO 639-1), определяется автоматически",
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "urgent": {
+                    "description": "Срочный сбор: рассматривается модератором вне очереди и после публикации\nдо UrgentUntil показывается первым в ленте",
+                    "type": "boolean"
+                },
+                "urgent_until": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PostCollaborator": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "invited_by": {
+                    "type": "integer"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "post_title": {
+                    "type": "string"
+                },
+                "responded_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/main.UserInfo"
+                }
+            }
+        },
+        "main.PostComment": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "$ref": "#/definitions/main.UserInfo"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PostCommentsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PostComment"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationResponse"
+                }
+            }
+        },
+        "main.PostInfo": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "collected": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PostLimitRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "post_id": {
+                    "description": "Пост, на который израсходовано одобренное исключение",
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reviewed_at": {
+                    "type": "string"
+                },
+                "reviewed_by": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "used_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PostLineItem": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "collected": {
+                    "description": "Часть собранной суммы, приходящаяся на статью: статьи закрываются по порядку",
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_index": {
+                    "type": "integer"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PostMedia": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "media_type": {
+                    "type": "string"
+                },
+                "media_url": {
+                    "type": "string"
+                },
+                "order_index": {
+                    "type": "integer"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "variants": {
+                    "description": "Уменьшенные копии изображения (для видео не создаются)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.ImageVariants"
+                        }
+                    ]
+                }
+            }
+        },
+        "main.PostProgressEvent": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "collected": {
+                    "type": "number"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PostQuestion": {
+            "type": "object",
+            "properties": {
+                "answer": {
+                    "type": "string"
+                },
+                "answered_at": {
+                    "type": "string"
+                },
+                "author": {
+                    "$ref": "#/definitions/main.UserInfo"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "post_id": {
+                    "type": "integer"
+                },
+                "question": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PostQuestionsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PostQuestion"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationResponse"
+                }
+            }
+        },
+        "main.PostResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "collected": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.PostUpdateResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PostWithDetails": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "answered_questions": {
+                    "description": "Вопросы с ответом автора (публичные)",
+                    "type": "integer"
+                },
+                "author": {
+                    "$ref": "#/definitions/main.UserInfo"
+                },
+                "bank": {
+                    "type": "string"
+                },
+                "beneficiary_is_minor": {
+                    "description": "Сбор в пользу несовершеннолетнего, создается родителем или опекуном",
+                    "type": "boolean"
+                },
+                "collected": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deadline": {
+                    "description": "Срок сбора: после него активный пост закрывается автоматически",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_editable": {
+                    "type": "boolean"
                 },
-                "id": {
-                    "type": "integer"
+                "language": {
+                    "description": "Язык текста поста (ISO 639-1), определяется автоматически",
+                    "type": "string"
                 },
-                "interlocutor": {
-                    "$ref": "#/definitions/main.UserInfo"
+                "line_items": {
+                    "description": "Разбивка целевой суммы по статьям расходов",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PostLineItem"
+                    }
                 },
-                "last_message": {
-                    "$ref": "#/definitions/main.Message"
+                "media": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.PostMedia"
+                    }
                 },
-                "needy_id": {
-                    "type": "integer"
+                "phone": {
+                    "type": "string"
                 },
-                "post": {
-                    "$ref": "#/definitions/main.PostWithDetails"
+                "recipient": {
+                    "type": "string"
                 },
-                "post_id": {
-                    "type": "integer"
+                "status": {
+                    "type": "string"
                 },
-                "unread_count": {
+                "title": {
+                    "type": "string"
+                },
+                "unanswered_questions": {
+                    "description": "Вопросы без ответа, только для автора поста и соавторов с правом edit",
                     "type": "integer"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "urgent": {
+                    "description": "Срочный сбор: рассматривается модератором вне очереди и после публикации\nдо UrgentUntil показывается первым в ленте",
+                    "type": "boolean"
+                },
+                "urgent_until": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "main.ChatsListResponse": {
+        "main.PostsListResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.ChatWithDetails"
+                        "$ref": "#/definitions/main.PostWithDetails"
                     }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationResponse"
                 }
             }
         },
-        "main.CreateChatRequest": {
+        "main.PresignedGetURLRequest": {
             "type": "object",
             "required": [
-                "post_id"
+                "bucket",
+                "object_key"
             ],
             "properties": {
-                "post_id": {
+                "bucket": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "max 7 days",
+                    "type": "integer",
+                    "maximum": 604800,
+                    "minimum": 1
+                },
+                "object_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PresignedGetURLResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PresignedURLRequest": {
+            "type": "object",
+            "required": [
+                "bucket",
+                "content_type",
+                "object_key"
+            ],
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "content_type": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "max 7 days",
+                    "type": "integer",
+                    "maximum": 604800,
+                    "minimum": 1
+                },
+                "object_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.PresignedURLResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "object_key": {
+                    "description": "Ключ, под которым будет сохранен файл (uploads/{user_id}/...)",
+                    "type": "string"
+                },
+                "object_url": {
+                    "type": "string"
+                },
+                "upload_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.QuotaResponse": {
+            "type": "object",
+            "properties": {
+                "rate_limit": {
+                    "description": "null, если лимит запросов отключен",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.RateLimitStatus"
+                        }
+                    ]
+                },
+                "storage": {
+                    "$ref": "#/definitions/main.StorageUsage"
+                }
+            }
+        },
+        "main.RateLimitStatus": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "remaining": {
                     "type": "integer"
+                },
+                "reset": {
+                    "type": "string"
                 }
             }
         },
-        "main.DonationResponse": {
+        "main.RatingEvent": {
             "type": "object",
             "properties": {
                 "amount": {
                     "type": "number"
                 },
+                "balance": {
+                    "description": "Баллы после изменения",
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
-                "donor_id": {
+                "donation_id": {
                     "type": "integer"
                 },
                 "id": {
                     "type": "integer"
                 },
+                "points": {
+                    "type": "integer"
+                },
                 "post_id": {
                     "type": "integer"
                 },
-                "receipt_url": {
+                "post_title": {
                     "type": "string"
                 },
+                "referral_id": {
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "string",
+                    "enum": [
+                        "donation",
+                        "donation_reversal",
+                        "referral",
+                        "referral_reversal",
+                        "opening_balance"
+                    ]
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.RatingHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RatingEvent"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationResponse"
+                }
+            }
+        },
+        "main.RatingWithDetails": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "position": {
+                    "type": "integer"
+                },
                 "status": {
                     "type": "string"
+                },
+                "total_donated": {
+                    "type": "number"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/main.UserInfo"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "main.DonationUpdateResponse": {
+        "main.RatingsListResponse": {
             "type": "object",
             "properties": {
-                "confirmed_at": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.RatingWithDetails"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/main.PaginationResponse"
+                },
+                "period": {
+                    "description": "Только с параметром period: период и его начало",
+                    "type": "string",
+                    "enum": [
+                        "week",
+                        "month",
+                        "year"
+                    ]
+                },
+                "since": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ReadinessResponse": {
+            "type": "object",
+            "properties": {
+                "breakers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "database": {
                     "type": "string"
                 },
-                "confirmed_by": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Referral": {
+            "type": "object",
+            "properties": {
+                "bonus_points": {
                     "type": "integer"
                 },
+                "created_at": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "referee_id": {
+                    "type": "integer"
+                },
+                "referrer_id": {
+                    "type": "integer"
+                },
+                "reject_reason": {
+                    "type": "string"
+                },
+                "rewarded_at": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "string"
                 }
             }
         },
-        "main.DonationWithDetails": {
+        "main.ReferralDashboard": {
+            "type": "object",
+            "properties": {
+                "bonus_points": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "invited": {
+                    "type": "integer"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "referrals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Referral"
+                    }
+                },
+                "rewarded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.RefreshToken": {
             "type": "object",
             "properties": {
-                "amount": {
-                    "type": "number"
+                "created_at": {
+                    "type": "string"
                 },
-                "confirmed_at": {
+                "expires_at": {
                     "type": "string"
                 },
-                "confirmed_by": {
+                "id": {
                     "type": "integer"
                 },
-                "created_at": {
+                "ip_address": {
                     "type": "string"
                 },
-                "donor": {
-                    "$ref": "#/definitions/main.UserInfo"
-                },
-                "donor_id": {
+                "replaced_by": {
                     "type": "integer"
                 },
-                "id": {
-                    "type": "integer"
+                "revoked_at": {
+                    "type": "string"
                 },
-                "post": {
-                    "$ref": "#/definitions/main.PostInfo"
+                "user_agent": {
+                    "type": "string"
                 },
-                "post_id": {
+                "user_id": {
                     "type": "integer"
-                },
-                "receipt_url": {
+                }
+            }
+        },
+        "main.RefreshTokenRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.RefreshTokenResponse": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
                     "type": "string"
                 },
-                "status": {
+                "token": {
                     "type": "string"
                 }
             }
         },
-        "main.DonationsListResponse": {
+        "main.RegisterDeviceRequest": {
             "type": "object",
+            "required": [
+                "platform",
+                "token"
+            ],
             "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.DonationWithDetails"
-                    }
+                "platform": {
+                    "type": "string",
+                    "enum": [
+                        "android",
+                        "ios",
+                        "web"
+                    ]
                 },
-                "pagination": {
-                    "$ref": "#/definitions/main.PaginationResponse"
+                "token": {
+                    "type": "string",
+                    "maxLength": 512
                 }
             }
         },
-        "main.ErrorDetail": {
+        "main.RegisterRequest": {
             "type": "object",
+            "required": [
+                "first_name",
+                "last_name",
+                "password",
+                "phone"
+            ],
             "properties": {
-                "code": {
+                "first_name": {
                     "type": "string"
                 },
-                "details": {
-                    "type": "object",
-                    "additionalProperties": true
+                "invite_code": {
+                    "description": "Код приглашения; обязателен, если регистрация только по приглашениям",
+                    "type": "string"
                 },
-                "message": {
+                "last_name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "referral_code": {
+                    "description": "Реферальный код пригласившего пользователя",
                     "type": "string"
                 }
             }
         },
-        "main.ErrorResponse": {
+        "main.RegisterResponse": {
             "type": "object",
             "properties": {
-                "error": {
-                    "$ref": "#/definitions/main.ErrorDetail"
+                "message": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "main.HealthCheckResponse": {
+        "main.RegistryOrganization": {
             "type": "object",
             "properties": {
-                "database": {
+                "address": {
                     "type": "string"
                 },
-                "minio": {
+                "full_name": {
                     "type": "string"
                 },
-                "status": {
+                "inn": {
                     "type": "string"
                 },
-                "timestamp": {
+                "kpp": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "non_profit": {
+                    "description": "Некоммерческая организация по коду ОКОПФ",
+                    "type": "boolean"
+                },
+                "ogrn": {
+                    "type": "string"
+                },
+                "registered_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Состояние: ACTIVE, LIQUIDATING, LIQUIDATED, BANKRUPT, REORGANIZING",
                     "type": "string"
                 }
             }
         },
-        "main.LoginRequest": {
+        "main.RequestOTPRequest": {
             "type": "object",
             "required": [
-                "password",
                 "phone"
             ],
             "properties": {
-                "password": {
-                    "type": "string"
-                },
                 "phone": {
                     "type": "string"
                 }
             }
         },
-        "main.LoginResponse": {
+        "main.ResetPasswordRequest": {
             "type": "object",
+            "required": [
+                "code",
+                "new_password",
+                "phone"
+            ],
             "properties": {
-                "token": {
+                "code": {
                     "type": "string"
                 },
-                "user": {
-                    "$ref": "#/definitions/main.User"
+                "new_password": {
+                    "type": "string",
+                    "minLength": 6
                 },
-                "user_id": {
-                    "type": "integer"
+                "phone": {
+                    "type": "string"
                 }
             }
         },
-        "main.MarkMessagesReadRequest": {
+        "main.RespondCollaborationRequest": {
             "type": "object",
+            "required": [
+                "status"
+            ],
             "properties": {
-                "message_ids": {
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "accepted",
+                        "declined"
+                    ]
                 }
             }
         },
-        "main.MarkMessagesReadResponse": {
+        "main.ResponseTime": {
             "type": "object",
             "properties": {
-                "message": {
+                "hint": {
                     "type": "string"
                 },
-                "updated_count": {
+                "median_minutes": {
                     "type": "integer"
                 }
             }
         },
-        "main.Message": {
+        "main.RetentionReport": {
             "type": "object",
             "properties": {
-                "attachment_url": {
-                    "type": "string"
-                },
-                "chat_id": {
+                "chat_attachments": {
+                    "description": "Вложения сообщений, отправленных до ChatAttachmentsBefore",
                     "type": "integer"
                 },
-                "created_at": {
+                "chat_attachments_before": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "is_edited": {
+                "dry_run": {
                     "type": "boolean"
                 },
-                "is_read": {
+                "enabled": {
                     "type": "boolean"
                 },
-                "sender_id": {
+                "expired_otp_codes": {
+                    "description": "Коды подтверждения, истекшие до OTPExpiredBefore",
                     "type": "integer"
                 },
-                "text": {
-                    "type": "string"
+                "inactive_accounts": {
+                    "description": "Аккаунты без входа до InactiveBefore: обезличиваются",
+                    "type": "integer"
                 },
-                "updated_at": {
+                "inactive_before": {
                     "type": "string"
-                }
-            }
-        },
-        "main.MessageResponse": {
-            "type": "object",
-            "properties": {
-                "attachment_url": {
+                },
+                "otp_expired_before": {
                     "type": "string"
                 },
-                "chat_id": {
+                "retained_post_documents": {
+                    "description": "Документы законного представителя и срочности в постах обезличиваемых аккаунтов:\nостаются вместе с постами. Считаются только в режиме dry_run",
                     "type": "integer"
                 },
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
+                "retained_verification_docs": {
                     "type": "integer"
                 },
-                "is_edited": {
-                    "type": "boolean"
+                "unverified_accounts": {
+                    "description": "Неподтвержденные регистрации без входа до UnverifiedBefore: удаляются",
+                    "type": "integer"
                 },
-                "is_read": {
-                    "type": "boolean"
+                "unverified_before": {
+                    "type": "string"
                 },
-                "sender_id": {
+                "verification_docs": {
+                    "description": "Заявки на верификацию обезличиваемых аккаунтов удаляются вместе с паспортными данными.\nVerificationDocs - их сканы, удаляемые из хранилища; RetainedVerificationDocs - сканы,\nзагруженные после VerificationDocsRetainedAfter: под блокировкой хранилища они остаются\nдо истечения законного срока",
                     "type": "integer"
                 },
-                "text": {
+                "verification_docs_retained_after": {
                     "type": "string"
                 }
             }
         },
-        "main.MessageUpdateResponse": {
+        "main.ReviewPostLimitRequestRequest": {
             "type": "object",
+            "required": [
+                "status"
+            ],
             "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "is_edited": {
-                    "type": "boolean"
-                },
-                "text": {
-                    "type": "string"
+                "comment": {
+                    "type": "string",
+                    "maxLength": 500
                 },
-                "updated_at": {
-                    "type": "string"
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "approved",
+                        "rejected"
+                    ]
                 }
             }
         },
-        "main.MessageWithDetails": {
+        "main.RiskTier": {
             "type": "object",
             "properties": {
-                "attachment_url": {
-                    "type": "string"
-                },
-                "chat_id": {
-                    "type": "integer"
-                },
-                "created_at": {
+                "description": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "is_edited": {
-                    "type": "boolean"
-                },
-                "is_read": {
-                    "type": "boolean"
-                },
-                "sender": {
-                    "$ref": "#/definitions/main.UserInfo"
+                "max_post_amount": {
+                    "type": "number"
                 },
-                "sender_id": {
+                "min_completed_posts": {
+                    "description": "Число завершенных сборов для автоматического назначения; nil - только вручную",
                     "type": "integer"
                 },
-                "text": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Role": {
+            "type": "object",
+            "properties": {
+                "description": {
                     "type": "string"
                 },
-                "updated_at": {
+                "name": {
                     "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "main.MessagesListResponse": {
+        "main.SaveChatDraftRequest": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string",
+                    "maxLength": 4000
+                }
+            }
+        },
+        "main.SavePaymentNoteTemplateRequest": {
             "type": "object",
+            "required": [
+                "name",
+                "text"
+            ],
             "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.MessageWithDetails"
-                    }
+                "name": {
+                    "type": "string",
+                    "maxLength": 50
                 },
-                "pagination": {
-                    "$ref": "#/definitions/main.PaginationResponse"
+                "text": {
+                    "type": "string",
+                    "maxLength": 210
                 }
             }
         },
-        "main.PaginationResponse": {
+        "main.SaveRiskTierRequest": {
             "type": "object",
+            "required": [
+                "max_post_amount"
+            ],
             "properties": {
-                "limit": {
-                    "type": "integer"
-                },
-                "page": {
-                    "type": "integer"
+                "description": {
+                    "type": "string",
+                    "maxLength": 200
                 },
-                "total": {
-                    "type": "integer"
+                "max_post_amount": {
+                    "type": "number"
                 },
-                "total_pages": {
-                    "type": "integer"
+                "min_completed_posts": {
+                    "type": "integer",
+                    "minimum": 0
                 }
             }
         },
-        "main.PhotoUploadResponse": {
+        "main.SaveRoleRequest": {
             "type": "object",
+            "required": [
+                "permissions"
+            ],
             "properties": {
-                "photo_url": {
-                    "type": "string"
+                "description": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "main.PostInfo": {
+        "main.SelfTestCheck": {
             "type": "object",
             "properties": {
-                "amount": {
+                "duration_ms": {
                     "type": "number"
                 },
-                "collected": {
-                    "type": "number"
+                "error": {
+                    "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "name": {
+                    "type": "string"
                 },
-                "title": {
+                "status": {
                     "type": "string"
                 }
             }
         },
-        "main.PostMedia": {
+        "main.SelfTestReport": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.SelfTestCheck"
+                    }
                 },
-                "media_type": {
+                "started_at": {
                     "type": "string"
                 },
-                "media_url": {
+                "status": {
                     "type": "string"
-                },
-                "order_index": {
-                    "type": "integer"
-                },
-                "post_id": {
-                    "type": "integer"
                 }
             }
         },
-        "main.PostResponse": {
+        "main.SetAwayRequest": {
             "type": "object",
+            "required": [
+                "message"
+            ],
             "properties": {
-                "amount": {
-                    "type": "number"
-                },
-                "collected": {
-                    "type": "number"
+                "message": {
+                    "type": "string",
+                    "maxLength": 1000
                 },
+                "until": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.StatementImport": {
+            "type": "object",
+            "properties": {
                 "created_at": {
                     "type": "string"
                 },
-                "description": {
+                "file_name": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "status": {
-                    "type": "string"
-                },
-                "title": {
-                    "type": "string"
+                "post_id": {
+                    "type": "integer"
                 },
-                "updated_at": {
-                    "type": "string"
+                "rows_skipped": {
+                    "description": "Строки, которые не удалось разобрать, и исходящие платежи",
+                    "type": "integer"
                 },
-                "user_id": {
+                "uploaded_by": {
                     "type": "integer"
                 }
             }
         },
-        "main.PostUpdateResponse": {
+        "main.StatementImportResponse": {
             "type": "object",
             "properties": {
-                "id": {
+                "import": {
+                    "$ref": "#/definitions/main.StatementImport"
+                },
+                "proposed": {
                     "type": "integer"
                 },
-                "title": {
-                    "type": "string"
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.StatementRow"
+                    }
                 },
-                "updated_at": {
-                    "type": "string"
+                "unmatched": {
+                    "type": "integer"
                 }
             }
         },
-        "main.PostWithDetails": {
+        "main.StatementRow": {
             "type": "object",
             "properties": {
                 "amount": {
                     "type": "number"
                 },
-                "author": {
-                    "$ref": "#/definitions/main.UserInfo"
-                },
-                "bank": {
-                    "type": "string"
-                },
-                "collected": {
+                "confidence": {
                     "type": "number"
                 },
-                "created_at": {
+                "date": {
                     "type": "string"
                 },
                 "description": {
                     "type": "string"
                 },
+                "donation_id": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "is_editable": {
-                    "type": "boolean"
+                "payer": {
+                    "type": "string"
                 },
-                "media": {
+                "reasons": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.PostMedia"
+                        "type": "string"
                     }
                 },
-                "phone": {
-                    "type": "string"
-                },
-                "recipient": {
+                "reviewed_at": {
                     "type": "string"
                 },
-                "status": {
-                    "type": "string"
+                "reviewed_by": {
+                    "type": "integer"
                 },
-                "title": {
-                    "type": "string"
+                "row_number": {
+                    "type": "integer"
                 },
-                "updated_at": {
+                "status": {
                     "type": "string"
-                },
-                "user_id": {
-                    "type": "integer"
                 }
             }
         },
-        "main.PostsListResponse": {
+        "main.StorageUsage": {
             "type": "object",
             "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.PostWithDetails"
-                    }
+                "limit_bytes": {
+                    "description": "0 - квота не ограничена",
+                    "type": "integer"
                 },
-                "pagination": {
-                    "$ref": "#/definitions/main.PaginationResponse"
+                "used_bytes": {
+                    "type": "integer"
                 }
             }
         },
-        "main.PresignedGetURLRequest": {
+        "main.Subscription": {
             "type": "object",
-            "required": [
-                "bucket",
-                "object_key"
-            ],
             "properties": {
-                "bucket": {
+                "amount": {
+                    "type": "number"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "expires_in": {
-                    "description": "max 7 days",
-                    "type": "integer",
-                    "maximum": 604800,
-                    "minimum": 1
+                "donor_id": {
+                    "type": "integer"
                 },
-                "object_key": {
-                    "type": "string"
-                }
-            }
-        },
-        "main.PresignedGetURLResponse": {
-            "type": "object",
-            "properties": {
-                "expires_at": {
-                    "type": "string"
+                "id": {
+                    "type": "integer"
                 },
-                "url": {
-                    "type": "string"
-                }
-            }
-        },
-        "main.PresignedURLRequest": {
-            "type": "object",
-            "required": [
-                "bucket",
-                "content_type",
-                "object_key"
-            ],
-            "properties": {
-                "bucket": {
+                "interval": {
                     "type": "string"
                 },
-                "content_type": {
-                    "type": "string"
+                "is_active": {
+                    "description": "Приостановленная подписка не создает пожертвований; при закрытии сбора останавливается сама",
+                    "type": "boolean"
                 },
-                "expires_in": {
-                    "description": "max 7 days",
-                    "type": "integer",
-                    "maximum": 604800,
-                    "minimum": 1
+                "is_anonymous": {
+                    "type": "boolean"
                 },
-                "object_key": {
-                    "type": "string"
-                }
-            }
-        },
-        "main.PresignedURLResponse": {
-            "type": "object",
-            "properties": {
-                "expires_at": {
-                    "type": "string"
+                "last_donation_id": {
+                    "type": "integer"
                 },
-                "object_url": {
+                "last_run_at": {
                     "type": "string"
                 },
-                "upload_url": {
+                "next_run_at": {
                     "type": "string"
-                }
-            }
-        },
-        "main.RatingWithDetails": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "points": {
-                    "type": "integer"
                 },
-                "position": {
+                "post_id": {
                     "type": "integer"
                 },
-                "status": {
+                "post_title": {
                     "type": "string"
                 },
-                "total_donated": {
-                    "type": "number"
-                },
                 "updated_at": {
                     "type": "string"
-                },
-                "user": {
-                    "$ref": "#/definitions/main.UserInfo"
-                },
-                "user_id": {
-                    "type": "integer"
-                }
-            }
-        },
-        "main.RatingsListResponse": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.RatingWithDetails"
-                    }
-                },
-                "pagination": {
-                    "$ref": "#/definitions/main.PaginationResponse"
                 }
             }
         },
-        "main.RefreshTokenResponse": {
+        "main.SuccessResponse": {
             "type": "object",
             "properties": {
-                "token": {
+                "message": {
                     "type": "string"
                 }
             }
         },
-        "main.RegisterRequest": {
+        "main.TopDonor": {
             "type": "object",
-            "required": [
-                "first_name",
-                "last_name",
-                "password",
-                "phone"
-            ],
             "properties": {
-                "first_name": {
-                    "type": "string"
-                },
-                "last_name": {
-                    "type": "string"
+                "amount": {
+                    "type": "number"
                 },
-                "password": {
-                    "type": "string",
-                    "minLength": 6
+                "count": {
+                    "type": "integer"
                 },
-                "phone": {
-                    "type": "string"
+                "donor": {
+                    "$ref": "#/definitions/main.UserInfo"
                 }
             }
         },
-        "main.RegisterResponse": {
+        "main.UpdateAdminUserRequest": {
             "type": "object",
+            "required": [
+                "is_active"
+            ],
             "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "token": {
-                    "type": "string"
+                "is_active": {
+                    "type": "boolean"
                 },
-                "user_id": {
-                    "type": "integer"
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
                 }
             }
         },
-        "main.SuccessResponse": {
+        "main.UpdateCollaboratorRequest": {
             "type": "object",
+            "required": [
+                "permissions"
+            ],
             "properties": {
-                "message": {
-                    "type": "string"
+                "permissions": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
@@ -2712,7 +12227,8 @@ const docTemplate = `{
                     "type": "string",
                     "enum": [
                         "confirmed",
-                        "rejected"
+                        "rejected",
+                        "refunded"
                     ]
                 }
             }
@@ -2728,6 +12244,21 @@ const docTemplate = `{
                 }
             }
         },
+        "main.UpdateNotificationPreferencesRequest": {
+            "type": "object",
+            "required": [
+                "preferences"
+            ],
+            "properties": {
+                "preferences": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/main.NotificationPreference"
+                    }
+                }
+            }
+        },
         "main.UpdatePostRequest": {
             "type": "object",
             "properties": {
@@ -2737,9 +12268,22 @@ const docTemplate = `{
                 "bank": {
                     "type": "string"
                 },
+                "deadline": {
+                    "description": "Новый срок сбора в RFC 3339, пустая строка убирает срок",
+                    "type": "string",
+                    "example": "2025-03-01T21:00:00Z"
+                },
                 "description": {
                     "type": "string"
                 },
+                "line_items": {
+                    "description": "Новый список статей расходов, пустой список удаляет разбивку",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "$ref": "#/definitions/main.LineItemRequest"
+                    }
+                },
                 "phone": {
                     "type": "string"
                 },
@@ -2762,6 +12306,53 @@ const docTemplate = `{
                 },
                 "last_name": {
                     "type": "string"
+                },
+                "timezone": {
+                    "description": "Часовой пояс IANA, например Europe/Moscow",
+                    "type": "string",
+                    "example": "Europe/Moscow"
+                }
+            }
+        },
+        "main.UpdateSubscriptionRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "interval": {
+                    "type": "string",
+                    "enum": [
+                        "weekly",
+                        "monthly"
+                    ]
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "is_anonymous": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.UpdateUserRiskTierRequest": {
+            "type": "object",
+            "properties": {
+                "tier": {
+                    "type": "string",
+                    "maxLength": 20
+                }
+            }
+        },
+        "main.UpdateUserRoleRequest": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "maxLength": 20
                 }
             }
         },
@@ -2772,7 +12363,8 @@ const docTemplate = `{
             ],
             "properties": {
                 "rejection_reason": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 500
                 },
                 "status": {
                     "type": "string",
@@ -2783,9 +12375,59 @@ const docTemplate = `{
                 }
             }
         },
+        "main.UpdateWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1
+                },
+                "rotate_secret": {
+                    "type": "boolean"
+                },
+                "url": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "main.UpsertIntegrationRequest": {
+            "type": "object",
+            "required": [
+                "target",
+                "type"
+            ],
+            "properties": {
+                "target": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "webhook",
+                        "telegram"
+                    ]
+                }
+            }
+        },
         "main.User": {
             "type": "object",
             "properties": {
+                "away": {
+                    "$ref": "#/definitions/main.AwayStatus"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -2798,6 +12440,13 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "identities": {
+                    "description": "Привязанные VK ID, Яндекс и Google (заполняется только в профиле)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.UserIdentity"
+                    }
+                },
                 "is_active": {
                     "type": "boolean"
                 },
@@ -2807,34 +12456,79 @@ const docTemplate = `{
                 "phone": {
                     "type": "string"
                 },
+                "phone_verified": {
+                    "type": "boolean"
+                },
                 "photo_url": {
                     "type": "string"
                 },
+                "photo_variants": {
+                    "$ref": "#/definitions/main.ImageVariants"
+                },
                 "role": {
                     "type": "string"
                 },
+                "timezone": {
+                    "description": "Часовой пояс IANA (заполняется только в профиле)",
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
                 }
             }
         },
+        "main.UserIdentity": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                }
+            }
+        },
         "main.UserInfo": {
             "type": "object",
             "properties": {
                 "avatar": {
                     "type": "string"
                 },
+                "away": {
+                    "$ref": "#/definitions/main.AwayStatus"
+                },
                 "id": {
                     "type": "integer"
                 },
                 "name": {
                     "type": "string"
+                },
+                "response_time": {
+                    "description": "Время ответа автора поста (если набралось достаточно чатов)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.ResponseTime"
+                        }
+                    ]
                 }
             }
         },
         "main.Verification": {
             "type": "object",
             "properties": {
+                "age": {
+                    "description": "Результат проверки возраста для проверяющего (вычисляется, не хранится)",
+                    "type": "integer"
+                },
                 "birth_date": {
                     "type": "string"
                 },
@@ -2847,9 +12541,22 @@ const docTemplate = `{
                 "consent3": {
                     "type": "boolean"
                 },
+                "country": {
+                    "description": "Страна и удостоверение личности из справочника DocumentCountries. Серия, номер, кем\nи когда выдан хранятся в столбцах passport_*, остальные поля документа - в DocumentData.",
+                    "type": "string"
+                },
                 "doc_type": {
                     "type": "string"
                 },
+                "document_data": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "document_type": {
+                    "type": "string"
+                },
                 "first_name": {
                     "type": "string"
                 },
@@ -2862,9 +12569,40 @@ const docTemplate = `{
                 "last_name": {
                     "type": "string"
                 },
+                "meets_age_requirement": {
+                    "type": "boolean"
+                },
                 "middle_name": {
                     "type": "string"
                 },
+                "organization_check": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "matched",
+                        "mismatch",
+                        "not_found"
+                    ]
+                },
+                "organization_inn": {
+                    "description": "Организация заявителя (ИНН и ОГРН юридического лица) и результат ее сверки с реестром:\nOrganizationCheck - pending, matched, mismatch или not_found, OrganizationMismatches - что\nрасходится с реестром, OrganizationRegistry - сведения реестра на момент сверки",
+                    "type": "string"
+                },
+                "organization_mismatches": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "organization_name": {
+                    "type": "string"
+                },
+                "organization_ogrn": {
+                    "type": "string"
+                },
+                "organization_registry": {
+                    "$ref": "#/definitions/main.RegistryOrganization"
+                },
                 "passport_date": {
                     "type": "string"
                 },
@@ -2909,6 +12647,34 @@ const docTemplate = `{
                 }
             }
         },
+        "main.VerificationDocument": {
+            "type": "object",
+            "properties": {
+                "type": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.VerificationDocumentsResponse": {
+            "type": "object",
+            "properties": {
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.VerificationDocument"
+                    }
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "verification_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "main.VerificationResponse": {
             "type": "object",
             "properties": {
@@ -2951,6 +12717,56 @@ const docTemplate = `{
                     "$ref": "#/definitions/main.PaginationResponse"
                 }
             }
+        },
+        "main.VerifyOTPRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "phone"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.WebhookResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
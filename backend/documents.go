@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Типы полей документа
+const (
+	// Произвольный текст (кем выдан)
+	DocumentFieldText = "text"
+	// Номер: пробелы и дефисы убираются, буквы приводятся к верхнему регистру
+	DocumentFieldCode = "code"
+	// Дата в формате YYYY-MM-DD, не раньше даты рождения и не в будущем
+	DocumentFieldDate = "date"
+)
+
+// DefaultDocumentCountry и DefaultIdentityDocument применяются к заявкам без страны и
+// типа документа: так подавались заявки до появления справочника
+const (
+	DefaultDocumentCountry  = "RU"
+	DefaultIdentityDocument = "ru_passport"
+)
+
+// DocumentField поле документа в форме верификации
+type DocumentField struct {
+	// Имя поля формы и ключ в document_data
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Kind     string `json:"kind"`
+	Required bool   `json:"required"`
+	// Регулярное выражение номера после нормализации, для подсказки в форме
+	Pattern string `json:"pattern,omitempty"`
+
+	// Скомпилированный Pattern, заполняется LoadDocumentCountries
+	pattern *regexp.Regexp
+	// Столбец verifications, в котором хранится значение; пусто - document_data
+	column string
+	// Дополнительная проверка номера, например контрольной суммы
+	check func(string) bool
+}
+
+// DocumentType тип документа
+type DocumentType struct {
+	Code   string          `json:"code"`
+	Name   string          `json:"name"`
+	Fields []DocumentField `json:"fields"`
+	// Сколько сканов страниц нужно приложить
+	MinScans int `json:"min_scans,omitempty"`
+}
+
+// DocumentCountry документы, по которым проходят верификацию граждане страны
+type DocumentCountry struct {
+	// Код страны ISO 3166-1 alpha-2
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// Удостоверение личности, одно на выбор
+	IdentityDocuments []DocumentType `json:"identity_documents"`
+	// Дополнительный документ (doc_type), один на выбор; пустой список - не требуется
+	TaxDocuments []DocumentType `json:"tax_documents,omitempty"`
+}
+
+// DocumentCountries справочник документов верификации. Чтобы принимать документы
+// другой страны, достаточно добавить ее сюда: форма и проверка заявок строятся по
+// справочнику, значения полей без столбца хранятся в document_data.
+var DocumentCountries = []DocumentCountry{
+	{
+		Code: "RU",
+		Name: "Россия",
+		IdentityDocuments: []DocumentType{{
+			Code:     "ru_passport",
+			Name:     "Паспорт гражданина РФ",
+			MinScans: 2,
+			Fields: []DocumentField{
+				{Name: "passport_series", Label: "Серия", Kind: DocumentFieldCode, Required: true, Pattern: `^\d{4}$`, column: "passport_series"},
+				{Name: "passport_number", Label: "Номер", Kind: DocumentFieldCode, Required: true, Pattern: `^\d{6}$`, column: "passport_number"},
+				{Name: "passport_issuer", Label: "Кем выдан", Kind: DocumentFieldText, Required: true, column: "passport_issuer"},
+				{Name: "passport_date", Label: "Дата выдачи", Kind: DocumentFieldDate, Required: true, column: "passport_date"},
+			},
+		}},
+		TaxDocuments: []DocumentType{
+			{
+				Code:   "inn",
+				Name:   "ИНН",
+				Fields: []DocumentField{{Name: "inn", Label: "ИНН", Kind: DocumentFieldCode, Required: true, Pattern: `^\d{12}$`, column: "inn", check: validINN}},
+			},
+			{
+				Code:   "snils",
+				Name:   "СНИЛС",
+				Fields: []DocumentField{{Name: "snils", Label: "СНИЛС", Kind: DocumentFieldCode, Required: true, Pattern: `^\d{11}$`, column: "snils", check: validSNILS}},
+			},
+		},
+	},
+	{
+		Code: "KZ",
+		Name: "Казахстан",
+		IdentityDocuments: []DocumentType{{
+			Code:     "kz_id_card",
+			Name:     "Удостоверение личности гражданина РК",
+			MinScans: 2,
+			Fields: []DocumentField{
+				{Name: "passport_number", Label: "Номер удостоверения", Kind: DocumentFieldCode, Required: true, Pattern: `^\d{9}$`, column: "passport_number"},
+				{Name: "iin", Label: "ИИН", Kind: DocumentFieldCode, Required: true, Pattern: `^\d{12}$`, check: validIIN},
+				{Name: "passport_issuer", Label: "Орган выдачи", Kind: DocumentFieldText, Required: true, column: "passport_issuer"},
+				{Name: "passport_date", Label: "Дата выдачи", Kind: DocumentFieldDate, Required: true, column: "passport_date"},
+			},
+		}},
+	},
+	{
+		Code: "BY",
+		Name: "Беларусь",
+		IdentityDocuments: []DocumentType{{
+			Code:     "by_passport",
+			Name:     "Паспорт гражданина Республики Беларусь",
+			MinScans: 2,
+			Fields: []DocumentField{
+				{Name: "passport_number", Label: "Серия и номер", Kind: DocumentFieldCode, Required: true, Pattern: `^[A-Z]{2}\d{7}$`, column: "passport_number"},
+				{Name: "personal_number", Label: "Идентификационный номер", Kind: DocumentFieldCode, Required: true, Pattern: `^\d{7}[A-Z]\d{3}[A-Z]{2}\d$`},
+				{Name: "passport_issuer", Label: "Кем выдан", Kind: DocumentFieldText, Required: true, column: "passport_issuer"},
+				{Name: "passport_date", Label: "Дата выдачи", Kind: DocumentFieldDate, Required: true, column: "passport_date"},
+			},
+		}},
+	},
+}
+
+// LoadDocumentCountries проверяет справочник и компилирует форматы номеров. Вызывается
+// один раз при запуске: ошибка в справочнике не должна обнаруживаться на заявке.
+func LoadDocumentCountries(countries []DocumentCountry) error {
+	for i := range countries {
+		country := &countries[i]
+		for _, types := range [][]DocumentType{country.IdentityDocuments, country.TaxDocuments} {
+			for j := range types {
+				for k := range types[j].Fields {
+					field := &types[j].Fields[k]
+					if field.Pattern == "" {
+						continue
+					}
+					pattern, err := regexp.Compile(field.Pattern)
+					if err != nil {
+						return fmt.Errorf("document %s/%s field %s: invalid pattern: %w", country.Code, types[j].Code, field.Name, err)
+					}
+					field.pattern = pattern
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// FindDocumentCountry ищет страну в справочнике документов
+func FindDocumentCountry(code string) (*DocumentCountry, bool) {
+	for i := range DocumentCountries {
+		if DocumentCountries[i].Code == code {
+			return &DocumentCountries[i], true
+		}
+	}
+	return nil, false
+}
+
+// findDocumentType ищет документ по коду в списке
+func findDocumentType(types []DocumentType, code string) (*DocumentType, bool) {
+	for i := range types {
+		if types[i].Code == code {
+			return &types[i], true
+		}
+	}
+	return nil, false
+}
+
+// ApplyDocument проверяет поля документа (value возвращает значение поля формы) и
+// записывает их в заявку: в столбцы verifications или в DocumentData
+func (t *DocumentType) ApplyDocument(v *Verification, value func(name string) string) error {
+	for _, field := range t.Fields {
+		raw := strings.TrimSpace(value(field.Name))
+		if field.Kind == DocumentFieldCode {
+			raw = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(raw))
+		}
+		details := map[string]interface{}{"field": field.Name}
+		if raw == "" {
+			if field.Required {
+				return NewValidationError(fmt.Sprintf("Заполните поле «%s»", field.Label), details)
+			}
+			continue
+		}
+		if len([]rune(raw)) > 500 {
+			return NewValidationError(fmt.Sprintf("Поле «%s» слишком длинное", field.Label), details)
+		}
+
+		var date time.Time
+		switch field.Kind {
+		case DocumentFieldCode:
+			if field.Pattern != "" && field.pattern == nil {
+				return NewInternalError("Справочник документов не загружен")
+			}
+			if field.pattern != nil && !field.pattern.MatchString(raw) {
+				return NewValidationError(fmt.Sprintf("Неверный формат поля «%s»", field.Label), details)
+			}
+			if field.check != nil && !field.check(raw) {
+				return NewValidationError(fmt.Sprintf("Неверная контрольная сумма поля «%s»", field.Label), details)
+			}
+		case DocumentFieldDate:
+			var err error
+			date, err = time.Parse("2006-01-02", raw)
+			if err != nil {
+				return NewValidationError(fmt.Sprintf("Неверный формат даты «%s»", field.Label), details)
+			}
+			if date.Before(v.BirthDate) || date.After(time.Now()) {
+				return NewValidationError(fmt.Sprintf("Неверная дата «%s»", field.Label), details)
+			}
+		}
+
+		switch field.column {
+		case "passport_series":
+			v.PassportSeries = raw
+		case "passport_number":
+			v.PassportNumber = raw
+		case "passport_issuer":
+			v.PassportIssuer = raw
+		case "passport_date":
+			v.PassportDate = date
+		case "inn":
+			v.INN = &raw
+		case "snils":
+			v.SNILS = &raw
+		default:
+			if v.DocumentData == nil {
+				v.DocumentData = make(map[string]string)
+			}
+			v.DocumentData[field.Name] = raw
+		}
+	}
+	return nil
+}
+
+// checksumDigits переводит строку из цифр в числа
+func checksumDigits(s string) []int {
+	digits := make([]int, len(s))
+	for i, r := range s {
+		digits[i] = int(r - '0')
+	}
+	return digits
+}
+
+func weightedSum(digits, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += digits[i] * w
+	}
+	return sum
+}
+
+// validINN проверяет контрольные цифры ИНН физического лица (12 цифр)
+func validINN(inn string) bool {
+	d := checksumDigits(inn)
+	n11 := weightedSum(d, []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) % 11 % 10
+	n12 := weightedSum(d, []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) % 11 % 10
+	return d[10] == n11 && d[11] == n12
+}
+
+// validOrgINN проверяет контрольную цифру ИНН юридического лица (10 цифр)
+func validOrgINN(inn string) bool {
+	d := checksumDigits(inn)
+	return d[9] == weightedSum(d, []int{2, 4, 10, 3, 5, 9, 4, 6, 8})%11%10
+}
+
+// validOGRN проверяет контрольную цифру ОГРН (13 цифр): остаток от деления
+// первых 12 цифр на 11, последний разряд остатка
+func validOGRN(ogrn string) bool {
+	d := checksumDigits(ogrn)
+	rest := 0
+	for _, digit := range d[:12] {
+		rest = (rest*10 + digit) % 11
+	}
+	return d[12] == rest%10
+}
+
+// validSNILS проверяет контрольное число СНИЛС (11 цифр)
+func validSNILS(snils string) bool {
+	d := checksumDigits(snils)
+	sum := weightedSum(d, []int{9, 8, 7, 6, 5, 4, 3, 2, 1})
+	control := sum % 101
+	if control == 100 {
+		control = 0
+	}
+	return d[9]*10+d[10] == control
+}
+
+// validIIN проверяет контрольную цифру ИИН Казахстана (12 цифр)
+func validIIN(iin string) bool {
+	d := checksumDigits(iin)
+	control := weightedSum(d, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}) % 11
+	if control == 10 {
+		control = weightedSum(d, []int{3, 4, 5, 6, 7, 8, 9, 10, 11, 1, 2}) % 11
+		if control == 10 {
+			return false
+		}
+	}
+	return d[11] == control
+}
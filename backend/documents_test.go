@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Контрольные суммы номеров документов
+func TestDocumentChecksums(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(string) bool
+		value string
+		want  bool
+	}{
+		{"inn", validINN, "500100732259", true},
+		{"inn wrong 11th digit", validINN, "500100732249", false},
+		{"inn wrong 12th digit", validINN, "500100732258", false},
+		{"snils", validSNILS, "11223344595", true},
+		{"snils wrong control", validSNILS, "11223344596", false},
+		{"snils control 100 is 00", validSNILS, "10001899900", true},
+		{"iin", validIIN, "900101300007", true},
+		{"iin wrong control", validIIN, "900101300008", false},
+		{"iin second weights", validIIN, "900101300811", true},
+		{"iin both controls 10", validIIN, "900101300800", false},
+		{"organization inn", validOrgINN, "7707083893", true},
+		{"organization inn wrong control", validOrgINN, "7707083894", false},
+		{"ogrn", validOGRN, "1027700132195", true},
+		{"ogrn wrong control", validOGRN, "1027700132196", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(tt.value); got != tt.want {
+				t.Errorf("check(%s) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// Поля документа нормализуются, проверяются и записываются в столбцы заявки или document_data
+func TestApplyDocument(t *testing.T) {
+	ru, _ := FindDocumentCountry("RU")
+	passport, _ := findDocumentType(ru.IdentityDocuments, "ru_passport")
+	inn, _ := findDocumentType(ru.TaxDocuments, "inn")
+	kz, _ := FindDocumentCountry("KZ")
+	kzCard, _ := findDocumentType(kz.IdentityDocuments, "kz_id_card")
+
+	validPassport := map[string]string{
+		"passport_series": "45 10",
+		"passport_number": "123-456",
+		"passport_issuer": " ОВД района ",
+		"passport_date":   "2015-06-01",
+	}
+	with := func(form map[string]string, name, value string) map[string]string {
+		changed := map[string]string{name: value}
+		for k, v := range form {
+			if k != name {
+				changed[k] = v
+			}
+		}
+		return changed
+	}
+
+	tests := []struct {
+		name     string
+		document *DocumentType
+		form     map[string]string
+		field    string
+	}{
+		{name: "passport", document: passport, form: validPassport},
+		{name: "missing required", document: passport, form: with(validPassport, "passport_issuer", ""), field: "passport_issuer"},
+		{name: "series format", document: passport, form: with(validPassport, "passport_series", "45A0"), field: "passport_series"},
+		{name: "date format", document: passport, form: with(validPassport, "passport_date", "01.06.2015"), field: "passport_date"},
+		{name: "date before birth", document: passport, form: with(validPassport, "passport_date", "1989-12-31"), field: "passport_date"},
+		{name: "date in future", document: passport, form: with(validPassport, "passport_date", time.Now().AddDate(0, 0, 2).Format("2006-01-02")), field: "passport_date"},
+		{name: "inn", document: inn, form: map[string]string{"inn": "5001 0073 2259"}},
+		{name: "inn checksum", document: inn, form: map[string]string{"inn": "500100732258"}, field: "inn"},
+		{name: "kz id card", document: kzCard, form: with(with(validPassport, "passport_number", "012345678"), "iin", "900101300007")},
+		{name: "iin checksum", document: kzCard, form: with(with(validPassport, "passport_number", "012345678"), "iin", "900101300008"), field: "iin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &Verification{BirthDate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)}
+			err := tt.document.ApplyDocument(v, func(name string) string { return tt.form[name] })
+			if tt.field != "" {
+				var appErr *AppError
+				if !errors.As(err, &appErr) || appErr.Status != http.StatusBadRequest || appErr.Details["field"] != tt.field {
+					t.Fatalf("ApplyDocument = %v, want validation error of %s", err, tt.field)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyDocument: %v", err)
+			}
+		})
+	}
+
+	v := &Verification{BirthDate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := passport.ApplyDocument(v, func(name string) string { return validPassport[name] }); err != nil {
+		t.Fatalf("ApplyDocument: %v", err)
+	}
+	if v.PassportSeries != "4510" || v.PassportNumber != "123456" || v.PassportIssuer != "ОВД района" || v.PassportDate.Format("2006-01-02") != "2015-06-01" {
+		t.Errorf("passport columns = %q %q %q %s", v.PassportSeries, v.PassportNumber, v.PassportIssuer, v.PassportDate)
+	}
+	v = &Verification{BirthDate: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)}
+	kzForm := with(with(validPassport, "passport_number", "012345678"), "iin", "900101300007")
+	if err := kzCard.ApplyDocument(v, func(name string) string { return kzForm[name] }); err != nil {
+		t.Fatalf("ApplyDocument: %v", err)
+	}
+	if v.DocumentData["iin"] != "900101300007" {
+		t.Errorf("document_data = %v, want iin", v.DocumentData)
+	}
+}
+
+// Неверный формат номера в справочнике обнаруживается при загрузке, а не на заявке
+func TestLoadDocumentCountriesInvalidPattern(t *testing.T) {
+	countries := []DocumentCountry{{
+		Code: "XX",
+		IdentityDocuments: []DocumentType{{
+			Code:   "broken",
+			Fields: []DocumentField{{Name: "number", Kind: DocumentFieldCode, Pattern: `^[0-9`}},
+		}},
+	}}
+	if err := LoadDocumentCountries(countries); err == nil {
+		t.Fatal("LoadDocumentCountries accepted invalid pattern")
+	}
+
+	// Справочник, который не загружен, не паникует на заявке
+	document := DocumentType{Fields: []DocumentField{{Name: "number", Label: "Номер", Kind: DocumentFieldCode, Pattern: `^\d+$`}}}
+	err := document.ApplyDocument(&Verification{}, func(string) string { return "1" })
+	var appErr *AppError
+	if !errors.As(err, &appErr) || appErr.Status != http.StatusInternalServerError {
+		t.Errorf("ApplyDocument without LoadDocumentCountries = %v, want internal error", err)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// Возврат пожертвования с незавершенной или успешной оплатой картой отклоняется: деньги,
+// списанные провайдером, система не возвращает. Отмененная оплата возврату не мешает.
+func TestRefundDonationWithCardPayment(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.createUser(RoleAdmin)
+	author := s.createUser("user")
+	donor := s.createUser("user")
+	post := s.createPost(author, "active")
+
+	for status, want := range map[string]int{
+		"new":       http.StatusConflict,
+		"pending":   http.StatusConflict,
+		"succeeded": http.StatusConflict,
+		"canceled":  http.StatusNoContent,
+	} {
+		donation := &Donation{PostID: post.ID, DonorID: donor.ID, Amount: 500}
+		if err := s.db.CreateDonation(donation); err != nil {
+			t.Fatalf("create donation: %v", err)
+		}
+		payment, err := s.db.OpenPayment(donation.ID, "test", donation.Amount)
+		if err != nil {
+			t.Fatalf("open payment: %v", err)
+		}
+		if _, err := s.db.Exec(`UPDATE payments SET status = $1 WHERE id = $2`, status, payment.ID); err != nil {
+			t.Fatalf("set payment status: %v", err)
+		}
+
+		path := fmt.Sprintf("/api/v1/donations/%d", donation.ID)
+		if status != "canceled" {
+			expectStatus(t, s.request(donor, "DELETE", path, nil), "donor, payment "+status, http.StatusConflict)
+		}
+		expectStatus(t, s.request(admin, "DELETE", path, nil), "admin, payment "+status, want)
+	}
+}
+
+// Отклонение подтвержденного и возврат пожертвования списывают реферальный бонус, начисленный
+// за него, у приглашенного и пригласившего. Приглашение снова ждет первого пожертвования.
+func TestRevertDonationRevertsReferralBonus(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.createUser(RoleAdmin)
+	author := s.createUser("user")
+	post := s.createPost(author, "active")
+	const bonus = 100
+
+	for name, revert := range map[string]func(id int64) error{
+		"reject": func(id int64) error {
+			_, err := s.db.RejectDonation(id, admin.ID)
+			return err
+		},
+		"refund": func(id int64) error {
+			_, err := s.db.RefundDonation(id, admin.ID, false)
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			referrer := s.createUser("user")
+			referee := s.createUser("user")
+			if err := s.db.CreateReferral(referrer.ID, referee.ID, nil); err != nil {
+				t.Fatalf("create referral: %v", err)
+			}
+			points := func(u testUser) int {
+				rating, err := s.db.GetOrCreateRating(u.ID)
+				if err != nil {
+					t.Fatalf("get rating: %v", err)
+				}
+				return rating.Points
+			}
+
+			donation := &Donation{PostID: post.ID, DonorID: referee.ID, Amount: 500}
+			if err := s.db.CreateDonation(donation); err != nil {
+				t.Fatalf("create donation: %v", err)
+			}
+			_, referral, err := s.db.ConfirmDonation(donation.ID, admin.ID, bonus, nil)
+			if err != nil || referral == nil {
+				t.Fatalf("ConfirmDonation = %v, %v, want rewarded referral", referral, err)
+			}
+			if got := points(referrer); got != bonus {
+				t.Fatalf("referrer points after confirm = %d, want %d", got, bonus)
+			}
+
+			if err := revert(donation.ID); err != nil {
+				t.Fatalf("revert donation: %v", err)
+			}
+			if got := points(referrer); got != 0 {
+				t.Errorf("referrer points after %s = %d, want 0", name, got)
+			}
+			if got := points(referee); got != 0 {
+				t.Errorf("referee points after %s = %d, want 0", name, got)
+			}
+
+			var status string
+			var reversals int
+			err = s.db.QueryRow(`SELECT status, (SELECT COUNT(*) FROM rating_events WHERE referral_id = r.id AND source = $2)
+			                     FROM referrals r WHERE referee_id = $1`, referee.ID, RatingEventReferralReversal).Scan(&status, &reversals)
+			if err != nil {
+				t.Fatalf("get referral: %v", err)
+			}
+			if status != ReferralPending || reversals != 2 {
+				t.Errorf("referral status = %s with %d reversals, want %s with 2", status, reversals, ReferralPending)
+			}
+		})
+	}
+}
+
+// Если чек не загрузился, пожертвование не создается: повтор запроса не оставляет дубликата
+func TestCreateDonationWithFailedReceiptUpload(t *testing.T) {
+	s := newTestServer(t)
+	author := s.createUser("user")
+	donor := s.createUser("user")
+	post := s.createPost(author, "active")
+
+	donation := &Donation{PostID: post.ID, DonorID: donor.ID, Amount: 500}
+	err := s.db.CreateDonationWithReceipt(donation, func(int64) (string, error) {
+		return "", NewInternalError("Ошибка загрузки чека")
+	})
+	if err == nil {
+		t.Fatal("CreateDonationWithReceipt succeeded with failed upload")
+	}
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM donations WHERE donor_id = $1`, donor.ID).Scan(&count); err != nil {
+		t.Fatalf("count donations: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("donations after failed upload = %d, want 0", count)
+	}
+
+	donation = &Donation{PostID: post.ID, DonorID: donor.ID, Amount: 500}
+	err = s.db.CreateDonationWithReceipt(donation, func(id int64) (string, error) {
+		return fmt.Sprintf("http://localhost/donation-receipts/donations/%d/receipt.png", id), nil
+	})
+	if err != nil {
+		t.Fatalf("CreateDonationWithReceipt: %v", err)
+	}
+	stored, err := s.db.GetDonationByID(donation.ID)
+	if err != nil {
+		t.Fatalf("get donation: %v", err)
+	}
+	if stored.ReceiptURL == nil || *stored.ReceiptURL != *donation.ReceiptURL {
+		t.Errorf("receipt_url = %v, want %s", stored.ReceiptURL, *donation.ReceiptURL)
+	}
+}
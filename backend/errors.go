@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -17,6 +18,8 @@ const (
 	ErrCodeTooLarge         = "FILE_TOO_LARGE"
 	ErrCodeUnsupportedMedia = "UNSUPPORTED_MEDIA_TYPE"
 	ErrCodeUnprocessable    = "UNPROCESSABLE_ENTITY"
+	ErrCodeTooManyRequests  = "TOO_MANY_REQUESTS"
+	ErrCodeUnavailable      = "SERVICE_UNAVAILABLE"
 	ErrCodeInternal         = "INTERNAL_ERROR"
 )
 
@@ -111,6 +114,24 @@ func NewUnprocessableError(message string) *AppError {
 	}
 }
 
+// NewTooManyRequestsError создает ошибку превышения лимита запросов
+func NewTooManyRequestsError(message string) *AppError {
+	return &AppError{
+		Code:    ErrCodeTooManyRequests,
+		Message: message,
+		Status:  http.StatusTooManyRequests,
+	}
+}
+
+// NewServiceUnavailableError создает ошибку временной недоступности сервиса
+func NewServiceUnavailableError(message string) *AppError {
+	return &AppError{
+		Code:    ErrCodeUnavailable,
+		Message: message,
+		Status:  http.StatusServiceUnavailable,
+	}
+}
+
 // NewInternalError создает внутреннюю ошибку
 func NewInternalError(message string) *AppError {
 	return &AppError{
@@ -131,6 +152,8 @@ func WriteError(w http.ResponseWriter, err error) {
 		// Проверяем стандартные ошибки БД
 		if e == sql.ErrNoRows {
 			appErr = NewNotFoundError("Ресурс")
+		} else if errors.Is(e, ErrCircuitOpen) {
+			appErr = NewServiceUnavailableError("Сервис временно недоступен, повторите попытку позже")
 		} else {
 			appErr = NewInternalError("Внутренняя ошибка сервера")
 		}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// sanitizedJPEGQuality качество JPEG при пережатии загруженных фото
+const sanitizedJPEGQuality = 90
+
+// maxImagePixels изображения большего размера не декодируются, чтобы не исчерпать память
+const maxImagePixels = 50_000_000
+
+// SanitizeImage декодирует JPEG, PNG или WebP и кодирует заново, отбрасывая
+// EXIF и прочие метаданные (в том числе GPS координаты). Поворот из EXIF
+// применяется к пикселям, чтобы фото не легло на бок. WebP сохраняется как JPEG,
+// а с прозрачностью как PNG. Возвращает новое содержимое и его Content-Type.
+func SanitizeImage(data []byte) ([]byte, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image config: %w", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, "", fmt.Errorf("image is too large: %dx%d", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		img = orientImage(img, jpegOrientation(data))
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: sanitizedJPEGQuality})
+	case "png":
+		err = png.Encode(&buf, img)
+	case "webp":
+		if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+			format = "png"
+			err = png.Encode(&buf, img)
+		} else {
+			format = "jpeg"
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: sanitizedJPEGQuality})
+		}
+	default:
+		return nil, "", fmt.Errorf("unsupported image format %q", format)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), "image/" + format, nil
+}
+
+// jpegOrientation читает тег Orientation (0x0112) из EXIF блока JPEG.
+// Без EXIF или при ошибке разбора возвращает 1 (без поворота).
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+
+	// Проходим сегменты до начала данных изображения
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xff {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xda || marker == 0xd9 { // SOS, EOI
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation ищет тег Orientation в первом IFD TIFF заголовка EXIF
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orientImage поворачивает и отражает изображение согласно EXIF Orientation
+func orientImage(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Ориентации 5-8 меняют ширину и высоту местами
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // отражение по горизонтали
+				sx, sy = w-1-x, y
+			case 3: // поворот на 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // отражение по вертикали
+				sx, sy = x, h-1-y
+			case 5: // отражение относительно главной диагонали
+				sx, sy = y, x
+			case 6: // поворот на 90 по часовой
+				sx, sy = y, h-1-x
+			case 7: // отражение относительно побочной диагонали
+				sx, sy = w-1-y, h-1-x
+			case 8: // поворот на 90 против часовой
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
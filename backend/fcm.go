@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"tmphackbackend/httpclient"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// errFCMTokenInvalid токен устройства больше не принимается FCM
+var errFCMTokenInvalid = errors.New("FCM token is no longer valid")
+
+// fcmServiceAccount поля JSON ключа сервисного аккаунта Firebase
+type fcmServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	PrivateKey  string `json:"private_key"`
+	ClientEmail string `json:"client_email"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMSender отправляет push уведомления на устройства пользователей через
+// FCM HTTP v1 API. Подключается к Notifier как внешний канал доставки.
+type FCMSender struct {
+	db      *DB
+	account fcmServiceAccount
+	client  *httpclient.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMSender создает отправителя по ключу сервисного аккаунта
+func NewFCMSender(db *DB, credentialsFile string, client *httpclient.Client) (*FCMSender, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+
+	var account fcmServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse FCM credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.PrivateKey == "" || account.ClientEmail == "" {
+		return nil, fmt.Errorf("FCM credentials must contain project_id, private_key and client_email")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	return &FCMSender{
+		db:      db,
+		account: account,
+		client:  client,
+	}, nil
+}
+
+// SendBatch отправляет уведомления на все устройства получателей, кроме выключивших
+// push для типа уведомления. Токены, которые FCM больше не принимает, удаляются.
+func (s *FCMSender) SendBatch(ctx context.Context, notifications []Notification) error {
+	notifications, err := s.db.FilterNotificationChannel(NotificationChannelPush, notifications)
+	if err != nil {
+		return err
+	}
+	userIDs := make([]int64, 0, len(notifications))
+	for _, n := range notifications {
+		userIDs = append(userIDs, n.UserID)
+	}
+
+	tokens, err := s.db.GetDeviceTokens(userIDs)
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	var sendErr error
+	for _, n := range notifications {
+		for _, token := range tokens[n.UserID] {
+			result := "ok"
+			err := s.send(ctx, token, n)
+			switch {
+			case errors.Is(err, errFCMTokenInvalid):
+				stale = append(stale, token)
+				result = "invalid_token"
+			case err != nil:
+				sendErr = err
+				result = "error"
+			}
+			metrics.Inc("push_sent_total", "Push notifications sent via FCM", map[string]string{"result": result})
+		}
+	}
+
+	if err := s.db.DeleteDeviceTokens(stale); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+func (s *FCMSender) send(ctx context.Context, token string, n Notification) error {
+	data := map[string]string{"type": n.Type}
+	if n.PostID != nil {
+		data["post_id"] = strconv.FormatInt(*n.PostID, 10)
+	}
+	for k, v := range n.Data {
+		data[k] = v
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": token,
+			"notification": map[string]string{
+				"title": n.Title,
+				"body":  n.Body,
+			},
+			"data":    data,
+			"android": map[string]string{"priority": "high"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", s.account.ProjectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create FCM request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	// Приложение удалено или токен устарел
+	if resp.StatusCode == http.StatusNotFound || result.Error.Status == "UNREGISTERED" {
+		return errFCMTokenInvalid
+	}
+	if resp.StatusCode == http.StatusBadRequest && strings.Contains(result.Error.Message, "registration token") {
+		return errFCMTokenInvalid
+	}
+	return fmt.Errorf("FCM error %d: %s", resp.StatusCode, result.Error.Message)
+}
+
+// token возвращает OAuth2 токен доступа, обновляя его за минуту до истечения
+func (s *FCMSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Now().Add(time.Minute).Before(s.expiresAt) {
+		return s.accessToken, nil
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(s.account.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse FCM private key: %w", err)
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   s.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign FCM assertion: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get FCM access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode FCM token response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("failed to get FCM access token: %s", result.Error)
+	}
+
+	s.accessToken = result.AccessToken
+	s.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
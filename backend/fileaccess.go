@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// publicBuckets файлы этих bucket отдаются через /files без авторизации
+var publicBuckets = map[string]bool{
+	BucketUserPhotos: true,
+	BucketPostMedia:  true,
+}
+
+// presignedUploadTypes bucket, в которые можно загружать файлы напрямую по presigned URL,
+// и допустимые типы содержимого. Документы, чеки и вложения чатов загружаются только
+// через API, где они проверяются антивирусом.
+var presignedUploadTypes = map[string][]string{
+	BucketUserPhotos: {"image/jpeg", "image/png", "image/webp"},
+	BucketPostMedia:  {"image/jpeg", "image/png", "image/webp", "video/mp4", "video/webm"},
+}
+
+// presignedUploadKey проверяет bucket и тип содержимого и возвращает ключ объекта
+// для прямой загрузки: uploads/{user_id}/{имя файла}, расширение соответствует типу.
+// Каталоги из запрошенного ключа отбрасываются.
+func presignedUploadKey(userID int64, bucket, objectKey, contentType string) (string, error) {
+	allowedTypes, ok := presignedUploadTypes[bucket]
+	if !ok {
+		return "", NewForbiddenError(fmt.Sprintf("Прямая загрузка в bucket '%s' запрещена", bucket))
+	}
+
+	allowed := false
+	for _, allowedType := range allowedTypes {
+		if contentType == allowedType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", NewValidationError("Недопустимый тип файла для bucket", map[string]interface{}{
+			"field":         "content_type",
+			"allowed_types": allowedTypes,
+		})
+	}
+
+	name := path.Base(path.Clean("/" + objectKey))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if name == "" || name == "/" {
+		return "", NewValidationError("Неверный ключ объекта", map[string]interface{}{"field": "object_key"})
+	}
+	return fmt.Sprintf("uploads/%d/%s%s", userID, name, getExtensionFromContentType(contentType)), nil
+}
+
+// authorizeFileRequest проверяет доступ к файлу в запросе к /files. Маршрут публичный,
+// поэтому JWT разбирается только для файлов закрытых bucket.
+func (h *Handlers) authorizeFileRequest(r *http.Request, bucket, objectKey string) error {
+	if publicBuckets[bucket] {
+		return nil
+	}
+
+	claims, err := OptionalClaims(h.cfg, h.db, r)
+	if err != nil {
+		return err
+	}
+	if claims == nil {
+		return h.authorizeFileAccess(0, "", bucket, objectKey)
+	}
+	return h.authorizeFileAccess(claims.UserID, claims.Role, bucket, objectKey)
+}
+
+// authorizeFileAccess проверяет, что пользователь может читать файл bucket/objectKey:
+//   - фото профилей и медиа постов доступны всем;
+//   - документы верификации - владельцу и праву verifications.documents, документы поста (законного
+//     представителя, срочного сбора) - автору поста и модераторам;
+//   - вложения чатов - участникам чата и соавторам поста с правом chats;
+//   - чеки - автору пожертвования, автору поста и соавторам с правом donations.
+//
+// Файлы остальных bucket (архив, карантин) не отдаются. Нулевой userID - анонимный запрос.
+func (h *Handlers) authorizeFileAccess(userID int64, role, bucket, objectKey string) error {
+	if publicBuckets[bucket] {
+		return nil
+	}
+	if bucket != BucketVerificationDocs && bucket != BucketChatAttachments && bucket != BucketDonationReceipts {
+		return NewNotFoundError("Bucket")
+	}
+	if userID == 0 {
+		return NewUnauthorizedError("Файл доступен только после авторизации")
+	}
+
+	// Ключи закрытых bucket начинаются с {тип}/{id}/, см. Upload* в minio.go
+	parts := strings.SplitN(objectKey, "/", 3)
+	if len(parts) < 3 {
+		return NewForbiddenError("Нет доступа к файлу")
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return NewForbiddenError("Нет доступа к файлу")
+	}
+
+	allowed := false
+	switch {
+	case bucket == BucketVerificationDocs && parts[0] == "verifications":
+		verification, err := h.db.GetVerificationByID(id)
+		if err != nil {
+			return err
+		}
+		allowed = verification.UserID == userID || h.perms.Has(role, PermVerificationDocumentsView)
+	case bucket == BucketVerificationDocs && parts[0] == "posts":
+		post, err := h.db.GetPostByID(id)
+		if err != nil {
+			return err
+		}
+		allowed = post.UserID == userID || h.perms.Has(role, PermPostsModerate)
+	case bucket == BucketChatAttachments && parts[0] == "chats":
+		chat, err := h.db.GetChatByID(id)
+		if err != nil {
+			return err
+		}
+		allowed = chat.HelperID == userID || chat.NeedyID == userID
+		if !allowed {
+			// Соавтор с правом chats ведет чаты поста, как в chatParticipant
+			allowed, err = h.hasCollaboratorPermission(chat.PostID, userID, CollaboratorChats)
+			if err != nil {
+				return err
+			}
+		}
+	case bucket == BucketDonationReceipts && parts[0] == "donations":
+		donation, err := h.db.GetDonationByID(id)
+		if err != nil {
+			return err
+		}
+		allowed = donation.DonorID == userID
+		if !allowed {
+			post, err := h.db.GetPostByID(donation.PostID)
+			if err != nil {
+				return err
+			}
+			allowed = post.UserID == userID
+		}
+		if !allowed {
+			allowed, err = h.hasCollaboratorPermission(donation.PostID, userID, CollaboratorDonations)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !allowed {
+		return NewForbiddenError("Нет доступа к файлу")
+	}
+	return nil
+}
+
+// hasCollaboratorPermission проверяет, что пользователь - принявший приглашение соавтор поста с правом permission
+func (h *Handlers) hasCollaboratorPermission(postID, userID int64, permission string) (bool, error) {
+	permissions, err := h.db.GetCollaboratorPermissions(postID, userID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(permissions, permission), nil
+}
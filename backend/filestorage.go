@@ -0,0 +1,748 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileStorage хранит объекты в каталоге и отдает их по подмножеству S3 API, которым
+// пользуется minio-go: buckets, загрузка (в том числе по частям и по presigned URL),
+// чтение, удаление и список объектов. Используется вместо MinIO в демонстрационном
+// режиме, поэтому код работы с хранилищем не зависит от режима.
+//
+// Запросы принимаются только с подписью AWS Signature V4 ключами MINIO_ACCESS_KEY_ID и
+// MINIO_SECRET_ACCESS_KEY: в заголовке Authorization или в presigned URL. Правила хранения и
+// блокировка объектов принимаются, но не применяются. Не предназначено для эксплуатации.
+type FileStorage struct {
+	dir       string
+	accessKey string
+	secretKey string
+}
+
+// fileObjectMeta метаданные объекта, хранятся рядом с данными в каталоге .meta
+type fileObjectMeta struct {
+	ContentType  string            `json:"content_type"`
+	ETag         string            `json:"etag"`
+	UserMetadata map[string]string `json:"user_metadata,omitempty"`
+}
+
+const (
+	fileStorageMetaDir    = ".meta"
+	fileStorageUploadsDir = ".uploads"
+	fileStorageMaxKeys    = 1000
+)
+
+func NewFileStorage(dir, accessKey, secretKey string) (*FileStorage, error) {
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("file storage requires MINIO_ACCESS_KEY_ID and MINIO_SECRET_ACCESS_KEY")
+	}
+	for _, sub := range []string{fileStorageMetaDir, fileStorageUploadsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+	return &FileStorage{dir: dir, accessKey: accessKey, secretKey: secretKey}, nil
+}
+
+func (s *FileStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if code, err := s.verifySignature(r, time.Now()); err != nil {
+		writeS3Error(w, http.StatusForbidden, code, err.Error(), r.URL.Path)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !validStorageName(bucket) || (key != "" && !validStorageKey(key)) {
+		writeS3Error(w, http.StatusBadRequest, "InvalidArgument", "Invalid bucket or object name", r.URL.Path)
+		return
+	}
+	query := r.URL.Query()
+
+	if key == "" {
+		switch {
+		case r.Method == http.MethodHead:
+			s.headBucket(w, r, bucket)
+		case r.Method == http.MethodPut && (query.Has("lifecycle") || query.Has("object-lock")):
+			// Настройки принимаются, чтобы запуск не отличался от MinIO
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPut:
+			s.makeBucket(w, r, bucket)
+		case r.Method == http.MethodGet && query.Has("location"):
+			writeS3XML(w, http.StatusOK, struct {
+				XMLName xml.Name `xml:"LocationConstraint"`
+			}{})
+		case r.Method == http.MethodGet && query.Has("object-lock"):
+			writeS3Error(w, http.StatusNotFound, "ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket", r.URL.Path)
+		case r.Method == http.MethodGet:
+			s.listObjects(w, r, bucket)
+		default:
+			writeS3Error(w, http.StatusNotImplemented, "NotImplemented", "Operation is not supported by filesystem storage", r.URL.Path)
+		}
+		return
+	}
+
+	if !s.bucketExists(bucket) {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist", r.URL.Path)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.createMultipartUpload(w, r, bucket, key)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		s.uploadPart(w, r, query.Get("uploadId"), query.Get("partNumber"))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.completeMultipartUpload(w, r, bucket, key, query.Get("uploadId"))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		s.abortMultipartUpload(w, query.Get("uploadId"))
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, bucket, key)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		s.getObject(w, r, bucket, key)
+	case r.Method == http.MethodDelete:
+		s.deleteObject(w, r, bucket, key)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", "Operation is not supported by filesystem storage", r.URL.Path)
+	}
+}
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	// Допустимое расхождение часов для подписи в заголовке
+	sigV4MaxSkew = 15 * time.Minute
+	// Наибольший срок presigned URL в S3
+	sigV4MaxExpires = 7 * 24 * time.Hour
+)
+
+// verifySignature проверяет подпись AWS Signature V4 запроса: в заголовке Authorization
+// или в параметрах presigned URL. Возвращает код ошибки S3 и причину. Тело запроса не
+// хешируется: X-Amz-Content-Sha256 входит в подпись как есть, фрагменты aws-chunked
+// не проверяются.
+func (s *FileStorage) verifySignature(r *http.Request, now time.Time) (string, error) {
+	query := r.URL.Query()
+	var credential, signedHeaders, signature, amzDate, payloadHash string
+	presigned := query.Has("X-Amz-Signature")
+
+	if presigned {
+		if query.Get("X-Amz-Algorithm") != sigV4Algorithm {
+			return "AccessDenied", errors.New("Unsupported signature algorithm")
+		}
+		credential, signedHeaders = query.Get("X-Amz-Credential"), query.Get("X-Amz-SignedHeaders")
+		signature, amzDate = query.Get("X-Amz-Signature"), query.Get("X-Amz-Date")
+		payloadHash = "UNSIGNED-PAYLOAD"
+		query.Del("X-Amz-Signature")
+	} else {
+		auth := r.Header.Get("Authorization")
+		if auth == "" {
+			return "AccessDenied", errors.New("Access Denied")
+		}
+		fields, ok := strings.CutPrefix(auth, sigV4Algorithm+" ")
+		if !ok {
+			return "AccessDenied", errors.New("Unsupported signature algorithm")
+		}
+		for _, field := range strings.Split(fields, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		amzDate, payloadHash = r.Header.Get("X-Amz-Date"), r.Header.Get("X-Amz-Content-Sha256")
+		if payloadHash == "" {
+			payloadHash = "UNSIGNED-PAYLOAD"
+		}
+	}
+
+	// Credential: {ключ}/{дата}/{регион}/s3/aws4_request
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[3] != "s3" || parts[4] != "aws4_request" {
+		return "AuthorizationQueryParametersError", errors.New("Malformed credential")
+	}
+	if parts[0] != s.accessKey {
+		return "InvalidAccessKeyId", errors.New("The access key ID you provided does not exist in our records")
+	}
+	signedAt, err := time.Parse(sigV4TimeFormat, amzDate)
+	if err != nil || signedAt.Format("20060102") != parts[1] {
+		return "AccessDenied", errors.New("Malformed request date")
+	}
+	if presigned {
+		expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || expires < 1 || time.Duration(expires)*time.Second > sigV4MaxExpires {
+			return "AuthorizationQueryParametersError", errors.New("X-Amz-Expires must be between 1 and 604800 seconds")
+		}
+		if now.After(signedAt.Add(time.Duration(expires) * time.Second)) {
+			return "AccessDenied", errors.New("Request has expired")
+		}
+		if signedAt.After(now.Add(sigV4MaxSkew)) {
+			return "RequestTimeTooSkewed", errors.New("The difference between the request time and the server's time is too large")
+		}
+	} else if signedAt.Before(now.Add(-sigV4MaxSkew)) || signedAt.After(now.Add(sigV4MaxSkew)) {
+		return "RequestTimeTooSkewed", errors.New("The difference between the request time and the server's time is too large")
+	}
+
+	headers := strings.Split(signedHeaders, ";")
+	if !slices.Contains(headers, "host") {
+		return "AccessDenied", errors.New("Host header must be signed")
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range headers {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = strconv.FormatInt(r.ContentLength, 10)
+		default:
+			values := r.Header.Values(name)
+			for i, v := range values {
+				values[i] = strings.Join(strings.Fields(v), " ")
+			}
+			value = strings.Join(values, ",")
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		encodeSigV4Path(r.URL.Path),
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, strings.Join(parts[1:], "/"), hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range append(parts[1:], stringToSign) {
+		key = hmacSHA256(key, part)
+	}
+	if !hmac.Equal([]byte(hex.EncodeToString(key)), []byte(signature)) {
+		return "SignatureDoesNotMatch", errors.New("The request signature we calculated does not match the signature you provided")
+	}
+	return "", nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// encodeSigV4Path кодирует путь для подписи, как клиент MinIO: кроме букв, цифр и
+// -_.~/ все байты заменяются на %XX
+func encodeSigV4Path(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// validStorageName проверяет имя bucket: служебные каталоги начинаются с точки
+func validStorageName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
+}
+
+// validStorageKey не пропускает ключи, выходящие за каталог bucket
+func validStorageKey(key string) bool {
+	return !strings.HasSuffix(key, "/") && !strings.Contains(key, `\`) && path.Clean("/"+key) == "/"+key
+}
+
+func (s *FileStorage) bucketExists(bucket string) bool {
+	info, err := os.Stat(filepath.Join(s.dir, bucket))
+	return err == nil && info.IsDir()
+}
+
+func (s *FileStorage) objectPath(bucket, key string) string {
+	return filepath.Join(s.dir, bucket, filepath.FromSlash(key))
+}
+
+func (s *FileStorage) metaPath(bucket, key string) string {
+	return filepath.Join(s.dir, fileStorageMetaDir, bucket, filepath.FromSlash(key)+".json")
+}
+
+func (s *FileStorage) headBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	if !s.bucketExists(bucket) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *FileStorage) makeBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	if s.bucketExists(bucket) {
+		writeS3Error(w, http.StatusConflict, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded", r.URL.Path)
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, bucket), 0o755); err != nil {
+		writeS3InternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *FileStorage) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	meta := fileObjectMeta{ContentType: r.Header.Get("Content-Type"), UserMetadata: userMetadataFromHeader(r.Header)}
+	etag, err := s.writeObject(bucket, key, requestPayload(r), meta)
+	if err != nil {
+		writeS3InternalError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", `"`+etag+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeObject записывает данные во временный файл и переименовывает его, чтобы
+// читатели не видели объект частично записанным. Возвращает ETag (MD5 содержимого).
+func (s *FileStorage) writeObject(bucket, key string, body io.Reader, meta fileObjectMeta) (string, error) {
+	target := s.objectPath(bucket, key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), body); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	meta.ETag = hex.EncodeToString(hash.Sum(nil))
+	if meta.ContentType == "" {
+		meta.ContentType = "application/octet-stream"
+	}
+
+	if err := writeJSONFile(s.metaPath(bucket, key), meta); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", err
+	}
+	return meta.ETag, nil
+}
+
+func (s *FileStorage) readMeta(bucket, key string) fileObjectMeta {
+	meta := fileObjectMeta{ContentType: "application/octet-stream"}
+	if data, err := os.ReadFile(s.metaPath(bucket, key)); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+func (s *FileStorage) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	file, err := os.Open(s.objectPath(bucket, key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist", r.URL.Path)
+			return
+		}
+		writeS3InternalError(w, r, err)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist", r.URL.Path)
+		return
+	}
+
+	meta := s.readMeta(bucket, key)
+	w.Header().Set("Content-Type", meta.ContentType)
+	if meta.ETag != "" {
+		w.Header().Set("ETag", `"`+meta.ETag+`"`)
+	}
+	for name, value := range meta.UserMetadata {
+		w.Header().Set("X-Amz-Meta-"+name, value)
+	}
+	// ServeContent обрабатывает HEAD, Range и условные заголовки
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+func (s *FileStorage) deleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	// Удаление отсутствующего объекта в S3 не считается ошибкой
+	for _, name := range []string{s.objectPath(bucket, key), s.metaPath(bucket, key)} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			writeS3InternalError(w, r, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type s3ListObject struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type s3CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type s3ListBucketResult struct {
+	XMLName               xml.Name         `xml:"ListBucketResult"`
+	Name                  string           `xml:"Name"`
+	Prefix                string           `xml:"Prefix"`
+	Delimiter             string           `xml:"Delimiter,omitempty"`
+	MaxKeys               int              `xml:"MaxKeys"`
+	KeyCount              int              `xml:"KeyCount"`
+	IsTruncated           bool             `xml:"IsTruncated"`
+	ContinuationToken     string           `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string           `xml:"NextContinuationToken,omitempty"`
+	Contents              []s3ListObject   `xml:"Contents"`
+	CommonPrefixes        []s3CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// listObjects отвечает на ListObjectsV2. Продолжение списка - последний выданный ключ.
+func (s *FileStorage) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	if !s.bucketExists(bucket) {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist", r.URL.Path)
+		return
+	}
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	after := query.Get("continuation-token")
+	if after == "" {
+		after = query.Get("start-after")
+	}
+	maxKeys := fileStorageMaxKeys
+	if n, err := strconv.Atoi(query.Get("max-keys")); err == nil && n > 0 && n < maxKeys {
+		maxKeys = n
+	}
+
+	root := filepath.Join(s.dir, bucket)
+	var keys []string
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		writeS3InternalError(w, r, err)
+		return
+	}
+	sort.Strings(keys)
+
+	result := s3ListBucketResult{Name: bucket, Prefix: prefix, Delimiter: delimiter, MaxKeys: maxKeys, ContinuationToken: query.Get("continuation-token")}
+	var last string
+	for _, key := range keys {
+		entry := key
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				entry = key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if entry <= after || entry == last {
+			continue
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = last
+			break
+		}
+		last = entry
+		result.KeyCount++
+
+		if entry != key {
+			result.CommonPrefixes = append(result.CommonPrefixes, s3CommonPrefix{Prefix: entry})
+			continue
+		}
+		info, err := os.Stat(s.objectPath(bucket, key))
+		if err != nil {
+			continue
+		}
+		result.Contents = append(result.Contents, s3ListObject{
+			Key:          key,
+			LastModified: info.ModTime().UTC().Format("2006-01-02T15:04:05.000Z"),
+			ETag:         `"` + s.readMeta(bucket, key).ETag + `"`,
+			Size:         info.Size(),
+			StorageClass: "STANDARD",
+		})
+	}
+	writeS3XML(w, http.StatusOK, result)
+}
+
+// ========== Multipart upload ==========
+// minio-go загружает по частям объекты больше 16 МБ. Части хранятся в
+// .uploads/{uploadId} и склеиваются при завершении загрузки.
+
+func (s *FileStorage) uploadDir(uploadID string) (string, bool) {
+	if uploadID == "" || !validStorageName(uploadID) {
+		return "", false
+	}
+	dir := filepath.Join(s.dir, fileStorageUploadsDir, uploadID)
+	info, err := os.Stat(dir)
+	return dir, err == nil && info.IsDir()
+}
+
+func (s *FileStorage) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		writeS3InternalError(w, r, err)
+		return
+	}
+	uploadID := hex.EncodeToString(id)
+	meta := fileObjectMeta{ContentType: r.Header.Get("Content-Type"), UserMetadata: userMetadataFromHeader(r.Header)}
+	if err := writeJSONFile(filepath.Join(s.dir, fileStorageUploadsDir, uploadID, "meta.json"), meta); err != nil {
+		writeS3InternalError(w, r, err)
+		return
+	}
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}{Bucket: bucket, Key: key, UploadID: uploadID})
+}
+
+func (s *FileStorage) uploadPart(w http.ResponseWriter, r *http.Request, uploadID, partNumber string) {
+	dir, ok := s.uploadDir(uploadID)
+	number, err := strconv.Atoi(partNumber)
+	if !ok || err != nil || number < 1 {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist", r.URL.Path)
+		return
+	}
+	file, err := os.Create(filepath.Join(dir, fmt.Sprintf("part-%05d", number)))
+	if err != nil {
+		writeS3InternalError(w, r, err)
+		return
+	}
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), requestPayload(r))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		writeS3InternalError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", `"`+hex.EncodeToString(hash.Sum(nil))+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *FileStorage) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) {
+	dir, ok := s.uploadDir(uploadID)
+	if !ok {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist", r.URL.Path)
+		return
+	}
+	var complete struct {
+		Parts []struct {
+			PartNumber int `xml:"PartNumber"`
+		} `xml:"Part"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil {
+		writeS3Error(w, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed", r.URL.Path)
+		return
+	}
+
+	var meta fileObjectMeta
+	if data, err := os.ReadFile(filepath.Join(dir, "meta.json")); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	var readers []io.Reader
+	for _, part := range complete.Parts {
+		file, err := os.Open(filepath.Join(dir, fmt.Sprintf("part-%05d", part.PartNumber)))
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found", r.URL.Path)
+			return
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+
+	etag, err := s.writeObject(bucket, key, io.MultiReader(readers...), meta)
+	if err != nil {
+		writeS3InternalError(w, r, err)
+		return
+	}
+	os.RemoveAll(dir)
+	writeS3XML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string   `xml:"Bucket"`
+		Key     string   `xml:"Key"`
+		ETag    string   `xml:"ETag"`
+	}{Bucket: bucket, Key: key, ETag: `"` + etag + `"`})
+}
+
+func (s *FileStorage) abortMultipartUpload(w http.ResponseWriter, uploadID string) {
+	if dir, ok := s.uploadDir(uploadID); ok {
+		os.RemoveAll(dir)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ========== Helpers ==========
+
+// requestPayload возвращает тело загрузки. По HTTP minio-go передает его в формате
+// aws-chunked (подпись каждого фрагмента), по presigned URL - как есть.
+func requestPayload(r *http.Request) io.Reader {
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return &awsChunkedReader{r: bufio.NewReader(r.Body)}
+	}
+	return r.Body
+}
+
+// awsChunkedReader декодирует тело aws-chunked: фрагменты "размер;подпись\r\nданные\r\n",
+// последний нулевого размера. Подписи фрагментов не проверяются.
+type awsChunkedReader struct {
+	r         *bufio.Reader
+	remaining int64
+	done      bool
+}
+
+func (c *awsChunkedReader) Read(p []byte) (int, error) {
+	for c.remaining == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return 0, fmt.Errorf("invalid aws-chunked body: %w", err)
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid aws-chunked size %q", sizeHex)
+		}
+		if size == 0 {
+			c.done = true
+			continue
+		}
+		c.remaining = size
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining == 0 && err == nil {
+		// Перевод строки после данных фрагмента
+		if _, err := c.r.Discard(2); err != nil {
+			return n, err
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func userMetadataFromHeader(header http.Header) map[string]string {
+	metadata := map[string]string{}
+	for name, values := range header {
+		if rest, ok := strings.CutPrefix(name, "X-Amz-Meta-"); ok && len(values) > 0 {
+			metadata[rest] = values[0]
+		}
+	}
+	return metadata
+}
+
+func writeJSONFile(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
+
+func writeS3XML(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// writeS3Error отвечает ошибкой в формате S3, minio-go разбирает из нее код
+func writeS3Error(w http.ResponseWriter, status int, code, message, resource string) {
+	writeS3XML(w, status, struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string   `xml:"Code"`
+		Message  string   `xml:"Message"`
+		Resource string   `xml:"Resource"`
+	}{Code: code, Message: message, Resource: resource})
+}
+
+func writeS3InternalError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("File storage: %s %s: %v", r.Method, r.URL.Path, err)
+	writeS3Error(w, http.StatusInternalServerError, "InternalError", "We encountered an internal error. Please try again.", r.URL.Path)
+}
+
+// StartFileStorage открывает каталог хранилища и начинает отдавать его по адресу
+// addr (MINIO_ENDPOINT): по нему же к хранилищу обращаются клиент MinIO и presigned URL.
+func StartFileStorage(dir, addr, accessKey, secretKey string) (*http.Server, error) {
+	storage, err := NewFileStorage(dir, accessKey, secretKey)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Addr: addr, Handler: storage, ReadHeaderTimeout: 10 * time.Second}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("File storage stopped: %v", err)
+		}
+	}()
+	return srv, nil
+}
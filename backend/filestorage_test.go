@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Файловое хранилище принимает запросы клиента MinIO и presigned URL с подписью
+// ключами хранилища и отклоняет запросы без подписи, с чужим ключом или измененные
+func TestFileStorageSignatures(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir(), "storage-key", "storage-secret")
+	if err != nil {
+		t.Fatalf("NewFileStorage: %v", err)
+	}
+	srv := httptest.NewServer(storage)
+	defer srv.Close()
+	endpoint := strings.TrimPrefix(srv.URL, "http://")
+
+	newClient := func(secret string) *minio.Client {
+		client, err := minio.New(endpoint, &minio.Options{Creds: credentials.NewStaticV4("storage-key", secret, "")})
+		if err != nil {
+			t.Fatalf("minio client: %v", err)
+		}
+		return client
+	}
+	ctx := context.Background()
+	client := newClient("storage-secret")
+
+	if err := client.MakeBucket(ctx, BucketVerificationDocs, minio.MakeBucketOptions{}); err != nil {
+		t.Fatalf("MakeBucket: %v", err)
+	}
+	content := []byte("passport scan")
+	const key = "verifications/1/passport scan 0.png"
+	_, err = client.PutObject(ctx, BucketVerificationDocs, key, bytes.NewReader(content), int64(len(content)), minio.PutObjectOptions{ContentType: "image/png"})
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	object, err := client.GetObject(ctx, BucketVerificationDocs, key, minio.GetObjectOptions{})
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	if got, err := io.ReadAll(object); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("GetObject = %q, %v", got, err)
+	}
+
+	var listed []string
+	for info := range client.ListObjects(ctx, BucketVerificationDocs, minio.ListObjectsOptions{Prefix: "verifications/", Recursive: true}) {
+		if info.Err != nil {
+			t.Fatalf("ListObjects: %v", info.Err)
+		}
+		listed = append(listed, info.Key)
+	}
+	if len(listed) != 1 || listed[0] != key {
+		t.Errorf("ListObjects = %v, want [%s]", listed, key)
+	}
+
+	if _, err := newClient("other-secret").StatObject(ctx, BucketVerificationDocs, key, minio.StatObjectOptions{}); err == nil {
+		t.Error("StatObject with wrong secret succeeded")
+	}
+
+	get := func(rawURL string) int {
+		resp, err := http.Get(rawURL)
+		if err != nil {
+			t.Fatalf("GET %s: %v", rawURL, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get(srv.URL + "/" + BucketVerificationDocs + "/" + url.PathEscape(key)); code != http.StatusForbidden {
+		t.Errorf("unsigned GET = %d, want 403", code)
+	}
+	if code := get(srv.URL + "/" + BucketVerificationDocs + "?list-type=2"); code != http.StatusForbidden {
+		t.Errorf("unsigned list = %d, want 403", code)
+	}
+
+	presigned, err := client.PresignedGetObject(ctx, BucketVerificationDocs, key, time.Minute, nil)
+	if err != nil {
+		t.Fatalf("PresignedGetObject: %v", err)
+	}
+	if code := get(presigned.String()); code != http.StatusOK {
+		t.Errorf("presigned GET = %d, want 200", code)
+	}
+
+	other := *presigned
+	other.Path = "/" + BucketVerificationDocs + "/verifications/2/passport_scan_0.png"
+	if code := get(other.String()); code != http.StatusForbidden {
+		t.Errorf("presigned GET of other object = %d, want 403", code)
+	}
+	extended := *presigned
+	query := extended.Query()
+	query.Set("X-Amz-Expires", "604800")
+	extended.RawQuery = query.Encode()
+	if code := get(extended.String()); code != http.StatusForbidden {
+		t.Errorf("presigned GET with changed expiry = %d, want 403", code)
+	}
+
+	if err := client.RemoveObject(ctx, BucketVerificationDocs, key, minio.RemoveObjectOptions{}); err != nil {
+		t.Fatalf("RemoveObject: %v", err)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, presigned.String(), nil)
+	if code, err := storage.verifySignature(request, time.Now().Add(2*time.Minute)); err == nil {
+		t.Error("expired presigned URL accepted")
+	} else if code != "AccessDenied" {
+		t.Errorf("expired presigned URL: code = %s, want AccessDenied", code)
+	}
+}
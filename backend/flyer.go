@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// DefaultFlyerTemplate шаблон листовки, если он не указан в запросе
+const DefaultFlyerTemplate = "classic"
+
+// FlyerTemplate оформление листовки для печати
+type FlyerTemplate struct {
+	// Фото поста под заголовком
+	Photo bool
+	// Цветной прогресс сбора и заголовки, иначе только оттенки серого
+	Color bool
+	// Отрывные полоски с телефоном для перевода внизу листа
+	TearOff bool
+}
+
+// FlyerTemplates доступные шаблоны листовок
+var FlyerTemplates = map[string]FlyerTemplate{
+	"classic": {Photo: true, Color: true},
+	"tearoff": {Photo: true, Color: true, TearOff: true},
+	// Без фото и цвета, для экономии краски на черно-белом принтере
+	"mono": {TearOff: true},
+}
+
+// FlyerTemplateNames возвращает названия шаблонов для сообщений об ошибках
+func FlyerTemplateNames() []string {
+	names := make([]string, 0, len(FlyerTemplates))
+	for name := range FlyerTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Разметка листа A4 в миллиметрах
+const (
+	flyerPageWidth    = 210.0
+	flyerPageHeight   = 297.0
+	flyerMargin       = 15.0
+	flyerQRSize       = 45.0
+	flyerTearOffCount = 7
+	flyerTearOffLen   = 55.0
+	// Высота блока с прогрессом, реквизитами и QR кодом
+	flyerFooterHeight = 75.0
+)
+
+// RenderFlyer создает PDF листовку A4 для сбора офлайн: заголовок, фото,
+// описание, прогресс, реквизиты для перевода и QR код со ссылкой на пост.
+// photo может быть nil.
+func RenderFlyer(post *Post, photo image.Image, postURL string, tmpl FlyerTemplate) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(post.Title, true)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetMargins(flyerMargin, flyerMargin, flyerMargin)
+	pdf.AddUTF8FontFromBytes("Go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("Go", "B", gobold.TTF)
+	pdf.AddPage()
+
+	accent := [3]int{0x2f, 0x9e, 0x44}
+	if !tmpl.Color {
+		accent = [3]int{0x21, 0x25, 0x29}
+	}
+	contentWidth := flyerPageWidth - 2*flyerMargin
+
+	// Заголовок
+	y := flyerMargin
+	pdf.SetTextColor(accent[0], accent[1], accent[2])
+	pdf.SetFont("Go", "B", 12)
+	pdf.Text(flyerMargin, y+4, "БЛАГОТВОРИТЕЛЬНЫЙ СБОР")
+	y += 8
+
+	pdf.SetTextColor(0x21, 0x25, 0x29)
+	pdf.SetFont("Go", "B", 24)
+	for _, line := range limitLines(pdf.SplitText(post.Title, contentWidth), 3) {
+		y += 10
+		pdf.Text(flyerMargin, y, line)
+	}
+	y += 6
+
+	// Фото
+	if tmpl.Photo && photo != nil {
+		photoHeight := 100.0
+		if tmpl.TearOff {
+			photoHeight = 80
+		}
+		data, err := flyerPhoto(photo, contentWidth, photoHeight)
+		if err != nil {
+			return nil, err
+		}
+		pdf.RegisterImageOptionsReader("photo", fpdf.ImageOptions{ImageType: "JPG"}, bytes.NewReader(data))
+		pdf.ImageOptions("photo", flyerMargin, y, contentWidth, photoHeight, false, fpdf.ImageOptions{ImageType: "JPG"}, 0, "")
+		y += photoHeight + 6
+	}
+
+	// Описание занимает место до блока с реквизитами
+	footerY := flyerPageHeight - flyerMargin - flyerFooterHeight
+	if tmpl.TearOff {
+		footerY -= flyerTearOffLen - flyerMargin
+	}
+	const descriptionLineHeight = 5.5
+	pdf.SetFont("Go", "", 12)
+	maxLines := int((footerY - y - 4) / descriptionLineHeight)
+	for _, line := range limitLines(pdf.SplitText(post.Description, contentWidth), maxLines) {
+		y += descriptionLineHeight
+		pdf.Text(flyerMargin, y, line)
+	}
+
+	// Прогресс сбора
+	y = footerY
+	pdf.SetFont("Go", "B", 14)
+	pdf.Text(flyerMargin, y+5, fmt.Sprintf("Собрано %s из %s руб.", formatAmount(post.Collected), formatAmount(post.Amount)))
+	progress := 0.0
+	if post.Amount > 0 {
+		progress = math.Min(post.Collected/post.Amount, 1)
+	}
+	pdf.SetFillColor(0xe9, 0xec, 0xef)
+	pdf.Rect(flyerMargin, y+8, contentWidth, 5, "F")
+	if progress > 0 {
+		pdf.SetFillColor(accent[0], accent[1], accent[2])
+		pdf.Rect(flyerMargin, y+8, contentWidth*progress, 5, "F")
+	}
+	y += 22
+
+	// Реквизиты и QR код
+	qr, err := qrcode.Encode(postURL, qrcode.Medium, 512)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create qr code: %w", err)
+	}
+	qrX := flyerPageWidth - flyerMargin - flyerQRSize
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	pdf.ImageOptions("qr", qrX, y, flyerQRSize, flyerQRSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	pdf.SetFont("Go", "", 9)
+	pdf.SetTextColor(0x6c, 0x75, 0x7d)
+	pdf.Text(qrX+2, y+flyerQRSize+3, "Помочь онлайн")
+
+	requisitesWidth := qrX - flyerMargin - 5
+	pdf.SetTextColor(0x21, 0x25, 0x29)
+	pdf.SetFont("Go", "B", 14)
+	pdf.Text(flyerMargin, y+5, "Как помочь")
+	y += 7
+	for _, field := range [][2]string{
+		{"Получатель", post.Recipient},
+		{"Банк", post.Bank},
+		{"Перевод по номеру телефона (СБП)", post.Phone},
+	} {
+		if field[1] == "" {
+			continue
+		}
+		pdf.SetFont("Go", "", 10)
+		pdf.SetTextColor(0x6c, 0x75, 0x7d)
+		y += 5.5
+		pdf.Text(flyerMargin, y, field[0])
+		pdf.SetFont("Go", "B", 13)
+		pdf.SetTextColor(0x21, 0x25, 0x29)
+		for _, line := range limitLines(pdf.SplitText(field[1], requisitesWidth), 2) {
+			y += 6
+			pdf.Text(flyerMargin, y, line)
+		}
+	}
+
+	if tmpl.TearOff {
+		drawTearOffStrips(pdf, post)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render flyer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawTearOffStrips рисует внизу листа отрывные полоски с телефоном для перевода
+func drawTearOffStrips(pdf *fpdf.Fpdf, post *Post) {
+	top := flyerPageHeight - flyerTearOffLen
+	stripWidth := flyerPageWidth / flyerTearOffCount
+
+	pdf.SetDrawColor(0xad, 0xb5, 0xbd)
+	pdf.SetLineWidth(0.2)
+	pdf.SetDashPattern([]float64{1.5, 1.5}, 0)
+	pdf.Line(0, top, flyerPageWidth, top)
+	for i := 1; i < flyerTearOffCount; i++ {
+		pdf.Line(float64(i)*stripWidth, top, float64(i)*stripWidth, flyerPageHeight)
+	}
+	pdf.SetDashPattern(nil, 0)
+
+	textLength := flyerTearOffLen - 6
+	lines := []string{post.Phone, post.Title}
+	if post.Phone == "" {
+		lines = []string{post.Recipient, post.Title}
+	}
+
+	pdf.SetTextColor(0x21, 0x25, 0x29)
+	for i := 0; i < flyerTearOffCount; i++ {
+		left := float64(i) * stripWidth
+		x, y := left+stripWidth/2-0.5, flyerPageHeight-3
+		// Текст идет снизу вверх вдоль полоски
+		pdf.TransformBegin()
+		pdf.TransformRotate(90, x, y)
+		pdf.SetFont("Go", "B", 11)
+		pdf.Text(x, y, fitText(pdf, lines[0], textLength))
+		pdf.SetFont("Go", "", 8)
+		pdf.Text(x, y+5, fitText(pdf, lines[1], textLength))
+		pdf.TransformEnd()
+	}
+}
+
+// flyerPhoto обрезает фото под пропорции области на листе и кодирует в JPEG
+func flyerPhoto(photo image.Image, width, height float64) ([]byte, error) {
+	// 150 точек на дюйм достаточно для печати
+	const dotsPerMM = 150 / 25.4
+	pw, ph := int(width*dotsPerMM), int(height*dotsPerMM)
+
+	img := scaleImage(cropAspect(photo, pw, ph), pw, ph)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode flyer photo: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// limitLines оставляет не больше maxLines строк, обрезанную последнюю помечает многоточием
+func limitLines(lines []string, maxLines int) []string {
+	if maxLines <= 0 {
+		return nil
+	}
+	if len(lines) <= maxLines {
+		return lines
+	}
+	lines = lines[:maxLines]
+	lines[maxLines-1] = strings.TrimRight(lines[maxLines-1], " .,") + "…"
+	return lines
+}
+
+// fitText обрезает строку с многоточием, чтобы она помещалась в width текущим шрифтом
+func fitText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 1 && pdf.GetStringWidth(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimRight(string(runes), " ") + "…"
+}
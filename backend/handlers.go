@@ -1,30 +1,65 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
 	"io"
+	"log"
+	"math"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/minio/minio-go/v7"
+
+	"tmphackbackend/backup"
 )
 
 type Handlers struct {
 	db          *DB
 	minioClient *minio.Client
+	sms         SMSProvider
+	payments    PaymentProvider
+	notifier    *Notifier
+	perms       *Permissions
+	oauth       *OAuthProviders
+	scanner     FileScanner
+	limiter     *RateLimiter
+	scheduler   *Scheduler
+	selfTest    *SelfTest
+	events      *EventHub
+	registry    *OrganizationRegistry
 	cfg         *Config
 }
 
-func NewHandlers(db *DB, minioClient *minio.Client, cfg *Config) *Handlers {
+func NewHandlers(db *DB, minioClient *minio.Client, sms SMSProvider, payments PaymentProvider, notifier *Notifier, perms *Permissions, oauth *OAuthProviders, scanner FileScanner, limiter *RateLimiter, scheduler *Scheduler, selfTest *SelfTest, events *EventHub, registry *OrganizationRegistry, cfg *Config) *Handlers {
 	return &Handlers{
 		db:          db,
 		minioClient: minioClient,
+		sms:         sms,
+		payments:    payments,
+		notifier:    notifier,
+		perms:       perms,
+		oauth:       oauth,
+		scanner:     scanner,
+		limiter:     limiter,
+		scheduler:   scheduler,
+		selfTest:    selfTest,
+		events:      events,
+		registry:    registry,
 		cfg:         cfg,
 	}
 }
@@ -33,17 +68,27 @@ func NewHandlers(db *DB, minioClient *minio.Client, cfg *Config) *Handlers {
 
 // HealthCheck проверяет состояние сервера
 // @Summary     Health check
-// @Description Проверяет состояние сервера, подключение к базе данных и MinIO
+// @Description Проверяет состояние сервера, подключение к базе данных и MinIO.
+// @Description С deep=true (нужен JWT с правом system.view) возвращает DeepHealthResponse: задержку запроса к базе,
+// @Description запись и чтение пробного объекта в MinIO, очереди уведомлений и вебхуков, последние запуски фоновых задач.
 // @Tags        Утилиты
 // @Accept      json
 // @Produce     json
+// @Param       deep query bool false "Подробная диагностика"
 // @Success     200  {object}  HealthCheckResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
 // @Failure     503  {object}  ErrorResponse
 // @Router      /health [get]
 func (h *Handlers) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("deep") == "true" {
+		h.deepHealthCheck(w, r)
+		return
+	}
+
 	status := map[string]interface{}{
 		"status":    "ok",
-		"timestamp": time.Now().Format(time.RFC3339),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
 
 	if err := h.db.Ping(); err != nil {
@@ -71,9 +116,280 @@ func (h *Handlers) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusServiceUnavailable)
 	}
 
+	// Состояние резервного копирования не влияет на код ответа
+	backupStatus := h.getBackupStatus()
+	switch {
+	case !backupStatus.Enabled:
+		status["backup"] = "disabled"
+	case backupStatus.LastBackup == nil:
+		status["backup"] = "never"
+	case !backupStatus.LastBackup.Success:
+		status["backup"] = "failed"
+	case backupStatus.Stale:
+		status["backup"] = "stale"
+	default:
+		status["backup"] = "ok"
+	}
+
 	WriteJSON(w, http.StatusOK, status)
 }
 
+// deepHealthCheck подробная диагностика для дежурного, только для роли с правом system.view
+func (h *Handlers) deepHealthCheck(w http.ResponseWriter, r *http.Request) {
+	claims, err := OptionalClaims(h.cfg, h.db, r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if claims == nil {
+		WriteError(w, NewUnauthorizedError("Не авторизован"))
+		return
+	}
+	if !h.perms.Has(claims.Role, PermSystemView) {
+		WriteError(w, NewForbiddenError("Недостаточно прав"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	response := DeepHealthResponse{
+		Status:    "ok",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Database:  HealthCheckTiming{Status: "ok"},
+		MinIO:     h.probeMinIO(ctx),
+		Breakers:  make(map[string]string),
+		Jobs:      h.scheduler.Status(),
+	}
+
+	startedAt := time.Now()
+	var one int
+	if err := h.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		response.Database = HealthCheckTiming{Status: "error", Error: err.Error()}
+	}
+	response.Database.DurationMS = elapsedMS(startedAt)
+
+	for _, b := range CircuitBreakers() {
+		response.Breakers[b.Name()] = b.State().String()
+	}
+
+	response.Backlog.NotificationQueue, response.Backlog.NotificationQueueCapacity = h.notifier.QueueLength()
+	response.Backlog.WebhookPending, response.Backlog.WebhookOldestPendingAt, err = h.db.GetWebhookBacklog(ctx)
+	if err != nil {
+		log.Printf("Failed to get webhook backlog: %v", err)
+	}
+
+	status := http.StatusOK
+	if response.Database.Status != "ok" || response.MinIO.Status != "ok" {
+		response.Status = "degraded"
+		status = http.StatusServiceUnavailable
+	}
+	WriteJSON(w, status, response)
+}
+
+// probeMinIO записывает, читает и удаляет пробный объект в bucket медиа постов
+func (h *Handlers) probeMinIO(ctx context.Context) MinIOHealthCheck {
+	result := MinIOHealthCheck{Status: "error"}
+	objectKey := fmt.Sprintf("healthcheck/probe-%d", time.Now().UnixNano())
+	payload := []byte("healthcheck")
+
+	startedAt := time.Now()
+	_, err := h.minioClient.PutObject(ctx, BucketPostMedia, objectKey, bytes.NewReader(payload), int64(len(payload)), minio.PutObjectOptions{
+		ContentType: "text/plain",
+	})
+	result.PutMS = elapsedMS(startedAt)
+	if err != nil {
+		result.Error = "put: " + err.Error()
+		return result
+	}
+
+	startedAt = time.Now()
+	obj, err := h.minioClient.GetObject(ctx, BucketPostMedia, objectKey, minio.GetObjectOptions{})
+	if err == nil {
+		var data []byte
+		data, err = io.ReadAll(obj)
+		obj.Close()
+		if err == nil && !bytes.Equal(data, payload) {
+			err = fmt.Errorf("probe content mismatch")
+		}
+	}
+	result.GetMS = elapsedMS(startedAt)
+
+	startedAt = time.Now()
+	deleteErr := h.minioClient.RemoveObject(ctx, BucketPostMedia, objectKey, minio.RemoveObjectOptions{})
+	result.DeleteMS = elapsedMS(startedAt)
+
+	switch {
+	case err != nil:
+		result.Error = "get: " + err.Error()
+	case deleteErr != nil:
+		result.Error = "delete: " + deleteErr.Error()
+	default:
+		result.Status = "ok"
+	}
+	return result
+}
+
+// elapsedMS возвращает время с startedAt в миллисекундах
+func elapsedMS(startedAt time.Time) float64 {
+	return float64(time.Since(startedAt).Microseconds()) / 1000
+}
+
+// ReadinessCheck проверяет готовность сервера принимать трафик
+// @Summary     Readiness check
+// @Description Возвращает 503, если база данных недоступна или разомкнут circuit breaker PostgreSQL или MinIO.
+// @Description Breakers внешних интеграций выводятся, но на готовность не влияют.
+// @Tags        Утилиты
+// @Produce     json
+// @Success     200  {object}  ReadinessResponse
+// @Failure     503  {object}  ReadinessResponse
+// @Router      /readyz [get]
+func (h *Handlers) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	response := ReadinessResponse{
+		Status:   "ready",
+		Breakers: make(map[string]string),
+	}
+	status := http.StatusOK
+
+	for _, b := range CircuitBreakers() {
+		state := b.State()
+		response.Breakers[b.Name()] = state.String()
+		if b.Critical() && state == BreakerOpen {
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	if err := h.db.PingContext(r.Context()); err != nil {
+		response.Database = "error: " + err.Error()
+		status = http.StatusServiceUnavailable
+	} else {
+		response.Database = "connected"
+	}
+
+	if status != http.StatusOK {
+		response.Status = "degraded"
+	}
+	WriteJSON(w, status, response)
+}
+
+// GetBackupStatus возвращает статус последнего резервного копирования
+// @Summary     Статус резервного копирования
+// @Description Возвращает результат последнего резервного копирования (только для администраторов)
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  BackupStatusResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/backups/status [get]
+func (h *Handlers) GetBackupStatus(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, h.getBackupStatus())
+}
+
+// GetSelfTest возвращает результат самотестирования при запуске
+// @Summary     Результат самотестирования
+// @Description Возвращает результат проверки при запуске сервера: транзакция в PostgreSQL и запись, чтение и удаление пробного объекта в каждом bucket MinIO.
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  SelfTestReport
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/selftest [get]
+func (h *Handlers) GetSelfTest(w http.ResponseWriter, r *http.Request) {
+	report := h.selfTest.Last()
+	if report == nil {
+		WriteError(w, NewNotFoundError("Результат самотестирования"))
+		return
+	}
+	WriteJSON(w, http.StatusOK, report)
+}
+
+// RunSelfTest повторяет самотестирование
+// @Summary     Запустить самотестирование
+// @Description Повторяет проверки самотестирования, например после исправления учетных данных. Код ответа 200 и при проваленных проверках.
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  SelfTestReport
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/selftest [post]
+func (h *Handlers) RunSelfTest(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, h.selfTest.Run(r.Context()))
+}
+
+// GetOps возвращает сводку очередей и фоновых задач
+// @Summary     Очереди и фоновые задачи
+// @Description Сводка для дежурного: очередь исходящих событий интеграций и вебхуков партнеров, очередь рассылки уведомлений,
+// @Description события с прекращенной доставкой, чеки, сверка которых не завершилась за 30 минут, и последние запуски фоновых задач
+// @Description (stale - задача не завершалась успешно дольше трех интервалов), число заданий в очереди недоставленных.
+// @Description Очередь уведомлений и задачи - данные этого экземпляра.
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  OpsResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/ops [get]
+func (h *Handlers) GetOps(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	response := OpsResponse{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Jobs:      h.scheduler.Status(),
+	}
+	response.NotificationQueue, response.NotificationQueueCapacity = h.notifier.QueueLength()
+
+	var err error
+	if response.Outbox.Count, response.Outbox.OldestAt, err = h.db.GetWebhookBacklog(ctx); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if response.WebhookFailed, response.WebhookFailed24h, err = h.db.GetFailedWebhookDeliveries(ctx); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if response.StuckUploads.Count, response.StuckUploads.OldestAt, err = h.db.GetStuckReceiptChecks(ctx, OpsStuckUploadAfter); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if response.DeadLetters, err = h.db.CountFailedDeadLetters(ctx); err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetRetentionReport показывает, что затронет следующий запуск задачи retention
+// @Summary     Отчет по срокам хранения
+// @Description Пробный запуск без изменений: число неактивных аккаунтов к обезличиванию, неподтвержденных регистраций
+// @Description и истекших кодов к удалению, вложений чатов старше срока хранения, и границы по времени для каждой очистки.
+// @Description Заявки на верификацию обезличиваемых аккаунтов удаляются: verification_docs - сканы к удалению,
+// @Description retained_verification_docs - сканы под блокировкой хранилища, retained_post_documents - документы
+// @Description законного представителя и срочности, которые остаются вместе с постами.
+// @Description enabled=false - задача выключена (RETENTION_ENABLED), отчет показывает, что она сделала бы.
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  RetentionReport
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/retention/report [get]
+func (h *Handlers) GetRetentionReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := PreviewRetention(ctx, h.db, h.cfg.Retention)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, report)
+}
+
 // ========== Auth Endpoints ==========
 
 // Register регистрирует нового пользователя
@@ -104,6 +420,22 @@ func (h *Handlers) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	inviteCode := NormalizeInviteCode(req.InviteCode)
+	if inviteCode == "" && h.cfg.Invites.Required {
+		WriteError(w, NewValidationError("Для регистрации нужен код приглашения", map[string]interface{}{"field": "invite_code"}))
+		return
+	}
+
+	var referrerID int64
+	if referralCode := NormalizeInviteCode(req.ReferralCode); referralCode != "" {
+		id, err := h.db.GetUserIDByReferralCode(referralCode)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		referrerID = id
+	}
+
 	phone := FormatPhone(req.Phone)
 	passwordHash, err := HashPassword(req.Password)
 	if err != nil {
@@ -111,22 +443,41 @@ func (h *Handlers) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.db.CreateUser(phone, passwordHash, req.FirstName, req.LastName)
+	user, err := h.db.CreateUser(phone, passwordHash, req.FirstName, req.LastName, inviteCode)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
+	// Без приглашения пользователь просто не участвует в реферальной программе
+	if referrerID != 0 {
+		if err := h.db.CreateReferral(referrerID, user.ID, getStringPtr(getClientIP(r, h.cfg.TrustedProxies))); err != nil {
+			log.Printf("Failed to save referral of user %d: %v", user.ID, err)
+		}
+	}
+
 	token, err := GenerateToken(h.cfg, user.ID, user.Role, false)
 	if err != nil {
 		WriteError(w, NewInternalError("Ошибка генерации токена"))
 		return
 	}
 
+	refreshToken, err := h.issueRefreshToken(r, user.ID)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка генерации токена"))
+		return
+	}
+
+	// Код подтверждения телефона; при ошибке отправки его можно запросить повторно
+	if err := h.sendOTP(r, phone, OTPPurposeVerifyPhone); err != nil {
+		log.Printf("Failed to send verification code to user %d: %v", user.ID, err)
+	}
+
 	response := RegisterResponse{
-		UserID:  user.ID,
-		Token:   token,
-		Message: "Пользователь успешно зарегистрирован",
+		UserID:       user.ID,
+		Token:        token,
+		RefreshToken: refreshToken,
+		Message:      "Пользователь успешно зарегистрирован",
 	}
 	WriteJSON(w, http.StatusCreated, response)
 }
@@ -161,6 +512,7 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !CheckPassword(req.Password, user.PasswordHash) {
+		h.recordLogin(r, user.ID, false)
 		WriteError(w, NewUnauthorizedError("Неверные учетные данные"))
 		return
 	}
@@ -170,225 +522,234 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := GenerateToken(h.cfg, user.ID, user.Role, false)
-	if err != nil {
-		WriteError(w, NewInternalError("Ошибка генерации токена"))
-		return
-	}
-
-	// Очищаем пароль из ответа
-	user.PasswordHash = ""
-	response := LoginResponse{
-		UserID: user.ID,
-		Token:  token,
-		User:   user,
-	}
-	WriteJSON(w, http.StatusOK, response)
+	h.completeLogin(w, r, user)
 }
 
 // RefreshToken обновляет JWT токен
 // @Summary     Обновление токена
-// @Description Обновляет JWT токен пользователя
+// @Description Выдает новый access токен по refresh токену. Refresh токен одноразовый: в ответе возвращается новый, старый отзывается
 // @Tags        Аутентификация
 // @Accept      json
 // @Produce     json
-// @Security    BearerAuth
+// @Param       request body RefreshTokenRequest true "Refresh токен"
 // @Success     200  {object}  RefreshTokenResponse
+// @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
 // @Router      /auth/refresh [post]
 func (h *Handlers) RefreshToken(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	tokenString, err := ExtractTokenFromHeader(authHeader)
-	if err != nil {
-		WriteError(w, NewUnauthorizedError("Токен не найден"))
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
 		return
 	}
 
-	claims, err := ValidateToken(h.cfg, tokenString)
+	stored, err := h.db.GetRefreshTokenByHash(sha256Hex(req.RefreshToken))
 	if err != nil {
 		WriteError(w, NewUnauthorizedError("Неверный токен"))
 		return
 	}
 
-	user, err := h.db.GetUserByID(claims.UserID)
+	// Повторное использование отозванного токена - признак утечки, завершаем все сессии
+	if stored.RevokedAt != nil {
+		if err := h.db.RevokeUserRefreshTokens(stored.UserID); err != nil {
+			WriteError(w, NewInternalError("Ошибка отзыва сессий"))
+			return
+		}
+		WriteError(w, NewUnauthorizedError("Токен отозван"))
+		return
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		WriteError(w, NewUnauthorizedError("Срок действия токена истек"))
+		return
+	}
+
+	user, err := h.db.GetUserByID(stored.UserID)
 	if err != nil {
 		WriteError(w, NewUnauthorizedError("Пользователь не найден"))
 		return
 	}
 
-	newToken, err := GenerateToken(h.cfg, user.ID, user.Role, false)
+	if !user.IsActive {
+		WriteError(w, NewForbiddenError("Аккаунт деактивирован"))
+		return
+	}
+
+	newRefreshToken, err := GenerateRefreshToken()
 	if err != nil {
 		WriteError(w, NewInternalError("Ошибка генерации токена"))
 		return
 	}
 
-	response := RefreshTokenResponse{Token: newToken}
-	WriteJSON(w, http.StatusOK, response)
-}
-
-// ========== User Endpoints ==========
-
-// GetProfile получает профиль текущего пользователя
-// @Summary     Получить профиль
-// @Description Возвращает информацию о текущем пользователе
-// @Tags        Профиль
-// @Accept      json
-// @Produce     json
-// @Security    BearerAuth
-// @Success     200  {object}  User
-// @Failure     401  {object}  ErrorResponse
-// @Router      /users/me [get]
-func (h *Handlers) GetProfile(w http.ResponseWriter, r *http.Request) {
-	userID, err := GetUserIDFromContext(r.Context())
+	rotated, err := h.db.RotateRefreshToken(stored.ID, sha256Hex(newRefreshToken), time.Now().Add(h.cfg.JWTRefreshExpiry),
+		getStringPtr(r.UserAgent()), getStringPtr(getClientIP(r, h.cfg.TrustedProxies)))
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	if !rotated {
+		WriteError(w, NewUnauthorizedError("Токен отозван"))
+		return
+	}
 
-	user, err := h.db.GetUserByID(userID)
+	newToken, err := GenerateToken(h.cfg, user.ID, user.Role, false)
 	if err != nil {
-		WriteError(w, err)
+		WriteError(w, NewInternalError("Ошибка генерации токена"))
 		return
 	}
 
-	user.PasswordHash = ""
-
-	// Преобразуем photo_url в URL через backend проксирование, если он есть
-	if user.PhotoURL != nil && *user.PhotoURL != "" {
-		backendURL := ConvertMinIOURLToBackendURL(*user.PhotoURL)
-		user.PhotoURL = &backendURL
+	response := RefreshTokenResponse{
+		Token:        newToken,
+		RefreshToken: newRefreshToken,
 	}
-
-	WriteJSON(w, http.StatusOK, user)
+	WriteJSON(w, http.StatusOK, response)
 }
 
-// UpdateProfile обновляет профиль пользователя
-// @Summary     Обновить профиль
-// @Description Обновляет данные профиля текущего пользователя
-// @Tags        Профиль
+// RequestOTP отправляет код подтверждения телефона
+// @Summary     Запрос кода подтверждения
+// @Description Отправляет SMS с одноразовым кодом для подтверждения телефона. Повторная отправка возможна не чаще одного раза в минуту
+// @Tags        Аутентификация
 // @Accept      json
 // @Produce     json
-// @Security    BearerAuth
-// @Param       request body UpdateProfileRequest true "Данные для обновления"
-// @Success     200  {object}  User
+// @Param       request body RequestOTPRequest true "Телефон"
+// @Success     200  {object}  SuccessResponse
 // @Failure     400  {object}  ErrorResponse
-// @Failure     401  {object}  ErrorResponse
-// @Router      /users/me [patch]
-func (h *Handlers) UpdateProfile(w http.ResponseWriter, r *http.Request) {
-	userID, err := GetUserIDFromContext(r.Context())
-	if err != nil {
-		WriteError(w, err)
-		return
-	}
-
-	var req UpdateProfileRequest
+// @Failure     429  {object}  ErrorResponse
+// @Router      /auth/request-otp [post]
+func (h *Handlers) RequestOTP(w http.ResponseWriter, r *http.Request) {
+	var req RequestOTPRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
 	}
 
-	if err := h.db.UpdateUser(userID, req.FirstName, req.LastName, req.HelperName, nil); err != nil {
+	if err := ValidateStruct(&req); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	user, err := h.db.GetUserByID(userID)
-	if err != nil {
+	if err := ValidatePhoneNumber(req.Phone); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	user.PasswordHash = ""
+	phone := FormatPhone(req.Phone)
 
-	// Преобразуем photo_url в URL через backend проксирование, если он есть
-	if user.PhotoURL != nil && *user.PhotoURL != "" {
-		backendURL := ConvertMinIOURLToBackendURL(*user.PhotoURL)
-		user.PhotoURL = &backendURL
+	// Не раскрываем, зарегистрирован ли телефон: ответ одинаковый
+	user, err := h.db.GetUserByPhone(phone)
+	if err == nil && !user.PhoneVerified {
+		if err := h.sendOTP(r, phone, OTPPurposeVerifyPhone); err != nil {
+			WriteError(w, err)
+			return
+		}
 	}
 
-	WriteJSON(w, http.StatusOK, user)
+	WriteSuccess(w, http.StatusOK, "Если номер зарегистрирован, на него отправлен код подтверждения")
 }
 
-// UploadPhoto загружает фото профиля
-// @Summary     Загрузить фото профиля
-// @Description Загружает фото профиля пользователя
-// @Tags        Профиль
-// @Accept      multipart/form-data
+// VerifyOTP подтверждает телефон одноразовым кодом
+// @Summary     Подтверждение телефона
+// @Description Проверяет код из SMS и отмечает телефон пользователя как подтвержденный
+// @Tags        Аутентификация
+// @Accept      json
 // @Produce     json
-// @Security    BearerAuth
-// @Param       photo formData file true "Фото профиля (JPEG, PNG, max 5MB)"
-// @Success     200  {object}  PhotoUploadResponse
+// @Param       request body VerifyOTPRequest true "Телефон и код"
+// @Success     200  {object}  SuccessResponse
 // @Failure     400  {object}  ErrorResponse
-// @Failure     401  {object}  ErrorResponse
-// @Failure     413  {object}  ErrorResponse
-// @Router      /users/me/photo [post]
-func (h *Handlers) UploadPhoto(w http.ResponseWriter, r *http.Request) {
-	userID, err := GetUserIDFromContext(r.Context())
-	if err != nil {
-		WriteError(w, err)
+// @Failure     429  {object}  ErrorResponse
+// @Router      /auth/verify-otp [post]
+func (h *Handlers) VerifyOTP(w http.ResponseWriter, r *http.Request) {
+	var req VerifyOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
 	}
 
-	if err := ParseMultipartForm(r, 5<<20); err != nil { // 5MB
+	if err := ValidateStruct(&req); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	file, header, err := GetFileFromForm(r, "photo")
-	if err != nil {
+	phone := FormatPhone(req.Phone)
+	if err := h.checkOTP(phone, OTPPurposeVerifyPhone, req.Code); err != nil {
 		WriteError(w, err)
 		return
 	}
-	defer file.Close()
 
-	if err := ValidateFileSize(header, 5<<20); err != nil { // 5MB
+	user, err := h.db.GetUserByPhone(phone)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	if err := ValidateImageFile(header); err != nil {
+	if err := h.db.SetUserPhoneVerified(user.ID); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	ctx := r.Context()
-	objectKey, err := UploadUserPhoto(ctx, h.minioClient, userID, file, header.Size, header.Header.Get("Content-Type"))
-	if err != nil {
-		WriteError(w, NewInternalError("Ошибка загрузки фото"))
+	WriteSuccess(w, http.StatusOK, "Телефон подтвержден")
+}
+
+// ForgotPassword отправляет код для восстановления пароля
+// @Summary     Восстановление пароля
+// @Description Отправляет SMS с одноразовым кодом для сброса пароля. Повторная отправка возможна не чаще одного раза в минуту
+// @Tags        Аутентификация
+// @Accept      json
+// @Produce     json
+// @Param       request body ForgotPasswordRequest true "Телефон"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     429  {object}  ErrorResponse
+// @Router      /auth/forgot-password [post]
+func (h *Handlers) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
 	}
 
-	photoURL := GetObjectURL(h.cfg.MinIOConfig, BucketUserPhotos, objectKey)
-	if err := h.db.UpdateUser(userID, nil, nil, nil, &photoURL); err != nil {
+	if err := ValidateStruct(&req); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	// Преобразуем для ответа клиенту
-	backendURL := ConvertMinIOURLToBackendURL(photoURL)
-	WriteJSON(w, http.StatusOK, map[string]string{"photo_url": backendURL})
+	if err := ValidatePhoneNumber(req.Phone); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	phone := FormatPhone(req.Phone)
+
+	// Не раскрываем, зарегистрирован ли телефон: ответ одинаковый
+	user, err := h.db.GetUserByPhone(phone)
+	if err == nil && user.IsActive {
+		if err := h.sendOTP(r, phone, OTPPurposeResetPassword); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	WriteSuccess(w, http.StatusOK, "Если номер зарегистрирован, на него отправлен код для сброса пароля")
 }
 
-// ChangePassword изменяет пароль пользователя
-// @Summary     Изменить пароль
-// @Description Изменяет пароль текущего пользователя
-// @Tags        Профиль
+// ResetPassword устанавливает новый пароль по коду из SMS
+// @Summary     Сброс пароля
+// @Description Проверяет код из SMS и устанавливает новый пароль. Все сессии пользователя завершаются, выданные access токены перестают действовать
+// @Tags        Аутентификация
 // @Accept      json
 // @Produce     json
-// @Security    BearerAuth
-// @Param       request body ChangePasswordRequest true "Старый и новый пароль"
+// @Param       request body ResetPasswordRequest true "Телефон, код и новый пароль"
 // @Success     200  {object}  SuccessResponse
 // @Failure     400  {object}  ErrorResponse
-// @Failure     401  {object}  ErrorResponse
-// @Router      /users/me/change-password [post]
-func (h *Handlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
-	userID, err := GetUserIDFromContext(r.Context())
-	if err != nil {
-		WriteError(w, err)
-		return
-	}
-
-	var req ChangePasswordRequest
+// @Failure     429  {object}  ErrorResponse
+// @Router      /auth/reset-password [post]
+func (h *Handlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
@@ -399,24 +760,39 @@ func (h *Handlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.db.GetUserByID(userID)
-	if err != nil {
+	phone := FormatPhone(req.Phone)
+	if err := h.checkOTP(phone, OTPPurposeResetPassword, req.Code); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	if !CheckPassword(req.OldPassword, user.PasswordHash) {
-		WriteError(w, NewUnauthorizedError("Неверный старый пароль"))
+	user, err := h.db.GetUserByPhone(phone)
+	if err != nil {
+		WriteError(w, err)
 		return
 	}
 
-	newPasswordHash, err := HashPassword(req.NewPassword)
+	passwordHash, err := HashPassword(req.NewPassword)
 	if err != nil {
 		WriteError(w, NewInternalError("Ошибка обработки пароля"))
 		return
 	}
 
-	if err := h.db.UpdateUserPassword(userID, newPasswordHash); err != nil {
+	if err := h.db.UpdateUserPassword(user.ID, passwordHash); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Код пришел на телефон пользователя, значит телефон подтвержден
+	if !user.PhoneVerified {
+		if err := h.db.SetUserPhoneVerified(user.ID); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	// Сброс пароля завершает все сессии, включая выданные access токены
+	if err := h.db.RevokeUserTokens(user.ID); err != nil {
 		WriteError(w, err)
 		return
 	}
@@ -424,284 +800,7222 @@ func (h *Handlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	WriteSuccess(w, http.StatusOK, "Пароль успешно изменен")
 }
 
-// ========== Verification Endpoints ==========
-
-// CreateVerification создает заявку на верификацию
-// @Summary     Подать заявку на верификацию
-// @Description Создает заявку на верификацию пользователя
-// @Tags        Верификация
-// @Accept      multipart/form-data
+// Logout выход из системы
+// @Summary     Выход из системы
+// @Description Отзывает текущий access токен. Если передан refresh токен, он также отзывается; all_sessions завершает все сессии пользователя
+// @Tags        Аутентификация
+// @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       user_photo formData file true "Фото пользователя"
-// @Param       last_name formData string true "Фамилия"
-// @Param       first_name formData string true "Имя"
-// @Param       middle_name formData string false "Отчество"
-// @Param       birth_date formData string true "Дата рождения (YYYY-MM-DD)"
-// @Param       passport_series formData string true "Серия паспорта"
-// @Param       passport_number formData string true "Номер паспорта"
-// @Param       passport_issuer formData string true "Кем выдан"
-// @Param       passport_date formData string true "Дата выдачи (YYYY-MM-DD)"
-// @Param       doc_type formData string true "Тип документа (inn или snils)" Enums(inn, snils)
-// @Param       inn formData string false "ИНН"
-// @Param       snils formData string false "СНИЛС"
-// @Param       passport_scans formData file true "Сканы паспорта (минимум 2)"
-// @Param       consent1 formData bool true "Согласие 1"
-// @Param       consent2 formData bool true "Согласие 2"
-// @Param       consent3 formData bool true "Согласие 3"
-// @Success     201  {object}  VerificationResponse
+// @Param       request body LogoutRequest false "Параметры выхода"
+// @Success     200  {object}  SuccessResponse
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
-// @Failure     409  {object}  ErrorResponse
-// @Router      /verifications [post]
-func (h *Handlers) CreateVerification(w http.ResponseWriter, r *http.Request) {
-	userID, err := GetUserIDFromContext(r.Context())
+// @Router      /auth/logout [post]
+func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, err := GetTokenClaimsFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	// Проверяем, нет ли уже верификации
-	existing, _ := h.db.GetVerificationByUserID(userID)
-	if existing != nil {
-		WriteError(w, NewConflictError("Заявка на верификацию уже подана"))
-		return
+	var req LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, NewValidationError("Неверный формат запроса", nil))
+			return
+		}
 	}
 
-	if err := ParseMultipartForm(r, 50<<20); err != nil { // 50MB для всех файлов
-		WriteError(w, err)
-		return
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := h.db.RevokeAccessToken(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			WriteError(w, err)
+			return
+		}
 	}
 
-	var req VerificationRequest
-	req.LastName = r.FormValue("last_name")
-	req.FirstName = r.FormValue("first_name")
-	req.MiddleName = getStringPtr(r.FormValue("middle_name"))
-	req.BirthDate = r.FormValue("birth_date")
-	req.PassportSeries = r.FormValue("passport_series")
-	req.PassportNumber = r.FormValue("passport_number")
-	req.PassportIssuer = r.FormValue("passport_issuer")
-	req.PassportDate = r.FormValue("passport_date")
-	req.DocType = r.FormValue("doc_type")
-	req.INN = getStringPtr(r.FormValue("inn"))
-	req.SNILS = getStringPtr(r.FormValue("snils"))
-	req.Consent1 = r.FormValue("consent1") == "true"
-	req.Consent2 = r.FormValue("consent2") == "true"
-	req.Consent3 = r.FormValue("consent3") == "true"
+	if req.AllSessions {
+		if err := h.db.RevokeUserRefreshTokens(claims.UserID); err != nil {
+			WriteError(w, err)
+			return
+		}
+	} else if req.RefreshToken != "" {
+		if err := h.db.RevokeRefreshToken(claims.UserID, sha256Hex(req.RefreshToken)); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
 
-	if err := ValidateStruct(&req); err != nil {
+	WriteSuccess(w, http.StatusOK, "Выход выполнен")
+}
+
+// ========== OAuth Endpoints ==========
+
+// GetOAuthProviders получает доступных провайдеров входа
+// @Summary     Провайдеры входа
+// @Description Возвращает провайдеров (vk, yandex, google), через которых можно войти
+// @Tags        Аутентификация
+// @Accept      json
+// @Produce     json
+// @Success     200  {object}  map[string]interface{}
+// @Router      /auth/oauth/providers [get]
+func (h *Handlers) GetOAuthProviders(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"data": h.oauth.Names()})
+}
+
+// StartOAuthLogin начинает вход через провайдера
+// @Summary     Вход через провайдера
+// @Description Возвращает ссылку на страницу входа VK ID, Яндекс или Google. Войти можно только в аккаунт, к которому провайдер уже привязан.
+// @Tags        Аутентификация
+// @Accept      json
+// @Produce     json
+// @Param       provider path string true "Провайдер" Enums(vk, yandex, google)
+// @Success     200  {object}  OAuthAuthorizeResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /auth/oauth/{provider} [get]
+func (h *Handlers) StartOAuthLogin(w http.ResponseWriter, r *http.Request) {
+	h.startOAuth(w, mux.Vars(r)["provider"], nil)
+}
+
+// OAuthCallback завершает вход или привязку провайдера
+// @Summary     Callback провайдера входа
+// @Description Принимает пользователя, вернувшегося от провайдера. При входе выдает токены, при привязке сохраняет учетную запись провайдера в аккаунте. Привязка завершается только запросом с access токеном пользователя, который ее начал.
+// @Tags        Аутентификация
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       provider path string true "Провайдер" Enums(vk, yandex, google)
+// @Param       code query string true "Код авторизации"
+// @Param       state query string true "State из ссылки входа"
+// @Param       device_id query string false "Идентификатор устройства (VK ID)"
+// @Success     200  {object}  LoginResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /auth/oauth/{provider}/callback [get]
+func (h *Handlers) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider, err := h.oauth.Get(mux.Vars(r)["provider"])
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	birthDate, err := time.Parse("2006-01-02", req.BirthDate)
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		WriteError(w, NewUnauthorizedError("Вход через провайдера отменен"))
+		return
+	}
+	if query.Get("code") == "" || query.Get("state") == "" {
+		WriteError(w, NewValidationError("Не переданы code и state", nil))
+		return
+	}
+
+	claims, err := OptionalClaims(h.cfg, h.db, r)
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный формат даты рождения", map[string]interface{}{"field": "birth_date"}))
+		WriteError(w, err)
 		return
 	}
 
-	passportDate, err := time.Parse("2006-01-02", req.PassportDate)
+	state, err := h.db.ConsumeOAuthState(query.Get("state"), provider.Name)
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный формат даты выдачи паспорта", map[string]interface{}{"field": "passport_date"}))
+		WriteError(w, NewUnauthorizedError("Ссылка входа устарела, начните вход заново"))
 		return
 	}
 
-	verification := &Verification{
-		UserID:         userID,
-		LastName:       req.LastName,
-		FirstName:      req.FirstName,
-		MiddleName:     req.MiddleName,
-		BirthDate:      birthDate,
-		PassportSeries: req.PassportSeries,
-		PassportNumber: req.PassportNumber,
-		PassportIssuer: req.PassportIssuer,
-		PassportDate:   passportDate,
-		DocType:        req.DocType,
-		INN:            req.INN,
-		SNILS:          req.SNILS,
-		Consent1:       req.Consent1,
-		Consent2:       req.Consent2,
-		Consent3:       req.Consent3,
+	// Ссылку привязки можно подсунуть другому пользователю: тогда его учетная запись провайдера
+	// привязалась бы к аккаунту автора ссылки. Поэтому привязку завершает только тот, кто ее начал.
+	if state.UserID != nil && (claims == nil || claims.UserID != *state.UserID) {
+		WriteError(w, NewForbiddenError("Привязку нужно завершить в аккаунте, в котором она начата"))
+		return
 	}
 
-	ctx := r.Context()
+	oauthUser, err := h.oauth.Exchange(r.Context(), provider, query.Get("code"), state.CodeVerifier, query.Get("device_id"))
+	if err != nil {
+		log.Printf("OAuth exchange failed: %v", err)
+		WriteError(w, NewUnauthorizedError("Не удалось войти через провайдера"))
+		return
+	}
 
-	// Загружаем фото пользователя
-	if userPhoto, header, err := r.FormFile("user_photo"); err == nil {
-		defer userPhoto.Close()
-		if err := ValidateImageFile(header); err != nil {
+	identity, err := h.db.GetUserIdentity(provider.Name, oauthUser.Subject)
+	if err != nil {
+		if appErr, ok := err.(*AppError); !ok || appErr.Code != ErrCodeNotFound {
 			WriteError(w, err)
 			return
 		}
-		objectKey, err := UploadVerificationDoc(ctx, h.minioClient, 0, "user_photo", userPhoto, header.Size, header.Header.Get("Content-Type"))
-		if err != nil {
-			WriteError(w, NewInternalError("Ошибка загрузки фото"))
-			return
-		}
-		url := GetObjectURL(h.cfg.MinIOConfig, BucketVerificationDocs, objectKey)
-		verification.UserPhotoURL = &url
 	}
 
-	// Загружаем сканы паспорта
-	var passportScans []string
-	if files, ok := r.MultipartForm.File["passport_scans"]; ok {
-		if len(files) < 2 {
-			WriteError(w, NewValidationError("Необходимо загрузить минимум 2 страницы паспорта", nil))
+	// Привязка провайдера к аккаунту
+	if state.UserID != nil {
+		if identity != nil && identity.UserID != *state.UserID {
+			WriteError(w, NewConflictError("Этот аккаунт провайдера уже привязан к другому пользователю"))
 			return
 		}
-		for i, fileHeader := range files {
-			file, err := fileHeader.Open()
-			if err != nil {
-				WriteError(w, NewInternalError("Ошибка чтения файла"))
-				return
-			}
-			defer file.Close()
-
-			if err := ValidateImageFile(fileHeader); err != nil {
-				WriteError(w, err)
-				return
-			}
 
-			objectKey, err := UploadVerificationDoc(ctx, h.minioClient, 0, fmt.Sprintf("passport_scan_%d", i), file, fileHeader.Size, fileHeader.Header.Get("Content-Type"))
-			if err != nil {
-				WriteError(w, NewInternalError("Ошибка загрузки скана"))
-				return
-			}
-			url := GetObjectURL(h.cfg.MinIOConfig, BucketVerificationDocs, objectKey)
-			passportScans = append(passportScans, url)
+		linked, err := h.db.LinkUserIdentity(*state.UserID, provider.Name, oauthUser)
+		if err != nil {
+			WriteError(w, err)
+			return
 		}
-		verification.PassportScansURLs = passportScans
+		WriteJSON(w, http.StatusOK, linked)
+		return
 	}
 
-	// Создаем верификацию
-	if err := h.db.CreateVerification(verification); err != nil {
-		WriteError(w, err)
+	// Вход: аккаунты создаются по номеру телефона, провайдер только подтверждает личность
+	if identity == nil {
+		WriteError(w, NewNotFoundError("Аккаунт с привязанным провайдером"))
 		return
 	}
 
-	// Обновляем objectKey с правильным verification_id
-	if verification.UserPhotoURL != nil {
-		// Переименовываем файлы с правильным ID (упрощенная версия - в реальности нужно переименовать)
+	user, err := h.db.GetUserByID(identity.UserID)
+	if err != nil {
+		WriteError(w, err)
+		return
 	}
-
-	response := map[string]interface{}{
-		"id":           verification.ID,
-		"user_id":      verification.UserID,
-		"status":       verification.Status,
-		"submitted_at": verification.SubmittedAt,
-		"message":      "Заявка на верификацию подана",
+	if !user.IsActive {
+		WriteError(w, NewForbiddenError("Аккаунт деактивирован"))
+		return
 	}
-	WriteJSON(w, http.StatusCreated, response)
+
+	h.completeLogin(w, r, user)
 }
 
-// GetMyVerification получает статус верификации текущего пользователя
-// @Summary     Получить статус верификации
-// @Description Возвращает статус верификации текущего пользователя
-// @Tags        Верификация
+// ========== User Endpoints ==========
+
+// GetProfile получает профиль текущего пользователя
+// @Summary     Получить профиль
+// @Description Возвращает информацию о текущем пользователе
+// @Tags        Профиль
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Success     200  {object}  VerificationResponse
+// @Success     200  {object}  User
 // @Failure     401  {object}  ErrorResponse
-// @Failure     404  {object}  ErrorResponse
-// @Router      /verifications/me [get]
-func (h *Handlers) GetMyVerification(w http.ResponseWriter, r *http.Request) {
+// @Router      /users/me [get]
+func (h *Handlers) GetProfile(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	verification, err := h.db.GetVerificationByUserID(userID)
+	user, err := h.db.GetUserByID(userID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	response := map[string]interface{}{
-		"id":               verification.ID,
-		"user_id":          verification.UserID,
-		"status":           verification.Status,
-		"submitted_at":     verification.SubmittedAt,
-		"reviewed_at":      verification.ReviewedAt,
-		"rejection_reason": verification.RejectionReason,
+	user.PasswordHash = ""
+
+	// Преобразуем photo_url в URL через backend проксирование, если он есть
+	if user.PhotoURL != nil && *user.PhotoURL != "" {
+		backendURL := ConvertMinIOURLToBackendURL(*user.PhotoURL)
+		user.PhotoURL = &backendURL
 	}
-	WriteJSON(w, http.StatusOK, response)
+
+	user.Identities, err = h.db.GetUserIdentities(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, user)
+}
+
+// UpdateProfile обновляет профиль пользователя
+// @Summary     Обновить профиль
+// @Description Обновляет данные профиля текущего пользователя
+// @Tags        Профиль
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body UpdateProfileRequest true "Данные для обновления"
+// @Success     200  {object}  User
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me [patch]
+func (h *Handlers) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if req.Timezone != nil {
+		if _, err := LoadUserLocation(*req.Timezone); err != nil {
+			WriteError(w, NewValidationError("Неизвестный часовой пояс", map[string]interface{}{"field": "timezone"}))
+			return
+		}
+	}
+
+	if err := h.db.UpdateUser(userID, req.FirstName, req.LastName, req.HelperName, nil, req.Timezone); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	user, err := h.db.GetUserByID(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	user.PasswordHash = ""
+
+	// Преобразуем photo_url в URL через backend проксирование, если он есть
+	if user.PhotoURL != nil && *user.PhotoURL != "" {
+		backendURL := ConvertMinIOURLToBackendURL(*user.PhotoURL)
+		user.PhotoURL = &backendURL
+	}
+
+	WriteJSON(w, http.StatusOK, user)
+}
+
+// UploadPhoto загружает фото профиля
+// @Summary     Загрузить фото профиля
+// @Description Загружает фото профиля пользователя
+// @Tags        Профиль
+// @Accept      multipart/form-data
+// @Produce     json
+// @Security    BearerAuth
+// @Param       photo formData file true "Фото профиля (JPEG, PNG, max 5MB)"
+// @Success     200  {object}  PhotoUploadResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     413  {object}  ErrorResponse
+// @Router      /users/me/photo [post]
+func (h *Handlers) UploadPhoto(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := ParseMultipartForm(r, 5<<20); err != nil { // 5MB
+		WriteError(w, err)
+		return
+	}
+
+	file, header, err := GetFileFromForm(r, "photo")
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	defer file.Close()
+
+	if err := ValidateFileSize(header, 5<<20); err != nil { // 5MB
+		WriteError(w, err)
+		return
+	}
+
+	if err := ValidateImageFile(header); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	data, contentType, err := readImageUpload(file)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	objectKey, err := UploadUserPhoto(ctx, h.minioClient, userID, bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка загрузки фото"))
+		return
+	}
+
+	photoURL := GetObjectURL(h.cfg.MinIOConfig, BucketUserPhotos, objectKey)
+	if err := h.db.UpdateUser(userID, nil, nil, nil, &photoURL, nil); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	variants := h.uploadImageVariants(ctx, BucketUserPhotos, objectKey, data)
+	if err := h.db.UpdateUserPhotoVariants(userID, variants); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Преобразуем для ответа клиенту
+	backendURL := ConvertMinIOURLToBackendURL(photoURL)
+	ConvertImageVariantsToBackendURLs(variants)
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"photo_url": backendURL, "photo_variants": variants})
+}
+
+// ChangePassword изменяет пароль пользователя
+// @Summary     Изменить пароль
+// @Description Изменяет пароль текущего пользователя
+// @Tags        Профиль
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body ChangePasswordRequest true "Старый и новый пароль"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/change-password [post]
+func (h *Handlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	user, err := h.db.GetUserByID(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if !CheckPassword(req.OldPassword, user.PasswordHash) {
+		WriteError(w, NewUnauthorizedError("Неверный старый пароль"))
+		return
+	}
+
+	newPasswordHash, err := HashPassword(req.NewPassword)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка обработки пароля"))
+		return
+	}
+
+	if err := h.db.UpdateUserPassword(userID, newPasswordHash); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// После смены пароля завершаем все сессии пользователя, включая выданные access токены
+	if err := h.db.RevokeUserTokens(userID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, "Пароль успешно изменен")
+}
+
+// SetAway включает режим "нет на месте"
+// @Summary     Включить режим "нет на месте"
+// @Description Включает автоответ: он один раз отправляется в каждый чат, где пользователю пишут, пока режим действует.
+// @Description Статус показывается на постах пользователя и собеседникам в чатах. Без until режим действует до отключения.
+// @Tags        Профиль
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body SetAwayRequest true "Текст автоответа и время окончания"
+// @Success     200  {object}  AwayStatus
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/away [put]
+func (h *Handlers) SetAway(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req SetAwayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		WriteError(w, NewValidationError("Время окончания должно быть в будущем", map[string]interface{}{"field": "until"}))
+		return
+	}
+
+	if err := h.db.SetUserAway(userID, &req.Message, req.Until); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, AwayStatus{Message: req.Message, Until: req.Until})
+}
+
+// ClearAway выключает режим "нет на месте"
+// @Summary     Выключить режим "нет на месте"
+// @Description Выключает автоответ и статус на постах
+// @Tags        Профиль
+// @Produce     json
+// @Security    BearerAuth
+// @Success     204  "Режим выключен"
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/away [delete]
+func (h *Handlers) ClearAway(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := h.db.SetUserAway(userID, nil, nil); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAdminUsers получает список пользователей для поддержки
+// @Summary     Список пользователей
+// @Description Возвращает пользователей с поиском и фильтрами, новые первыми. q из цифр ищет по телефону, иначе по имени и фамилии.
+// @Tags        Пользователи
+// @Produce     json
+// @Security    BearerAuth
+// @Param       q query string false "Телефон или имя"
+// @Param       role query string false "Роль"
+// @Param       verification_status query string false "Статус верификации" Enums(none, pending, approved, rejected)
+// @Param       is_active query bool false "Активен ли аккаунт"
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(50)
+// @Success     200  {object}  map[string]interface{}
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/users [get]
+func (h *Handlers) GetAdminUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := AdminUserFilter{
+		Query:              strings.TrimSpace(query.Get("q")),
+		Role:               query.Get("role"),
+		VerificationStatus: query.Get("verification_status"),
+	}
+	switch filter.VerificationStatus {
+	case "", "none", "pending", "approved", "rejected":
+	default:
+		WriteError(w, NewValidationError("Неверный статус верификации", map[string]interface{}{"field": "verification_status"}))
+		return
+	}
+	if value := query.Get("is_active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			WriteError(w, NewValidationError("Неверное значение is_active", map[string]interface{}{"field": "is_active"}))
+			return
+		}
+		filter.IsActive = &active
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	users, total, err := h.db.GetAdminUsers(filter, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": users,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetAdminUser получает сводку по пользователю (только для админов)
+// @Summary     Сводка по пользователю
+// @Description Возвращает профиль, верификацию, последние посты, пожертвования, число чатов, активные сессии и историю входов пользователя
+// @Tags        Пользователи
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пользователя"
+// @Success     200  {object}  AdminUserDetailResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/users/{id} [get]
+func (h *Handlers) GetAdminUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пользователя", nil))
+		return
+	}
+
+	user, err := h.db.GetUserByID(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	user.PasswordHash = ""
+
+	response := AdminUserDetailResponse{User: user}
+
+	if verification, err := h.db.GetVerificationByUserID(userID); err == nil {
+		h.applyAgeCheck(verification)
+		response.Verification = verification
+	}
+
+	if response.Posts, response.PostsTotal, err = h.db.GetPosts("", &userID, "", 1, 20); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if response.DonationsGiven, response.DonationsReceived, err = h.db.GetUserDonationSummary(userID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if response.ChatsCount, err = h.db.CountUserChats(userID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if response.Sessions, err = h.db.GetActiveRefreshTokens(userID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if response.LoginHistory, err = h.db.GetLoginHistory(userID, 50); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if response.AdminActions, err = h.db.GetAdminActions(userID, 50); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// LinkIdentity начинает привязку провайдера к аккаунту
+// @Summary     Привязать провайдера входа
+// @Description Возвращает ссылку на страницу входа провайдера. После возврата от провайдера клиент вызывает callback с тем же access токеном, и учетная запись провайдера привязывается к текущему аккаунту.
+// @Tags        Профиль
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       provider path string true "Провайдер" Enums(vk, yandex, google)
+// @Success     200  {object}  OAuthAuthorizeResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/identities/{provider} [post]
+func (h *Handlers) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	h.startOAuth(w, mux.Vars(r)["provider"], &userID)
+}
+
+// UnlinkIdentity отвязывает провайдера от аккаунта
+// @Summary     Отвязать провайдера входа
+// @Description Удаляет привязку VK ID, Яндекс или Google. Вход по телефону остается доступен.
+// @Tags        Профиль
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       provider path string true "Провайдер" Enums(vk, yandex, google)
+// @Success     200  {object}  SuccessResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/identities/{provider} [delete]
+func (h *Handlers) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	deleted, err := h.db.DeleteUserIdentity(userID, mux.Vars(r)["provider"])
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if !deleted {
+		WriteError(w, NewNotFoundError("Привязанный провайдер"))
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, "Провайдер отвязан")
+}
+
+// BlockUser блокирует пользователя
+// @Summary     Заблокировать пользователя
+// @Description Деактивирует аккаунт и завершает его сессии. Причина обязательна: она сохраняется в журнале и отправляется пользователю.
+// @Tags        Пользователи
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пользователя"
+// @Param       request body BlockUserRequest true "Причина блокировки"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/users/{id}/block [post]
+func (h *Handlers) BlockUser(w http.ResponseWriter, r *http.Request) {
+	targetID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пользователя", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if targetID == userID {
+		WriteError(w, NewForbiddenError("Нельзя заблокировать собственный аккаунт"))
+		return
+	}
+
+	var req BlockUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := h.setUserActive(r.Context(), targetID, false, req.Reason); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, "Пользователь заблокирован")
+}
+
+// UnblockUser снимает блокировку с пользователя
+// @Summary     Разблокировать пользователя
+// @Description Снова активирует аккаунт. Действие сохраняется в журнале.
+// @Tags        Пользователи
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пользователя"
+// @Success     200  {object}  SuccessResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/users/{id}/block [delete]
+func (h *Handlers) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	targetID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пользователя", nil))
+		return
+	}
+
+	wasActive, err := h.db.SetUserActive(targetID, true)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:       AdminActionUserUnblock,
+		TargetType:   "user",
+		TargetID:     targetID,
+		TargetUserID: targetID,
+		OldValue:     map[string]interface{}{"is_active": wasActive},
+		NewValue:     map[string]interface{}{"is_active": true},
+	})
+
+	WriteSuccess(w, http.StatusOK, "Пользователь разблокирован")
+}
+
+// UpdateAdminUser деактивирует или снова активирует аккаунт
+// @Summary     Деактивировать или активировать пользователя
+// @Description Меняет is_active аккаунта. Причина обязательна: она сохраняется в журнале и отправляется пользователю.
+// @Description При деактивации сессии завершаются, а выданные access и API токены перестают приниматься сразу.
+// @Tags        Пользователи
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пользователя"
+// @Param       request body UpdateAdminUserRequest true "Новое состояние и причина"
+// @Success     200  {object}  User
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/users/{id} [patch]
+func (h *Handlers) UpdateAdminUser(w http.ResponseWriter, r *http.Request) {
+	targetID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пользователя", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req UpdateAdminUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if targetID == userID && !*req.IsActive {
+		WriteError(w, NewForbiddenError("Нельзя деактивировать собственный аккаунт"))
+		return
+	}
+
+	if err := h.setUserActive(r.Context(), targetID, *req.IsActive, req.Reason); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	user, err := h.db.GetUserByID(targetID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, user)
+}
+
+// setUserActive деактивирует или активирует аккаунт с причиной. При деактивации
+// refresh токены отзываются, а access токены отклоняет checkAccessToken.
+func (h *Handlers) setUserActive(ctx context.Context, targetID int64, active bool, reason string) error {
+	action := &adminAction{
+		Action:       AdminActionUserBlock,
+		TargetType:   "user",
+		TargetID:     targetID,
+		TargetUserID: targetID,
+		Reason:       reason,
+		NewValue:     map[string]interface{}{"is_active": active},
+		Notification: NotificationAccountBlocked,
+		Title:        "Аккаунт заблокирован",
+		Body:         "Ваш аккаунт заблокирован администратором.",
+	}
+	if active {
+		action.Action = AdminActionUserUnblock
+		action.Notification = NotificationAccountUnblocked
+		action.Title = "Аккаунт разблокирован"
+		action.Body = "Ваш аккаунт снова активен."
+	}
+
+	return h.performAdminAction(ctx, action, func() error {
+		wasActive, err := h.db.SetUserActive(targetID, active)
+		if err != nil {
+			return err
+		}
+		action.OldValue = map[string]interface{}{"is_active": wasActive}
+		if active {
+			return nil
+		}
+		return h.db.RevokeUserRefreshTokens(targetID)
+	})
+}
+
+// ========== Audit Log Endpoints ==========
+
+// GetAuditLog получает журнал действий администраторов и модераторов
+// @Summary     Журнал действий администраторов
+// @Description Возвращает действия администраторов и модераторов (блокировки, решения по верификациям, постам,
+// @Description пожертвованиям и лимитам, смена ролей и прав) со значениями до и после изменения, новые первыми.
+// @Tags        Пользователи
+// @Produce     json
+// @Security    BearerAuth
+// @Param       actor_id query int false "Кто выполнил действие"
+// @Param       action query string false "Действие, например user.block"
+// @Param       target_type query string false "Тип объекта" Enums(user, verification, post, donation, post_limit_request, role, risk_tier, invite_batch)
+// @Param       target_id query int false "ID объекта"
+// @Param       target_user_id query int false "Пользователь, которого касается действие"
+// @Param       from query string false "Начало периода, RFC 3339"
+// @Param       to query string false "Конец периода (не включая), RFC 3339"
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(50)
+// @Success     200  {object}  map[string]interface{}
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/audit-log [get]
+func (h *Handlers) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := AuditLogFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+	}
+	for param, dest := range map[string]*int64{
+		"actor_id":       &filter.ActorID,
+		"target_id":      &filter.TargetID,
+		"target_user_id": &filter.TargetUserID,
+	} {
+		if value := query.Get(param); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				WriteError(w, NewValidationError("Неверный ID", map[string]interface{}{"field": param}))
+				return
+			}
+			*dest = id
+		}
+	}
+	for param, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				WriteError(w, NewValidationError("Неверный формат даты, ожидается RFC 3339", map[string]interface{}{"field": param}))
+				return
+			}
+			*dest = &t
+		}
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	actions, total, err := h.db.GetAuditLog(filter, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": actions,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// ========== Digest Endpoints ==========
+
+// GetDigests получает архив еженедельных сводок
+// @Summary     Еженедельные сводки
+// @Description Возвращает сводки для администраторов, новые первыми: новые сборы, подтвержденные пожертвования, очередь
+// @Description верификации, сборы на модерации и чеки с расхождением за неделю, текст в том виде, в каком он был отправлен,
+// @Description и результат отправки каждому получателю (почта и Telegram).
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  map[string]interface{}
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/digests [get]
+func (h *Handlers) GetDigests(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	digests, total, err := h.db.GetDigests(page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": digests,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetDigest получает еженедельную сводку
+// @Summary     Еженедельная сводка
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID сводки"
+// @Success     200  {object}  Digest
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/digests/{id} [get]
+func (h *Handlers) GetDigest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID сводки", nil))
+		return
+	}
+
+	digest, err := h.db.GetDigest(id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, digest)
+}
+
+// ========== Dead Letter Endpoints ==========
+
+// GetDeadLetters получает очередь недоставленных
+// @Summary     Очередь недоставленных
+// @Description Возвращает фоновые задания, которые не удалось выполнить: рассылки уведомлений (не сохранены в базу
+// @Description или не поместились в очередь) и события интеграций и вебхуков партнеров после всех попыток доставки.
+// @Description Для каждого задания - ошибка, число попыток и ручных повторов, время, раньше которого повтор запрещен.
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       kind query string false "Вид задания" Enums(notification, webhook)
+// @Param       status query string false "Состояние" Enums(failed, retrying, resolved, discarded)
+// @Param       from query string false "Начало периода, RFC 3339"
+// @Param       to query string false "Конец периода (не включая), RFC 3339"
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  map[string]interface{}
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/dead-letters [get]
+func (h *Handlers) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := DeadLetterFilter{
+		Kind:   query.Get("kind"),
+		Status: query.Get("status"),
+	}
+	switch filter.Kind {
+	case "", DeadLetterNotification, DeadLetterWebhook:
+	default:
+		WriteError(w, NewValidationError("Неверный вид задания", map[string]interface{}{"field": "kind"}))
+		return
+	}
+	switch filter.Status {
+	case "", DeadLetterFailed, DeadLetterRetrying, DeadLetterResolved, DeadLetterDiscarded:
+	default:
+		WriteError(w, NewValidationError("Неверное состояние задания", map[string]interface{}{"field": "status"}))
+		return
+	}
+	for param, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				WriteError(w, NewValidationError("Неверный формат даты, ожидается RFC 3339", map[string]interface{}{"field": param}))
+				return
+			}
+			*dest = &t
+		}
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	letters, total, err := h.db.GetDeadLetters(filter, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": letters,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// RetryDeadLetter повторяет задание из очереди недоставленных
+// @Summary     Повторить задание
+// @Description Возвращает задание в очередь: рассылку - в очередь уведомлений (только пользователям, которым она не дошла),
+// @Description событие - в webhook_deliveries для одной попытки доставки. Пока задание выполняется, оно в состоянии retrying;
+// @Description при успехе переходит в resolved, при ошибке снова в failed. Следующий повтор разрешен не раньше next_retry_at
+// @Description (30с после первого повтора, затем пауза удваивается до 6 часов).
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID задания"
+// @Success     200  {object}  DeadLetter
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Failure     429  {object}  ErrorResponse
+// @Failure     503  {object}  ErrorResponse
+// @Router      /admin/dead-letters/{id}/retry [post]
+func (h *Handlers) RetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID задания", nil))
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	letter, err := h.db.GetDeadLetter(id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if letter.Status != DeadLetterFailed {
+		WriteError(w, NewConflictError("Задание уже повторяется или закрыто"))
+		return
+	}
+	if letter.NextRetryAt != nil && letter.NextRetryAt.After(time.Now()) {
+		WriteError(w, NewTooManyRequestsError("Повтор возможен после "+letter.NextRetryAt.UTC().Format(time.RFC3339)))
+		return
+	}
+
+	var job FanoutJob
+	if letter.Kind == DeadLetterNotification {
+		if err := json.Unmarshal(letter.Payload, &job); err != nil {
+			WriteError(w, NewUnprocessableError("Не удалось прочитать задание рассылки"))
+			return
+		}
+		job.DeadLetterID = letter.ID
+	}
+
+	marked, err := h.db.MarkDeadLetterRetrying(id, userID, time.Now().Add(webhookBackoff(letter.Retries+1)))
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if !marked {
+		WriteError(w, NewConflictError("Задание уже повторяется или закрыто"))
+		return
+	}
+
+	// Если задание не удалось поставить в очередь, оно возвращается в failed
+	fail := func(reason string) {
+		if err := h.db.RecordDeadLetter(&DeadLetter{ID: letter.ID, Payload: letter.Payload, Error: reason}); err != nil {
+			log.Printf("Failed to update dead letter %d: %v", letter.ID, err)
+		}
+	}
+	switch letter.Kind {
+	case DeadLetterNotification:
+		if !h.notifier.Enqueue(job) {
+			fail("notification queue is full")
+			WriteError(w, NewServiceUnavailableError("Очередь уведомлений заполнена, повторите позже"))
+			return
+		}
+	case DeadLetterWebhook:
+		requeued := false
+		if letter.ReferenceID != nil {
+			if requeued, err = h.db.RequeueWebhookDelivery(*letter.ReferenceID); err != nil {
+				fail(err.Error())
+				WriteError(w, err)
+				return
+			}
+		}
+		if !requeued {
+			fail("webhook delivery no longer exists")
+			WriteError(w, NewUnprocessableError("Событие удалено вместе с интеграцией или вебхуком, задание можно только закрыть"))
+			return
+		}
+	default:
+		fail("unknown dead letter kind")
+		WriteError(w, NewUnprocessableError("Неизвестный вид задания"))
+		return
+	}
+
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:     AdminActionDeadLetterRetry,
+		TargetType: "dead_letter",
+		TargetID:   letter.ID,
+		TargetName: letter.Kind,
+		OldValue:   map[string]interface{}{"status": letter.Status, "error": letter.Error},
+		NewValue:   map[string]interface{}{"status": DeadLetterRetrying, "retries": letter.Retries + 1},
+	})
+
+	updated, err := h.db.GetDeadLetter(id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, updated)
+}
+
+// DiscardDeadLetter закрывает задание без повтора
+// @Summary     Закрыть задание
+// @Description Отмечает задание из очереди недоставленных как discarded: оно больше не повторяется
+// @Description и не учитывается в /admin/ops. Событие в webhook_deliveries остается в состоянии failed.
+// @Tags        Утилиты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID задания"
+// @Success     200  {object}  DeadLetter
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /admin/dead-letters/{id}/discard [post]
+func (h *Handlers) DiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID задания", nil))
+		return
+	}
+
+	letter, err := h.db.GetDeadLetter(id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	discarded, err := h.db.DiscardDeadLetter(id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if !discarded {
+		WriteError(w, NewConflictError("Задание уже повторяется или закрыто"))
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:     AdminActionDeadLetterDiscard,
+		TargetType: "dead_letter",
+		TargetID:   letter.ID,
+		TargetName: letter.Kind,
+		OldValue:   map[string]interface{}{"status": letter.Status, "error": letter.Error},
+		NewValue:   map[string]interface{}{"status": DeadLetterDiscarded},
+	})
+
+	letter.Status = DeadLetterDiscarded
+	WriteJSON(w, http.StatusOK, letter)
+}
+
+// ========== Role Endpoints ==========
+
+// GetRoles получает роли и их права
+// @Summary     Получить роли
+// @Description Возвращает роли с назначенными правами и список всех доступных прав
+// @Tags        Роли
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  map[string]interface{}
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/roles [get]
+func (h *Handlers) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.db.GetRoles()
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data":        roles,
+		"permissions": AllPermissions,
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// SaveRole создает роль или заменяет ее права
+// @Summary     Создать или изменить роль
+// @Description Создает роль или полностью заменяет ее набор прав. Роль admin всегда имеет все права и не изменяется, роли moderator нельзя выдать roles.manage, donations.manage, users.block и limits.manage.
+// @Tags        Роли
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       name path string true "Имя роли"
+// @Param       request body SaveRoleRequest true "Описание и права роли"
+// @Success     200  {object}  Role
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/roles/{name} [put]
+func (h *Handlers) SaveRole(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if name == RoleAdmin {
+		WriteError(w, NewForbiddenError("Права роли admin не изменяются"))
+		return
+	}
+	if len(name) > 20 {
+		WriteError(w, NewValidationError("Слишком длинное имя роли", map[string]interface{}{"field": "name"}))
+		return
+	}
+
+	var req SaveRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	for _, permission := range req.Permissions {
+		if !IsKnownPermission(permission) {
+			WriteError(w, NewValidationError(fmt.Sprintf("Неизвестное право: %s", permission), map[string]interface{}{"field": "permissions"}))
+			return
+		}
+		if !CanGrant(name, permission) {
+			WriteError(w, NewForbiddenError(fmt.Sprintf("Право %s нельзя выдать роли %s", permission, name)))
+			return
+		}
+	}
+
+	roles, err := h.db.GetRoles()
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	var oldRole *Role
+	for i := range roles {
+		if roles[i].Name == name {
+			oldRole = &roles[i]
+		}
+	}
+
+	if err := h.db.SaveRole(name, req.Description, req.Permissions); err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.perms.Invalidate()
+
+	rec := AdminActionRecord{
+		Action:     AdminActionRoleSave,
+		TargetType: "role",
+		TargetName: name,
+		NewValue:   Role{Name: name, Description: req.Description, Permissions: req.Permissions},
+	}
+	if oldRole != nil {
+		rec.OldValue = oldRole
+	}
+	h.recordAdminAction(r.Context(), rec)
+
+	WriteJSON(w, http.StatusOK, Role{Name: name, Description: req.Description, Permissions: req.Permissions})
+}
+
+// UpdateUserRole меняет роль пользователя
+// @Summary     Сменить роль пользователя
+// @Description Назначает пользователю роль (admin, moderator, user или роль из /admin/roles). Новая роль действует со следующего запроса.
+// @Tags        Роли
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пользователя"
+// @Param       request body UpdateUserRoleRequest true "Новая роль"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/users/{id}/role [patch]
+func (h *Handlers) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	targetID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пользователя", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if targetID == userID {
+		WriteError(w, NewForbiddenError("Нельзя изменить собственную роль"))
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	exists, err := h.db.RoleExists(req.Role)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if !exists {
+		WriteError(w, NewValidationError("Роль не найдена", map[string]interface{}{"field": "role"}))
+		return
+	}
+
+	oldRole, err := h.db.UpdateUserRole(targetID, req.Role)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:       AdminActionUserRole,
+		TargetType:   "user",
+		TargetID:     targetID,
+		TargetUserID: targetID,
+		OldValue:     map[string]interface{}{"role": oldRole},
+		NewValue:     map[string]interface{}{"role": req.Role},
+	})
+
+	WriteSuccess(w, http.StatusOK, "Роль пользователя изменена")
+}
+
+// ========== Quota Endpoints ==========
+
+// GetMyQuota возвращает лимит запросов и квоту хранилища текущего клиента
+// @Summary     Лимиты запросов и квота хранилища
+// @Description Возвращает состояние лимита запросов в текущем окне (по API токену - лимит этого токена) и место, занятое медиа постов.
+// @Description Те же значения лимита приходят в заголовках X-RateLimit-Limit, X-RateLimit-Remaining и X-RateLimit-Reset каждого ответа.
+// @Description Доступно также по API токену с любой областью действия.
+// @Tags        Профиль
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  QuotaResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     429  {object}  ErrorResponse
+// @Router      /users/me/quota [get]
+func (h *Handlers) GetMyQuota(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	used, err := h.db.GetUserStorageUsed(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	response := QuotaResponse{
+		Storage: StorageUsage{UsedBytes: used, LimitBytes: h.cfg.StorageQuota},
+	}
+	if key, limit, _ := rateLimitKey(r, h.cfg.RateLimit); limit > 0 {
+		status := h.limiter.Status(key, limit)
+		response.RateLimit = &status
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// checkStorageQuota проверяет, что загрузка size байт не превысит квоту хранилища пользователя
+func (h *Handlers) checkStorageQuota(userID, size int64) error {
+	if h.cfg.StorageQuota <= 0 {
+		return nil
+	}
+	used, err := h.db.GetUserStorageUsed(userID)
+	if err != nil {
+		return err
+	}
+	if used+size > h.cfg.StorageQuota {
+		return NewUnprocessableError(fmt.Sprintf("Превышена квота хранилища: занято %d МБ из %d МБ", used>>20, h.cfg.StorageQuota>>20))
+	}
+	return nil
+}
+
+// ========== Referral Endpoints ==========
+
+// GetMyReferrals возвращает реферальную ссылку и приглашенных пользователей
+// @Summary     Реферальная программа
+// @Description Возвращает реферальный код и ссылку текущего пользователя (код создается при первом запросе)
+// @Description и приглашенных им пользователей. За первое подтвержденное пожертвование приглашенного
+// @Description оба получают бонусные баллы рейтинга. Бонус не начисляется, если пользователи входили
+// @Description с одного IP адреса или устройства (status rejected, reject_reason same_ip или same_device).
+// @Tags        Пользователи
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  ReferralDashboard
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/referrals [get]
+func (h *Handlers) GetMyReferrals(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	code, err := h.db.GetOrCreateReferralCode(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	referrals, err := h.db.GetReferrals(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	dashboard := ReferralDashboard{
+		Code:      code,
+		Link:      strings.ReplaceAll(h.cfg.Referrals.LinkURL, "{code}", url.QueryEscape(code)),
+		Invited:   len(referrals),
+		Referrals: referrals,
+	}
+	for _, referral := range referrals {
+		if referral.Status == ReferralRewarded {
+			dashboard.Rewarded++
+			dashboard.BonusPoints += referral.BonusPoints
+		}
+	}
+	WriteJSON(w, http.StatusOK, dashboard)
+}
+
+// ========== Post Limit Endpoints ==========
+
+// GetMyPostLimit возвращает лимит целевой суммы текущего пользователя
+// @Summary     Лимит целевой суммы
+// @Description Возвращает уровень доверия пользователя, максимальную целевую сумму поста и запросы на исключение.
+// @Description limit равен null, если лимит не действует.
+// @Tags        Лимиты
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  map[string]interface{}
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/post-limit [get]
+func (h *Handlers) GetMyPostLimit(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	limit, err := h.db.GetPostLimit(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	requests, err := h.db.GetUserPostLimitRequests(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if requests == nil {
+		requests = []PostLimitRequest{}
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"limit":    limit,
+		"requests": requests,
+	})
+}
+
+// CreatePostLimitRequest отправляет администраторам запрос на сбор сверх лимита
+// @Summary     Запросить исключение из лимита
+// @Description Создает запрос на сбор с целевой суммой больше лимита уровня доверия. Одновременно может рассматриваться только один запрос.
+// @Description Одобренное исключение действует на один пост с суммой не больше запрошенной.
+// @Tags        Лимиты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body CreatePostLimitRequestRequest true "Сумма и обоснование"
+// @Success     201  {object}  PostLimitRequest
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /users/me/post-limit/requests [post]
+func (h *Handlers) CreatePostLimitRequest(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req CreatePostLimitRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	limit, err := h.db.GetPostLimit(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if limit == nil || req.Amount <= limit.MaxAmount {
+		WriteError(w, NewValidationError("Сумма не превышает ваш лимит, исключение не требуется", map[string]interface{}{"field": "amount"}))
+		return
+	}
+
+	limitRequest := &PostLimitRequest{
+		UserID: userID,
+		Amount: req.Amount,
+		Reason: strings.TrimSpace(req.Reason),
+	}
+	if err := h.db.CreatePostLimitRequest(limitRequest); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, limitRequest)
+}
+
+// GetRiskTiers получает уровни доверия авторов
+// @Summary     Уровни доверия
+// @Description Возвращает уровни доверия авторов с лимитами целевой суммы. Уровни с min_completed_posts назначаются автоматически
+// @Description по числу завершенных сборов, уровни без него (organization) - только администратором.
+// @Tags        Лимиты
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  map[string]interface{}
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/risk-tiers [get]
+func (h *Handlers) GetRiskTiers(w http.ResponseWriter, r *http.Request) {
+	tiers, err := h.db.GetRiskTiers()
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if tiers == nil {
+		tiers = []RiskTier{}
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"data": tiers})
+}
+
+// SaveRiskTier создает или изменяет уровень доверия
+// @Summary     Создать или изменить уровень доверия
+// @Description Задает максимальную целевую сумму поста для уровня доверия и порог автоматического назначения
+// @Tags        Лимиты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       name path string true "Название уровня"
+// @Param       request body SaveRiskTierRequest true "Лимит и порог"
+// @Success     200  {object}  RiskTier
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/risk-tiers/{name} [put]
+func (h *Handlers) SaveRiskTier(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if len(name) > 20 {
+		WriteError(w, NewValidationError("Слишком длинное название уровня", map[string]interface{}{"field": "name"}))
+		return
+	}
+
+	var req SaveRiskTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	tier := RiskTier{
+		Name:              name,
+		Description:       req.Description,
+		MaxPostAmount:     req.MaxPostAmount,
+		MinCompletedPosts: req.MinCompletedPosts,
+	}
+	tiers, err := h.db.GetRiskTiers()
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	var oldTier *RiskTier
+	for i := range tiers {
+		if tiers[i].Name == name {
+			oldTier = &tiers[i]
+		}
+	}
+
+	if err := h.db.SaveRiskTier(tier); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	rec := AdminActionRecord{
+		Action:     AdminActionRiskTierSave,
+		TargetType: "risk_tier",
+		TargetName: name,
+		NewValue:   tier,
+	}
+	if oldTier != nil {
+		rec.OldValue = oldTier
+	}
+	h.recordAdminAction(r.Context(), rec)
+
+	WriteJSON(w, http.StatusOK, tier)
+}
+
+// UpdateUserRiskTier назначает пользователю уровень доверия
+// @Summary     Назначить уровень доверия
+// @Description Назначает пользователю уровень доверия (например, organization). tier = null возвращает автоматический выбор по завершенным сборам.
+// @Tags        Лимиты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пользователя"
+// @Param       request body UpdateUserRiskTierRequest true "Уровень доверия"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/users/{id}/risk-tier [patch]
+func (h *Handlers) UpdateUserRiskTier(w http.ResponseWriter, r *http.Request) {
+	targetID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пользователя", nil))
+		return
+	}
+
+	var req UpdateUserRiskTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Tier != nil {
+		exists, err := h.db.RiskTierExists(*req.Tier)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if !exists {
+			WriteError(w, NewValidationError(fmt.Sprintf("Неизвестный уровень доверия: %s", *req.Tier), map[string]interface{}{"field": "tier"}))
+			return
+		}
+	}
+
+	oldTier, err := h.db.SetUserRiskTier(targetID, req.Tier)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:       AdminActionUserRiskTier,
+		TargetType:   "user",
+		TargetID:     targetID,
+		TargetUserID: targetID,
+		OldValue:     map[string]interface{}{"risk_tier": oldTier},
+		NewValue:     map[string]interface{}{"risk_tier": req.Tier},
+	})
+
+	WriteSuccess(w, http.StatusOK, "Уровень доверия обновлен")
+}
+
+// GetPostLimitRequests получает запросы на исключение из лимита
+// @Summary     Запросы на исключение из лимита
+// @Description Возвращает запросы авторов на сбор сверх лимита, по умолчанию ожидающие рассмотрения
+// @Tags        Лимиты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       status query string false "Статус запроса" Enums(pending, approved, rejected) default(pending)
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  map[string]interface{}
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/post-limit-requests [get]
+func (h *Handlers) GetPostLimitRequests(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = PostLimitRequestPending
+	case PostLimitRequestPending, PostLimitRequestApproved, PostLimitRequestRejected:
+	default:
+		WriteError(w, NewValidationError("Неверный статус запроса", map[string]interface{}{"field": "status"}))
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	requests, total, err := h.db.GetPostLimitRequests(status, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if requests == nil {
+		requests = []PostLimitRequest{}
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": requests,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// ReviewPostLimitRequest одобряет или отклоняет запрос на исключение из лимита
+// @Summary     Решение по запросу на исключение
+// @Description Одобряет или отклоняет запрос автора на сбор сверх лимита. Автор получает уведомление с комментарием.
+// @Tags        Лимиты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID запроса"
+// @Param       request body ReviewPostLimitRequestRequest true "Решение"
+// @Success     200  {object}  PostLimitRequest
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /admin/post-limit-requests/{id} [patch]
+func (h *Handlers) ReviewPostLimitRequest(w http.ResponseWriter, r *http.Request) {
+	requestID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID запроса", nil))
+		return
+	}
+
+	reviewerID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req ReviewPostLimitRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	limitRequest, err := h.db.ReviewPostLimitRequest(requestID, reviewerID, req.Status, req.Comment)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:       AdminActionPostLimitReview,
+		TargetType:   "post_limit_request",
+		TargetID:     requestID,
+		TargetUserID: limitRequest.UserID,
+		OldValue:     map[string]interface{}{"status": PostLimitRequestPending},
+		NewValue:     map[string]interface{}{"status": req.Status, "comment": req.Comment},
+	})
+
+	title := "Исключение из лимита одобрено"
+	body := fmt.Sprintf("Можно создать сбор на сумму до %s руб.", formatAmount(limitRequest.Amount))
+	if req.Status == PostLimitRequestRejected {
+		title = "Исключение из лимита отклонено"
+		body = fmt.Sprintf("Запрос на сбор %s руб. отклонен.", formatAmount(limitRequest.Amount))
+	}
+	if req.Comment != nil && *req.Comment != "" {
+		body += " Комментарий: " + *req.Comment
+	}
+	h.notifier.Enqueue(FanoutJob{
+		Type:    NotificationPostLimitReviewed,
+		Title:   title,
+		Body:    body,
+		UserIDs: []int64{limitRequest.UserID},
+	})
+
+	WriteJSON(w, http.StatusOK, limitRequest)
+}
+
+// ========== Invite Endpoints ==========
+
+// CreateInviteCodes выпускает партию кодов приглашения
+// @Summary     Выпустить коды приглашения
+// @Description Создает count кодов партии batch. По каждому коду можно зарегистрироваться max_uses раз до expires_at.
+// @Tags        Приглашения
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body CreateInviteCodesRequest true "Параметры партии"
+// @Success     201  {object}  map[string]interface{}
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/invites [post]
+func (h *Handlers) CreateInviteCodes(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req CreateInviteCodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	req.Batch = strings.TrimSpace(req.Batch)
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		WriteError(w, NewValidationError("Срок действия должен быть в будущем", map[string]interface{}{"field": "expires_at"}))
+		return
+	}
+
+	codes := make([]string, 0, req.Count)
+	seen := make(map[string]bool, req.Count)
+	for len(codes) < req.Count {
+		code, err := GenerateInviteCode()
+		if err != nil {
+			WriteError(w, NewInternalError("Ошибка генерации кода"))
+			return
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+
+	created, err := h.db.CreateInviteCodes(req.Batch, codes, req.MaxUses, req.ExpiresAt, userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:     AdminActionInvitesCreate,
+		TargetType: "invite_batch",
+		TargetName: req.Batch,
+		NewValue:   map[string]interface{}{"count": req.Count, "max_uses": req.MaxUses, "expires_at": req.ExpiresAt},
+	})
+	WriteJSON(w, http.StatusCreated, map[string]interface{}{"data": created})
+}
+
+// GetInviteCodes получает коды приглашения и число регистраций по ним
+// @Summary     Коды приглашения
+// @Description Возвращает коды приглашения с числом регистраций по каждому, новые первыми
+// @Tags        Приглашения
+// @Produce     json
+// @Security    BearerAuth
+// @Param       batch query string false "Партия"
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  map[string]interface{}
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/invites [get]
+func (h *Handlers) GetInviteCodes(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	codes, total, err := h.db.GetInviteCodes(r.URL.Query().Get("batch"), page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if codes == nil {
+		codes = []InviteCode{}
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": codes,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetInvitedUsers получает пользователей, зарегистрированных по коду приглашения
+// @Summary     Пользователи по коду приглашения
+// @Description Возвращает пользователей, зарегистрированных по коду, в порядке регистрации
+// @Tags        Приглашения
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID кода приглашения"
+// @Success     200  {array}   InvitedUser
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/invites/{id}/users [get]
+func (h *Handlers) GetInvitedUsers(w http.ResponseWriter, r *http.Request) {
+	codeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID кода приглашения", nil))
+		return
+	}
+
+	users, err := h.db.GetInvitedUsers(codeID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, users)
+}
+
+// ========== Points Event Endpoints ==========
+
+// CreatePointsEvent создает акцию с повышенным начислением баллов
+// @Summary     Создать акцию с множителем баллов
+// @Description Планирует акцию (например, «двойные баллы на выходных»): за пожертвования, созданные с starts_at до ends_at,
+// @Description при подтверждении начисляется в multiplier раз больше баллов рейтинга. При пересечении акций действует наибольший множитель.
+// @Tags        Рейтинг
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body CreatePointsEventRequest true "Название, множитель и период"
+// @Success     201  {object}  PointsEvent
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/points-events [post]
+func (h *Handlers) CreatePointsEvent(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req CreatePointsEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		WriteError(w, NewValidationError("Акция должна заканчиваться позже начала", map[string]interface{}{"field": "ends_at"}))
+		return
+	}
+	if !req.EndsAt.After(time.Now()) {
+		WriteError(w, NewValidationError("Акция должна заканчиваться в будущем", map[string]interface{}{"field": "ends_at"}))
+		return
+	}
+
+	event := &PointsEvent{
+		Title:      req.Title,
+		Multiplier: math.Round(req.Multiplier*100) / 100,
+		StartsAt:   req.StartsAt,
+		EndsAt:     req.EndsAt,
+		CreatedBy:  &userID,
+	}
+	if err := h.db.CreatePointsEvent(event); err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:     AdminActionPointsEventCreate,
+		TargetType: "points_event",
+		TargetID:   event.ID,
+		TargetName: event.Title,
+		NewValue:   map[string]interface{}{"multiplier": event.Multiplier, "starts_at": event.StartsAt, "ends_at": event.EndsAt},
+	})
+	WriteJSON(w, http.StatusCreated, event)
+}
+
+// GetPointsEvents получает все акции с множителем баллов
+// @Summary     Акции с множителем баллов
+// @Description Возвращает прошедшие, идущие и запланированные акции, последние по началу первыми
+// @Tags        Рейтинг
+// @Produce     json
+// @Security    BearerAuth
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  map[string]interface{}
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/points-events [get]
+func (h *Handlers) GetPointsEvents(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	events, total, err := h.db.GetPointsEvents(page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": events,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// CancelPointsEvent отменяет акцию с множителем баллов
+// @Summary     Отменить акцию
+// @Description Запланированная акция удаляется, идущая завершается сейчас: пожертвования, созданные во время акции, сохраняют множитель.
+// @Tags        Рейтинг
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID акции"
+// @Success     200  {object}  PointsEvent
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /admin/points-events/{id} [delete]
+func (h *Handlers) CancelPointsEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID акции", nil))
+		return
+	}
+
+	event, err := h.db.CancelPointsEvent(id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:     AdminActionPointsEventCancel,
+		TargetType: "points_event",
+		TargetID:   event.ID,
+		TargetName: event.Title,
+		NewValue:   map[string]interface{}{"ends_at": event.EndsAt},
+	})
+	WriteJSON(w, http.StatusOK, event)
+}
+
+// GetActivePointsEvents получает идущие и ближайшие акции для баннеров
+// @Summary     Текущие акции с множителем баллов
+// @Description Возвращает идущие и запланированные акции, ближайшие первыми. active = true, если акция идет сейчас.
+// @Tags        Рейтинг
+// @Produce     json
+// @Success     200  {object}  map[string]interface{}
+// @Router      /points-events [get]
+func (h *Handlers) GetActivePointsEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.db.GetCurrentPointsEvents()
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	now := time.Now()
+	data := make([]map[string]interface{}, 0, len(events))
+	for _, e := range events {
+		data = append(data, map[string]interface{}{
+			"id":         e.ID,
+			"title":      e.Title,
+			"multiplier": e.Multiplier,
+			"starts_at":  e.StartsAt,
+			"ends_at":    e.EndsAt,
+			"active":     !e.StartsAt.After(now),
+		})
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+// ========== Verification Endpoints ==========
+
+// CreateVerification создает заявку на верификацию
+// @Summary     Подать заявку на верификацию
+// @Description Создает заявку на верификацию пользователя. Состав полей документа зависит от страны и типа документа,
+// @Description см. GET /verifications/document-types: поля передаются под своими именами (passport_series, inn, iin...).
+// @Tags        Верификация
+// @Accept      multipart/form-data
+// @Produce     json
+// @Security    BearerAuth
+// @Param       user_photo formData file true "Фото пользователя"
+// @Param       last_name formData string true "Фамилия"
+// @Param       first_name formData string true "Имя"
+// @Param       middle_name formData string false "Отчество"
+// @Param       birth_date formData string true "Дата рождения (YYYY-MM-DD)"
+// @Param       country formData string false "Страна документа (ISO 3166-1 alpha-2)" default(RU)
+// @Param       document_type formData string false "Удостоверение личности из справочника" default(ru_passport)
+// @Param       passport_series formData string false "Серия паспорта"
+// @Param       passport_number formData string false "Номер документа"
+// @Param       passport_issuer formData string false "Кем выдан"
+// @Param       passport_date formData string false "Дата выдачи (YYYY-MM-DD)"
+// @Param       doc_type formData string false "Дополнительный документ, если страна его требует (для RU - inn или snils)"
+// @Param       inn formData string false "ИНН"
+// @Param       snils formData string false "СНИЛС"
+// @Param       organization_inn formData string false "ИНН организации (10 цифр), от имени которой собирает пользователь"
+// @Param       organization_ogrn formData string false "ОГРН организации, без него заполняется из реестра"
+// @Param       organization_name formData string false "Название организации, без него заполняется из реестра"
+// @Param       passport_scans formData file true "Сканы документа (не меньше min_scans из справочника)"
+// @Param       consent1 formData bool true "Согласие 1"
+// @Param       consent2 formData bool true "Согласие 2"
+// @Param       consent3 formData bool true "Согласие 3"
+// @Success     201  {object}  VerificationResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /verifications [post]
+func (h *Handlers) CreateVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Проверяем, нет ли уже верификации
+	existing, _ := h.db.GetVerificationByUserID(userID)
+	if existing != nil {
+		WriteError(w, NewConflictError("Заявка на верификацию уже подана"))
+		return
+	}
+
+	if err := ParseMultipartForm(r, 50<<20); err != nil { // 50MB для всех файлов
+		WriteError(w, err)
+		return
+	}
+
+	var req VerificationRequest
+	req.LastName = r.FormValue("last_name")
+	req.FirstName = r.FormValue("first_name")
+	req.MiddleName = getStringPtr(r.FormValue("middle_name"))
+	req.BirthDate = r.FormValue("birth_date")
+	req.Country = strings.ToUpper(r.FormValue("country"))
+	req.DocumentType = r.FormValue("document_type")
+	req.DocType = r.FormValue("doc_type")
+	req.Consent1 = r.FormValue("consent1") == "true"
+	req.Consent2 = r.FormValue("consent2") == "true"
+	req.Consent3 = r.FormValue("consent3") == "true"
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	birthDate, err := time.Parse("2006-01-02", req.BirthDate)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный формат даты рождения", map[string]interface{}{"field": "birth_date"}))
+		return
+	}
+
+	if birthDate.After(time.Now()) || AgeAt(birthDate, time.Now()) < h.cfg.Age.MinVerificationAge {
+		WriteError(w, NewValidationError(fmt.Sprintf("Верификация доступна с %d лет", h.cfg.Age.MinVerificationAge), map[string]interface{}{"field": "birth_date"}))
+		return
+	}
+
+	// Заявки без страны и типа документа подаются по паспорту РФ, как до появления справочника
+	if req.Country == "" {
+		req.Country = DefaultDocumentCountry
+	}
+	country, ok := FindDocumentCountry(req.Country)
+	if !ok {
+		WriteError(w, NewValidationError("Документы этой страны не принимаются", map[string]interface{}{"field": "country"}))
+		return
+	}
+	if req.DocumentType == "" && len(country.IdentityDocuments) == 1 {
+		req.DocumentType = country.IdentityDocuments[0].Code
+	}
+	identity, ok := findDocumentType(country.IdentityDocuments, req.DocumentType)
+	if !ok {
+		WriteError(w, NewValidationError("Неверный тип документа", map[string]interface{}{"field": "document_type"}))
+		return
+	}
+
+	verification := &Verification{
+		UserID:       userID,
+		LastName:     req.LastName,
+		FirstName:    req.FirstName,
+		MiddleName:   req.MiddleName,
+		BirthDate:    birthDate,
+		Country:      country.Code,
+		DocumentType: identity.Code,
+		Consent1:     req.Consent1,
+		Consent2:     req.Consent2,
+		Consent3:     req.Consent3,
+	}
+	if err := identity.ApplyDocument(verification, r.FormValue); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if len(country.TaxDocuments) > 0 {
+		tax, ok := findDocumentType(country.TaxDocuments, req.DocType)
+		if !ok {
+			WriteError(w, NewValidationError("Неверный тип дополнительного документа", map[string]interface{}{"field": "doc_type"}))
+			return
+		}
+		if err := tax.ApplyDocument(verification, r.FormValue); err != nil {
+			WriteError(w, err)
+			return
+		}
+		verification.DocType = tax.Code
+	}
+
+	if err := ApplyOrganization(verification, r.FormValue); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if verification.OrganizationINN != nil && h.registry != nil {
+		check := OrganizationCheckPending
+		verification.OrganizationCheck = &check
+	}
+
+	ctx := r.Context()
+
+	// Загружаем фото пользователя
+	if userPhoto, header, err := r.FormFile("user_photo"); err == nil {
+		defer userPhoto.Close()
+		if err := ValidateImageFile(header); err != nil {
+			WriteError(w, err)
+			return
+		}
+		if err := h.scanUpload(ctx, "verification", userID, userPhoto, header); err != nil {
+			WriteError(w, err)
+			return
+		}
+		data, contentType, err := readImageUpload(userPhoto)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		objectKey, err := UploadVerificationDoc(ctx, h.minioClient, 0, "user_photo", bytes.NewReader(data), int64(len(data)), contentType)
+		if err != nil {
+			WriteError(w, NewInternalError("Ошибка загрузки фото"))
+			return
+		}
+		url := GetObjectURL(h.cfg.MinIOConfig, BucketVerificationDocs, objectKey)
+		verification.UserPhotoURL = &url
+	}
+
+	// Загружаем сканы документа
+	var passportScans []string
+	if files, ok := r.MultipartForm.File["passport_scans"]; ok {
+		if len(files) < identity.MinScans {
+			WriteError(w, NewValidationError(fmt.Sprintf("Необходимо загрузить минимум %d страницы документа", identity.MinScans), nil))
+			return
+		}
+		for i, fileHeader := range files {
+			file, err := fileHeader.Open()
+			if err != nil {
+				WriteError(w, NewInternalError("Ошибка чтения файла"))
+				return
+			}
+			defer file.Close()
+
+			if err := ValidateImageFile(fileHeader); err != nil {
+				WriteError(w, err)
+				return
+			}
+			if err := h.scanUpload(ctx, "verification", userID, file, fileHeader); err != nil {
+				WriteError(w, err)
+				return
+			}
+
+			data, contentType, err := readImageUpload(file)
+			if err != nil {
+				WriteError(w, err)
+				return
+			}
+
+			objectKey, err := UploadVerificationDoc(ctx, h.minioClient, 0, fmt.Sprintf("passport_scan_%d", i), bytes.NewReader(data), int64(len(data)), contentType)
+			if err != nil {
+				WriteError(w, NewInternalError("Ошибка загрузки скана"))
+				return
+			}
+			url := GetObjectURL(h.cfg.MinIOConfig, BucketVerificationDocs, objectKey)
+			passportScans = append(passportScans, url)
+		}
+		verification.PassportScansURLs = passportScans
+	}
+
+	// Создаем верификацию
+	if err := h.db.CreateVerification(verification); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Обновляем objectKey с правильным verification_id
+	if verification.UserPhotoURL != nil {
+		// Переименовываем файлы с правильным ID (упрощенная версия - в реальности нужно переименовать)
+	}
+
+	response := map[string]interface{}{
+		"id":           verification.ID,
+		"user_id":      verification.UserID,
+		"status":       verification.Status,
+		"submitted_at": verification.SubmittedAt,
+		"message":      "Заявка на верификацию подана",
+	}
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+// GetDocumentTypes получает справочник документов верификации
+// @Summary     Документы для верификации
+// @Description Возвращает страны и документы, по которым принимаются заявки на верификацию, с полями формы: имя, подпись, тип (text, code, date), обязательность и формат номера. По справочнику строятся форма заявки и карточка заявки у проверяющего.
+// @Tags        Верификация
+// @Produce     json
+// @Success     200  {array}  DocumentCountry
+// @Router      /verifications/document-types [get]
+func (h *Handlers) GetDocumentTypes(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, DocumentCountries)
+}
+
+// LookupOrganization ищет организацию в реестре по ИНН
+// @Summary     Найти организацию в реестре
+// @Description Возвращает сведения реестра юридических лиц по ИНН организации (10 цифр) для заполнения формы верификации:
+// @Description ОГРН, название, адрес, состояние и признак некоммерческой организации. Ответы реестра кэшируются.
+// @Tags        Верификация
+// @Produce     json
+// @Security    BearerAuth
+// @Param       inn  path      string  true  "ИНН организации"
+// @Success     200  {object}  RegistryOrganization
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     503  {object}  ErrorResponse
+// @Router      /verifications/organizations/{inn} [get]
+func (h *Handlers) LookupOrganization(w http.ResponseWriter, r *http.Request) {
+	inn := mux.Vars(r)["inn"]
+	if !organizationINNPattern.MatchString(inn) || !validOrgINN(inn) {
+		WriteError(w, NewValidationError("Неверный ИНН организации", nil))
+		return
+	}
+	if h.registry == nil {
+		WriteError(w, NewServiceUnavailableError("Поиск в реестре организаций не настроен"))
+		return
+	}
+
+	org, err := h.registry.Lookup(r.Context(), inn)
+	if err != nil {
+		log.Printf("Failed to look up organization %s: %v", inn, err)
+		WriteError(w, NewServiceUnavailableError("Реестр организаций недоступен"))
+		return
+	}
+	if org == nil {
+		WriteError(w, NewNotFoundError("Организация"))
+		return
+	}
+	WriteJSON(w, http.StatusOK, org)
+}
+
+// GetMyVerification получает статус верификации текущего пользователя
+// @Summary     Получить статус верификации
+// @Description Возвращает статус верификации текущего пользователя
+// @Tags        Верификация
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  VerificationResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /verifications/me [get]
+func (h *Handlers) GetMyVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	verification, err := h.db.GetVerificationByUserID(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	h.applyAgeCheck(verification)
+	response := map[string]interface{}{
+		"id":                    verification.ID,
+		"user_id":               verification.UserID,
+		"status":                verification.Status,
+		"submitted_at":          verification.SubmittedAt,
+		"reviewed_at":           verification.ReviewedAt,
+		"rejection_reason":      verification.RejectionReason,
+		"meets_age_requirement": verification.MeetsAgeRequirement,
+	}
+	WriteJSON(w, http.StatusOK, response)
 }
 
 // GetVerifications получает список заявок на верификацию (только для админов)
 // @Summary     Получить список заявок на верификацию
-// @Description Возвращает список всех заявок на верификацию с пагинацией
+// @Description Возвращает список всех заявок на верификацию с пагинацией. У заявок с организацией - ИНН и результат сверки с реестром.
+// @Tags        Верификация
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       status query string false "Фильтр по статусу" Enums(pending, approved, rejected)
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  VerificationsListResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /verifications [get]
+func (h *Handlers) GetVerifications(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	verifications, total, err := h.db.GetVerifications(status, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	for i := range verifications {
+		h.applyAgeCheck(&verifications[i])
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": verifications,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetVerification получает заявку на верификацию для проверки
+// @Summary     Получить заявку на верификацию
+// @Description Возвращает заявку: паспортные данные и результат проверки возраста. Ссылок на сканы и фото в ответе нет:
+// @Description их выдает GET /verifications/{id}/documents с правом verifications.documents и записью в журнал.
+// @Description organization_check - результат сверки организации заявителя с реестром (pending, matched, mismatch, not_found),
+// @Description organization_mismatches - расхождения (name, ogrn, inactive, not_nonprofit), organization_registry - сведения реестра.
+// @Tags        Верификация
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID верификации"
+// @Success     200  {object}  Verification
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /verifications/{id} [get]
+func (h *Handlers) GetVerification(w http.ResponseWriter, r *http.Request) {
+	verificationID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID верификации", nil))
+		return
+	}
+
+	verification, err := h.db.GetVerificationByID(verificationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	h.applyAgeCheck(verification)
+	// Сканы видит только право verifications.documents через журналируемый GetVerificationDocuments
+	verification.PassportScansURLs, verification.UserPhotoURL = nil, nil
+
+	WriteJSON(w, http.StatusOK, verification)
+}
+
+// verificationDocumentURLTTL время жизни ссылок на документы верификации
+const verificationDocumentURLTTL = 5 * time.Minute
+
+// GetVerificationDocuments выдает ссылки на документы заявки на верификацию
+// @Summary     Документы заявки на верификацию
+// @Description Возвращает presigned URL сканов паспорта и фото пользователя, действующие 5 минут.
+// @Description Доступно с правом verifications.documents (по умолчанию только admin). Каждый запрос записывается в журнал admin_actions.
+// @Tags        Верификация
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID верификации"
+// @Success     200  {object}  VerificationDocumentsResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /verifications/{id}/documents [get]
+func (h *Handlers) GetVerificationDocuments(w http.ResponseWriter, r *http.Request) {
+	verificationID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID верификации", nil))
+		return
+	}
+
+	verification, err := h.db.GetVerificationByID(verificationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	type document struct{ docType, objectURL string }
+	var documents []document
+	for _, scanURL := range verification.PassportScansURLs {
+		documents = append(documents, document{VerificationDocumentPassportScan, scanURL})
+	}
+	if verification.UserPhotoURL != nil && *verification.UserPhotoURL != "" {
+		documents = append(documents, document{VerificationDocumentUserPhoto, *verification.UserPhotoURL})
+	}
+
+	response := VerificationDocumentsResponse{
+		VerificationID: verification.ID,
+		Documents:      []VerificationDocument{},
+		ExpiresAt:      time.Now().Add(verificationDocumentURLTTL),
+	}
+	for _, doc := range documents {
+		objectKey, ok := ObjectKeyFromURL(doc.objectURL, BucketVerificationDocs)
+		if !ok {
+			log.Printf("Verification %d: unexpected document URL", verification.ID)
+			continue
+		}
+		presignedURL, err := GeneratePresignedGetURL(r.Context(), h.minioClient, BucketVerificationDocs, objectKey, verificationDocumentURLTTL)
+		if err != nil {
+			WriteError(w, NewInternalError("Ошибка генерации URL"))
+			return
+		}
+		response.Documents = append(response.Documents, VerificationDocument{Type: doc.docType, URL: presignedURL})
+	}
+
+	// Журнал доступа к паспортным данным: кто и когда получил ссылки
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:       AdminActionVerificationDocumentsView,
+		TargetType:   "verification",
+		TargetID:     verification.ID,
+		TargetUserID: verification.UserID,
+	})
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// UpdateVerification обновляет статус верификации (только для админов)
+// @Summary     Одобрить/отклонить верификацию
+// @Description Обновляет статус верификации (одобрить или отклонить). При отклонении обязательна rejection_reason: она сохраняется в журнале и отправляется пользователю.
 // @Tags        Верификация
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       status query string false "Фильтр по статусу" Enums(pending, approved, rejected)
-// @Param       page query int false "Номер страницы" default(1)
-// @Param       limit query int false "Количество на странице" default(20)
-// @Success     200  {object}  VerificationsListResponse
+// @Param       id path int true "ID верификации"
+// @Param       request body UpdateVerificationRequest true "Статус и причина отклонения"
+// @Success     200  {object}  VerificationResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /verifications/{id} [patch]
+func (h *Handlers) UpdateVerification(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	verificationID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID верификации", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req UpdateVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	verification, err := h.db.GetVerificationByID(verificationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	update := func() error {
+		return h.db.UpdateVerificationStatus(verificationID, req.Status, userID, req.RejectionReason)
+	}
+	oldValue := map[string]interface{}{"status": verification.Status}
+	newValue := map[string]interface{}{"status": req.Status}
+	if req.Status == "rejected" {
+		reason := ""
+		if req.RejectionReason != nil {
+			reason = *req.RejectionReason
+		}
+		err = h.performAdminAction(r.Context(), &adminAction{
+			Action:       AdminActionVerificationReject,
+			TargetType:   "verification",
+			TargetID:     verificationID,
+			TargetUserID: verification.UserID,
+			Reason:       reason,
+			OldValue:     oldValue,
+			NewValue:     newValue,
+			Notification: NotificationVerificationRejected,
+			Title:        "Верификация отклонена",
+			Body:         "Ваша заявка на верификацию отклонена. Исправьте данные и отправьте ее повторно.",
+		}, update)
+	} else if err = update(); err == nil {
+		h.recordAdminAction(r.Context(), AdminActionRecord{
+			Action:       AdminActionVerificationApprove,
+			TargetType:   "verification",
+			TargetID:     verificationID,
+			TargetUserID: verification.UserID,
+			OldValue:     oldValue,
+			NewValue:     newValue,
+		})
+	}
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	verification, err = h.db.GetVerificationByID(verificationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	event := WebhookEventVerificationApproved
+	if verification.Status == "rejected" {
+		event = WebhookEventVerificationRejected
+	}
+	emitPartnerWebhook(h.db, event, map[string]interface{}{
+		"verification_id": verification.ID,
+		"user_id":         verification.UserID,
+		"status":          verification.Status,
+		"reviewed_at":     verification.ReviewedAt,
+	})
+
+	response := map[string]interface{}{
+		"id":          verification.ID,
+		"status":      verification.Status,
+		"reviewed_at": verification.ReviewedAt,
+		"reviewed_by": verification.ReviewedBy,
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// ========== Post Endpoints ==========
+
+// GetPosts получает список постов
+// @Summary     Получить список постов
+// @Description Возвращает список постов с пагинацией и фильтрацией
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Param       status query string false "Фильтр по статусу" Enums(active, completed, closed, moderated)
+// @Param       user_id query int false "Фильтр по автору"
+// @Param       q query string false "Поиск по заголовку и описанию"
+// @Param       language query string false "Фильтр по языку поста" Enums(ru, uk, be, kk, en, de, fr, es)
+// @Param       deadline_before query string false "Посты со сроком не позже даты (RFC 3339)"
+// @Param       sort query string false "Порядок: newest - новые, urgent - срочные и новые (по умолчанию без поиска), most_collected - больше всего собрано, least_donated - меньше всего собрано, closest_to_goal - ближе всего к цели, deadline - ближайший срок" Enums(newest, urgent, most_collected, least_donated, closest_to_goal, deadline)
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  PostsListResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /posts [get]
+func (h *Handlers) GetPosts(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	userIDStr := r.URL.Query().Get("user_id")
+	searchQuery := strings.TrimSpace(r.URL.Query().Get("q"))
+	language := r.URL.Query().Get("language")
+	if language != "" && !IsSupportedLanguage(language) {
+		WriteError(w, NewValidationError("Неподдерживаемый язык", map[string]interface{}{"field": "language"}))
+		return
+	}
+	filter := PostFilter{Status: status, Search: searchQuery, Language: language, Sort: r.URL.Query().Get("sort")}
+	if _, ok := postSortOrders[filter.Sort]; filter.Sort != "" && !ok {
+		WriteError(w, NewValidationError("Неподдерживаемый порядок сортировки", map[string]interface{}{"field": "sort"}))
+		return
+	}
+	if value := r.URL.Query().Get("deadline_before"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			WriteError(w, NewValidationError("Неверный формат даты, ожидается RFC 3339", map[string]interface{}{"field": "deadline_before"}))
+			return
+		}
+		filter.DeadlineBefore = &t
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	if userIDStr != "" {
+		id, _ := strconv.ParseInt(userIDStr, 10, 64)
+		filter.UserID = &id
+	}
+
+	// Авторизованному пользователю не показываются скрытые им посты и авторы
+	claims, err := OptionalClaims(h.cfg, h.db, r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if claims != nil {
+		filter.ViewerID = claims.UserID
+	}
+
+	// Посты вместе с авторами и медиа за постоянное число запросов
+	postsWithDetails, total, err := h.db.GetPostsWithDetails(filter, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	convertPostsWithDetailsURLs(postsWithDetails)
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": postsWithDetails,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// convertPostsWithDetailsURLs преобразует аватары авторов и медиа постов в URL через backend проксирование
+func convertPostsWithDetailsURLs(posts []PostWithDetails) {
+	for i := range posts {
+		if author := posts[i].Author; author != nil && author.Avatar != nil {
+			if *author.Avatar == "" {
+				author.Avatar = nil
+			} else {
+				backendURL := ConvertMinIOURLToBackendURL(*author.Avatar)
+				author.Avatar = &backendURL
+			}
+		}
+
+		media := posts[i].Media
+		for j := range media {
+			if media[j].MediaURL != "" {
+				media[j].MediaURL = ConvertMinIOURLToBackendURL(media[j].MediaURL)
+			}
+			ConvertImageVariantsToBackendURLs(media[j].Variants)
+		}
+	}
+}
+
+// GetPostShareImage возвращает картинку поста для соцсетей
+// @Summary     Картинка для соцсетей
+// @Description Возвращает PNG 1200x630 с фото, заголовком, прогрессом сбора и QR кодом со ссылкой на пост.
+// @Description Картинка кэшируется и перерисовывается при изменении поста или заметном изменении собранной суммы.
+// @Tags        Посты
+// @Produce     png
+// @Param       id path int true "ID поста"
+// @Success     200  {file}    binary
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/share-image [get]
+func (h *Handlers) GetPostShareImage(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	photoURL := h.postPhotoURL(post.ID)
+	postURL := strings.ReplaceAll(h.cfg.Share.PostURL, "{id}", strconv.FormatInt(post.ID, 10))
+	hash := ShareImageHash(post, photoURL, postURL)
+
+	cachedHash, cachedCollected, err := h.db.GetShareImageState(post.ID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	objectKey := fmt.Sprintf("posts/%d/share.png", post.ID)
+	if !ShareImageStale(post, hash, cachedHash, cachedCollected) {
+		if data, err := readObject(ctx, h.minioClient, BucketPostMedia, objectKey); err == nil {
+			writeShareImage(w, data)
+			return
+		}
+		// Картинки нет в хранилище - рисуем заново
+	}
+
+	data, err := RenderShareImage(post, h.readPostPhoto(ctx, photoURL), postURL)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка создания картинки"))
+		return
+	}
+
+	if _, err := UploadShareImage(ctx, h.minioClient, post.ID, data); err != nil {
+		log.Printf("Failed to cache share image of post %d: %v", post.ID, err)
+	} else if err := h.db.SaveShareImageState(post.ID, hash, post.Collected); err != nil {
+		log.Printf("Failed to save share image state of post %d: %v", post.ID, err)
+	}
+
+	writeShareImage(w, data)
+}
+
+func writeShareImage(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetPostFlyer возвращает листовку поста для печати
+// @Summary     Листовка для печати
+// @Description Возвращает PDF листовку A4 с фото, описанием, прогрессом сбора, реквизитами для перевода и QR кодом
+// @Description для сбора офлайн (в храмах, магазинах). Шаблоны: classic, tearoff (с отрывными полосками), mono (черно-белый).
+// @Tags        Посты
+// @Produce     application/pdf
+// @Param       id       path  int    true  "ID поста"
+// @Param       template query string false "Шаблон листовки" Enums(classic, tearoff, mono) default(classic)
+// @Success     200  {file}    binary
+// @Failure     400  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Router      /posts/{id}/flyer [get]
+func (h *Handlers) GetPostFlyer(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	templateName := r.URL.Query().Get("template")
+	if templateName == "" {
+		templateName = DefaultFlyerTemplate
+	}
+	tmpl, ok := FlyerTemplates[templateName]
+	if !ok {
+		WriteError(w, NewValidationError("Неизвестный шаблон листовки", map[string]interface{}{
+			"template": templateName,
+			"allowed":  FlyerTemplateNames(),
+		}))
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if post.Status != "active" {
+		WriteError(w, NewUnprocessableError("Листовку можно распечатать только для активного сбора"))
+		return
+	}
+
+	var photo image.Image
+	if tmpl.Photo {
+		photo = h.readPostPhoto(r.Context(), h.postPhotoURL(post.ID))
+	}
+	postURL := strings.ReplaceAll(h.cfg.Share.PostURL, "{id}", strconv.FormatInt(post.ID, 10))
+
+	data, err := RenderFlyer(post, photo, postURL, tmpl)
+	if err != nil {
+		log.Printf("Failed to render flyer of post %d: %v", post.ID, err)
+		WriteError(w, NewInternalError("Ошибка создания листовки"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"flyer-%d.pdf\"", post.ID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetPost получает пост по ID
+// @Summary     Получить пост
+// @Description Возвращает детальную информацию о посте
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "ID поста"
+// @Success     200  {object}  PostWithDetails
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id} [get]
+func (h *Handlers) GetPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	author, _ := h.db.GetUserByID(post.UserID)
+	media, _ := h.db.GetPostMedia(post.ID)
+
+	var authorInfo *UserInfo
+	if author != nil {
+		name := fmt.Sprintf("%s %s", author.FirstName, author.LastName)
+		// Преобразуем photo_url в URL через backend проксирование, если он есть
+		var avatarURL *string
+		if author.PhotoVariants != nil && author.PhotoVariants.Small != "" {
+			backendURL := ConvertMinIOURLToBackendURL(author.PhotoVariants.Small)
+			avatarURL = &backendURL
+		} else if author.PhotoURL != nil && *author.PhotoURL != "" {
+			backendURL := ConvertMinIOURLToBackendURL(*author.PhotoURL)
+			avatarURL = &backendURL
+		}
+		authorInfo = &UserInfo{
+			ID:     author.ID,
+			Name:   name,
+			Avatar: avatarURL,
+			Away:   author.Away,
+		}
+		if authorInfo.ResponseTime, err = h.db.GetAuthorResponseTime(author.ID); err != nil {
+			log.Printf("Failed to get response time of user %d: %v", author.ID, err)
+		}
+	}
+
+	// Преобразуем URL медиа файлов через backend проксирование
+	for i := range media {
+		if media[i].MediaURL != "" {
+			media[i].MediaURL = ConvertMinIOURLToBackendURL(media[i].MediaURL)
+		}
+		ConvertImageVariantsToBackendURLs(media[i].Variants)
+	}
+
+	lineItems, _ := h.db.GetPostLineItems(post.ID)
+	AllocateLineItems(lineItems, post.Collected)
+
+	response := PostWithDetails{
+		Post:      *post,
+		Author:    authorInfo,
+		Media:     media,
+		LineItems: lineItems,
+	}
+
+	// Число вопросов без ответа показывается тем, кто может на них ответить
+	answered, unanswered, err := h.db.CountPostQuestions(post.ID)
+	if err != nil {
+		log.Printf("Failed to count questions of post %d: %v", post.ID, err)
+	}
+	response.AnsweredQuestions = answered
+	if claims, _ := OptionalClaims(h.cfg, h.db, r); claims != nil && h.canSeeUnansweredQuestions(post, claims) {
+		response.UnansweredQuestions = &unanswered
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetPostEvents открывает поток событий поста
+// @Summary     Поток прогресса сбора
+// @Description Server-Sent Events: сразу и после каждого подтвержденного пожертвования приходит событие progress
+// @Description с собранной суммой, чтобы открытая страница поста обновляла прогресс без перезагрузки.
+// @Description Каждые 25 секунд приходит комментарий keep-alive. Поток открыт, пока клиент не отключится.
+// @Tags        Посты
+// @Produce     text/event-stream
+// @Param       id path int true "ID поста"
+// @Success     200  {object}  PostProgressEvent
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/events [get]
+func (h *Handlers) GetPostEvents(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	h.events.ServeSSE(w, r, PostTopic(post.ID), postProgressEvent(post))
+}
+
+// postProgressEvent событие progress с текущей собранной суммой поста
+func postProgressEvent(post *Post) *HubEvent {
+	return &HubEvent{Name: "progress", Data: PostProgressEvent{
+		PostID:    post.ID,
+		Collected: post.Collected,
+		Amount:    post.Amount,
+		Status:    post.Status,
+	}}
+}
+
+// CreatePost создает новый пост (только для верифицированных пользователей)
+// @Summary     Создать пост
+// @Description Создает новый пост о помощи. Автор должен быть не младше MIN_POSTING_AGE лет. Сбор в пользу несовершеннолетнего требует согласия и документа законного представителя и публикуется после модерации. Срочный сбор (urgent) доступен верифицированным авторам, требует документа, подтверждающего срочность, и рассматривается модератором вне очереди.
+// @Tags        Посты
+// @Accept      multipart/form-data
+// @Produce     json
+// @Security    BearerAuth
+// @Param       title formData string true "Заголовок"
+// @Param       description formData string true "Описание"
+// @Param       amount formData number true "Целевая сумма"
+// @Param       recipient formData string true "Получатель средств"
+// @Param       bank formData string true "Банк получателя"
+// @Param       phone formData string true "Телефон для связи"
+// @Param       media formData file false "Медиа файлы (максимум 10, каждый до 10MB)"
+// @Param       beneficiary_is_minor formData bool false "Сбор в пользу несовершеннолетнего"
+// @Param       guardian_consent formData bool false "Согласие законного представителя"
+// @Param       guardian_document formData file false "Документ законного представителя (PDF, JPEG, PNG до 10MB)"
+// @Param       urgent formData bool false "Срочный сбор"
+// @Param       deadline formData string false "Срок сбора (RFC 3339), после него пост закрывается автоматически"
+// @Param       urgent_document formData file false "Документ, подтверждающий срочность: выписка, счет клиники (PDF, JPEG, PNG до 10MB), обязателен для срочного сбора"
+// @Param       line_items formData string false "Статьи расходов: JSON массив объектов с полями title и amount (до 20), сумма должна совпадать с amount"
+// @Param       Idempotency-Key header string false "Ключ идемпотентности (до 255 символов): повтор с тем же ключом возвращает сохраненный ответ с заголовком Idempotent-Replayed"
+// @Success     201  {object}  PostResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Router      /posts [post]
+func (h *Handlers) CreatePost(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// // Проверяем верификацию
+	// if !h.db.IsUserVerified(userID) {
+	// 	WriteError(w, NewForbiddenError("Пользователь не верифицирован"))
+	// 	return
+	// }
+
+	if err := ParseMultipartForm(r, 100<<20); err != nil { // 100MB
+		WriteError(w, err)
+		return
+	}
+
+	var req CreatePostRequest
+	req.Title = r.FormValue("title")
+	req.Description = r.FormValue("description")
+	req.Amount, _ = strconv.ParseFloat(r.FormValue("amount"), 64)
+	req.Recipient = r.FormValue("recipient")
+	req.Bank = r.FormValue("bank")
+	req.Phone = r.FormValue("phone")
+	req.BeneficiaryIsMinor = r.FormValue("beneficiary_is_minor") == "true"
+	req.GuardianConsent = r.FormValue("guardian_consent") == "true"
+	req.Urgent = r.FormValue("urgent") == "true"
+	req.Deadline = r.FormValue("deadline")
+	if lineItems := r.FormValue("line_items"); lineItems != "" {
+		if err := json.Unmarshal([]byte(lineItems), &req.LineItems); err != nil {
+			WriteError(w, NewValidationError("Неверный формат статей расходов", map[string]interface{}{"field": "line_items"}))
+			return
+		}
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if len(req.LineItems) > 0 {
+		if err := ValidateLineItemsTotal(LineItemsTotal(req.LineItems), req.Amount); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	// Возраст автора проверяется по дате рождения из заявки на верификацию
+	if verification, err := h.db.GetVerificationByUserID(userID); err == nil {
+		if AgeAt(verification.BirthDate, time.Now()) < h.cfg.Age.MinPostingAge {
+			WriteError(w, NewForbiddenError(fmt.Sprintf("Создавать сборы можно с %d лет", h.cfg.Age.MinPostingAge)))
+			return
+		}
+	}
+
+	limitExceptionID, err := h.checkPostAmountLimit(userID, req.Amount)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var mediaSize int64
+	for i, fileHeader := range r.MultipartForm.File["media"] {
+		if i >= 10 {
+			break
+		}
+		mediaSize += fileHeader.Size
+	}
+	if err := h.checkStorageQuota(userID, mediaSize); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post := &Post{
+		UserID:             userID,
+		Title:              req.Title,
+		Description:        req.Description,
+		Amount:             req.Amount,
+		Recipient:          req.Recipient,
+		Bank:               req.Bank,
+		Phone:              req.Phone,
+		BeneficiaryIsMinor: req.BeneficiaryIsMinor,
+	}
+	if req.Deadline != "" {
+		deadline, err := parsePostDeadline(req.Deadline)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		post.Deadline = &deadline
+	}
+
+	// Сбор в пользу ребенка публикуется только после проверки согласия и
+	// документа законного представителя (свидетельство о рождении, решение об опеке)
+	guardianDocument, guardianHeader, guardianErr := r.FormFile("guardian_document")
+	if guardianErr == nil {
+		defer guardianDocument.Close()
+	}
+	if req.BeneficiaryIsMinor {
+		if !req.GuardianConsent {
+			WriteError(w, NewValidationError("Для сбора в пользу несовершеннолетнего требуется согласие законного представителя", map[string]interface{}{"field": "guardian_consent"}))
+			return
+		}
+		if guardianErr != nil {
+			WriteError(w, NewValidationError("Загрузите документ, подтверждающий право представлять ребенка", map[string]interface{}{"field": "guardian_document"}))
+			return
+		}
+
+		if err := ValidateFileSize(guardianHeader, 10<<20); err != nil {
+			WriteError(w, err)
+			return
+		}
+		if err := ValidateDocumentFile(guardianHeader); err != nil {
+			WriteError(w, err)
+			return
+		}
+		if err := h.scanUpload(r.Context(), "guardian", userID, guardianDocument, guardianHeader); err != nil {
+			WriteError(w, err)
+			return
+		}
+		post.Status = "moderated"
+	}
+
+	// Срочный сбор поднимается в ленте, поэтому требования строже: автор должен
+	// пройти верификацию и приложить документ, подтверждающий срочность
+	urgentDocument, urgentHeader, urgentErr := r.FormFile("urgent_document")
+	if urgentErr == nil {
+		defer urgentDocument.Close()
+	}
+	if req.Urgent {
+		if !h.db.IsUserVerified(userID) {
+			WriteError(w, NewForbiddenError("Срочный сбор доступен только после верификации"))
+			return
+		}
+		if urgentErr != nil {
+			WriteError(w, NewValidationError("Загрузите документ, подтверждающий срочность сбора", map[string]interface{}{"field": "urgent_document"}))
+			return
+		}
+		if err := ValidateFileSize(urgentHeader, 10<<20); err != nil {
+			WriteError(w, err)
+			return
+		}
+		if err := ValidateDocumentFile(urgentHeader); err != nil {
+			WriteError(w, err)
+			return
+		}
+		if err := h.scanUpload(r.Context(), "urgent", userID, urgentDocument, urgentHeader); err != nil {
+			WriteError(w, err)
+			return
+		}
+		post.Urgent = true
+		post.Status = "moderated"
+	}
+
+	post.Language = DetectLanguage(post.Title + "\n" + post.Description)
+
+	if err := h.db.CreatePost(post); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if limitExceptionID != nil {
+		if err := h.db.UsePostLimitException(*limitExceptionID, post.ID); err != nil {
+			log.Printf("Failed to mark post limit exception %d as used: %v", *limitExceptionID, err)
+		}
+	}
+	if len(req.LineItems) > 0 {
+		if err := h.db.ReplacePostLineItems(post.ID, req.LineItems); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	if req.BeneficiaryIsMinor {
+		objectKey, err := UploadGuardianDocument(ctx, h.minioClient, post.ID, guardianDocument, guardianHeader.Size, guardianHeader.Header.Get("Content-Type"))
+		if err != nil {
+			WriteError(w, NewInternalError("Ошибка загрузки документа"))
+			return
+		}
+		documentURL := GetObjectURL(h.cfg.MinIOConfig, BucketVerificationDocs, objectKey)
+		if err := h.db.SetPostGuardianDocument(post.ID, documentURL); err != nil {
+			WriteError(w, err)
+			return
+		}
+		post.GuardianDocumentURL = &documentURL
+	}
+	if req.Urgent {
+		objectKey, err := UploadUrgentDocument(ctx, h.minioClient, post.ID, urgentDocument, urgentHeader.Size, urgentHeader.Header.Get("Content-Type"))
+		if err != nil {
+			WriteError(w, NewInternalError("Ошибка загрузки документа"))
+			return
+		}
+		documentURL := GetObjectURL(h.cfg.MinIOConfig, BucketVerificationDocs, objectKey)
+		if err := h.db.SetPostUrgentDocument(post.ID, documentURL); err != nil {
+			WriteError(w, err)
+			return
+		}
+		post.UrgentDocumentURL = &documentURL
+	}
+
+	// Загружаем медиа файлы
+	if files, ok := r.MultipartForm.File["media"]; ok {
+		for i, fileHeader := range files {
+			if i >= 10 { // Максимум 10 файлов
+				break
+			}
+
+			file, err := fileHeader.Open()
+			if err != nil {
+				continue
+			}
+
+			if err := ValidateFileSize(fileHeader, 10<<20); err != nil { // 10MB
+				file.Close()
+				continue
+			}
+
+			if err := ValidateMediaFile(fileHeader); err != nil {
+				file.Close()
+				continue
+			}
+
+			mediaType := "image"
+			if strings.Contains(strings.ToLower(filepath.Ext(fileHeader.Filename)), "mp4") ||
+				strings.Contains(strings.ToLower(filepath.Ext(fileHeader.Filename)), "webm") {
+				mediaType = "video"
+			}
+
+			// Изображения пережимаются без метаданных, видео загружаются как есть
+			var data []byte
+			var body io.Reader = file
+			size, contentType := fileHeader.Size, fileHeader.Header.Get("Content-Type")
+			if mediaType == "image" {
+				data, contentType, err = readImageUpload(file)
+				if err != nil {
+					file.Close()
+					continue
+				}
+				body, size = bytes.NewReader(data), int64(len(data))
+			}
+
+			objectKey, err := UploadPostMedia(ctx, h.minioClient, post.ID, i, body, size, contentType)
+			file.Close()
+			if err != nil {
+				continue
+			}
+
+			var variants *ImageVariants
+			if mediaType == "image" {
+				variants = h.uploadImageVariants(ctx, BucketPostMedia, objectKey, data)
+			}
+
+			mediaURL := GetObjectURL(h.cfg.MinIOConfig, BucketPostMedia, objectKey)
+			h.db.CreatePostMedia(post.ID, mediaURL, mediaType, i, size, variants)
+		}
+	}
+
+	emitPartnerWebhook(h.db, WebhookEventPostCreated, map[string]interface{}{
+		"post_id":    post.ID,
+		"user_id":    post.UserID,
+		"title":      post.Title,
+		"amount":     post.Amount,
+		"status":     post.Status,
+		"urgent":     post.Urgent,
+		"deadline":   post.Deadline,
+		"created_at": post.CreatedAt,
+	})
+
+	response := map[string]interface{}{
+		"id":          post.ID,
+		"user_id":     post.UserID,
+		"title":       post.Title,
+		"description": post.Description,
+		"amount":      post.Amount,
+		"collected":   post.Collected,
+		"status":      post.Status,
+		"created_at":  post.CreatedAt,
+	}
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+// UpdatePost обновляет пост (автор или соавтор с правом edit)
+// @Summary     Обновить пост
+// @Description Обновляет данные поста (автор или соавтор с правом edit). Доступно также по API токену с областью posts:write.
+// @Description Статьи расходов line_items заменяются целиком, их сумма должна совпадать с целевой суммой.
+// @Description Срок deadline задается в RFC 3339, пустая строка убирает срок. Закрытый по сроку пост новым сроком не открывается.
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       request body UpdatePostRequest true "Данные для обновления"
+// @Success     200  {object}  PostUpdateResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id} [patch]
+func (h *Handlers) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	collaborator, err := h.postAccess(post, userID, CollaboratorEdit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req UpdatePostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Статьи расходов должны сходиться с целевой суммой после изменения
+	amount := post.Amount
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if req.LineItems != nil {
+		if len(*req.LineItems) > 0 {
+			if err := ValidateLineItemsTotal(LineItemsTotal(*req.LineItems), amount); err != nil {
+				WriteError(w, err)
+				return
+			}
+		}
+	} else if req.Amount != nil {
+		items, err := h.db.GetPostLineItems(postID)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if len(items) > 0 {
+			total := 0.0
+			for _, item := range items {
+				total += item.Amount
+			}
+			if err := ValidateLineItemsTotal(total, amount); err != nil {
+				WriteError(w, err)
+				return
+			}
+		}
+	}
+
+	// Увеличение целевой суммы проверяется по лимиту уровня доверия
+	var limitExceptionID *int64
+	if req.Amount != nil && *req.Amount > post.Amount {
+		limitExceptionID, err = h.checkPostAmountLimit(post.UserID, *req.Amount)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	// Текст изменился - определяем язык заново
+	var language *string
+	if req.Title != nil || req.Description != nil {
+		title, description := post.Title, post.Description
+		if req.Title != nil {
+			title = *req.Title
+		}
+		if req.Description != nil {
+			description = *req.Description
+		}
+		detected := DetectLanguage(title + "\n" + description)
+		language = &detected
+	}
+
+	var deadline *time.Time
+	if req.Deadline != nil && *req.Deadline != "" {
+		parsed, err := parsePostDeadline(*req.Deadline)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		deadline = &parsed
+	}
+
+	if err := h.db.UpdatePost(postID, req.Title, req.Description, req.Amount, req.Recipient, req.Bank, req.Phone, language); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if req.Deadline != nil {
+		if err := h.db.SetPostDeadline(postID, deadline); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+	if limitExceptionID != nil {
+		if err := h.db.UsePostLimitException(*limitExceptionID, postID); err != nil {
+			log.Printf("Failed to mark post limit exception %d as used: %v", *limitExceptionID, err)
+		}
+	}
+	if req.LineItems != nil {
+		if err := h.db.ReplacePostLineItems(postID, *req.LineItems); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	if collaborator {
+		h.recordCollaboratorAction(r.Context(), post, AdminActionPostUpdate, req)
+	}
+
+	post, _ = h.db.GetPostByID(postID)
+	response := map[string]interface{}{
+		"id":         post.ID,
+		"title":      post.Title,
+		"updated_at": post.UpdatedAt,
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// parsePostDeadline разбирает срок сбора, срок должен быть в будущем
+func parsePostDeadline(value string) (time.Time, error) {
+	deadline, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, NewValidationError("Неверный формат срока, ожидается RFC 3339", map[string]interface{}{"field": "deadline"})
+	}
+	if !deadline.After(time.Now()) {
+		return time.Time{}, NewValidationError("Срок сбора должен быть в будущем", map[string]interface{}{"field": "deadline"})
+	}
+	return deadline, nil
+}
+
+// AddPostMedia добавляет медиа к посту (автор или соавтор с правом edit)
+// @Summary     Добавить медиа к посту
+// @Description Добавляет медиа файл к существующему посту
+// @Tags        Посты
+// @Accept      multipart/form-data
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       media formData file true "Медиа файл (изображение/видео, до 10MB)"
+// @Success     201  {object}  PostMedia
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Router      /posts/{id}/media [post]
+func (h *Handlers) AddPostMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	collaborator, err := h.postAccess(post, userID, CollaboratorEdit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := ParseMultipartForm(r, 10<<20); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	file, header, err := GetFileFromForm(r, "media")
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	defer file.Close()
+
+	if err := ValidateFileSize(header, 10<<20); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := ValidateMediaFile(header); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := h.checkStorageQuota(post.UserID, header.Size); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	media, _ := h.db.GetPostMedia(postID)
+	orderIndex := len(media)
+
+	mediaType := "image"
+	if strings.Contains(strings.ToLower(filepath.Ext(header.Filename)), "mp4") ||
+		strings.Contains(strings.ToLower(filepath.Ext(header.Filename)), "webm") {
+		mediaType = "video"
+	}
+
+	// Изображения пережимаются без метаданных, видео загружаются как есть
+	var data []byte
+	var body io.Reader = file
+	size, contentType := header.Size, header.Header.Get("Content-Type")
+	if mediaType == "image" {
+		data, contentType, err = readImageUpload(file)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	ctx := r.Context()
+	objectKey, err := UploadPostMedia(ctx, h.minioClient, postID, orderIndex, body, size, contentType)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка загрузки медиа"))
+		return
+	}
+
+	var variants *ImageVariants
+	if mediaType == "image" {
+		variants = h.uploadImageVariants(ctx, BucketPostMedia, objectKey, data)
+	}
+
+	mediaURL := GetObjectURL(h.cfg.MinIOConfig, BucketPostMedia, objectKey)
+	postMedia, err := h.db.CreatePostMedia(postID, mediaURL, mediaType, orderIndex, size, variants)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if collaborator {
+		h.recordCollaboratorAction(r.Context(), post, AdminActionPostMedia, map[string]interface{}{"added": []int64{postMedia.ID}})
+	}
+
+	WriteJSON(w, http.StatusCreated, postMedia)
+}
+
+// AddPostMediaBulk загружает несколько медиа файлов поста одним запросом
+// @Summary     Массовая загрузка медиа
+// @Description Принимает файлы в поле media и/или zip архивы в поле archive (не больше 50 файлов, каждый до 10MB).
+// @Description Каждый файл проверяется отдельно: ошибка одного файла не отменяет загрузку остальных, результат по каждому файлу возвращается в отчете.
+// @Description Суммарный размер проверяется по квоте хранилища до загрузки. Доступно также по API токену с областью posts:write.
+// @Tags        Посты
+// @Accept      multipart/form-data
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       media formData file false "Медиа файлы (можно несколько)"
+// @Param       archive formData file false "Zip архив с медиа файлами"
+// @Success     200  {object}  BulkMediaResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Router      /posts/{id}/media/bulk [post]
+func (h *Handlers) AddPostMediaBulk(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	collaborator, err := h.postAccess(post, userID, CollaboratorEdit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := ParseMultipartForm(r, 32<<20); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	files, closeArchives, err := collectBulkMediaFiles(r.MultipartForm)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	defer closeArchives()
+
+	if len(files) == 0 {
+		WriteError(w, NewValidationError("Добавьте файлы в поле media или zip архив в поле archive", nil))
+		return
+	}
+	if len(files) > maxBulkMediaFiles {
+		WriteError(w, NewValidationError(fmt.Sprintf("Можно загрузить не больше %d файлов за раз", maxBulkMediaFiles), map[string]interface{}{"files": len(files)}))
+		return
+	}
+
+	var totalSize int64
+	for _, file := range files {
+		totalSize += file.size
+	}
+	if err := h.checkStorageQuota(post.UserID, totalSize); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	media, _ := h.db.GetPostMedia(postID)
+	orderIndex := len(media)
+
+	ctx := r.Context()
+	response := BulkMediaResponse{Files: make([]BulkMediaResult, 0, len(files))}
+	for _, file := range files {
+		result := BulkMediaResult{Name: file.name}
+		postMedia, err := h.storeBulkMedia(ctx, postID, orderIndex, file)
+		if err != nil {
+			result.Error = err.Error()
+			response.Failed++
+		} else {
+			result.Media = postMedia
+			response.Uploaded++
+			orderIndex++
+		}
+		response.Files = append(response.Files, result)
+	}
+
+	if collaborator && response.Uploaded > 0 {
+		h.recordCollaboratorAction(r.Context(), post, AdminActionPostMedia, map[string]interface{}{"uploaded": response.Uploaded})
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// storeBulkMedia проверяет и загружает один файл массовой загрузки.
+// Возвращает только *AppError, сообщение которой попадает в отчет.
+func (h *Handlers) storeBulkMedia(ctx context.Context, postID int64, orderIndex int, file bulkMediaFile) (*PostMedia, error) {
+	data, contentType, mediaType, err := readBulkMediaFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	objectKey, err := UploadPostMedia(ctx, h.minioClient, postID, orderIndex, bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		log.Printf("Failed to upload bulk media %q for post %d: %v", file.name, postID, err)
+		return nil, NewInternalError("Ошибка загрузки медиа")
+	}
+
+	var variants *ImageVariants
+	if mediaType == "image" {
+		variants = h.uploadImageVariants(ctx, BucketPostMedia, objectKey, data)
+	}
+
+	mediaURL := GetObjectURL(h.cfg.MinIOConfig, BucketPostMedia, objectKey)
+	postMedia, err := h.db.CreatePostMedia(postID, mediaURL, mediaType, orderIndex, int64(len(data)), variants)
+	if err != nil {
+		log.Printf("Failed to save bulk media %q for post %d: %v", file.name, postID, err)
+		return nil, NewInternalError("Ошибка сохранения медиа")
+	}
+	return postMedia, nil
+}
+
+// DeletePostMedia удаляет медиа из поста (автор или соавтор с правом edit)
+// @Summary     Удалить медиа из поста
+// @Description Удаляет медиа файл из поста
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       media_id path int true "ID медиа"
+// @Success     204  "Успешно удалено"
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/media/{media_id} [delete]
+func (h *Handlers) DeletePostMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	mediaID, err := strconv.ParseInt(vars["media_id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID медиа", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	collaborator, err := h.postAccess(post, userID, CollaboratorEdit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	media, err := h.db.DeletePostMedia(postID, mediaID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	urls := []string{media.MediaURL}
+	if media.Variants != nil {
+		urls = append(urls, media.Variants.Small, media.Variants.Medium, media.Variants.Large)
+	}
+	DeleteObjectsByURL(r.Context(), h.minioClient, BucketPostMedia, urls)
+
+	if collaborator {
+		h.recordCollaboratorAction(r.Context(), post, AdminActionPostMedia, map[string]interface{}{"deleted": []int64{mediaID}})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeletePost удаляет пост (только автор)
+// @Summary     Удалить пост
+// @Description Удаляет пост (только автор может удалить)
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Success     204  "Успешно удалено"
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id} [delete]
+func (h *Handlers) DeletePost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if post.UserID != userID {
+		WriteError(w, NewForbiddenError("Недостаточно прав"))
+		return
+	}
+
+	// Файлы собираются до удаления: записи о них удаляются из базы каскадно
+	objectURLs, err := h.db.GetPostObjectURLs(postID)
+	if err != nil {
+		log.Printf("Failed to collect files of post %d: %v", postID, err)
+	}
+
+	if err := h.db.DeletePost(postID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Документ законного представителя хранится в bucket с блокировкой объектов
+	// и удаляется только по истечении срока хранения
+	for bucket, urls := range objectURLs {
+		DeleteObjectsByURL(r.Context(), h.minioClient, bucket, urls)
+	}
+	shareKey := fmt.Sprintf("posts/%d/share.png", postID)
+	if err := DeleteObject(r.Context(), h.minioClient, BucketPostMedia, shareKey); err != nil {
+		log.Printf("Failed to delete %s/%s: %v", BucketPostMedia, shareKey, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetModerationPosts получает посты, ожидающие модерации (только для админов)
+// @Summary     Посты на модерации
+// @Description Возвращает посты со статусом moderated вместе с документами законных представителей
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {array}   ModerationPostResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/posts/moderation [get]
+func (h *Handlers) GetModerationPosts(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	posts, total, err := h.db.GetPosts("moderated", nil, "", page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	data := make([]ModerationPostResponse, 0, len(posts))
+	for _, post := range posts {
+		data = append(data, h.moderationPost(post))
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": data,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// GetUrgentModerationPosts получает срочные сборы, ожидающие модерации
+// @Summary     Срочные сборы на модерации
+// @Description Отдельная очередь срочных сборов: самые старые первыми, со сроком рассмотрения review_deadline (URGENT_REVIEW_SLA_HOURS после создания) и отметкой overdue, если срок прошел. Срочные сборы есть и в общей очереди.
+// @Tags        Посты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице (до 100)" default(20)
+// @Success     200  {array}   ModerationPostResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/posts/moderation/urgent [get]
+func (h *Handlers) GetUrgentModerationPosts(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	posts, total, err := h.db.GetUrgentModerationPosts(page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	data := make([]ModerationPostResponse, 0, len(posts))
+	for _, post := range posts {
+		data = append(data, h.moderationPost(post))
+	}
+
+	response := map[string]interface{}{
+		"data": data,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// moderationPost добавляет к посту на модерации документы и срок рассмотрения срочного сбора
+func (h *Handlers) moderationPost(post Post) ModerationPostResponse {
+	item := ModerationPostResponse{
+		Post:                post,
+		GuardianDocumentURL: post.GuardianDocumentURL,
+		UrgentDocumentURL:   post.UrgentDocumentURL,
+	}
+	if post.Urgent && post.Status == "moderated" {
+		deadline := post.CreatedAt.Add(h.cfg.Urgent.ReviewSLA)
+		item.ReviewDeadline = &deadline
+		item.Overdue = time.Now().After(deadline)
+	}
+	return item
+}
+
+// ModeratePost публикует или отклоняет пост на модерации (только для админов)
+// @Summary     Решение по посту на модерации
+// @Description Переводит пост в статус active (опубликовать) или closed (отклонить). При закрытии обязательна причина reason: она сохраняется в журнале и отправляется автору. Опубликованный срочный сбор показывается первым в ленте URGENT_DURATION_DAYS дней.
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       request body ModeratePostRequest true "Решение"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/posts/{id}/moderation [patch]
+func (h *Handlers) ModeratePost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	var req ModeratePostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if post.Status != "moderated" {
+		WriteError(w, NewConflictError("Пост не находится на модерации"))
+		return
+	}
+
+	update := func() error {
+		return h.db.UpdatePostStatus(postID, req.Status)
+	}
+	oldValue := map[string]interface{}{"status": post.Status}
+	newValue := map[string]interface{}{"status": req.Status}
+	if post.Urgent && req.Status == "active" {
+		urgentUntil := time.Now().Add(h.cfg.Urgent.Duration)
+		update = func() error {
+			return h.db.PublishUrgentPost(postID, urgentUntil)
+		}
+		newValue["urgent_until"] = urgentUntil
+	}
+	if req.Status == "closed" {
+		err = h.performAdminAction(r.Context(), &adminAction{
+			Action:       AdminActionPostClose,
+			TargetType:   "post",
+			TargetID:     postID,
+			TargetUserID: post.UserID,
+			PostID:       &postID,
+			Reason:       req.Reason,
+			OldValue:     oldValue,
+			NewValue:     newValue,
+			Notification: NotificationPostClosed,
+			Title:        "Сбор не прошел модерацию",
+			Body:         fmt.Sprintf("Сбор «%s» закрыт модератором.", post.Title),
+		}, update)
+	} else if err = update(); err == nil {
+		h.recordAdminAction(r.Context(), AdminActionRecord{
+			Action:       AdminActionPostApprove,
+			TargetType:   "post",
+			TargetID:     postID,
+			TargetUserID: post.UserID,
+			OldValue:     oldValue,
+			NewValue:     newValue,
+		})
+	}
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, "Решение по посту сохранено")
+}
+
+// ========== Collaborator Endpoints ==========
+
+// GetPostCollaborators получает соавторов поста
+// @Summary     Соавторы поста
+// @Description Возвращает соавторов и приглашения поста с правами. Доступно автору и соавторам.
+// @Tags        Посты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Success     200  {array}   PostCollaborator
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/collaborators [get]
+func (h *Handlers) GetPostCollaborators(w http.ResponseWriter, r *http.Request) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if post.UserID != userID {
+		permissions, err := h.db.GetCollaboratorPermissions(post.ID, userID)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if permissions == nil {
+			WriteError(w, NewForbiddenError("Недостаточно прав"))
+			return
+		}
+	}
+
+	collaborators, err := h.db.GetPostCollaborators(post.ID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, convertCollaboratorAvatars(collaborators))
+}
+
+// InviteCollaborator приглашает соавтора поста
+// @Summary     Пригласить соавтора
+// @Description Приглашает зарегистрированного пользователя по телефону помогать с постом. Права: edit - редактирование поста и медиа,
+// @Description chats - переписка в чатах поста, donations - подтверждение пожертвований. Права действуют после принятия приглашения
+// @Description в PATCH /users/me/collaborations/{id}. Только автор поста.
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       request body InviteCollaboratorRequest true "Телефон и права"
+// @Success     201  {object}  PostCollaborator
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /posts/{id}/collaborators [post]
+func (h *Handlers) InviteCollaborator(w http.ResponseWriter, r *http.Request) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if post.UserID != userID {
+		WriteError(w, NewForbiddenError("Приглашать соавторов может только автор поста"))
+		return
+	}
+
+	var req InviteCollaboratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	invitee, err := h.db.GetUserByPhone(FormatPhone(req.Phone))
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if invitee.ID == userID {
+		WriteError(w, NewValidationError("Нельзя пригласить себя", map[string]interface{}{"field": "phone"}))
+		return
+	}
+	if !invitee.IsActive {
+		WriteError(w, NewValidationError("Аккаунт пользователя деактивирован", map[string]interface{}{"field": "phone"}))
+		return
+	}
+
+	permissions := collaboratorPermissions(req.Permissions)
+	id, err := h.db.InviteCollaborator(post.ID, invitee.ID, userID, permissions)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:       AdminActionCollaboratorInvite,
+		TargetType:   "post",
+		TargetID:     post.ID,
+		TargetName:   post.Title,
+		TargetUserID: invitee.ID,
+		NewValue:     map[string]interface{}{"permissions": permissions},
+	})
+
+	h.notifier.Enqueue(FanoutJob{
+		Type:    NotificationCollaborationInvite,
+		Title:   "Приглашение в соавторы",
+		Body:    fmt.Sprintf("Вас приглашают помогать со сбором «%s».", post.Title),
+		PostID:  &post.ID,
+		UserIDs: []int64{invitee.ID},
+	})
+
+	collaborators, err := h.db.GetPostCollaborators(post.ID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	for _, c := range convertCollaboratorAvatars(collaborators) {
+		if c.ID == id {
+			WriteJSON(w, http.StatusCreated, c)
+			return
+		}
+	}
+	WriteError(w, NewNotFoundError("Соавтор"))
+}
+
+// UpdateCollaborator меняет права соавтора
+// @Summary     Изменить права соавтора
+// @Description Заменяет права соавтора или приглашенного. Только автор поста.
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       user_id path int true "ID соавтора"
+// @Param       request body UpdateCollaboratorRequest true "Права"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/collaborators/{user_id} [patch]
+func (h *Handlers) UpdateCollaborator(w http.ResponseWriter, r *http.Request) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if post.UserID != userID {
+		WriteError(w, NewForbiddenError("Менять права соавторов может только автор поста"))
+		return
+	}
+	collaboratorID, err := strconv.ParseInt(mux.Vars(r)["user_id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пользователя", nil))
+		return
+	}
+
+	var req UpdateCollaboratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	permissions := collaboratorPermissions(req.Permissions)
+	old, err := h.db.UpdateCollaboratorPermissions(post.ID, collaboratorID, permissions)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:       AdminActionCollaboratorUpdate,
+		TargetType:   "post",
+		TargetID:     post.ID,
+		TargetName:   post.Title,
+		TargetUserID: collaboratorID,
+		OldValue:     map[string]interface{}{"permissions": old},
+		NewValue:     map[string]interface{}{"permissions": permissions},
+	})
+
+	WriteSuccess(w, http.StatusOK, "Права соавтора обновлены")
+}
+
+// RemoveCollaborator удаляет соавтора поста
+// @Summary     Удалить соавтора
+// @Description Автор удаляет соавтора или отзывает приглашение, соавтор может выйти из соавторов сам
+// @Tags        Посты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       user_id path int true "ID соавтора"
+// @Success     204
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/collaborators/{user_id} [delete]
+func (h *Handlers) RemoveCollaborator(w http.ResponseWriter, r *http.Request) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	collaboratorID, err := strconv.ParseInt(mux.Vars(r)["user_id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пользователя", nil))
+		return
+	}
+	if post.UserID != userID && collaboratorID != userID {
+		WriteError(w, NewForbiddenError("Недостаточно прав"))
+		return
+	}
+
+	deleted, err := h.db.DeleteCollaborator(post.ID, collaboratorID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if !deleted {
+		WriteError(w, NewNotFoundError("Соавтор"))
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:       AdminActionCollaboratorRemove,
+		TargetType:   "post",
+		TargetID:     post.ID,
+		TargetName:   post.Title,
+		TargetUserID: collaboratorID,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMyCollaborations получает приглашения в соавторы и посты, соавтором которых является пользователь
+// @Summary     Мои соавторства
+// @Description Возвращает ожидающие ответа приглашения (status pending) и принятые (status accepted)
+// @Tags        Пользователи
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {array}   PostCollaborator
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/collaborations [get]
+func (h *Handlers) GetMyCollaborations(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	collaborations, err := h.db.GetUserCollaborations(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, convertCollaboratorAvatars(collaborations))
+}
+
+// RespondCollaboration принимает или отклоняет приглашение в соавторы
+// @Summary     Ответить на приглашение в соавторы
+// @Description Принимает (accepted) или отклоняет (declined) приглашение. Автор поста получает уведомление.
+// @Tags        Пользователи
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID приглашения"
+// @Param       request body RespondCollaborationRequest true "Ответ"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/collaborations/{id} [patch]
+func (h *Handlers) RespondCollaboration(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID приглашения", nil))
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req RespondCollaborationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	postID, err := h.db.RespondCollaboration(id, userID, req.Status)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if post, err := h.db.GetPostByID(postID); err == nil {
+		name := "Пользователь"
+		if user, err := h.db.GetUserByID(userID); err == nil {
+			name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		}
+		verb := "принял(а)"
+		if req.Status == "declined" {
+			verb = "отклонил(а)"
+		}
+		h.notifier.Enqueue(FanoutJob{
+			Type:    NotificationCollaborationReply,
+			Title:   "Ответ на приглашение в соавторы",
+			Body:    fmt.Sprintf("%s %s приглашение помогать со сбором «%s».", name, verb, post.Title),
+			PostID:  &post.ID,
+			UserIDs: []int64{post.UserID},
+		})
+	}
+
+	WriteSuccess(w, http.StatusOK, "Ответ сохранен")
+}
+
+// collaboratorPost получает пост из пути и текущего пользователя
+func (h *Handlers) collaboratorPost(r *http.Request) (*Post, int64, error) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return nil, 0, NewValidationError("Неверный ID поста", nil)
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		return nil, 0, err
+	}
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return post, userID, nil
+}
+
+// convertCollaboratorAvatars преобразует аватары соавторов в URL через backend проксирование
+func convertCollaboratorAvatars(collaborators []PostCollaborator) []PostCollaborator {
+	for i := range collaborators {
+		if avatar := collaborators[i].User.Avatar; avatar != nil && *avatar != "" {
+			backendURL := ConvertMinIOURLToBackendURL(*avatar)
+			collaborators[i].User.Avatar = &backendURL
+		}
+	}
+	return collaborators
+}
+
+// collaboratorPermissions упорядочивает права и убирает повторы
+func collaboratorPermissions(permissions []string) []string {
+	permissions = slices.Clone(permissions)
+	slices.Sort(permissions)
+	return slices.Compact(permissions)
+}
+
+// ========== Comment Endpoints ==========
+
+// GetPostComments получает комментарии поста
+// @Summary     Комментарии поста
+// @Description Возвращает публичные комментарии поста с авторами, новые первыми
+// @Tags        Посты
+// @Produce     json
+// @Param       id path int true "ID поста"
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице (до 100)" default(20)
+// @Success     200  {object}  PostCommentsResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/comments [get]
+func (h *Handlers) GetPostComments(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+	if _, err := h.db.GetPostByID(postID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	comments, total, err := h.db.GetPostComments(postID, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	for i := range comments {
+		convertCommentAvatar(&comments[i])
+	}
+
+	WriteJSON(w, http.StatusOK, PostCommentsResponse{
+		Data: comments,
+		Pagination: PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	})
+}
+
+// CreatePostComment добавляет комментарий к посту
+// @Summary     Добавить комментарий
+// @Description Публикует комментарий к посту, например вопрос автору сбора. Автор поста получает уведомление.
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       request body CreateCommentRequest true "Текст комментария"
+// @Success     201  {object}  PostComment
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/comments [post]
+func (h *Handlers) CreatePostComment(w http.ResponseWriter, r *http.Request) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	comment, err := h.db.CreatePostComment(post.ID, userID, req.Text)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	convertCommentAvatar(comment)
+
+	if post.UserID != userID {
+		h.notifier.Enqueue(FanoutJob{
+			Type:    NotificationPostComment,
+			Title:   comment.Author.Name,
+			Body:    fmt.Sprintf("Комментарий к сбору «%s»: %s", post.Title, shortenText(comment.Text, 100)),
+			PostID:  &post.ID,
+			UserIDs: []int64{post.UserID},
+		})
+	}
+
+	WriteJSON(w, http.StatusCreated, comment)
+}
+
+// DeletePostComment удаляет комментарий
+// @Summary     Удалить комментарий
+// @Description Удаляет комментарий. Доступно автору комментария, автору поста, соавтору с правом edit и пользователям с правом posts.moderate.
+// @Tags        Посты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       comment_id path int true "ID комментария"
+// @Success     204  "Комментарий удален"
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/comments/{comment_id} [delete]
+func (h *Handlers) DeletePostComment(w http.ResponseWriter, r *http.Request) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	commentID, err := strconv.ParseInt(mux.Vars(r)["comment_id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID комментария", nil))
+		return
+	}
+
+	comment, err := h.db.GetPostComment(commentID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if comment.PostID != post.ID {
+		WriteError(w, NewNotFoundError("Комментарий"))
+		return
+	}
+
+	if comment.Author.ID != userID && !h.hasPermission(r.Context(), PermPostsModerate) {
+		if _, err := h.postAccess(post, userID, CollaboratorEdit); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	if err := h.db.DeletePostComment(comment.ID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Удаление чужого комментария (модератор или соавтор) записывается в журнал
+	if comment.Author.ID != userID && post.UserID != userID {
+		h.recordAdminAction(r.Context(), AdminActionRecord{
+			Action:       AdminActionCommentDelete,
+			TargetType:   "comment",
+			TargetID:     comment.ID,
+			TargetUserID: comment.Author.ID,
+			OldValue:     map[string]interface{}{"post_id": post.ID, "text": comment.Text},
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// convertCommentAvatar преобразует аватар автора комментария в URL через backend проксирование
+func convertCommentAvatar(comment *PostComment) {
+	if avatar := comment.Author.Avatar; avatar != nil && *avatar != "" {
+		backendURL := ConvertMinIOURLToBackendURL(*avatar)
+		comment.Author.Avatar = &backendURL
+	}
+}
+
+// ========== Question Endpoints ==========
+
+// GetPostQuestions получает вопросы поста
+// @Summary     Вопросы и ответы поста
+// @Description Возвращает вопросы с ответами автора, последние отвеченные первыми. С unanswered=true - вопросы без ответа
+// @Description в порядке поступления: доступно автору поста, соавтору с правом edit и пользователям с правом posts.moderate.
+// @Tags        Посты
+// @Produce     json
+// @Param       id path int true "ID поста"
+// @Param       unanswered query bool false "Вопросы без ответа"
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице (до 100)" default(20)
+// @Success     200  {object}  PostQuestionsResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/questions [get]
+func (h *Handlers) GetPostQuestions(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Маршрут публичный: вопросы без ответа только для тех, кто на них отвечает или их модерирует
+	unanswered := r.URL.Query().Get("unanswered") == "true"
+	if unanswered {
+		claims, err := OptionalClaims(h.cfg, h.db, r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if claims == nil {
+			WriteError(w, NewUnauthorizedError("Не авторизован"))
+			return
+		}
+		if !h.canSeeUnansweredQuestions(post, claims) {
+			WriteError(w, NewForbiddenError("Недостаточно прав"))
+			return
+		}
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	questions, total, err := h.db.GetPostQuestions(post.ID, !unanswered, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	for i := range questions {
+		convertQuestionAvatar(&questions[i])
+	}
+
+	WriteJSON(w, http.StatusOK, PostQuestionsResponse{
+		Data: questions,
+		Pagination: PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	})
+}
+
+// CreatePostQuestion задает вопрос автору поста
+// @Summary     Задать вопрос
+// @Description Отправляет вопрос автору поста. Вопрос появится в GET /posts/{id}/questions после ответа автора, до этого
+// @Description его видят только автор, соавторы с правом edit и модераторы. Автор поста получает уведомление.
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       request body CreateQuestionRequest true "Вопрос"
+// @Success     201  {object}  PostQuestion
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Router      /posts/{id}/questions [post]
+func (h *Handlers) CreatePostQuestion(w http.ResponseWriter, r *http.Request) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if post.UserID == userID {
+		WriteError(w, NewUnprocessableError("Нельзя задать вопрос к своему посту"))
+		return
+	}
+
+	var req CreateQuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	question, err := h.db.CreatePostQuestion(post.ID, userID, req.Question)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	convertQuestionAvatar(question)
+
+	h.notifier.Enqueue(FanoutJob{
+		Type:    NotificationPostQuestion,
+		Title:   "Новый вопрос",
+		Body:    fmt.Sprintf("Вопрос к сбору «%s»: %s", post.Title, shortenText(question.Question, 100)),
+		PostID:  &post.ID,
+		UserIDs: []int64{post.UserID},
+	})
+
+	WriteJSON(w, http.StatusCreated, question)
+}
+
+// AnswerPostQuestion публикует ответ на вопрос
+// @Summary     Ответить на вопрос
+// @Description Сохраняет ответ, после чего вопрос становится публичным. Повторный ответ заменяет прежний.
+// @Description Доступно автору поста и соавтору с правом edit. Автор вопроса получает уведомление о первом ответе.
+// @Tags        Посты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       question_id path int true "ID вопроса"
+// @Param       request body AnswerQuestionRequest true "Ответ"
+// @Success     200  {object}  PostQuestion
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/questions/{question_id}/answer [put]
+func (h *Handlers) AnswerPostQuestion(w http.ResponseWriter, r *http.Request) {
+	post, question, userID, err := h.postQuestion(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if _, err := h.postAccess(post, userID, CollaboratorEdit); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req AnswerQuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	req.Answer = strings.TrimSpace(req.Answer)
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	answered, err := h.db.AnswerPostQuestion(question.ID, userID, req.Answer)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	convertQuestionAvatar(answered)
+
+	if question.Answer == nil {
+		h.notifier.Enqueue(FanoutJob{
+			Type:    NotificationQuestionAnswered,
+			Title:   "Автор ответил на вопрос",
+			Body:    fmt.Sprintf("Ответ на ваш вопрос к сбору «%s»: %s", post.Title, shortenText(req.Answer, 100)),
+			PostID:  &post.ID,
+			UserIDs: []int64{question.Author.ID},
+		})
+	}
+
+	WriteJSON(w, http.StatusOK, answered)
+}
+
+// DeletePostQuestion удаляет вопрос
+// @Summary     Удалить вопрос
+// @Description Удаляет вопрос вместе с ответом. Доступно автору вопроса, автору поста, соавтору с правом edit и пользователям
+// @Description с правом posts.moderate.
+// @Tags        Посты
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       question_id path int true "ID вопроса"
+// @Success     204  "Вопрос удален"
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/questions/{question_id} [delete]
+func (h *Handlers) DeletePostQuestion(w http.ResponseWriter, r *http.Request) {
+	post, question, userID, err := h.postQuestion(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if question.Author.ID != userID && !h.hasPermission(r.Context(), PermPostsModerate) {
+		if _, err := h.postAccess(post, userID, CollaboratorEdit); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	if err := h.db.DeletePostQuestion(question.ID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Удаление чужого вопроса (модератор или соавтор) записывается в журнал
+	if question.Author.ID != userID && post.UserID != userID {
+		h.recordAdminAction(r.Context(), AdminActionRecord{
+			Action:       AdminActionQuestionDelete,
+			TargetType:   "question",
+			TargetID:     question.ID,
+			TargetUserID: question.Author.ID,
+			OldValue:     map[string]interface{}{"post_id": post.ID, "question": question.Question, "answer": question.Answer},
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// postQuestion получает пост и вопрос из пути запроса
+func (h *Handlers) postQuestion(r *http.Request) (*Post, *PostQuestion, int64, error) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	questionID, err := strconv.ParseInt(mux.Vars(r)["question_id"], 10, 64)
+	if err != nil {
+		return nil, nil, 0, NewValidationError("Неверный ID вопроса", nil)
+	}
+	question, err := h.db.GetPostQuestion(questionID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if question.PostID != post.ID {
+		return nil, nil, 0, NewNotFoundError("Вопрос")
+	}
+	return post, question, userID, nil
+}
+
+// canSeeUnansweredQuestions проверяет, видит ли пользователь вопросы без ответа:
+// автор поста, соавтор с правом edit или модератор
+func (h *Handlers) canSeeUnansweredQuestions(post *Post, claims *Claims) bool {
+	if h.perms.Has(claims.Role, PermPostsModerate) {
+		return true
+	}
+	_, err := h.postAccess(post, claims.UserID, CollaboratorEdit)
+	return err == nil
+}
+
+// convertQuestionAvatar преобразует аватар автора вопроса в URL через backend проксирование
+func convertQuestionAvatar(question *PostQuestion) {
+	if avatar := question.Author.Avatar; avatar != nil && *avatar != "" {
+		backendURL := ConvertMinIOURLToBackendURL(*avatar)
+		question.Author.Avatar = &backendURL
+	}
+}
+
+// shortenText обрезает текст для уведомления до limit символов
+func shortenText(text string, limit int) string {
+	if runes := []rune(text); len(runes) > limit {
+		return string(runes[:limit]) + "…"
+	}
+	return text
+}
+
+// ========== Favorite Endpoints ==========
+
+// AddFavorite добавляет пост в избранное
+// @Summary     Добавить в избранное
+// @Description Сохраняет пост в избранное текущего пользователя. Повторное добавление не ошибка.
+// @Tags        Посты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Success     204  "Пост в избранном"
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/favorite [post]
+func (h *Handlers) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	post, userID, err := h.collaboratorPost(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if err := h.db.AddFavorite(userID, post.ID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveFavorite убирает пост из избранного
+// @Summary     Убрать из избранного
+// @Description Убирает пост из избранного текущего пользователя. Удаление отсутствующего поста не ошибка.
+// @Tags        Посты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Success     204  "Пост убран из избранного"
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /posts/{id}/favorite [delete]
+func (h *Handlers) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if err := h.db.RemoveFavorite(userID, postID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMyFavorites получает избранные посты
+// @Summary     Избранные посты
+// @Description Возвращает посты из избранного текущего пользователя с авторами и медиа, последние добавленные первыми
+// @Tags        Посты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице (до 100)" default(20)
+// @Success     200  {object}  PostsListResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/favorites [get]
+func (h *Handlers) GetMyFavorites(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	posts, total, err := h.db.GetFavoritePosts(userID, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if posts == nil {
+		posts = []PostWithDetails{}
+	}
+	convertPostsWithDetailsURLs(posts)
+
+	response := map[string]interface{}{
+		"data": posts,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// ========== Feed Preference Endpoints ==========
+
+// GetHiddenPosts получает скрытые посты
+// @Summary     Скрытые посты
+// @Description Возвращает посты, скрытые текущим пользователем из ленты, последние скрытые первыми
+// @Tags        Пользователи
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {array}   HiddenPost
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/hidden-posts [get]
+func (h *Handlers) GetHiddenPosts(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	posts, err := h.db.GetHiddenPosts(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, posts)
+}
+
+// HidePost скрывает пост из ленты
+// @Summary     Скрыть пост
+// @Description Убирает пост из ленты GET /posts текущего пользователя. Повторное скрытие не ошибка.
+// @Tags        Пользователи
+// @Accept      json
+// @Security    BearerAuth
+// @Param       request body HidePostRequest true "Пост"
+// @Success     204  "Пост скрыт"
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/hidden-posts [post]
+func (h *Handlers) HidePost(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	var req HidePostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if _, err := h.db.GetPostByID(req.PostID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if err := h.db.HidePost(userID, req.PostID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnhidePost возвращает пост в ленту
+// @Summary     Вернуть пост в ленту
+// @Description Отменяет скрытие поста. Отмена для нескрытого поста не ошибка.
+// @Tags        Пользователи
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Success     204  "Пост возвращен в ленту"
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/hidden-posts/{id} [delete]
+func (h *Handlers) UnhidePost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if err := h.db.UnhidePost(userID, postID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMutedAuthors получает скрытых авторов
+// @Summary     Скрытые авторы
+// @Description Возвращает авторов, посты которых текущий пользователь скрыл из ленты, последних скрытых первыми
+// @Tags        Пользователи
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {array}   MutedAuthor
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/muted-authors [get]
+func (h *Handlers) GetMutedAuthors(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	authors, err := h.db.GetMutedAuthors(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	for i := range authors {
+		if avatar := authors[i].Author.Avatar; avatar != nil && *avatar != "" {
+			backendURL := ConvertMinIOURLToBackendURL(*avatar)
+			authors[i].Author.Avatar = &backendURL
+		}
+	}
+	WriteJSON(w, http.StatusOK, authors)
+}
+
+// MuteAuthor скрывает посты автора из ленты
+// @Summary     Скрыть автора
+// @Description Убирает посты автора из ленты GET /posts текущего пользователя. Посты автора по-прежнему доступны по фильтру user_id. Повторное скрытие не ошибка.
+// @Tags        Пользователи
+// @Accept      json
+// @Security    BearerAuth
+// @Param       request body MuteAuthorRequest true "Автор"
+// @Success     204  "Автор скрыт"
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/muted-authors [post]
+func (h *Handlers) MuteAuthor(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	var req MuteAuthorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if req.AuthorID == userID {
+		WriteError(w, NewValidationError("Нельзя скрыть собственные посты", map[string]interface{}{"field": "author_id"}))
+		return
+	}
+	if _, err := h.db.GetUserByID(req.AuthorID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if err := h.db.MuteAuthor(userID, req.AuthorID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnmuteAuthor возвращает посты автора в ленту
+// @Summary     Вернуть автора в ленту
+// @Description Отменяет скрытие автора. Отмена для нескрытого автора не ошибка.
+// @Tags        Пользователи
+// @Security    BearerAuth
+// @Param       id path int true "ID автора"
+// @Success     204  "Автор возвращен в ленту"
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/muted-authors/{id} [delete]
+func (h *Handlers) UnmuteAuthor(w http.ResponseWriter, r *http.Request) {
+	authorID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID автора", nil))
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if err := h.db.UnmuteAuthor(userID, authorID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ========== Donation Endpoints ==========
+
+// CreateDonation создает пожертвование
+// @Summary     Создать пожертвование
+// @Description Создает новое пожертвование для поста. Донора анонимного пожертвования видят только он сам и администраторы,
+// @Description баллы рейтинга начисляются как обычно.
+// @Tags        Пожертвования
+// @Accept      multipart/form-data
+// @Produce     json
+// @Security    BearerAuth
+// @Param       post_id formData int true "ID поста"
+// @Param       amount formData number true "Сумма пожертвования"
+// @Param       is_anonymous formData bool false "Скрыть донора"
+// @Param       receipt formData file false "Чек/скриншот (JPEG, PNG, PDF, до 10MB)"
+// @Param       Idempotency-Key header string false "Ключ идемпотентности (до 255 символов): повтор с тем же ключом возвращает сохраненный ответ с заголовком Idempotent-Replayed"
+// @Success     201  {object}  DonationResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Router      /donations [post]
+func (h *Handlers) CreateDonation(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := ParseMultipartForm(r, 10<<20); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req CreateDonationRequest
+	req.PostID, _ = strconv.ParseInt(r.FormValue("post_id"), 10, 64)
+	req.Amount, _ = strconv.ParseFloat(r.FormValue("amount"), 64)
+	req.IsAnonymous, _ = strconv.ParseBool(r.FormValue("is_anonymous"))
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	req.Amount = h.cfg.Money.RoundAmount(req.Amount)
+	if err := h.cfg.Money.ValidateDonationAmount(req.Amount); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Проверяем существование поста
+	post, err := h.db.GetPostByID(req.PostID)
+	if err != nil {
+		WriteError(w, NewNotFoundError("Пост"))
+		return
+	}
+
+	donation := &Donation{
+		PostID:      req.PostID,
+		DonorID:     userID,
+		Amount:      req.Amount,
+		IsAnonymous: req.IsAnonymous,
+	}
+
+	// Загружаем чек если есть
+	if receipt, header, err := r.FormFile("receipt"); err == nil {
+		defer receipt.Close()
+
+		if err := ValidateFileSize(header, 10<<20); err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		if err := ValidateDocumentFile(header); err != nil {
+			WriteError(w, err)
+			return
+		}
+		if err := h.scanUpload(r.Context(), "receipt", userID, receipt, header); err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		// Чек загружается под ID пожертвования в транзакции его создания: если загрузка не
+		// удалась, пожертвование не создается
+		ctx := r.Context()
+		var objectKey string
+		err := h.db.CreateDonationWithReceipt(donation, func(donationID int64) (string, error) {
+			var err error
+			objectKey, err = UploadDonationReceipt(ctx, h.minioClient, donationID, receipt, header.Size, header.Header.Get("Content-Type"))
+			if err != nil {
+				log.Printf("Failed to upload receipt of donation %d: %v", donationID, err)
+				return "", NewInternalError("Ошибка загрузки чека")
+			}
+			return GetObjectURL(h.cfg.MinIOConfig, BucketDonationReceipts, objectKey), nil
+		})
+		if err != nil {
+			// Чек без пожертвования не нужен
+			if objectKey != "" {
+				if err := h.minioClient.RemoveObject(ctx, BucketDonationReceipts, objectKey, minio.RemoveObjectOptions{}); err != nil {
+					log.Printf("Failed to remove receipt %s of not created donation: %v", objectKey, err)
+				}
+			}
+			WriteError(w, err)
+			return
+		}
+
+		if h.cfg.OCR.Provider != "" {
+			if err := h.db.QueueReceiptCheck(donation.ID); err != nil {
+				log.Printf("Failed to queue receipt check for donation %d: %v", donation.ID, err)
+			} else {
+				donation.ReceiptCheck = getStringPtr(ReceiptCheckPending)
+			}
+		}
+	} else {
+		if err := h.db.CreateDonation(donation); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	notifyDonationIntegrations(h.db, post, donation)
+
+	response := map[string]interface{}{
+		"id":            donation.ID,
+		"post_id":       donation.PostID,
+		"donor_id":      donation.DonorID,
+		"amount":        donation.Amount,
+		"receipt_url":   donation.ReceiptURL,
+		"receipt_check": donation.ReceiptCheck,
+		"status":        donation.Status,
+		"is_anonymous":  donation.IsAnonymous,
+		"created_at":    donation.CreatedAt,
+	}
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+// GetDonations получает список пожертвований
+// @Summary     Получить список пожертвований
+// @Description Возвращает список пожертвований с фильтрацией и пагинацией. У анонимных пожертвований донор (donor_id, donor)
+// @Description виден только ему самому и пользователям с правом donations.manage, чек - еще автору поста и соавторам с правом donations.
+// @Description С фильтром donor_id анонимные пожертвования видят только они же.
+// @Tags        Пожертвования
+// @Accept      json
+// @Produce     json
+// @Param       post_id query int false "Фильтр по посту"
+// @Param       donor_id query int false "Фильтр по донору"
+// @Param       status query string false "Фильтр по статусу" Enums(pending, confirmed, rejected, refunded)
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  DonationsListResponse
+// @Router      /donations [get]
+func (h *Handlers) GetDonations(w http.ResponseWriter, r *http.Request) {
+	postIDStr := r.URL.Query().Get("post_id")
+	donorIDStr := r.URL.Query().Get("donor_id")
+	status := r.URL.Query().Get("status")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	var postID, donorID *int64
+	if postIDStr != "" {
+		id, _ := strconv.ParseInt(postIDStr, 10, 64)
+		postID = &id
+	}
+	if donorIDStr != "" {
+		id, _ := strconv.ParseInt(donorIDStr, 10, 64)
+		donorID = &id
+	}
+
+	// Маршрут публичный: авторизация нужна только, чтобы видеть доноров анонимных пожертвований
+	claims, err := OptionalClaims(h.cfg, h.db, r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	h.writeDonationsList(w, postID, donorID, status, page, limit, claims)
+}
+
+// GetMyDonations получает пожертвования текущего пользователя
+// @Summary     Получить мои пожертвования
+// @Description Возвращает пожертвования текущего пользователя. Доступно также по API токену с областью donations:read.
+// @Tags        Пожертвования
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       status query string false "Фильтр по статусу" Enums(pending, confirmed, rejected, refunded)
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  DonationsListResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /users/me/donations [get]
+func (h *Handlers) GetMyDonations(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+
+	h.writeDonationsList(w, nil, &userID, r.URL.Query().Get("status"), page, limit, &Claims{UserID: userID})
+}
+
+// writeDonationsList отдает страницу пожертвований с данными доноров и постов.
+// claims - просматривающий пользователь, nil для неавторизованного запроса.
+func (h *Handlers) writeDonationsList(w http.ResponseWriter, postID, donorID *int64, status string, page, limit int, claims *Claims) {
+	// Фильтр по донору раскрыл бы автора анонимных пожертвований
+	excludeAnonymous := donorID != nil && (claims == nil ||
+		(claims.UserID != *donorID && !h.perms.Has(claims.Role, PermDonationsManage)))
+
+	donations, total, err := h.db.GetDonations(postID, donorID, status, excludeAnonymous, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Обогащаем данными
+	var donationsWithDetails []DonationWithDetails
+	for _, donation := range donations {
+		post, _ := h.db.GetPostByID(donation.PostID)
+		showDonor := h.showAnonymousDonor(&donation, post, claims)
+
+		var donor *User
+		if showDonor {
+			donor, _ = h.db.GetUserByID(donation.DonorID)
+		}
+
+		var donorInfo *UserInfo
+		if donor != nil {
+			name := donor.FirstName + " " + donor.LastName
+			if donor.HelperName != nil {
+				name = *donor.HelperName
+			}
+			donorInfo = &UserInfo{
+				ID:     donor.ID,
+				Name:   name,
+				Avatar: donor.PhotoURL,
+			}
+		}
+
+		var postInfo *PostInfo
+		if post != nil {
+			postInfo = &PostInfo{
+				ID:        post.ID,
+				Title:     post.Title,
+				Amount:    post.Amount,
+				Collected: post.Collected,
+			}
+		}
+
+		donationsWithDetails = append(donationsWithDetails, DonationWithDetails{
+			Donation: donation,
+			Donor:    donorInfo,
+			Post:     postInfo,
+		})
+	}
+
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": donationsWithDetails,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// showAnonymousDonor возвращает, можно ли показать донора пожертвования. Донор анонимного
+// пожертвования виден только себе и пользователям с правом donations.manage, для остальных
+// он убирается из donation. Чек с именем отправителя остается автору поста и соавторам с
+// правом donations: по нему пожертвование подтверждается.
+func (h *Handlers) showAnonymousDonor(donation *Donation, post *Post, claims *Claims) bool {
+	if !donation.IsAnonymous {
+		return true
+	}
+	if claims != nil && (claims.UserID == donation.DonorID || h.perms.Has(claims.Role, PermDonationsManage)) {
+		return true
+	}
+
+	donation.DonorID = 0
+	if claims == nil || post == nil {
+		donation.ReceiptURL = nil
+	} else if _, err := h.postAccess(post, claims.UserID, CollaboratorDonations); err != nil {
+		donation.ReceiptURL = nil
+	}
+	return false
+}
+
+// GetDonation получает пожертвование по ID
+// @Summary     Получить пожертвование
+// @Description Возвращает детальную информацию о пожертвовании. Донор анонимного пожертвования скрыт так же, как в списке.
+// @Tags        Пожертвования
+// @Accept      json
+// @Produce     json
+// @Param       id path int true "ID пожертвования"
+// @Success     200  {object}  DonationWithDetails
+// @Failure     404  {object}  ErrorResponse
+// @Router      /donations/{id} [get]
+func (h *Handlers) GetDonation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	donationID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пожертвования", nil))
+		return
+	}
+
+	donation, err := h.db.GetDonationByID(donationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	claims, err := OptionalClaims(h.cfg, h.db, r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, _ := h.db.GetPostByID(donation.PostID)
+	var donor *User
+	if h.showAnonymousDonor(donation, post, claims) {
+		donor, _ = h.db.GetUserByID(donation.DonorID)
+	}
+
+	var donorInfo *UserInfo
+	if donor != nil {
+		name := donor.FirstName + " " + donor.LastName
+		if donor.HelperName != nil {
+			name = *donor.HelperName
+		}
+		donorInfo = &UserInfo{
+			ID:     donor.ID,
+			Name:   name,
+			Avatar: donor.PhotoURL,
+		}
+	}
+
+	var postInfo *PostInfo
+	if post != nil {
+		postInfo = &PostInfo{
+			ID:        post.ID,
+			Title:     post.Title,
+			Amount:    post.Amount,
+			Collected: post.Collected,
+		}
+	}
+
+	response := DonationWithDetails{
+		Donation: *donation,
+		Donor:    donorInfo,
+		Post:     postInfo,
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// UpdateDonation подтверждает/отклоняет пожертвование (только для админов или автора поста)
+// @Summary     Подтвердить/отклонить/вернуть пожертвование
+// @Description Обновляет статус пожертвования. confirmed и rejected - автор поста, соавтор с правом donations или право donations.manage.
+// @Description refunded - возврат: право donations.manage для любого пожертвования или донор, пока пожертвование ожидает подтверждения.
+// @Description Возврат подтвержденного пожертвования уменьшает собранную сумму поста и рейтинг донора, возвращенное пожертвование больше не меняется.
+// @Description Пожертвование с незавершенной или успешной оплатой картой не возвращается (409): деньги возвращает платежный провайдер.
+// @Tags        Пожертвования
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пожертвования"
+// @Param       request body UpdateDonationRequest true "Статус"
+// @Success     200  {object}  DonationUpdateResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /donations/{id} [patch]
+func (h *Handlers) UpdateDonation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	donationID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пожертвования", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	donation, err := h.db.GetDonationByID(donationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(donation.PostID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req UpdateDonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if req.Status == "refunded" {
+		if err := h.refundDonation(r, donation, post, userID); err != nil {
+			WriteError(w, err)
+			return
+		}
+		h.writeDonationStatus(w, donationID)
+		return
+	}
+
+	// Проверяем права: автор поста, соавтор с правом donations или право donations.manage
+	if !h.hasPermission(r.Context(), PermDonationsManage) {
+		if _, err := h.postAccess(post, userID, CollaboratorDonations); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	// Подтверждение и отклонение вместе с собранной суммой, рейтингом и событием
+	// пожертвования выполняются одной транзакцией
+	confirmed := false
+	rejectEvent := ""
+	var referral *Referral
+	if req.Status == "confirmed" {
+		confirmed, referral, err = h.db.ConfirmDonation(donationID, userID, h.cfg.Referrals.BonusPoints, nil)
+	} else {
+		rejectEvent, err = h.db.RejectDonation(donationID, userID)
+	}
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Решения по чужим пожертвованиям (соавтор или право donations.manage) записываются в журнал
+	if post.UserID != userID && (confirmed || rejectEvent != "") {
+		action := AdminActionDonationConfirm
+		if req.Status != "confirmed" {
+			action = AdminActionDonationReject
+		}
+		h.recordAdminAction(r.Context(), AdminActionRecord{
+			Action:       action,
+			TargetType:   "donation",
+			TargetID:     donationID,
+			TargetUserID: donation.DonorID,
+			OldValue:     map[string]interface{}{"status": donation.Status},
+			NewValue:     map[string]interface{}{"status": req.Status, "amount": donation.Amount},
+		})
+	}
+
+	if confirmed {
+		h.afterDonationConfirmed(post, donation, referral)
+	}
+	// Отмена подтверждения уменьшила собранную сумму
+	if rejectEvent == DonationEventReverted {
+		h.publishPostProgress(post.ID)
+	}
+
+	h.writeDonationStatus(w, donationID)
+}
+
+// writeDonationStatus отвечает статусом пожертвования после изменения
+func (h *Handlers) writeDonationStatus(w http.ResponseWriter, donationID int64) {
+	donation, err := h.db.GetDonationByID(donationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	response := map[string]interface{}{
+		"id":           donation.ID,
+		"status":       donation.Status,
+		"confirmed_at": donation.ConfirmedAt,
+		"confirmed_by": donation.ConfirmedBy,
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// publishPostProgress отправляет подписчикам поста собранную сумму после ее уменьшения
+func (h *Handlers) publishPostProgress(postID int64) {
+	updated, err := h.db.GetPostByID(postID)
+	if err != nil {
+		log.Printf("Failed to get post %d for progress event: %v", postID, err)
+		return
+	}
+	h.events.Publish(PostTopic(updated.ID), *postProgressEvent(updated))
+}
+
+// refundDonation возвращает пожертвование: право donations.manage - любое, донор -
+// свое, пока оно ожидает подтверждения. Возврат за другого пользователя записывается в журнал.
+func (h *Handlers) refundDonation(r *http.Request, donation *Donation, post *Post, userID int64) error {
+	manager := h.hasPermission(r.Context(), PermDonationsManage)
+	if !manager && donation.DonorID != userID {
+		return NewForbiddenError("Отменить пожертвование может только донор или администратор")
+	}
+
+	previous, err := h.db.RefundDonation(donation.ID, userID, !manager)
+	if err != nil || previous == "" {
+		return err
+	}
+
+	if donation.DonorID != userID {
+		h.recordAdminAction(r.Context(), AdminActionRecord{
+			Action:       AdminActionDonationRefund,
+			TargetType:   "donation",
+			TargetID:     donation.ID,
+			TargetUserID: donation.DonorID,
+			OldValue:     map[string]interface{}{"status": previous},
+			NewValue:     map[string]interface{}{"status": "refunded", "amount": donation.Amount},
+		})
+	}
+	if previous == "confirmed" {
+		h.publishPostProgress(post.ID)
+	}
+	return nil
+}
+
+// CancelDonation отменяет пожертвование
+// @Summary     Отменить пожертвование
+// @Description То же, что PATCH /donations/{id} со статусом refunded: донор отменяет свое пожертвование, пока оно ожидает
+// @Description подтверждения, право donations.manage возвращает любое, кроме оплаченных или оплачиваемых картой. Пожертвование
+// @Description не удаляется, а получает статус refunded; возврат подтвержденного уменьшает собранную сумму поста и рейтинг донора.
+// @Tags        Пожертвования
+// @Security    BearerAuth
+// @Param       id path int true "ID пожертвования"
+// @Success     204  "Пожертвование отменено"
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /donations/{id} [delete]
+func (h *Handlers) CancelDonation(w http.ResponseWriter, r *http.Request) {
+	donationID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пожертвования", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	donation, err := h.db.GetDonationByID(donationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	post, err := h.db.GetPostByID(donation.PostID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := h.refundDonation(r, donation, post, userID); err != nil {
+		WriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDonationHistory возвращает историю пожертвования
+// @Summary     История пожертвования
+// @Description Возвращает события пожертвования (created, receipt_attached, confirmed, rejected, reverted, refunded) с автором и данными изменения и статус, восстановленный по событиям. Доступно жертвователю, автору поста, соавтору с правом donations и пользователям с правом donations.manage. Донор анонимного пожертвования скрыт от автора и соавторов: actor_id его событий не возвращается.
+// @Tags        Пожертвования
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пожертвования"
+// @Success     200  {object}  DonationHistoryResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /donations/{id}/history [get]
+func (h *Handlers) GetDonationHistory(w http.ResponseWriter, r *http.Request) {
+	donationID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пожертвования", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	donation, err := h.db.GetDonationByID(donationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	showDonor := donation.DonorID == userID || h.hasPermission(r.Context(), PermDonationsManage)
+	if !showDonor {
+		post, err := h.db.GetPostByID(donation.PostID)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if _, err := h.postAccess(post, userID, CollaboratorDonations); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	events, err := h.db.GetDonationEvents(donationID)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка получения истории пожертвования"))
+		return
+	}
+
+	// Донор анонимного пожертвования скрыт, как в showAnonymousDonor: он указан
+	// в событии created и в возврате, который выполнил сам
+	if donation.IsAnonymous && !showDonor {
+		for i := range events {
+			if events[i].ActorID != nil && *events[i].ActorID == donation.DonorID {
+				events[i].ActorID = nil
+			}
+		}
+	}
+
+	status := donationStatusFromEvents(events)
+	WriteJSON(w, http.StatusOK, DonationHistoryResponse{
+		DonationID: donationID,
+		Status:     status,
+		Consistent: status == donation.Status,
+		Events:     events,
+	})
+}
+
+// GetPostDonationStats получает статистику пожертвований поста
+// @Summary     Статистика пожертвований поста
+// @Description Для графиков сбора: суммы и количество пожертвований по статусам, уникальные доноры и средняя сумма,
+// @Description подтвержденные суммы по дням за последние days дней (дни в часовом поясе автора, дни без пожертвований - с нулями)
+// @Description и доноры с наибольшей суммой. Анонимные пожертвования входят в итоги (anonymous_amount), но не в список доноров.
+// @Description Доступна автору поста, соавторам с правом donations и пользователям с правом donations.manage.
+// @Tags        Пожертвования
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       days query int false "Число дней временного ряда, до 365" default(30)
+// @Param       top query int false "Число доноров, до 50" default(10)
+// @Success     200  {object}  DonationStatsResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/donations/stats [get]
+func (h *Handlers) GetPostDonationStats(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if !h.hasPermission(r.Context(), PermDonationsManage) {
+		if _, err := h.postAccess(post, userID, CollaboratorDonations); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days < 1 {
+		days = 30
+	}
+	if days > 365 {
+		days = 365
+	}
+	top, _ := strconv.Atoi(r.URL.Query().Get("top"))
+	if top < 1 {
+		top = 10
+	}
+	if top > 50 {
+		top = 50
+	}
+
+	stats, err := h.db.GetPostDonationStats(post, days, top)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, stats)
+}
+
+// GetDonationReceipt возвращает квитанцию о подтвержденном пожертвовании
+// @Summary     Квитанция о пожертвовании
+// @Description Возвращает PDF квитанцию: сбор, получатель, жертвователь, сумма и дата подтверждения в часовом поясе жертвователя.
+// @Description Открывается по ссылке из уведомления (параметр token) без входа, иначе доступна жертвователю, автору поста,
+// @Description соавтору с правом donations и пользователям с правом donations.manage.
+// @Tags        Пожертвования
+// @Produce     application/pdf
+// @Security    BearerAuth
+// @Param       id    path  int    true  "ID пожертвования"
+// @Param       token query string false "Подпись ссылки из уведомления"
+// @Success     200  {file}    binary
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Router      /donations/{id}/receipt [get]
+func (h *Handlers) GetDonationReceipt(w http.ResponseWriter, r *http.Request) {
+	donationID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пожертвования", nil))
+		return
+	}
+
+	donation, err := h.db.GetDonationByID(donationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	post, err := h.db.GetPostByID(donation.PostID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Маршрут публичный: без подписанной ссылки проверяется JWT. Соавтору донор
+	// анонимного пожертвования не показывается.
+	showDonor := true
+	if token := r.URL.Query().Get("token"); token == "" || !ValidReceiptToken(h.cfg.JWTSecret, donationID, token) {
+		claims, err := OptionalClaims(h.cfg, h.db, r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if claims == nil {
+			WriteError(w, NewUnauthorizedError("Не авторизован"))
+			return
+		}
+		if donation.DonorID != claims.UserID && !h.perms.Has(claims.Role, PermDonationsManage) {
+			if _, err := h.postAccess(post, claims.UserID, CollaboratorDonations); err != nil {
+				WriteError(w, err)
+				return
+			}
+			showDonor = !donation.IsAnonymous
+		}
+	}
+
+	if donation.Status != "confirmed" || donation.ConfirmedAt == nil {
+		WriteError(w, NewUnprocessableError("Квитанция доступна только для подтвержденного пожертвования"))
+		return
+	}
+
+	donor, err := h.db.GetUserByID(donation.DonorID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	loc, err := LoadUserLocation(donor.Timezone)
+	if err != nil {
+		loc, _ = LoadUserLocation(DefaultTimezone)
+	}
+
+	receipt := DonationReceipt{
+		DonationID:  donation.ID,
+		PostTitle:   post.Title,
+		Recipient:   post.Recipient,
+		Amount:      donation.Amount,
+		ConfirmedAt: *donation.ConfirmedAt,
+	}
+	if showDonor {
+		receipt.DonorName = strings.TrimSpace(donor.FirstName + " " + donor.LastName)
+	}
+	data, err := RenderDonationReceipt(receipt, loc)
+	if err != nil {
+		log.Printf("Failed to render receipt of donation %d: %v", donation.ID, err)
+		WriteError(w, NewInternalError("Ошибка создания квитанции"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"receipt-%d.pdf\"", donation.ID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// afterDonationConfirmed уведомляет о подтвержденном пожертвовании: вехи сбора, прогресс
+// на открытых страницах поста, жертвователя и участников реферальной программы.
+// post.Collected - сумма до подтверждения.
+func (h *Handlers) afterDonationConfirmed(post *Post, donation *Donation, referral *Referral) {
+	h.notifyPostMilestone(post, post.Collected+donation.Amount)
+
+	// Открытые страницы поста получают собранную сумму после подтверждения
+	if updated, err := h.db.GetPostByID(post.ID); err != nil {
+		log.Printf("Failed to get post %d for progress event: %v", post.ID, err)
+	} else {
+		h.events.Publish(PostTopic(updated.ID), *postProgressEvent(updated))
+	}
+
+	emitPartnerWebhook(h.db, WebhookEventDonationConfirmed, map[string]interface{}{
+		"donation_id":  donation.ID,
+		"post_id":      post.ID,
+		"amount":       donation.Amount,
+		"collected":    post.Collected + donation.Amount,
+		"confirmed_at": time.Now(),
+	})
+
+	// Квитанция жертвователю: в приложении, push и SMS по его настройкам каналов
+	confirmedAt := time.Now()
+	if donor, err := h.db.GetUserByID(donation.DonorID); err != nil {
+		log.Printf("Failed to get donor %d for receipt: %v", donation.DonorID, err)
+	} else if loc, err := LoadUserLocation(donor.Timezone); err == nil {
+		confirmedAt = confirmedAt.In(loc)
+	}
+	h.notifier.Enqueue(FanoutJob{
+		Type:  NotificationDonationConfirmed,
+		Title: "Пожертвование подтверждено",
+		Body: fmt.Sprintf("Ваше пожертвование %.2f ₽ на сбор «%s» подтверждено %s. Спасибо!",
+			donation.Amount, post.Title, confirmedAt.Format("02.01.2006")),
+		PostID:  &post.ID,
+		UserIDs: []int64{donation.DonorID},
+		Data: map[string]string{
+			"donation_id": strconv.FormatInt(donation.ID, 10),
+			"receipt_url": ReceiptURL(h.cfg, donation.ID),
+		},
+	})
+
+	if referral != nil && referral.BonusPoints > 0 {
+		h.notifier.Enqueue(FanoutJob{
+			Type:    NotificationReferralBonus,
+			Title:   "Бонус за приглашение",
+			Body:    fmt.Sprintf("Приглашенный вами пользователь сделал первое пожертвование. Начислено баллов: %d.", referral.BonusPoints),
+			UserIDs: []int64{referral.ReferrerID},
+		})
+		h.notifier.Enqueue(FanoutJob{
+			Type:    NotificationReferralBonus,
+			Title:   "Бонус за первое пожертвование",
+			Body:    fmt.Sprintf("Спасибо за первое пожертвование! Вам и пригласившему вас пользователю начислено баллов: %d.", referral.BonusPoints),
+			UserIDs: []int64{referral.RefereeID},
+		})
+	}
+}
+
+// ========== Payment Endpoints ==========
+
+// PayDonation создает оплату пожертвования картой
+// @Summary     Оплатить пожертвование
+// @Description Создает платеж у платежного шлюза (PAYMENT_PROVIDER) и возвращает confirmation_url страницы оплаты.
+// @Description Пока платеж не завершен, повторный запрос возвращает его же. После оплаты пожертвование подтверждается
+// @Description автоматически по уведомлению шлюза. Доступно жертвователю для ожидающего подтверждения пожертвования.
+// @Tags        Пожертвования
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID пожертвования"
+// @Success     200  {object}  Payment
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     422  {object}  ErrorResponse
+// @Failure     503  {object}  ErrorResponse
+// @Router      /donations/{id}/pay [post]
+func (h *Handlers) PayDonation(w http.ResponseWriter, r *http.Request) {
+	donationID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID пожертвования", nil))
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if h.payments == nil {
+		WriteError(w, NewUnprocessableError("Оплата картой не подключена"))
+		return
+	}
+
+	donation, err := h.db.GetDonationByID(donationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if donation.DonorID != userID {
+		WriteError(w, NewForbiddenError("Оплатить можно только свое пожертвование"))
+		return
+	}
+	if donation.Status != "pending" {
+		WriteError(w, NewUnprocessableError("Пожертвование уже обработано"))
+		return
+	}
+	post, err := h.db.GetPostByID(donation.PostID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	payment, err := h.db.OpenPayment(donation.ID, h.payments.Name(), donation.Amount)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if payment.Status == PaymentStatusPending && payment.ConfirmationURL != nil {
+		WriteJSON(w, http.StatusOK, payment)
+		return
+	}
+
+	// Описание платежа у ЮKassa не длиннее 128 символов
+	description := []rune(fmt.Sprintf("Пожертвование №%d на сбор «%s»", donation.ID, post.Title))
+	if len(description) > 128 {
+		description = append(description[:127], '…')
+	}
+
+	// Ключ идемпотентности постоянный для платежа: если ответ провайдера потерялся,
+	// повторный запрос вернет уже созданный у него платеж, а не спишет деньги дважды
+	created, err := h.payments.CreatePayment(r.Context(), PaymentRequest{
+		IdempotenceKey: fmt.Sprintf("donation-%d-payment-%d", donation.ID, payment.ID),
+		DonationID:     donation.ID,
+		Amount:         donation.Amount,
+		Currency:       h.cfg.Money.Currency,
+		Description:    string(description),
+		ReturnURL:      strings.ReplaceAll(h.cfg.Payments.ReturnURL, "{id}", strconv.FormatInt(donation.ID, 10)),
+	})
+	if err != nil {
+		log.Printf("Failed to create payment for donation %d: %v", donation.ID, err)
+		WriteError(w, NewServiceUnavailableError("Платежный сервис временно недоступен"))
+		return
+	}
+	payment, err = h.db.SetPaymentCreated(payment.ID, created.ID, created.Status, created.ConfirmationURL)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	metrics.Inc("payments_created_total", "Donation payments created at the payment provider", map[string]string{"provider": payment.Provider})
+	WriteJSON(w, http.StatusOK, payment)
+}
+
+// PaymentWebhook принимает уведомления платежного шлюза
+// @Summary     Уведомление платежного шлюза
+// @Description Принимает уведомление о смене статуса платежа. Статус перечитывается у провайдера, повторные уведомления
+// @Description ничего не меняют. Успешная оплата подтверждает пожертвование (событие confirmed с payment_id), отмена
+// @Description оставляет его ожидающим: жертвователь может оплатить снова или приложить чек.
+// @Tags        Пожертвования
+// @Accept      json
+// @Success     200  "Уведомление обработано"
+// @Failure     400  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     500  {object}  ErrorResponse
+// @Router      /payments/webhook [post]
+func (h *Handlers) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.payments == nil {
+		WriteError(w, NewNotFoundError("Платежный шлюз"))
+		return
+	}
+
+	update, err := h.payments.ParseWebhook(r.Context(), r)
+	if err != nil {
+		// Ошибка проверки у провайдера возвращается как 500, чтобы он повторил уведомление
+		WriteError(w, err)
+		return
+	}
+	if update == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	payment, changed, err := h.db.UpdatePaymentStatus(h.payments.Name(), update.ID, update.Status)
+	if err != nil {
+		if appErr, ok := err.(*AppError); ok && appErr.Code == ErrCodeNotFound {
+			// Платеж создан не через API (например, вручную в личном кабинете)
+			log.Printf("Payment webhook for unknown %s payment %s", h.payments.Name(), update.ID)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		WriteError(w, err)
+		return
+	}
+	if changed {
+		metrics.Inc("payment_status_changes_total", "Donation payment status changes from provider webhooks", map[string]string{"status": payment.Status})
+	}
+	if payment.Status != PaymentStatusSucceeded {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	donation, err := h.db.GetDonationByID(payment.DonationID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	// Повторное уведомление подтверждает пожертвование, только если прошлая попытка
+	// не дошла до подтверждения; отклоненное после оплаты автором не трогаем
+	if !changed && donation.Status != "pending" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	// Администратор вернул пожертвование во время оплаты: деньги возвращаются вне системы
+	if donation.Status == "refunded" {
+		log.Printf("Payment %d of donation %d succeeded after refund, not confirming", payment.ID, donation.ID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if math.Abs(update.Amount-payment.Amount) >= 0.005 {
+		log.Printf("Payment %d of donation %d: paid %.2f instead of %.2f, not confirming", payment.ID, donation.ID, update.Amount, payment.Amount)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	post, err := h.db.GetPostByID(donation.PostID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	metadata := map[string]interface{}{
+		"payment_id":          payment.ID,
+		"provider":            payment.Provider,
+		"provider_payment_id": update.ID,
+	}
+	confirmed, referral, err := h.db.ConfirmDonation(donation.ID, 0, h.cfg.Referrals.BonusPoints, metadata)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if confirmed {
+		h.afterDonationConfirmed(post, donation, referral)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ========== Statement Import Endpoints ==========
+
+// ImportDonationStatement загружает выписку банка для сверки пожертвований
+// @Summary     Загрузить выписку банка
+// @Description Принимает CSV выписку (до 5MB, разделитель ; , или табуляция, UTF-8 или Windows-1251) в поле file.
+// @Description Столбцы находятся по заголовкам: дата, сумма, отправитель, назначение платежа. Каждый входящий перевод
+// @Description сопоставляется с ожидающим подтверждения пожертвованием поста: сумма до копейки, дата (не дальше 7 дней),
+// @Description телефон и имя жертвователя в отправителе или назначении. Пожертвования не подтверждаются: предложения
+// @Description с уверенностью от 0 до 1 сохраняются для проверки и подтверждаются в POST /statement-imports/{id}/confirm.
+// @Description Доступно автору поста и роли с правом donations.manage.
+// @Tags        Пожертвования
+// @Accept      multipart/form-data
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID поста"
+// @Param       file formData file true "CSV выписка"
+// @Success     201  {object}  StatementImportResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /posts/{id}/statement-imports [post]
+func (h *Handlers) ImportDonationStatement(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		return
+	}
+
+	userID, _, err := h.statementPostAccess(r, postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := ParseMultipartForm(r, maxStatementSize); err != nil {
+		WriteError(w, err)
+		return
+	}
+	file, header, err := GetFileFromForm(r, "file")
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	defer file.Close()
+
+	if err := ValidateFileSize(header, maxStatementSize); err != nil {
+		WriteError(w, err)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxStatementSize+1))
+	if err != nil {
+		WriteError(w, NewValidationError("Не удалось прочитать файл", nil))
+		return
+	}
+
+	loc, err := LoadUserLocation(DefaultTimezone)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка загрузки часового пояса"))
+		return
+	}
+	transfers, skipped, err := ParseBankStatement(data, loc)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	candidates, err := h.db.GetStatementCandidates(postID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	rows := MatchStatement(transfers, candidates, loc)
+
+	imp := &StatementImport{
+		PostID:      postID,
+		UploadedBy:  userID,
+		FileName:    header.Filename,
+		RowsSkipped: skipped,
+	}
+	if err := h.db.CreateStatementImport(imp, rows); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, newStatementImportResponse(imp, rows))
+}
+
+// GetStatementImport получает выписку с предложенными подтверждениями
+// @Summary     Получить выписку банка
+// @Description Возвращает строки выписки с предложенными пожертвованиями, уверенностью и результатом подтверждения
+// @Tags        Пожертвования
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID выписки"
+// @Success     200  {object}  StatementImportResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /statement-imports/{id} [get]
+func (h *Handlers) GetStatementImport(w http.ResponseWriter, r *http.Request) {
+	imp, _, err := h.statementImportAccess(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	rows, err := h.db.GetStatementRows(imp.ID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, newStatementImportResponse(imp, rows))
+}
+
+// ConfirmStatementImport подтверждает предложенные по выписке пожертвования
+// @Summary     Подтвердить пожертвования по выписке
+// @Description Подтверждает пожертвования из строк со статусом proposed: перечисленных в row_ids и/или всех
+// @Description с уверенностью не ниже min_confidence. Подтверждение выполняется как PATCH /donations/{id}
+// @Description (собранная сумма, рейтинг, уведомления). Строка получает статус confirmed, либо skipped,
+// @Description если пожертвование уже подтверждено или отклонено.
+// @Tags        Пожертвования
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID выписки"
+// @Param       request body ConfirmStatementRequest true "Строки для подтверждения"
+// @Success     200  {object}  StatementImportResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /statement-imports/{id}/confirm [post]
+func (h *Handlers) ConfirmStatementImport(w http.ResponseWriter, r *http.Request) {
+	imp, post, err := h.statementImportAccess(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req ConfirmStatementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if len(req.RowIDs) == 0 && req.MinConfidence == nil {
+		WriteError(w, NewValidationError("Укажите row_ids или min_confidence", nil))
+		return
+	}
+
+	rows, err := h.db.GetStatementRows(imp.ID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	selected := make(map[int64]bool, len(req.RowIDs))
+	for _, id := range req.RowIDs {
+		selected[id] = true
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.Status != StatementRowProposed || row.DonationID == nil {
+			continue
+		}
+		if !selected[row.ID] && (req.MinConfidence == nil || row.Confidence < *req.MinConfidence) {
+			continue
+		}
+
+		// Отклоненное после загрузки выписки пожертвование не подтверждается
+		donation, err := h.db.GetDonationByID(*row.DonationID)
+		if err != nil {
+			if appErr, ok := err.(*AppError); !ok || appErr.Code != ErrCodeNotFound {
+				WriteError(w, err)
+				return
+			}
+		}
+		confirmed := false
+		var referral *Referral
+		if donation != nil && donation.Status == "pending" {
+			metadata := map[string]interface{}{"statement_import_id": imp.ID, "statement_row": row.RowNumber}
+			confirmed, referral, err = h.db.ConfirmDonation(donation.ID, userID, h.cfg.Referrals.BonusPoints, metadata)
+			if err != nil {
+				WriteError(w, err)
+				return
+			}
+		}
+
+		row.Status = StatementRowSkipped
+		if confirmed {
+			row.Status = StatementRowConfirmed
+		}
+		if err := h.db.SetStatementRowStatus(row.ID, row.Status, userID); err != nil {
+			WriteError(w, err)
+			return
+		}
+		now := time.Now()
+		row.ReviewedBy, row.ReviewedAt = &userID, &now
+		if !confirmed {
+			continue
+		}
+
+		if post.UserID != userID {
+			h.recordAdminAction(r.Context(), AdminActionRecord{
+				Action:       AdminActionDonationConfirm,
+				TargetType:   "donation",
+				TargetID:     donation.ID,
+				TargetUserID: donation.DonorID,
+				OldValue:     map[string]interface{}{"status": donation.Status},
+				NewValue: map[string]interface{}{"status": "confirmed", "amount": donation.Amount,
+					"statement_import_id": imp.ID, "confidence": row.Confidence},
+			})
+		}
+		h.afterDonationConfirmed(post, donation, referral)
+		post.Collected += donation.Amount
+	}
+
+	WriteJSON(w, http.StatusOK, newStatementImportResponse(imp, rows))
+}
+
+// statementPostAccess проверяет, что текущий пользователь - автор поста, соавтор с правом
+// donations или имеет право donations.manage
+func (h *Handlers) statementPostAccess(r *http.Request, postID int64) (int64, *Post, error) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		return 0, nil, err
+	}
+	post, err := h.db.GetPostByID(postID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !h.hasPermission(r.Context(), PermDonationsManage) {
+		if _, err := h.postAccess(post, userID, CollaboratorDonations); err != nil {
+			return 0, nil, err
+		}
+	}
+	return userID, post, nil
+}
+
+// statementImportAccess получает выписку из пути и ее пост с проверкой прав
+func (h *Handlers) statementImportAccess(r *http.Request) (*StatementImport, *Post, error) {
+	importID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return nil, nil, NewValidationError("Неверный ID выписки", nil)
+	}
+	imp, err := h.db.GetStatementImport(importID)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, post, err := h.statementPostAccess(r, imp.PostID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return imp, post, nil
+}
+
+func newStatementImportResponse(imp *StatementImport, rows []StatementRow) StatementImportResponse {
+	response := StatementImportResponse{Import: imp, Rows: rows}
+	for _, row := range rows {
+		switch row.Status {
+		case StatementRowProposed:
+			response.Proposed++
+		case StatementRowUnmatched:
+			response.Unmatched++
+		}
+	}
+	return response
+}
+
+// ========== Chat Endpoints ==========
+
+// GetChats получает список чатов текущего пользователя
+// @Summary     Получить список чатов
+// @Description Возвращает список всех чатов текущего пользователя
+// @Tags        Чаты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  ChatsListResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /chats [get]
+func (h *Handlers) GetChats(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Пост, собеседник, последнее сообщение и непрочитанные - одним запросом
+	chatsWithDetails, err := h.db.GetChatsWithDetails(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": chatsWithDetails,
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// CreateChat создает чат (при первом сообщении помощника к посту)
+// @Summary     Создать чат
+// @Description Создает новый чат между помощником и нуждающимся по посту
+// @Tags        Чаты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body CreateChatRequest true "ID поста"
+// @Success     201  {object}  ChatResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /chats [post]
+func (h *Handlers) CreateChat(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req CreateChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	post, err := h.db.GetPostByID(req.PostID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if post.UserID == userID {
+		WriteError(w, NewValidationError("Нельзя создать чат по своему посту", map[string]interface{}{"field": "post_id"}))
+		return
+	}
+
+	// Проверяем, существует ли уже чат
+	existingChat, _ := h.db.GetChatByPostAndHelper(req.PostID, userID)
+	if existingChat != nil {
+		WriteError(w, NewConflictError("Чат уже существует"))
+		return
+	}
+
+	chat, err := h.db.CreateChat(req.PostID, userID, post.UserID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"id":         chat.ID,
+		"post_id":    chat.PostID,
+		"helper_id":  chat.HelperID,
+		"needy_id":   chat.NeedyID,
+		"created_at": chat.CreatedAt,
+	}
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+// GetMessages получает сообщения чата
+// @Summary     Получить сообщения чата
+// @Description Возвращает сообщения чата от старых к новым. С before_id возвращаются сообщения старше указанного
+// @Description (next_cursor - следующий before_id для подгрузки истории), с after_id - новее указанного
+// @Description (next_cursor - следующий after_id для получения новых сообщений). Без курсора работает постраничная выдача page/limit.
+// @Tags        Чаты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID чата"
+// @Param       before_id query int false "Сообщения старше сообщения с этим ID"
+// @Param       after_id query int false "Сообщения новее сообщения с этим ID"
+// @Param       page query int false "Номер страницы (без курсора)" default(1)
+// @Param       limit query int false "Количество сообщений" default(50)
+// @Success     200  {object}  MessagesListResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /chats/{id}/messages [get]
+func (h *Handlers) GetMessages(w http.ResponseWriter, r *http.Request) {
+	chatID, _, err := h.chatParticipant(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	beforeID, err := parseOptionalID(r.URL.Query().Get("before_id"))
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный before_id", map[string]interface{}{"field": "before_id"}))
+		return
+	}
+	afterID, err := parseOptionalID(r.URL.Query().Get("after_id"))
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный after_id", map[string]interface{}{"field": "after_id"}))
+		return
+	}
+	if beforeID != nil && afterID != nil {
+		WriteError(w, NewValidationError("Укажите только before_id или только after_id", nil))
+		return
+	}
+
+	var messages []Message
+	var hasMore bool
+	var pagination *PaginationResponse
+	if beforeID != nil || afterID != nil {
+		messages, hasMore, err = h.db.GetMessagesByCursor(chatID, beforeID, afterID, limit)
+	} else {
+		var total int
+		messages, total, err = h.db.GetMessages(chatID, page, limit)
+		hasMore = page*limit < total
+		pagination = &PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		}
+	}
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	// Курсор следующей страницы: для after_id - самое новое сообщение (или прежний курсор,
+	// если новых нет), иначе - самое старое сообщение, если есть более ранние
+	var nextCursor *int64
+	switch {
+	case afterID != nil && len(messages) > 0:
+		nextCursor = &messages[len(messages)-1].ID
+	case afterID != nil:
+		nextCursor = afterID
+	case hasMore && len(messages) > 0:
+		nextCursor = &messages[0].ID
+	}
+
+	var messagesWithDetails []MessageWithDetails
+	for _, msg := range messages {
+		sender, _ := h.db.GetUserByID(msg.SenderID)
+		var senderInfo *UserInfo
+		if sender != nil {
+			name := fmt.Sprintf("%s %s", sender.FirstName, sender.LastName)
+			if sender.HelperName != nil {
+				name = *sender.HelperName
+			}
+			senderInfo = &UserInfo{
+				ID:     sender.ID,
+				Name:   name,
+				Avatar: sender.PhotoURL,
+			}
+		}
+
+		messagesWithDetails = append(messagesWithDetails, MessageWithDetails{
+			Message: msg,
+			Sender:  senderInfo,
+		})
+	}
+
+	response := MessagesListResponse{
+		Data:       messagesWithDetails,
+		Pagination: pagination,
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// SendMessage отправляет сообщение в чат
+// @Summary     Отправить сообщение
+// @Description Отправляет новое сообщение в чат (текст или вложение)
+// @Tags        Чаты
+// @Accept      multipart/form-data
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID чата"
+// @Param       text formData string false "Текст сообщения"
+// @Param       attachment formData file false "Вложение (изображение, до 5MB)"
+// @Success     201  {object}  MessageResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /chats/{id}/messages [post]
+func (h *Handlers) SendMessage(w http.ResponseWriter, r *http.Request) {
+	chatID, userID, err := h.chatParticipant(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := ParseMultipartForm(r, 5<<20); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var text *string
+	if textVal := r.FormValue("text"); textVal != "" {
+		text = &textVal
+	}
+
+	var message *Message
+	var attachmentURL *string
+
+	if attachment, header, err := r.FormFile("attachment"); err == nil {
+		defer attachment.Close()
+
+		if err := ValidateFileSize(header, 5<<20); err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		if err := ValidateImageFile(header); err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		if err := h.scanUpload(r.Context(), "chat", userID, attachment, header); err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		data, contentType, err := readImageUpload(attachment)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		// Резервируем ID, чтобы загрузить вложение до создания сообщения:
+		// сообщение без текста нельзя сохранить без attachment_url
+		messageID, err := h.db.NextMessageID()
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		ctx := r.Context()
+		objectKey, err := UploadChatAttachment(ctx, h.minioClient, chatID, messageID, bytes.NewReader(data), int64(len(data)), contentType)
+		if err != nil {
+			WriteError(w, NewInternalError("Ошибка загрузки вложения"))
+			return
+		}
+
+		url := GetObjectURL(h.cfg.MinIOConfig, BucketChatAttachments, objectKey)
+		attachmentURL = &url
+		message = &Message{
+			ID:            messageID,
+			ChatID:        chatID,
+			SenderID:      userID,
+			Text:          text,
+			AttachmentURL: attachmentURL,
+		}
+		if err := h.db.CreateMessage(message); err != nil {
+			WriteError(w, err)
+			return
+		}
+	} else {
+		if text == nil {
+			WriteError(w, NewValidationError("Текст или вложение обязательны", nil))
+			return
+		}
+
+		message = &Message{
+			ChatID:        chatID,
+			SenderID:      userID,
+			Text:          text,
+			AttachmentURL: attachmentURL,
+		}
+		if err := h.db.CreateMessage(message); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	// Обновляем время последнего сообщения в чате
+	h.db.UpdateChatUpdatedAt(chatID)
+	// Отправленный текст больше не нужен в черновике на других устройствах
+	if err := h.db.DeleteChatDraft(chatID, userID); err != nil {
+		log.Printf("Failed to clear draft in chat %d: %v", chatID, err)
+	}
+	h.notifyNewMessage(message)
+	h.sendAwayReply(chatID, userID)
+
+	response := map[string]interface{}{
+		"id":             message.ID,
+		"chat_id":        message.ChatID,
+		"sender_id":      message.SenderID,
+		"text":           message.Text,
+		"attachment_url": message.AttachmentURL,
+		"is_read":        message.IsRead,
+		"is_edited":      message.IsEdited,
+		"created_at":     message.CreatedAt,
+	}
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+// MarkMessagesRead отмечает сообщения как прочитанные
+// @Summary     Отметить сообщения как прочитанные
+// @Description Отмечает сообщения в чате как прочитанные
+// @Tags        Чаты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID чата"
+// @Param       request body MarkMessagesReadRequest false "ID сообщений (опционально, если пусто - все полученные сообщения)"
+// @Success     200  {object}  MarkMessagesReadResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /chats/{id}/messages/read [patch]
+func (h *Handlers) MarkMessagesRead(w http.ResponseWriter, r *http.Request) {
+	chatID, userID, err := h.chatParticipant(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var req MarkMessagesReadRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, NewValidationError("Неверный формат запроса", nil))
+			return
+		}
+	}
+
+	count, err := h.db.MarkMessagesAsRead(chatID, userID, req.MessageIDs)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"updated_count": count,
+		"message":       "Сообщения отмечены как прочитанные",
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// UpdateMessage редактирует сообщение (только отправитель)
+// @Summary     Редактировать сообщение
+// @Description Редактирует текст сообщения (только отправитель может редактировать)
+// @Tags        Чаты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID чата"
+// @Param       message_id path int true "ID сообщения"
+// @Param       request body UpdateMessageRequest true "Новый текст"
+// @Success     200  {object}  MessageUpdateResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /chats/{id}/messages/{message_id} [patch]
+func (h *Handlers) UpdateMessage(w http.ResponseWriter, r *http.Request) {
+	chatID, userID, err := h.chatParticipant(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	messageID, err := strconv.ParseInt(mux.Vars(r)["message_id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID сообщения", nil))
+		return
+	}
+
+	message, err := h.db.GetMessageByID(messageID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if message.ChatID != chatID {
+		WriteError(w, NewNotFoundError("Сообщение"))
+		return
+	}
+	if message.SenderID != userID {
+		WriteError(w, NewForbiddenError("Недостаточно прав"))
+		return
+	}
+
+	var req UpdateMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := h.db.UpdateMessage(messageID, req.Text); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"id":         messageID,
+		"text":       req.Text,
+		"is_edited":  true,
+		"updated_at": time.Now(),
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// DeleteMessage удаляет сообщение (только отправитель)
+// @Summary     Удалить сообщение
+// @Description Удаляет сообщение из чата (только отправитель может удалить)
+// @Tags        Чаты
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID чата"
+// @Param       message_id path int true "ID сообщения"
+// @Success     204  "Успешно удалено"
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /chats/{id}/messages/{message_id} [delete]
+func (h *Handlers) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	chatID, userID, err := h.chatParticipant(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	messageID, err := strconv.ParseInt(mux.Vars(r)["message_id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID сообщения", nil))
+		return
+	}
+
+	message, err := h.db.GetMessageByID(messageID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if message.ChatID != chatID {
+		WriteError(w, NewNotFoundError("Сообщение"))
+		return
+	}
+	if message.SenderID != userID {
+		WriteError(w, NewForbiddenError("Недостаточно прав"))
+		return
+	}
+
+	if err := h.db.DeleteMessage(messageID); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if message.AttachmentURL != nil {
+		DeleteObjectsByURL(r.Context(), h.minioClient, BucketChatAttachments, []string{*message.AttachmentURL})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetChatEvents открывает поток событий чата
+// @Summary     Поток событий чата
+// @Description Server-Sent Events: событие message с каждым новым сообщением чата, включая автоответ.
+// @Description Каждые 25 секунд приходит комментарий keep-alive. Доступно только участникам чата.
+// @Tags        Чаты
+// @Produce     text/event-stream
+// @Security    BearerAuth
+// @Param       id path int true "ID чата"
+// @Success     200  {object}  Message
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /chats/{id}/events [get]
+func (h *Handlers) GetChatEvents(w http.ResponseWriter, r *http.Request) {
+	chatID, _, err := h.chatParticipant(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	h.events.ServeSSE(w, r, ChatTopic(chatID), nil)
+}
+
+// GetChatDraft получает черновик сообщения в чате
+// @Summary     Получить черновик
+// @Description Возвращает неотправленный текст сообщения текущего пользователя в чате. Если черновика нет, text пустой.
+// @Tags        Чаты
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID чата"
+// @Success     200  {object}  ChatDraft
 // @Failure     401  {object}  ErrorResponse
 // @Failure     403  {object}  ErrorResponse
-// @Router      /verifications [get]
-func (h *Handlers) GetVerifications(w http.ResponseWriter, r *http.Request) {
-	status := r.URL.Query().Get("status")
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	if limit < 1 {
-		limit = 20
-	}
-
-	verifications, total, err := h.db.GetVerifications(status, page, limit)
+// @Failure     404  {object}  ErrorResponse
+// @Router      /chats/{id}/draft [get]
+func (h *Handlers) GetChatDraft(w http.ResponseWriter, r *http.Request) {
+	chatID, userID, err := h.chatParticipant(r)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	totalPages := (total + limit - 1) / limit
-	response := map[string]interface{}{
-		"data": verifications,
-		"pagination": PaginationResponse{
-			Page:       page,
-			Limit:      limit,
-			Total:      total,
-			TotalPages: totalPages,
-		},
+	draft, err := h.db.GetChatDraft(chatID, userID)
+	if err != nil {
+		WriteError(w, err)
+		return
 	}
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, draft)
 }
 
-// UpdateVerification обновляет статус верификации (только для админов)
-// @Summary     Одобрить/отклонить верификацию
-// @Description Обновляет статус верификации (одобрить или отклонить)
-// @Tags        Верификация
+// SaveChatDraft сохраняет черновик сообщения в чате
+// @Summary     Сохранить черновик
+// @Description Сохраняет неотправленный текст сообщения (до 4000 символов), чтобы продолжить на другом устройстве.
+// @Description Пустой текст удаляет черновик. Черновик также удаляется при отправке сообщения.
+// @Tags        Чаты
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID верификации"
-// @Param       request body UpdateVerificationRequest true "Статус и причина отклонения"
-// @Success     200  {object}  VerificationResponse
+// @Param       id path int true "ID чата"
+// @Param       request body SaveChatDraftRequest true "Текст черновика"
+// @Success     200  {object}  ChatDraft
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
 // @Failure     403  {object}  ErrorResponse
-// @Router      /verifications/{id} [patch]
-func (h *Handlers) UpdateVerification(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	verificationID, err := strconv.ParseInt(vars["id"], 10, 64)
-	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID верификации", nil))
-		return
-	}
-
-	userID, err := GetUserIDFromContext(r.Context())
+// @Failure     404  {object}  ErrorResponse
+// @Router      /chats/{id}/draft [put]
+func (h *Handlers) SaveChatDraft(w http.ResponseWriter, r *http.Request) {
+	chatID, userID, err := h.chatParticipant(r)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	var req UpdateVerificationRequest
+	var req SaveChatDraftRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
@@ -712,110 +8026,96 @@ func (h *Handlers) UpdateVerification(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.UpdateVerificationStatus(verificationID, req.Status, userID, req.RejectionReason); err != nil {
-		WriteError(w, err)
+	if strings.TrimSpace(req.Text) == "" {
+		if err := h.db.DeleteChatDraft(chatID, userID); err != nil {
+			WriteError(w, err)
+			return
+		}
+		WriteJSON(w, http.StatusOK, ChatDraft{ChatID: chatID})
 		return
 	}
 
-	verification, err := h.db.GetVerificationByUserID(0) // Нужно добавить GetVerificationByID
+	draft, err := h.db.SaveChatDraft(chatID, userID, req.Text)
 	if err != nil {
-		// Упрощенная версия
-		response := map[string]interface{}{
-			"id":          verificationID,
-			"status":      req.Status,
-			"reviewed_at": time.Now(),
-			"reviewed_by": userID,
-		}
-		WriteJSON(w, http.StatusOK, response)
+		WriteError(w, err)
 		return
 	}
-
-	response := map[string]interface{}{
-		"id":          verification.ID,
-		"status":      verification.Status,
-		"reviewed_at": verification.ReviewedAt,
-		"reviewed_by": verification.ReviewedBy,
-	}
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, draft)
 }
 
-// ========== Post Endpoints ==========
+// ========== Rating Endpoints ==========
 
-// GetPosts получает список постов
-// @Summary     Получить список постов
-// @Description Возвращает список постов с пагинацией и фильтрацией
-// @Tags        Посты
+// GetRatings получает рейтинг пользователей
+// @Summary     Получить рейтинг пользователей
+// @Description Возвращает рейтинг пользователей с пагинацией. Без period - за все время по накопленным баллам.
+// @Description period=week|month|year - за текущую календарную неделю (с понедельника), месяц или год по московскому времени:
+// @Description баллы и сумма считаются по пожертвованиям, подтвержденным в периоде, без реферальных бонусов.
+// @Tags        Рейтинг
 // @Accept      json
 // @Produce     json
-// @Param       status query string false "Фильтр по статусу" Enums(active, completed, closed, moderated)
-// @Param       user_id query int false "Фильтр по автору"
+// @Param       period query string false "Период рейтинга" Enums(week, month, year)
 // @Param       page query int false "Номер страницы" default(1)
-// @Param       limit query int false "Количество на странице" default(20)
-// @Success     200  {object}  PostsListResponse
-// @Router      /posts [get]
-func (h *Handlers) GetPosts(w http.ResponseWriter, r *http.Request) {
-	status := r.URL.Query().Get("status")
-	userIDStr := r.URL.Query().Get("user_id")
+// @Param       limit query int false "Количество на странице" default(50)
+// @Success     200  {object}  RatingsListResponse
+// @Failure     400  {object}  ErrorResponse
+// @Router      /ratings [get]
+func (h *Handlers) GetRatings(w http.ResponseWriter, r *http.Request) {
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	if page < 1 {
 		page = 1
 	}
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	if limit < 1 {
-		limit = 20
+		limit = 50
 	}
 
-	var userID *int64
-	if userIDStr != "" {
-		id, _ := strconv.ParseInt(userIDStr, 10, 64)
-		userID = &id
+	var ratings []Rating
+	var total int
+	var since time.Time
+	var err error
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		ratings, total, err = h.db.GetRatings(page, limit)
+	} else {
+		var ok bool
+		if since, ok = ratingPeriodStart(period, time.Now()); !ok {
+			WriteError(w, NewValidationError("Неверный период рейтинга", map[string]interface{}{"field": "period", "allowed": []string{"week", "month", "year"}}))
+			return
+		}
+		ratings, total, err = h.db.GetPeriodRatings(since, page, limit)
 	}
-
-	posts, total, err := h.db.GetPosts(status, userID, page, limit)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	// Обогащаем посты данными автора и медиа
-	var postsWithDetails []PostWithDetails
-	for _, post := range posts {
-		author, _ := h.db.GetUserByID(post.UserID)
-		media, _ := h.db.GetPostMedia(post.ID)
-
-		var authorInfo *UserInfo
-		if author != nil {
-			name := fmt.Sprintf("%s %s", author.FirstName, author.LastName)
-			// Преобразуем photo_url в URL через backend проксирование, если он есть
-			var avatarURL *string
-			if author.PhotoURL != nil && *author.PhotoURL != "" {
-				backendURL := ConvertMinIOURLToBackendURL(*author.PhotoURL)
-				avatarURL = &backendURL
+	var ratingsWithDetails []RatingWithDetails
+	for i, rating := range ratings {
+		user, _ := h.db.GetUserByID(rating.UserID)
+		var userInfo *UserInfo
+		if user != nil {
+			name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+			if user.HelperName != nil {
+				name = *user.HelperName
 			}
-			authorInfo = &UserInfo{
-				ID:     author.ID,
+			userInfo = &UserInfo{
+				ID:     user.ID,
 				Name:   name,
-				Avatar: avatarURL,
-			}
-		}
-
-		// Преобразуем URL медиа файлов через backend проксирование
-		for i := range media {
-			if media[i].MediaURL != "" {
-				media[i].MediaURL = ConvertMinIOURLToBackendURL(media[i].MediaURL)
+				Avatar: user.PhotoURL,
 			}
 		}
 
-		postsWithDetails = append(postsWithDetails, PostWithDetails{
-			Post:   post,
-			Author: authorInfo,
-			Media:  media,
+		position := (page-1)*limit + i + 1
+		ratingsWithDetails = append(ratingsWithDetails, RatingWithDetails{
+			Rating:   rating,
+			User:     userInfo,
+			Position: position,
 		})
 	}
 
 	totalPages := (total + limit - 1) / limit
 	response := map[string]interface{}{
-		"data": postsWithDetails,
+		"data": ratingsWithDetails,
 		"pagination": PaginationResponse{
 			Page:       page,
 			Limit:      limit,
@@ -823,226 +8123,297 @@ func (h *Handlers) GetPosts(w http.ResponseWriter, r *http.Request) {
 			TotalPages: totalPages,
 		},
 	}
+	if period != "" {
+		response["period"] = period
+		response["since"] = since.UTC()
+	}
 	WriteJSON(w, http.StatusOK, response)
 }
 
-// GetPost получает пост по ID
-// @Summary     Получить пост
-// @Description Возвращает детальную информацию о посте
-// @Tags        Посты
+// ratingPeriodStart возвращает начало текущей недели (с понедельника), месяца или года
+// по московскому времени, как в еженедельной сводке
+func ratingPeriodStart(period string, now time.Time) (time.Time, bool) {
+	if loc, err := LoadUserLocation(DefaultTimezone); err == nil {
+		now = now.In(loc)
+	}
+	switch period {
+	case "week":
+		offset := (int(now.Weekday()) + 6) % 7
+		return time.Date(now.Year(), now.Month(), now.Day()-offset, 0, 0, 0, 0, now.Location()), true
+	case "month":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), true
+	case "year":
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location()), true
+	}
+	return time.Time{}, false
+}
+
+// GetMyRating получает рейтинг текущего пользователя
+// @Summary     Получить свой рейтинг
+// @Description Возвращает рейтинг текущего пользователя с позицией
+// @Tags        Рейтинг
 // @Accept      json
 // @Produce     json
-// @Param       id path int true "ID поста"
-// @Success     200  {object}  PostWithDetails
+// @Security    BearerAuth
+// @Success     200  {object}  RatingWithDetails
+// @Failure     401  {object}  ErrorResponse
 // @Failure     404  {object}  ErrorResponse
-// @Router      /posts/{id} [get]
-func (h *Handlers) GetPost(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Router      /ratings/me [get]
+func (h *Handlers) GetMyRating(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		WriteError(w, err)
 		return
 	}
 
-	post, err := h.db.GetPostByID(postID)
+	rating, err := h.db.GetOrCreateRating(userID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	author, _ := h.db.GetUserByID(post.UserID)
-	media, _ := h.db.GetPostMedia(post.ID)
+	position, _ := h.db.GetRatingPosition(userID)
 
-	var authorInfo *UserInfo
-	if author != nil {
-		name := fmt.Sprintf("%s %s", author.FirstName, author.LastName)
-		// Преобразуем photo_url в URL через backend проксирование, если он есть
-		var avatarURL *string
-		if author.PhotoURL != nil && *author.PhotoURL != "" {
-			backendURL := ConvertMinIOURLToBackendURL(*author.PhotoURL)
-			avatarURL = &backendURL
-		}
-		authorInfo = &UserInfo{
-			ID:     author.ID,
-			Name:   name,
-			Avatar: avatarURL,
-		}
+	response := RatingWithDetails{
+		Rating:   *rating,
+		Position: position,
 	}
+	WriteJSON(w, http.StatusOK, response)
+}
 
-	// Преобразуем URL медиа файлов через backend проксирование
-	for i := range media {
-		if media[i].MediaURL != "" {
-			media[i].MediaURL = ConvertMinIOURLToBackendURL(media[i].MediaURL)
-		}
+// GetMyRatingHistory получает журнал баллов текущего пользователя
+// @Summary     История баллов рейтинга
+// @Description Возвращает изменения баллов текущего пользователя, новые первыми: начисления за подтвержденные пожертвования (donation),
+// @Description списания при отмене подтверждения или возврате (donation_reversal), реферальные бонусы (referral), их списание
+// @Description вместе с пожертвованием, за которое они начислены (referral_reversal), и баллы,
+// @Description накопленные до появления журнала (opening_balance). balance - баллы после изменения.
+// @Tags        Рейтинг
+// @Produce     json
+// @Security    BearerAuth
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  RatingHistoryResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /ratings/me/history [get]
+func (h *Handlers) GetMyRatingHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
 	}
 
-	response := PostWithDetails{
-		Post:   *post,
-		Author: authorInfo,
-		Media:  media,
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
 	}
-	WriteJSON(w, http.StatusOK, response)
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	events, total, err := h.db.GetRatingHistory(userID, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, RatingHistoryResponse{
+		Data: events,
+		Pagination: PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	})
 }
 
-// CreatePost создает новый пост (только для верифицированных пользователей)
-// @Summary     Создать пост
-// @Description Создает новый пост о помощи
-// @Tags        Посты
-// @Accept      multipart/form-data
+// ========== Notification Endpoints ==========
+
+// GetNotifications получает уведомления текущего пользователя
+// @Summary     Получить уведомления
+// @Description Возвращает уведомления текущего пользователя, новые первыми
+// @Tags        Уведомления
+// @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       title formData string true "Заголовок"
-// @Param       description formData string true "Описание"
-// @Param       amount formData number true "Целевая сумма"
-// @Param       recipient formData string true "Получатель средств"
-// @Param       bank formData string true "Банк получателя"
-// @Param       phone formData string true "Телефон для связи"
-// @Param       media formData file false "Медиа файлы (максимум 10, каждый до 10MB)"
-// @Success     201  {object}  PostResponse
-// @Failure     400  {object}  ErrorResponse
+// @Param       unread query bool false "Только непрочитанные"
+// @Param       page query int false "Номер страницы" default(1)
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  NotificationsListResponse
 // @Failure     401  {object}  ErrorResponse
-// @Failure     403  {object}  ErrorResponse
-// @Router      /posts [post]
-func (h *Handlers) CreatePost(w http.ResponseWriter, r *http.Request) {
+// @Router      /notifications [get]
+func (h *Handlers) GetNotifications(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	// // Проверяем верификацию
-	// if !h.db.IsUserVerified(userID) {
-	// 	WriteError(w, NewForbiddenError("Пользователь не верифицирован"))
-	// 	return
-	// }
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
 
-	if err := ParseMultipartForm(r, 100<<20); err != nil { // 100MB
+	notifications, total, err := h.db.GetNotifications(userID, unreadOnly, page, limit)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	var req CreatePostRequest
-	req.Title = r.FormValue("title")
-	req.Description = r.FormValue("description")
-	req.Amount, _ = strconv.ParseFloat(r.FormValue("amount"), 64)
-	req.Recipient = r.FormValue("recipient")
-	req.Bank = r.FormValue("bank")
-	req.Phone = r.FormValue("phone")
+	totalPages := (total + limit - 1) / limit
+	response := map[string]interface{}{
+		"data": notifications,
+		"pagination": PaginationResponse{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
 
-	if err := ValidateStruct(&req); err != nil {
+// MarkNotificationsRead отмечает уведомления прочитанными
+// @Summary     Отметить уведомления прочитанными
+// @Description Отмечает указанные уведомления прочитанными. Если список пуст - все уведомления пользователя
+// @Tags        Уведомления
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body MarkNotificationsReadRequest false "ID уведомлений"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /notifications/read [patch]
+func (h *Handlers) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	post := &Post{
-		UserID:      userID,
-		Title:       req.Title,
-		Description: req.Description,
-		Amount:      req.Amount,
-		Recipient:   req.Recipient,
-		Bank:        req.Bank,
-		Phone:       req.Phone,
+	var req MarkNotificationsReadRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, NewValidationError("Неверный формат запроса", nil))
+			return
+		}
 	}
 
-	if err := h.db.CreatePost(post); err != nil {
+	if err := h.db.MarkNotificationsRead(userID, req.IDs); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	ctx := r.Context()
-	// Загружаем медиа файлы
-	if files, ok := r.MultipartForm.File["media"]; ok {
-		for i, fileHeader := range files {
-			if i >= 10 { // Максимум 10 файлов
-				break
-			}
-
-			file, err := fileHeader.Open()
-			if err != nil {
-				continue
-			}
-
-			if err := ValidateFileSize(fileHeader, 10<<20); err != nil { // 10MB
-				file.Close()
-				continue
-			}
-
-			if err := ValidateMediaFile(fileHeader); err != nil {
-				file.Close()
-				continue
-			}
-
-			mediaType := "image"
-			if strings.Contains(strings.ToLower(filepath.Ext(fileHeader.Filename)), "mp4") ||
-				strings.Contains(strings.ToLower(filepath.Ext(fileHeader.Filename)), "webm") {
-				mediaType = "video"
-			}
-
-			objectKey, err := UploadPostMedia(ctx, h.minioClient, post.ID, i, file, fileHeader.Size, fileHeader.Header.Get("Content-Type"))
-			file.Close()
-			if err != nil {
-				continue
-			}
+	WriteSuccess(w, http.StatusOK, "Уведомления отмечены прочитанными")
+}
 
-			mediaURL := GetObjectURL(h.cfg.MinIOConfig, BucketPostMedia, objectKey)
-			h.db.CreatePostMedia(post.ID, mediaURL, mediaType, i)
-		}
+// GetNotificationPreferences получает каналы доставки уведомлений
+// @Summary     Каналы уведомлений
+// @Description Возвращает для каждого типа уведомлений, включены ли push и SMS. Уведомление в приложении сохраняется всегда.
+// @Description По умолчанию push включен, SMS выключены. SMS доступны только для типов с sms_available (квитанция о пожертвовании).
+// @Tags        Уведомления
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {array}   NotificationPreference
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/notification-preferences [get]
+func (h *Handlers) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
 	}
-
-	response := map[string]interface{}{
-		"id":          post.ID,
-		"user_id":     post.UserID,
-		"title":       post.Title,
-		"description": post.Description,
-		"amount":      post.Amount,
-		"collected":   post.Collected,
-		"status":      post.Status,
-		"created_at":  post.CreatedAt,
+	preferences, err := h.db.GetNotificationPreferences(userID)
+	if err != nil {
+		WriteError(w, err)
+		return
 	}
-	WriteJSON(w, http.StatusCreated, response)
+	WriteJSON(w, http.StatusOK, preferences)
 }
 
-// UpdatePost обновляет пост (только автор)
-// @Summary     Обновить пост
-// @Description Обновляет данные поста (только автор может редактировать)
-// @Tags        Посты
+// UpdateNotificationPreferences изменяет каналы доставки уведомлений
+// @Summary     Изменить каналы уведомлений
+// @Description Сохраняет push и SMS для перечисленных типов, остальные типы не меняются. SMS отправляются на подтвержденный телефон.
+// @Tags        Уведомления
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID поста"
-// @Param       request body UpdatePostRequest true "Данные для обновления"
-// @Success     200  {object}  PostUpdateResponse
+// @Param       request body UpdateNotificationPreferencesRequest true "Каналы по типам уведомлений"
+// @Success     200  {array}   NotificationPreference
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
-// @Failure     403  {object}  ErrorResponse
-// @Failure     404  {object}  ErrorResponse
-// @Router      /posts/{id} [patch]
-func (h *Handlers) UpdatePost(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Router      /users/me/notification-preferences [put]
+func (h *Handlers) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		WriteError(w, err)
 		return
 	}
 
-	userID, err := GetUserIDFromContext(r.Context())
-	if err != nil {
+	var req UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	if err := ValidateStruct(&req); err != nil {
 		WriteError(w, err)
 		return
 	}
+	for _, p := range req.Preferences {
+		if !slices.Contains(NotificationTypes, p.Type) {
+			WriteError(w, NewValidationError("Неизвестный тип уведомлений", map[string]interface{}{
+				"type":    p.Type,
+				"allowed": NotificationTypes,
+			}))
+			return
+		}
+		if p.SMS && !smsNotificationTypes[p.Type] {
+			WriteError(w, NewValidationError("SMS недоступны для этого типа уведомлений", map[string]interface{}{"type": p.Type}))
+			return
+		}
+	}
 
-	post, err := h.db.GetPostByID(postID)
+	if err := h.db.SetNotificationPreferences(userID, req.Preferences); err != nil {
+		WriteError(w, err)
+		return
+	}
+	preferences, err := h.db.GetNotificationPreferences(userID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	WriteJSON(w, http.StatusOK, preferences)
+}
 
-	if post.UserID != userID {
-		WriteError(w, NewForbiddenError("Недостаточно прав"))
+// ========== Device Endpoints ==========
+
+// RegisterDevice регистрирует устройство для push уведомлений
+// @Summary     Зарегистрировать устройство
+// @Description Сохраняет FCM токен устройства. На него приходят push уведомления о новых сообщениях и подтвержденных пожертвованиях, даже когда приложение закрыто.
+// @Tags        Уведомления
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body RegisterDeviceRequest true "Токен устройства"
+// @Success     200  {object}  Device
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/devices [post]
+func (h *Handlers) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
 		return
 	}
 
-	var req UpdatePostRequest
+	var req RegisterDeviceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
@@ -1053,1112 +8424,1098 @@ func (h *Handlers) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.UpdatePost(postID, req.Title, req.Description, req.Amount, req.Recipient, req.Bank, req.Phone); err != nil {
+	device, err := h.db.UpsertDevice(userID, req.Token, req.Platform)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	post, _ = h.db.GetPostByID(postID)
-	response := map[string]interface{}{
-		"id":         post.ID,
-		"title":      post.Title,
-		"updated_at": post.UpdatedAt,
-	}
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, device)
 }
 
-// AddPostMedia добавляет медиа к посту (только автор)
-// @Summary     Добавить медиа к посту
-// @Description Добавляет медиа файл к существующему посту
-// @Tags        Посты
-// @Accept      multipart/form-data
+// UnregisterDevice удаляет устройство
+// @Summary     Удалить устройство
+// @Description Удаляет FCM токен устройства (например, при выходе из аккаунта)
+// @Tags        Уведомления
+// @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID поста"
-// @Param       media formData file true "Медиа файл (изображение/видео, до 10MB)"
-// @Success     201  {object}  PostMedia
-// @Failure     400  {object}  ErrorResponse
+// @Param       token path string true "FCM токен устройства"
+// @Success     200  {object}  SuccessResponse
 // @Failure     401  {object}  ErrorResponse
-// @Failure     403  {object}  ErrorResponse
-// @Router      /posts/{id}/media [post]
-func (h *Handlers) AddPostMedia(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/devices/{token} [delete]
+func (h *Handlers) UnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		WriteError(w, err)
+		return
+	}
+
+	deleted, err := h.db.DeleteDevice(userID, mux.Vars(r)["token"])
+	if err != nil {
+		WriteError(w, err)
 		return
 	}
+	if !deleted {
+		WriteError(w, NewNotFoundError("Устройство"))
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, "Устройство удалено")
+}
 
+// ========== API Token Endpoints ==========
+
+// GetAPITokens получает персональные API токены пользователя
+// @Summary     Получить API токены
+// @Description Возвращает персональные API токены текущего пользователя (без значений токенов)
+// @Tags        API токены
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  map[string]interface{}
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/tokens [get]
+func (h *Handlers) GetAPITokens(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	post, err := h.db.GetPostByID(postID)
+	tokens, err := h.db.GetAPITokens(userID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	if post.UserID != userID {
-		WriteError(w, NewForbiddenError("Недостаточно прав"))
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"data": tokens})
+}
+
+// CreateAPIToken выпускает персональный API токен
+// @Summary     Создать API токен
+// @Description Выпускает токен для ботов и автоматизации с ограниченными областями действия: donations:read (чтение своих пожертвований) и posts:write (обновление своих постов). Значение токена возвращается только один раз.
+// @Tags        API токены
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body CreateAPITokenRequest true "Название, области действия и срок жизни токена"
+// @Success     201  {object}  CreateAPITokenResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /users/me/tokens [post]
+func (h *Handlers) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
 		return
 	}
 
-	if err := ParseMultipartForm(r, 10<<20); err != nil {
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+
+	if err := ValidateStruct(&req); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	file, header, err := GetFileFromForm(r, "media")
+	count, err := h.db.CountAPITokens(userID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
-	defer file.Close()
+	if count >= MaxAPITokensPerUser {
+		WriteError(w, NewConflictError(fmt.Sprintf("Можно создать не более %d API токенов", MaxAPITokensPerUser)))
+		return
+	}
 
-	if err := ValidateFileSize(header, 10<<20); err != nil {
+	value, err := GenerateAPIToken()
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	if err := ValidateMediaFile(header); err != nil {
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		t := time.Now().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	token, err := h.db.CreateAPIToken(userID, req.Name, sha256Hex(value), value[:len(APITokenPrefix)+6], req.Scopes, expiresAt)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	media, _ := h.db.GetPostMedia(postID)
-	orderIndex := len(media)
+	WriteJSON(w, http.StatusCreated, CreateAPITokenResponse{APIToken: *token, Token: value})
+}
 
-	mediaType := "image"
-	if strings.Contains(strings.ToLower(filepath.Ext(header.Filename)), "mp4") ||
-		strings.Contains(strings.ToLower(filepath.Ext(header.Filename)), "webm") {
-		mediaType = "video"
+// DeleteAPIToken отзывает персональный API токен
+// @Summary     Отозвать API токен
+// @Description Удаляет API токен, после чего запросы с ним отклоняются
+// @Tags        API токены
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID токена"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/tokens/{id} [delete]
+func (h *Handlers) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
 	}
 
-	ctx := r.Context()
-	objectKey, err := UploadPostMedia(ctx, h.minioClient, postID, orderIndex, file, header.Size, header.Header.Get("Content-Type"))
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
-		WriteError(w, NewInternalError("Ошибка загрузки медиа"))
+		WriteError(w, NewValidationError("Неверный ID токена", nil))
 		return
 	}
 
-	mediaURL := GetObjectURL(h.cfg.MinIOConfig, BucketPostMedia, objectKey)
-	postMedia, err := h.db.CreatePostMedia(postID, mediaURL, mediaType, orderIndex)
+	deleted, err := h.db.DeleteAPIToken(userID, id)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	if !deleted {
+		WriteError(w, NewNotFoundError("API токен"))
+		return
+	}
 
-	WriteJSON(w, http.StatusCreated, postMedia)
+	WriteSuccess(w, http.StatusOK, "API токен отозван")
 }
 
-// DeletePostMedia удаляет медиа из поста (только автор)
-// @Summary     Удалить медиа из поста
-// @Description Удаляет медиа файл из поста
-// @Tags        Посты
-// @Accept      json
+// ========== Calendar Endpoints ==========
+
+// CreateCalendarFeed выпускает ссылку на календарь сроков сборов
+// @Summary     Ссылка на календарь сроков
+// @Description Выпускает ссылку на календарь iCalendar со сроками сборов пользователя для подписки в календаре телефона.
+// @Description Ссылка работает без входа и показывается только один раз; повторный вызов выпускает новую, старая перестает работать.
+// @Tags        Профиль
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID поста"
-// @Param       media_id path int true "ID медиа"
-// @Success     204  "Успешно удалено"
+// @Success     201  {object}  CalendarFeedResponse
 // @Failure     401  {object}  ErrorResponse
-// @Failure     403  {object}  ErrorResponse
-// @Router      /posts/{id}/media/{media_id} [delete]
-func (h *Handlers) DeletePostMedia(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Router      /users/me/calendar [post]
+func (h *Handlers) CreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		WriteError(w, err)
 		return
 	}
 
-	mediaID, err := strconv.ParseInt(vars["media_id"], 10, 64)
+	token, err := GenerateCalendarToken()
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID медиа", nil))
+		WriteError(w, err)
 		return
 	}
+	tokenHash := sha256Hex(token)
+	if err := h.db.SetCalendarTokenHash(userID, &tokenHash); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, CalendarFeedResponse{URL: CalendarFeedURL(h.cfg, token)})
+}
 
+// DeleteCalendarFeed выключает календарь сроков сборов
+// @Summary     Выключить календарь сроков
+// @Description Отзывает ссылку на календарь, после чего она возвращает 404
+// @Tags        Профиль
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {object}  SuccessResponse
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/calendar [delete]
+func (h *Handlers) DeleteCalendarFeed(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	post, err := h.db.GetPostByID(postID)
+	if err := h.db.SetCalendarTokenHash(userID, nil); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteSuccess(w, http.StatusOK, "Календарь выключен")
+}
+
+// GetCalendarFeed возвращает календарь сроков сборов по ссылке
+// @Summary     Календарь сроков сборов
+// @Description Календарь iCalendar со сроками сборов автора: событие на момент окончания срока и напоминание за сутки
+// @Description для активных сборов. Доступен без входа по ссылке из POST /users/me/calendar.
+// @Tags        Профиль
+// @Produce     text/calendar
+// @Param       token path string true "Токен календаря"
+// @Success     200  {file}    binary
+// @Failure     404  {object}  ErrorResponse
+// @Router      /calendar/{token}.ics [get]
+func (h *Handlers) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	deadlines, err := h.db.GetCalendarDeadlines(sha256Hex(mux.Vars(r)["token"]))
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	if post.UserID != userID {
-		WriteError(w, NewForbiddenError("Недостаточно прав"))
+	data := RenderDeadlineCalendar(deadlines, h.cfg.Share.PostURL)
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=\"deadlines.ics\"")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ========== Payment Note Template Endpoints ==========
+
+// GetPaymentNoteTemplates получает шаблоны назначения платежа
+// @Summary     Шаблоны назначения платежа
+// @Description Возвращает сохраненные тексты назначения платежа текущего пользователя в порядке создания.
+// @Description Клиент подставляет их в банковское приложение при ручном переводе; шаблоны общие для всех устройств.
+// @Tags        Пользователи
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {array}   PaymentNoteTemplate
+// @Failure     401  {object}  ErrorResponse
+// @Router      /users/me/templates [get]
+func (h *Handlers) GetPaymentNoteTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
 		return
 	}
 
-	if err := h.db.DeletePostMedia(mediaID); err != nil {
+	templates, err := h.db.GetPaymentNoteTemplates(userID)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	w.WriteHeader(http.StatusNoContent)
+	WriteJSON(w, http.StatusOK, templates)
 }
 
-// DeletePost удаляет пост (только автор)
-// @Summary     Удалить пост
-// @Description Удаляет пост (только автор может удалить)
-// @Tags        Посты
+// CreatePaymentNoteTemplate сохраняет шаблон назначения платежа
+// @Summary     Создать шаблон назначения платежа
+// @Description Сохраняет текст назначения платежа (до 210 символов) под уникальным названием. У пользователя может быть не больше 20 шаблонов.
+// @Tags        Пользователи
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID поста"
-// @Success     204  "Успешно удалено"
+// @Param       request body SavePaymentNoteTemplateRequest true "Название и текст"
+// @Success     201  {object}  PaymentNoteTemplate
+// @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
-// @Failure     403  {object}  ErrorResponse
-// @Failure     404  {object}  ErrorResponse
-// @Router      /posts/{id} [delete]
-func (h *Handlers) DeletePost(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Failure     409  {object}  ErrorResponse
+// @Router      /users/me/templates [post]
+func (h *Handlers) CreatePaymentNoteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID поста", nil))
+		WriteError(w, err)
 		return
 	}
 
-	userID, err := GetUserIDFromContext(r.Context())
+	req, err := decodePaymentNoteTemplate(r)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	post, err := h.db.GetPostByID(postID)
+	count, err := h.db.CountPaymentNoteTemplates(userID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	if post.UserID != userID {
-		WriteError(w, NewForbiddenError("Недостаточно прав"))
+	if count >= MaxPaymentNoteTemplates {
+		WriteError(w, NewConflictError(fmt.Sprintf("Можно сохранить не более %d шаблонов", MaxPaymentNoteTemplates)))
 		return
 	}
 
-	if err := h.db.DeletePost(postID); err != nil {
+	template, err := h.db.CreatePaymentNoteTemplate(userID, req.Name, req.Text)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	w.WriteHeader(http.StatusNoContent)
+	WriteJSON(w, http.StatusCreated, template)
 }
 
-// ========== Donation Endpoints ==========
-
-// CreateDonation создает пожертвование
-// @Summary     Создать пожертвование
-// @Description Создает новое пожертвование для поста
-// @Tags        Пожертвования
-// @Accept      multipart/form-data
+// UpdatePaymentNoteTemplate изменяет шаблон назначения платежа
+// @Summary     Изменить шаблон назначения платежа
+// @Description Заменяет название и текст шаблона. По updated_at клиенты на других устройствах узнают об изменении.
+// @Tags        Пользователи
+// @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       post_id formData int true "ID поста"
-// @Param       amount formData number true "Сумма пожертвования"
-// @Param       receipt formData file false "Чек/скриншот (JPEG, PNG, PDF, до 10MB)"
-// @Success     201  {object}  DonationResponse
+// @Param       id path int true "ID шаблона"
+// @Param       request body SavePaymentNoteTemplateRequest true "Название и текст"
+// @Success     200  {object}  PaymentNoteTemplate
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
 // @Failure     404  {object}  ErrorResponse
-// @Router      /donations [post]
-func (h *Handlers) CreateDonation(w http.ResponseWriter, r *http.Request) {
+// @Failure     409  {object}  ErrorResponse
+// @Router      /users/me/templates/{id} [put]
+func (h *Handlers) UpdatePaymentNoteTemplate(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	if err := ParseMultipartForm(r, 10<<20); err != nil {
-		WriteError(w, err)
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID шаблона", nil))
 		return
 	}
 
-	var req CreateDonationRequest
-	req.PostID, _ = strconv.ParseInt(r.FormValue("post_id"), 10, 64)
-	req.Amount, _ = strconv.ParseFloat(r.FormValue("amount"), 64)
-
-	if err := ValidateStruct(&req); err != nil {
+	req, err := decodePaymentNoteTemplate(r)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	// Проверяем существование поста
-	_, err = h.db.GetPostByID(req.PostID)
+	template, err := h.db.UpdatePaymentNoteTemplate(userID, id, req.Name, req.Text)
 	if err != nil {
-		WriteError(w, NewNotFoundError("Пост"))
+		WriteError(w, err)
 		return
 	}
-
-	donation := &Donation{
-		PostID:  req.PostID,
-		DonorID: userID,
-		Amount:  req.Amount,
-	}
-
-	// Загружаем чек если есть
-	if receipt, header, err := r.FormFile("receipt"); err == nil {
-		defer receipt.Close()
-
-		if err := ValidateFileSize(header, 10<<20); err != nil {
-			WriteError(w, err)
-			return
-		}
-
-		if err := ValidateDocumentFile(header); err != nil {
-			WriteError(w, err)
-			return
-		}
-
-		// Создаем donation сначала чтобы получить ID
-		if err := h.db.CreateDonation(donation); err != nil {
-			WriteError(w, err)
-			return
-		}
-
-		ctx := r.Context()
-		objectKey, err := UploadDonationReceipt(ctx, h.minioClient, donation.ID, receipt, header.Size, header.Header.Get("Content-Type"))
-		if err != nil {
-			WriteError(w, NewInternalError("Ошибка загрузки чека"))
-			return
-		}
-
-		receiptURL := GetObjectURL(h.cfg.MinIOConfig, BucketDonationReceipts, objectKey)
-		donation.ReceiptURL = &receiptURL
-		// Обновляем donation с receipt_url (нужно добавить функцию UpdateDonationReceiptURL)
-	} else {
-		if err := h.db.CreateDonation(donation); err != nil {
-			WriteError(w, err)
-			return
-		}
-	}
-
-	response := map[string]interface{}{
-		"id":          donation.ID,
-		"post_id":     donation.PostID,
-		"donor_id":    donation.DonorID,
-		"amount":      donation.Amount,
-		"receipt_url": donation.ReceiptURL,
-		"status":      donation.Status,
-		"created_at":  donation.CreatedAt,
-	}
-	WriteJSON(w, http.StatusCreated, response)
+	WriteJSON(w, http.StatusOK, template)
 }
 
-// GetDonations получает список пожертвований
-// @Summary     Получить список пожертвований
-// @Description Возвращает список пожертвований с фильтрацией и пагинацией
-// @Tags        Пожертвования
-// @Accept      json
+// DeletePaymentNoteTemplate удаляет шаблон назначения платежа
+// @Summary     Удалить шаблон назначения платежа
+// @Description Удаляет шаблон текущего пользователя
+// @Tags        Пользователи
 // @Produce     json
-// @Param       post_id query int false "Фильтр по посту"
-// @Param       donor_id query int false "Фильтр по донору"
-// @Param       status query string false "Фильтр по статусу" Enums(pending, confirmed, rejected)
-// @Param       page query int false "Номер страницы" default(1)
-// @Param       limit query int false "Количество на странице" default(20)
-// @Success     200  {object}  DonationsListResponse
-// @Router      /donations [get]
-func (h *Handlers) GetDonations(w http.ResponseWriter, r *http.Request) {
-	postIDStr := r.URL.Query().Get("post_id")
-	donorIDStr := r.URL.Query().Get("donor_id")
-	status := r.URL.Query().Get("status")
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	if limit < 1 {
-		limit = 20
-	}
-
-	var postID, donorID *int64
-	if postIDStr != "" {
-		id, _ := strconv.ParseInt(postIDStr, 10, 64)
-		postID = &id
-	}
-	if donorIDStr != "" {
-		id, _ := strconv.ParseInt(donorIDStr, 10, 64)
-		donorID = &id
-	}
-
-	donations, total, err := h.db.GetDonations(postID, donorID, status, page, limit)
+// @Security    BearerAuth
+// @Param       id path int true "ID шаблона"
+// @Success     200  {object}  SuccessResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/templates/{id} [delete]
+func (h *Handlers) DeletePaymentNoteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	// Обогащаем данными
-	var donationsWithDetails []DonationWithDetails
-	for _, donation := range donations {
-		donor, _ := h.db.GetUserByID(donation.DonorID)
-		post, _ := h.db.GetPostByID(donation.PostID)
-
-		var donorInfo *UserInfo
-		if donor != nil {
-			name := donor.FirstName + " " + donor.LastName
-			if donor.HelperName != nil {
-				name = *donor.HelperName
-			}
-			donorInfo = &UserInfo{
-				ID:     donor.ID,
-				Name:   name,
-				Avatar: donor.PhotoURL,
-			}
-		}
-
-		var postInfo *PostInfo
-		if post != nil {
-			postInfo = &PostInfo{
-				ID:        post.ID,
-				Title:     post.Title,
-				Amount:    post.Amount,
-				Collected: post.Collected,
-			}
-		}
-
-		donationsWithDetails = append(donationsWithDetails, DonationWithDetails{
-			Donation: donation,
-			Donor:    donorInfo,
-			Post:     postInfo,
-		})
-	}
-
-	totalPages := (total + limit - 1) / limit
-	response := map[string]interface{}{
-		"data": donationsWithDetails,
-		"pagination": PaginationResponse{
-			Page:       page,
-			Limit:      limit,
-			Total:      total,
-			TotalPages: totalPages,
-		},
-	}
-	WriteJSON(w, http.StatusOK, response)
-}
-
-// GetDonation получает пожертвование по ID
-// @Summary     Получить пожертвование
-// @Description Возвращает детальную информацию о пожертвовании
-// @Tags        Пожертвования
-// @Accept      json
-// @Produce     json
-// @Param       id path int true "ID пожертвования"
-// @Success     200  {object}  DonationWithDetails
-// @Failure     404  {object}  ErrorResponse
-// @Router      /donations/{id} [get]
-func (h *Handlers) GetDonation(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	donationID, err := strconv.ParseInt(vars["id"], 10, 64)
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID пожертвования", nil))
+		WriteError(w, NewValidationError("Неверный ID шаблона", nil))
 		return
 	}
 
-	donation, err := h.db.GetDonationByID(donationID)
+	deleted, err := h.db.DeletePaymentNoteTemplate(userID, id)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	donor, _ := h.db.GetUserByID(donation.DonorID)
-	post, _ := h.db.GetPostByID(donation.PostID)
-
-	var donorInfo *UserInfo
-	if donor != nil {
-		name := donor.FirstName + " " + donor.LastName
-		if donor.HelperName != nil {
-			name = *donor.HelperName
-		}
-		donorInfo = &UserInfo{
-			ID:     donor.ID,
-			Name:   name,
-			Avatar: donor.PhotoURL,
-		}
+	if !deleted {
+		WriteError(w, NewNotFoundError("Шаблон"))
+		return
 	}
 
-	var postInfo *PostInfo
-	if post != nil {
-		postInfo = &PostInfo{
-			ID:        post.ID,
-			Title:     post.Title,
-			Amount:    post.Amount,
-			Collected: post.Collected,
-		}
-	}
+	WriteSuccess(w, http.StatusOK, "Шаблон удален")
+}
 
-	response := DonationWithDetails{
-		Donation: *donation,
-		Donor:    donorInfo,
-		Post:     postInfo,
+// decodePaymentNoteTemplate читает и проверяет шаблон назначения платежа из тела запроса
+func decodePaymentNoteTemplate(r *http.Request) (*SavePaymentNoteTemplateRequest, error) {
+	var req SavePaymentNoteTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, NewValidationError("Неверный формат запроса", nil)
 	}
-	WriteJSON(w, http.StatusOK, response)
+	req.Name = strings.TrimSpace(req.Name)
+	req.Text = strings.TrimSpace(req.Text)
+	if err := ValidateStruct(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
 }
 
-// UpdateDonation подтверждает/отклоняет пожертвование (только для админов или автора поста)
-// @Summary     Подтвердить/отклонить пожертвование
-// @Description Обновляет статус пожертвования (подтвердить или отклонить)
+// ========== Subscription Endpoints ==========
+
+// GetSubscriptions получает подписки на регулярные пожертвования
+// @Summary     Регулярные пожертвования
+// @Description Возвращает подписки текущего пользователя: активные первыми по ближайшему сроку, затем приостановленные
 // @Tags        Пожертвования
-// @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID пожертвования"
-// @Param       request body UpdateDonationRequest true "Статус"
-// @Success     200  {object}  DonationUpdateResponse
-// @Failure     400  {object}  ErrorResponse
+// @Success     200  {array}   Subscription
 // @Failure     401  {object}  ErrorResponse
-// @Failure     403  {object}  ErrorResponse
-// @Router      /donations/{id} [patch]
-func (h *Handlers) UpdateDonation(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	donationID, err := strconv.ParseInt(vars["id"], 10, 64)
-	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID пожертвования", nil))
-		return
-	}
-
+// @Router      /users/me/subscriptions [get]
+func (h *Handlers) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	userRole, _ := GetUserRoleFromContext(r.Context())
-
-	donation, err := h.db.GetDonationByID(donationID)
+	subscriptions, err := h.db.GetSubscriptions(userID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	WriteJSON(w, http.StatusOK, subscriptions)
+}
 
-	// Проверяем права: admin или автор поста
-	post, err := h.db.GetPostByID(donation.PostID)
+// CreateSubscription оформляет регулярное пожертвование
+// @Summary     Оформить регулярное пожертвование
+// @Description Раз в неделю или месяц планировщик создает пожертвование на сбор в статусе pending и присылает уведомление
+// @Description subscription_due (donation_id в данных push), донор оплачивает его или прикладывает чек. Первое пожертвование
+// @Description создается в start_at или, без него, в течение 10 минут. На один сбор у донора одна подписка.
+// @Tags        Пожертвования
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       request body CreateSubscriptionRequest true "Сбор, сумма и периодичность"
+// @Success     201  {object}  Subscription
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /users/me/subscriptions [post]
+func (h *Handlers) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	if userRole != "admin" && post.UserID != userID {
-		WriteError(w, NewForbiddenError("Недостаточно прав"))
-		return
-	}
-
-	var req UpdateDonationRequest
+	var req CreateSubscriptionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
 	}
-
 	if err := ValidateStruct(&req); err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	if err := h.db.UpdateDonationStatus(donationID, req.Status, userID); err != nil {
+	req.Amount = h.cfg.Money.RoundAmount(req.Amount)
+	if err := h.cfg.Money.ValidateDonationAmount(req.Amount); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	// Если подтверждено, обновляем рейтинг и собранную сумму
-	if req.Status == "confirmed" {
-		// Обновляем собранную сумму поста
-		h.db.UpdatePostCollected(donation.PostID, donation.Amount)
-
-		// Обновляем рейтинг донора
-		rating, err := h.db.GetOrCreateRating(donation.DonorID)
-		if err == nil {
-			newPoints := rating.Points + int(donation.Amount) // 1 рубль = 1 балл
-			newTotalDonated := rating.TotalDonated + donation.Amount
-			h.db.UpdateRating(donation.DonorID, newPoints, newTotalDonated)
-		}
+	post, err := h.db.GetPostByID(req.PostID)
+	if err != nil {
+		WriteError(w, NewNotFoundError("Пост"))
+		return
+	}
+	if post.Status != "active" {
+		WriteError(w, NewConflictError("Сбор не принимает пожертвования"))
+		return
 	}
 
-	donation, _ = h.db.GetDonationByID(donationID)
-	response := map[string]interface{}{
-		"id":           donation.ID,
-		"status":       donation.Status,
-		"confirmed_at": donation.ConfirmedAt,
-		"confirmed_by": donation.ConfirmedBy,
+	subscription := &Subscription{
+		PostID:      post.ID,
+		PostTitle:   post.Title,
+		DonorID:     userID,
+		Amount:      req.Amount,
+		Interval:    req.Interval,
+		IsAnonymous: req.IsAnonymous,
+		NextRunAt:   time.Now(),
 	}
-	WriteJSON(w, http.StatusOK, response)
+	if req.StartAt != nil && req.StartAt.After(subscription.NextRunAt) {
+		subscription.NextRunAt = *req.StartAt
+	}
+	if err := h.db.CreateSubscription(subscription); err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, subscription)
 }
 
-// ========== Chat Endpoints ==========
-
-// GetChats получает список чатов текущего пользователя
-// @Summary     Получить список чатов
-// @Description Возвращает список всех чатов текущего пользователя
-// @Tags        Чаты
+// UpdateSubscription изменяет подписку
+// @Summary     Изменить регулярное пожертвование
+// @Description Меняет сумму, периодичность или анонимность следующих пожертвований; is_active=false приостанавливает подписку.
+// @Description После возобновления срок остается прежним, а если он прошел - переносится на следующий период без пропущенных пожертвований.
+// @Tags        Пожертвования
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Success     200  {object}  ChatsListResponse
+// @Param       id path int true "ID подписки"
+// @Param       request body UpdateSubscriptionRequest true "Изменяемые поля"
+// @Success     200  {object}  Subscription
+// @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
-// @Router      /chats [get]
-func (h *Handlers) GetChats(w http.ResponseWriter, r *http.Request) {
+// @Failure     404  {object}  ErrorResponse
+// @Failure     409  {object}  ErrorResponse
+// @Router      /users/me/subscriptions/{id} [patch]
+func (h *Handlers) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	chats, err := h.db.GetChatsByUserID(userID)
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
-		WriteError(w, err)
+		WriteError(w, NewValidationError("Неверный ID подписки", nil))
 		return
 	}
 
-	var chatsWithDetails []ChatWithDetails
-	for _, chat := range chats {
-		post, _ := h.db.GetPostByID(chat.PostID)
-		var interlocutorID int64
-		if chat.HelperID == userID {
-			interlocutorID = chat.NeedyID
-		} else {
-			interlocutorID = chat.HelperID
+	var req UpdateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if req.Amount != nil {
+		*req.Amount = h.cfg.Money.RoundAmount(*req.Amount)
+		if err := h.cfg.Money.ValidateDonationAmount(*req.Amount); err != nil {
+			WriteError(w, err)
+			return
 		}
-		interlocutor, _ := h.db.GetUserByID(interlocutorID)
-		lastMessage, _ := h.db.GetLastMessage(chat.ID)
-		unreadCount, _ := h.db.GetUnreadCount(chat.ID, userID)
+	}
 
-		var postDetails *PostWithDetails
-		if post != nil {
-			author, _ := h.db.GetUserByID(post.UserID)
-			var authorInfo *UserInfo
-			if author != nil {
-				name := fmt.Sprintf("%s %s", author.FirstName, author.LastName)
-				authorInfo = &UserInfo{
-					ID:     author.ID,
-					Name:   name,
-					Avatar: author.PhotoURL,
-				}
+	subscription, err := h.db.GetSubscription(userID, id)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	if req.Amount != nil {
+		subscription.Amount = *req.Amount
+	}
+	if req.Interval != nil {
+		subscription.Interval = *req.Interval
+	}
+	if req.IsAnonymous != nil {
+		subscription.IsAnonymous = *req.IsAnonymous
+	}
+	if req.IsActive != nil && *req.IsActive != subscription.IsActive {
+		if *req.IsActive {
+			post, err := h.db.GetPostByID(subscription.PostID)
+			if err != nil {
+				WriteError(w, err)
+				return
 			}
-			postDetails = &PostWithDetails{
-				Post:   *post,
-				Author: authorInfo,
+			if post.Status != "active" {
+				WriteError(w, NewConflictError("Сбор не принимает пожертвования"))
+				return
 			}
-		}
-
-		var interlocutorInfo *UserInfo
-		if interlocutor != nil {
-			name := fmt.Sprintf("%s %s", interlocutor.FirstName, interlocutor.LastName)
-			interlocutorInfo = &UserInfo{
-				ID:     interlocutor.ID,
-				Name:   name,
-				Avatar: interlocutor.PhotoURL,
+			if now := time.Now(); subscription.NextRunAt.Before(now) {
+				subscription.NextRunAt = NextSubscriptionRun(subscription.Interval, subscription.NextRunAt, now)
 			}
 		}
-
-		chatsWithDetails = append(chatsWithDetails, ChatWithDetails{
-			Chat:         chat,
-			Post:         postDetails,
-			Interlocutor: interlocutorInfo,
-			LastMessage:  lastMessage,
-			UnreadCount:  unreadCount,
-		})
+		subscription.IsActive = *req.IsActive
 	}
 
-	response := map[string]interface{}{
-		"data": chatsWithDetails,
+	if err := h.db.UpdateSubscription(subscription); err != nil {
+		WriteError(w, err)
+		return
 	}
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, subscription)
 }
 
-// CreateChat создает чат (при первом сообщении помощника к посту)
-// @Summary     Создать чат
-// @Description Создает новый чат между помощником и нуждающимся по посту
-// @Tags        Чаты
-// @Accept      json
+// DeleteSubscription отменяет подписку
+// @Summary     Отменить регулярное пожертвование
+// @Description Удаляет подписку текущего пользователя. Уже созданные пожертвования остаются.
+// @Tags        Пожертвования
 // @Produce     json
 // @Security    BearerAuth
-// @Param       request body CreateChatRequest true "ID поста"
-// @Success     201  {object}  ChatResponse
+// @Param       id path int true "ID подписки"
+// @Success     200  {object}  SuccessResponse
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
-// @Failure     409  {object}  ErrorResponse
-// @Router      /chats [post]
-func (h *Handlers) CreateChat(w http.ResponseWriter, r *http.Request) {
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/subscriptions/{id} [delete]
+func (h *Handlers) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	var req CreateChatRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, NewValidationError("Неверный формат запроса", nil))
-		return
-	}
-
-	if err := ValidateStruct(&req); err != nil {
-		WriteError(w, err)
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID подписки", nil))
 		return
 	}
 
-	post, err := h.db.GetPostByID(req.PostID)
+	deleted, err := h.db.DeleteSubscription(userID, id)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	// Проверяем, существует ли уже чат
-	existingChat, _ := h.db.GetChatByPostAndHelper(req.PostID, userID)
-	if existingChat != nil {
-		WriteError(w, NewConflictError("Чат уже существует"))
-		return
-	}
-
-	chat, err := h.db.CreateChat(req.PostID, userID, post.UserID)
-	if err != nil {
-		WriteError(w, err)
+	if !deleted {
+		WriteError(w, NewNotFoundError("Подписка"))
 		return
 	}
 
-	response := map[string]interface{}{
-		"id":         chat.ID,
-		"post_id":    chat.PostID,
-		"helper_id":  chat.HelperID,
-		"needy_id":   chat.NeedyID,
-		"created_at": chat.CreatedAt,
-	}
-	WriteJSON(w, http.StatusCreated, response)
+	WriteSuccess(w, http.StatusOK, "Подписка отменена")
 }
 
-// GetMessages получает сообщения чата
-// @Summary     Получить сообщения чата
-// @Description Возвращает список сообщений в чате с пагинацией
-// @Tags        Чаты
+// ========== Integration Endpoints ==========
+
+// GetIntegrations получает интеграции текущего пользователя
+// @Summary     Получить интеграции
+// @Description Возвращает подключенные webhook и Telegram чат для оповещений о пожертвованиях
+// @Tags        Интеграции
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID чата"
-// @Param       page query int false "Номер страницы" default(1)
-// @Param       limit query int false "Количество сообщений" default(50)
-// @Success     200  {object}  MessagesListResponse
+// @Success     200  {array}   Integration
 // @Failure     401  {object}  ErrorResponse
-// @Router      /chats/{id}/messages [get]
-func (h *Handlers) GetMessages(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	chatID, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Router      /users/me/integrations [get]
+func (h *Handlers) GetIntegrations(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID чата", nil))
+		WriteError(w, err)
 		return
 	}
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	if limit < 1 {
-		limit = 50
-	}
-	if limit > 100 {
-		limit = 100
-	}
-
-	messages, total, err := h.db.GetMessages(chatID, page, limit)
+	integrations, err := h.db.GetIntegrations(userID)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	var messagesWithDetails []MessageWithDetails
-	for _, msg := range messages {
-		sender, _ := h.db.GetUserByID(msg.SenderID)
-		var senderInfo *UserInfo
-		if sender != nil {
-			name := fmt.Sprintf("%s %s", sender.FirstName, sender.LastName)
-			if sender.HelperName != nil {
-				name = *sender.HelperName
-			}
-			senderInfo = &UserInfo{
-				ID:     sender.ID,
-				Name:   name,
-				Avatar: sender.PhotoURL,
-			}
-		}
-
-		messagesWithDetails = append(messagesWithDetails, MessageWithDetails{
-			Message: msg,
-			Sender:  senderInfo,
-		})
+	if integrations == nil {
+		integrations = []Integration{}
 	}
 
-	totalPages := (total + limit - 1) / limit
-	response := map[string]interface{}{
-		"data": messagesWithDetails,
-		"pagination": PaginationResponse{
-			Page:       page,
-			Limit:      limit,
-			Total:      total,
-			TotalPages: totalPages,
-		},
-	}
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, integrations)
 }
 
-// SendMessage отправляет сообщение в чат
-// @Summary     Отправить сообщение
-// @Description Отправляет новое сообщение в чат (текст или вложение)
-// @Tags        Чаты
-// @Accept      multipart/form-data
+// UpsertIntegration подключает webhook или Telegram чат
+// @Summary     Подключить интеграцию
+// @Description Подключает личный webhook (target - URL) или Telegram чат (target - chat_id) для мгновенных оповещений о пожертвованиях на посты пользователя. Доступно только верифицированным авторам. Для webhook в ответе возвращается секрет подписи X-Webhook-Signature, повторно он не выдается.
+// @Tags        Интеграции
+// @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID чата"
-// @Param       text formData string false "Текст сообщения"
-// @Param       attachment formData file false "Вложение (изображение, до 5MB)"
-// @Success     201  {object}  MessageResponse
+// @Param       request body UpsertIntegrationRequest true "Параметры интеграции"
+// @Success     200  {object}  IntegrationResponse
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
-// @Router      /chats/{id}/messages [post]
-func (h *Handlers) SendMessage(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	chatID, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Failure     403  {object}  ErrorResponse
+// @Router      /users/me/integrations [put]
+func (h *Handlers) UpsertIntegration(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID чата", nil))
+		WriteError(w, err)
 		return
 	}
 
-	userID, err := GetUserIDFromContext(r.Context())
-	if err != nil {
-		WriteError(w, err)
+	var req UpsertIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
 	}
 
-	if err := ParseMultipartForm(r, 5<<20); err != nil {
+	if err := ValidateStruct(&req); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	var text *string
-	if textVal := r.FormValue("text"); textVal != "" {
-		text = &textVal
+	if !h.db.IsUserVerified(userID) {
+		WriteError(w, NewForbiddenError("Интеграции доступны только верифицированным авторам"))
+		return
 	}
 
-	var message *Message
-	var attachmentURL *string
-
-	if attachment, header, err := r.FormFile("attachment"); err == nil {
-		defer attachment.Close()
-
-		if err := ValidateFileSize(header, 5<<20); err != nil {
-			WriteError(w, err)
-			return
-		}
-
-		if err := ValidateImageFile(header); err != nil {
-			WriteError(w, err)
-			return
-		}
-
-		// Создаем сообщение сначала чтобы получить ID
-		message = &Message{
-			ChatID:   chatID,
-			SenderID: userID,
-			Text:     text,
-		}
-		if err := h.db.CreateMessage(message); err != nil {
-			WriteError(w, err)
-			return
-		}
-
-		ctx := r.Context()
-		objectKey, err := UploadChatAttachment(ctx, h.minioClient, chatID, message.ID, attachment, header.Size, header.Header.Get("Content-Type"))
-		if err != nil {
-			WriteError(w, NewInternalError("Ошибка загрузки вложения"))
-			return
-		}
-
-		url := GetObjectURL(h.cfg.MinIOConfig, BucketChatAttachments, objectKey)
-		attachmentURL = &url
-		message.AttachmentURL = attachmentURL
-		// Обновляем сообщение с attachment_url (нужно добавить функцию)
-	} else {
-		if text == nil {
-			WriteError(w, NewValidationError("Текст или вложение обязательны", nil))
-			return
-		}
+	req.Target = strings.TrimSpace(req.Target)
+	if err := h.validateIntegrationTarget(r.Context(), req.Type, req.Target); err != nil {
+		WriteError(w, err)
+		return
+	}
 
-		message = &Message{
-			ChatID:        chatID,
-			SenderID:      userID,
-			Text:          text,
-			AttachmentURL: attachmentURL,
-		}
-		if err := h.db.CreateMessage(message); err != nil {
-			WriteError(w, err)
-			return
-		}
+	secret, err := GenerateWebhookSecret()
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка генерации секрета"))
+		return
 	}
 
-	// Обновляем время последнего сообщения в чате
-	h.db.UpdateChatUpdatedAt(chatID)
+	integration, err := h.db.UpsertIntegration(userID, req.Type, req.Target, secret)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
 
-	response := map[string]interface{}{
-		"id":             message.ID,
-		"chat_id":        message.ChatID,
-		"sender_id":      message.SenderID,
-		"text":           message.Text,
-		"attachment_url": message.AttachmentURL,
-		"is_read":        message.IsRead,
-		"is_edited":      message.IsEdited,
-		"created_at":     message.CreatedAt,
+	response := IntegrationResponse{Integration: *integration}
+	if integration.Type == IntegrationWebhook {
+		response.Secret = integration.Secret
 	}
-	WriteJSON(w, http.StatusCreated, response)
+	WriteJSON(w, http.StatusOK, response)
 }
 
-// MarkMessagesRead отмечает сообщения как прочитанные
-// @Summary     Отметить сообщения как прочитанные
-// @Description Отмечает сообщения в чате как прочитанные
-// @Tags        Чаты
+// DeleteIntegration отключает интеграцию
+// @Summary     Отключить интеграцию
+// @Description Отключает интеграцию указанного типа. Неотправленные события удаляются.
+// @Tags        Интеграции
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID чата"
-// @Param       request body MarkMessagesReadRequest false "ID сообщений (опционально, если пусто - все сообщения)"
-// @Success     200  {object}  MarkMessagesReadResponse
+// @Param       type path string true "Тип интеграции (webhook, telegram)"
+// @Success     200  {object}  SuccessResponse
 // @Failure     401  {object}  ErrorResponse
-// @Router      /chats/{id}/messages/read [patch]
-func (h *Handlers) MarkMessagesRead(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	chatID, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Failure     404  {object}  ErrorResponse
+// @Router      /users/me/integrations/{type} [delete]
+func (h *Handlers) DeleteIntegration(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID чата", nil))
+		WriteError(w, err)
 		return
 	}
 
-	var req MarkMessagesReadRequest
-	if r.ContentLength > 0 {
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			WriteError(w, NewValidationError("Неверный формат запроса", nil))
-			return
-		}
-	}
-
-	count, err := h.db.MarkMessagesAsRead(chatID, req.MessageIDs)
+	deleted, err := h.db.DeleteIntegration(userID, mux.Vars(r)["type"])
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	response := map[string]interface{}{
-		"updated_count": count,
-		"message":       "Сообщения отмечены как прочитанные",
+	if !deleted {
+		WriteError(w, NewNotFoundError("Интеграция"))
+		return
 	}
-	WriteJSON(w, http.StatusOK, response)
+
+	WriteSuccess(w, http.StatusOK, "Интеграция отключена")
 }
 
-// UpdateMessage редактирует сообщение (только отправитель)
-// @Summary     Редактировать сообщение
-// @Description Редактирует текст сообщения (только отправитель может редактировать)
-// @Tags        Чаты
+// ========== Partner Webhook Endpoints ==========
+
+// CreateWebhook создает вебхук партнера
+// @Summary     Создать вебхук партнера
+// @Description Подписывает URL партнера на события платформы: post.created, donation.created, donation.confirmed,
+// @Description verification.approved, verification.rejected. События доставляются POST запросом с повторами,
+// @Description тело подписывается HMAC-SHA256 (заголовок X-Webhook-Signature). Секрет подписи возвращается только в ответе.
+// @Tags        Интеграции
 // @Accept      json
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID чата"
-// @Param       message_id path int true "ID сообщения"
-// @Param       request body UpdateMessageRequest true "Новый текст"
-// @Success     200  {object}  MessageUpdateResponse
+// @Param       request body CreateWebhookRequest true "Название, URL и события"
+// @Success     201  {object}  WebhookResponse
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
 // @Failure     403  {object}  ErrorResponse
-// @Router      /chats/{id}/messages/{message_id} [patch]
-func (h *Handlers) UpdateMessage(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	_, err := strconv.ParseInt(vars["id"], 10, 64)
+// @Router      /admin/webhooks [post]
+func (h *Handlers) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, err := GetUserIDFromContext(r.Context())
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID чата", nil))
+		WriteError(w, err)
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, NewValidationError("Неверный формат запроса", nil))
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.URL = strings.TrimSpace(req.URL)
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+	if err := h.validateIntegrationTarget(r.Context(), IntegrationWebhook, req.URL); err != nil {
+		WriteError(w, err)
 		return
 	}
 
-	messageID, err := strconv.ParseInt(vars["message_id"], 10, 64)
+	secret, err := GenerateWebhookSecret()
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID сообщения", nil))
+		WriteError(w, NewInternalError("Ошибка генерации секрета"))
 		return
 	}
 
-	_, err = GetUserIDFromContext(r.Context())
+	webhook := &PartnerWebhook{
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    uniqueStrings(req.Events),
+		CreatedBy: &userID,
+	}
+	if err := h.db.CreatePartnerWebhook(webhook); err != nil {
+		WriteError(w, err)
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:     AdminActionWebhookCreate,
+		TargetType: "webhook",
+		TargetID:   webhook.ID,
+		TargetName: webhook.Name,
+		NewValue:   map[string]interface{}{"url": webhook.URL, "events": webhook.Events},
+	})
+	WriteJSON(w, http.StatusCreated, WebhookResponse{PartnerWebhook: *webhook, Secret: webhook.Secret})
+}
+
+// GetWebhooks получает вебхуки партнеров
+// @Summary     Вебхуки партнеров
+// @Description Возвращает все вебхуки партнеров без секретов подписи
+// @Tags        Интеграции
+// @Produce     json
+// @Security    BearerAuth
+// @Success     200  {array}   PartnerWebhook
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Router      /admin/webhooks [get]
+func (h *Handlers) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.db.GetPartnerWebhooks()
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	WriteJSON(w, http.StatusOK, webhooks)
+}
 
-	// Проверяем, что сообщение принадлежит пользователю (нужно добавить GetMessageByID)
-	// Пока пропускаем проверку
+// UpdateWebhook изменяет вебхук партнера
+// @Summary     Изменить вебхук партнера
+// @Description Меняет название, URL, события или включает и отключает вебхук. При отключении неотправленные события
+// @Description отменяются. С rotate_secret выдается новый секрет подписи, он возвращается только в ответе.
+// @Tags        Интеграции
+// @Accept      json
+// @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID вебхука"
+// @Param       request body UpdateWebhookRequest true "Изменяемые поля"
+// @Success     200  {object}  WebhookResponse
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/webhooks/{id} [patch]
+func (h *Handlers) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID вебхука", nil))
+		return
+	}
 
-	var req UpdateMessageRequest
+	var req UpdateWebhookRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
 	}
-
+	if req.Name != nil {
+		*req.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.URL != nil {
+		*req.URL = strings.TrimSpace(*req.URL)
+	}
 	if err := ValidateStruct(&req); err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	if err := h.db.UpdateMessage(messageID, req.Text); err != nil {
+	webhook, err := h.db.GetPartnerWebhook(id)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	oldValue := map[string]interface{}{"name": webhook.Name, "url": webhook.URL, "events": webhook.Events, "is_active": webhook.IsActive}
 
-	response := map[string]interface{}{
-		"id":         messageID,
-		"text":       req.Text,
-		"is_edited":  true,
-		"updated_at": time.Now(),
+	if req.Name != nil {
+		webhook.Name = *req.Name
+	}
+	if req.URL != nil {
+		if err := h.validateIntegrationTarget(r.Context(), IntegrationWebhook, *req.URL); err != nil {
+			WriteError(w, err)
+			return
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = uniqueStrings(req.Events)
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+	if req.RotateSecret {
+		if webhook.Secret, err = GenerateWebhookSecret(); err != nil {
+			WriteError(w, NewInternalError("Ошибка генерации секрета"))
+			return
+		}
+	}
+
+	if err := h.db.UpdatePartnerWebhook(webhook); err != nil {
+		WriteError(w, err)
+		return
+	}
+	newValue := map[string]interface{}{"name": webhook.Name, "url": webhook.URL, "events": webhook.Events, "is_active": webhook.IsActive}
+	if req.RotateSecret {
+		newValue["secret_rotated"] = true
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:     AdminActionWebhookUpdate,
+		TargetType: "webhook",
+		TargetID:   webhook.ID,
+		TargetName: webhook.Name,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+	})
+
+	response := WebhookResponse{PartnerWebhook: *webhook}
+	if req.RotateSecret {
+		response.Secret = webhook.Secret
 	}
 	WriteJSON(w, http.StatusOK, response)
 }
 
-// DeleteMessage удаляет сообщение (только отправитель)
-// @Summary     Удалить сообщение
-// @Description Удаляет сообщение из чата (только отправитель может удалить)
-// @Tags        Чаты
-// @Accept      json
+// DeleteWebhook удаляет вебхук партнера
+// @Summary     Удалить вебхук партнера
+// @Description Удаляет вебхук вместе с очередью и историей его событий
+// @Tags        Интеграции
 // @Produce     json
 // @Security    BearerAuth
-// @Param       id path int true "ID чата"
-// @Param       message_id path int true "ID сообщения"
-// @Success     204  "Успешно удалено"
+// @Param       id path int true "ID вебхука"
+// @Success     200  {object}  SuccessResponse
 // @Failure     401  {object}  ErrorResponse
 // @Failure     403  {object}  ErrorResponse
-// @Router      /chats/{id}/messages/{message_id} [delete]
-func (h *Handlers) DeleteMessage(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	messageID, err := strconv.ParseInt(vars["message_id"], 10, 64)
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/webhooks/{id} [delete]
+func (h *Handlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
-		WriteError(w, NewValidationError("Неверный ID сообщения", nil))
+		WriteError(w, NewValidationError("Неверный ID вебхука", nil))
 		return
 	}
 
-	_, err = GetUserIDFromContext(r.Context())
+	webhook, err := h.db.GetPartnerWebhook(id)
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	// Проверяем права (нужно добавить GetMessageByID)
-	// Пока пропускаем
-
-	if err := h.db.DeleteMessage(messageID); err != nil {
+	deleted, err := h.db.DeletePartnerWebhook(id)
+	if err != nil {
 		WriteError(w, err)
 		return
 	}
+	if !deleted {
+		WriteError(w, NewNotFoundError("Вебхук"))
+		return
+	}
+	h.recordAdminAction(r.Context(), AdminActionRecord{
+		Action:     AdminActionWebhookDelete,
+		TargetType: "webhook",
+		TargetID:   webhook.ID,
+		TargetName: webhook.Name,
+		OldValue:   map[string]interface{}{"url": webhook.URL, "events": webhook.Events},
+	})
 
-	w.WriteHeader(http.StatusNoContent)
+	WriteSuccess(w, http.StatusOK, "Вебхук удален")
 }
 
-// ========== Rating Endpoints ==========
-
-// GetRatings получает рейтинг пользователей
-// @Summary     Получить рейтинг пользователей
-// @Description Возвращает рейтинг пользователей с пагинацией
-// @Tags        Рейтинг
-// @Accept      json
+// GetWebhookDeliveries получает события вебхука партнера
+// @Summary     События вебхука партнера
+// @Description Возвращает события вебхука с состоянием доставки, числом попыток и последней ошибкой, новые первыми
+// @Tags        Интеграции
 // @Produce     json
+// @Security    BearerAuth
+// @Param       id path int true "ID вебхука"
+// @Param       status query string false "Состояние доставки" Enums(pending, delivered, failed)
 // @Param       page query int false "Номер страницы" default(1)
-// @Param       limit query int false "Количество на странице" default(50)
-// @Success     200  {object}  RatingsListResponse
-// @Router      /ratings [get]
-func (h *Handlers) GetRatings(w http.ResponseWriter, r *http.Request) {
+// @Param       limit query int false "Количество на странице" default(20)
+// @Success     200  {object}  map[string]interface{}
+// @Failure     400  {object}  ErrorResponse
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
+// @Router      /admin/webhooks/{id}/deliveries [get]
+func (h *Handlers) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		WriteError(w, NewValidationError("Неверный ID вебхука", nil))
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", "pending", "delivered", "failed":
+	default:
+		WriteError(w, NewValidationError("Неверный статус доставки", map[string]interface{}{"field": "status"}))
+		return
+	}
+
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	if page < 1 {
 		page = 1
 	}
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	if limit < 1 {
-		limit = 50
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
 	}
 
-	ratings, total, err := h.db.GetRatings(page, limit)
-	if err != nil {
+	if _, err := h.db.GetPartnerWebhook(id); err != nil {
 		WriteError(w, err)
 		return
 	}
-
-	var ratingsWithDetails []RatingWithDetails
-	for i, rating := range ratings {
-		user, _ := h.db.GetUserByID(rating.UserID)
-		var userInfo *UserInfo
-		if user != nil {
-			name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
-			if user.HelperName != nil {
-				name = *user.HelperName
-			}
-			userInfo = &UserInfo{
-				ID:     user.ID,
-				Name:   name,
-				Avatar: user.PhotoURL,
-			}
-		}
-
-		position := (page-1)*limit + i + 1
-		ratingsWithDetails = append(ratingsWithDetails, RatingWithDetails{
-			Rating:   rating,
-			User:     userInfo,
-			Position: position,
-		})
+	deliveries, total, err := h.db.GetPartnerWebhookDeliveries(id, status, page, limit)
+	if err != nil {
+		WriteError(w, err)
+		return
 	}
 
-	totalPages := (total + limit - 1) / limit
 	response := map[string]interface{}{
-		"data": ratingsWithDetails,
+		"data": deliveries,
 		"pagination": PaginationResponse{
 			Page:       page,
 			Limit:      limit,
 			Total:      total,
-			TotalPages: totalPages,
+			TotalPages: (total + limit - 1) / limit,
 		},
 	}
 	WriteJSON(w, http.StatusOK, response)
 }
 
-// GetMyRating получает рейтинг текущего пользователя
-// @Summary     Получить свой рейтинг
-// @Description Возвращает рейтинг текущего пользователя с позицией
-// @Tags        Рейтинг
-// @Accept      json
-// @Produce     json
-// @Security    BearerAuth
-// @Success     200  {object}  RatingWithDetails
-// @Failure     401  {object}  ErrorResponse
-// @Failure     404  {object}  ErrorResponse
-// @Router      /ratings/me [get]
-func (h *Handlers) GetMyRating(w http.ResponseWriter, r *http.Request) {
-	userID, err := GetUserIDFromContext(r.Context())
-	if err != nil {
-		WriteError(w, err)
-		return
+// uniqueStrings убирает повторы, сохраняя порядок
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
 	}
+	return result
+}
 
-	rating, err := h.db.GetOrCreateRating(userID)
+// ========== Utility Endpoints ==========
+
+// GetMoneyConfig получает валюту и правила денежных сумм
+// @Summary     Правила денежных сумм
+// @Description Возвращает валюту, округление, формат записи, границы суммы пожертвования и начисление баллов.
+// @Description Суммы округляются до fraction_digits знаков (half_up) и проверяются по donation.min и donation.max при создании пожертвования и подписки.
+// @Description Баллы за подтвержденное пожертвование: floor(сумма * points.per_unit * points.multiplier), multiplier - множитель идущей акции.
+// @Tags        Утилиты
+// @Produce     json
+// @Success     200  {object}  MoneyConfigResponse
+// @Router      /config/money [get]
+func (h *Handlers) GetMoneyConfig(w http.ResponseWriter, r *http.Request) {
+	events, err := h.db.GetCurrentPointsEvents()
 	if err != nil {
 		WriteError(w, err)
 		return
 	}
 
-	position, _ := h.db.GetRatingPosition(userID)
-
-	response := RatingWithDetails{
-		Rating:   *rating,
-		Position: position,
+	// Как в ConfirmDonation: из пересекающихся акций действует наибольший множитель
+	now := time.Now()
+	multiplier := 1.0
+	for _, e := range events {
+		if !e.StartsAt.After(now) && e.Multiplier > multiplier {
+			multiplier = e.Multiplier
+		}
 	}
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, h.cfg.Money.Response(multiplier))
 }
 
-// ========== Utility Endpoints ==========
-
 // GetPresignedURL получает presigned URL для загрузки файла
 // @Summary     Получить presigned URL
-// @Description Генерирует presigned URL для прямой загрузки файла в MinIO
+// @Description Генерирует presigned URL для прямой загрузки файла в MinIO. Доступно только для bucket user-photos (JPEG, PNG, WebP)
+// @Description и post-media (также MP4, WebM). Файл сохраняется под ключом uploads/{user_id}/{имя}, загружать нужно с тем же Content-Type.
 // @Tags        Утилиты
 // @Accept      json
 // @Produce     json
@@ -2167,6 +9524,7 @@ func (h *Handlers) GetMyRating(w http.ResponseWriter, r *http.Request) {
 // @Success     200  {object}  PresignedURLResponse
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
 // @Router      /upload/presigned-url [post]
 func (h *Handlers) GetPresignedURL(w http.ResponseWriter, r *http.Request) {
 	var req PresignedURLRequest
@@ -2180,30 +9538,48 @@ func (h *Handlers) GetPresignedURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	objectKey, err := presignedUploadKey(userID, req.Bucket, req.ObjectKey, req.ContentType)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
 	expiresIn := time.Duration(req.ExpiresIn) * time.Second
 	if expiresIn == 0 {
 		expiresIn = time.Hour
 	}
 
 	ctx := r.Context()
-	uploadURL, err := GeneratePresignedURL(ctx, h.minioClient, req.Bucket, req.ObjectKey, req.ContentType, expiresIn)
+	uploadURL, err := GeneratePresignedURL(ctx, h.minioClient, req.Bucket, objectKey, req.ContentType, expiresIn)
 	if err != nil {
 		WriteError(w, NewInternalError("Ошибка генерации URL"))
 		return
 	}
 
-	objectURL := GetObjectURL(h.cfg.MinIOConfig, req.Bucket, req.ObjectKey)
+	objectURL := GetObjectURL(h.cfg.MinIOConfig, req.Bucket, objectKey)
 	response := PresignedURLResponse{
 		UploadURL: uploadURL,
+		ObjectKey: objectKey,
 		ObjectURL: objectURL,
 		ExpiresAt: time.Now().Add(expiresIn),
 	}
 	WriteJSON(w, http.StatusOK, response)
 }
 
+// privatePresignedURLTTL наибольшее время жизни ссылки на файл закрытого bucket
+const privatePresignedURLTTL = 15 * time.Minute
+
 // GetPresignedGetURL получает presigned URL для чтения файла
 // @Summary     Получить presigned URL для чтения
-// @Description Генерирует presigned URL для чтения (скачивания) файла из MinIO
+// @Description Генерирует presigned URL для чтения (скачивания) файла из MinIO. Доступ к файлу проверяется так же, как в GET /files.
+// @Description Ссылки на файлы закрытых bucket действуют не дольше 15 минут. Ссылки на документы верификации здесь не выдаются:
+// @Description владелец получает их через GET /files с JWT, проверяющие - через GET /verifications/{id}/documents.
 // @Tags        Утилиты
 // @Accept      json
 // @Produce     json
@@ -2212,6 +9588,8 @@ func (h *Handlers) GetPresignedURL(w http.ResponseWriter, r *http.Request) {
 // @Success     200  {object}  PresignedGetURLResponse
 // @Failure     400  {object}  ErrorResponse
 // @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
+// @Failure     404  {object}  ErrorResponse
 // @Router      /files/presigned-url [post]
 func (h *Handlers) GetPresignedGetURL(w http.ResponseWriter, r *http.Request) {
 	var req PresignedGetURLRequest
@@ -2219,16 +9597,35 @@ func (h *Handlers) GetPresignedGetURL(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, NewValidationError("Неверный формат запроса", nil))
 		return
 	}
-
-	if err := ValidateStruct(&req); err != nil {
+
+	if err := ValidateStruct(&req); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	role, _ := GetUserRoleFromContext(r.Context())
+	if err := h.authorizeFileAccess(userID, role, req.Bucket, req.ObjectKey); err != nil {
 		WriteError(w, err)
 		return
 	}
+	// Ссылка работает без JWT и не попадает в журнал, поэтому паспортные сканы так не выдаются
+	if req.Bucket == BucketVerificationDocs {
+		WriteError(w, NewForbiddenError("Ссылки на документы верификации выдаются через GET /verifications/{id}/documents"))
+		return
+	}
 
 	expiresIn := time.Duration(req.ExpiresIn) * time.Second
 	if expiresIn == 0 {
 		expiresIn = time.Hour // По умолчанию 1 час
 	}
+	if !publicBuckets[req.Bucket] && expiresIn > privatePresignedURLTTL {
+		expiresIn = privatePresignedURLTTL
+	}
 
 	ctx := r.Context()
 	url, err := GeneratePresignedGetURL(ctx, h.minioClient, req.Bucket, req.ObjectKey, expiresIn)
@@ -2247,13 +9644,25 @@ func (h *Handlers) GetPresignedGetURL(w http.ResponseWriter, r *http.Request) {
 // GetFile проксирует файл из MinIO через backend
 // @Summary     Получить файл
 // @Description Получает файл из MinIO и отдает его клиенту (проксирование). Путь к файлу может содержать слэши, например: /files/user-photos/users/1/photo.jpg
+// @Description Поддерживает заголовок Range для частичной загрузки и перемотки видео.
+// @Description Отдает ETag и Last-Modified, на If-None-Match и If-Modified-Since отвечает 304 без загрузки файла из MinIO.
+// @Description Фото профилей и медиа постов публичны. Документы верификации, чеки и вложения чатов требуют JWT владельца
+// @Description (для тегов img используйте POST /files/presigned-url).
 // @Tags        Утилиты
 // @Accept      json
 // @Produce     application/octet-stream
+// @Security    BearerAuth
 // @Param       bucket path string true "Название bucket"
 // @Param       objectKey path string true "Ключ объекта (путь к файлу, может содержать слэши)"
+// @Param       Range header string false "Диапазон байт, например bytes=0-1048575"
+// @Param       If-None-Match header string false "ETag закэшированной копии"
 // @Success     200  "Файл"
+// @Success     206  "Часть файла"
+// @Success     304  "Файл не изменился"
+// @Failure     401  {object}  ErrorResponse
+// @Failure     403  {object}  ErrorResponse
 // @Failure     404  {object}  ErrorResponse
+// @Failure     416  "Диапазон за пределами файла"
 // @Failure     500  {object}  ErrorResponse
 // @Router      /files/{bucket}/{objectKey} [get]
 func (h *Handlers) GetFile(w http.ResponseWriter, r *http.Request) {
@@ -2274,6 +9683,11 @@ func (h *Handlers) GetFile(w http.ResponseWriter, r *http.Request) {
 	}
 	objectKey = decodedObjectKey
 
+	if err := h.authorizeFileRequest(r, bucket, objectKey); err != nil {
+		WriteError(w, err)
+		return
+	}
+
 	ctx := r.Context()
 
 	// Сначала проверяем существование bucket
@@ -2310,22 +9724,625 @@ func (h *Handlers) GetFile(w http.ResponseWriter, r *http.Request) {
 
 	// Устанавливаем заголовки
 	w.Header().Set("Content-Type", objInfo.ContentType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", objInfo.Size))
 	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filepath.Base(objectKey)))
+	w.Header().Set("Cache-Control", CacheControlForBucket(bucket))
+	if objInfo.ETag != "" {
+		w.Header().Set("ETag", `"`+objInfo.ETag+`"`)
+	}
 
-	// Копируем содержимое файла в ответ
-	w.WriteHeader(http.StatusOK)
-	if _, err := io.Copy(w, obj); err != nil {
-		// Ошибка уже произошла, но мы не можем отправить ошибку, так как заголовки уже отправлены
-		return
+	// Видео отдается дольше общего таймаута записи сервера
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(fileStreamTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to extend write deadline for %s/%s: %v", bucket, objectKey, err)
 	}
+
+	// ServeContent обрабатывает Range (206, Accept-Ranges) и условные запросы (304 по ETag
+	// и Last-Modified), читает из MinIO только запрошенную часть
+	http.ServeContent(w, r, filepath.Base(objectKey), objInfo.LastModified, obj)
 }
 
+// fileStreamTimeout время на отдачу файла через GetFile
+const fileStreamTimeout = 10 * time.Minute
+
 // ========== Helper functions ==========
 
+// postPhotoURL возвращает URL первого изображения поста, по возможности большой копии
+func (h *Handlers) postPhotoURL(postID int64) string {
+	media, _ := h.db.GetPostMedia(postID)
+	for _, m := range media {
+		if m.MediaType != "image" {
+			continue
+		}
+		if m.Variants != nil && m.Variants.Large != "" {
+			return m.Variants.Large
+		}
+		return m.MediaURL
+	}
+	return ""
+}
+
+// readPostPhoto загружает и декодирует фото поста из хранилища, при ошибке возвращает nil
+func (h *Handlers) readPostPhoto(ctx context.Context, photoURL string) image.Image {
+	key, ok := ObjectKeyFromURL(photoURL, BucketPostMedia)
+	if !ok {
+		return nil
+	}
+	data, err := readObject(ctx, h.minioClient, BucketPostMedia, key)
+	if err != nil {
+		return nil
+	}
+	photo, _, _ := image.Decode(bytes.NewReader(data))
+	return photo
+}
+
+// scanUpload проверяет загруженный файл антивирусом и перематывает его в начало.
+// Зараженный файл помещается в карантин, а запрос отклоняется. Если сканер
+// недоступен, файл тоже не принимается.
+func (h *Handlers) scanUpload(ctx context.Context, kind string, userID int64, file io.ReadSeeker, header *multipart.FileHeader) error {
+	if h.scanner == nil {
+		return nil
+	}
+
+	result, err := h.scanner.Scan(ctx, file)
+	if _, seekErr := file.Seek(0, io.SeekStart); err == nil {
+		err = seekErr
+	}
+	if err != nil {
+		log.Printf("Failed to scan %s upload of user %d: %v", kind, userID, err)
+		metrics.Inc("upload_scans_total", "Antivirus scans of uploaded files by result", map[string]string{"result": "error"})
+		return NewServiceUnavailableError("Проверка файлов временно недоступна, попробуйте позже")
+	}
+	if !result.Infected {
+		metrics.Inc("upload_scans_total", "Antivirus scans of uploaded files by result", map[string]string{"result": "clean"})
+		return nil
+	}
+
+	metrics.Inc("upload_scans_total", "Antivirus scans of uploaded files by result", map[string]string{"result": "infected"})
+	log.Printf("Malware %q detected in %s upload of user %d", result.Signature, kind, userID)
+	if _, err := QuarantineObject(ctx, h.minioClient, kind, userID, file, header.Size, header.Header.Get("Content-Type"), result.Signature); err != nil {
+		log.Printf("Failed to quarantine %s upload of user %d: %v", kind, userID, err)
+	}
+	return NewUnprocessableError("Файл не прошел антивирусную проверку")
+}
+
+// checkPostAmountLimit проверяет целевую сумму по лимиту уровня доверия автора.
+// Сумма сверх лимита допускается по одобренному исключению: возвращается его ID,
+// чтобы после сохранения поста отметить исключение использованным.
+func (h *Handlers) checkPostAmountLimit(userID int64, amount float64) (*int64, error) {
+	limit, err := h.db.GetPostLimit(userID)
+	if err != nil {
+		return nil, err
+	}
+	if limit == nil || amount <= limit.MaxAmount {
+		return nil, nil
+	}
+
+	exceptionID, err := h.db.FindPostLimitException(userID, amount)
+	if err != nil {
+		return nil, err
+	}
+	if exceptionID != nil {
+		return exceptionID, nil
+	}
+	return nil, NewValidationError(
+		fmt.Sprintf("Максимальная сумма сбора для вашего уровня доверия - %s руб. Для большей суммы запросите исключение у администрации", formatAmount(limit.MaxAmount)),
+		map[string]interface{}{"field": "amount", "tier": limit.Tier, "max_amount": limit.MaxAmount},
+	)
+}
+
+// readImageUpload читает загруженное изображение и пережимает его без метаданных
+func readImageUpload(file io.Reader) ([]byte, string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", NewInternalError("Ошибка чтения файла")
+	}
+	clean, contentType, err := SanitizeImage(data)
+	if err != nil {
+		log.Printf("Failed to sanitize uploaded image: %v", err)
+		return nil, "", NewUnsupportedMediaError("Не удалось прочитать изображение")
+	}
+	return clean, contentType, nil
+}
+
+// uploadImageVariants создает уменьшенные копии загруженного изображения.
+// Ошибка не прерывает загрузку: без копий клиенты используют оригинал.
+func (h *Handlers) uploadImageVariants(ctx context.Context, bucket, objectKey string, data []byte) *ImageVariants {
+	keys, err := UploadImageVariants(ctx, h.minioClient, bucket, objectKey, data)
+	if err != nil {
+		log.Printf("Failed to create thumbnails for %s: %v", objectKey, err)
+		return nil
+	}
+
+	variants := &ImageVariants{}
+	for name, key := range keys {
+		variants.set(name, GetObjectURL(h.cfg.MinIOConfig, bucket, key))
+	}
+	return variants
+}
+
+// chatParticipant возвращает ID чата из пути и ID текущего пользователя,
+// если пользователь участвует в чате
+func (h *Handlers) chatParticipant(r *http.Request) (int64, int64, error) {
+	chatID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, 0, NewValidationError("Неверный ID чата", nil)
+	}
+
+	userID, err := GetUserIDFromContext(r.Context())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	chat, err := h.db.GetChatByID(chatID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if chat.HelperID != userID && chat.NeedyID != userID {
+		// Соавтор с правом chats отвечает в чатах поста от имени автора
+		permissions, err := h.db.GetCollaboratorPermissions(chat.PostID, userID)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !slices.Contains(permissions, CollaboratorChats) {
+			return 0, 0, NewForbiddenError("Нет доступа к чату")
+		}
+	}
+	return chatID, userID, nil
+}
+
+// postAccess проверяет, что пользователь - автор поста или принявший приглашение соавтор
+// с правом permission. collaborator = true, если действует соавтор.
+func (h *Handlers) postAccess(post *Post, userID int64, permission string) (collaborator bool, err error) {
+	if post.UserID == userID {
+		return false, nil
+	}
+	permissions, err := h.db.GetCollaboratorPermissions(post.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	if !slices.Contains(permissions, permission) {
+		return false, NewForbiddenError("Недостаточно прав")
+	}
+	return true, nil
+}
+
+// recordCollaboratorAction записывает в журнал изменение поста соавтором: действие
+// выполняет соавтор, пользователь действия - автор поста
+func (h *Handlers) recordCollaboratorAction(ctx context.Context, post *Post, action string, newValue interface{}) {
+	h.recordAdminAction(ctx, AdminActionRecord{
+		Action:       action,
+		TargetType:   "post",
+		TargetID:     post.ID,
+		TargetName:   post.Title,
+		TargetUserID: post.UserID,
+		NewValue:     newValue,
+	})
+}
+
+// parseOptionalID разбирает необязательный числовой ID из query параметра
+func parseOptionalID(value string) (*int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return nil, fmt.Errorf("invalid id %q", value)
+	}
+	return &id, nil
+}
+
 func getStringPtr(s string) *string {
 	if s == "" {
 		return nil
 	}
 	return &s
 }
+
+// getBackupStatus читает статус последнего резервного копирования.
+// Копия считается устаревшей, если с ее создания прошло больше двух интервалов.
+func (h *Handlers) getBackupStatus() BackupStatusResponse {
+	response := BackupStatusResponse{Enabled: h.cfg.Backup.Interval > 0}
+
+	last, err := backup.LastStatus(h.cfg.Backup.Dir)
+	if err != nil {
+		return response
+	}
+	response.LastBackup = last
+
+	if response.Enabled && last != nil {
+		response.Stale = time.Since(last.FinishedAt) > 2*h.cfg.Backup.Interval
+	}
+	return response
+}
+
+// sendOTP генерирует одноразовый код и отправляет его по SMS
+func (h *Handlers) sendOTP(r *http.Request, phone, purpose string) error {
+	last, err := h.db.GetActiveOTPCode(phone, purpose)
+	if err == nil && time.Since(last.CreatedAt) < h.cfg.OTP.ResendCooldown {
+		return NewTooManyRequestsError("Код уже отправлен, повторите попытку позже")
+	}
+
+	code, err := GenerateOTPCode(h.cfg.OTP.Length)
+	if err != nil {
+		return NewInternalError("Ошибка генерации кода")
+	}
+
+	if err := h.db.CreateOTPCode(phone, purpose, HashOTPCode(phone, code), time.Now().Add(h.cfg.OTP.TTL)); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Код подтверждения: %s. Никому не сообщайте его.", code)
+	if err := h.sms.Send(r.Context(), phone, message); err != nil {
+		log.Printf("Failed to send SMS: %v", err)
+		return NewInternalError("Не удалось отправить SMS")
+	}
+	return nil
+}
+
+// checkOTP проверяет одноразовый код и отмечает его использованным.
+// После исчерпания попыток код перестает приниматься.
+func (h *Handlers) checkOTP(phone, purpose, code string) error {
+	stored, err := h.db.GetActiveOTPCode(phone, purpose)
+	if err != nil {
+		return NewValidationError("Неверный или просроченный код", nil)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return NewValidationError("Неверный или просроченный код", nil)
+	}
+
+	// Попытка засчитывается до сравнения, иначе параллельные запросы обходят лимит
+	codeHash, claimed, err := h.db.ClaimOTPAttempt(stored.ID, h.cfg.OTP.MaxAttempts)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return NewTooManyRequestsError("Превышено число попыток, запросите новый код")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(HashOTPCode(phone, code)), []byte(codeHash)) != 1 {
+		return NewValidationError("Неверный или просроченный код", nil)
+	}
+
+	consumed, err := h.db.ConsumeOTPCode(stored.ID)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return NewValidationError("Неверный или просроченный код", nil)
+	}
+	return nil
+}
+
+// postMilestones доли собранной суммы, о достижении которых уведомляются доноры
+var postMilestones = []int{50, 100}
+
+// notifyPostMilestone рассылает донорам поста уведомление, если после
+// пожертвования собранная сумма пересекла очередной порог
+func (h *Handlers) notifyPostMilestone(post *Post, collected float64) {
+	before := post.Collected / post.Amount * 100
+	after := collected / post.Amount * 100
+
+	reached := 0
+	for _, milestone := range postMilestones {
+		if before < float64(milestone) && after >= float64(milestone) {
+			reached = milestone
+		}
+	}
+	if reached == 0 {
+		return
+	}
+
+	title := fmt.Sprintf("Собрано %d%%", reached)
+	body := fmt.Sprintf("Сбор «%s» достиг %d%% от цели. Спасибо за вашу помощь!", post.Title, reached)
+	if reached == 100 {
+		title = "Сбор завершен"
+		body = fmt.Sprintf("Сбор «%s» полностью собран. Спасибо за вашу помощь!", post.Title)
+	}
+
+	postID := post.ID
+	h.notifier.Enqueue(FanoutJob{
+		Type:       NotificationPostMilestone,
+		Title:      title,
+		Body:       body,
+		PostID:     &postID,
+		PostDonors: true,
+	})
+}
+
+// validateIntegrationTarget проверяет адрес интеграции: для webhook - абсолютный
+// http(s) URL публичного хоста, для Telegram - числовой chat_id или @username канала
+func (h *Handlers) validateIntegrationTarget(ctx context.Context, integrationType, target string) error {
+	switch integrationType {
+	case IntegrationWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return NewValidationError("Некорректный URL webhook", map[string]interface{}{"field": "target"})
+		}
+		if err := checkWebhookHost(ctx, u.Hostname()); err != nil {
+			return err
+		}
+	case IntegrationTelegram:
+		if h.cfg.Webhooks.TelegramBotToken == "" {
+			return NewValidationError("Оповещения в Telegram не настроены", nil)
+		}
+		if _, err := strconv.ParseInt(target, 10, 64); err != nil && !strings.HasPrefix(target, "@") {
+			return NewValidationError("Некорректный chat_id Telegram", map[string]interface{}{"field": "target"})
+		}
+	}
+	return nil
+}
+
+// sendAwayReply отправляет автоответ собеседника, если он в режиме "нет на месте".
+// В каждый чат автоответ отправляется один раз.
+func (h *Handlers) sendAwayReply(chatID, senderID int64) {
+	recipientID, text, ok, err := h.db.ClaimAwayReply(chatID, senderID)
+	if err != nil {
+		log.Printf("Failed to check away reply in chat %d: %v", chatID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	reply := &Message{ChatID: chatID, SenderID: recipientID, Text: &text}
+	if err := h.db.CreateMessage(reply); err != nil {
+		log.Printf("Failed to send away reply in chat %d: %v", chatID, err)
+		return
+	}
+	h.notifyNewMessage(reply)
+}
+
+// notifyNewMessage отправляет собеседнику push уведомление о новом сообщении.
+// В списке уведомлений оно не сохраняется: непрочитанные видны в чатах.
+func (h *Handlers) notifyNewMessage(message *Message) {
+	h.events.Publish(ChatTopic(message.ChatID), HubEvent{Name: "message", Data: message})
+
+	chat, err := h.db.GetChatByID(message.ChatID)
+	if err != nil {
+		log.Printf("Failed to get chat for push: %v", err)
+		return
+	}
+
+	// Сообщение автора или соавтора получает помогающий, сообщение помогающего - автор
+	// и соавторы с правом chats
+	recipientIDs := []int64{chat.HelperID}
+	if message.SenderID == chat.HelperID {
+		recipientIDs = []int64{chat.NeedyID}
+		collaborators, err := h.db.GetCollaboratorIDs(chat.PostID, CollaboratorChats)
+		if err != nil {
+			log.Printf("Failed to get collaborators of post %d: %v", chat.PostID, err)
+		}
+		recipientIDs = append(recipientIDs, collaborators...)
+	}
+
+	title := "Новое сообщение"
+	if sender, err := h.db.GetUserByID(message.SenderID); err == nil {
+		title = strings.TrimSpace(sender.FirstName + " " + sender.LastName)
+	}
+	body := "Вложение"
+	if message.Text != nil {
+		body = *message.Text
+		if runes := []rune(body); len(runes) > 100 {
+			body = string(runes[:100]) + "…"
+		}
+	}
+
+	h.notifier.Enqueue(FanoutJob{
+		Type:     NotificationNewMessage,
+		Title:    title,
+		Body:     body,
+		PostID:   &chat.PostID,
+		UserIDs:  recipientIDs,
+		PushOnly: true,
+		Data: map[string]string{
+			"chat_id":    strconv.FormatInt(chat.ID, 10),
+			"message_id": strconv.FormatInt(message.ID, 10),
+		},
+	})
+}
+
+// applyAgeCheck заполняет результат проверки возраста для проверяющего
+func (h *Handlers) applyAgeCheck(v *Verification) {
+	v.Age = AgeAt(v.BirthDate, time.Now())
+	v.MeetsAgeRequirement = v.Age >= h.cfg.Age.MinPostingAge
+}
+
+// recordLogin сохраняет попытку входа в историю (ошибка записи не мешает входу)
+func (h *Handlers) recordLogin(r *http.Request, userID int64, success bool) {
+	if err := h.db.RecordLoginEvent(userID, success, getStringPtr(getClientIP(r, h.cfg.TrustedProxies)), getStringPtr(r.UserAgent())); err != nil {
+		log.Printf("Failed to record login event: %v", err)
+	}
+}
+
+// hasPermission проверяет право текущего пользователя внутри обработчика
+func (h *Handlers) hasPermission(ctx context.Context, permission string) bool {
+	role, err := GetUserRoleFromContext(ctx)
+	if err != nil {
+		return false
+	}
+	return h.perms.Has(role, permission)
+}
+
+// minAdminReasonLength минимальная длина причины разрушительного действия администратора
+const minAdminReasonLength = 5
+
+// adminAction разрушительное действие администратора или модератора над пользователем
+// или его контентом. Выполняется только через performAdminAction.
+type adminAction struct {
+	Action       string
+	TargetType   string
+	TargetID     int64
+	TargetUserID int64
+	PostID       *int64
+	Reason       string
+	// Значения до и после изменения для журнала; apply может заполнить OldValue
+	OldValue interface{}
+	NewValue interface{}
+	// Уведомление пользователю; причина добавляется к Body
+	Notification string
+	Title        string
+	Body         string
+}
+
+// performAdminAction выполняет действие, только если указана причина, записывает его
+// в журнал admin_actions и отправляет пользователю уведомление с причиной
+func (h *Handlers) performAdminAction(ctx context.Context, action *adminAction, apply func() error) error {
+	reason := strings.TrimSpace(action.Reason)
+	if len([]rune(reason)) < minAdminReasonLength {
+		return NewValidationError("Укажите причину действия", map[string]interface{}{"field": "reason"})
+	}
+
+	if _, err := GetUserIDFromContext(ctx); err != nil {
+		return err
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+
+	h.recordAdminAction(ctx, AdminActionRecord{
+		Action:       action.Action,
+		TargetType:   action.TargetType,
+		TargetID:     action.TargetID,
+		TargetUserID: action.TargetUserID,
+		Reason:       reason,
+		OldValue:     action.OldValue,
+		NewValue:     action.NewValue,
+	})
+
+	h.notifier.Enqueue(FanoutJob{
+		Type:    action.Notification,
+		Title:   action.Title,
+		Body:    fmt.Sprintf("%s Причина: %s", action.Body, reason),
+		PostID:  action.PostID,
+		UserIDs: []int64{action.TargetUserID},
+	})
+	return nil
+}
+
+// recordAdminAction записывает действие текущего пользователя в журнал admin_actions.
+// Действие уже выполнено, поэтому ошибка записи только логируется.
+func (h *Handlers) recordAdminAction(ctx context.Context, rec AdminActionRecord) {
+	actorID, err := GetUserIDFromContext(ctx)
+	if err != nil {
+		log.Printf("Failed to record admin action %s: %v", rec.Action, err)
+		return
+	}
+	rec.ActorID = actorID
+	if err := h.db.RecordAdminAction(rec); err != nil {
+		log.Printf("Failed to record admin action %s: %v", rec.Action, err)
+	}
+}
+
+// completeLogin выдает токены пользователю, прошедшему проверку, и отвечает LoginResponse
+func (h *Handlers) completeLogin(w http.ResponseWriter, r *http.Request, user *User) {
+	token, err := GenerateToken(h.cfg, user.ID, user.Role, false)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка генерации токена"))
+		return
+	}
+
+	refreshToken, err := h.issueRefreshToken(r, user.ID)
+	if err != nil {
+		WriteError(w, NewInternalError("Ошибка генерации токена"))
+		return
+	}
+	h.recordLogin(r, user.ID, true)
+
+	// Очищаем пароль из ответа
+	user.PasswordHash = ""
+	response := LoginResponse{
+		UserID:       user.ID,
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         user,
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// startOAuth сохраняет state и возвращает ссылку на страницу входа провайдера.
+// userID задан при привязке провайдера к аккаунту.
+func (h *Handlers) startOAuth(w http.ResponseWriter, providerName string, userID *int64) {
+	provider, err := h.oauth.Get(providerName)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	state, codeVerifier, err := GenerateOAuthState()
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := h.db.CreateOAuthState(state, provider.Name, userID, codeVerifier, time.Now().Add(oauthStateTTL)); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, OAuthAuthorizeResponse{URL: h.oauth.AuthorizeURL(provider, state, codeVerifier)})
+}
+
+// issueRefreshToken создает и сохраняет новый refresh токен пользователя
+func (h *Handlers) issueRefreshToken(r *http.Request, userID int64) (string, error) {
+	token, err := GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(h.cfg.JWTRefreshExpiry)
+	if _, err := h.db.CreateRefreshToken(userID, sha256Hex(token), expiresAt, getStringPtr(r.UserAgent()), getStringPtr(getClientIP(r, h.cfg.TrustedProxies))); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// getClientIP возвращает IP клиента. X-Forwarded-For и X-Real-IP учитываются, только если
+// запрос пришел от доверенного прокси: иначе клиент подставил бы в них любой адрес.
+func getClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host, trustedProxies) {
+		return host
+	}
+
+	// Адреса добавляются в конец цепочки, клиент - первый адрес справа, не принадлежащий
+	// доверенным прокси. Левее него значения мог подставить сам клиент.
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		chain := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(chain) - 1; i >= 0; i-- {
+			addr := strings.TrimSpace(chain[i])
+			if _, err := netip.ParseAddr(addr); err != nil {
+				break
+			}
+			host = addr
+			if !isTrustedProxy(addr, trustedProxies) {
+				break
+			}
+		}
+		return host
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		if _, err := netip.ParseAddr(realIP); err == nil {
+			return realIP
+		}
+	}
+	return host
+}
+
+// isTrustedProxy проверяет, что адрес входит в TRUSTED_PROXIES
+func isTrustedProxy(host string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
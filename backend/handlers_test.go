@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetClientIP(t *testing.T) {
+	trusted := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "bad"})
+
+	tests := []struct {
+		name, remoteAddr, forwarded, realIP, want string
+	}{
+		{"без прокси", "203.0.113.5:5000", "", "", "203.0.113.5"},
+		{"заголовки от клиента", "203.0.113.5:5000", "1.2.3.4", "5.6.7.8", "203.0.113.5"},
+		{"X-Forwarded-For от прокси", "10.0.0.2:5000", "198.51.100.7", "", "198.51.100.7"},
+		{"подставленный клиентом адрес", "10.0.0.2:5000", "1.2.3.4, 198.51.100.7, 10.0.0.3", "", "198.51.100.7"},
+		{"прокси по адресу", "192.168.1.1:5000", "198.51.100.7", "", "198.51.100.7"},
+		{"X-Real-IP от прокси", "10.0.0.2:5000", "", "198.51.100.7", "198.51.100.7"},
+		{"некорректный X-Real-IP", "10.0.0.2:5000", "", "unknown", "10.0.0.2"},
+		{"IPv6", "[2001:db8::1]:5000", "1.2.3.4", "", "2001:db8::1"},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		if tc.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		if tc.realIP != "" {
+			r.Header.Set("X-Real-IP", tc.realIP)
+		}
+		if got := getClientIP(r, trusted); got != tc.want {
+			t.Errorf("%s: getClientIP = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package httpclient HTTP клиент для внешних интеграций (SMS, OCR, push, вебхуки):
+// таймаут попытки, повторы с экспоненциальной паузой и случайным разбросом,
+// circuit breaker, метрики и логирование по назначению.
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrCircuitOpen возвращается без отправки запроса, если breaker назначения разомкнут
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Breaker circuit breaker назначения. Allow возвращает ошибку, если вызовы не выполняются.
+type Breaker interface {
+	Allow() error
+	Success()
+	Failure()
+	Release()
+}
+
+// Metrics счетчики, в которые клиент пишет результаты запросов
+type Metrics interface {
+	Inc(name, help string, labels map[string]string)
+	Add(name, help string, labels map[string]string, value float64)
+}
+
+// Config настройки клиента
+type Config struct {
+	// Таймаут одной попытки
+	Timeout time.Duration
+	// Сколько раз повторить запрос после первой попытки, 0 - без повторов
+	MaxRetries int
+	// Пауза перед первым повтором, дальше удваивается до RetryMaxDelay
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// Транспорт запросов, nil - http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Client выполняет запросы к одному назначению. Повторяются сетевые ошибки и ответы
+// 429, 502, 503, 504, если тело запроса можно отправить заново.
+type Client struct {
+	destination string
+	cfg         Config
+	client      *http.Client
+	breaker     Breaker
+	metrics     Metrics
+}
+
+// New создает клиент назначения destination. breaker и metrics могут быть nil.
+func New(destination string, cfg Config, breaker Breaker, metrics Metrics) *Client {
+	return &Client{
+		destination: destination,
+		cfg:         cfg,
+		client:      &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		breaker:     breaker,
+		metrics:     metrics,
+	}
+}
+
+// Destination возвращает имя назначения
+func (c *Client) Destination() string {
+	return c.destination
+}
+
+// Do выполняет запрос с повторами. Как и http.Client.Do, при успехе вызывающий
+// закрывает тело ответа.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	startedAt := time.Now()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.send(req)
+		if attempt >= c.cfg.MaxRetries || errors.Is(err, ErrCircuitOpen) || !retryable(req, resp, err) {
+			c.record(req, resp, err, attempt, startedAt)
+			return resp, err
+		}
+
+		delay := c.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		log.Printf("HTTP %s %s %s: attempt %d failed: %v, retrying in %s",
+			c.destination, req.Method, req.URL.Host, attempt+1, withoutURL(err), delay.Round(time.Millisecond))
+		c.inc("http_client_retries_total", "Outbound HTTP request retries by destination", map[string]string{"destination": c.destination})
+
+		select {
+		case <-req.Context().Done():
+			c.record(req, nil, req.Context().Err(), attempt, startedAt)
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// send выполняет одну попытку через breaker
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.breaker == nil {
+		return c.client.Do(req)
+	}
+	if err := c.breaker.Allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.destination, ErrCircuitOpen)
+	}
+
+	resp, err := c.client.Do(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// Отмена запроса вызывающим не говорит о недоступности сервиса
+		c.breaker.Release()
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		c.breaker.Failure()
+	default:
+		c.breaker.Success()
+	}
+	return resp, err
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff пауза перед повтором attempt+1: случайная в пределах RetryBaseDelay * 2^attempt,
+// но не больше RetryMaxDelay. Retry-After в ответе 429 и 503 учитывается с тем же ограничением.
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, c.cfg.RetryMaxDelay)
+		}
+	}
+
+	ceiling := c.cfg.RetryBaseDelay
+	for i := 0; i < attempt && ceiling < c.cfg.RetryMaxDelay; i++ {
+		ceiling *= 2
+	}
+	ceiling = min(ceiling, c.cfg.RetryMaxDelay)
+	if ceiling <= 0 {
+		return 0
+	}
+	return ceiling/2 + time.Duration(rand.Int63n(int64(ceiling/2)+1))
+}
+
+// record пишет метрики итогового результата и логирует неудачу
+func (c *Client) record(req *http.Request, resp *http.Response, err error, retries int, startedAt time.Time) {
+	result := "error"
+	if err == nil {
+		result = fmt.Sprintf("%dxx", resp.StatusCode/100)
+	}
+	labels := map[string]string{"destination": c.destination, "result": result}
+	c.inc("http_client_requests_total", "Outbound HTTP requests by destination and result", labels)
+	if c.metrics != nil {
+		c.metrics.Add("http_client_request_seconds_total", "Time spent in outbound HTTP requests including retries",
+			map[string]string{"destination": c.destination}, time.Since(startedAt).Seconds())
+	}
+
+	if err != nil {
+		log.Printf("HTTP %s %s %s failed after %d attempts: %v", c.destination, req.Method, req.URL.Host, retries+1, withoutURL(err))
+	} else if resp.StatusCode >= http.StatusInternalServerError {
+		log.Printf("HTTP %s %s %s responded with status %d after %d attempts", c.destination, req.Method, req.URL.Host, resp.StatusCode, retries+1)
+	}
+}
+
+// withoutURL убирает из ошибки http.Client адрес запроса: в нем могут быть токены
+// (например, Telegram Bot API), поэтому в лог попадает только хост
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func (c *Client) inc(name, help string, labels map[string]string) {
+	if c.metrics != nil {
+		c.metrics.Inc(name, help, labels)
+	}
+}
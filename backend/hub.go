@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// hubBufferSize сколько событий ждут отправки одному подписчику. Если клиент
+// не успевает их читать, новые события для него пропускаются: события несут
+// текущее состояние, поэтому следующее событие исправит пропущенное.
+const hubBufferSize = 16
+
+// sseKeepAlive интервал комментариев, которые не дают прокси закрыть тихое соединение
+const sseKeepAlive = 25 * time.Second
+
+// HubEvent событие канала: имя события SSE и данные в JSON
+type HubEvent struct {
+	Name string
+	Data interface{}
+}
+
+// EventHub рассылает события подписчикам каналов (пост, чат) в памяти процесса.
+// Подписчики - открытые соединения SSE, поэтому события видят только клиенты,
+// подключенные к тому же экземпляру backend.
+type EventHub struct {
+	mu     sync.Mutex
+	topics map[string]map[chan HubEvent]struct{}
+	closed bool
+}
+
+func NewEventHub() *EventHub {
+	hub := &EventHub{topics: make(map[string]map[chan HubEvent]struct{})}
+	metrics.GaugeFunc("event_hub_subscribers", "Open live event connections", func() []MetricSample {
+		return []MetricSample{{Value: float64(hub.subscribers())}}
+	})
+	return hub
+}
+
+// PostTopic канал изменений собранной суммы поста
+func PostTopic(postID int64) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// ChatTopic канал событий чата
+func ChatTopic(chatID int64) string {
+	return fmt.Sprintf("chat:%d", chatID)
+}
+
+// Subscribe подписывает на канал topic. Канал событий закрывается после отписки
+// или при остановке сервера.
+func (h *EventHub) Subscribe(topic string) (<-chan HubEvent, func()) {
+	ch := make(chan HubEvent, hubBufferSize)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[chan HubEvent]struct{})
+	}
+	h.topics[topic][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.topics[topic][ch]; !ok {
+			return
+		}
+		delete(h.topics[topic], ch)
+		if len(h.topics[topic]) == 0 {
+			delete(h.topics, topic)
+		}
+		close(ch)
+	}
+}
+
+// Publish отправляет событие подписчикам канала topic без ожидания
+func (h *EventHub) Publish(topic string, event HubEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.topics[topic] {
+		select {
+		case ch <- event:
+		default:
+			metrics.Inc("event_hub_dropped_total", "Live events skipped for slow subscribers", map[string]string{"event": event.Name})
+		}
+	}
+}
+
+// Close отписывает всех подписчиков, чтобы соединения SSE завершились до остановки сервера
+func (h *EventHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for topic, subscribers := range h.topics {
+		for ch := range subscribers {
+			close(ch)
+		}
+		delete(h.topics, topic)
+	}
+}
+
+func (h *EventHub) subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	count := 0
+	for _, subscribers := range h.topics {
+		count += len(subscribers)
+	}
+	return count
+}
+
+// ServeSSE передает события канала topic клиенту как text/event-stream, пока клиент
+// не отключится. initial отправляется сразу после подписки, чтобы клиент получил
+// текущее состояние без отдельного запроса.
+func (h *EventHub) ServeSSE(w http.ResponseWriter, r *http.Request, topic string, initial *HubEvent) {
+	rc := http.NewResponseController(w)
+	// WriteTimeout сервера рассчитан на обычные запросы, поток открыт долго
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		WriteError(w, NewInternalError("Потоковая передача не поддерживается"))
+		return
+	}
+
+	events, unsubscribe := h.Subscribe(topic)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Отключает буферизацию ответа в nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if initial != nil {
+		if err := writeSSE(w, *initial); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event HubEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, data)
+	return err
+}
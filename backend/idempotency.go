@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader заголовок с ключом идемпотентности, значение выбирает клиент
+	IdempotencyKeyHeader = "Idempotency-Key"
+	idempotencyKeyMaxLen = 255
+	// Запрос, не завершившийся за это время (сервер перезапущен во время обработки),
+	// считается прерванным, и ключ можно использовать снова
+	idempotencyLockTimeout = time.Minute
+	// Память для разбора multipart формы, остальное во временных файлах
+	idempotencyFormMemory = 32 << 20
+)
+
+// Idempotency защищает создающие запросы от повторов: мобильные клиенты повторяют
+// запрос при обрыве связи, не зная, дошел ли первый. Ответ на запрос с заголовком
+// Idempotency-Key сохраняется, и повтор с тем же ключом получает его без повторного
+// выполнения. Ключи хранятся ttl и принадлежат пользователю.
+type Idempotency struct {
+	db  *DB
+	ttl time.Duration
+}
+
+func NewIdempotency(db *DB, ttl time.Duration) *Idempotency {
+	return &Idempotency{db: db, ttl: ttl}
+}
+
+// Wrap оборачивает обработчик, подключается после аутентификации. Запросы без
+// заголовка выполняются как обычно.
+func (i *Idempotency) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > idempotencyKeyMaxLen {
+			WriteError(w, NewValidationError(fmt.Sprintf("Ключ идемпотентности длиннее %d символов", idempotencyKeyMaxLen),
+				map[string]interface{}{"header": IdempotencyKeyHeader}))
+			return
+		}
+		userID, err := GetUserIDFromContext(r.Context())
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		hash, err := requestFingerprint(r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		now := time.Now()
+		record, claimed, err := i.db.ClaimIdempotencyKey(userID, key, hash, now.Add(-i.ttl), now.Add(-idempotencyLockTimeout))
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if !claimed {
+			switch {
+			case record.RequestHash != hash:
+				WriteError(w, NewUnprocessableError("Ключ идемпотентности уже использован для другого запроса"))
+			case record.StatusCode == nil:
+				WriteError(w, NewConflictError("Запрос с этим ключом идемпотентности еще выполняется"))
+			default:
+				metrics.Inc("idempotent_replays_total", "Responses replayed for a repeated Idempotency-Key", map[string]string{"path": r.URL.Path})
+				if record.ContentType != "" {
+					w.Header().Set("Content-Type", record.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(*record.StatusCode)
+				w.Write(record.Body)
+			}
+			return
+		}
+
+		// Ключ освобождается, если ответ не сохранен: ошибка сервера или паника
+		// обработчика. Клиент может повторить запрос с тем же ключом.
+		saved := false
+		defer func() {
+			if !saved {
+				if err := i.db.ReleaseIdempotencyKey(userID, key); err != nil {
+					log.Printf("Failed to release idempotency key: %v", err)
+				}
+			}
+		}()
+
+		rec := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		next(rec, r)
+
+		// Ответ 429 зависит от момента запроса, а не от его содержимого
+		if rec.status < http.StatusInternalServerError && rec.status != http.StatusTooManyRequests {
+			err := i.db.CompleteIdempotencyKey(userID, key, rec.status, rec.header.Get("Content-Type"), rec.body.Bytes())
+			if err != nil {
+				log.Printf("Failed to save idempotent response: %v", err)
+			} else {
+				saved = true
+			}
+		}
+
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	}
+}
+
+// requestFingerprint вычисляет хеш запроса для сравнения повторов. Для multipart
+// формы хешируются поля и содержимое файлов, а не тело: клиент, собирающий запрос
+// заново, меняет boundary. Разобранная форма остается в запросе для обработчика.
+func requestFingerprint(r *http.Request) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.Path)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", NewValidationError("Не удалось прочитать тело запроса", nil)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	if err := ParseMultipartForm(r, idempotencyFormMemory); err != nil {
+		return "", err
+	}
+	for _, name := range sortedFormKeys(r.MultipartForm.Value) {
+		for _, value := range r.MultipartForm.Value[name] {
+			fmt.Fprintf(h, "%q=%q\n", name, value)
+		}
+	}
+	for _, name := range sortedFormKeys(r.MultipartForm.File) {
+		for _, fh := range r.MultipartForm.File[name] {
+			file, err := fh.Open()
+			if err != nil {
+				return "", NewValidationError("Не удалось прочитать файл", map[string]interface{}{"field": name})
+			}
+			fmt.Fprintf(h, "%q:%q:", name, fh.Filename)
+			_, err = io.Copy(h, file)
+			file.Close()
+			if err != nil {
+				return "", NewValidationError("Не удалось прочитать файл", map[string]interface{}{"field": name})
+			}
+			h.Write([]byte("\n"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sortedFormKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
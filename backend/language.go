@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Языки, которые определяет DetectLanguage
+const (
+	LanguageRussian    = "ru"
+	LanguageUkrainian  = "uk"
+	LanguageBelarusian = "be"
+	LanguageKazakh     = "kk"
+	LanguageEnglish    = "en"
+	LanguageGerman     = "de"
+	LanguageFrench     = "fr"
+	LanguageSpanish    = "es"
+)
+
+// SupportedLanguages языки, по которым можно фильтровать ленту
+var SupportedLanguages = []string{
+	LanguageRussian, LanguageUkrainian, LanguageBelarusian, LanguageKazakh,
+	LanguageEnglish, LanguageGerman, LanguageFrench, LanguageSpanish,
+}
+
+// minLanguageLetters текст короче этого числа букв считается неопределенным
+const minLanguageLetters = 12
+
+// Буквы, которые встречаются только в одном из кириллических языков
+var cyrillicMarkers = []struct {
+	language string
+	letters  string
+}{
+	{LanguageKazakh, "әғқңөұүһ"},
+	{LanguageUkrainian, "їєґ"},
+	{LanguageBelarusian, "ў"},
+}
+
+// Частые служебные слова латинских языков
+var latinStopwords = map[string][]string{
+	LanguageEnglish: {"the", "and", "for", "with", "to", "of", "is", "my", "help", "we", "you", "this", "please"},
+	LanguageGerman:  {"der", "die", "das", "und", "ist", "mit", "für", "ich", "nicht", "wir", "bitte", "ein", "eine"},
+	LanguageFrench:  {"le", "la", "les", "et", "est", "pour", "avec", "une", "des", "nous", "pas", "aide", "merci"},
+	LanguageSpanish: {"el", "los", "las", "y", "es", "para", "con", "una", "por", "que", "ayuda", "gracias", "del"},
+}
+
+// DetectLanguage определяет язык текста по алфавиту и характерным буквам и словам.
+// Возвращает код ISO 639-1 или пустую строку, если язык определить не удалось.
+func DetectLanguage(text string) string {
+	var cyrillic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	if cyrillic+latin < minLanguageLetters {
+		return ""
+	}
+
+	lower := strings.ToLower(text)
+	if cyrillic >= latin {
+		for _, marker := range cyrillicMarkers {
+			if strings.ContainsAny(lower, marker.letters) {
+				return marker.language
+			}
+		}
+		// "і" есть и в украинском, и в белорусском, и в казахском; без других маркеров считаем украинским
+		if strings.ContainsRune(lower, 'і') {
+			return LanguageUkrainian
+		}
+		return LanguageRussian
+	}
+
+	return detectLatinLanguage(lower)
+}
+
+// detectLatinLanguage выбирает латинский язык с наибольшим числом служебных слов
+func detectLatinLanguage(lower string) string {
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	counts := make(map[string]int)
+	for _, word := range words {
+		for language, stopwords := range latinStopwords {
+			for _, stopword := range stopwords {
+				if word == stopword {
+					counts[language]++
+				}
+			}
+		}
+	}
+
+	// Порядок обхода фиксирован, чтобы при равенстве результат не зависел от map
+	best, bestCount := LanguageEnglish, 0
+	for _, language := range []string{LanguageEnglish, LanguageGerman, LanguageFrench, LanguageSpanish} {
+		if counts[language] > bestCount {
+			best, bestCount = language, counts[language]
+		}
+	}
+	return best
+}
+
+// IsSupportedLanguage проверяет код языка фильтра ленты
+func IsSupportedLanguage(language string) bool {
+	for _, l := range SupportedLanguages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// ValidateLineItemsTotal проверяет, что статьи расходов в сумме дают целевую сумму поста.
+// Суммы сравниваются в копейках.
+func ValidateLineItemsTotal(total, amount float64) error {
+	if math.Round(total*100) == math.Round(amount*100) {
+		return nil
+	}
+	return NewValidationError(
+		fmt.Sprintf("Сумма статей расходов (%.2f руб.) должна совпадать с целевой суммой (%.2f руб.)", total, amount),
+		map[string]interface{}{"field": "line_items", "items_total": total, "amount": amount},
+	)
+}
+
+// LineItemsTotal суммирует статьи расходов из запроса
+func LineItemsTotal(items []LineItemRequest) float64 {
+	total := 0.0
+	for _, item := range items {
+		total += item.Amount
+	}
+	return total
+}
+
+// AllocateLineItems распределяет собранную сумму по статьям расходов по порядку:
+// следующая статья начинает заполняться, когда закрыта предыдущая
+func AllocateLineItems(items []PostLineItem, collected float64) {
+	remaining := collected
+	for i := range items {
+		items[i].Collected = math.Max(0, math.Min(items[i].Amount, remaining))
+		remaining -= items[i].Collected
+	}
+}
@@ -0,0 +1,96 @@
+// Нагрузочный сценарий k6 для ленты, чатов и пожертвований.
+// Пороги совпадают с loadtest/budgets.json.
+//
+//   k6 run -e BASE_URL=http://localhost:8080 -e PHONE=+79990000000 -e PASSWORD=secret \
+//          -e POST_ID=1 -e CHAT_ID=1 loadtest/k6/scenarios.js
+import http from 'k6/http';
+import { check } from 'k6';
+
+const BASE_URL = (__ENV.BASE_URL || 'http://localhost:8080') + '/api/v1';
+const RATE = parseInt(__ENV.RATE || '10');
+const DURATION = __ENV.DURATION || '30s';
+const POST_ID = __ENV.POST_ID;
+const CHAT_ID = __ENV.CHAT_ID;
+const WRITE = __ENV.WRITE === 'true';
+
+const budgets = JSON.parse(open('../budgets.json'));
+
+function constantRate(exec) {
+  return {
+    executor: 'constant-arrival-rate',
+    rate: RATE,
+    timeUnit: '1s',
+    duration: DURATION,
+    preAllocatedVUs: RATE * 2,
+    maxVUs: RATE * 10,
+    exec: exec,
+    tags: { scenario: exec },
+  };
+}
+
+const scenarios = { feed: constantRate('feed') };
+if (POST_ID) {
+  scenarios.post = constantRate('post');
+  scenarios.donations = constantRate('donations');
+}
+if (__ENV.TOKEN || __ENV.PHONE) {
+  scenarios.chats = constantRate('chats');
+  if (CHAT_ID) {
+    scenarios.messages = constantRate('messages');
+    if (WRITE) {
+      scenarios.send_message = constantRate('send_message');
+    }
+  }
+}
+
+const thresholds = {};
+for (const name of Object.keys(scenarios)) {
+  const budget = budgets[name];
+  thresholds[`http_req_duration{scenario:${name}}`] = [`p(95)<${budget.p95_ms}`, `p(99)<${budget.p99_ms}`];
+  thresholds[`http_req_failed{scenario:${name}}`] = [`rate<=${budget.max_error_rate}`];
+}
+
+export const options = { scenarios, thresholds };
+
+export function setup() {
+  if (__ENV.TOKEN) {
+    return { token: __ENV.TOKEN };
+  }
+  if (!__ENV.PHONE) {
+    return { token: '' };
+  }
+  const res = http.post(`${BASE_URL}/auth/login`, JSON.stringify({ phone: __ENV.PHONE, password: __ENV.PASSWORD }), {
+    headers: { 'Content-Type': 'application/json' },
+  });
+  check(res, { 'login ok': (r) => r.status === 200 });
+  return { token: res.json('token') };
+}
+
+function auth(data) {
+  return { headers: { Authorization: `Bearer ${data.token}` } };
+}
+
+export function feed() {
+  check(http.get(`${BASE_URL}/posts?status=active&page=1&limit=20`), { 'status 200': (r) => r.status === 200 });
+}
+
+export function post() {
+  check(http.get(`${BASE_URL}/posts/${POST_ID}`), { 'status 200': (r) => r.status === 200 });
+}
+
+export function donations() {
+  check(http.get(`${BASE_URL}/donations?post_id=${POST_ID}&page=1&limit=20`), { 'status 200': (r) => r.status === 200 });
+}
+
+export function chats(data) {
+  check(http.get(`${BASE_URL}/chats`, auth(data)), { 'status 200': (r) => r.status === 200 });
+}
+
+export function messages(data) {
+  check(http.get(`${BASE_URL}/chats/${CHAT_ID}/messages?page=1&limit=50`, auth(data)), { 'status 200': (r) => r.status === 200 });
+}
+
+export function send_message(data) {
+  const res = http.post(`${BASE_URL}/chats/${CHAT_ID}/messages`, { text: `loadtest ${Date.now()}` }, auth(data));
+  check(res, { 'status 2xx': (r) => r.status >= 200 && r.status < 300 });
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SendMail отправляет текстовое письмо одному получателю. Авторизация выполняется,
+// если задан Username; net/smtp передает пароль только после STARTTLS.
+func SendMail(cfg SMTPConfig, to, subject, body string) error {
+	if cfg.Host == "" {
+		return fmt.Errorf("SMTP_HOST is not configured")
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + cfg.From + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	if err := smtp.SendMail(addr, auth, cfg.From, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
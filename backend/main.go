@@ -2,18 +2,20 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
-	httpSwagger "github.com/swaggo/http-swagger"
 
+	"tmphackbackend/backup"
 	_ "tmphackbackend/docs" // swagger docs
+	"tmphackbackend/httpclient"
 )
 
 // @title           Благотворительное приложение API
@@ -36,6 +38,9 @@ import (
 // @description Type "Bearer" followed by a space and JWT token.
 
 func main() {
+	// Время в ответах API сериализуется в UTC независимо от часового пояса контейнера
+	time.Local = time.UTC
+
 	// Загружаем переменные окружения
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
@@ -44,8 +49,36 @@ func main() {
 	// Инициализируем конфигурацию
 	cfg := NewConfig()
 
+	if err := LoadDocumentCountries(DocumentCountries); err != nil {
+		log.Fatalf("Failed to load verification document types: %v", err)
+	}
+
+	// Circuit breakers для внешних зависимостей
+	dbBreaker := NewCircuitBreaker("postgres", cfg.Breaker.FailureThreshold, cfg.Breaker.Cooldown)
+	minioBreaker := NewCircuitBreaker("minio", cfg.Breaker.FailureThreshold, cfg.Breaker.Cooldown)
+	registerBreakerMetrics(metrics)
+
+	// Демонстрационный режим: PostgreSQL и хранилище файлов запускает сам сервер
+	if cfg.Standalone.Database == DatabaseModeEmbedded {
+		embeddedDB, dsn, err := StartEmbeddedDatabase(cfg.Standalone)
+		if err != nil {
+			log.Fatalf("Failed to start embedded database: %v", err)
+		}
+		defer embeddedDB.Stop()
+		cfg.DatabaseURL, cfg.DatabaseFailover = dsn, nil
+	}
+	if cfg.Standalone.Storage == StorageBackendFilesystem {
+		storageDir := filepath.Join(cfg.Standalone.DataDir, "storage")
+		storage, err := StartFileStorage(storageDir, cfg.MinIOConfig.Endpoint, cfg.MinIOConfig.AccessKeyID, cfg.MinIOConfig.SecretAccessKey)
+		if err != nil {
+			log.Fatalf("Failed to start file storage: %v", err)
+		}
+		defer storage.Close()
+		log.Printf("File storage serving %s on %s", storageDir, cfg.MinIOConfig.Endpoint)
+	}
+
 	// Подключаемся к PostgreSQL
-	db, err := NewDB(cfg.DatabaseURL)
+	db, err := NewDB(cfg, dbBreaker)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -57,7 +90,7 @@ func main() {
 	}
 
 	// Инициализируем MinIO клиент
-	minioClient, err := NewMinIOClient(cfg.MinIOConfig)
+	minioClient, err := NewMinIOClient(cfg.MinIOConfig, minioBreaker)
 	if err != nil {
 		log.Fatalf("Failed to initialize MinIO client: %v", err)
 	}
@@ -68,86 +101,247 @@ func main() {
 		log.Fatalf("Failed to initialize MinIO buckets: %v", err)
 	}
 
-	// Инициализируем роутер
-	router := mux.NewRouter()
+	// Настраиваем правила хранения объектов (не критично для запуска)
+	if err := ConfigureBucketLifecycles(ctx, minioClient, cfg.StorageLifecycle); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Самотестирование: проверяем права на запись в базу и buckets до первых запросов пользователей
+	selfTest := NewSelfTest(db, minioClient)
+	if report := selfTest.Run(ctx); report.Status != "ok" {
+		log.Printf("Warning: startup self-test failed, see GET /api/v1/admin/selftest")
+	} else {
+		log.Printf("Startup self-test passed")
+	}
 
-	// Применяем middleware (кроме CORS - он будет применен на уровне HTTP handler)
-	router.Use(RecoverMiddleware)
-	router.Use(LoggingMiddleware)
+	// Инициализируем провайдера SMS
+	smsProvider, err := NewSMSProvider(cfg.SMS, newOutboundClient(cfg, "sms", 10*time.Second))
+	if err != nil {
+		log.Fatalf("Failed to initialize SMS provider: %v", err)
+	}
+
+	// Платежный шлюз для оплаты пожертвований картой (не обязателен)
+	payments, err := NewPaymentProvider(cfg.Payments, newOutboundClient(cfg, "payments", 15*time.Second))
+	if err != nil {
+		log.Fatalf("Failed to initialize payment provider: %v", err)
+	}
+
+	// Запускаем фоновую рассылку уведомлений
+	notifier := NewNotifier(db, cfg.Notifications)
+	notifier.AddSender(NewSMSNotificationSender(db, smsProvider))
+	if cfg.Push.FCMCredentialsFile != "" {
+		fcm, err := NewFCMSender(db, cfg.Push.FCMCredentialsFile, newOutboundClient(cfg, "fcm", 10*time.Second))
+		if err != nil {
+			log.Fatalf("Failed to initialize FCM: %v", err)
+		}
+		notifier.AddSender(fcm)
+	}
+	notifier.Start(context.Background())
+
+	// Запускаем фоновые задачи
+	scheduler := NewScheduler()
+	scheduler.Add(Job{
+		Name:     "backup",
+		Interval: cfg.Backup.Interval,
+		Run: func(ctx context.Context) error {
+			backupCfg := backup.Config{
+				DatabaseURL:   cfg.DatabaseURL,
+				Dir:           cfg.Backup.Dir,
+				RetentionDays: cfg.Backup.RetentionDays,
+				Buckets:       AllBuckets,
+			}
+			status, err := backup.Run(ctx, backupCfg, minioClient)
+			if err != nil {
+				return err
+			}
+			log.Printf("Backup created at %s", status.Path)
+			_, err = backup.Prune(backupCfg)
+			return err
+		},
+	})
+	scheduler.Add(Job{
+		Name:     "partitions",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			if err := db.EnsurePartitions(partitionMonthsAhead); err != nil {
+				return err
+			}
+			return ArchiveOldPartitions(ctx, db, minioClient, cfg.Archive.AfterMonths, cfg.Archive.StorageClass)
+		},
+	})
+	scheduler.Add(Job{
+		Name:     "collected",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			// Страховка от расхождений собранных сумм с подтвержденными пожертвованиями
+			fixed, err := db.RecalculatePostsCollected()
+			if fixed > 0 {
+				log.Printf("Recalculated collected amount for %d posts", fixed)
+			}
+			return err
+		},
+	})
+	scheduler.Add(Job{
+		Name:     "ratings",
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			// Страховка от расхождений рейтингов с журналом баллов
+			fixed, err := db.RecalculateRatings()
+			if fixed > 0 {
+				log.Printf("Recalculated points of %d ratings from the ledger", fixed)
+			}
+			return err
+		},
+	})
+	scheduler.Add(Job{
+		Name:     "deadlines",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			expired, err := db.CloseExpiredPosts()
+			for _, post := range expired {
+				deadline := post.Deadline
+				if loc, err := LoadUserLocation(post.Timezone); err == nil {
+					deadline = deadline.In(loc)
+				}
+				notifier.Enqueue(FanoutJob{
+					Type:    NotificationPostExpired,
+					Title:   "Срок сбора истек",
+					Body:    fmt.Sprintf("Сбор «%s» закрыт: срок истек %s.", post.Title, deadline.Format("02.01.2006 15:04")),
+					PostID:  &post.ID,
+					UserIDs: []int64{post.UserID},
+				})
+			}
+			if len(expired) > 0 {
+				log.Printf("Closed %d posts after their deadline", len(expired))
+			}
+			return err
+		},
+	})
+	scheduler.Add(Job{
+		Name:     "subscriptions",
+		Interval: 10 * time.Minute,
+		Run:      NewSubscriptionRunner(db, notifier, 100).Run,
+	})
+	scheduler.Add(Job{
+		Name:     "urgent",
+		Interval: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			expired, err := db.ExpireUrgentPosts()
+			if err != nil {
+				return err
+			}
+			if expired > 0 {
+				log.Printf("Removed urgent flag from %d posts", expired)
+			}
+			overdue, err := db.CountOverdueUrgentReviews(cfg.Urgent.ReviewSLA)
+			if err != nil {
+				return err
+			}
+			metrics.Set("urgent_reviews_overdue", "Urgent posts waiting for moderation longer than the review SLA", nil, float64(overdue))
+			if overdue > 0 {
+				log.Printf("WARNING: %d urgent posts are waiting for moderation longer than %s", overdue, cfg.Urgent.ReviewSLA)
+			}
+			return nil
+		},
+	})
+	scheduler.Add(Job{
+		Name:     "response_stats",
+		Interval: 6 * time.Hour,
+		Run: func(ctx context.Context) error {
+			updated, err := db.RecalculateResponseStats()
+			if updated > 0 {
+				log.Printf("Updated response time of %d authors", updated)
+			}
+			return err
+		},
+	})
+	// Вебхуки идут на адреса пользователей: общий breaker не нужен, ошибка одного
+	// адреса не должна останавливать доставку остальным. Соединения - только с публичными адресами.
+	webhookClient := httpclient.New("webhooks", httpclient.Config{Timeout: cfg.Webhooks.Timeout, Transport: publicOnlyTransport()}, nil, metrics)
+	telegramClient := httpclient.New("telegram", httpclient.Config{Timeout: cfg.Webhooks.Timeout},
+		NewIntegrationBreaker("telegram", cfg.Breaker.FailureThreshold, cfg.Breaker.Cooldown), metrics)
+	webhooks := NewWebhookDispatcher(db, cfg.Webhooks, webhookClient, telegramClient)
+	scheduler.Add(Job{
+		Name:     "languages",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			// Определяем язык постов, созданных до появления автоопределения
+			detected, err := db.DetectMissingPostLanguages(500)
+			if detected > 0 {
+				log.Printf("Detected language of %d posts", detected)
+			}
+			return err
+		},
+	})
+	if ocr := NewReceiptOCR(cfg.OCR, newOutboundClient(cfg, "ocr", 30*time.Second)); ocr != nil {
+		receipts := NewReceiptChecker(db, minioClient, ocr, cfg.OCR.BatchSize)
+		scheduler.Add(Job{
+			Name:     "receipts",
+			Interval: cfg.OCR.Interval,
+			Run:      receipts.Run,
+		})
+	}
+	registry := NewOrganizationRegistry(db, NewRegistryProvider(cfg.Registry, newOutboundClient(cfg, "registry", 10*time.Second)), cfg.Registry)
+	if registry != nil {
+		scheduler.Add(Job{
+			Name:     "registry",
+			Interval: cfg.Registry.Interval,
+			Run:      registry.Run,
+		})
+	}
+	scheduler.Add(Job{
+		Name:     "webhooks",
+		Interval: cfg.Webhooks.PollInterval,
+		Run:      webhooks.Run,
+	})
+	scheduler.Add(Job{
+		Name:     "digest",
+		Interval: time.Hour,
+		Run:      NewDigestRunner(db, cfg.Digest, cfg.Webhooks.TelegramBotToken, telegramClient).Run,
+	})
+	scheduler.Add(Job{
+		Name:     "idempotency",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			deleted, err := db.DeleteExpiredIdempotencyKeys(time.Now().Add(-cfg.IdempotencyKeyTTL))
+			if deleted > 0 {
+				log.Printf("Deleted %d expired idempotency keys", deleted)
+			}
+			return err
+		},
+	})
+	if cfg.Retention.Enabled {
+		scheduler.Add(Job{
+			Name:     "retention",
+			Interval: 24 * time.Hour,
+			Run: func(ctx context.Context) error {
+				_, err := RunRetention(ctx, db, minioClient, cfg.Retention)
+				return err
+			},
+		})
+	}
+	scheduler.Start(context.Background())
 
 	// Создаем обработчики
-	handlers := NewHandlers(db, minioClient, cfg)
-
-	// Публичные маршруты
-	router.HandleFunc("/health", handlers.HealthCheck).Methods("GET")
-
-	// Swagger документация
-	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
-
-	// API v1 маршруты
-	api := router.PathPrefix("/api/v1").Subrouter()
-
-	// Аутентификация (публичные)
-	api.HandleFunc("/auth/register", handlers.Register).Methods("POST")
-	api.HandleFunc("/auth/login", handlers.Login).Methods("POST")
-	api.HandleFunc("/auth/refresh", handlers.RefreshToken).Methods("POST")
-
-	// Защищенные маршруты (требуют JWT)
-	protected := api.PathPrefix("").Subrouter()
-	protected.Use(JWTAuthMiddleware(cfg))
-
-	// Профиль пользователя
-	protected.HandleFunc("/users/me", handlers.GetProfile).Methods("GET")
-	protected.HandleFunc("/users/me", handlers.UpdateProfile).Methods("PATCH")
-	protected.HandleFunc("/users/me/photo", handlers.UploadPhoto).Methods("POST")
-	protected.HandleFunc("/users/me/change-password", handlers.ChangePassword).Methods("POST")
-
-	// Верификация
-	protected.HandleFunc("/verifications", handlers.CreateVerification).Methods("POST")
-	protected.HandleFunc("/verifications/me", handlers.GetMyVerification).Methods("GET")
-
-	// Верификация (только для админов)
-	adminOnly := protected.PathPrefix("").Subrouter()
-	adminOnly.Use(RoleMiddleware("admin"))
-	adminOnly.HandleFunc("/verifications", handlers.GetVerifications).Methods("GET")
-	adminOnly.HandleFunc("/verifications/{id}", handlers.UpdateVerification).Methods("PATCH")
-
-	// Посты
-	api.HandleFunc("/posts", handlers.GetPosts).Methods("GET")
-	api.HandleFunc("/posts/{id}", handlers.GetPost).Methods("GET")
-	protected.HandleFunc("/posts", handlers.CreatePost).Methods("POST")
-	protected.HandleFunc("/posts/{id}", handlers.UpdatePost).Methods("PATCH")
-	protected.HandleFunc("/posts/{id}", handlers.DeletePost).Methods("DELETE")
-	protected.HandleFunc("/posts/{id}/media", handlers.AddPostMedia).Methods("POST")
-	protected.HandleFunc("/posts/{id}/media/{media_id}", handlers.DeletePostMedia).Methods("DELETE")
-
-	// Пожертвования
-	protected.HandleFunc("/donations", handlers.CreateDonation).Methods("POST")
-	api.HandleFunc("/donations", handlers.GetDonations).Methods("GET")
-	api.HandleFunc("/donations/{id}", handlers.GetDonation).Methods("GET")
-	protected.HandleFunc("/donations/{id}", handlers.UpdateDonation).Methods("PATCH")
-
-	// Чаты
-	protected.HandleFunc("/chats", handlers.GetChats).Methods("GET")
-	protected.HandleFunc("/chats", handlers.CreateChat).Methods("POST")
-	protected.HandleFunc("/chats/{id}/messages", handlers.GetMessages).Methods("GET")
-	protected.HandleFunc("/chats/{id}/messages", handlers.SendMessage).Methods("POST")
-	protected.HandleFunc("/chats/{id}/messages/read", handlers.MarkMessagesRead).Methods("PATCH")
-	protected.HandleFunc("/chats/{id}/messages/{message_id}", handlers.UpdateMessage).Methods("PATCH")
-	protected.HandleFunc("/chats/{id}/messages/{message_id}", handlers.DeleteMessage).Methods("DELETE")
-
-	// Рейтинг
-	api.HandleFunc("/ratings", handlers.GetRatings).Methods("GET")
-	protected.HandleFunc("/ratings/me", handlers.GetMyRating).Methods("GET")
-
-	// Утилиты
-	protected.HandleFunc("/upload/presigned-url", handlers.GetPresignedURL).Methods("POST")
-	protected.HandleFunc("/files/presigned-url", handlers.GetPresignedGetURL).Methods("POST")
-
-	// Публичный endpoint для получения файлов (проксирование через backend)
-	// Поддерживаем оба варианта: /files/... и /api/v1/files/...
-	router.HandleFunc("/files/{bucket}/{objectKey:.*}", handlers.GetFile).Methods("GET")
-	api.HandleFunc("/files/{bucket}/{objectKey:.*}", handlers.GetFile).Methods("GET")
+	// Матрица прав ролей кэшируется и перечитывается из базы раз в минуту
+	perms := NewPermissions(db, time.Minute)
+
+	// Антивирусная проверка загружаемых документов и вложений
+	scanner, err := NewFileScanner(cfg.Antivirus)
+	if err != nil {
+		log.Fatalf("Failed to initialize antivirus scanner: %v", err)
+	}
+
+	// Лимит запросов на пользователя и API токен, состояние - в заголовках X-RateLimit-*
+	limiter := NewRateLimiter(cfg.RateLimit.Window)
+
+	// События постов и чатов для открытых страниц (SSE)
+	events := NewEventHub()
+
+	handlers := NewHandlers(db, minioClient, smsProvider, payments, notifier, perms, NewOAuthProviders(cfg.OAuth), scanner, limiter, scheduler, selfTest, events, registry, cfg)
+
+	// Инициализируем роутер
+	router := NewRouter(cfg, db, handlers, perms, limiter)
 
 	// Оборачиваем роутер в CORS handler для обработки всех запросов, включая OPTIONS
 	// Это гарантирует, что CORS заголовки будут установлены даже для несуществующих маршрутов
@@ -161,6 +355,8 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Shutdown ждет завершения запросов, поэтому потоки SSE закрываются сразу
+	srv.RegisterOnShutdown(events.Close)
 
 	// Запускаем сервер в горутине
 	go func() {
@@ -177,6 +373,9 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	scheduler.Stop()
+	notifier.Stop()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
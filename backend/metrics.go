@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MetricSample значение метрики с набором меток
+type MetricSample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+type metricFamily struct {
+	help    string
+	kind    string
+	samples map[string]*MetricSample
+	collect func() []MetricSample
+}
+
+// Metrics реестр метрик в текстовом формате Prometheus
+type Metrics struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+var metrics = NewMetrics()
+
+func NewMetrics() *Metrics {
+	return &Metrics{families: make(map[string]*metricFamily)}
+}
+
+// Inc увеличивает счетчик на 1
+func (m *Metrics) Inc(name, help string, labels map[string]string) {
+	m.Add(name, help, labels, 1)
+}
+
+// Add увеличивает счетчик на value
+func (m *Metrics) Add(name, help string, labels map[string]string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sample := m.sample(name, help, "counter", labels)
+	sample.Value += value
+}
+
+// Set устанавливает значение gauge
+func (m *Metrics) Set(name, help string, labels map[string]string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sample := m.sample(name, help, "gauge", labels)
+	sample.Value = value
+}
+
+// GaugeFunc регистрирует gauge, значения которого вычисляются при каждом сборе
+func (m *Metrics) GaugeFunc(name, help string, collect func() []MetricSample) {
+	m.register(name, help, "gauge", collect)
+}
+
+// CounterFunc регистрирует счетчик, значения которого вычисляются при каждом сборе
+func (m *Metrics) CounterFunc(name, help string, collect func() []MetricSample) {
+	m.register(name, help, "counter", collect)
+}
+
+func (m *Metrics) register(name, help, kind string, collect func() []MetricSample) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.families[name] = &metricFamily{help: help, kind: kind, collect: collect}
+}
+
+func (m *Metrics) sample(name, help, kind string, labels map[string]string) *MetricSample {
+	family, ok := m.families[name]
+	if !ok {
+		family = &metricFamily{help: help, kind: kind, samples: make(map[string]*MetricSample)}
+		m.families[name] = family
+	}
+
+	key := formatLabels(labels)
+	sample, ok := family.samples[key]
+	if !ok {
+		sample = &MetricSample{Labels: labels}
+		family.samples[key] = sample
+	}
+	return sample
+}
+
+// Handler отдает метрики в текстовом формате Prometheus
+func (m *Metrics) Handler(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		family := m.families[name]
+
+		var samples []MetricSample
+		if family.collect != nil {
+			samples = family.collect()
+		} else {
+			for _, sample := range family.samples {
+				samples = append(samples, *sample)
+			}
+		}
+		sort.Slice(samples, func(i, j int) bool {
+			return formatLabels(samples[i].Labels) < formatLabels(samples[j].Labels)
+		})
+
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, family.help, name, family.kind)
+		for _, sample := range samples {
+			fmt.Fprintf(&b, "%s%s %g\n", name, formatLabels(sample.Labels), sample.Value)
+		}
+	}
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[k])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// registerBreakerMetrics экспортирует состояние circuit breakers
+func registerBreakerMetrics(m *Metrics) {
+	m.GaugeFunc("circuit_breaker_state", "Circuit breaker state (0 - closed, 1 - open, 2 - half-open)", func() []MetricSample {
+		var samples []MetricSample
+		for _, b := range CircuitBreakers() {
+			samples = append(samples, MetricSample{Labels: map[string]string{"name": b.Name()}, Value: float64(b.State())})
+		}
+		return samples
+	})
+	m.CounterFunc("circuit_breaker_trips_total", "Number of times the circuit breaker has opened since start", func() []MetricSample {
+		var samples []MetricSample
+		for _, b := range CircuitBreakers() {
+			samples = append(samples, MetricSample{Labels: map[string]string{"name": b.Name()}, Value: float64(b.Trips())})
+		}
+		return samples
+	})
+}
@@ -2,17 +2,23 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type contextKey string
 
 const UserIDKey contextKey = "user_id"
 const UserRoleKey contextKey = "role"
+const TokenClaimsKey contextKey = "claims"
+const APITokenKey contextKey = "api_token"
 
-// JWTAuthMiddleware проверяет JWT токен и добавляет user_id в контекст
-func JWTAuthMiddleware(cfg *Config) func(http.Handler) http.Handler {
+// JWTAuthMiddleware проверяет JWT токен и добавляет user_id в контекст.
+// Токены из списка отозванных отклоняются до истечения их срока действия.
+func JWTAuthMiddleware(cfg *Config, db *DB) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -28,32 +34,117 @@ func JWTAuthMiddleware(cfg *Config) func(http.Handler) http.Handler {
 				return
 			}
 
+			// Роль берется из базы: смена роли действует сразу, а не после обновления токена
+			role, err := checkAccessToken(db, claims.ID, claims.UserID, claims.issuedAt())
+			if err != nil {
+				WriteError(w, err)
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-			ctx = context.WithValue(ctx, UserRoleKey, claims.Role)
+			ctx = context.WithValue(ctx, UserRoleKey, role)
+			ctx = context.WithValue(ctx, TokenClaimsKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// RoleMiddleware проверяет, что у пользователя есть одна из указанных ролей
-func RoleMiddleware(roles ...string) func(http.Handler) http.Handler {
+// APITokenAuthMiddleware дополнительно к JWT принимает персональные API токены
+// с нужной областью действия. Маршруты без этого middleware API токены не принимают.
+// Запросы по API токену выполняются без роли, поэтому права ролей на них не действуют.
+// Пустая scope пропускает токен с любой областью действия.
+func APITokenAuthMiddleware(cfg *Config, db *DB, scope string) func(http.Handler) http.Handler {
+	jwtAuth := JWTAuthMiddleware(cfg, db)
 	return func(next http.Handler) http.Handler {
+		jwtNext := jwtAuth(next)
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userRole, ok := r.Context().Value(UserRoleKey).(string)
-			if !ok {
-				WriteError(w, NewUnauthorizedError("Роль не найдена"))
+			tokenString, err := ExtractTokenFromHeader(r.Header.Get("Authorization"))
+			if err != nil || !strings.HasPrefix(tokenString, APITokenPrefix) {
+				jwtNext.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := db.GetAPITokenByHash(sha256Hex(tokenString))
+			if err != nil {
+				WriteError(w, NewUnauthorizedError("Неверный токен"))
+				return
+			}
+			if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+				WriteError(w, NewUnauthorizedError("Срок действия токена истек"))
+				return
+			}
+			if scope != "" && !token.HasScope(scope) {
+				WriteError(w, NewForbiddenError(fmt.Sprintf("Токену не выдан доступ %s", scope)))
 				return
 			}
+			if _, err := checkAccessToken(db, "", token.UserID, time.Time{}); err != nil {
+				WriteError(w, err)
+				return
+			}
+
+			if err := db.TouchAPIToken(token.ID); err != nil {
+				log.Printf("Failed to update api token usage: %v", err)
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, token.UserID)
+			ctx = context.WithValue(ctx, UserRoleKey, "")
+			ctx = context.WithValue(ctx, APITokenKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalClaims разбирает JWT на публичных маршрутах, поведение которых зависит от пользователя.
+// Без заголовка Authorization возвращает nil, неверный или отозванный токен - ошибка.
+func OptionalClaims(cfg *Config, db *DB, r *http.Request) (*Claims, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, nil
+	}
+	tokenString, err := ExtractTokenFromHeader(authHeader)
+	if err != nil {
+		return nil, NewUnauthorizedError("Не авторизован")
+	}
+	claims, err := ValidateToken(cfg, tokenString)
+	if err != nil {
+		return nil, NewUnauthorizedError("Неверный токен")
+	}
+	role, err := checkAccessToken(db, claims.ID, claims.UserID, claims.issuedAt())
+	if err != nil {
+		return nil, err
+	}
+	claims.Role = role
+	return claims, nil
+}
+
+// checkAccessToken отклоняет отозванный токен, токены, выпущенные до смены или сброса пароля,
+// и токены деактивированных аккаунтов: блокировка действует сразу, не дожидаясь истечения
+// выданных токенов. Возвращает текущую роль пользователя.
+func checkAccessToken(db *DB, jti string, userID int64, issuedAt time.Time) (string, error) {
+	revoked, active, role, err := db.CheckAccessToken(jti, userID, issuedAt)
+	if err != nil {
+		return "", NewInternalError("Ошибка проверки токена")
+	}
+	if revoked {
+		return "", NewUnauthorizedError("Токен отозван")
+	}
+	if !active {
+		return "", NewForbiddenError("Аккаунт деактивирован")
+	}
+	return role, nil
+}
 
-			hasRole := false
-			for _, role := range roles {
-				if userRole == role {
-					hasRole = true
-					break
-				}
+// PermissionMiddleware пропускает запрос, только если роль пользователя имеет право
+func PermissionMiddleware(perms *Permissions, permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, err := GetUserRoleFromContext(r.Context())
+			if err != nil {
+				WriteError(w, NewUnauthorizedError("Роль не найдена"))
+				return
 			}
 
-			if !hasRole {
+			if !perms.Has(role, permission) {
 				WriteError(w, NewForbiddenError("Недостаточно прав"))
 				return
 			}
@@ -124,6 +215,15 @@ func GetUserRoleFromContext(ctx context.Context) (string, error) {
 	return role, nil
 }
 
+// GetTokenClaimsFromContext извлекает claims текущего токена из контекста
+func GetTokenClaimsFromContext(ctx context.Context) (*Claims, error) {
+	claims, ok := ctx.Value(TokenClaimsKey).(*Claims)
+	if !ok {
+		return nil, NewUnauthorizedError("Токен не найден в контексте")
+	}
+	return claims, nil
+}
+
 // RecoverMiddleware обрабатывает паники
 func RecoverMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"log"
+	"net/http"
 	"net/url"
 	"path/filepath"
 	"strings"
@@ -11,6 +14,7 @@ import (
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
 )
 
 const (
@@ -19,13 +23,51 @@ const (
 	BucketPostMedia        = "post-media"
 	BucketDonationReceipts = "donation-receipts"
 	BucketChatAttachments  = "chat-attachments"
+	BucketArchive          = "archive"
+	// Зараженные файлы, не входит в AllBuckets: не отдается через /files и не попадает в резервные копии
+	BucketQuarantine = "quarantine"
 )
 
-func NewMinIOClient(cfg MinIOConfig) (*minio.Client, error) {
+// AllBuckets список всех buckets приложения
+var AllBuckets = []string{
+	BucketUserPhotos,
+	BucketVerificationDocs,
+	BucketPostMedia,
+	BucketDonationReceipts,
+	BucketChatAttachments,
+	BucketArchive,
+}
+
+// bucketCacheControl заголовок Cache-Control для файлов, отдаваемых через /files.
+// Фото профиля и медиа постов перезаписываются под тем же ключом, поэтому кэшируются
+// ограниченное время. Документы и чеки всегда перепроверяются по ETag.
+var bucketCacheControl = map[string]string{
+	BucketUserPhotos:       "public, max-age=3600",
+	BucketPostMedia:        "public, max-age=86400",
+	BucketChatAttachments:  "private, max-age=86400",
+	BucketVerificationDocs: "private, no-cache",
+	BucketDonationReceipts: "private, no-cache",
+}
+
+// CacheControlForBucket возвращает Cache-Control для файлов bucket
+func CacheControlForBucket(bucket string) string {
+	if cacheControl, ok := bucketCacheControl[bucket]; ok {
+		return cacheControl
+	}
+	return "no-cache"
+}
+
+func NewMinIOClient(cfg MinIOConfig, breaker *CircuitBreaker) (*minio.Client, error) {
+	transport, err := minio.DefaultTransport(cfg.UseSSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO transport: %w", err)
+	}
+
 	client, err := minio.New(cfg.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
-		Secure: cfg.UseSSL,
-		Region: cfg.Region,
+		Creds:     credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure:    cfg.UseSSL,
+		Region:    cfg.Region,
+		Transport: &breakerTransport{base: transport, breaker: breaker},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
@@ -42,7 +84,11 @@ func EnsureBucket(ctx context.Context, client *minio.Client, bucketName string)
 	}
 
 	if !exists {
-		if err := client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{}); err != nil {
+		// Блокировку объектов можно включить только при создании bucket
+		opts := minio.MakeBucketOptions{
+			ObjectLocking: bucketName == BucketVerificationDocs,
+		}
+		if err := client.MakeBucket(ctx, bucketName, opts); err != nil {
 			return fmt.Errorf("failed to create bucket: %w", err)
 		}
 	}
@@ -52,15 +98,7 @@ func EnsureBucket(ctx context.Context, client *minio.Client, bucketName string)
 
 // InitAllBuckets инициализирует все необходимые buckets
 func InitAllBuckets(ctx context.Context, client *minio.Client) error {
-	buckets := []string{
-		BucketUserPhotos,
-		BucketVerificationDocs,
-		BucketPostMedia,
-		BucketDonationReceipts,
-		BucketChatAttachments,
-	}
-
-	for _, bucket := range buckets {
+	for _, bucket := range AllBuckets {
 		if err := EnsureBucket(ctx, client, bucket); err != nil {
 			return fmt.Errorf("failed to initialize bucket %s: %w", bucket, err)
 		}
@@ -69,6 +107,64 @@ func InitAllBuckets(ctx context.Context, client *minio.Client) error {
 	return nil
 }
 
+// ConfigureBucketLifecycles применяет правила хранения объектов к buckets.
+// Ошибки по отдельным buckets не прерывают настройку остальных.
+func ConfigureBucketLifecycles(ctx context.Context, client *minio.Client, cfg StorageLifecycleConfig) error {
+	var errs []string
+
+	// Вложения чатов удаляются автоматически через N месяцев
+	if cfg.ChatAttachmentsExpiryMonths > 0 {
+		config := lifecycle.NewConfiguration()
+		config.Rules = []lifecycle.Rule{{
+			ID:         "expire-chat-attachments",
+			Status:     "Enabled",
+			RuleFilter: lifecycle.Filter{Prefix: "chats/"},
+			Expiration: lifecycle.Expiration{
+				Days: lifecycle.ExpirationDays(cfg.ChatAttachmentsExpiryMonths * 30),
+			},
+		}}
+		if err := client.SetBucketLifecycle(ctx, BucketChatAttachments, config); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", BucketChatAttachments, err))
+		}
+	}
+
+	// Старые медиа постов переводятся в класс хранения с пониженной избыточностью
+	if cfg.PostMediaTransitionDays > 0 && cfg.PostMediaStorageClass != "" {
+		config := lifecycle.NewConfiguration()
+		config.Rules = []lifecycle.Rule{{
+			ID:         "transition-post-media",
+			Status:     "Enabled",
+			RuleFilter: lifecycle.Filter{Prefix: "posts/"},
+			Transition: lifecycle.Transition{
+				Days:         lifecycle.ExpirationDays(cfg.PostMediaTransitionDays),
+				StorageClass: cfg.PostMediaStorageClass,
+			},
+		}}
+		if err := client.SetBucketLifecycle(ctx, BucketPostMedia, config); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", BucketPostMedia, err))
+		}
+	}
+
+	// Документы верификации защищены от удаления на установленный законом срок
+	if cfg.VerificationDocsRetentionDays > 0 {
+		mode := minio.RetentionMode(strings.ToUpper(cfg.VerificationDocsRetentionMode))
+		if !mode.IsValid() {
+			errs = append(errs, fmt.Sprintf("%s: invalid retention mode %q", BucketVerificationDocs, cfg.VerificationDocsRetentionMode))
+		} else {
+			validity := uint(cfg.VerificationDocsRetentionDays)
+			unit := minio.Days
+			if err := client.SetObjectLockConfig(ctx, BucketVerificationDocs, &mode, &validity, &unit); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v (object locking must be enabled when the bucket is created)", BucketVerificationDocs, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to configure bucket lifecycles: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
 // UploadUserPhoto загружает фото профиля пользователя
 func UploadUserPhoto(ctx context.Context, client *minio.Client, userID int64, file io.Reader, size int64, contentType string) (string, error) {
 	ext := getExtensionFromContentType(contentType)
@@ -84,6 +180,43 @@ func UploadUserPhoto(ctx context.Context, client *minio.Client, userID int64, fi
 	return objectKey, nil
 }
 
+// UploadImageVariants загружает уменьшенные копии изображения рядом с оригиналом:
+// posts/1/media_0.png -> posts/1/media_0_small.jpg. Возвращает ключи объектов по размерам.
+func UploadImageVariants(ctx context.Context, client *minio.Client, bucket, objectKey string, data []byte) (map[string]string, error) {
+	thumbnails, err := GenerateThumbnails(data)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(objectKey, filepath.Ext(objectKey))
+	keys := make(map[string]string, len(thumbnails))
+	for name, thumbnail := range thumbnails {
+		key := fmt.Sprintf("%s_%s.jpg", base, name)
+		_, err := client.PutObject(ctx, bucket, key, bytes.NewReader(thumbnail), int64(len(thumbnail)), minio.PutObjectOptions{
+			ContentType: "image/jpeg",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload %s thumbnail: %w", name, err)
+		}
+		keys[name] = key
+	}
+	return keys, nil
+}
+
+// UploadShareImage загружает картинку поста для соцсетей
+func UploadShareImage(ctx context.Context, client *minio.Client, postID int64, data []byte) (string, error) {
+	objectKey := fmt.Sprintf("posts/%d/share.png", postID)
+
+	_, err := client.PutObject(ctx, BucketPostMedia, objectKey, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "image/png",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload share image: %w", err)
+	}
+
+	return objectKey, nil
+}
+
 // UploadVerificationDoc загружает документ верификации
 func UploadVerificationDoc(ctx context.Context, client *minio.Client, verificationID int64, filename string, file io.Reader, size int64, contentType string) (string, error) {
 	ext := filepath.Ext(filename)
@@ -94,6 +227,8 @@ func UploadVerificationDoc(ctx context.Context, client *minio.Client, verificati
 
 	_, err := client.PutObject(ctx, BucketVerificationDocs, objectKey, file, size, minio.PutObjectOptions{
 		ContentType: contentType,
+		// Bucket с блокировкой объектов требует Content-MD5
+		SendContentMd5: true,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to upload verification doc: %w", err)
@@ -102,6 +237,40 @@ func UploadVerificationDoc(ctx context.Context, client *minio.Client, verificati
 	return objectKey, nil
 }
 
+// UploadGuardianDocument загружает документ законного представителя для сбора
+// в пользу несовершеннолетнего. Хранится вместе с документами верификации.
+func UploadGuardianDocument(ctx context.Context, client *minio.Client, postID int64, file io.Reader, size int64, contentType string) (string, error) {
+	ext := getExtensionFromContentType(contentType)
+	objectKey := fmt.Sprintf("posts/%d/guardian_document%s", postID, ext)
+
+	_, err := client.PutObject(ctx, BucketVerificationDocs, objectKey, file, size, minio.PutObjectOptions{
+		ContentType:    contentType,
+		SendContentMd5: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload guardian document: %w", err)
+	}
+
+	return objectKey, nil
+}
+
+// UploadUrgentDocument загружает документ, подтверждающий срочность сбора (выписка,
+// счет клиники). Хранится вместе с документом законного представителя.
+func UploadUrgentDocument(ctx context.Context, client *minio.Client, postID int64, file io.Reader, size int64, contentType string) (string, error) {
+	ext := getExtensionFromContentType(contentType)
+	objectKey := fmt.Sprintf("posts/%d/urgent_document%s", postID, ext)
+
+	_, err := client.PutObject(ctx, BucketVerificationDocs, objectKey, file, size, minio.PutObjectOptions{
+		ContentType:    contentType,
+		SendContentMd5: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload urgent document: %w", err)
+	}
+
+	return objectKey, nil
+}
+
 // UploadPostMedia загружает медиа файл поста
 func UploadPostMedia(ctx context.Context, client *minio.Client, postID int64, index int, file io.Reader, size int64, contentType string) (string, error) {
 	ext := getExtensionFromContentType(contentType)
@@ -132,6 +301,37 @@ func UploadDonationReceipt(ctx context.Context, client *minio.Client, donationID
 	return objectKey, nil
 }
 
+// GetDonationReceipt читает чек пожертвования. Расширение файла заранее неизвестно,
+// поэтому чек ищется по префиксу donations/{id}/receipt.
+func GetDonationReceipt(ctx context.Context, client *minio.Client, donationID int64) ([]byte, string, error) {
+	prefix := fmt.Sprintf("donations/%d/receipt", donationID)
+	for info := range client.ListObjects(ctx, BucketDonationReceipts, minio.ListObjectsOptions{Prefix: prefix}) {
+		if info.Err != nil {
+			return nil, "", fmt.Errorf("failed to list donation receipts: %w", info.Err)
+		}
+		if info.Size > maxReceiptSize {
+			return nil, "", fmt.Errorf("donation receipt is too large: %d bytes", info.Size)
+		}
+
+		obj, err := GetObject(ctx, client, BucketDonationReceipts, info.Key)
+		if err != nil {
+			return nil, "", err
+		}
+		defer obj.Close()
+
+		content, err := io.ReadAll(obj)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read donation receipt: %w", err)
+		}
+		stat, err := obj.Stat()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to stat donation receipt: %w", err)
+		}
+		return content, stat.ContentType, nil
+	}
+	return nil, "", fmt.Errorf("donation receipt not found")
+}
+
 // UploadChatAttachment загружает вложение в сообщении чата
 func UploadChatAttachment(ctx context.Context, client *minio.Client, chatID, messageID int64, file io.Reader, size int64, contentType string) (string, error) {
 	ext := getExtensionFromContentType(contentType)
@@ -147,9 +347,29 @@ func UploadChatAttachment(ctx context.Context, client *minio.Client, chatID, mes
 	return objectKey, nil
 }
 
+// QuarantineObject сохраняет файл, в котором антивирус нашел угрозу, для разбора администратором
+func QuarantineObject(ctx context.Context, client *minio.Client, kind string, userID int64, file io.Reader, size int64, contentType, signature string) (string, error) {
+	if err := EnsureBucket(ctx, client, BucketQuarantine); err != nil {
+		return "", err
+	}
+	objectKey := fmt.Sprintf("%s/%d/%d", kind, userID, time.Now().UnixNano())
+
+	_, err := client.PutObject(ctx, BucketQuarantine, objectKey, file, size, minio.PutObjectOptions{
+		ContentType:  contentType,
+		UserMetadata: map[string]string{"Signature": signature},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to quarantine object: %w", err)
+	}
+
+	return objectKey, nil
+}
+
 // GeneratePresignedURL генерирует presigned URL для загрузки
+// Content-Type входит в подпись: загрузить по ссылке файл с другим типом нельзя.
 func GeneratePresignedURL(ctx context.Context, client *minio.Client, bucket, objectKey, contentType string, expiresIn time.Duration) (string, error) {
-	url, err := client.PresignedPutObject(ctx, bucket, objectKey, expiresIn)
+	headers := http.Header{"Content-Type": []string{contentType}}
+	url, err := client.PresignHeader(ctx, http.MethodPut, bucket, objectKey, expiresIn, nil, headers)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
@@ -185,6 +405,40 @@ func GetObjectURL(cfg MinIOConfig, bucket, objectKey string) string {
 	return fmt.Sprintf("%s://%s/%s/%s", scheme, endpoint, bucket, objectKey)
 }
 
+// ObjectKeyFromURL извлекает ключ объекта из URL, сохраненного через GetObjectURL
+func ObjectKeyFromURL(objectURL, bucket string) (string, bool) {
+	marker := "/" + bucket + "/"
+	i := strings.Index(objectURL, marker)
+	if i < 0 {
+		return "", false
+	}
+	return objectURL[i+len(marker):], true
+}
+
+// DeleteObjectsByURL удаляет из хранилища файлы по сохраненным URL. Ошибки только
+// логируются: запись в базе уже удалена, и запрос не должен из-за них падать.
+func DeleteObjectsByURL(ctx context.Context, client *minio.Client, bucket string, urls []string) {
+	for _, objectURL := range urls {
+		key, ok := ObjectKeyFromURL(objectURL, bucket)
+		if !ok {
+			continue
+		}
+		if err := DeleteObject(ctx, client, bucket, key); err != nil {
+			log.Printf("Failed to delete %s/%s: %v", bucket, key, err)
+		}
+	}
+}
+
+// ConvertImageVariantsToBackendURLs преобразует ссылки на уменьшенные копии в URL через backend проксирование
+func ConvertImageVariantsToBackendURLs(v *ImageVariants) {
+	if v == nil {
+		return
+	}
+	v.Small = ConvertMinIOURLToBackendURL(v.Small)
+	v.Medium = ConvertMinIOURLToBackendURL(v.Medium)
+	v.Large = ConvertMinIOURLToBackendURL(v.Large)
+}
+
 // ConvertMinIOURLToBackendURL преобразует MinIO URL в URL через backend проксирование
 func ConvertMinIOURLToBackendURL(url string) string {
 	if url == "" {
@@ -243,6 +497,21 @@ func GetObject(ctx context.Context, client *minio.Client, bucket, objectKey stri
 	return obj, nil
 }
 
+// readObject читает объект из MinIO целиком
+func readObject(ctx context.Context, client *minio.Client, bucket, objectKey string) ([]byte, error) {
+	obj, err := GetObject(ctx, client, bucket, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
 // getExtensionFromContentType извлекает расширение из content type
 func getExtensionFromContentType(contentType string) string {
 	contentType = strings.ToLower(contentType)
@@ -2,7 +2,10 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
+
+	"tmphackbackend/backup"
 )
 
 // User модель пользователя
@@ -18,6 +21,124 @@ type User struct {
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 	IsActive    bool      `json:"is_active" db:"is_active"`
+	PhoneVerified bool    `json:"phone_verified" db:"phone_verified"`
+	// Привязанные VK ID, Яндекс и Google (заполняется только в профиле)
+	Identities  []UserIdentity `json:"identities,omitempty" db:"-"`
+	Away        *AwayStatus    `json:"away,omitempty" db:"-"`
+	PhotoVariants *ImageVariants `json:"photo_variants,omitempty" db:"photo_variants"`
+	// Часовой пояс IANA (заполняется только в профиле)
+	Timezone    string         `json:"timezone,omitempty" db:"timezone"`
+}
+
+// AwayStatus режим "нет на месте". Until подсказывает собеседнику, когда ждать ответа
+// (пусто - до отключения режима).
+type AwayStatus struct {
+	Message string     `json:"message"`
+	Until   *time.Time `json:"until,omitempty"`
+}
+
+// AdminActionEntry запись журнала действий администраторов. Объект действия задается
+// TargetID (пользователь, пост, заявка) или TargetName (роль, уровень доверия, партия кодов).
+type AdminActionEntry struct {
+	ID           int64           `json:"id"`
+	ActorID      *int64          `json:"actor_id,omitempty" db:"actor_id"`
+	Action       string          `json:"action"`
+	TargetType   string          `json:"target_type" db:"target_type"`
+	TargetID     *int64          `json:"target_id,omitempty" db:"target_id"`
+	TargetName   *string         `json:"target_name,omitempty" db:"target_name"`
+	TargetUserID *int64          `json:"target_user_id,omitempty" db:"target_user_id"`
+	Reason       string          `json:"reason"`
+	OldValue     json.RawMessage `json:"old_value,omitempty" db:"old_value" swaggertype:"object"`
+	NewValue     json.RawMessage `json:"new_value,omitempty" db:"new_value" swaggertype:"object"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AdminActionRecord действие для записи в журнал. Нулевые TargetID и TargetUserID и пустое
+// TargetName не сохраняются, OldValue и NewValue сохраняются в JSON.
+type AdminActionRecord struct {
+	ActorID      int64
+	Action       string
+	TargetType   string
+	TargetID     int64
+	TargetName   string
+	TargetUserID int64
+	Reason       string
+	OldValue     interface{}
+	NewValue     interface{}
+}
+
+// AuditLogFilter фильтры журнала действий администраторов, нулевые значения не применяются
+type AuditLogFilter struct {
+	ActorID      int64
+	Action       string
+	TargetType   string
+	TargetID     int64
+	TargetUserID int64
+	From         *time.Time
+	To           *time.Time
+}
+
+// Действия администраторов, для которых обязательна причина
+const (
+	AdminActionVerificationReject = "verification.reject"
+	AdminActionPostClose          = "post.close"
+	AdminActionUserBlock          = "user.block"
+)
+
+// Действия, которые записываются в журнал без причины
+const (
+	AdminActionUserUnblock               = "user.unblock"
+	AdminActionUserRole                  = "user.role"
+	AdminActionUserRiskTier              = "user.risk_tier"
+	AdminActionVerificationApprove       = "verification.approve"
+	AdminActionVerificationDocumentsView = "verification.documents_view"
+	AdminActionPostApprove               = "post.approve"
+	AdminActionDonationConfirm           = "donation.confirm"
+	AdminActionDonationReject            = "donation.reject"
+	AdminActionDonationRefund            = "donation.refund"
+	AdminActionRoleSave                  = "role.save"
+	AdminActionRiskTierSave              = "risk_tier.save"
+	AdminActionPostLimitReview           = "post_limit.review"
+	AdminActionInvitesCreate             = "invites.create"
+	AdminActionPointsEventCreate         = "points_event.create"
+	AdminActionPointsEventCancel         = "points_event.cancel"
+	// Повтор и закрытие заданий из очереди недоставленных
+	AdminActionDeadLetterRetry   = "dead_letter.retry"
+	AdminActionDeadLetterDiscard = "dead_letter.discard"
+	// Вебхуки партнеров
+	AdminActionWebhookCreate = "webhook.create"
+	AdminActionWebhookUpdate = "webhook.update"
+	AdminActionWebhookDelete = "webhook.delete"
+	// Изменения поста соавтором и управление соавторами
+	AdminActionPostUpdate         = "post.update"
+	AdminActionPostMedia          = "post.media"
+	AdminActionCollaboratorInvite = "post.collaborator_invite"
+	AdminActionCollaboratorUpdate = "post.collaborator_update"
+	AdminActionCollaboratorRemove = "post.collaborator_remove"
+	// Удаление чужого комментария модератором или соавтором
+	AdminActionCommentDelete = "post.comment_delete"
+	// Удаление чужого вопроса к посту модератором или соавтором
+	AdminActionQuestionDelete = "post.question_delete"
+)
+
+// UserIdentity учетная запись внешнего провайдера, привязанная к аккаунту
+type UserIdentity struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-" db:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"-"`
+	Email     *string   `json:"email,omitempty"`
+	Name      *string   `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// OAuthState незавершенный вход через провайдера
+type OAuthState struct {
+	State        string
+	Provider     string
+	UserID       *int64
+	CodeVerifier string
+	ExpiresAt    time.Time
 }
 
 // Verification модель верификации
@@ -36,6 +157,11 @@ type Verification struct {
 	DocType          string         `json:"doc_type" db:"doc_type"`
 	INN              *string        `json:"inn,omitempty"`
 	SNILS            *string        `json:"snils,omitempty"`
+	// Страна и удостоверение личности из справочника DocumentCountries. Серия, номер, кем
+	// и когда выдан хранятся в столбцах passport_*, остальные поля документа - в DocumentData.
+	Country      string            `json:"country" db:"country"`
+	DocumentType string            `json:"document_type" db:"document_type"`
+	DocumentData map[string]string `json:"document_data,omitempty" db:"document_data"`
 	PassportScansURLs []string      `json:"passport_scans_urls,omitempty" db:"passport_scans_urls"`
 	Consent1         bool           `json:"consent1"`
 	Consent2         bool           `json:"consent2"`
@@ -45,6 +171,33 @@ type Verification struct {
 	ReviewedAt       *time.Time     `json:"reviewed_at,omitempty" db:"reviewed_at"`
 	ReviewedBy       *int64         `json:"reviewed_by,omitempty" db:"reviewed_by"`
 	RejectionReason  *string        `json:"rejection_reason,omitempty" db:"rejection_reason"`
+	// Организация заявителя (ИНН и ОГРН юридического лица) и результат ее сверки с реестром:
+	// OrganizationCheck - pending, matched, mismatch или not_found, OrganizationMismatches - что
+	// расходится с реестром, OrganizationRegistry - сведения реестра на момент сверки
+	OrganizationINN        *string               `json:"organization_inn,omitempty"`
+	OrganizationOGRN       *string               `json:"organization_ogrn,omitempty"`
+	OrganizationName       *string               `json:"organization_name,omitempty"`
+	OrganizationCheck      *string               `json:"organization_check,omitempty" enums:"pending,matched,mismatch,not_found"`
+	OrganizationMismatches []string              `json:"organization_mismatches,omitempty"`
+	OrganizationRegistry   *RegistryOrganization `json:"organization_registry,omitempty"`
+	// Результат проверки возраста для проверяющего (вычисляется, не хранится)
+	Age                 int  `json:"age"`
+	MeetsAgeRequirement bool `json:"meets_age_requirement"`
+}
+
+// RegistryOrganization сведения об организации из реестра юридических лиц
+type RegistryOrganization struct {
+	INN      string `json:"inn"`
+	OGRN     string `json:"ogrn"`
+	KPP      string `json:"kpp,omitempty"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name,omitempty"`
+	Address  string `json:"address,omitempty"`
+	// Состояние: ACTIVE, LIQUIDATING, LIQUIDATED, BANKRUPT, REORGANIZING
+	Status       string     `json:"status"`
+	RegisteredAt *time.Time `json:"registered_at,omitempty"`
+	// Некоммерческая организация по коду ОКОПФ
+	NonProfit bool `json:"non_profit"`
 }
 
 // Post модель поста
@@ -62,29 +215,181 @@ type Post struct {
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 	IsEditable  bool      `json:"is_editable" db:"is_editable"`
+	// Сбор в пользу несовершеннолетнего, создается родителем или опекуном
+	BeneficiaryIsMinor  bool    `json:"beneficiary_is_minor" db:"beneficiary_is_minor"`
+	GuardianDocumentURL *string `json:"-" db:"guardian_document_url"`
+	// Язык текста поста (ISO 639-1), определяется автоматически
+	Language string `json:"language,omitempty" db:"language"`
+	// Срок сбора: после него активный пост закрывается автоматически
+	Deadline *time.Time `json:"deadline,omitempty" db:"deadline"`
+	// Срочный сбор: рассматривается модератором вне очереди и после публикации
+	// до UrgentUntil показывается первым в ленте
+	Urgent            bool       `json:"urgent" db:"urgent"`
+	UrgentUntil       *time.Time `json:"urgent_until,omitempty" db:"urgent_until"`
+	UrgentDocumentURL *string    `json:"-" db:"urgent_document_url"`
 }
 
 // PostMedia модель медиа файла поста
 type PostMedia struct {
-	ID        int64     `json:"id"`
-	PostID    int64     `json:"post_id" db:"post_id"`
-	MediaURL  string    `json:"media_url" db:"media_url"`
-	MediaType string    `json:"media_type" db:"media_type"`
-	OrderIndex int      `json:"order_index" db:"order_index"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID         int64  `json:"id"`
+	PostID     int64  `json:"post_id" db:"post_id"`
+	MediaURL   string `json:"media_url" db:"media_url"`
+	MediaType  string `json:"media_type" db:"media_type"`
+	OrderIndex int    `json:"order_index" db:"order_index"`
+	// Уменьшенные копии изображения (для видео не создаются)
+	Variants  *ImageVariants `json:"variants,omitempty"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+}
+
+// BulkMediaResult результат загрузки одного файла в POST /posts/{id}/media/bulk
+type BulkMediaResult struct {
+	// Имя файла в форме или путь в zip архиве
+	Name  string     `json:"name"`
+	Media *PostMedia `json:"media,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// BulkMediaResponse отчет о массовой загрузке медиа поста
+type BulkMediaResponse struct {
+	Uploaded int               `json:"uploaded"`
+	Failed   int               `json:"failed"`
+	Files    []BulkMediaResult `json:"files"`
+}
+
+// PostLineItem статья расходов сбора (операция, дорога, реабилитация)
+type PostLineItem struct {
+	ID         int64   `json:"id"`
+	PostID     int64   `json:"post_id" db:"post_id"`
+	Title      string  `json:"title"`
+	Amount     float64 `json:"amount"`
+	OrderIndex int     `json:"order_index" db:"order_index"`
+	// Часть собранной суммы, приходящаяся на статью: статьи закрываются по порядку
+	Collected float64 `json:"collected"`
+}
+
+// PostProgressEvent событие progress канала поста: собранная сумма после подтверждения пожертвования
+type PostProgressEvent struct {
+	PostID    int64   `json:"post_id"`
+	Collected float64 `json:"collected"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"`
 }
 
 // Donation модель пожертвования
 type Donation struct {
 	ID          int64      `json:"id"`
 	PostID      int64      `json:"post_id" db:"post_id"`
-	DonorID     int64      `json:"donor_id" db:"donor_id"`
+	DonorID     int64      `json:"donor_id,omitempty" db:"donor_id"`
 	Amount      float64    `json:"amount"`
 	ReceiptURL  *string    `json:"receipt_url,omitempty" db:"receipt_url"`
 	Status      string     `json:"status"`
 	ConfirmedAt *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
-	ConfirmedBy *int64    `json:"confirmed_by,omitempty" db:"confirmed_by"`
+	ConfirmedBy *int64     `json:"confirmed_by,omitempty" db:"confirmed_by"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	// Сверка чека: pending, matched, mismatch или unreadable
+	ReceiptCheck     *string    `json:"receipt_check,omitempty" db:"receipt_check"`
+	ReceiptAmount    *float64   `json:"receipt_amount,omitempty" db:"receipt_amount"`
+	ReceiptDate      *time.Time `json:"receipt_date,omitempty" db:"receipt_date"`
+	ReceiptCheckedAt *time.Time `json:"receipt_checked_at,omitempty" db:"receipt_checked_at"`
+	// Анонимное пожертвование: донор виден только ему самому и администраторам,
+	// баллы рейтинга начисляются как обычно
+	IsAnonymous bool `json:"is_anonymous" db:"is_anonymous"`
+}
+
+// События жизненного цикла пожертвования
+const (
+	DonationEventCreated         = "created"
+	DonationEventReceiptAttached = "receipt_attached"
+	DonationEventConfirmed       = "confirmed"
+	DonationEventRejected        = "rejected"
+	// Отклонение подтвержденного пожертвования: собранная сумма и рейтинг донора уменьшаются
+	DonationEventReverted = "reverted"
+	// Отмена донором до подтверждения или возврат администратором, статус refunded окончательный
+	DonationEventRefunded = "refunded"
+)
+
+// DonationEvent неизменяемая запись об изменении пожертвования
+type DonationEvent struct {
+	ID         int64           `json:"id"`
+	DonationID int64           `json:"donation_id" db:"donation_id"`
+	Type       string          `json:"type" db:"event_type"`
+	ActorID    *int64          `json:"actor_id,omitempty" db:"actor_id"`
+	Metadata   json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Payment оплата пожертвования через платежный шлюз. У пожертвования может быть
+// несколько платежей (например, после отмены), но незавершенный - только один.
+type Payment struct {
+	ID                int64     `json:"id"`
+	DonationID        int64     `json:"donation_id" db:"donation_id"`
+	Provider          string    `json:"provider"`
+	ProviderPaymentID *string   `json:"provider_payment_id,omitempty" db:"provider_payment_id"`
+	Amount            float64   `json:"amount"`
+	Status            string    `json:"status"`
+	ConfirmationURL   *string   `json:"confirmation_url,omitempty" db:"confirmation_url"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MaxPaymentNoteTemplates ограничивает число шаблонов назначения платежа пользователя
+const MaxPaymentNoteTemplates = 20
+
+// PaymentNoteTemplate сохраненный текст назначения платежа. При ручном переводе клиент
+// подставляет его в банковское приложение; шаблоны общие для всех устройств пользователя.
+type PaymentNoteTemplate struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Периодичность подписки на пожертвования
+const (
+	SubscriptionWeekly  = "weekly"
+	SubscriptionMonthly = "monthly"
+)
+
+// Subscription регулярное пожертвование: в next_run_at создается пожертвование в статусе
+// pending, донор получает уведомление и оплачивает его или прикладывает чек
+type Subscription struct {
+	ID          int64   `json:"id"`
+	PostID      int64   `json:"post_id" db:"post_id"`
+	PostTitle   string  `json:"post_title"`
+	DonorID     int64   `json:"donor_id" db:"donor_id"`
+	Amount      float64 `json:"amount"`
+	Interval    string  `json:"interval" db:"period"`
+	IsAnonymous bool    `json:"is_anonymous" db:"is_anonymous"`
+	// Приостановленная подписка не создает пожертвований; при закрытии сбора останавливается сама
+	IsActive       bool       `json:"is_active" db:"is_active"`
+	NextRunAt      time.Time  `json:"next_run_at" db:"next_run_at"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastDonationID *int64     `json:"last_donation_id,omitempty" db:"last_donation_id"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// NextSubscriptionRun возвращает первый срок после now. Пропущенные периоды
+// (подписка была приостановлена) не наверстываются.
+func NextSubscriptionRun(interval string, from, now time.Time) time.Time {
+	next := from
+	for !next.After(now) {
+		if interval == SubscriptionWeekly {
+			next = next.AddDate(0, 0, 7)
+		} else {
+			next = next.AddDate(0, 1, 0)
+		}
+	}
+	return next
+}
+
+// SubscriptionRun результат срабатывания подписки планировщиком
+type SubscriptionRun struct {
+	Subscription Subscription
+	PostUserID   int64
+	// Созданное пожертвование; nil, если сбор закрыт и подписка остановлена
+	Donation *Donation
 }
 
 // Chat модель чата
@@ -110,6 +415,13 @@ type Message struct {
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// ChatDraft неотправленный текст сообщения пользователя в чате
+type ChatDraft struct {
+	ChatID    int64      `json:"chat_id"`
+	Text      string     `json:"text"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
 // Rating модель рейтинга
 type Rating struct {
 	ID          int64     `json:"id"`
@@ -120,6 +432,401 @@ type Rating struct {
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Источники изменений баллов рейтинга
+const (
+	RatingEventDonation = "donation"
+	// Подтверждение пожертвования отменено или пожертвование возвращено
+	RatingEventDonationReversal = "donation_reversal"
+	RatingEventReferral         = "referral"
+	// Пожертвование, за которое начислен реферальный бонус, отклонено или возвращено
+	RatingEventReferralReversal = "referral_reversal"
+	// Баллы, накопленные до появления журнала
+	RatingEventOpeningBalance = "opening_balance"
+)
+
+// RatingEvent запись журнала баллов рейтинга. Записи только добавляются, points и
+// total_donated в Rating - их сумма.
+
+type RatingEvent struct {
+	ID         int64   `json:"id"`
+	UserID     int64   `json:"user_id" db:"user_id"`
+	Source     string  `json:"source" enums:"donation,donation_reversal,referral,referral_reversal,opening_balance"`
+	DonationID *int64  `json:"donation_id,omitempty" db:"donation_id"`
+	PostID     *int64  `json:"post_id,omitempty" db:"post_id"`
+	PostTitle  *string `json:"post_title,omitempty" db:"post_title"`
+	ReferralID *int64  `json:"referral_id,omitempty" db:"referral_id"`
+	Points     int     `json:"points" db:"points_delta"`
+	Amount     float64 `json:"amount" db:"amount_delta"`
+	// Баллы после изменения
+	Balance   int       `json:"balance"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Notification системное уведомление пользователя
+type Notification struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	PostID    *int64    `json:"post_id,omitempty" db:"post_id"`
+	IsRead    bool      `json:"is_read" db:"is_read"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Data дополнительные поля push уведомления (не сохраняются)
+	Data map[string]string `json:"-"`
+}
+
+// Типы уведомлений
+const (
+	NotificationPostMilestone        = "post_milestone"
+	NotificationDonationConfirmed    = "donation_confirmed"
+	NotificationNewMessage           = "new_message"
+	NotificationVerificationRejected = "verification_rejected"
+	NotificationPostClosed           = "post_closed"
+	NotificationAccountBlocked       = "account_blocked"
+	NotificationAccountUnblocked     = "account_unblocked"
+	NotificationPostLimitReviewed    = "post_limit_reviewed"
+	NotificationReferralBonus        = "referral_bonus"
+	NotificationCollaborationInvite  = "collaboration_invite"
+	NotificationCollaborationReply   = "collaboration_reply"
+	NotificationPostComment          = "post_comment"
+	NotificationPostExpired          = "post_expired"
+	NotificationPostQuestion         = "post_question"
+	NotificationQuestionAnswered     = "question_answered"
+	NotificationSubscriptionDue      = "subscription_due"
+	NotificationSubscriptionStopped  = "subscription_stopped"
+)
+
+// NotificationTypes типы уведомлений, для которых настраиваются каналы доставки
+var NotificationTypes = []string{
+	NotificationPostMilestone,
+	NotificationDonationConfirmed,
+	NotificationNewMessage,
+	NotificationVerificationRejected,
+	NotificationPostClosed,
+	NotificationAccountBlocked,
+	NotificationAccountUnblocked,
+	NotificationPostLimitReviewed,
+	NotificationReferralBonus,
+	NotificationCollaborationInvite,
+	NotificationCollaborationReply,
+	NotificationPostComment,
+	NotificationPostExpired,
+	NotificationPostQuestion,
+	NotificationQuestionAnswered,
+	NotificationSubscriptionDue,
+	NotificationSubscriptionStopped,
+}
+
+// Внешние каналы доставки уведомлений. Уведомление в приложении сохраняется всегда.
+const (
+	NotificationChannelPush = "push"
+	NotificationChannelSMS  = "sms"
+)
+
+// smsNotificationTypes уведомления, которые можно получать по SMS. SMS платные,
+// поэтому канал доступен только для квитанций о пожертвованиях.
+var smsNotificationTypes = map[string]bool{
+	NotificationDonationConfirmed: true,
+}
+
+// NotificationPreference каналы доставки уведомлений одного типа
+type NotificationPreference struct {
+	Type string `json:"type" validate:"required"`
+	Push bool   `json:"push"`
+	SMS  bool   `json:"sms"`
+	// Можно ли включить SMS для этого типа
+	SMSAvailable bool `json:"sms_available"`
+}
+
+// DefaultNotificationPreference каналы уведомлений, которые пользователь не настраивал:
+// push включен, SMS выключены
+func DefaultNotificationPreference(notificationType string) NotificationPreference {
+	return NotificationPreference{
+		Type:         notificationType,
+		Push:         true,
+		SMSAvailable: smsNotificationTypes[notificationType],
+	}
+}
+
+// Enabled проверяет, включен ли канал
+func (p NotificationPreference) Enabled(channel string) bool {
+	switch channel {
+	case NotificationChannelPush:
+		return p.Push
+	case NotificationChannelSMS:
+		return p.SMS
+	}
+	return false
+}
+
+// UpdateNotificationPreferencesRequest запрос на изменение каналов уведомлений.
+// Типы, которых нет в запросе, не меняются.
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreference `json:"preferences" validate:"required,min=1,dive"`
+}
+
+// Device устройство пользователя для push уведомлений
+type Device struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id" db:"user_id"`
+	Token      string    `json:"token"`
+	Platform   string    `json:"platform"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// APIToken персональный токен доступа для ботов и автоматизации (хранится только хеш)
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Prefix     string     `json:"prefix" db:"token_prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// HasScope проверяет, что токену выдана область действия
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Integration личный канал оповещений пользователя (webhook или Telegram чат)
+type Integration struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Type      string    `json:"type"`
+	Target    string    `json:"target"`
+	Secret    string    `json:"-"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Типы интеграций
+const (
+	IntegrationWebhook  = "webhook"
+	IntegrationTelegram = "telegram"
+)
+
+// WebhookDelivery событие в очереди доставки. Событие адресовано либо интеграции
+// пользователя (IntegrationID), либо вебхуку партнера (WebhookID); Integration
+// содержит адрес и секрет получателя в обоих случаях.
+type WebhookDelivery struct {
+	ID            int64
+	IntegrationID *int64
+	WebhookID     *int64
+	Event         string
+	Payload       []byte
+	Summary       string
+	Attempts      int
+	Integration   Integration
+}
+
+// События, доставляемые через интеграции
+const (
+	WebhookEventDonationCreated = "donation.created"
+)
+
+// DonationWebhookPayload тело события о новом пожертвовании
+type DonationWebhookPayload struct {
+	Event      string    `json:"event"`
+	DonationID int64     `json:"donation_id"`
+	PostID     int64     `json:"post_id"`
+	PostTitle  string    `json:"post_title"`
+	Amount     float64   `json:"amount"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// События платформы для вебхуков партнеров (кроме donation.created)
+const (
+	WebhookEventPostCreated          = "post.created"
+	WebhookEventDonationConfirmed    = "donation.confirmed"
+	WebhookEventVerificationApproved = "verification.approved"
+	WebhookEventVerificationRejected = "verification.rejected"
+)
+
+// PartnerWebhook адрес партнера, на который отправляются события платформы,
+// на которые он подписан. Тело подписывается секретом так же, как в личных интеграциях.
+type PartnerWebhook struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	CreatedBy *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PartnerWebhookPayload тело события вебхука партнера
+type PartnerWebhookPayload struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDeliveryInfo попытки доставки события на вебхук партнера
+type WebhookDeliveryInfo struct {
+	ID            int64      `json:"id"`
+	Event         string     `json:"event"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     *string    `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RefreshToken модель refresh токена (хранится только хеш)
+type RefreshToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ReplacedBy *int64     `json:"replaced_by,omitempty" db:"replaced_by"`
+	UserAgent  *string    `json:"user_agent,omitempty" db:"user_agent"`
+	IPAddress  *string    `json:"ip_address,omitempty" db:"ip_address"`
+}
+
+// Role роль с набором прав
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// RiskTier уровень доверия автора с максимальной целевой суммой поста
+type RiskTier struct {
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	MaxPostAmount float64 `json:"max_post_amount" db:"max_post_amount"`
+	// Число завершенных сборов для автоматического назначения; nil - только вручную
+	MinCompletedPosts *int `json:"min_completed_posts" db:"min_completed_posts"`
+}
+
+// PostLimit действующий лимит целевой суммы автора
+type PostLimit struct {
+	Tier      string  `json:"tier"`
+	MaxAmount float64 `json:"max_amount"`
+	// Уровень назначен администратором, а не определен автоматически
+	Assigned bool `json:"assigned"`
+}
+
+// StorageUsage занятое место в хранилище и квота пользователя
+type StorageUsage struct {
+	UsedBytes int64 `json:"used_bytes"`
+	// 0 - квота не ограничена
+	LimitBytes int64 `json:"limit_bytes"`
+}
+
+// QuotaResponse лимит запросов и квота хранилища текущего клиента
+type QuotaResponse struct {
+	// null, если лимит запросов отключен
+	RateLimit *RateLimitStatus `json:"rate_limit"`
+	Storage   StorageUsage     `json:"storage"`
+}
+
+// Статусы запросов на исключение из лимита
+const (
+	PostLimitRequestPending  = "pending"
+	PostLimitRequestApproved = "approved"
+	PostLimitRequestRejected = "rejected"
+)
+
+// PostLimitRequest запрос автора на сбор сверх лимита своего уровня доверия
+type PostLimitRequest struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	Amount     float64    `json:"amount"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	Comment    *string    `json:"comment,omitempty"`
+	ReviewedBy *int64     `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	// Пост, на который израсходовано одобренное исключение
+	PostID    *int64     `json:"post_id,omitempty" db:"post_id"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// LoginEvent попытка входа пользователя
+type LoginEvent struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Success   bool      `json:"success"`
+	IPAddress *string   `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent *string   `json:"user_agent,omitempty" db:"user_agent"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// InviteCode код приглашения. Uses - число регистраций по коду.
+type InviteCode struct {
+	ID        int64      `json:"id"`
+	Code      string     `json:"code"`
+	Batch     string     `json:"batch"`
+	MaxUses   int        `json:"max_uses" db:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedBy *int64     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// InvitedUser пользователь, зарегистрированный по коду приглашения
+type InvitedUser struct {
+	ID        int64     `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Статусы приглашений реферальной программы
+const (
+	ReferralPending  = "pending"
+	ReferralRewarded = "rewarded"
+	ReferralRejected = "rejected"
+)
+
+// Причины отказа в реферальном бонусе
+const (
+	ReferralRejectSameIP     = "same_ip"
+	ReferralRejectSameDevice = "same_device"
+)
+
+// Referral пользователь, зарегистрированный по реферальной ссылке
+type Referral struct {
+	ID           int64      `json:"id"`
+	ReferrerID   int64      `json:"referrer_id" db:"referrer_id"`
+	RefereeID    int64      `json:"referee_id" db:"referee_id"`
+	FirstName    string     `json:"first_name" db:"first_name"`
+	Status       string     `json:"status"`
+	RejectReason *string    `json:"reject_reason,omitempty" db:"reject_reason"`
+	BonusPoints  int        `json:"bonus_points" db:"bonus_points"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	RewardedAt   *time.Time `json:"rewarded_at,omitempty" db:"rewarded_at"`
+}
+
+// ReferralDashboard реферальный код пользователя и приглашенные им пользователи
+type ReferralDashboard struct {
+	Code        string     `json:"code"`
+	Link        string     `json:"link"`
+	Invited     int        `json:"invited"`
+	Rewarded    int        `json:"rewarded"`
+	BonusPoints int        `json:"bonus_points"`
+	Referrals   []Referral `json:"referrals"`
+}
+
 // File модель файла (старая, оставляем для совместимости)
 type File struct {
 	ID              int       `json:"id"`
@@ -138,13 +845,46 @@ type RegisterRequest struct {
 	Password  string `json:"password" validate:"required,min=6"`
 	FirstName string `json:"first_name" validate:"required"`
 	LastName  string `json:"last_name" validate:"required"`
+	// Код приглашения; обязателен, если регистрация только по приглашениям
+	InviteCode string `json:"invite_code,omitempty"`
+	// Реферальный код пригласившего пользователя
+	ReferralCode string `json:"referral_code,omitempty"`
 }
 
 // RegisterResponse ответ на регистрацию
 type RegisterResponse struct {
-	UserID  int64  `json:"user_id"`
-	Token   string `json:"token"`
-	Message string `json:"message"`
+	UserID       int64  `json:"user_id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	Message      string `json:"message"`
+}
+
+// CreateInviteCodesRequest запрос на выпуск партии кодов приглашения
+type CreateInviteCodesRequest struct {
+	Batch     string     `json:"batch" validate:"required,max=100"`
+	Count     int        `json:"count" validate:"required,min=1,max=1000"`
+	MaxUses   int        `json:"max_uses" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// PointsEvent акция, во время которой за пожертвования начисляется в Multiplier раз
+// больше баллов рейтинга (например, «двойные баллы на выходных»)
+type PointsEvent struct {
+	ID         int64     `json:"id"`
+	Title      string    `json:"title"`
+	Multiplier float64   `json:"multiplier"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	CreatedBy  *int64    `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreatePointsEventRequest запрос на создание акции
+type CreatePointsEventRequest struct {
+	Title      string    `json:"title" validate:"required,max=100"`
+	Multiplier float64   `json:"multiplier" validate:"required,gt=1,lte=10"`
+	StartsAt   time.Time `json:"starts_at" validate:"required"`
+	EndsAt     time.Time `json:"ends_at" validate:"required"`
 }
 
 // LoginRequest запрос на вход
@@ -155,14 +895,68 @@ type LoginRequest struct {
 
 // LoginResponse ответ на вход
 type LoginResponse struct {
-	UserID int64      `json:"user_id"`
-	Token  string     `json:"token"`
-	User   *User      `json:"user"`
+	UserID       int64  `json:"user_id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	User         *User  `json:"user"`
+}
+
+// OTPCode одноразовый код подтверждения, отправленный по SMS
+type OTPCode struct {
+	ID         int64      `json:"id"`
+	Phone      string     `json:"phone"`
+	Purpose    string     `json:"purpose"`
+	CodeHash   string     `json:"-"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Attempts   int        `json:"attempts"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Назначения одноразовых кодов
+const (
+	OTPPurposeVerifyPhone   = "verify_phone"
+	OTPPurposeResetPassword = "reset_password"
+)
+
+// RequestOTPRequest запрос на отправку кода подтверждения
+type RequestOTPRequest struct {
+	Phone string `json:"phone" validate:"required"`
+}
+
+// VerifyOTPRequest запрос на подтверждение телефона кодом
+type VerifyOTPRequest struct {
+	Phone string `json:"phone" validate:"required"`
+	Code  string `json:"code" validate:"required"`
+}
+
+// ForgotPasswordRequest запрос на восстановление пароля
+type ForgotPasswordRequest struct {
+	Phone string `json:"phone" validate:"required"`
+}
+
+// ResetPasswordRequest запрос на установку нового пароля по коду
+type ResetPasswordRequest struct {
+	Phone       string `json:"phone" validate:"required"`
+	Code        string `json:"code" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// RefreshTokenRequest запрос на обновление токена
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest запрос на выход из системы
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+	AllSessions  bool   `json:"all_sessions,omitempty"`
 }
 
 // RefreshTokenResponse ответ на обновление токена
 type RefreshTokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // UpdateProfileRequest запрос на обновление профиля
@@ -170,6 +964,8 @@ type UpdateProfileRequest struct {
 	FirstName  *string `json:"first_name,omitempty"`
 	LastName   *string `json:"last_name,omitempty"`
 	HelperName *string `json:"helper_name,omitempty"`
+	// Часовой пояс IANA, например Europe/Moscow
+	Timezone *string `json:"timezone,omitempty" example:"Europe/Moscow"`
 }
 
 // ChangePasswordRequest запрос на изменение пароля
@@ -178,19 +974,22 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password" validate:"required,min=6"`
 }
 
-// VerificationRequest запрос на верификацию (multipart)
+// SetAwayRequest запрос на включение режима "нет на месте"
+type SetAwayRequest struct {
+	Message string     `json:"message" validate:"required,max=1000"`
+	Until   *time.Time `json:"until,omitempty"`
+}
+
+// VerificationRequest запрос на верификацию (multipart). Поля документов
+// проверяются по справочнику DocumentCountries.
 type VerificationRequest struct {
 	LastName        string    `form:"last_name" validate:"required"`
 	FirstName       string    `form:"first_name" validate:"required"`
 	MiddleName      *string   `form:"middle_name"`
 	BirthDate       string    `form:"birth_date" validate:"required"`
-	PassportSeries  string    `form:"passport_series" validate:"required"`
-	PassportNumber  string    `form:"passport_number" validate:"required"`
-	PassportIssuer  string    `form:"passport_issuer" validate:"required"`
-	PassportDate    string    `form:"passport_date" validate:"required"`
-	DocType         string    `form:"doc_type" validate:"required,oneof=inn snils"`
-	INN             *string   `form:"inn"`
-	SNILS           *string   `form:"snils"`
+	Country         string    `form:"country"`
+	DocumentType    string    `form:"document_type"`
+	DocType         string    `form:"doc_type"`
 	Consent1        bool      `form:"consent1"`
 	Consent2        bool      `form:"consent2"`
 	Consent3        bool      `form:"consent3"`
@@ -199,7 +998,7 @@ type VerificationRequest struct {
 // UpdateVerificationRequest запрос на обновление статуса верификации
 type UpdateVerificationRequest struct {
 	Status         string  `json:"status" validate:"required,oneof=approved rejected"`
-	RejectionReason *string `json:"rejection_reason,omitempty"`
+	RejectionReason *string `json:"rejection_reason,omitempty" validate:"omitempty,max=500"`
 }
 
 // CreatePostRequest запрос на создание поста
@@ -210,6 +1009,128 @@ type CreatePostRequest struct {
 	Recipient   string  `form:"recipient" validate:"required"`
 	Bank        string  `form:"bank" validate:"required"`
 	Phone       string  `form:"phone" validate:"required"`
+	BeneficiaryIsMinor bool `form:"beneficiary_is_minor"`
+	GuardianConsent    bool `form:"guardian_consent"`
+	// Срочный сбор требует документа, подтверждающего срочность, и одобренной верификации автора
+	Urgent bool `form:"urgent"`
+	// Срок сбора в RFC 3339
+	Deadline string `form:"deadline"`
+	// Статьи расходов, передаются JSON строкой; сумма должна совпадать с Amount
+	LineItems []LineItemRequest `form:"line_items" validate:"max=20,dive"`
+}
+
+// LineItemRequest статья расходов в запросе на создание или изменение поста
+type LineItemRequest struct {
+	Title  string  `json:"title" validate:"required,max=200"`
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+}
+
+// SaveRoleRequest запрос на создание или изменение роли
+type SaveRoleRequest struct {
+	Description string   `json:"description" validate:"max=200"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+// UpdateUserRoleRequest запрос на смену роли пользователя
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" validate:"required,max=20"`
+}
+
+// SaveRiskTierRequest запрос на создание или изменение уровня доверия
+type SaveRiskTierRequest struct {
+	Description       string  `json:"description" validate:"max=200"`
+	MaxPostAmount     float64 `json:"max_post_amount" validate:"required,gt=0"`
+	MinCompletedPosts *int    `json:"min_completed_posts" validate:"omitempty,gte=0"`
+}
+
+// UpdateUserRiskTierRequest запрос на назначение уровня доверия, null - автоматический выбор
+type UpdateUserRiskTierRequest struct {
+	Tier *string `json:"tier" validate:"omitempty,max=20"`
+}
+
+// CreatePostLimitRequestRequest запрос автора на исключение из лимита целевой суммы
+type CreatePostLimitRequestRequest struct {
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+	Reason string  `json:"reason" validate:"required,min=10,max=2000"`
+}
+
+// ReviewPostLimitRequestRequest решение администратора по запросу на исключение
+type ReviewPostLimitRequestRequest struct {
+	Status  string  `json:"status" validate:"required,oneof=approved rejected"`
+	Comment *string `json:"comment,omitempty" validate:"omitempty,max=500"`
+}
+
+// DonationSummary сводка пожертвований пользователя
+type DonationSummary struct {
+	Count           int     `json:"count"`
+	ConfirmedCount  int     `json:"confirmed_count"`
+	ConfirmedAmount float64 `json:"confirmed_amount"`
+}
+
+
+// AdminUserDetailResponse сводка по пользователю для модерации
+type AdminUserDetailResponse struct {
+	User              *User              `json:"user"`
+	Verification      *Verification      `json:"verification,omitempty"`
+	Posts             []Post             `json:"posts"`
+	PostsTotal        int                `json:"posts_total"`
+	DonationsGiven    DonationSummary    `json:"donations_given"`
+	DonationsReceived DonationSummary    `json:"donations_received"`
+	ChatsCount        int                `json:"chats_count"`
+	Sessions          []RefreshToken     `json:"sessions"`
+	LoginHistory      []LoginEvent       `json:"login_history"`
+	AdminActions      []AdminActionEntry `json:"admin_actions"`
+}
+
+// AdminUserFilter фильтры списка пользователей. Query ищет по телефону, если состоит из цифр, иначе по имени.
+// VerificationStatus none - пользователи без заявки на верификацию.
+type AdminUserFilter struct {
+	Query              string
+	Role               string
+	VerificationStatus string
+	IsActive           *bool
+}
+
+// AdminUserListItem пользователь в списке администратора
+type AdminUserListItem struct {
+	ID                 int64      `json:"id"`
+	Phone              string     `json:"phone"`
+	FirstName          string     `json:"first_name"`
+	LastName           string     `json:"last_name"`
+	Role               string     `json:"role"`
+	IsActive           bool       `json:"is_active"`
+	PhoneVerified      bool       `json:"phone_verified"`
+	VerificationStatus *string    `json:"verification_status,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	LastLoginAt        *time.Time `json:"last_login_at,omitempty"`
+}
+
+// ModeratePostRequest решение по посту на модерации
+type ModeratePostRequest struct {
+	Status string `json:"status" validate:"required,oneof=active closed"`
+	// Обязательна при закрытии поста, передается автору в уведомлении
+	Reason string `json:"reason,omitempty" validate:"max=500"`
+}
+
+// BlockUserRequest запрос на блокировку пользователя
+type BlockUserRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// UpdateAdminUserRequest запрос на деактивацию или повторную активацию аккаунта
+type UpdateAdminUserRequest struct {
+	IsActive *bool  `json:"is_active" validate:"required"`
+	Reason   string `json:"reason" validate:"max=500"`
+}
+
+// ModerationPostResponse пост на модерации с документами законного представителя и срочного сбора
+type ModerationPostResponse struct {
+	Post
+	GuardianDocumentURL *string `json:"guardian_document_url,omitempty"`
+	UrgentDocumentURL   *string `json:"urgent_document_url,omitempty"`
+	// Срок рассмотрения срочного сбора и его нарушение
+	ReviewDeadline *time.Time `json:"review_deadline,omitempty"`
+	Overdue        bool       `json:"overdue,omitempty"`
 }
 
 // UpdatePostRequest запрос на обновление поста
@@ -220,17 +1141,185 @@ type UpdatePostRequest struct {
 	Recipient   *string  `json:"recipient,omitempty"`
 	Bank        *string  `json:"bank,omitempty"`
 	Phone       *string  `json:"phone,omitempty"`
+	// Новый срок сбора в RFC 3339, пустая строка убирает срок
+	Deadline *string `json:"deadline,omitempty" example:"2025-03-01T21:00:00Z"`
+	// Новый список статей расходов, пустой список удаляет разбивку
+	LineItems *[]LineItemRequest `json:"line_items,omitempty" validate:"omitempty,max=20,dive"`
 }
 
 // CreateDonationRequest запрос на создание пожертвования
 type CreateDonationRequest struct {
-	PostID  int64   `form:"post_id" validate:"required"`
-	Amount  float64 `form:"amount" validate:"required,gt=0"`
+	PostID      int64   `form:"post_id" validate:"required"`
+	Amount      float64 `form:"amount" validate:"required,gt=0"`
+	IsAnonymous bool    `form:"is_anonymous"`
 }
 
 // UpdateDonationRequest запрос на обновление статуса пожертвования
 type UpdateDonationRequest struct {
-	Status string `json:"status" validate:"required,oneof=confirmed rejected"`
+	Status string `json:"status" validate:"required,oneof=confirmed rejected refunded"`
+}
+
+// Права соавтора поста
+const (
+	// Редактирование поста и его медиа
+	CollaboratorEdit = "edit"
+	// Переписка в чатах поста от имени автора
+	CollaboratorChats = "chats"
+	// Подтверждение и отклонение пожертвований, сверка по выписке
+	CollaboratorDonations = "donations"
+)
+
+// PostCollaborator соавтор поста (например, родственник подопечного). Права действуют
+// после того, как приглашенный примет приглашение (status accepted).
+type PostCollaborator struct {
+	ID          int64      `json:"id"`
+	PostID      int64      `json:"post_id"`
+	PostTitle   string     `json:"post_title,omitempty"`
+	User        UserInfo   `json:"user"`
+	InvitedBy   *int64     `json:"invited_by,omitempty"`
+	Permissions []string   `json:"permissions"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+// InviteCollaboratorRequest приглашение зарегистрированного пользователя в соавторы по телефону
+type InviteCollaboratorRequest struct {
+	Phone       string   `json:"phone" validate:"required"`
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,oneof=edit chats donations"`
+}
+
+// UpdateCollaboratorRequest новые права соавтора
+type UpdateCollaboratorRequest struct {
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,oneof=edit chats donations"`
+}
+
+// RespondCollaborationRequest ответ на приглашение в соавторы
+type RespondCollaborationRequest struct {
+	Status string `json:"status" validate:"required,oneof=accepted declined"`
+}
+
+// HiddenPost пост, скрытый пользователем из ленты
+type HiddenPost struct {
+	PostID   int64     `json:"post_id"`
+	Title    string    `json:"title"`
+	HiddenAt time.Time `json:"hidden_at"`
+}
+
+// MutedAuthor автор, посты которого пользователь не видит в ленте
+type MutedAuthor struct {
+	Author  UserInfo  `json:"author"`
+	MutedAt time.Time `json:"muted_at"`
+}
+
+// HidePostRequest запрос на скрытие поста из ленты
+type HidePostRequest struct {
+	PostID int64 `json:"post_id" validate:"required"`
+}
+
+// MuteAuthorRequest запрос на скрытие постов автора из ленты
+type MuteAuthorRequest struct {
+	AuthorID int64 `json:"author_id" validate:"required"`
+}
+
+// PostComment публичный комментарий к посту
+type PostComment struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	Author    UserInfo  `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateCommentRequest запрос на создание комментария
+type CreateCommentRequest struct {
+	Text string `json:"text" validate:"required,max=2000"`
+}
+
+// PostCommentsResponse список комментариев поста
+type PostCommentsResponse struct {
+	Data       []PostComment      `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+// PostQuestion вопрос к автору поста. Пока нет ответа, вопрос видят только автор,
+// соавторы с правом edit и модераторы.
+type PostQuestion struct {
+	ID         int64      `json:"id"`
+	PostID     int64      `json:"post_id"`
+	Author     UserInfo   `json:"author"`
+	Question   string     `json:"question"`
+	Answer     *string    `json:"answer,omitempty"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateQuestionRequest запрос на вопрос автору поста
+type CreateQuestionRequest struct {
+	Question string `json:"question" validate:"required,max=1000"`
+}
+
+// AnswerQuestionRequest ответ автора на вопрос
+type AnswerQuestionRequest struct {
+	Answer string `json:"answer" validate:"required,max=4000"`
+}
+
+// PostQuestionsResponse список вопросов поста
+type PostQuestionsResponse struct {
+	Data       []PostQuestion     `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+// Статусы строк выписки
+const (
+	StatementRowUnmatched = "unmatched"
+	StatementRowProposed  = "proposed"
+	StatementRowConfirmed = "confirmed"
+	// Пожертвование к моменту подтверждения уже не ожидало подтверждения
+	StatementRowSkipped = "skipped"
+)
+
+// StatementImport загруженная выписка банка для сверки пожертвований поста
+type StatementImport struct {
+	ID         int64  `json:"id"`
+	PostID     int64  `json:"post_id"`
+	UploadedBy int64  `json:"uploaded_by"`
+	FileName   string `json:"file_name"`
+	// Строки, которые не удалось разобрать, и исходящие платежи
+	RowsSkipped int       `json:"rows_skipped"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// StatementRow входящий перевод из выписки и предложенное для него пожертвование.
+// Reasons: amount, same_day, date_close, phone, name.
+type StatementRow struct {
+	ID          int64      `json:"id"`
+	RowNumber   int        `json:"row_number"`
+	Date        time.Time  `json:"date"`
+	Amount      float64    `json:"amount"`
+	Payer       string     `json:"payer,omitempty"`
+	Description string     `json:"description,omitempty"`
+	DonationID  *int64     `json:"donation_id,omitempty"`
+	Confidence  float64    `json:"confidence"`
+	Reasons     []string   `json:"reasons"`
+	Status      string     `json:"status"`
+	ReviewedBy  *int64     `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// StatementImportResponse выписка со строками и предложенными подтверждениями
+type StatementImportResponse struct {
+	Import    *StatementImport `json:"import"`
+	Rows      []StatementRow   `json:"rows"`
+	Proposed  int              `json:"proposed"`
+	Unmatched int              `json:"unmatched"`
+}
+
+// ConfirmStatementRequest подтверждение предложенных пожертвований: выбранные строки
+// и/или все строки с уверенностью не ниже min_confidence
+type ConfirmStatementRequest struct {
+	RowIDs        []int64  `json:"row_ids" validate:"max=1000"`
+	MinConfidence *float64 `json:"min_confidence,omitempty" validate:"omitempty,gt=0,lte=1"`
 }
 
 // CreateChatRequest запрос на создание чата
@@ -253,6 +1342,55 @@ type UpdateMessageRequest struct {
 	Text string `json:"text" validate:"required"`
 }
 
+// SaveChatDraftRequest запрос на сохранение черновика. Пустой текст удаляет черновик.
+type SaveChatDraftRequest struct {
+	Text string `json:"text" validate:"max=4000"`
+}
+
+// SavePaymentNoteTemplateRequest запрос на создание или изменение шаблона назначения платежа.
+// 210 символов - предел поля назначения платежа в банковских приложениях.
+type SavePaymentNoteTemplateRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+	Text string `json:"text" validate:"required,max=210"`
+}
+
+// CreateSubscriptionRequest запрос на подписку. Без start_at первое пожертвование
+// создается при ближайшем запуске планировщика.
+type CreateSubscriptionRequest struct {
+	PostID      int64      `json:"post_id" validate:"required"`
+	Amount      float64    `json:"amount" validate:"required,gt=0"`
+	Interval    string     `json:"interval" validate:"required,oneof=weekly monthly"`
+	IsAnonymous bool       `json:"is_anonymous"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+}
+
+// UpdateSubscriptionRequest запрос на изменение подписки, пустые поля не меняются
+type UpdateSubscriptionRequest struct {
+	Amount      *float64 `json:"amount,omitempty" validate:"omitempty,gt=0"`
+	Interval    *string  `json:"interval,omitempty" validate:"omitempty,oneof=weekly monthly"`
+	IsAnonymous *bool    `json:"is_anonymous,omitempty"`
+	IsActive    *bool    `json:"is_active,omitempty"`
+}
+
+// Типы документов заявки на верификацию
+const (
+	VerificationDocumentPassportScan = "passport_scan"
+	VerificationDocumentUserPhoto    = "user_photo"
+)
+
+// VerificationDocument ссылка на документ заявки на верификацию
+type VerificationDocument struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// VerificationDocumentsResponse короткоживущие ссылки на документы заявки на верификацию
+type VerificationDocumentsResponse struct {
+	VerificationID int64                  `json:"verification_id"`
+	Documents      []VerificationDocument `json:"documents"`
+	ExpiresAt      time.Time              `json:"expires_at"`
+}
+
 // PresignedURLRequest запрос на получение presigned URL
 type PresignedURLRequest struct {
 	Bucket      string `json:"bucket" validate:"required"`
@@ -263,9 +1401,11 @@ type PresignedURLRequest struct {
 
 // PresignedURLResponse ответ с presigned URL
 type PresignedURLResponse struct {
-	UploadURL  string    `json:"upload_url"`
-	ObjectURL  string    `json:"object_url"`
-	ExpiresAt  time.Time `json:"expires_at"`
+	UploadURL string `json:"upload_url"`
+	// Ключ, под которым будет сохранен файл (uploads/{user_id}/...)
+	ObjectKey string    `json:"object_key"`
+	ObjectURL string    `json:"object_url"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // PresignedGetURLRequest запрос на получение presigned URL для чтения
@@ -295,18 +1435,76 @@ type PaginationResponse struct {
 	TotalPages int `json:"total_pages"`
 }
 
+// PostFilter фильтры списка постов, нулевые значения не применяются
+type PostFilter struct {
+	Status   string
+	UserID   *int64
+	Search   string
+	Language string
+	// Пользователь, которому не показываются скрытые им посты и авторы
+	ViewerID int64
+	// Посты со сроком не позже DeadlineBefore
+	DeadlineBefore *time.Time
+	// Порядок PostSort*: пусто - срочные и новые первыми, при поиске - по релевантности
+	Sort string
+}
+
+// Порядок ленты постов
+const (
+	PostSortNewest        = "newest"
+	PostSortUrgent        = "urgent"
+	PostSortMostCollected = "most_collected"
+	PostSortLeastDonated  = "least_donated"
+	PostSortClosestToGoal = "closest_to_goal"
+	PostSortDeadline      = "deadline"
+)
+
+// ExpiredPost пост, закрытый по истечении срока
+type ExpiredPost struct {
+	ID       int64
+	UserID   int64
+	Title    string
+	Deadline time.Time
+	// Часовой пояс автора для даты в уведомлении
+	Timezone string
+}
+
+// PostDeadline срок сбора в календаре автора
+type PostDeadline struct {
+	PostID    int64
+	Title     string
+	Status    string
+	Deadline  time.Time
+	UpdatedAt time.Time
+}
+
 // PostWithDetails пост с деталями (автор, медиа)
 type PostWithDetails struct {
 	Post
 	Author *UserInfo      `json:"author,omitempty"`
 	Media  []PostMedia    `json:"media,omitempty"`
+	// Разбивка целевой суммы по статьям расходов
+	LineItems []PostLineItem `json:"line_items,omitempty"`
+	// Вопросы с ответом автора (публичные)
+	AnsweredQuestions int `json:"answered_questions"`
+	// Вопросы без ответа, только для автора поста и соавторов с правом edit
+	UnansweredQuestions *int `json:"unanswered_questions,omitempty"`
 }
 
 // UserInfo краткая информация о пользователе
 type UserInfo struct {
-	ID    int64   `json:"id"`
-	Name  string  `json:"name"`
-	Avatar *string `json:"avatar,omitempty"`
+	ID     int64       `json:"id"`
+	Name   string      `json:"name"`
+	Avatar *string     `json:"avatar,omitempty"`
+	Away   *AwayStatus `json:"away,omitempty"`
+	// Время ответа автора поста (если набралось достаточно чатов)
+	ResponseTime *ResponseTime `json:"response_time,omitempty"`
+}
+
+// ResponseTime медиана времени первого ответа автора в чатах
+type ResponseTime struct {
+	MedianMinutes int    `json:"median_minutes"`
+	Hint          string `json:"hint"`
 }
 
 // DonationWithDetails пожертвование с деталями
@@ -352,6 +1550,217 @@ type HealthCheckResponse struct {
 	Timestamp string                 `json:"timestamp"`
 	Database  string                 `json:"database"`
 	MinIO     string                 `json:"minio"`
+	Backup    string                 `json:"backup"`
+}
+
+// DeepHealthResponse подробная диагностика /health?deep=true для дежурного
+type DeepHealthResponse struct {
+	Status    string            `json:"status"`
+	Timestamp string            `json:"timestamp"`
+	Database  HealthCheckTiming `json:"database"`
+	MinIO     MinIOHealthCheck  `json:"minio"`
+	Breakers  map[string]string `json:"breakers"`
+	Backlog   HealthBacklog     `json:"backlog"`
+	Jobs      []JobStatus       `json:"jobs"`
+}
+
+// HealthCheckTiming результат и длительность проверки зависимости
+type HealthCheckTiming struct {
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// MinIOHealthCheck длительность записи, чтения и удаления пробного объекта
+type MinIOHealthCheck struct {
+	Status   string  `json:"status"`
+	PutMS    float64 `json:"put_ms"`
+	GetMS    float64 `json:"get_ms"`
+	DeleteMS float64 `json:"delete_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// HealthBacklog очереди фоновой доставки
+type HealthBacklog struct {
+	NotificationQueue         int        `json:"notification_queue"`
+	NotificationQueueCapacity int        `json:"notification_queue_capacity"`
+	WebhookPending            int        `json:"webhook_pending"`
+	WebhookOldestPendingAt    *time.Time `json:"webhook_oldest_pending_at"`
+}
+
+// RetentionReport результат применения сроков хранения. В режиме dry_run - число записей,
+// которые будут затронуты при следующем запуске. Пустая граница - очистка отключена.
+type RetentionReport struct {
+	DryRun  bool `json:"dry_run"`
+	Enabled bool `json:"enabled"`
+	// Аккаунты без входа до InactiveBefore: обезличиваются
+	InactiveAccounts int        `json:"inactive_accounts"`
+	InactiveBefore   *time.Time `json:"inactive_before,omitempty"`
+	// Неподтвержденные регистрации без входа до UnverifiedBefore: удаляются
+	UnverifiedAccounts int        `json:"unverified_accounts"`
+	UnverifiedBefore   *time.Time `json:"unverified_before,omitempty"`
+	// Коды подтверждения, истекшие до OTPExpiredBefore
+	ExpiredOTPCodes  int        `json:"expired_otp_codes"`
+	OTPExpiredBefore *time.Time `json:"otp_expired_before,omitempty"`
+	// Вложения сообщений, отправленных до ChatAttachmentsBefore
+	ChatAttachments       int        `json:"chat_attachments"`
+	ChatAttachmentsBefore *time.Time `json:"chat_attachments_before,omitempty"`
+	// Заявки на верификацию обезличиваемых аккаунтов удаляются вместе с паспортными данными.
+	// VerificationDocs - их сканы, удаляемые из хранилища; RetainedVerificationDocs - сканы,
+	// загруженные после VerificationDocsRetainedAfter: под блокировкой хранилища они остаются
+	// до истечения законного срока
+	VerificationDocs              int        `json:"verification_docs"`
+	RetainedVerificationDocs      int        `json:"retained_verification_docs"`
+	VerificationDocsRetainedAfter *time.Time `json:"verification_docs_retained_after,omitempty"`
+	// Документы законного представителя и срочности в постах обезличиваемых аккаунтов:
+	// остаются вместе с постами. Считаются только в режиме dry_run
+	RetainedPostDocuments int `json:"retained_post_documents"`
+}
+
+// ChatAttachmentRef вложение сообщения для удаления по сроку хранения
+type ChatAttachmentRef struct {
+	MessageID int64
+	CreatedAt time.Time
+	URL       string
+}
+
+// Виды заданий в очереди недоставленных
+const (
+	// Рассылка уведомлений (FanoutJob): не сохранена в базу или не поместилась в очередь
+	DeadLetterNotification = "notification"
+	// Событие webhook_deliveries, не доставленное за все попытки
+	DeadLetterWebhook = "webhook"
+)
+
+// Состояния задания в очереди недоставленных
+const (
+	DeadLetterFailed    = "failed"
+	DeadLetterRetrying  = "retrying"
+	DeadLetterResolved  = "resolved"
+	DeadLetterDiscarded = "discarded"
+)
+
+// DeadLetter фоновое задание, которое не удалось выполнить. Администратор повторяет
+// или закрывает его; повторно упавшее задание можно повторить не раньше NextRetryAt.
+type DeadLetter struct {
+	ID   int64  `json:"id"`
+	Kind string `json:"kind"`
+	// ID события в webhook_deliveries для заданий webhook
+	ReferenceID *int64 `json:"reference_id,omitempty" db:"reference_id"`
+	// Задание для повтора (FanoutJob) или описание события
+	Payload  json.RawMessage `json:"payload" swaggertype:"object"`
+	Error    string          `json:"error"`
+	Attempts int             `json:"attempts"`
+	Status   string          `json:"status"`
+	// Ручные повторы
+	Retries       int        `json:"retries"`
+	NextRetryAt   *time.Time `json:"next_retry_at,omitempty" db:"next_retry_at"`
+	LastRetriedAt *time.Time `json:"last_retried_at,omitempty" db:"last_retried_at"`
+	LastRetriedBy *int64     `json:"last_retried_by,omitempty" db:"last_retried_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// DeadLetterFilter фильтры очереди недоставленных, нулевые значения не применяются
+type DeadLetterFilter struct {
+	Kind   string
+	Status string
+	From   *time.Time
+	To     *time.Time
+}
+
+// DigestReport показатели еженедельной сводки для администраторов
+type DigestReport struct {
+	// Посты, созданные за неделю
+	NewPosts int `json:"new_posts"`
+	// Пожертвования, подтвержденные за неделю
+	DonationsConfirmed int     `json:"donations_confirmed"`
+	AmountRaised       float64 `json:"amount_raised"`
+	// Очередь верификации на конец недели и заявки, рассмотренные за неделю
+	VerificationsPending  int        `json:"verifications_pending"`
+	VerificationsOldestAt *time.Time `json:"verifications_oldest_at,omitempty"`
+	VerificationsReviewed int        `json:"verifications_reviewed"`
+	// Требует внимания модераторов: посты на модерации, запросы на повышение лимита,
+	// чеки, сумма или дата которых не совпала с пожертвованием за неделю
+	PostsModeration      int `json:"posts_moderation"`
+	LimitRequestsPending int `json:"limit_requests_pending"`
+	ReceiptMismatches    int `json:"receipt_mismatches"`
+}
+
+// Каналы доставки сводки
+const (
+	DigestChannelEmail    = "email"
+	DigestChannelTelegram = "telegram"
+)
+
+// DigestDelivery результат отправки сводки одному получателю
+type DigestDelivery struct {
+	Channel string `json:"channel"`
+	Target  string `json:"target"`
+	Sent    bool   `json:"sent"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Digest еженедельная сводка. Хранится вместе с текстом и результатами отправки,
+// чтобы было видно, что и кому сообщалось.
+type Digest struct {
+	ID          int64            `json:"id"`
+	PeriodStart time.Time        `json:"period_start" db:"period_start"`
+	PeriodEnd   time.Time        `json:"period_end" db:"period_end"`
+	Report      DigestReport     `json:"report"`
+	Text        string           `json:"text"`
+	Deliveries  []DigestDelivery `json:"deliveries"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+}
+
+// IdempotencyRecord сохраненный ключ идемпотентности. StatusCode nil, пока
+// запрос выполняется.
+type IdempotencyRecord struct {
+	RequestHash string
+	StatusCode  *int
+	ContentType string
+	Body        []byte
+}
+
+// OpsStuckUploadAfter через сколько после загрузки несверенный чек считается зависшим
+const OpsStuckUploadAfter = 30 * time.Minute
+
+// OpsResponse сводка очередей и фоновых задач для дежурного администратора
+type OpsResponse struct {
+	Timestamp string `json:"timestamp"`
+	// Исходящие события интеграций и вебхуков партнеров, ожидающие доставки
+	Outbox OpsBacklog `json:"outbox"`
+	// Рассылки уведомлений в памяти процесса
+	NotificationQueue         int `json:"notification_queue"`
+	NotificationQueueCapacity int `json:"notification_queue_capacity"`
+	// События, доставка которых прекращена после всех попыток: всего и созданные за сутки
+	WebhookFailed    int `json:"webhook_failed"`
+	WebhookFailed24h int `json:"webhook_failed_24h"`
+	// Загруженные чеки, сверка которых не завершилась за OpsStuckUploadAfter
+	StuckUploads OpsBacklog  `json:"stuck_uploads"`
+	// Задания в очереди недоставленных, ожидающие решения администратора
+	DeadLetters  int         `json:"dead_letters"`
+	Jobs         []JobStatus `json:"jobs"`
+}
+
+// OpsBacklog размер очереди и время самой старой записи
+type OpsBacklog struct {
+	Count    int        `json:"count"`
+	OldestAt *time.Time `json:"oldest_at"`
+}
+
+// ReadinessResponse ответ readiness check
+type ReadinessResponse struct {
+	Status   string            `json:"status"`
+	Database string            `json:"database"`
+	Breakers map[string]string `json:"breakers"`
+}
+
+// BackupStatusResponse статус резервного копирования
+type BackupStatusResponse struct {
+	Enabled    bool           `json:"enabled"`
+	Stale      bool           `json:"stale"`
+	LastBackup *backup.Status `json:"last_backup"`
 }
 
 // VerificationResponse ответ верификации
@@ -400,13 +1809,89 @@ type PostUpdateResponse struct {
 
 // DonationResponse ответ пожертвования
 type DonationResponse struct {
-	ID          int64      `json:"id"`
-	PostID      int64      `json:"post_id"`
-	DonorID     int64      `json:"donor_id"`
-	Amount      float64    `json:"amount"`
-	ReceiptURL  *string    `json:"receipt_url,omitempty"`
-	Status      string     `json:"status"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID           int64     `json:"id"`
+	PostID       int64     `json:"post_id"`
+	DonorID      int64     `json:"donor_id"`
+	Amount       float64   `json:"amount"`
+	ReceiptURL   *string   `json:"receipt_url,omitempty"`
+	ReceiptCheck *string   `json:"receipt_check,omitempty"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NotificationsListResponse список уведомлений
+type NotificationsListResponse struct {
+	Data       []Notification     `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+// MarkNotificationsReadRequest запрос на отметку уведомлений прочитанными
+type MarkNotificationsReadRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// OAuthAuthorizeResponse ссылка на страницу входа провайдера
+type OAuthAuthorizeResponse struct {
+	URL string `json:"url"`
+}
+
+// RegisterDeviceRequest запрос на регистрацию FCM токена устройства
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" validate:"required,max=512"`
+	Platform string `json:"platform" validate:"required,oneof=android ios web"`
+}
+
+// CreateAPITokenRequest запрос на выпуск персонального API токена
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=donations:read posts:write"`
+	ExpiresInDays *int     `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=365"`
+}
+
+// CalendarFeedResponse ссылка на календарь сроков сборов (показывается только один раз)
+type CalendarFeedResponse struct {
+	URL string `json:"url" example:"https://api.example.com/api/v1/calendar/3f9a...c1.ics"`
+}
+
+// CreateAPITokenResponse выпущенный токен (значение показывается только один раз)
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// UpsertIntegrationRequest запрос на подключение интеграции.
+// Target - URL для webhook или chat_id для Telegram.
+type UpsertIntegrationRequest struct {
+	Type   string `json:"type" validate:"required,oneof=webhook telegram"`
+	Target string `json:"target" validate:"required,max=500"`
+}
+
+// IntegrationResponse интеграция с секретом подписи (возвращается только при подключении)
+type IntegrationResponse struct {
+	Integration
+	Secret string `json:"secret,omitempty"`
+}
+
+// CreateWebhookRequest запрос на создание вебхука партнера
+type CreateWebhookRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	URL    string   `json:"url" validate:"required,max=500"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=post.created donation.created donation.confirmed verification.approved verification.rejected"`
+}
+
+// UpdateWebhookRequest запрос на изменение вебхука партнера. rotate_secret выдает новый секрет подписи.
+type UpdateWebhookRequest struct {
+	Name         *string  `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	URL          *string  `json:"url,omitempty" validate:"omitempty,max=500"`
+	Events       []string `json:"events,omitempty" validate:"omitempty,min=1,dive,oneof=post.created donation.created donation.confirmed verification.approved verification.rejected"`
+	IsActive     *bool    `json:"is_active,omitempty"`
+	RotateSecret bool     `json:"rotate_secret,omitempty"`
+}
+
+// WebhookResponse вебхук партнера с секретом подписи (возвращается при создании и смене секрета)
+type WebhookResponse struct {
+	PartnerWebhook
+	Secret string `json:"secret,omitempty"`
 }
 
 // DonationsListResponse список пожертвований
@@ -423,6 +1908,96 @@ type DonationUpdateResponse struct {
 	ConfirmedBy *int64    `json:"confirmed_by,omitempty"`
 }
 
+// DonationHistoryResponse история пожертвования. Status восстановлен по событиям,
+// Consistent показывает, совпадает ли он со статусом, сохраненным в пожертвовании.
+type DonationHistoryResponse struct {
+	DonationID int64           `json:"donation_id"`
+	Status     string          `json:"status"`
+	Consistent bool            `json:"consistent"`
+	Events     []DonationEvent `json:"events"`
+}
+
+// DonationStatsResponse статистика пожертвований поста для графиков автора.
+// Дни считаются в часовом поясе автора поста.
+type DonationStatsResponse struct {
+	PostID    int64              `json:"post_id"`
+	Goal      float64            `json:"goal"`
+	Collected float64            `json:"collected"`
+	Timezone  string             `json:"timezone"`
+	Totals    DonationTotals     `json:"totals"`
+	Daily     []DonationDayStats `json:"daily"`
+	// Доноры с наибольшей подтвержденной суммой, анонимные пожертвования не учитываются
+	TopDonors []TopDonor `json:"top_donors"`
+}
+
+// DonationTotals суммы и количество пожертвований поста по статусам
+type DonationTotals struct {
+	ConfirmedAmount float64 `json:"confirmed_amount"`
+	ConfirmedCount  int     `json:"confirmed_count"`
+	PendingAmount   float64 `json:"pending_amount"`
+	PendingCount    int     `json:"pending_count"`
+	RejectedCount   int     `json:"rejected_count"`
+	RefundedCount   int     `json:"refunded_count"`
+	UniqueDonors    int     `json:"unique_donors"`
+	AverageAmount   float64 `json:"average_amount"`
+	// Подтвержденная сумма анонимных пожертвований
+	AnonymousAmount float64 `json:"anonymous_amount"`
+}
+
+// DonationDayStats подтвержденные за день пожертвования (по дате подтверждения)
+type DonationDayStats struct {
+	Date   string  `json:"date" example:"2026-10-01"`
+	Amount float64 `json:"amount"`
+	Count  int     `json:"count"`
+}
+
+// TopDonor донор поста и сумма его подтвержденных пожертвований
+type TopDonor struct {
+	Donor  UserInfo `json:"donor"`
+	Amount float64  `json:"amount"`
+	Count  int      `json:"count"`
+}
+
+// MoneyConfigResponse валюта и правила сумм, по которым клиенты форматируют и проверяют суммы
+type MoneyConfigResponse struct {
+	Currency       string `json:"currency" example:"RUB"`
+	CurrencySymbol string `json:"currency_symbol" example:"₽"`
+	FractionDigits int    `json:"fraction_digits" example:"2"`
+	// Минимальный шаг суммы, 10^-fraction_digits
+	Step     float64       `json:"step" example:"0.01"`
+	Rounding MoneyRounding `json:"rounding"`
+	Format   MoneyFormat   `json:"format"`
+	Donation MoneyLimits   `json:"donation"`
+	Points   PointsRates   `json:"points"`
+}
+
+// MoneyRounding способы округления сумм и баллов
+type MoneyRounding struct {
+	Amount string `json:"amount" enums:"half_up" example:"half_up"`
+	Points string `json:"points" enums:"floor" example:"floor"`
+}
+
+// MoneyFormat правила записи суммы для отображения
+type MoneyFormat struct {
+	DecimalSeparator string `json:"decimal_separator" example:","`
+	GroupSeparator   string `json:"group_separator" example:" "`
+	SymbolPosition   string `json:"symbol_position" enums:"before,after" example:"after"`
+	Example          string `json:"example" example:"1 500,50 ₽"`
+}
+
+// MoneyLimits границы суммы пожертвования, max отсутствует, если ограничения нет
+type MoneyLimits struct {
+	Min float64  `json:"min" example:"1"`
+	Max *float64 `json:"max,omitempty" example:"1000000"`
+}
+
+// PointsRates начисление баллов рейтинга: floor(сумма * per_unit * multiplier)
+type PointsRates struct {
+	PerUnit float64 `json:"per_unit" example:"1"`
+	// Множитель идущей акции, 1 без акции
+	Multiplier float64 `json:"multiplier" example:"1"`
+}
+
 // ChatResponse ответ чата
 type ChatResponse struct {
 	ID        int64     `json:"id"`
@@ -439,8 +2014,11 @@ type ChatsListResponse struct {
 
 // MessagesListResponse список сообщений
 type MessagesListResponse struct {
-	Data       []MessageWithDetails `json:"data"`
-	Pagination PaginationResponse    `json:"pagination"`
+	Data []MessageWithDetails `json:"data"`
+	// Только при постраничной выдаче (без before_id/after_id)
+	Pagination *PaginationResponse `json:"pagination,omitempty"`
+	NextCursor *int64              `json:"next_cursor"`
+	HasMore    bool                `json:"has_more"`
 }
 
 // MessageResponse ответ сообщения
@@ -473,6 +2051,15 @@ type MarkMessagesReadResponse struct {
 type RatingsListResponse struct {
 	Data       []RatingWithDetails `json:"data"`
 	Pagination PaginationResponse  `json:"pagination"`
+	// Только с параметром period: период и его начало
+	Period string     `json:"period,omitempty" enums:"week,month,year"`
+	Since  *time.Time `json:"since,omitempty"`
+}
+
+// RatingHistoryResponse журнал баллов рейтинга пользователя
+type RatingHistoryResponse struct {
+	Data       []RatingEvent      `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
 }
 
 // PhotoUploadResponse ответ загрузки фото
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// Модератор проверяет верификации и модерирует посты и комментарии, но не меняет роли,
+// не влияет на рейтинг и не работает с деньгами чужих сборов
+func TestModeratorPermissions(t *testing.T) {
+	s := newTestServer(t)
+	moderator := s.createUser(RoleModerator)
+	author := s.createUser("user")
+	donor := s.createUser("user")
+
+	post := s.createPost(author, "active")
+	donation := &Donation{PostID: post.ID, DonorID: donor.ID, Amount: 500}
+	if err := s.db.CreateDonation(donation); err != nil {
+		t.Fatalf("create donation: %v", err)
+	}
+
+	forbidden := []struct {
+		method, path string
+		body         interface{}
+	}{
+		{"PATCH", fmt.Sprintf("/api/v1/admin/users/%d/role", author.ID), map[string]string{"role": RoleModerator}},
+		{"GET", "/api/v1/admin/roles", nil},
+		{"PUT", "/api/v1/admin/roles/" + RoleModerator, map[string][]string{"permissions": {PermRolesManage}}},
+		// Подтверждение чужого пожертвования начисляет донору баллы рейтинга
+		{"PATCH", fmt.Sprintf("/api/v1/donations/%d", donation.ID), map[string]string{"status": "confirmed"}},
+		{"PATCH", fmt.Sprintf("/api/v1/donations/%d", donation.ID), map[string]string{"status": "refunded"}},
+		{"POST", "/api/v1/admin/points-events", map[string]interface{}{"name": "x2", "multiplier": 2}},
+		{"GET", "/api/v1/admin/points-events", nil},
+		{"GET", "/api/v1/admin/risk-tiers", nil},
+		{"GET", "/api/v1/admin/post-limit-requests", nil},
+		{"GET", fmt.Sprintf("/api/v1/posts/%d/donations/stats", post.ID), nil},
+		{"GET", "/api/v1/admin/webhooks", nil},
+		{"GET", "/api/v1/admin/users", nil},
+	}
+	for _, tc := range forbidden {
+		rec := s.request(moderator, tc.method, tc.path, tc.body)
+		expectStatus(t, rec, tc.method+" "+tc.path, http.StatusForbidden)
+	}
+
+	// Пожертвование осталось неподтвержденным
+	if d, err := s.db.GetDonationByID(donation.ID); err != nil || d.Status != "pending" {
+		t.Fatalf("donation after moderator requests: %+v, %v", d, err)
+	}
+
+	t.Run("verification review", func(t *testing.T) {
+		applicant := s.createUser("user")
+		verification := s.createVerification(applicant)
+
+		expectStatus(t, s.request(moderator, "GET", "/api/v1/verifications", nil), "GET /verifications", http.StatusOK)
+		path := fmt.Sprintf("/api/v1/verifications/%d", verification.ID)
+		expectStatus(t, s.request(moderator, "GET", path, nil), "GET "+path, http.StatusOK)
+		expectStatus(t, s.request(moderator, "PATCH", path, map[string]string{"status": "approved"}), "PATCH "+path, http.StatusOK)
+	})
+
+	t.Run("post moderation", func(t *testing.T) {
+		pending := s.createPost(author, "moderated")
+
+		expectStatus(t, s.request(moderator, "GET", "/api/v1/admin/posts/moderation", nil), "GET /admin/posts/moderation", http.StatusOK)
+		path := fmt.Sprintf("/api/v1/admin/posts/%d/moderation", pending.ID)
+		expectStatus(t, s.request(moderator, "PATCH", path, map[string]string{"status": "active"}), "PATCH "+path, http.StatusOK)
+	})
+
+	t.Run("comment moderation", func(t *testing.T) {
+		comment, err := s.db.CreatePostComment(post.ID, donor.ID, "Комментарий")
+		if err != nil {
+			t.Fatalf("create comment: %v", err)
+		}
+
+		path := fmt.Sprintf("/api/v1/posts/%d/comments/%d", post.ID, comment.ID)
+		expectStatus(t, s.request(moderator, "DELETE", path, nil), "DELETE "+path, http.StatusNoContent)
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Правила записи сумм. Клиенты получают их вместе с MoneyConfig в GET /config/money,
+// чтобы форматировать и проверять суммы так же, как сервер.
+const (
+	MoneyRoundingHalfUp  = "half_up"
+	MoneyRoundingFloor   = "floor"
+	moneyDecimalSep      = ","
+	moneyGroupSep        = "\u00a0" // неразрывный пробел
+	moneySymbolPosition  = "after"
+	moneyFormatExampleIn = 1500.5
+)
+
+// RoundAmount округляет сумму до FractionDigits знаков, половина - от нуля. Округляется
+// десятичная запись: 1.005 в float64 чуть меньше 1.005, но клиент передал именно 1.005.
+func (m MoneyConfig) RoundAmount(amount float64) float64 {
+	s := strconv.FormatFloat(math.Abs(amount), 'f', -1, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) <= m.FractionDigits {
+		return amount
+	}
+	units, err := strconv.ParseInt(whole+frac[:m.FractionDigits], 10, 64)
+	if err != nil {
+		scale := math.Pow10(m.FractionDigits)
+		return math.Round(amount*scale) / scale
+	}
+	if frac[m.FractionDigits] >= '5' {
+		units++
+	}
+	return math.Copysign(float64(units)/math.Pow10(m.FractionDigits), amount)
+}
+
+// ValidateDonationAmount проверяет округленную сумму пожертвования по MinDonation и MaxDonation
+func (m MoneyConfig) ValidateDonationAmount(amount float64) error {
+	if amount >= m.MinDonation && (m.MaxDonation <= 0 || amount <= m.MaxDonation) {
+		return nil
+	}
+	details := map[string]interface{}{"field": "amount", "min": m.MinDonation}
+	message := fmt.Sprintf("Сумма пожертвования должна быть не меньше %s", m.FormatAmount(m.MinDonation))
+	if m.MaxDonation > 0 {
+		details["max"] = m.MaxDonation
+		message = fmt.Sprintf("Сумма пожертвования должна быть от %s до %s", m.FormatAmount(m.MinDonation), m.FormatAmount(m.MaxDonation))
+	}
+	return NewValidationError(message, details)
+}
+
+// Points возвращает баллы рейтинга за подтвержденное пожертвование с множителем акции,
+// дробная часть отбрасывается
+func (m MoneyConfig) Points(amount, multiplier float64) int {
+	// Поправка на погрешность float64: 0.1 * 3 = 0.30000000000000004, 1.1 * 3 = 3.3000000000000003
+	return int(math.Floor(amount*m.PointsPerUnit*multiplier + 1e-9))
+}
+
+// FormatAmount записывает сумму по правилам из GET /config/money: "1 500,50 ₽"
+func (m MoneyConfig) FormatAmount(amount float64) string {
+	s := strconv.FormatFloat(math.Abs(m.RoundAmount(amount)), 'f', m.FractionDigits, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if amount < 0 {
+		b.WriteString("-")
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(moneyGroupSep)
+		}
+		b.WriteRune(digit)
+	}
+	if frac != "" {
+		b.WriteString(moneyDecimalSep + frac)
+	}
+	if m.CurrencySymbol != "" {
+		b.WriteString(moneyGroupSep + m.CurrencySymbol)
+	}
+	return b.String()
+}
+
+// MoneyConfigResponse собирает ответ GET /config/money. multiplier - множитель идущей акции.
+func (m MoneyConfig) Response(multiplier float64) MoneyConfigResponse {
+	response := MoneyConfigResponse{
+		Currency:       m.Currency,
+		CurrencySymbol: m.CurrencySymbol,
+		FractionDigits: m.FractionDigits,
+		Step:           1 / math.Pow10(m.FractionDigits),
+		Rounding:       MoneyRounding{Amount: MoneyRoundingHalfUp, Points: MoneyRoundingFloor},
+		Format: MoneyFormat{
+			DecimalSeparator: moneyDecimalSep,
+			GroupSeparator:   moneyGroupSep,
+			SymbolPosition:   moneySymbolPosition,
+			Example:          m.FormatAmount(moneyFormatExampleIn),
+		},
+		Donation: MoneyLimits{Min: m.MinDonation},
+		Points:   PointsRates{PerUnit: m.PointsPerUnit, Multiplier: multiplier},
+	}
+	if m.MaxDonation > 0 {
+		response.Donation.Max = &m.MaxDonation
+	}
+	return response
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// NotificationSender доставляет уже сохраненные уведомления по внешнему каналу
+type NotificationSender interface {
+	SendBatch(ctx context.Context, notifications []Notification) error
+}
+
+// FanoutJob рассылка одного уведомления группе пользователей.
+// Упавшая рассылка сохраняется в очередь недоставленных в JSON.
+type FanoutJob struct {
+	Type    string  `json:"type"`
+	Title   string  `json:"title"`
+	Body    string  `json:"body"`
+	PostID  *int64  `json:"post_id,omitempty"`
+	UserIDs []int64 `json:"user_ids"`
+	// PostDonors - получатели все доноры поста PostID (вычисляются в воркере)
+	PostDonors bool `json:"post_donors,omitempty"`
+	// PushOnly - не сохранять уведомление, только доставить по внешним каналам
+	PushOnly bool              `json:"push_only,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+	// DeadLetterID - рассылка повторяет задание из очереди недоставленных
+	DeadLetterID int64 `json:"-"`
+}
+
+// Notifier рассылает уведомления в фоне пачками, чтобы рассылка по
+// популярному посту не нагружала базу одним большим всплеском
+type Notifier struct {
+	db        *DB
+	batchSize int
+	pace      time.Duration
+	queue     chan FanoutJob
+	senders   []NotificationSender
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewNotifier(db *DB, cfg NotificationConfig) *Notifier {
+	return &Notifier{
+		db:        db,
+		batchSize: cfg.BatchSize,
+		pace:      cfg.Pace,
+		queue:     make(chan FanoutJob, cfg.QueueSize),
+	}
+}
+
+// AddSender подключает внешний канал доставки
+func (n *Notifier) AddSender(sender NotificationSender) {
+	n.senders = append(n.senders, sender)
+}
+
+// Enqueue ставит рассылку в очередь. Возвращает false, если очередь заполнена;
+// такая рассылка сохраняется в очередь недоставленных (кроме повторов из нее).
+func (n *Notifier) Enqueue(job FanoutJob) bool {
+	select {
+	case n.queue <- job:
+		return true
+	default:
+		metrics.Inc("notification_jobs_dropped_total", "Fan-out jobs dropped because the queue was full", nil)
+		if job.DeadLetterID == 0 {
+			n.deadLetter(job, "notification queue is full")
+		}
+		return false
+	}
+}
+
+// QueueLength возвращает число рассылок в очереди и ее емкость
+func (n *Notifier) QueueLength() (int, int) {
+	return len(n.queue), cap(n.queue)
+}
+
+// Start запускает воркер рассылки
+func (n *Notifier) Start(ctx context.Context) {
+	ctx, n.cancel = context.WithCancel(ctx)
+	n.wg.Add(1)
+	go n.loop(ctx)
+}
+
+// Stop останавливает воркер и ожидает завершения текущей рассылки
+func (n *Notifier) Stop() {
+	if n.cancel != nil {
+		n.cancel()
+	}
+	n.wg.Wait()
+}
+
+func (n *Notifier) loop(ctx context.Context) {
+	defer n.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-n.queue:
+			remaining, err := n.process(ctx, job)
+			if err != nil {
+				log.Printf("Notification fan-out %s failed: %v", job.Type, err)
+				// Повторять нужно только рассылку пользователям, которым она не дошла
+				if remaining != nil {
+					job.UserIDs = remaining
+					job.PostDonors = false
+				}
+				n.deadLetter(job, err.Error())
+				continue
+			}
+			if job.DeadLetterID != 0 {
+				if err := n.db.ResolveDeadLetter(job.DeadLetterID); err != nil {
+					log.Printf("Failed to resolve dead letter %d: %v", job.DeadLetterID, err)
+				}
+			}
+		}
+	}
+}
+
+// deadLetter сохраняет упавшую рассылку в очередь недоставленных
+func (n *Notifier) deadLetter(job FanoutJob, reason string) {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		log.Printf("Failed to encode notification fan-out %s: %v", job.Type, err)
+		return
+	}
+	letter := &DeadLetter{
+		ID:       job.DeadLetterID,
+		Kind:     DeadLetterNotification,
+		Payload:  payload,
+		Error:    reason,
+		Attempts: 1,
+	}
+	if err := n.db.RecordDeadLetter(letter); err != nil {
+		log.Printf("Failed to record dead letter for fan-out %s: %v", job.Type, err)
+	}
+}
+
+// process выполняет рассылку. При ошибке возвращает пользователей, которым
+// уведомление еще не сохранено, или nil, если рассылка не начиналась.
+func (n *Notifier) process(ctx context.Context, job FanoutJob) ([]int64, error) {
+	userIDs := job.UserIDs
+	if job.PostDonors && job.PostID != nil {
+		donors, err := n.db.GetPostDonorIDs(*job.PostID)
+		if err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, donors...)
+	}
+
+	for start := 0; start < len(userIDs); start += n.batchSize {
+		end := start + n.batchSize
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+
+		batch := make([]Notification, 0, end-start)
+		for _, userID := range userIDs[start:end] {
+			batch = append(batch, Notification{
+				UserID: userID,
+				Type:   job.Type,
+				Title:  job.Title,
+				Body:   job.Body,
+				PostID: job.PostID,
+				Data:   job.Data,
+			})
+		}
+
+		if !job.PushOnly {
+			if err := n.db.CreateNotificationsBatch(batch); err != nil {
+				return userIDs[start:], err
+			}
+			metrics.Add("notifications_created_total", "Notifications stored by the fan-out worker", map[string]string{"type": job.Type}, float64(len(batch)))
+		}
+
+		for _, sender := range n.senders {
+			if err := sender.SendBatch(ctx, batch); err != nil {
+				log.Printf("Failed to deliver notifications: %v", err)
+			}
+		}
+
+		// Выдерживаем паузу между пачками
+		if end < len(userIDs) {
+			select {
+			case <-ctx.Done():
+				return userIDs[end:], ctx.Err()
+			case <-time.After(n.pace):
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Провайдеры внешнего входа
+const (
+	OAuthProviderVK     = "vk"
+	OAuthProviderYandex = "yandex"
+	OAuthProviderGoogle = "google"
+)
+
+// oauthStateTTL время, за которое пользователь должен вернуться от провайдера
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthUser учетная запись пользователя у провайдера
+type OAuthUser struct {
+	Subject string
+	Email   *string
+	Name    *string
+}
+
+// OAuthProvider OAuth2/OIDC провайдер с authorization code flow и PKCE
+type OAuthProvider struct {
+	Name        string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	Scopes      []string
+	Client      OAuthClientConfig
+
+	// fetchUser получает учетную запись пользователя по access токену
+	fetchUser func(ctx context.Context, p *OAuthProvider, client *http.Client, accessToken string) (*OAuthUser, error)
+}
+
+// OAuthProviders настроенные провайдеры внешнего входа
+type OAuthProviders struct {
+	callbackBaseURL string
+	providers       map[string]*OAuthProvider
+	client          *http.Client
+}
+
+// NewOAuthProviders создает провайдеров, для которых задан client_id.
+// Провайдеры без настроек недоступны.
+func NewOAuthProviders(cfg OAuthConfig) *OAuthProviders {
+	all := []*OAuthProvider{
+		{
+			Name:        OAuthProviderVK,
+			AuthURL:     "https://id.vk.com/authorize",
+			TokenURL:    "https://id.vk.com/oauth2/auth",
+			UserInfoURL: "https://id.vk.com/oauth2/user_info",
+			Scopes:      []string{"email"},
+			Client:      cfg.VK,
+			fetchUser:   fetchVKUser,
+		},
+		{
+			Name:        OAuthProviderYandex,
+			AuthURL:     "https://oauth.yandex.ru/authorize",
+			TokenURL:    "https://oauth.yandex.ru/token",
+			UserInfoURL: "https://login.yandex.ru/info?format=json",
+			Scopes:      []string{"login:email", "login:info"},
+			Client:      cfg.Yandex,
+			fetchUser:   fetchYandexUser,
+		},
+		{
+			Name:        OAuthProviderGoogle,
+			AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL:    "https://oauth2.googleapis.com/token",
+			UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+			Scopes:      []string{"openid", "email", "profile"},
+			Client:      cfg.Google,
+			fetchUser:   fetchGoogleUser,
+		},
+	}
+
+	providers := make(map[string]*OAuthProvider)
+	for _, p := range all {
+		if p.Client.ClientID != "" {
+			providers[p.Name] = p
+		}
+	}
+
+	return &OAuthProviders{
+		callbackBaseURL: strings.TrimRight(cfg.CallbackBaseURL, "/"),
+		providers:       providers,
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Get возвращает настроенного провайдера
+func (o *OAuthProviders) Get(name string) (*OAuthProvider, error) {
+	p, ok := o.providers[name]
+	if !ok {
+		return nil, NewNotFoundError("Провайдер входа")
+	}
+	return p, nil
+}
+
+// Names возвращает имена настроенных провайдеров
+func (o *OAuthProviders) Names() []string {
+	names := make([]string, 0, len(o.providers))
+	for _, name := range []string{OAuthProviderVK, OAuthProviderYandex, OAuthProviderGoogle} {
+		if _, ok := o.providers[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// RedirectURI адрес callback, зарегистрированный у провайдера
+func (o *OAuthProviders) RedirectURI(p *OAuthProvider) string {
+	return fmt.Sprintf("%s/auth/oauth/%s/callback", o.callbackBaseURL, p.Name)
+}
+
+// AuthorizeURL формирует ссылку на страницу входа провайдера
+func (o *OAuthProviders) AuthorizeURL(p *OAuthProvider, state, codeVerifier string) string {
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.Client.ClientID)
+	q.Set("redirect_uri", o.RedirectURI(p))
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	return p.AuthURL + "?" + q.Encode()
+}
+
+// Exchange обменивает код авторизации на учетную запись пользователя.
+// deviceID передается только VK ID.
+func (o *OAuthProviders) Exchange(ctx context.Context, p *OAuthProvider, code, codeVerifier, deviceID string) (*OAuthUser, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.RedirectURI(p))
+	form.Set("client_id", p.Client.ClientID)
+	form.Set("client_secret", p.Client.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+	if deviceID != "" {
+		form.Set("device_id", deviceID)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := o.postForm(ctx, p.TokenURL, form, &token); err != nil {
+		return nil, fmt.Errorf("failed to exchange %s code: %w", p.Name, err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("failed to exchange %s code: %s %s", p.Name, token.Error, token.Description)
+	}
+
+	user, err := p.fetchUser(ctx, p, o.client, token.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s user: %w", p.Name, err)
+	}
+	if user.Subject == "" {
+		return nil, fmt.Errorf("%s returned empty user id", p.Name)
+	}
+	return user, nil
+}
+
+func (o *OAuthProviders) postForm(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doOAuthRequest(o.client, req, out)
+}
+
+func doOAuthRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("provider returned status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
+
+func fetchVKUser(ctx context.Context, p *OAuthProvider, client *http.Client, accessToken string) (*OAuthUser, error) {
+	form := url.Values{}
+	form.Set("client_id", p.Client.ClientID)
+	form.Set("access_token", accessToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.UserInfoURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var result struct {
+		User struct {
+			UserID    json.Number `json:"user_id"`
+			FirstName string      `json:"first_name"`
+			LastName  string      `json:"last_name"`
+			Email     string      `json:"email"`
+		} `json:"user"`
+	}
+	if err := doOAuthRequest(client, req, &result); err != nil {
+		return nil, err
+	}
+
+	return &OAuthUser{
+		Subject: result.User.UserID.String(),
+		Email:   getStringPtr(result.User.Email),
+		Name:    getStringPtr(strings.TrimSpace(result.User.FirstName + " " + result.User.LastName)),
+	}, nil
+}
+
+func fetchYandexUser(ctx context.Context, p *OAuthProvider, client *http.Client, accessToken string) (*OAuthUser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "OAuth "+accessToken)
+
+	var result struct {
+		ID           string `json:"id"`
+		DefaultEmail string `json:"default_email"`
+		RealName     string `json:"real_name"`
+	}
+	if err := doOAuthRequest(client, req, &result); err != nil {
+		return nil, err
+	}
+
+	return &OAuthUser{
+		Subject: result.ID,
+		Email:   getStringPtr(result.DefaultEmail),
+		Name:    getStringPtr(result.RealName),
+	}, nil
+}
+
+func fetchGoogleUser(ctx context.Context, p *OAuthProvider, client *http.Client, accessToken string) (*OAuthUser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var result struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := doOAuthRequest(client, req, &result); err != nil {
+		return nil, err
+	}
+
+	user := &OAuthUser{Subject: result.Sub, Name: getStringPtr(result.Name)}
+	if result.EmailVerified {
+		user.Email = getStringPtr(result.Email)
+	}
+	return user, nil
+}
+
+// GenerateOAuthState генерирует одноразовый state и PKCE code_verifier
+func GenerateOAuthState() (state, codeVerifier string, err error) {
+	b := make([]byte, 64)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate oauth state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:24]), base64.RawURLEncoding.EncodeToString(b[24:]), nil
+}
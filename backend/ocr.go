@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"tmphackbackend/httpclient"
+)
+
+// Результаты сверки чека с пожертвованием
+const (
+	ReceiptCheckPending    = "pending"
+	ReceiptCheckMatched    = "matched"
+	ReceiptCheckMismatch   = "mismatch"
+	ReceiptCheckUnreadable = "unreadable"
+)
+
+// receiptAmountTolerance допустимое расхождение суммы из-за округления
+const receiptAmountTolerance = 0.01
+
+// maxReceiptSize чек больше этого размера не отправляется на распознавание
+const maxReceiptSize = 10 << 20
+
+// ReceiptOCR распознает текст на изображении или PDF чека
+type ReceiptOCR interface {
+	RecognizeText(ctx context.Context, content []byte, contentType string) (string, error)
+}
+
+// NewReceiptOCR создает провайдера распознавания. Без провайдера сверка чеков отключена.
+func NewReceiptOCR(cfg OCRConfig, client *httpclient.Client) ReceiptOCR {
+	switch cfg.Provider {
+	case "yandex":
+		return &YandexOCR{
+			apiKey:   cfg.APIKey,
+			folderID: cfg.FolderID,
+			client:   client,
+		}
+	case "":
+		return nil
+	default:
+		log.Printf("Unknown OCR provider %q, receipt check disabled", cfg.Provider)
+		return nil
+	}
+}
+
+// YandexOCR распознавание через Yandex Vision OCR
+type YandexOCR struct {
+	apiKey   string
+	folderID string
+	client   *httpclient.Client
+}
+
+func (y *YandexOCR) RecognizeText(ctx context.Context, content []byte, contentType string) (string, error) {
+	mimeType := "JPEG"
+	switch strings.ToLower(contentType) {
+	case "image/png":
+		mimeType = "PNG"
+	case "application/pdf":
+		mimeType = "PDF"
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"mimeType":      mimeType,
+		"languageCodes": []string{"ru", "en"},
+		"content":       base64.StdEncoding.EncodeToString(content),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://ocr.api.cloud.yandex.net/ocr/v1/recognizeText", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create ocr request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Api-Key "+y.apiKey)
+	if y.folderID != "" {
+		req.Header.Set("x-folder-id", y.folderID)
+	}
+
+	resp, err := y.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send ocr request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ocr responded with status %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Result struct {
+			TextAnnotation struct {
+				FullText string `json:"fullText"`
+			} `json:"textAnnotation"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to decode ocr response: %w", err)
+	}
+	return result.Result.TextAnnotation.FullText, nil
+}
+
+var (
+	// Сумма: 1 500, 1500,00, 1 500.50 с необязательным знаком валюты
+	receiptAmountPattern = regexp.MustCompile(`(\d{1,3}(?:[ \x{00a0}]\d{3})+|\d+)(?:[.,](\d{2}))?\s*(₽|руб|rub|р\.)?`)
+	// Строки с итоговой суммой перевода
+	receiptTotalWords = []string{"итого", "сумма", "всего", "к оплате", "списано", "amount", "total"}
+
+	receiptNumericDatePattern = regexp.MustCompile(`\b(\d{2})[./](\d{2})[./](\d{4})\b`)
+	receiptTextDatePattern    = regexp.MustCompile(`\b(\d{1,2})\s+([а-яё]+)\s+(\d{4})\b`)
+	receiptMonths             = map[string]time.Month{
+		"января": time.January, "февраля": time.February, "марта": time.March,
+		"апреля": time.April, "мая": time.May, "июня": time.June,
+		"июля": time.July, "августа": time.August, "сентября": time.September,
+		"октября": time.October, "ноября": time.November, "декабря": time.December,
+	}
+)
+
+// ParseReceiptAmount ищет сумму перевода в тексте чека.
+// Сначала в строках с итоговой суммой, затем среди чисел со знаком валюты.
+func ParseReceiptAmount(text string) *float64 {
+	lines := strings.Split(strings.ToLower(text), "\n")
+
+	for i, line := range lines {
+		for _, word := range receiptTotalWords {
+			if !strings.Contains(line, word) {
+				continue
+			}
+			// Сумма бывает на той же строке или на следующей
+			if amount := firstReceiptAmount(line, false); amount != nil {
+				return amount
+			}
+			if i+1 < len(lines) {
+				if amount := firstReceiptAmount(lines[i+1], false); amount != nil {
+					return amount
+				}
+			}
+		}
+	}
+
+	for _, line := range lines {
+		if amount := firstReceiptAmount(line, true); amount != nil {
+			return amount
+		}
+	}
+	return nil
+}
+
+func firstReceiptAmount(line string, requireCurrency bool) *float64 {
+	// Даты и время не должны попадать в сумму
+	line = receiptNumericDatePattern.ReplaceAllString(line, " ")
+	line = receiptTextDatePattern.ReplaceAllString(line, " ")
+
+	for _, m := range receiptAmountPattern.FindAllStringSubmatch(line, -1) {
+		if requireCurrency && m[3] == "" {
+			continue
+		}
+		number := strings.NewReplacer(" ", "", "\u00a0", "").Replace(m[1])
+		if m[2] != "" {
+			number += "." + m[2]
+		}
+		amount, err := strconv.ParseFloat(number, 64)
+		if err != nil || amount <= 0 {
+			continue
+		}
+		return &amount
+	}
+	return nil
+}
+
+// ParseReceiptDate ищет дату операции в тексте чека (02.01.2006 или "2 января 2006")
+func ParseReceiptDate(text string) *time.Time {
+	lower := strings.ToLower(text)
+
+	if m := receiptNumericDatePattern.FindStringSubmatch(lower); m != nil {
+		if date, err := time.Parse("02.01.2006", m[1]+"."+m[2]+"."+m[3]); err == nil {
+			return &date
+		}
+	}
+	if m := receiptTextDatePattern.FindStringSubmatch(lower); m != nil {
+		month, ok := receiptMonths[m[2]]
+		day, _ := strconv.Atoi(m[1])
+		year, _ := strconv.Atoi(m[3])
+		if ok && day >= 1 && day <= 31 {
+			date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+			return &date
+		}
+	}
+	return nil
+}
+
+// ReceiptCheckResult результат сверки чека с заявленным пожертвованием
+type ReceiptCheckResult struct {
+	Status string
+	Amount *float64
+	Date   *time.Time
+}
+
+// CheckReceipt сравнивает распознанные сумму и дату с пожертвованием.
+// Дата чека позже создания пожертвования (с запасом в сутки на часовые пояса)
+// тоже считается расхождением.
+func CheckReceipt(text string, donation Donation) ReceiptCheckResult {
+	result := ReceiptCheckResult{
+		Amount: ParseReceiptAmount(text),
+		Date:   ParseReceiptDate(text),
+	}
+
+	switch {
+	case result.Amount == nil:
+		result.Status = ReceiptCheckUnreadable
+	case math.Abs(*result.Amount-donation.Amount) > receiptAmountTolerance:
+		result.Status = ReceiptCheckMismatch
+	case result.Date != nil && result.Date.After(donation.CreatedAt.AddDate(0, 0, 1)):
+		result.Status = ReceiptCheckMismatch
+	default:
+		result.Status = ReceiptCheckMatched
+	}
+	return result
+}
+
+// ReceiptChecker сверяет загруженные чеки пожертвований.
+// Запускается периодической задачей планировщика.
+type ReceiptChecker struct {
+	db          *DB
+	minioClient *minio.Client
+	ocr         ReceiptOCR
+	batchSize   int
+}
+
+func NewReceiptChecker(db *DB, minioClient *minio.Client, ocr ReceiptOCR, batchSize int) *ReceiptChecker {
+	return &ReceiptChecker{db: db, minioClient: minioClient, ocr: ocr, batchSize: batchSize}
+}
+
+// Run проверяет пачку чеков, ожидающих сверки. При ошибке провайдера
+// оставшиеся чеки остаются в очереди до следующего запуска.
+func (c *ReceiptChecker) Run(ctx context.Context) error {
+	donations, err := c.db.GetDonationsPendingReceiptCheck(c.batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, donation := range donations {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		content, contentType, err := GetDonationReceipt(ctx, c.minioClient, donation.ID)
+		if err != nil {
+			log.Printf("Failed to read receipt of donation %d: %v", donation.ID, err)
+			if err := c.db.SaveReceiptCheck(donation.ID, ReceiptCheckResult{Status: ReceiptCheckUnreadable}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		text, err := c.ocr.RecognizeText(ctx, content, contentType)
+		if err != nil {
+			return fmt.Errorf("failed to recognize receipt of donation %d: %w", donation.ID, err)
+		}
+
+		result := CheckReceipt(text, donation)
+		if err := c.db.SaveReceiptCheck(donation.ID, result); err != nil {
+			return err
+		}
+		metrics.Inc("receipt_checks_total", "Donation receipt checks by result", map[string]string{"result": result.Status})
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"time"
+
+	"tmphackbackend/httpclient"
+)
+
+// newOutboundClient создает HTTP клиент внешней интеграции с повторами и отдельным breaker
+func newOutboundClient(cfg *Config, destination string, timeout time.Duration) *httpclient.Client {
+	breaker := NewIntegrationBreaker(destination, cfg.Breaker.FailureThreshold, cfg.Breaker.Cooldown)
+	return httpclient.New(destination, httpclient.Config{
+		Timeout:        timeout,
+		MaxRetries:     cfg.Outbound.MaxRetries,
+		RetryBaseDelay: cfg.Outbound.RetryBaseDelay,
+		RetryMaxDelay:  cfg.Outbound.RetryMaxDelay,
+	}, breaker, metrics)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// partitionMonthsAhead на сколько месяцев вперед создаются секции
+const partitionMonthsAhead = 3
+
+// partitionedTables таблицы, секционированные по месяцам по created_at
+var partitionedTables = []string{"messages", "donations"}
+
+// archivedTables секционированные таблицы, старые секции которых выгружаются в архив.
+// donations не архивируются: на пожертвования ссылаются donation_events, payments и
+// rating_events, а по ним пересчитываются собранные суммы постов.
+var archivedTables = []string{"messages"}
+
+// partitionName возвращает имя месячной секции, например messages_p2025_01
+func partitionName(table string, month time.Time) string {
+	return fmt.Sprintf("%s_p%s", table, month.Format("2006_01"))
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// migratePartitionedTables преобразует обычные таблицы из partitionedTables,
+// созданные до введения секционирования, в секционированные с сохранением данных
+func (db *DB) migratePartitionedTables() error {
+	for _, table := range partitionedTables {
+		var kind string
+		err := db.QueryRow(`SELECT relkind FROM pg_class WHERE relname = $1 AND relnamespace = 'public'::regnamespace`, table).Scan(&kind)
+		if err == sql.ErrNoRows || kind == "p" {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check table %s: %w", table, err)
+		}
+
+		log.Printf("Migrating table %s to monthly partitions", table)
+		if err := db.migrateToPartitioned(table); err != nil {
+			return fmt.Errorf("failed to partition table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (db *DB) migrateToPartitioned(table string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Перенос данных может идти дольше statement_timeout
+	if _, err := tx.Exec(`SET LOCAL statement_timeout = 0`); err != nil {
+		return err
+	}
+
+	legacy := table + "_legacy"
+	var sequence string
+	if err := tx.QueryRow(`SELECT pg_get_serial_sequence($1, 'id')`, table).Scan(&sequence); err != nil {
+		return err
+	}
+
+	// Внешние ключи не копируются через LIKE, переносим их отдельно
+	rows, err := tx.Query(`SELECT conname, pg_get_constraintdef(oid) FROM pg_constraint
+	                       WHERE conrelid = $1::regclass AND contype = 'f'`, table)
+	if err != nil {
+		return err
+	}
+	var foreignKeys []string
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			rows.Close()
+			return err
+		}
+		foreignKeys = append(foreignKeys, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", table, name, def))
+	}
+	rows.Close()
+
+	var minCreated sql.NullTime
+	if err := tx.QueryRow(fmt.Sprintf(`SELECT MIN(created_at) FROM %s`, table)).Scan(&minCreated); err != nil {
+		return err
+	}
+
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, table, legacy),
+		fmt.Sprintf(`UPDATE %s SET created_at = NOW() WHERE created_at IS NULL`, legacy),
+		fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS) PARTITION BY RANGE (created_at)`, table, legacy),
+		fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN created_at SET NOT NULL`, table),
+	}
+	statements = append(statements, foreignKeys...)
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+
+	from := time.Now().UTC()
+	if minCreated.Valid {
+		from = minCreated.Time
+	}
+	if err := createPartitions(tx, table, monthStart(from), monthStart(time.Now().UTC()).AddDate(0, 1, 0)); err != nil {
+		return err
+	}
+
+	statements = []string{
+		fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, table, legacy),
+		fmt.Sprintf(`ALTER SEQUENCE %s OWNED BY %s.id`, sequence, table),
+		fmt.Sprintf(`DROP TABLE %s`, legacy),
+		fmt.Sprintf(`ALTER TABLE %s ADD PRIMARY KEY (id, created_at)`, table),
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// createPartitions создает месячные секции с from по to включительно и секцию по умолчанию
+func createPartitions(db execer, table string, from, to time.Time) error {
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+			partitionName(table, month), table, month.Format("2006-01-02"), month.AddDate(0, 1, 0).Format("2006-01-02"))
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", partitionName(table, month), err)
+		}
+	}
+
+	// Секция по умолчанию принимает строки вне созданных диапазонов
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s_default PARTITION OF %s DEFAULT`, table, table)
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create default partition for %s: %w", table, err)
+	}
+	return nil
+}
+
+// EnsurePartitions создает секции на текущий и monthsAhead следующих месяцев
+func (db *DB) EnsurePartitions(monthsAhead int) error {
+	now := monthStart(time.Now().UTC())
+	for _, table := range partitionedTables {
+		if err := createPartitions(db, table, now, now.AddDate(0, monthsAhead, 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArchiveOldPartitions выгружает секции archivedTables старше afterMonths месяцев в bucket
+// архива (gzip, одна JSON строка на запись) и удаляет их из базы
+func ArchiveOldPartitions(ctx context.Context, db *DB, client *minio.Client, afterMonths int, storageClass string) error {
+	if afterMonths <= 0 {
+		return nil
+	}
+
+	cutoff := monthStart(time.Now().UTC()).AddDate(0, -afterMonths, 0)
+	for _, table := range archivedTables {
+		rows, err := db.Query(`SELECT c.relname FROM pg_inherits i
+		                       JOIN pg_class c ON c.oid = i.inhrelid
+		                       WHERE i.inhparent = $1::regclass ORDER BY c.relname`, table)
+		if err != nil {
+			return err
+		}
+		var partitions []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return err
+			}
+			partitions = append(partitions, name)
+		}
+		rows.Close()
+
+		for _, partition := range partitions {
+			month, err := time.Parse("2006_01", strings.TrimPrefix(partition, table+"_p"))
+			if err != nil || month.AddDate(0, 1, 0).After(cutoff) {
+				continue
+			}
+
+			if err := archivePartition(ctx, db, client, table, partition, month, storageClass); err != nil {
+				return fmt.Errorf("failed to archive %s: %w", partition, err)
+			}
+			log.Printf("Partition %s archived", partition)
+		}
+	}
+	return nil
+}
+
+func archivePartition(ctx context.Context, db *DB, client *minio.Client, table, partition string, month time.Time, storageClass string) error {
+	// Выгрузка большой секции может идти дольше statement_timeout
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SET LOCAL statement_timeout = 0`); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT row_to_json(t)::text FROM %s t ORDER BY id`, partition))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	reader, writer := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(writer)
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if _, err := io.WriteString(gz, line+"\n"); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			writer.CloseWithError(err)
+			return
+		}
+		writer.CloseWithError(gz.Close())
+	}()
+
+	objectKey := fmt.Sprintf("%s/%s.jsonl.gz", table, month.Format("2006-01"))
+	_, err = client.PutObject(ctx, BucketArchive, objectKey, reader, -1, minio.PutObjectOptions{
+		ContentType:     "application/x-ndjson",
+		ContentEncoding: "gzip",
+		StorageClass:    storageClass,
+	})
+	if err != nil {
+		reader.CloseWithError(err)
+		return err
+	}
+
+	// Завершаем чтение, чтобы снять блокировку с секции перед отсоединением
+	rows.Close()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Секция удаляется только после успешной загрузки в архив
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, table, partition)); err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf(`DROP TABLE %s`, partition))
+	return err
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"tmphackbackend/httpclient"
+)
+
+// Статусы оплаты пожертвования
+const (
+	// Платеж еще не создан у провайдера (ошибка при создании, повтор использует тот же ключ идемпотентности)
+	PaymentStatusNew       = "new"
+	PaymentStatusPending   = "pending"
+	PaymentStatusSucceeded = "succeeded"
+	PaymentStatusCanceled  = "canceled"
+)
+
+// PaymentRequest платеж, который нужно создать у провайдера
+type PaymentRequest struct {
+	// Ключ идемпотентности: повторный запрос с тем же ключом возвращает тот же платеж
+	IdempotenceKey string
+	DonationID     int64
+	Amount         float64
+	Currency       string
+	Description    string
+	ReturnURL      string
+}
+
+// ProviderPayment платеж на стороне провайдера
+type ProviderPayment struct {
+	ID     string
+	Status string
+	Amount float64
+	// Страница оплаты, на которую перенаправляется жертвователь
+	ConfirmationURL string
+}
+
+// PaymentProvider платежный шлюз (ЮKassa, Stripe)
+type PaymentProvider interface {
+	Name() string
+	CreatePayment(ctx context.Context, req PaymentRequest) (*ProviderPayment, error)
+	// ParseWebhook разбирает уведомление провайдера и возвращает проверенное
+	// состояние платежа. nil без ошибки - уведомление не о платеже.
+	ParseWebhook(ctx context.Context, r *http.Request) (*ProviderPayment, error)
+}
+
+// NewPaymentProvider создает платежный шлюз. Без провайдера оплата картой отключена,
+// пожертвования подтверждаются по чеку.
+func NewPaymentProvider(cfg PaymentConfig, client *httpclient.Client) (PaymentProvider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "yookassa":
+		if cfg.ShopID == "" || cfg.SecretKey == "" {
+			return nil, fmt.Errorf("PAYMENT_SHOP_ID and PAYMENT_SECRET_KEY are required for yookassa provider")
+		}
+		return &YooKassaProvider{
+			shopID:    cfg.ShopID,
+			secretKey: cfg.SecretKey,
+			client:    client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider: %s", cfg.Provider)
+	}
+}
+
+// YooKassaProvider платежи через API ЮKassa v3
+type YooKassaProvider struct {
+	shopID    string
+	secretKey string
+	client    *httpclient.Client
+}
+
+const yooKassaAPI = "https://api.yookassa.ru/v3"
+
+type yooKassaPayment struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Amount struct {
+		Value    string `json:"value"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+	Confirmation struct {
+		ConfirmationURL string `json:"confirmation_url"`
+	} `json:"confirmation"`
+}
+
+func (y *YooKassaProvider) Name() string {
+	return "yookassa"
+}
+
+func (y *YooKassaProvider) CreatePayment(ctx context.Context, req PaymentRequest) (*ProviderPayment, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"amount":       map[string]string{"value": strconv.FormatFloat(req.Amount, 'f', 2, 64), "currency": req.Currency},
+		"capture":      true,
+		"confirmation": map[string]string{"type": "redirect", "return_url": req.ReturnURL},
+		"description":  req.Description,
+		"metadata":     map[string]string{"donation_id": strconv.FormatInt(req.DonationID, 10)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, yooKassaAPI+"/payments", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Idempotence-Key", req.IdempotenceKey)
+	return y.do(httpReq)
+}
+
+// ParseWebhook не доверяет телу уведомления: ЮKassa их не подписывает, поэтому
+// состояние платежа перечитывается из API
+func (y *YooKassaProvider) ParseWebhook(ctx context.Context, r *http.Request) (*ProviderPayment, error) {
+	var notification struct {
+		Type   string `json:"type"`
+		Event  string `json:"event"`
+		Object struct {
+			ID string `json:"id"`
+		} `json:"object"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&notification); err != nil {
+		return nil, NewValidationError("Неверный формат уведомления", nil)
+	}
+	if notification.Type != "notification" || notification.Object.ID == "" {
+		return nil, NewValidationError("Неверный формат уведомления", nil)
+	}
+	switch notification.Event {
+	case "payment.succeeded", "payment.canceled", "payment.waiting_for_capture":
+	default:
+		return nil, nil
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, yooKassaAPI+"/payments/"+notification.Object.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment request: %w", err)
+	}
+	return y.do(httpReq)
+}
+
+func (y *YooKassaProvider) do(req *http.Request) (*ProviderPayment, error) {
+	req.SetBasicAuth(y.shopID, y.secretKey)
+	resp, err := y.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send payment request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("yookassa responded with status %d: %s", resp.StatusCode, body)
+	}
+
+	var payment yooKassaPayment
+	if err := json.Unmarshal(body, &payment); err != nil {
+		return nil, fmt.Errorf("failed to decode payment response: %w", err)
+	}
+	amount, err := strconv.ParseFloat(payment.Amount.Value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment amount %q", payment.Amount.Value)
+	}
+
+	status := PaymentStatusPending
+	switch payment.Status {
+	case "succeeded":
+		status = PaymentStatusSucceeded
+	case "canceled":
+		status = PaymentStatusCanceled
+	case "pending", "waiting_for_capture":
+	default:
+		log.Printf("Unknown yookassa payment status %q of payment %s", payment.Status, payment.ID)
+	}
+	return &ProviderPayment{
+		ID:              payment.ID,
+		Status:          status,
+		Amount:          amount,
+		ConfirmationURL: payment.Confirmation.ConfirmationURL,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Права доступа. Роли получают права через таблицу role_permissions.
+const (
+	PermVerificationsReview = "verifications.review"
+	PermPostsModerate       = "posts.moderate"
+	PermDonationsManage     = "donations.manage"
+	PermUsersView           = "users.view"
+	PermUsersBlock          = "users.block"
+	PermRolesManage         = "roles.manage"
+	PermBackupsView         = "backups.view"
+	PermLimitsManage        = "limits.manage"
+	PermSystemView          = "system.view"
+	PermInvitesManage       = "invites.manage"
+	// Просмотр сканов паспорта и фото из заявок на верификацию
+	PermVerificationDocumentsView = "verifications.documents"
+	PermAuditView                 = "audit.view"
+	// Акции с повышенным начислением баллов рейтинга
+	PermPromotionsManage = "promotions.manage"
+	// Вебхуки партнеров: адреса получают события платформы
+	PermWebhooksManage = "webhooks.manage"
+	// Повтор и закрытие заданий из очереди недоставленных
+	PermJobsManage = "jobs.manage"
+)
+
+// AllPermissions все известные права. Роль admin всегда получает их все.
+var AllPermissions = []string{
+	PermVerificationsReview,
+	PermPostsModerate,
+	PermDonationsManage,
+	PermUsersView,
+	PermUsersBlock,
+	PermRolesManage,
+	PermBackupsView,
+	PermLimitsManage,
+	PermSystemView,
+	PermInvitesManage,
+	PermVerificationDocumentsView,
+	PermAuditView,
+	PermPromotionsManage,
+	PermWebhooksManage,
+	PermJobsManage,
+}
+
+// RoleAdmin роль с полным набором прав
+const RoleAdmin = "admin"
+
+// RoleModerator роль для проверки верификаций и модерации постов.
+// Не может менять роли и подтверждать пожертвования (начислять рейтинг).
+const RoleModerator = "moderator"
+
+// moderatorDenied права, которые нельзя выдать роли moderator
+var moderatorDenied = map[string]bool{
+	PermRolesManage:     true,
+	PermDonationsManage: true,
+	PermUsersBlock:      true,
+	PermLimitsManage:    true,
+	// Паспортные данные по умолчанию видит только admin
+	PermVerificationDocumentsView: true,
+	PermAuditView:                 true,
+	PermPromotionsManage:          true,
+	PermWebhooksManage:            true,
+	PermJobsManage:                true,
+}
+
+// CanGrant проверяет, можно ли выдать право роли
+func CanGrant(role, permission string) bool {
+	return !(role == RoleModerator && moderatorDenied[permission])
+}
+
+// IsKnownPermission проверяет, что право есть в AllPermissions
+func IsKnownPermission(permission string) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions кэширует матрицу прав ролей из базы, чтобы не читать ее на каждый запрос
+type Permissions struct {
+	db  *DB
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	byRole   map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func NewPermissions(db *DB, ttl time.Duration) *Permissions {
+	return &Permissions{db: db, ttl: ttl}
+}
+
+// Has проверяет, есть ли у роли право. При ошибке чтения из базы
+// используется последняя загруженная матрица.
+func (p *Permissions) Has(role, permission string) bool {
+	p.mu.RLock()
+	fresh := p.byRole != nil && time.Since(p.loadedAt) < p.ttl
+	granted := p.byRole[role][permission]
+	p.mu.RUnlock()
+	if fresh {
+		return granted
+	}
+
+	if err := p.reload(); err != nil {
+		log.Printf("Failed to load role permissions: %v", err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.byRole[role][permission]
+}
+
+// Invalidate сбрасывает кэш после изменения прав
+func (p *Permissions) Invalidate() {
+	p.mu.Lock()
+	p.loadedAt = time.Time{}
+	p.mu.Unlock()
+}
+
+func (p *Permissions) reload() error {
+	byRole, err := p.db.GetRolePermissions()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.byRole = byRole
+	p.loadedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
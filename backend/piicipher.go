@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// piiPrefix отличает зашифрованные значения от открытых, записанных до включения шифрования
+const piiPrefix = "enc:v1:"
+
+// PIICipher шифрует персональные данные (серия и номер паспорта, ИНН, СНИЛС) перед
+// записью в базу: AES-256-GCM со случайным nonce. Значение хранится как
+// enc:v1:{id ключа}:{base64(nonce + шифротекст)}, имя столбца входит в
+// дополнительные данные, поэтому значение нельзя перенести в другой столбец.
+// Старые ключи используются только для расшифровки, пока данные не перешифрованы.
+type PIICipher struct {
+	keyID   string
+	current cipher.AEAD
+	keys    map[string]cipher.AEAD
+}
+
+// NewPIICipher создает шифр из ключей в base64 (32 байта). Без ключа возвращает nil:
+// данные хранятся открыто.
+func NewPIICipher(cfg PIIConfig) (*PIICipher, error) {
+	if cfg.Key == "" {
+		if len(cfg.OldKeys) > 0 {
+			return nil, fmt.Errorf("PII_ENCRYPTION_OLD_KEYS is set without PII_ENCRYPTION_KEY")
+		}
+		return nil, nil
+	}
+
+	c := &PIICipher{keys: make(map[string]cipher.AEAD)}
+	for i, encoded := range append([]string{cfg.Key}, cfg.OldKeys...) {
+		keyID, aead, err := newPIIKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			c.keyID, c.current = keyID, aead
+		}
+		c.keys[keyID] = aead
+	}
+	return c, nil
+}
+
+func newPIIKey(encoded string) (string, cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid PII encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return "", nil, fmt.Errorf("invalid PII encryption key: want 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4]), aead, nil
+}
+
+// Encrypt шифрует значение столбца column. Без ключа возвращает значение как есть.
+func (c *PIICipher) Encrypt(column, value string) (string, error) {
+	if c == nil {
+		return value, nil
+	}
+	nonce := make([]byte, c.current.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.current.Seal(nonce, nonce, []byte(value), []byte(column))
+	return piiPrefix + c.keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение столбца column. Открытые значения возвращаются как есть.
+func (c *PIICipher) Decrypt(column, stored string) (string, error) {
+	if !strings.HasPrefix(stored, piiPrefix) {
+		return stored, nil
+	}
+	if c == nil {
+		return "", fmt.Errorf("%s is encrypted, but PII_ENCRYPTION_KEY is not set", column)
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(stored, piiPrefix), ":")
+	if !ok {
+		return "", fmt.Errorf("%s: malformed encrypted value", column)
+	}
+	aead, ok := c.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%s: unknown encryption key %s", column, keyID)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("%s: malformed encrypted value", column)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(column))
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to decrypt: %w", column, err)
+	}
+	return string(plain), nil
+}
+
+// encryptOptional шифрует необязательное значение
+func (c *PIICipher) encryptOptional(column string, value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encrypted, err := c.Encrypt(column, *value)
+	if err != nil {
+		return nil, err
+	}
+	return &encrypted, nil
+}
+
+// decryptOptional расшифровывает необязательное значение
+func (c *PIICipher) decryptOptional(column string, value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	plain, err := c.Decrypt(column, *value)
+	if err != nil {
+		return nil, err
+	}
+	return &plain, nil
+}
+
+// encryptDocumentData шифрует поля документа, хранящиеся в document_data, и
+// возвращает JSON для записи. Пустые данные записываются как NULL.
+func (c *PIICipher) encryptDocumentData(data map[string]string) (*string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	encrypted := make(map[string]string, len(data))
+	for name, value := range data {
+		var err error
+		if encrypted[name], err = c.Encrypt("document_data."+name, value); err != nil {
+			return nil, err
+		}
+	}
+	encoded, err := json.Marshal(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	s := string(encoded)
+	return &s, nil
+}
+
+// encryptVerificationPII шифрует персональные данные заявки перед записью
+func (c *PIICipher) encryptVerificationPII(v *Verification) (series, number string, inn, snils *string, err error) {
+	if series, err = c.Encrypt("passport_series", v.PassportSeries); err != nil {
+		return
+	}
+	if number, err = c.Encrypt("passport_number", v.PassportNumber); err != nil {
+		return
+	}
+	if inn, err = c.encryptOptional("inn", v.INN); err != nil {
+		return
+	}
+	snils, err = c.encryptOptional("snils", v.SNILS)
+	return
+}
+
+// decryptVerificationPII расшифровывает персональные данные прочитанной заявки
+func (c *PIICipher) decryptVerificationPII(v *Verification) error {
+	var err error
+	if v.PassportSeries, err = c.Decrypt("passport_series", v.PassportSeries); err != nil {
+		return err
+	}
+	if v.PassportNumber, err = c.Decrypt("passport_number", v.PassportNumber); err != nil {
+		return err
+	}
+	if v.INN, err = c.decryptOptional("inn", v.INN); err != nil {
+		return err
+	}
+	if v.SNILS, err = c.decryptOptional("snils", v.SNILS); err != nil {
+		return err
+	}
+	for name, value := range v.DocumentData {
+		if v.DocumentData[name], err = c.Decrypt("document_data."+name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encryptStoredVerificationPII шифрует текущим ключом персональные данные заявок,
+// записанные открыто или старым ключом. Выполняется при запуске, заявки
+// обрабатываются по одной, поэтому повторный запуск продолжает с места остановки.
+func (db *DB) encryptStoredVerificationPII() error {
+	if db.pii == nil {
+		var plain int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM verifications`).Scan(&plain); err == nil && plain > 0 {
+			log.Printf("WARNING: PII_ENCRYPTION_KEY is not set, passport data of new verifications is stored unencrypted")
+		}
+		return nil
+	}
+
+	rows, err := db.Query(`SELECT id, passport_series, passport_number, inn, snils, document_data FROM verifications
+	                       WHERE NOT (passport_series LIKE $1 AND passport_number LIKE $1
+	                                  AND COALESCE(inn, $2) LIKE $1 AND COALESCE(snils, $2) LIKE $1)
+	                          OR EXISTS (SELECT 1 FROM jsonb_each_text(document_data) d WHERE d.value NOT LIKE $1)`,
+		piiPrefix+db.pii.keyID+":%", piiPrefix+db.pii.keyID+":")
+	if err != nil {
+		return err
+	}
+	var pending []Verification
+	for rows.Next() {
+		var v Verification
+		var documentData []byte
+		if err := rows.Scan(&v.ID, &v.PassportSeries, &v.PassportNumber, &v.INN, &v.SNILS, &documentData); err != nil {
+			rows.Close()
+			return err
+		}
+		if documentData != nil {
+			if err := json.Unmarshal(documentData, &v.DocumentData); err != nil {
+				rows.Close()
+				return fmt.Errorf("verification %d: %w", v.ID, err)
+			}
+		}
+		pending = append(pending, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	log.Printf("Encrypting passport data of %d verifications", len(pending))
+	for i := range pending {
+		v := &pending[i]
+		if err := db.pii.decryptVerificationPII(v); err != nil {
+			return fmt.Errorf("verification %d: %w", v.ID, err)
+		}
+		series, number, inn, snils, err := db.pii.encryptVerificationPII(v)
+		if err != nil {
+			return fmt.Errorf("verification %d: %w", v.ID, err)
+		}
+		documentData, err := db.pii.encryptDocumentData(v.DocumentData)
+		if err != nil {
+			return fmt.Errorf("verification %d: %w", v.ID, err)
+		}
+		query := `UPDATE verifications SET passport_series = $2, passport_number = $3, inn = $4, snils = $5, document_data = $6 WHERE id = $1`
+		if _, err := db.Exec(query, v.ID, series, number, inn, snils, documentData); err != nil {
+			return fmt.Errorf("verification %d: %w", v.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func newTestPIIKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newTestPIICipher(t *testing.T, key string, oldKeys ...string) *PIICipher {
+	t.Helper()
+	c, err := NewPIICipher(PIIConfig{Key: key, OldKeys: oldKeys})
+	if err != nil {
+		t.Fatalf("NewPIICipher: %v", err)
+	}
+	return c
+}
+
+// Зашифрованное значение расшифровывается в исходное, открытое значение возвращается как есть
+func TestPIICipherRoundTrip(t *testing.T) {
+	c := newTestPIICipher(t, newTestPIIKey(t))
+
+	for _, value := range []string{"4510", "123456", "", "Серия АБ №1"} {
+		stored, err := c.Encrypt("passport_number", value)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", value, err)
+		}
+		if !strings.HasPrefix(stored, piiPrefix+c.keyID+":") || (value != "" && strings.Contains(stored, value)) {
+			t.Errorf("Encrypt(%q) = %q, want encrypted value", value, stored)
+		}
+		plain, err := c.Decrypt("passport_number", stored)
+		if err != nil || plain != value {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", value, plain, err)
+		}
+	}
+
+	again, _ := c.Encrypt("passport_number", "123456")
+	first, _ := c.Encrypt("passport_number", "123456")
+	if again == first {
+		t.Error("Encrypt is deterministic, want random nonce")
+	}
+	if plain, err := c.Decrypt("passport_number", "123456"); err != nil || plain != "123456" {
+		t.Errorf("Decrypt(plain) = %q, %v", plain, err)
+	}
+
+	var disabled *PIICipher
+	if stored, err := disabled.Encrypt("inn", "500100732259"); err != nil || stored != "500100732259" {
+		t.Errorf("Encrypt without key = %q, %v", stored, err)
+	}
+	if _, err := disabled.Decrypt("inn", first); err == nil {
+		t.Error("Decrypt without key accepted encrypted value")
+	}
+}
+
+// После смены ключа старые значения расшифровываются старым ключом, новые шифруются новым
+func TestPIICipherKeyRotation(t *testing.T) {
+	oldKey, newKey := newTestPIIKey(t), newTestPIIKey(t)
+	before := newTestPIICipher(t, oldKey)
+	stored, err := before.Encrypt("snils", "11223344595")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	after := newTestPIICipher(t, newKey, oldKey)
+	if after.keyID == before.keyID {
+		t.Fatal("key id did not change with key")
+	}
+	if plain, err := after.Decrypt("snils", stored); err != nil || plain != "11223344595" {
+		t.Errorf("Decrypt with old key = %q, %v", plain, err)
+	}
+	reencrypted, err := after.Encrypt("snils", "11223344595")
+	if err != nil || !strings.HasPrefix(reencrypted, piiPrefix+after.keyID+":") {
+		t.Errorf("Encrypt after rotation = %q, %v, want new key id", reencrypted, err)
+	}
+
+	withoutOld := newTestPIICipher(t, newKey)
+	if _, err := withoutOld.Decrypt("snils", stored); err == nil {
+		t.Error("Decrypt accepted value of removed key")
+	}
+
+	if _, err := NewPIICipher(PIIConfig{OldKeys: []string{oldKey}}); err == nil {
+		t.Error("NewPIICipher accepted old keys without current key")
+	}
+	if _, err := NewPIICipher(PIIConfig{Key: base64.StdEncoding.EncodeToString([]byte("short"))}); err == nil {
+		t.Error("NewPIICipher accepted short key")
+	}
+}
+
+// Измененный шифротекст и перенос значения в другой столбец не проходят проверку GCM
+func TestPIICipherTamper(t *testing.T) {
+	c := newTestPIICipher(t, newTestPIIKey(t))
+	stored, err := c.Encrypt("passport_number", "123456")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	prefix := piiPrefix + c.keyID + ":"
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, prefix))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for i := range sealed {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x01
+		if _, err := c.Decrypt("passport_number", prefix+base64.StdEncoding.EncodeToString(tampered)); err == nil {
+			t.Fatalf("Decrypt accepted value with byte %d changed", i)
+		}
+	}
+
+	if _, err := c.Decrypt("passport_series", stored); err == nil {
+		t.Error("Decrypt accepted value moved to another column")
+	}
+	for _, malformed := range []string{piiPrefix + c.keyID, prefix + "не base64", prefix + "AAAA", piiPrefix + "00000000:" + strings.TrimPrefix(stored, prefix)} {
+		if _, err := c.Decrypt("passport_number", malformed); err == nil {
+			t.Errorf("Decrypt(%q) accepted malformed value", malformed)
+		}
+	}
+}
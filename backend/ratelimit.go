@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitStatus состояние лимита запросов в текущем окне
+type RateLimitStatus struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// RateLimiter считает запросы в окнах фиксированной длины, выровненных по часам.
+// Счетчики хранятся в памяти процесса и сбрасываются с началом нового окна.
+type RateLimiter struct {
+	window time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+func NewRateLimiter(window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		window: window,
+		counts: make(map[string]int),
+	}
+}
+
+// Allow учитывает запрос с ключом key. Возвращает false, если лимит в окне исчерпан.
+func (l *RateLimiter) Allow(key string, limit int) (RateLimitStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rotate(time.Now())
+	allowed := l.counts[key] < limit
+	if allowed {
+		l.counts[key]++
+	}
+	return l.status(key, limit), allowed
+}
+
+// Status возвращает состояние лимита, не учитывая запрос
+func (l *RateLimiter) Status(key string, limit int) RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rotate(time.Now())
+	return l.status(key, limit)
+}
+
+func (l *RateLimiter) rotate(now time.Time) {
+	if now.Before(l.windowStart.Add(l.window)) {
+		return
+	}
+	l.windowStart = now.Truncate(l.window)
+	l.counts = make(map[string]int)
+}
+
+func (l *RateLimiter) status(key string, limit int) RateLimitStatus {
+	return RateLimitStatus{
+		Limit:     limit,
+		Remaining: max(limit-l.counts[key], 0),
+		Reset:     l.windowStart.Add(l.window),
+	}
+}
+
+// rateLimitKey возвращает ключ счетчика и лимит для запроса: запросы по API токену
+// считаются отдельно для каждого токена, остальные - для пользователя.
+// Нулевой лимит означает, что ограничение отключено.
+func rateLimitKey(r *http.Request, cfg RateLimitConfig) (string, int, string) {
+	if token, ok := r.Context().Value(APITokenKey).(*APIToken); ok {
+		return fmt.Sprintf("token:%d", token.ID), cfg.APITokenRequests, "api_token"
+	}
+	userID, _ := GetUserIDFromContext(r.Context())
+	return fmt.Sprintf("user:%d", userID), cfg.UserRequests, "user"
+}
+
+// RateLimitMiddleware ограничивает число запросов аутентифицированного клиента и
+// сообщает состояние лимита в заголовках X-RateLimit-*. Подключается после аутентификации.
+func RateLimitMiddleware(limiter *RateLimiter, cfg RateLimitConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, limit, kind := rateLimitKey(r, cfg)
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			status, allowed := limiter.Allow(key, limit)
+			setRateLimitHeaders(w, status)
+			if !allowed {
+				metrics.Inc("rate_limited_requests_total", "Requests rejected by the rate limit", map[string]string{"kind": kind})
+				retryAfter := int(time.Until(status.Reset).Seconds()) + 1
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				WriteError(w, NewTooManyRequestsError("Слишком много запросов, повторите попытку позже"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, status RateLimitStatus) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(status.Reset.Unix(), 10))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// DonationReceipt данные квитанции о подтвержденном пожертвовании
+type DonationReceipt struct {
+	DonationID  int64
+	PostTitle   string
+	Recipient   string
+	DonorName   string
+	Amount      float64
+	ConfirmedAt time.Time
+}
+
+// ReceiptToken подпись ссылки на квитанцию: по ней квитанция открывается из SMS
+// или push уведомления без входа в приложение
+func ReceiptToken(secret string, donationID int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("donation_receipt:" + strconv.FormatInt(donationID, 10)))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// ValidReceiptToken проверяет подпись ссылки на квитанцию
+func ValidReceiptToken(secret string, donationID int64, token string) bool {
+	return hmac.Equal([]byte(token), []byte(ReceiptToken(secret, donationID)))
+}
+
+// ReceiptURL ссылка на квитанцию пожертвования по шаблону NOTIFICATION_RECEIPT_URL
+func ReceiptURL(cfg *Config, donationID int64) string {
+	return strings.NewReplacer(
+		"{id}", strconv.FormatInt(donationID, 10),
+		"{token}", ReceiptToken(cfg.JWTSecret, donationID),
+	).Replace(cfg.Notifications.ReceiptURL)
+}
+
+// RenderDonationReceipt создает PDF квитанцию A5 о подтвержденном пожертвовании.
+// Дата подтверждения выводится в часовом поясе loc.
+func RenderDonationReceipt(receipt DonationReceipt, loc *time.Location) ([]byte, error) {
+	const (
+		margin = 15.0
+		width  = 148.0 - 2*margin
+	)
+
+	pdf := fpdf.New("P", "mm", "A5", "")
+	pdf.SetTitle(fmt.Sprintf("Квитанция о пожертвовании №%d", receipt.DonationID), true)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetMargins(margin, margin, margin)
+	pdf.AddUTF8FontFromBytes("Go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("Go", "B", gobold.TTF)
+	pdf.AddPage()
+
+	y := margin
+	pdf.SetTextColor(0x2f, 0x9e, 0x44)
+	pdf.SetFont("Go", "B", 11)
+	pdf.Text(margin, y+4, "КВИТАНЦИЯ О ПОЖЕРТВОВАНИИ")
+	y += 8
+
+	pdf.SetTextColor(0x21, 0x25, 0x29)
+	pdf.SetFont("Go", "B", 18)
+	y += 8
+	pdf.Text(margin, y, fmt.Sprintf("№%d", receipt.DonationID))
+	y += 6
+
+	pdf.SetDrawColor(0xde, 0xe2, 0xe6)
+	pdf.SetLineWidth(0.3)
+	pdf.Line(margin, y, margin+width, y)
+	y += 2
+
+	fields := [][2]string{
+		{"Сбор", receipt.PostTitle},
+		{"Получатель", receipt.Recipient},
+		{"Жертвователь", receipt.DonorName},
+		{"Сумма", formatAmount(receipt.Amount) + " руб."},
+		{"Дата подтверждения", receipt.ConfirmedAt.In(loc).Format("02.01.2006 15:04")},
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		pdf.SetFont("Go", "", 9)
+		pdf.SetTextColor(0x6c, 0x75, 0x7d)
+		y += 6
+		pdf.Text(margin, y, field[0])
+		pdf.SetFont("Go", "B", 12)
+		pdf.SetTextColor(0x21, 0x25, 0x29)
+		for _, line := range limitLines(pdf.SplitText(field[1], width), 3) {
+			y += 5.5
+			pdf.Text(margin, y, line)
+		}
+	}
+
+	y += 10
+	pdf.SetFont("Go", "", 9)
+	pdf.SetTextColor(0x6c, 0x75, 0x7d)
+	for _, line := range pdf.SplitText("Пожертвование подтверждено автором сбора. Квитанция сформирована автоматически и не является "+
+		"фискальным чеком.", width) {
+		y += 4.5
+		pdf.Text(margin, y, line)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render receipt: %w", err)
+	}
+	return buf.Bytes(), nil
+}
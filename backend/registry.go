@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"tmphackbackend/httpclient"
+)
+
+// Результаты сверки организации заявителя с реестром
+const (
+	OrganizationCheckPending  = "pending"
+	OrganizationCheckMatched  = "matched"
+	OrganizationCheckMismatch = "mismatch"
+	OrganizationCheckNotFound = "not_found"
+)
+
+// Расхождения заявки с реестром
+const (
+	// Название в заявке не совпадает ни с кратким, ни с полным названием из реестра
+	OrganizationMismatchName = "name"
+	// ОГРН в заявке не совпадает с реестром
+	OrganizationMismatchOGRN = "ogrn"
+	// Организация ликвидирована, ликвидируется, банкрот или реорганизуется
+	OrganizationMismatchInactive = "inactive"
+	// Организация коммерческая по ОКОПФ
+	OrganizationMismatchNotNonProfit = "not_nonprofit"
+)
+
+var (
+	organizationINNPattern  = regexp.MustCompile(`^\d{10}$`)
+	organizationOGRNPattern = regexp.MustCompile(`^\d{13}$`)
+	// Кавычки, знаки препинания и лишние пробелы при сравнении названий не учитываются
+	organizationNameNoise = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// RegistryProvider ищет организацию в реестре юридических лиц по ИНН.
+// Организации нет в реестре - nil без ошибки.
+type RegistryProvider interface {
+	FindByINN(ctx context.Context, inn string) (*RegistryOrganization, error)
+}
+
+// NewRegistryProvider создает провайдера реестра. Без провайдера сверка организаций отключена.
+func NewRegistryProvider(cfg RegistryConfig, client *httpclient.Client) RegistryProvider {
+	switch cfg.Provider {
+	case "dadata":
+		return &DaDataRegistry{apiKey: cfg.APIKey, client: client}
+	case "":
+		return nil
+	default:
+		log.Printf("Unknown registry provider %q, organization check disabled", cfg.Provider)
+		return nil
+	}
+}
+
+// DaDataRegistry поиск по ЕГРЮЛ через DaData (findById/party)
+type DaDataRegistry struct {
+	apiKey string
+	client *httpclient.Client
+}
+
+func (d *DaDataRegistry) FindByINN(ctx context.Context, inn string) (*RegistryOrganization, error) {
+	payload, err := json.Marshal(map[string]string{"query": inn, "branch_type": "MAIN"})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://suggestions.dadata.ru/suggestions/api/4_1/rs/findById/party", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Token "+d.apiKey)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send registry request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry responded with status %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Suggestions []struct {
+			Data struct {
+				INN  string `json:"inn"`
+				OGRN string `json:"ogrn"`
+				KPP  string `json:"kpp"`
+				Name struct {
+					Short string `json:"short_with_opf"`
+					Full  string `json:"full_with_opf"`
+				} `json:"name"`
+				OPF struct {
+					Code string `json:"code"`
+				} `json:"opf"`
+				State struct {
+					Status           string `json:"status"`
+					RegistrationDate *int64 `json:"registration_date"`
+				} `json:"state"`
+				Address struct {
+					Value string `json:"value"`
+				} `json:"address"`
+			} `json:"data"`
+		} `json:"suggestions"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	if len(result.Suggestions) == 0 {
+		return nil, nil
+	}
+
+	data := result.Suggestions[0].Data
+	org := &RegistryOrganization{
+		INN:      data.INN,
+		OGRN:     data.OGRN,
+		KPP:      data.KPP,
+		Name:     data.Name.Short,
+		FullName: data.Name.Full,
+		Address:  data.Address.Value,
+		Status:   data.State.Status,
+		// ОКОПФ 2xxxx - некоммерческие корпоративные организации, 7xxxx - учреждения и
+		// другие унитарные некоммерческие организации
+		NonProfit: strings.HasPrefix(data.OPF.Code, "2") || strings.HasPrefix(data.OPF.Code, "7"),
+	}
+	if org.Name == "" {
+		org.Name = org.FullName
+	}
+	if data.State.RegistrationDate != nil {
+		registered := time.UnixMilli(*data.State.RegistrationDate).UTC()
+		org.RegisteredAt = &registered
+	}
+	return org, nil
+}
+
+// ApplyOrganization проверяет реквизиты организации заявителя (value возвращает значение
+// поля формы) и записывает их в заявку. Организация необязательна: без ИНН ОГРН и название
+// не принимаются.
+func ApplyOrganization(v *Verification, value func(name string) string) error {
+	inn := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(value("organization_inn")))
+	ogrn := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(value("organization_ogrn")))
+	name := strings.TrimSpace(value("organization_name"))
+	if inn == "" {
+		if ogrn != "" || name != "" {
+			return NewValidationError("Укажите ИНН организации", map[string]interface{}{"field": "organization_inn"})
+		}
+		return nil
+	}
+
+	if !organizationINNPattern.MatchString(inn) || !validOrgINN(inn) {
+		return NewValidationError("Неверный ИНН организации", map[string]interface{}{"field": "organization_inn"})
+	}
+	v.OrganizationINN = &inn
+	if ogrn != "" {
+		if !organizationOGRNPattern.MatchString(ogrn) || !validOGRN(ogrn) {
+			return NewValidationError("Неверный ОГРН организации", map[string]interface{}{"field": "organization_ogrn"})
+		}
+		v.OrganizationOGRN = &ogrn
+	}
+	if name != "" {
+		if len([]rune(name)) > 500 {
+			return NewValidationError("Название организации слишком длинное", map[string]interface{}{"field": "organization_name"})
+		}
+		v.OrganizationName = &name
+	}
+	return nil
+}
+
+// OrganizationCheckResult результат сверки организации заявителя с реестром
+type OrganizationCheckResult struct {
+	Status     string
+	Mismatches []string
+	Registry   *RegistryOrganization
+}
+
+// CheckOrganization сравнивает реквизиты из заявки со сведениями реестра (org=nil -
+// организации в реестре нет). Не указанные в заявке ОГРН и название не проверяются:
+// они заполняются из реестра.
+func CheckOrganization(v Verification, org *RegistryOrganization) OrganizationCheckResult {
+	if org == nil {
+		return OrganizationCheckResult{Status: OrganizationCheckNotFound}
+	}
+
+	result := OrganizationCheckResult{Status: OrganizationCheckMatched, Registry: org}
+	if v.OrganizationOGRN != nil && *v.OrganizationOGRN != org.OGRN {
+		result.Mismatches = append(result.Mismatches, OrganizationMismatchOGRN)
+	}
+	if v.OrganizationName != nil {
+		name := normalizeOrganizationName(*v.OrganizationName)
+		if name != normalizeOrganizationName(org.Name) && name != normalizeOrganizationName(org.FullName) {
+			result.Mismatches = append(result.Mismatches, OrganizationMismatchName)
+		}
+	}
+	if org.Status != "ACTIVE" {
+		result.Mismatches = append(result.Mismatches, OrganizationMismatchInactive)
+	}
+	if !org.NonProfit {
+		result.Mismatches = append(result.Mismatches, OrganizationMismatchNotNonProfit)
+	}
+	if len(result.Mismatches) > 0 {
+		result.Status = OrganizationCheckMismatch
+	}
+	return result
+}
+
+func normalizeOrganizationName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+	return strings.TrimSpace(organizationNameNoise.ReplaceAllString(name, " "))
+}
+
+// OrganizationRegistry ищет организации в реестре с кэшированием ответов в registry_organizations
+// и сверяет с реестром организации из заявок на верификацию. Сверка запускается периодической
+// задачей планировщика.
+type OrganizationRegistry struct {
+	db        *DB
+	provider  RegistryProvider
+	cacheTTL  time.Duration
+	batchSize int
+}
+
+// NewOrganizationRegistry возвращает nil без провайдера
+func NewOrganizationRegistry(db *DB, provider RegistryProvider, cfg RegistryConfig) *OrganizationRegistry {
+	if provider == nil {
+		return nil
+	}
+	return &OrganizationRegistry{db: db, provider: provider, cacheTTL: cfg.CacheTTL, batchSize: cfg.BatchSize}
+}
+
+// Lookup возвращает сведения реестра по ИНН: из кэша, если ответ свежее CacheTTL, иначе от
+// провайдера. Отсутствие организации в реестре тоже кэшируется.
+func (r *OrganizationRegistry) Lookup(ctx context.Context, inn string) (*RegistryOrganization, error) {
+	org, found, err := r.db.GetCachedOrganization(inn, time.Now().Add(-r.cacheTTL))
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		metrics.Inc("registry_lookups_total", "Organization registry lookups by source", map[string]string{"source": "cache"})
+		return org, nil
+	}
+
+	org, err = r.provider.FindByINN(ctx, inn)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Inc("registry_lookups_total", "Organization registry lookups by source", map[string]string{"source": "provider"})
+	if err := r.db.CacheOrganization(inn, org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// Run сверяет пачку организаций из заявок, ожидающих сверки. При ошибке провайдера
+// оставшиеся заявки остаются в очереди до следующего запуска.
+func (r *OrganizationRegistry) Run(ctx context.Context) error {
+	verifications, err := r.db.GetVerificationsPendingOrganizationCheck(r.batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range verifications {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		org, err := r.Lookup(ctx, *v.OrganizationINN)
+		if err != nil {
+			return fmt.Errorf("failed to look up organization of verification %d: %w", v.ID, err)
+		}
+
+		result := CheckOrganization(v, org)
+		if err := r.db.SaveOrganizationCheck(v.ID, result); err != nil {
+			return err
+		}
+		metrics.Inc("organization_checks_total", "Organization registry checks by result", map[string]string{"result": result.Status})
+	}
+	return nil
+}
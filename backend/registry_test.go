@@ -0,0 +1,113 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// Сверка организации с реестром: отсутствие в реестре, совпадение и каждое расхождение
+func TestCheckOrganization(t *testing.T) {
+	sber := func() *RegistryOrganization {
+		return &RegistryOrganization{
+			INN:       "7707083893",
+			OGRN:      "1027700132195",
+			Name:      "ПАО СБЕРБАНК",
+			FullName:  `ПУБЛИЧНОЕ АКЦИОНЕРНОЕ ОБЩЕСТВО "СБЕРБАНК РОССИИ"`,
+			Status:    "ACTIVE",
+			NonProfit: true,
+		}
+	}
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name       string
+		ogrn, org  *string
+		registry   func(*RegistryOrganization) *RegistryOrganization
+		status     string
+		mismatches []string
+	}{
+		{
+			name:     "not found",
+			registry: func(*RegistryOrganization) *RegistryOrganization { return nil },
+			status:   OrganizationCheckNotFound,
+		},
+		{
+			name:   "only inn",
+			status: OrganizationCheckMatched,
+		},
+		{
+			name:   "full name with other quotes and case",
+			ogrn:   ptr("1027700132195"),
+			org:    ptr("Публичное акционерное общество «Сбербанк России»"),
+			status: OrganizationCheckMatched,
+		},
+		{
+			name:       "other name and ogrn",
+			ogrn:       ptr("1027739609391"),
+			org:        ptr("Фонд помощи"),
+			status:     OrganizationCheckMismatch,
+			mismatches: []string{OrganizationMismatchOGRN, OrganizationMismatchName},
+		},
+		{
+			name: "liquidated commercial",
+			registry: func(org *RegistryOrganization) *RegistryOrganization {
+				org.Status, org.NonProfit = "LIQUIDATED", false
+				return org
+			},
+			status:     OrganizationCheckMismatch,
+			mismatches: []string{OrganizationMismatchInactive, OrganizationMismatchNotNonProfit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			org := sber()
+			if tt.registry != nil {
+				org = tt.registry(org)
+			}
+			v := Verification{OrganizationINN: ptr("7707083893"), OrganizationOGRN: tt.ogrn, OrganizationName: tt.org}
+			result := CheckOrganization(v, org)
+			if result.Status != tt.status || !slices.Equal(result.Mismatches, tt.mismatches) {
+				t.Errorf("CheckOrganization = %s %v, want %s %v", result.Status, result.Mismatches, tt.status, tt.mismatches)
+			}
+			if result.Registry != org {
+				t.Errorf("registry data is not kept in result")
+			}
+		})
+	}
+}
+
+// Реквизиты организации в заявке: ИНН обязателен для остальных полей, номера проверяются по контрольной цифре
+func TestApplyOrganization(t *testing.T) {
+	tests := []struct {
+		name   string
+		form   map[string]string
+		field  string
+		wantOK bool
+	}{
+		{name: "empty", form: map[string]string{}, wantOK: true},
+		{name: "inn with spaces", form: map[string]string{"organization_inn": "77 0708 3893"}, wantOK: true},
+		{name: "inn and ogrn", form: map[string]string{"organization_inn": "7707083893", "organization_ogrn": "1027700132195"}, wantOK: true},
+		{name: "ogrn without inn", form: map[string]string{"organization_ogrn": "1027700132195"}, field: "organization_inn"},
+		{name: "personal inn", form: map[string]string{"organization_inn": "500100732259"}, field: "organization_inn"},
+		{name: "inn checksum", form: map[string]string{"organization_inn": "7707083894"}, field: "organization_inn"},
+		{name: "ogrn checksum", form: map[string]string{"organization_inn": "7707083893", "organization_ogrn": "1027700132196"}, field: "organization_ogrn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Verification
+			err := ApplyOrganization(&v, func(name string) string { return tt.form[name] })
+			if tt.wantOK {
+				if err != nil {
+					t.Fatalf("ApplyOrganization: %v", err)
+				}
+				return
+			}
+			appErr, ok := err.(*AppError)
+			if !ok || appErr.Details["field"] != tt.field {
+				t.Errorf("ApplyOrganization = %v, want validation error of %s", err, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "fmt"
+
+// minResponseSamples минимум чатов с ответом автора для расчета времени ответа
+const minResponseSamples = 3
+
+// responseStatsWindow за сколько дней учитываются чаты при расчете времени ответа
+const responseStatsWindow = 90
+
+// NewResponseTime формирует подсказку о времени ответа автора по медиане в секундах
+func NewResponseTime(medianSeconds int) *ResponseTime {
+	minutes := (medianSeconds + 59) / 60
+	rt := &ResponseTime{MedianMinutes: minutes}
+
+	switch {
+	case minutes <= 60:
+		// Округляем вверх до 5 минут, точнее пользователю не нужно
+		rounded := max(5, (minutes+4)/5*5)
+		if rounded == 60 {
+			rt.Hint = "Обычно отвечает в течение часа"
+		} else {
+			rt.Hint = fmt.Sprintf("Обычно отвечает в течение %d %s", rounded, pluralRu(rounded, "минуты", "минут", "минут"))
+		}
+	case minutes <= 24*60:
+		hours := (minutes + 59) / 60
+		if hours == 24 {
+			rt.Hint = "Обычно отвечает в течение дня"
+		} else {
+			rt.Hint = fmt.Sprintf("Обычно отвечает в течение %d %s", hours, pluralRu(hours, "часа", "часов", "часов"))
+		}
+	default:
+		days := (minutes + 24*60 - 1) / (24 * 60)
+		rt.Hint = fmt.Sprintf("Обычно отвечает в течение %d %s", days, pluralRu(days, "дня", "дней", "дней"))
+	}
+	return rt
+}
+
+// pluralRu выбирает форму слова для числа: one - 1, 21; few - 2-4, 22-24; many - остальные
+func pluralRu(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return few
+	default:
+		return many
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// retentionBounds вычисляет границы сроков хранения на момент now. Нулевой срок
+// оставляет границу пустой, и соответствующая очистка не выполняется.
+func retentionBounds(cfg RetentionConfig, now time.Time) *RetentionReport {
+	report := &RetentionReport{Enabled: cfg.Enabled}
+	bound := func(t time.Time) *time.Time { return &t }
+	if cfg.InactiveYears > 0 {
+		report.InactiveBefore = bound(now.AddDate(-cfg.InactiveYears, 0, 0))
+	}
+	if cfg.UnverifiedDays > 0 {
+		report.UnverifiedBefore = bound(now.AddDate(0, 0, -cfg.UnverifiedDays))
+	}
+	if cfg.OTPDays > 0 {
+		report.OTPExpiredBefore = bound(now.AddDate(0, 0, -cfg.OTPDays))
+	}
+	if cfg.ChatAttachmentDays > 0 {
+		report.ChatAttachmentsBefore = bound(now.AddDate(0, 0, -cfg.ChatAttachmentDays))
+	}
+	if cfg.VerificationDocsLockDays > 0 {
+		report.VerificationDocsRetainedAfter = bound(now.AddDate(0, 0, -cfg.VerificationDocsLockDays))
+	}
+	return report
+}
+
+// PreviewRetention считает, что затронет следующий запуск задачи retention, ничего не меняя
+func PreviewRetention(ctx context.Context, db *DB, cfg RetentionConfig) (*RetentionReport, error) {
+	report := retentionBounds(cfg, time.Now())
+	report.DryRun = true
+	if err := db.CountRetention(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// RunRetention применяет сроки хранения: обезличивает неактивные аккаунты вместе с заявками
+// на верификацию, удаляет неподтвержденные регистрации, истекшие коды и старые вложения чатов
+// вместе с файлами.
+// Аккаунты обрабатываются по одному: ошибка по одному аккаунту не останавливает остальные.
+func RunRetention(ctx context.Context, db *DB, client *minio.Client, cfg RetentionConfig) (*RetentionReport, error) {
+	report := retentionBounds(cfg, time.Now())
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	if report.InactiveBefore != nil {
+		var afterID int64
+		for ctx.Err() == nil {
+			ids, err := db.GetInactiveUserIDs(*report.InactiveBefore, afterID, batchSize)
+			if err != nil {
+				return report, err
+			}
+			for _, id := range ids {
+				afterID = id
+				photos, docs, ok, err := db.AnonymizeUser(id, *report.InactiveBefore)
+				if err != nil {
+					log.Printf("Retention: failed to anonymize user %d: %v", id, err)
+					continue
+				}
+				if ok {
+					DeleteObjectsByURL(ctx, client, BucketUserPhotos, photos)
+					deleted, retained := deleteUnlockedObjects(ctx, client, BucketVerificationDocs, docs)
+					report.VerificationDocs += deleted
+					report.RetainedVerificationDocs += retained
+					report.InactiveAccounts++
+				}
+			}
+			if len(ids) < batchSize {
+				break
+			}
+		}
+	}
+
+	if report.UnverifiedBefore != nil {
+		var afterID int64
+		for ctx.Err() == nil {
+			ids, err := db.GetUnverifiedUserIDs(*report.UnverifiedBefore, afterID, batchSize)
+			if err != nil {
+				return report, err
+			}
+			for _, id := range ids {
+				afterID = id
+				urls, ok, err := db.DeleteUnverifiedUser(id, *report.UnverifiedBefore)
+				if err != nil {
+					log.Printf("Retention: failed to delete unverified user %d: %v", id, err)
+					continue
+				}
+				if ok {
+					DeleteObjectsByURL(ctx, client, BucketUserPhotos, urls)
+					report.UnverifiedAccounts++
+				}
+			}
+			if len(ids) < batchSize {
+				break
+			}
+		}
+	}
+
+	if report.OTPExpiredBefore != nil {
+		deleted, err := db.DeleteExpiredOTPCodes(*report.OTPExpiredBefore)
+		if err != nil {
+			return report, err
+		}
+		report.ExpiredOTPCodes = int(deleted)
+	}
+
+	if report.ChatAttachmentsBefore != nil {
+		for ctx.Err() == nil {
+			refs, err := db.GetOldChatAttachments(*report.ChatAttachmentsBefore, batchSize)
+			if err != nil {
+				return report, err
+			}
+			for _, ref := range refs {
+				// Ссылка убирается до удаления файла, чтобы сообщение не ссылалось на удаленный объект
+				if err := db.ClearChatAttachment(ref.MessageID, ref.CreatedAt); err != nil {
+					return report, err
+				}
+				DeleteObjectsByURL(ctx, client, BucketChatAttachments, []string{ref.URL})
+				report.ChatAttachments++
+			}
+			if len(refs) < batchSize {
+				break
+			}
+		}
+	}
+
+	metrics.Add("retention_anonymized_users_total", "Inactive accounts anonymized by retention", nil, float64(report.InactiveAccounts))
+	metrics.Add("retention_deleted_users_total", "Unverified registrations deleted by retention", nil, float64(report.UnverifiedAccounts))
+	metrics.Add("retention_deleted_attachments_total", "Chat attachments deleted by retention", nil, float64(report.ChatAttachments))
+	log.Printf("Retention: anonymized %d accounts (%d verification docs deleted, %d kept under object lock), deleted %d unverified accounts, %d otp codes, %d chat attachments",
+		report.InactiveAccounts, report.VerificationDocs, report.RetainedVerificationDocs,
+		report.UnverifiedAccounts, report.ExpiredOTPCodes, report.ChatAttachments)
+	return report, ctx.Err()
+}
+
+// deleteUnlockedObjects удаляет файлы по URL, кроме защищенных блокировкой хранилища (срок
+// хранения или legal hold): такие файлы остаются до ее снятия. Возвращает число удаленных
+// и оставленных файлов.
+func deleteUnlockedObjects(ctx context.Context, client *minio.Client, bucket string, urls []string) (int, int) {
+	var deleted, retained int
+	for _, objectURL := range urls {
+		key, ok := ObjectKeyFromURL(objectURL, bucket)
+		if !ok {
+			continue
+		}
+		// Ошибка чтения означает, что срок хранения не задан
+		if _, until, err := client.GetObjectRetention(ctx, bucket, key, ""); err == nil && until != nil && until.After(time.Now()) {
+			log.Printf("Retention: %s/%s kept under object lock until %s", bucket, key, until.Format(time.RFC3339))
+			retained++
+			continue
+		}
+		if status, err := client.GetObjectLegalHold(ctx, bucket, key, minio.GetObjectLegalHoldOptions{}); err == nil && status != nil && *status == minio.LegalHoldEnabled {
+			log.Printf("Retention: %s/%s kept under legal hold", bucket, key)
+			retained++
+			continue
+		}
+		if err := DeleteObject(ctx, client, bucket, key); err != nil {
+			log.Printf("Failed to delete %s/%s: %v", bucket, key, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, retained
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Обезличивание удаляет заявку на верификацию с паспортными данными и возвращает ее сканы,
+// кроме файлов, на которые ссылается заявка другого пользователя
+func TestAnonymizeUserDeletesVerification(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("user")
+	other := s.createUser("user")
+	s.createVerification(user)
+	s.createVerification(other)
+
+	const docs = "http://localhost/verification-docs/verifications/"
+	shared := docs + "0/user_photo.jpg"
+	setDocs := func(u testUser, photo string, scans ...string) {
+		_, err := s.db.Exec(`UPDATE verifications SET user_photo_url = $1, passport_scans_urls = $2 WHERE user_id = $3`,
+			photo, pq.Array(scans), u.ID)
+		if err != nil {
+			t.Fatalf("set verification docs: %v", err)
+		}
+	}
+	setDocs(user, shared, docs+"1/passport_scan_0.jpg", docs+"1/passport_scan_1.jpg")
+	setDocs(other, shared)
+
+	if _, err := s.db.Exec(`UPDATE users SET last_active_at = NOW() - INTERVAL '5 years' WHERE id = $1`, user.ID); err != nil {
+		t.Fatalf("set last_active_at: %v", err)
+	}
+
+	_, scans, ok, err := s.db.AnonymizeUser(user.ID, time.Now().AddDate(-1, 0, 0))
+	if err != nil || !ok {
+		t.Fatalf("AnonymizeUser = %v, %v", ok, err)
+	}
+	slices.Sort(scans)
+	if want := []string{docs + "1/passport_scan_0.jpg", docs + "1/passport_scan_1.jpg"}; !slices.Equal(scans, want) {
+		t.Errorf("verification docs = %v, want %v", scans, want)
+	}
+
+	var appErr *AppError
+	if _, err := s.db.GetVerificationByUserID(user.ID); !errors.As(err, &appErr) || appErr.Status != http.StatusNotFound {
+		t.Errorf("verification of anonymized user: err = %v, want not found", err)
+	}
+	if _, err := s.db.GetVerificationByUserID(other.ID); err != nil {
+		t.Errorf("verification of other user: %v", err)
+	}
+}
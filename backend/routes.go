@@ -0,0 +1,268 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+// NewRouter регистрирует маршруты API. CORS применяется поверх роутера при создании сервера.
+func NewRouter(cfg *Config, db *DB, handlers *Handlers, perms *Permissions, limiter *RateLimiter) *mux.Router {
+	router := mux.NewRouter()
+
+	// Применяем middleware (кроме CORS - он будет применен на уровне HTTP handler)
+	router.Use(RecoverMiddleware)
+	router.Use(LoggingMiddleware)
+
+	// Подключается к защищенным маршрутам и маршрутам по API токену
+	rateLimit := RateLimitMiddleware(limiter, cfg.RateLimit)
+
+	// Публичные маршруты
+	router.HandleFunc("/health", handlers.HealthCheck).Methods("GET")
+	router.HandleFunc("/readyz", handlers.ReadinessCheck).Methods("GET")
+	router.HandleFunc("/metrics", metrics.Handler).Methods("GET")
+
+	// Swagger документация
+	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+
+	// API v1 маршруты
+	api := router.PathPrefix("/api/v1").Subrouter()
+
+	// Аутентификация (публичные)
+	api.HandleFunc("/auth/register", handlers.Register).Methods("POST")
+	api.HandleFunc("/auth/login", handlers.Login).Methods("POST")
+	api.HandleFunc("/auth/refresh", handlers.RefreshToken).Methods("POST")
+	api.HandleFunc("/auth/request-otp", handlers.RequestOTP).Methods("POST")
+	api.HandleFunc("/auth/verify-otp", handlers.VerifyOTP).Methods("POST")
+	api.HandleFunc("/auth/forgot-password", handlers.ForgotPassword).Methods("POST")
+	api.HandleFunc("/auth/reset-password", handlers.ResetPassword).Methods("POST")
+	api.HandleFunc("/auth/oauth/providers", handlers.GetOAuthProviders).Methods("GET")
+	api.HandleFunc("/auth/oauth/{provider}", handlers.StartOAuthLogin).Methods("GET")
+	api.HandleFunc("/auth/oauth/{provider}/callback", handlers.OAuthCallback).Methods("GET")
+
+	// Справочник документов для формы верификации (публичный)
+	api.HandleFunc("/verifications/document-types", handlers.GetDocumentTypes).Methods("GET")
+
+	// Валюта и правила сумм для клиентов (публичный)
+	api.HandleFunc("/config/money", handlers.GetMoneyConfig).Methods("GET")
+	api.HandleFunc("/calendar/{token:[0-9a-f]{64}}.ics", handlers.GetCalendarFeed).Methods("GET")
+
+	// Публичные списки отдаются из кэша, если хранилище недоступно
+	degradedCache := NewDegradedCache(cfg.DegradedCacheTTL, 1000)
+	// Одновременные одинаковые запросы ленты, постов и рейтинга выполняются один раз
+	coalescer := NewRequestCoalescer()
+	// Повтор создания сбора или пожертвования с тем же Idempotency-Key получает сохраненный ответ
+	idempotency := NewIdempotency(db, cfg.IdempotencyKeyTTL)
+
+	// Маршруты, доступные также по персональному API токену с нужной областью действия
+	withScope := func(scope string) *mux.Router {
+		router := api.PathPrefix("").Subrouter()
+		router.Use(APITokenAuthMiddleware(cfg, db, scope))
+		router.Use(rateLimit)
+		return router
+	}
+	withScope("").HandleFunc("/users/me/quota", handlers.GetMyQuota).Methods("GET")
+	donationReaders := withScope(ScopeDonationsRead)
+	donationReaders.HandleFunc("/users/me/donations", handlers.GetMyDonations).Methods("GET")
+	donationReaders.HandleFunc("/posts/{id}/donations/stats", handlers.GetPostDonationStats).Methods("GET")
+	postWriters := withScope(ScopePostsWrite)
+	postWriters.HandleFunc("/posts/{id}", handlers.UpdatePost).Methods("PATCH")
+	postWriters.HandleFunc("/posts/{id}/media", handlers.AddPostMedia).Methods("POST")
+	postWriters.HandleFunc("/posts/{id}/media/bulk", handlers.AddPostMediaBulk).Methods("POST")
+
+	// Защищенные маршруты (требуют JWT)
+	protected := api.PathPrefix("").Subrouter()
+	protected.Use(JWTAuthMiddleware(cfg, db))
+	protected.Use(rateLimit)
+	protected.HandleFunc("/auth/logout", handlers.Logout).Methods("POST")
+
+	// Профиль пользователя
+	protected.HandleFunc("/users/me", handlers.GetProfile).Methods("GET")
+	protected.HandleFunc("/users/me", handlers.UpdateProfile).Methods("PATCH")
+	protected.HandleFunc("/users/me/photo", handlers.UploadPhoto).Methods("POST")
+	protected.HandleFunc("/users/me/change-password", handlers.ChangePassword).Methods("POST")
+	protected.HandleFunc("/users/me/away", handlers.SetAway).Methods("PUT")
+	protected.HandleFunc("/users/me/away", handlers.ClearAway).Methods("DELETE")
+	protected.HandleFunc("/users/me/referrals", handlers.GetMyReferrals).Methods("GET")
+	protected.HandleFunc("/users/me/collaborations", handlers.GetMyCollaborations).Methods("GET")
+	protected.HandleFunc("/users/me/favorites", handlers.GetMyFavorites).Methods("GET")
+	protected.HandleFunc("/users/me/hidden-posts", handlers.GetHiddenPosts).Methods("GET")
+	protected.HandleFunc("/users/me/hidden-posts", handlers.HidePost).Methods("POST")
+	protected.HandleFunc("/users/me/hidden-posts/{id}", handlers.UnhidePost).Methods("DELETE")
+	protected.HandleFunc("/users/me/muted-authors", handlers.GetMutedAuthors).Methods("GET")
+	protected.HandleFunc("/users/me/muted-authors", handlers.MuteAuthor).Methods("POST")
+	protected.HandleFunc("/users/me/muted-authors/{id}", handlers.UnmuteAuthor).Methods("DELETE")
+	protected.HandleFunc("/users/me/collaborations/{id}", handlers.RespondCollaboration).Methods("PATCH")
+	protected.HandleFunc("/users/me/post-limit", handlers.GetMyPostLimit).Methods("GET")
+	protected.HandleFunc("/users/me/post-limit/requests", handlers.CreatePostLimitRequest).Methods("POST")
+	protected.HandleFunc("/users/me/devices", handlers.RegisterDevice).Methods("POST")
+	protected.HandleFunc("/users/me/devices/{token}", handlers.UnregisterDevice).Methods("DELETE")
+	protected.HandleFunc("/users/me/notification-preferences", handlers.GetNotificationPreferences).Methods("GET")
+	protected.HandleFunc("/users/me/notification-preferences", handlers.UpdateNotificationPreferences).Methods("PUT")
+	protected.HandleFunc("/users/me/identities/{provider}", handlers.LinkIdentity).Methods("POST")
+	protected.HandleFunc("/users/me/identities/{provider}", handlers.UnlinkIdentity).Methods("DELETE")
+	protected.HandleFunc("/users/me/tokens", handlers.GetAPITokens).Methods("GET")
+	protected.HandleFunc("/users/me/tokens", handlers.CreateAPIToken).Methods("POST")
+	protected.HandleFunc("/users/me/tokens/{id}", handlers.DeleteAPIToken).Methods("DELETE")
+	protected.HandleFunc("/users/me/calendar", handlers.CreateCalendarFeed).Methods("POST")
+	protected.HandleFunc("/users/me/calendar", handlers.DeleteCalendarFeed).Methods("DELETE")
+	protected.HandleFunc("/users/me/templates", handlers.GetPaymentNoteTemplates).Methods("GET")
+	protected.HandleFunc("/users/me/templates", handlers.CreatePaymentNoteTemplate).Methods("POST")
+	protected.HandleFunc("/users/me/templates/{id}", handlers.UpdatePaymentNoteTemplate).Methods("PUT")
+	protected.HandleFunc("/users/me/templates/{id}", handlers.DeletePaymentNoteTemplate).Methods("DELETE")
+	protected.HandleFunc("/users/me/subscriptions", handlers.GetSubscriptions).Methods("GET")
+	protected.HandleFunc("/users/me/subscriptions", handlers.CreateSubscription).Methods("POST")
+	protected.HandleFunc("/users/me/subscriptions/{id}", handlers.UpdateSubscription).Methods("PATCH")
+	protected.HandleFunc("/users/me/subscriptions/{id}", handlers.DeleteSubscription).Methods("DELETE")
+	protected.HandleFunc("/users/me/integrations", handlers.GetIntegrations).Methods("GET")
+	protected.HandleFunc("/users/me/integrations", handlers.UpsertIntegration).Methods("PUT")
+	protected.HandleFunc("/users/me/integrations/{type}", handlers.DeleteIntegration).Methods("DELETE")
+
+	// Верификация
+	protected.HandleFunc("/verifications", handlers.CreateVerification).Methods("POST")
+	protected.HandleFunc("/verifications/me", handlers.GetMyVerification).Methods("GET")
+	protected.HandleFunc("/verifications/organizations/{inn}", handlers.LookupOrganization).Methods("GET")
+
+	// Маршруты, доступные ролям с определенным правом
+	withPermission := func(permission string) *mux.Router {
+		router := protected.PathPrefix("").Subrouter()
+		router.Use(PermissionMiddleware(perms, permission))
+		return router
+	}
+
+	// Проверка верификаций
+	reviewers := withPermission(PermVerificationsReview)
+	reviewers.HandleFunc("/verifications", handlers.GetVerifications).Methods("GET")
+	reviewers.HandleFunc("/verifications/{id}", handlers.GetVerification).Methods("GET")
+	reviewers.HandleFunc("/verifications/{id}", handlers.UpdateVerification).Methods("PATCH")
+
+	documentViewers := withPermission(PermVerificationDocumentsView)
+	documentViewers.HandleFunc("/verifications/{id}/documents", handlers.GetVerificationDocuments).Methods("GET")
+
+	// Модерация постов
+	moderators := withPermission(PermPostsModerate)
+	moderators.HandleFunc("/admin/posts/moderation", handlers.GetModerationPosts).Methods("GET")
+	moderators.HandleFunc("/admin/posts/moderation/urgent", handlers.GetUrgentModerationPosts).Methods("GET")
+	moderators.HandleFunc("/admin/posts/{id}/moderation", handlers.ModeratePost).Methods("PATCH")
+
+	// Администрирование
+	userViewers := withPermission(PermUsersView)
+	userViewers.HandleFunc("/admin/users", handlers.GetAdminUsers).Methods("GET")
+	userViewers.HandleFunc("/admin/users/{id}", handlers.GetAdminUser).Methods("GET")
+	blockers := withPermission(PermUsersBlock)
+	blockers.HandleFunc("/admin/users/{id}", handlers.UpdateAdminUser).Methods("PATCH")
+	blockers.HandleFunc("/admin/users/{id}/block", handlers.BlockUser).Methods("POST")
+	blockers.HandleFunc("/admin/users/{id}/block", handlers.UnblockUser).Methods("DELETE")
+	withPermission(PermBackupsView).HandleFunc("/admin/backups/status", handlers.GetBackupStatus).Methods("GET")
+	auditViewers := withPermission(PermAuditView)
+	auditViewers.HandleFunc("/admin/audit-log", handlers.GetAuditLog).Methods("GET")
+	auditViewers.HandleFunc("/admin/digests", handlers.GetDigests).Methods("GET")
+	auditViewers.HandleFunc("/admin/digests/{id}", handlers.GetDigest).Methods("GET")
+	systemViewers := withPermission(PermSystemView)
+	systemViewers.HandleFunc("/admin/selftest", handlers.GetSelfTest).Methods("GET")
+	systemViewers.HandleFunc("/admin/selftest", handlers.RunSelfTest).Methods("POST")
+	systemViewers.HandleFunc("/admin/ops", handlers.GetOps).Methods("GET")
+	systemViewers.HandleFunc("/admin/dead-letters", handlers.GetDeadLetters).Methods("GET")
+	systemViewers.HandleFunc("/admin/retention/report", handlers.GetRetentionReport).Methods("GET")
+	jobManagers := withPermission(PermJobsManage)
+	jobManagers.HandleFunc("/admin/dead-letters/{id}/retry", handlers.RetryDeadLetter).Methods("POST")
+	jobManagers.HandleFunc("/admin/dead-letters/{id}/discard", handlers.DiscardDeadLetter).Methods("POST")
+	roleManagers := withPermission(PermRolesManage)
+	roleManagers.HandleFunc("/admin/roles", handlers.GetRoles).Methods("GET")
+	roleManagers.HandleFunc("/admin/roles/{name}", handlers.SaveRole).Methods("PUT")
+	roleManagers.HandleFunc("/admin/users/{id}/role", handlers.UpdateUserRole).Methods("PATCH")
+	limitManagers := withPermission(PermLimitsManage)
+	limitManagers.HandleFunc("/admin/risk-tiers", handlers.GetRiskTiers).Methods("GET")
+	limitManagers.HandleFunc("/admin/risk-tiers/{name}", handlers.SaveRiskTier).Methods("PUT")
+	limitManagers.HandleFunc("/admin/users/{id}/risk-tier", handlers.UpdateUserRiskTier).Methods("PATCH")
+	limitManagers.HandleFunc("/admin/post-limit-requests", handlers.GetPostLimitRequests).Methods("GET")
+	limitManagers.HandleFunc("/admin/post-limit-requests/{id}", handlers.ReviewPostLimitRequest).Methods("PATCH")
+
+	inviteManagers := withPermission(PermInvitesManage)
+	inviteManagers.HandleFunc("/admin/invites", handlers.CreateInviteCodes).Methods("POST")
+	inviteManagers.HandleFunc("/admin/invites", handlers.GetInviteCodes).Methods("GET")
+	inviteManagers.HandleFunc("/admin/invites/{id}/users", handlers.GetInvitedUsers).Methods("GET")
+
+	promotionManagers := withPermission(PermPromotionsManage)
+	promotionManagers.HandleFunc("/admin/points-events", handlers.CreatePointsEvent).Methods("POST")
+	promotionManagers.HandleFunc("/admin/points-events", handlers.GetPointsEvents).Methods("GET")
+	promotionManagers.HandleFunc("/admin/points-events/{id}", handlers.CancelPointsEvent).Methods("DELETE")
+
+	webhookManagers := withPermission(PermWebhooksManage)
+	webhookManagers.HandleFunc("/admin/webhooks", handlers.CreateWebhook).Methods("POST")
+	webhookManagers.HandleFunc("/admin/webhooks", handlers.GetWebhooks).Methods("GET")
+	webhookManagers.HandleFunc("/admin/webhooks/{id}", handlers.UpdateWebhook).Methods("PATCH")
+	webhookManagers.HandleFunc("/admin/webhooks/{id}", handlers.DeleteWebhook).Methods("DELETE")
+	webhookManagers.HandleFunc("/admin/webhooks/{id}/deliveries", handlers.GetWebhookDeliveries).Methods("GET")
+
+	// Посты
+	api.HandleFunc("/posts", coalescer.Wrap(degradedCache.Wrap(handlers.GetPosts))).Methods("GET")
+	api.HandleFunc("/posts/{id}", coalescer.Wrap(degradedCache.Wrap(handlers.GetPost))).Methods("GET")
+	api.HandleFunc("/posts/{id}/events", handlers.GetPostEvents).Methods("GET")
+	api.HandleFunc("/posts/{id}/share-image", handlers.GetPostShareImage).Methods("GET")
+	api.HandleFunc("/posts/{id}/flyer", handlers.GetPostFlyer).Methods("GET")
+	api.HandleFunc("/posts/{id}/comments", handlers.GetPostComments).Methods("GET")
+	api.HandleFunc("/posts/{id}/questions", handlers.GetPostQuestions).Methods("GET")
+	protected.HandleFunc("/posts", idempotency.Wrap(handlers.CreatePost)).Methods("POST")
+	protected.HandleFunc("/posts/{id}", handlers.DeletePost).Methods("DELETE")
+	protected.HandleFunc("/posts/{id}/media/{media_id}", handlers.DeletePostMedia).Methods("DELETE")
+	protected.HandleFunc("/posts/{id}/collaborators", handlers.GetPostCollaborators).Methods("GET")
+	protected.HandleFunc("/posts/{id}/collaborators", handlers.InviteCollaborator).Methods("POST")
+	protected.HandleFunc("/posts/{id}/collaborators/{user_id}", handlers.UpdateCollaborator).Methods("PATCH")
+	protected.HandleFunc("/posts/{id}/collaborators/{user_id}", handlers.RemoveCollaborator).Methods("DELETE")
+	protected.HandleFunc("/posts/{id}/comments", handlers.CreatePostComment).Methods("POST")
+	protected.HandleFunc("/posts/{id}/favorite", handlers.AddFavorite).Methods("POST")
+	protected.HandleFunc("/posts/{id}/favorite", handlers.RemoveFavorite).Methods("DELETE")
+	protected.HandleFunc("/posts/{id}/comments/{comment_id}", handlers.DeletePostComment).Methods("DELETE")
+	protected.HandleFunc("/posts/{id}/questions", handlers.CreatePostQuestion).Methods("POST")
+	protected.HandleFunc("/posts/{id}/questions/{question_id}/answer", handlers.AnswerPostQuestion).Methods("PUT")
+	protected.HandleFunc("/posts/{id}/questions/{question_id}", handlers.DeletePostQuestion).Methods("DELETE")
+
+	// Пожертвования
+	protected.HandleFunc("/donations", idempotency.Wrap(handlers.CreateDonation)).Methods("POST")
+	api.HandleFunc("/donations", degradedCache.Wrap(handlers.GetDonations)).Methods("GET")
+	api.HandleFunc("/donations/{id}", degradedCache.Wrap(handlers.GetDonation)).Methods("GET")
+	protected.HandleFunc("/donations/{id}", handlers.UpdateDonation).Methods("PATCH")
+	protected.HandleFunc("/donations/{id}", handlers.CancelDonation).Methods("DELETE")
+	protected.HandleFunc("/donations/{id}/history", handlers.GetDonationHistory).Methods("GET")
+	// Квитанция открывается по подписанной ссылке из уведомления, поэтому маршрут публичный
+	api.HandleFunc("/donations/{id}/receipt", handlers.GetDonationReceipt).Methods("GET")
+	protected.HandleFunc("/donations/{id}/pay", handlers.PayDonation).Methods("POST")
+	// Уведомления платежного шлюза: статус платежа перечитывается у провайдера
+	api.HandleFunc("/payments/webhook", handlers.PaymentWebhook).Methods("POST")
+	protected.HandleFunc("/posts/{id}/statement-imports", handlers.ImportDonationStatement).Methods("POST")
+	protected.HandleFunc("/statement-imports/{id}", handlers.GetStatementImport).Methods("GET")
+	protected.HandleFunc("/statement-imports/{id}/confirm", handlers.ConfirmStatementImport).Methods("POST")
+
+	// Чаты
+	protected.HandleFunc("/chats", handlers.GetChats).Methods("GET")
+	protected.HandleFunc("/chats", handlers.CreateChat).Methods("POST")
+	protected.HandleFunc("/chats/{id}/events", handlers.GetChatEvents).Methods("GET")
+	protected.HandleFunc("/chats/{id}/draft", handlers.GetChatDraft).Methods("GET")
+	protected.HandleFunc("/chats/{id}/draft", handlers.SaveChatDraft).Methods("PUT")
+	protected.HandleFunc("/chats/{id}/messages", handlers.GetMessages).Methods("GET")
+	protected.HandleFunc("/chats/{id}/messages", handlers.SendMessage).Methods("POST")
+	protected.HandleFunc("/chats/{id}/messages/read", handlers.MarkMessagesRead).Methods("PATCH")
+	protected.HandleFunc("/chats/{id}/messages/{message_id}", handlers.UpdateMessage).Methods("PATCH")
+	protected.HandleFunc("/chats/{id}/messages/{message_id}", handlers.DeleteMessage).Methods("DELETE")
+
+	// Рейтинг
+	api.HandleFunc("/ratings", coalescer.Wrap(degradedCache.Wrap(handlers.GetRatings))).Methods("GET")
+	protected.HandleFunc("/ratings/me", handlers.GetMyRating).Methods("GET")
+	protected.HandleFunc("/ratings/me/history", handlers.GetMyRatingHistory).Methods("GET")
+	api.HandleFunc("/points-events", coalescer.Wrap(handlers.GetActivePointsEvents)).Methods("GET")
+
+	// Уведомления
+	protected.HandleFunc("/notifications", handlers.GetNotifications).Methods("GET")
+	protected.HandleFunc("/notifications/read", handlers.MarkNotificationsRead).Methods("PATCH")
+
+	// Утилиты
+	protected.HandleFunc("/upload/presigned-url", handlers.GetPresignedURL).Methods("POST")
+	protected.HandleFunc("/files/presigned-url", handlers.GetPresignedGetURL).Methods("POST")
+
+	// Публичный endpoint для получения файлов (проксирование через backend)
+	// Поддерживаем оба варианта: /files/... и /api/v1/files/...
+	router.HandleFunc("/files/{bucket}/{objectKey:.*}", handlers.GetFile).Methods("GET")
+	api.HandleFunc("/files/{bucket}/{objectKey:.*}", handlers.GetFile).Methods("GET")
+
+	return router
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job периодическая фоновая задача
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// jobStaleRuns сколько интервалов задача может завершаться с ошибкой, прежде чем считается зависшей
+const jobStaleRuns = 3
+
+// JobStatus результат последнего запуска задачи, для диагностики
+type JobStatus struct {
+	Name           string     `json:"name"`
+	Interval       string     `json:"interval"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	LastSuccessAt  *time.Time `json:"last_success_at"`
+	LastDurationMS float64    `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty"`
+	// Задача запускалась, но не завершалась успешно дольше jobStaleRuns интервалов
+	Stale bool `json:"stale"`
+}
+
+// Scheduler запускает периодические задачи в фоне
+type Scheduler struct {
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	status    map[string]*JobStatus
+	startedAt time.Time
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{status: make(map[string]*JobStatus)}
+}
+
+// Add регистрирует задачу. Задачи с нулевым интервалом игнорируются.
+func (s *Scheduler) Add(job Job) {
+	if job.Interval <= 0 {
+		return
+	}
+	s.jobs = append(s.jobs, job)
+	s.status[job.Name] = &JobStatus{Name: job.Name, Interval: job.Interval.String()}
+}
+
+// Status возвращает результаты последних запусков задач в порядке регистрации
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := *s.status[job.Name]
+		if status.LastRunAt != nil {
+			lastSuccess := s.startedAt
+			if status.LastSuccessAt != nil {
+				lastSuccess = *status.LastSuccessAt
+			}
+			status.Stale = time.Since(lastSuccess) > jobStaleRuns*job.Interval
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// Start запускает все зарегистрированные задачи
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.startedAt = time.Now()
+	s.mu.Unlock()
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop останавливает задачи и ожидает завершения текущих запусков
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			startedAt := time.Now()
+			err := job.Run(ctx)
+			if err != nil {
+				log.Printf("Job %s failed: %v", job.Name, err)
+			}
+			s.recordRun(job.Name, startedAt, err)
+		}
+	}
+}
+
+func (s *Scheduler) recordRun(name string, startedAt time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status[name]
+	status.LastRunAt = &startedAt
+	status.LastDurationMS = elapsedMS(startedAt)
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		status.LastSuccessAt = &startedAt
+	}
+}
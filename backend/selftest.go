@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// SelfTestCheck результат одной проверки самотестирования
+type SelfTestCheck struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// SelfTestReport результат самотестирования
+type SelfTestReport struct {
+	Status    string          `json:"status"`
+	StartedAt time.Time       `json:"started_at"`
+	Checks    []SelfTestCheck `json:"checks"`
+}
+
+// SelfTest проверяет при запуске, что учетные данные PostgreSQL и MinIO позволяют
+// не только подключиться, но и писать: выполняет транзакцию с временной таблицей
+// и записывает, читает и удаляет пробный объект в каждом bucket.
+type SelfTest struct {
+	db     *DB
+	client *minio.Client
+
+	mu   sync.RWMutex
+	last *SelfTestReport
+}
+
+func NewSelfTest(db *DB, client *minio.Client) *SelfTest {
+	return &SelfTest{db: db, client: client}
+}
+
+// Last возвращает результат последнего самотестирования или nil
+func (s *SelfTest) Last() *SelfTestReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.last
+}
+
+// Run выполняет самотестирование, логирует ошибки и сохраняет результат
+func (s *SelfTest) Run(ctx context.Context) SelfTestReport {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	report := SelfTestReport{Status: "ok", StartedAt: time.Now()}
+	check := func(name string, run func() error) {
+		startedAt := time.Now()
+		result := SelfTestCheck{Name: name, Status: "ok"}
+		if err := run(); err != nil {
+			result.Status = "error"
+			result.Error = err.Error()
+			report.Status = "failed"
+			log.Printf("Self-test %s failed: %v", name, err)
+		}
+		result.DurationMS = elapsedMS(startedAt)
+		report.Checks = append(report.Checks, result)
+	}
+
+	check("postgres", func() error { return s.checkDatabase(ctx) })
+	for _, bucket := range AllBuckets {
+		check("minio:"+bucket, func() error { return s.checkBucket(ctx, bucket) })
+	}
+
+	s.mu.Lock()
+	s.last = &report
+	s.mu.Unlock()
+	return report
+}
+
+// checkDatabase выполняет транзакцию с записью во временную таблицу,
+// чтобы обнаружить подключение к реплике только для чтения
+func (s *SelfTest) checkDatabase(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE selftest_probe (value TEXT) ON COMMIT DROP`); err != nil {
+		return fmt.Errorf("create temp table: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO selftest_probe (value) VALUES ('probe')`); err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+	var value string
+	if err := tx.QueryRowContext(ctx, `SELECT value FROM selftest_probe`).Scan(&value); err != nil {
+		return fmt.Errorf("select: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// checkBucket записывает, читает и удаляет пробный объект. В bucket с блокировкой
+// объектов пробный объект получает короткий срок хранения в режиме GOVERNANCE и
+// удаляется в обход блокировки, чтобы не копить пробы на срок хранения документов.
+func (s *SelfTest) checkBucket(ctx context.Context, bucket string) error {
+	objectKey := fmt.Sprintf("selftest/probe-%d", time.Now().UnixNano())
+	payload := []byte("selftest")
+
+	opts := minio.PutObjectOptions{ContentType: "text/plain"}
+	locked := false
+	if _, _, _, _, err := s.client.GetObjectLockConfig(ctx, bucket); err == nil {
+		locked = true
+		opts.SendContentMd5 = true
+		opts.Mode = minio.Governance
+		opts.RetainUntilDate = time.Now().Add(time.Minute)
+	}
+
+	info, err := s.client.PutObject(ctx, bucket, objectKey, bytes.NewReader(payload), int64(len(payload)), opts)
+	if err != nil {
+		return fmt.Errorf("put: %w", err)
+	}
+
+	obj, err := s.client.GetObject(ctx, bucket, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	data, err := io.ReadAll(obj)
+	obj.Close()
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if !bytes.Equal(data, payload) {
+		return fmt.Errorf("get: probe content mismatch")
+	}
+
+	err = s.client.RemoveObject(ctx, bucket, objectKey, minio.RemoveObjectOptions{
+		VersionID:        info.VersionID,
+		GovernanceBypass: locked,
+	})
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
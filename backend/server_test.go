@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Тесты маршрутов собирают роутер так же, как main, с настоящей базой: PostgreSQL из
+// TEST_DATABASE_URL или встроенный (embedded-postgres, как в демонстрационном режиме).
+// Без базы такие тесты пропускаются с причиной.
+var testEnv struct {
+	db   *DB
+	skip string
+}
+
+func TestMain(m *testing.M) {
+	if err := LoadDocumentCountries(DocumentCountries); err != nil {
+		fmt.Fprintf(os.Stderr, "load document types: %v\n", err)
+		os.Exit(1)
+	}
+	stop := setupTestDatabase()
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// setupTestDatabase подключается к тестовой базе и создает схему. Возвращает функцию остановки.
+func setupTestDatabase() func() {
+	stop := func() {}
+	cfg := NewConfig()
+	cfg.DatabaseURL, cfg.DatabaseFailover = os.Getenv("TEST_DATABASE_URL"), nil
+	if cfg.DatabaseURL == "" {
+		if os.Geteuid() == 0 {
+			testEnv.skip = "TEST_DATABASE_URL не задан, а встроенный PostgreSQL не запускается от root"
+			return stop
+		}
+		dir, err := os.MkdirTemp("", "backend-test-")
+		if err != nil {
+			testEnv.skip = fmt.Sprintf("не удалось создать каталог для встроенного PostgreSQL: %v", err)
+			return stop
+		}
+		embedded, dsn, err := StartEmbeddedDatabase(StandaloneConfig{
+			DataDir:               dir,
+			DatabasePort:          getEnvInt("TEST_EMBEDDED_DB_PORT", 5434),
+			DatabaseBinariesDir:   os.Getenv("EMBEDDED_DB_BINARIES_DIR"),
+			DatabaseRepositoryURL: os.Getenv("EMBEDDED_DB_REPOSITORY_URL"),
+			DatabaseCacheDir:      os.Getenv("EMBEDDED_DB_CACHE_DIR"),
+		})
+		if err != nil {
+			os.RemoveAll(dir)
+			testEnv.skip = fmt.Sprintf("TEST_DATABASE_URL не задан, встроенный PostgreSQL не запущен: %v", err)
+			return stop
+		}
+		cfg.DatabaseURL = dsn
+		stop = func() {
+			embedded.Stop()
+			os.RemoveAll(dir)
+		}
+	}
+
+	db, err := NewDB(cfg, NewCircuitBreaker("postgres", cfg.Breaker.FailureThreshold, cfg.Breaker.Cooldown))
+	if err != nil {
+		testEnv.skip = fmt.Sprintf("тестовая база недоступна: %v", err)
+		return stop
+	}
+	if err := db.InitSchema(); err != nil {
+		log.Fatalf("Failed to initialize test database schema: %v", err)
+	}
+	testEnv.db = db
+	return func() {
+		db.Close()
+		stop()
+	}
+}
+
+// testServer роутер API поверх тестовой базы. MinIO и антивирус не настроены, платежи
+// принимает testPayments.
+type testServer struct {
+	t       *testing.T
+	cfg     *Config
+	db      *DB
+	router  *mux.Router
+	handler http.Handler
+	server  *httptest.Server
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	if testEnv.db == nil {
+		t.Skip(testEnv.skip)
+	}
+
+	cfg := NewConfig()
+	cfg.JWTSecret = "test-secret"
+	cfg.RateLimit.UserRequests, cfg.RateLimit.APITokenRequests = 0, 0
+
+	minioClient, err := NewMinIOClient(cfg.MinIOConfig, NewCircuitBreaker("minio", cfg.Breaker.FailureThreshold, cfg.Breaker.Cooldown))
+	if err != nil {
+		t.Fatalf("minio client: %v", err)
+	}
+	sms, err := NewSMSProvider(SMSConfig{}, nil)
+	if err != nil {
+		t.Fatalf("sms provider: %v", err)
+	}
+	perms := NewPermissions(testEnv.db, time.Minute)
+	limiter := NewRateLimiter(cfg.RateLimit.Window)
+	handlers := NewHandlers(testEnv.db, minioClient, sms, testPayments{}, NewNotifier(testEnv.db, cfg.Notifications), perms,
+		NewOAuthProviders(cfg.OAuth), nil, limiter, NewScheduler(), NewSelfTest(testEnv.db, minioClient), NewEventHub(), nil, cfg)
+
+	router := NewRouter(cfg, testEnv.db, handlers, perms, limiter)
+	return &testServer{t: t, cfg: cfg, db: testEnv.db, router: router, handler: CORSMiddleware(router)}
+}
+
+// testPayments платежный шлюз тестов: платеж сразу ожидает оплаты на странице шлюза,
+// уведомления не принимаются
+type testPayments struct{}
+
+func (testPayments) Name() string { return "test" }
+
+func (testPayments) CreatePayment(ctx context.Context, req PaymentRequest) (*ProviderPayment, error) {
+	return &ProviderPayment{
+		ID:              fmt.Sprintf("test-%s", req.IdempotenceKey),
+		Status:          PaymentStatusPending,
+		Amount:          req.Amount,
+		ConfirmationURL: fmt.Sprintf("https://pay.example.com/%d", req.DonationID),
+	}, nil
+}
+
+func (testPayments) ParseWebhook(ctx context.Context, r *http.Request) (*ProviderPayment, error) {
+	return nil, nil
+}
+
+// testPhoneSeq делает телефоны тестовых пользователей уникальными в пределах базы
+var testPhoneSeq = time.Now().UnixNano() % 1e9
+
+// testUser пользователь с access токеном
+type testUser struct {
+	ID    int64
+	Role  string
+	Token string
+}
+
+// createUser создает пользователя с ролью и подтвержденным телефоном
+func (s *testServer) createUser(role string) testUser {
+	s.t.Helper()
+	phone := fmt.Sprintf("+7%010d", atomic.AddInt64(&testPhoneSeq, 1)%1e10)
+	user, err := s.db.CreateUser(phone, "", "Тест", "Пользователь", "")
+	if err != nil {
+		s.t.Fatalf("create user: %v", err)
+	}
+	if _, err := s.db.Exec(`UPDATE users SET role = $1, phone_verified = true WHERE id = $2`, role, user.ID); err != nil {
+		s.t.Fatalf("set role: %v", err)
+	}
+	token, err := GenerateToken(s.cfg, user.ID, role, false)
+	if err != nil {
+		s.t.Fatalf("token: %v", err)
+	}
+	return testUser{ID: user.ID, Role: role, Token: token}
+}
+
+// createPost создает пост автора со статусом status
+func (s *testServer) createPost(author testUser, status string) *Post {
+	s.t.Helper()
+	post := &Post{
+		UserID:      author.ID,
+		Title:       "Тестовый сбор",
+		Description: "Описание тестового сбора",
+		Amount:      10000,
+		Recipient:   "Иванов Иван Иванович",
+		Bank:        "Тестбанк",
+		Phone:       "+79990000000",
+		Status:      status,
+	}
+	if err := s.db.CreatePost(post); err != nil {
+		s.t.Fatalf("create post: %v", err)
+	}
+	return post
+}
+
+// createVerification создает заявку на верификацию пользователя в статусе pending
+func (s *testServer) createVerification(user testUser) *Verification {
+	s.t.Helper()
+	verification := &Verification{
+		UserID:         user.ID,
+		LastName:       "Иванов",
+		FirstName:      "Иван",
+		BirthDate:      time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		PassportSeries: "4500",
+		PassportNumber: "123456",
+		PassportIssuer: "ОВД",
+		PassportDate:   time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+		DocType:        "inn",
+		Country:        "RU",
+		DocumentType:   "ru_passport",
+		Consent1:       true,
+		Consent2:       true,
+		Consent3:       true,
+	}
+	if err := s.db.CreateVerification(verification); err != nil {
+		s.t.Fatalf("create verification: %v", err)
+	}
+	return verification
+}
+
+// testForm тело запроса multipart/form-data из текстовых полей
+type testForm map[string]string
+
+// request выполняет запрос от имени user (нулевой testUser - без токена) и возвращает ответ.
+// body кодируется в JSON, json.RawMessage отправляется как есть, testForm - как multipart форма.
+func (s *testServer) request(user testUser, method, path string, body interface{}) *httptest.ResponseRecorder {
+	s.t.Helper()
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, s.newRequest(context.Background(), user, method, path, body))
+	return rec
+}
+
+// stream открывает поток событий (Server-Sent Events) и возвращает код ответа. Поток требует
+// настоящего соединения, поэтому запрос идет через HTTP сервер и закрывается после заголовков.
+func (s *testServer) stream(user testUser, path string) int {
+	s.t.Helper()
+	if s.server == nil {
+		s.server = httptest.NewServer(s.handler)
+		s.t.Cleanup(s.server.Close)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req := s.newRequest(ctx, user, "GET", s.server.URL+path, nil)
+	req.RequestURI = ""
+	resp, err := s.server.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("GET %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testServer) newRequest(ctx context.Context, user testUser, method, path string, body interface{}) *http.Request {
+	s.t.Helper()
+	var reader io.Reader
+	contentType := "application/json"
+	switch body := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case json.RawMessage:
+		reader = bytes.NewReader(body)
+	case testForm:
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for name, value := range body {
+			if err := writer.WriteField(name, value); err != nil {
+				s.t.Fatalf("write form field: %v", err)
+			}
+		}
+		writer.Close()
+		reader, contentType = &buf, writer.FormDataContentType()
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader).WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if user.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+user.Token)
+	}
+	return req
+}
+
+// expectStatus проверяет код ответа
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, name string, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Errorf("%s: status %d, want %d, body: %s", name, rec.Code, want, rec.Body.String())
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Размер картинки для соцсетей (Open Graph)
+const (
+	shareImageWidth  = 1200
+	shareImageHeight = 630
+)
+
+// shareRegenerateStep изменение собранной суммы (доля цели), после которого картинка перерисовывается
+const shareRegenerateStep = 0.05
+
+const (
+	shareMargin     = 40
+	shareQRSize     = 190
+	shareTitleLines = 3
+)
+
+var (
+	shareBackground  = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	sharePlaceholder = color.RGBA{0xf1, 0xf3, 0xf5, 0xff}
+	shareText        = color.RGBA{0x21, 0x25, 0x29, 0xff}
+	shareMuted       = color.RGBA{0x6c, 0x75, 0x7d, 0xff}
+	shareBarEmpty    = color.RGBA{0xe9, 0xec, 0xef, 0xff}
+	shareBarFilled   = color.RGBA{0x2f, 0x9e, 0x44, 0xff}
+
+	shareBoldFont    = mustParseFont(gobold.TTF)
+	shareRegularFont = mustParseFont(goregular.TTF)
+)
+
+func mustParseFont(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse font: %v", err))
+	}
+	return f
+}
+
+// ShareImageHash отпечаток данных поста, при изменении которых картинка перерисовывается
+// независимо от собранной суммы
+func ShareImageHash(post *Post, photoURL, postURL string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%.2f\x00%s\x00%s", post.Title, post.Amount, photoURL, postURL)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ShareImageStale проверяет, нужно ли перерисовать картинку: изменились данные поста,
+// собранная сумма сдвинулась на shareRegenerateStep цели или сбор завершен
+func ShareImageStale(post *Post, hash string, cachedHash *string, cachedCollected *float64) bool {
+	if cachedHash == nil || cachedCollected == nil || *cachedHash != hash {
+		return true
+	}
+	if post.Collected >= post.Amount && *cachedCollected < post.Amount {
+		return true
+	}
+	return math.Abs(post.Collected-*cachedCollected) >= post.Amount*shareRegenerateStep
+}
+
+// RenderShareImage рисует PNG для соцсетей: фото слева, справа заголовок,
+// прогресс сбора и QR код со ссылкой на пост. photo может быть nil.
+func RenderShareImage(post *Post, photo image.Image, postURL string) ([]byte, error) {
+	canvas := image.NewRGBA(image.Rect(0, 0, shareImageWidth, shareImageHeight))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(shareBackground), image.Point{}, draw.Src)
+
+	// Фото обрезается до квадрата по центру и занимает левую часть
+	photoArea := image.Rect(0, 0, shareImageHeight, shareImageHeight)
+	if photo != nil {
+		scaled := scaleImage(cropAspect(photo, 1, 1), photoArea.Dx(), photoArea.Dy())
+		draw.Draw(canvas, photoArea, scaled, image.Point{}, draw.Src)
+	} else {
+		draw.Draw(canvas, photoArea, image.NewUniform(sharePlaceholder), image.Point{}, draw.Src)
+	}
+
+	left := shareImageHeight + shareMargin
+	textWidth := shareImageWidth - left - shareMargin
+
+	titleFace, err := opentype.NewFace(shareBoldFont, &opentype.FaceOptions{Size: 44, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create title font: %w", err)
+	}
+	defer titleFace.Close()
+	textFace, err := opentype.NewFace(shareRegularFont, &opentype.FaceOptions{Size: 28, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create text font: %w", err)
+	}
+	defer textFace.Close()
+
+	y := shareMargin + 44
+	for _, line := range wrapText(titleFace, post.Title, textWidth, shareTitleLines) {
+		drawText(canvas, titleFace, shareText, left, y, line)
+		y += 54
+	}
+
+	// Прогресс сбора
+	y += 30
+	drawText(canvas, textFace, shareText, left, y,
+		fmt.Sprintf("Собрано %s из %s руб.", formatAmount(post.Collected), formatAmount(post.Amount)))
+	y += 24
+
+	progress := 0.0
+	if post.Amount > 0 {
+		progress = math.Min(post.Collected/post.Amount, 1)
+	}
+	bar := image.Rect(left, y, left+textWidth, y+28)
+	draw.Draw(canvas, bar, image.NewUniform(shareBarEmpty), image.Point{}, draw.Src)
+	filled := bar
+	filled.Max.X = bar.Min.X + int(float64(bar.Dx())*progress)
+	draw.Draw(canvas, filled, image.NewUniform(shareBarFilled), image.Point{}, draw.Src)
+	y += 28 + 36
+	drawText(canvas, textFace, shareMuted, left, y, fmt.Sprintf("%d%%", int(progress*100)))
+
+	// QR код со ссылкой на пост
+	qr, err := qrcode.New(postURL, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create qr code: %w", err)
+	}
+	qrImage := qr.Image(shareQRSize)
+	qrPoint := image.Pt(shareImageWidth-shareMargin-shareQRSize, shareImageHeight-shareMargin-shareQRSize)
+	draw.Draw(canvas, qrImage.Bounds().Add(qrPoint), qrImage, qrImage.Bounds().Min, draw.Src)
+	for i, line := range []string{"Наведите камеру,", "чтобы помочь"} {
+		drawText(canvas, textFace, shareMuted, left, shareImageHeight-shareMargin-shareQRSize/2+i*36, line)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("failed to encode share image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// cropAspect вырезает из центра изображения наибольшую область с соотношением сторон aw:ah
+func cropAspect(img image.Image, aw, ah int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), max(1, b.Dx()*ah/aw)
+	if h > b.Dy() {
+		w, h = max(1, b.Dy()*aw/ah), b.Dy()
+	}
+	area := image.Rect(0, 0, w, h).Add(image.Pt(b.Min.X+(b.Dx()-w)/2, b.Min.Y+(b.Dy()-h)/2))
+
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(area)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), img, area.Min, draw.Src)
+	return dst
+}
+
+func drawText(dst draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// wrapText разбивает текст на строки не шире width. Если строк больше maxLines,
+// последняя обрезается многоточием.
+func wrapText(face font.Face, text string, width, maxLines int) []string {
+	limit := fixed.I(width)
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if font.MeasureString(face, candidate) <= limit || line == "" {
+			line = candidate
+			continue
+		}
+		lines = append(lines, line)
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] += "…"
+	}
+	// Слишком длинные слова обрезаются по ширине
+	for i, l := range lines {
+		for font.MeasureString(face, l) > limit && len([]rune(l)) > 1 {
+			runes := []rune(strings.TrimSuffix(l, "…"))
+			l = string(runes[:len(runes)-1]) + "…"
+		}
+		lines[i] = l
+	}
+	return lines
+}
+
+// formatAmount форматирует сумму с разделением разрядов: 12 500
+func formatAmount(amount float64) string {
+	digits := strconv.FormatInt(int64(math.Round(amount)), 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"tmphackbackend/httpclient"
+)
+
+// SMSProvider отправляет SMS сообщения
+type SMSProvider interface {
+	Send(ctx context.Context, phone, message string) error
+}
+
+// NewSMSProvider создает провайдера SMS по конфигурации. client используется
+// провайдерами, которые отправляют сообщения по HTTP.
+func NewSMSProvider(cfg SMSConfig, client *httpclient.Client) (SMSProvider, error) {
+	switch cfg.Provider {
+	case "", "log":
+		return &LogSMSProvider{}, nil
+	case "smsru":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("SMS_API_KEY is required for smsru provider")
+		}
+		return &SMSRuProvider{
+			apiKey: cfg.APIKey,
+			sender: cfg.Sender,
+			client: client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider: %s", cfg.Provider)
+	}
+}
+
+// LogSMSProvider пишет сообщения в лог вместо отправки (для разработки)
+type LogSMSProvider struct{}
+
+func (p *LogSMSProvider) Send(ctx context.Context, phone, message string) error {
+	log.Printf("SMS to %s: %s", phone, message)
+	return nil
+}
+
+// SMSRuProvider отправляет SMS через API sms.ru
+type SMSRuProvider struct {
+	apiKey string
+	sender string
+	client *httpclient.Client
+}
+
+func (p *SMSRuProvider) Send(ctx context.Context, phone, message string) error {
+	params := url.Values{}
+	params.Set("api_id", p.apiKey)
+	params.Set("to", strings.TrimPrefix(phone, "+"))
+	params.Set("msg", message)
+	params.Set("json", "1")
+	if p.sender != "" {
+		params.Set("from", p.sender)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://sms.ru/sms/send", strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status     string `json:"status"`
+		StatusText string `json:"status_text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode SMS response: %w", err)
+	}
+	if result.Status != "OK" {
+		return fmt.Errorf("SMS provider error: %s", result.StatusText)
+	}
+	return nil
+}
+
+// SMSNotificationSender доставляет по SMS уведомления типов smsNotificationTypes
+// пользователям, включившим SMS для типа, на подтвержденный телефон
+type SMSNotificationSender struct {
+	db       *DB
+	provider SMSProvider
+}
+
+func NewSMSNotificationSender(db *DB, provider SMSProvider) *SMSNotificationSender {
+	return &SMSNotificationSender{db: db, provider: provider}
+}
+
+func (s *SMSNotificationSender) SendBatch(ctx context.Context, notifications []Notification) error {
+	var candidates []Notification
+	for _, n := range notifications {
+		if smsNotificationTypes[n.Type] {
+			candidates = append(candidates, n)
+		}
+	}
+	candidates, err := s.db.FilterNotificationChannel(NotificationChannelSMS, candidates)
+	if err != nil || len(candidates) == 0 {
+		return err
+	}
+
+	userIDs := make([]int64, 0, len(candidates))
+	for _, n := range candidates {
+		userIDs = append(userIDs, n.UserID)
+	}
+	phones, err := s.db.GetVerifiedPhones(userIDs)
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	for _, n := range candidates {
+		phone, ok := phones[n.UserID]
+		if !ok {
+			continue
+		}
+		message := n.Body
+		if url := n.Data["receipt_url"]; url != "" {
+			message += " Квитанция: " + url
+		}
+		result := "ok"
+		if err := s.provider.Send(ctx, phone, message); err != nil {
+			sendErr = err
+			result = "error"
+		}
+		metrics.Inc("sms_notifications_sent_total", "Notifications sent via SMS", map[string]string{"type": n.Type, "result": result})
+	}
+	return sendErr
+}
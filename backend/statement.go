@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// maxStatementSize ограничение на размер файла выписки
+	maxStatementSize = 5 << 20
+	// maxStatementRows сколько строк выписки разбирается за одну загрузку
+	maxStatementRows = 5000
+	// statementMaxDays перевод и пожертвование, разнесенные больше чем на столько дней, не сопоставляются
+	statementMaxDays = 7
+)
+
+// Названия столбцов выписки. Выгрузки банков различаются, поэтому столбец
+// находится по первому совпадению начала названия.
+var statementColumns = map[string][]string{
+	"date":        {"дата операции", "дата платежа", "дата", "date", "transaction date"},
+	"amount":      {"сумма операции", "сумма платежа", "сумма", "приход", "поступление", "кредит", "amount", "credit"},
+	"payer":       {"отправитель", "плательщик", "контрагент", "payer", "sender", "counterparty"},
+	"description": {"назначение платежа", "назначение", "описание", "комментарий", "description", "details", "purpose"},
+}
+
+var statementDateLayouts = []string{
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	time.RFC3339,
+}
+
+// StatementTransfer входящий перевод из выписки
+type StatementTransfer struct {
+	Row         int
+	Date        time.Time
+	Amount      float64
+	Payer       string
+	Description string
+}
+
+// StatementCandidate ожидающее подтверждения пожертвование, с которым сравниваются переводы
+type StatementCandidate struct {
+	DonationID int64
+	Amount     float64
+	CreatedAt  time.Time
+	DonorPhone string
+	FirstName  string
+	LastName   string
+}
+
+// ParseBankStatement разбирает CSV выписку (разделитель ; , или табуляция, UTF-8 или
+// Windows-1251). Возвращает входящие переводы и число пропущенных строк: исходящие
+// платежи и строки, в которых не удалось разобрать дату или сумму.
+func ParseBankStatement(data []byte, loc *time.Location) ([]StatementTransfer, int, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, 0, NewValidationError("Не удалось определить кодировку выписки", nil)
+		}
+		data = decoded
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = statementDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, 0, NewValidationError("Пустой файл выписки", nil)
+	}
+	columns := statementHeader(header)
+	if _, ok := columns["date"]; !ok {
+		return nil, 0, NewValidationError("В выписке не найден столбец с датой", map[string]interface{}{"field": "file"})
+	}
+	if _, ok := columns["amount"]; !ok {
+		return nil, 0, NewValidationError("В выписке не найден столбец с суммой", map[string]interface{}{"field": "file"})
+	}
+
+	var transfers []StatementTransfer
+	skipped := 0
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, NewValidationError(fmt.Sprintf("Ошибка в строке %d выписки", row), nil)
+		}
+		if row-1 > maxStatementRows {
+			return nil, 0, NewValidationError(fmt.Sprintf("В выписке больше %d строк", maxStatementRows), nil)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		date, dateErr := parseStatementDate(field("date"), loc)
+		amount, amountErr := parseStatementAmount(field("amount"))
+		if dateErr != nil || amountErr != nil || amount <= 0 {
+			skipped++
+			continue
+		}
+		transfers = append(transfers, StatementTransfer{
+			Row:         row,
+			Date:        date,
+			Amount:      amount,
+			Payer:       field("payer"),
+			Description: field("description"),
+		})
+	}
+	return transfers, skipped, nil
+}
+
+// statementDelimiter выбирает разделитель, которого больше всего в первой строке
+func statementDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	best, bestCount := ';', 0
+	for _, delimiter := range []rune{';', ',', '\t'} {
+		if count := bytes.Count(line, []byte(string(delimiter))); count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+// statementHeader сопоставляет столбцы выписки с полями перевода
+func statementHeader(header []string) map[string]int {
+	columns := make(map[string]int)
+	for field, aliases := range statementColumns {
+		for i, name := range header {
+			name = strings.ToLower(strings.TrimSpace(name))
+			matched := false
+			for _, alias := range aliases {
+				if strings.HasPrefix(name, alias) {
+					matched = true
+					break
+				}
+			}
+			if matched {
+				columns[field] = i
+				break
+			}
+		}
+	}
+	return columns
+}
+
+func parseStatementDate(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range statementDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseStatementAmount разбирает сумму вида "1 500,00", "1500.00 RUB" или "+1500"
+func parseStatementAmount(value string) (float64, error) {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9', r == '-', r == '.':
+			b.WriteRune(r)
+		case r == ',':
+			b.WriteRune('.')
+		}
+	}
+	return strconv.ParseFloat(b.String(), 64)
+}
+
+// MatchStatement сопоставляет переводы с ожидающими пожертвованиями. Сумма должна
+// совпадать до копейки, уверенность растет, если перевод пришел в день пожертвования
+// и если в отправителе или назначении есть телефон или имя жертвователя. Каждый перевод
+// и каждое пожертвование попадают не больше чем в одну пару, пары с большей
+// уверенностью выбираются первыми.
+func MatchStatement(transfers []StatementTransfer, candidates []StatementCandidate, loc *time.Location) []StatementRow {
+	type pair struct {
+		transfer, candidate int
+		confidence          float64
+		days                int
+		reasons             []string
+	}
+
+	var pairs []pair
+	for ti, t := range transfers {
+		text := strings.ToLower(t.Payer + " " + t.Description)
+		digits := onlyDigits(text)
+		for ci, c := range candidates {
+			if math.Abs(t.Amount-c.Amount) >= 0.005 {
+				continue
+			}
+			days := daysBetween(t.Date, c.CreatedAt, loc)
+			if days > statementMaxDays {
+				continue
+			}
+
+			p := pair{transfer: ti, candidate: ci, confidence: 0.5, days: days, reasons: []string{"amount"}}
+			switch {
+			case days == 0:
+				p.confidence += 0.2
+				p.reasons = append(p.reasons, "same_day")
+			case days <= 3:
+				p.confidence += 0.1
+				p.reasons = append(p.reasons, "date_close")
+			}
+			if phone := onlyDigits(c.DonorPhone); len(phone) >= 10 && strings.Contains(digits, phone[len(phone)-10:]) {
+				p.confidence += 0.3
+				p.reasons = append(p.reasons, "phone")
+			}
+			if donorNameMentioned(text, c.FirstName, c.LastName) {
+				p.confidence += 0.15
+				p.reasons = append(p.reasons, "name")
+			}
+			p.confidence = math.Min(p.confidence, 1)
+			pairs = append(pairs, p)
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].confidence != pairs[j].confidence {
+			return pairs[i].confidence > pairs[j].confidence
+		}
+		return pairs[i].days < pairs[j].days
+	})
+
+	rows := make([]StatementRow, len(transfers))
+	for i, t := range transfers {
+		rows[i] = StatementRow{
+			RowNumber:   t.Row,
+			Date:        t.Date,
+			Amount:      t.Amount,
+			Payer:       t.Payer,
+			Description: t.Description,
+			Reasons:     []string{},
+			Status:      StatementRowUnmatched,
+		}
+	}
+	usedCandidates := make(map[int]bool)
+	for _, p := range pairs {
+		row := &rows[p.transfer]
+		if row.DonationID != nil || usedCandidates[p.candidate] {
+			continue
+		}
+		usedCandidates[p.candidate] = true
+		donationID := candidates[p.candidate].DonationID
+		row.DonationID = &donationID
+		row.Confidence = math.Round(p.confidence*100) / 100
+		row.Reasons = p.reasons
+		row.Status = StatementRowProposed
+	}
+	return rows
+}
+
+// daysBetween число календарных дней между датами в часовом поясе loc
+func daysBetween(a, b time.Time, loc *time.Location) int {
+	ay, am, ad := a.In(loc).Date()
+	by, bm, bd := b.In(loc).Date()
+	days := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC).Sub(time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	return int(math.Abs(days))
+}
+
+// donorNameMentioned ищет фамилию жертвователя или имя с первой буквой фамилии ("Иван И."),
+// как банки обычно показывают отправителя
+func donorNameMentioned(text, firstName, lastName string) bool {
+	firstName = strings.ToLower(strings.TrimSpace(firstName))
+	lastName = strings.ToLower(strings.TrimSpace(lastName))
+	if utf8.RuneCountInString(lastName) >= 3 && strings.Contains(text, lastName) {
+		return true
+	}
+	if firstName == "" || lastName == "" {
+		return false
+	}
+	initial, _ := utf8.DecodeRuneInString(lastName)
+	return strings.Contains(text, firstName+" "+string(initial)+".")
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// SubscriptionRunner создает пожертвования по подпискам, срок которых наступил.
+// Запускается периодической задачей планировщика.
+type SubscriptionRunner struct {
+	db        *DB
+	notifier  *Notifier
+	batchSize int
+}
+
+func NewSubscriptionRunner(db *DB, notifier *Notifier, batchSize int) *SubscriptionRunner {
+	return &SubscriptionRunner{db: db, notifier: notifier, batchSize: batchSize}
+}
+
+// Run обрабатывает подписки пачками, пока не останется подписок с наступившим сроком
+func (r *SubscriptionRunner) Run(ctx context.Context) error {
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		runs, err := r.db.RunDueSubscriptions(r.batchSize, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, run := range runs {
+			r.notify(run)
+		}
+
+		if len(runs) < r.batchSize {
+			return nil
+		}
+	}
+}
+
+// notify сообщает донору о созданном пожертвовании или остановке подписки,
+// а автору поста - о новом пожертвовании через его интеграции
+func (r *SubscriptionRunner) notify(run SubscriptionRun) {
+	s := run.Subscription
+	if run.Donation == nil {
+		log.Printf("Subscription %d stopped: post %d is not active", s.ID, s.PostID)
+		r.notifier.Enqueue(FanoutJob{
+			Type:    NotificationSubscriptionStopped,
+			Title:   "Подписка остановлена",
+			Body:    fmt.Sprintf("Сбор «%s» больше не принимает пожертвования, регулярные пожертвования остановлены.", s.PostTitle),
+			PostID:  &s.PostID,
+			UserIDs: []int64{s.DonorID},
+		})
+		return
+	}
+
+	metrics.Inc("subscription_donations_total", "Donations created by recurring subscriptions", nil)
+	r.notifier.Enqueue(FanoutJob{
+		Type:    NotificationSubscriptionDue,
+		Title:   "Регулярное пожертвование",
+		Body:    fmt.Sprintf("Создано пожертвование %.2f ₽ на сбор «%s». Оплатите его или приложите чек.", s.Amount, s.PostTitle),
+		PostID:  &s.PostID,
+		UserIDs: []int64{s.DonorID},
+		Data: map[string]string{
+			"donation_id":     strconv.FormatInt(run.Donation.ID, 10),
+			"subscription_id": strconv.FormatInt(s.ID, 10),
+		},
+	})
+	notifyDonationIntegrations(r.db, &Post{ID: s.PostID, UserID: run.PostUserID, Title: s.PostTitle}, run.Donation)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Размеры уменьшенных копий изображений (по большей стороне), от большей к меньшей
+var thumbnailSizes = []struct {
+	name    string
+	maxSide int
+}{
+	{"large", 1280},
+	{"medium", 640},
+	{"small", 160},
+}
+
+// thumbnailQuality качество JPEG уменьшенных копий
+const thumbnailQuality = 80
+
+// ImageVariants ссылки на уменьшенные копии изображения.
+// Хранится в JSONB колонке.
+type ImageVariants struct {
+	Small  string `json:"small"`
+	Medium string `json:"medium"`
+	Large  string `json:"large"`
+}
+
+func (v *ImageVariants) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, v)
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	default:
+		return fmt.Errorf("unsupported image variants type %T", src)
+	}
+}
+
+func (v *ImageVariants) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// set сохраняет ссылку на копию по имени размера
+func (v *ImageVariants) set(name, url string) {
+	switch name {
+	case "small":
+		v.Small = url
+	case "medium":
+		v.Medium = url
+	case "large":
+		v.Large = url
+	}
+}
+
+// GenerateThumbnails создает JPEG копии изображения всех размеров из thumbnailSizes.
+// Изображения меньше размера копии не увеличиваются, а только пережимаются.
+func GenerateThumbnails(data []byte) (map[string][]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	thumbnails := make(map[string][]byte, len(thumbnailSizes))
+	// Каждая следующая копия уменьшается из предыдущей, чтобы не обходить оригинал несколько раз
+	for _, size := range thumbnailSizes {
+		img = resizeImage(img, size.maxSide)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s thumbnail: %w", size.name, err)
+		}
+		thumbnails[size.name] = buf.Bytes()
+	}
+	return thumbnails, nil
+}
+
+// resizeImage уменьшает изображение, чтобы большая сторона была не больше maxSide
+func resizeImage(src image.Image, maxSide int) image.Image {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	nw, nh := w, h
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			nw, nh = maxSide, max(1, h*maxSide/w)
+		} else {
+			nw, nh = max(1, w*maxSide/h), maxSide
+		}
+	}
+	return scaleImage(src, nw, nh)
+}
+
+// scaleImage масштабирует изображение до nw x nh: при уменьшении усредняет пиксели
+// по площади, при увеличении повторяет ближайший. Прозрачные области заливаются белым
+// (в JPEG нет альфа канала).
+func scaleImage(src image.Image, nw, nh int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		sy0 := bounds.Min.Y + y*h/nh
+		sy1 := max(sy0+1, bounds.Min.Y+(y+1)*h/nh)
+		for x := 0; x < nw; x++ {
+			sx0 := bounds.Min.X + x*w/nw
+			sx1 := max(sx0+1, bounds.Min.X+(x+1)*w/nw)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			// RGBA() возвращает значения, умноженные на альфу: добавляем белый фон
+			white := 0xffff - a/n
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r/n + white),
+				G: uint16(g/n + white),
+				B: uint16(b/n + white),
+				A: 0xffff,
+			})
+		}
+	}
+	return dst
+}
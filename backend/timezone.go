@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	_ "time/tzdata" // база часовых поясов встроена в бинарник: в образе alpine ее нет
+)
+
+// DefaultTimezone часовой пояс пользователя, пока он не выбрал свой
+const DefaultTimezone = "Europe/Moscow"
+
+// LoadUserLocation проверяет название часового пояса IANA (например, Europe/Moscow)
+// и возвращает его. Пустое название и Local не принимаются.
+func LoadUserLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// migrateTimestampColumns переводит столбцы TIMESTAMP в TIMESTAMPTZ. Старые значения
+// записаны в часовом поясе сервера PostgreSQL (DB_LEGACY_TIMEZONE) и пересчитываются
+// из него. Ключ секционирования изменить нельзя, поэтому секционированные таблицы
+// сначала собираются в обычные и затем секционируются заново (migratePartitionedTables).
+func (db *DB) migrateTimestampColumns() error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SET LOCAL statement_timeout = 0`); err != nil {
+		return err
+	}
+	// Приведение TIMESTAMP к TIMESTAMPTZ выполняется в часовом поясе сеанса
+	if _, err := tx.Exec(`SELECT set_config('TimeZone', $1, true)`, db.legacyTimezone); err != nil {
+		return fmt.Errorf("failed to set legacy time zone: %w", err)
+	}
+
+	for _, table := range partitionedTables {
+		var legacy bool
+		err := tx.QueryRow(`SELECT EXISTS (
+		                        SELECT 1 FROM pg_class t
+		                        JOIN pg_attribute a ON a.attrelid = t.oid
+		                        WHERE t.relname = $1 AND t.relnamespace = 'public'::regnamespace AND t.relkind = 'p'
+		                          AND a.attname = 'created_at' AND a.atttypid = 'timestamp'::regtype)`, table).Scan(&legacy)
+		if err != nil {
+			return fmt.Errorf("failed to check table %s: %w", table, err)
+		}
+		if !legacy {
+			continue
+		}
+
+		log.Printf("Merging partitions of %s to change created_at to TIMESTAMPTZ", table)
+		if err := unpartitionTable(tx, table); err != nil {
+			return fmt.Errorf("failed to merge partitions of %s: %w", table, err)
+		}
+	}
+
+	rows, err := tx.Query(`SELECT t.relname, a.attname FROM pg_class t
+	                       JOIN pg_attribute a ON a.attrelid = t.oid
+	                       WHERE t.relnamespace = 'public'::regnamespace AND t.relkind IN ('r', 'p') AND NOT t.relispartition
+	                         AND a.attnum > 0 AND NOT a.attisdropped AND a.atttypid = 'timestamp'::regtype
+	                       ORDER BY t.relname, a.attnum`)
+	if err != nil {
+		return err
+	}
+	var tables []string
+	columns := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			rows.Close()
+			return err
+		}
+		if columns[table] == nil {
+			tables = append(tables, table)
+		}
+		columns[table] = append(columns[table], fmt.Sprintf("ALTER COLUMN %s TYPE TIMESTAMPTZ", column))
+	}
+	rows.Close()
+
+	for _, table := range tables {
+		log.Printf("Converting timestamp columns of %s to TIMESTAMPTZ", table)
+		stmt := fmt.Sprintf(`ALTER TABLE %s %s`, table, strings.Join(columns[table], ", "))
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// unpartitionTable заменяет секционированную таблицу обычной с теми же данными,
+// столбцами и внешними ключами. Индексы создаются заново в InitSchema.
+func unpartitionTable(tx *sql.Tx, table string) error {
+	var sequence string
+	if err := tx.QueryRow(`SELECT pg_get_serial_sequence($1, 'id')`, table).Scan(&sequence); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT conname, pg_get_constraintdef(oid) FROM pg_constraint
+	                       WHERE conrelid = $1::regclass AND contype = 'f'`, table)
+	if err != nil {
+		return err
+	}
+	var foreignKeys []string
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			rows.Close()
+			return err
+		}
+		foreignKeys = append(foreignKeys, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", table, name, def))
+	}
+	rows.Close()
+
+	merged := table + "_merged"
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, merged, table),
+		fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, merged, table),
+		// Последовательность id удалилась бы вместе с таблицей
+		fmt.Sprintf(`ALTER SEQUENCE %s OWNED BY NONE`, sequence),
+		fmt.Sprintf(`DROP TABLE %s`, table),
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, merged, table),
+		fmt.Sprintf(`ALTER SEQUENCE %s OWNED BY %s.id`, sequence, table),
+	}
+	statements = append(statements, foreignKeys...)
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return nil
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"fmt"
+	"image"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -66,58 +69,101 @@ func ValidateFileSize(fileHeader *multipart.FileHeader, maxSize int64) error {
 
 // ValidateImageFile проверяет, что файл является изображением
 func ValidateImageFile(fileHeader *multipart.FileHeader) error {
+	message := "Разрешенные форматы изображений: JPEG, PNG, WebP"
 	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
 	allowedExts := []string{".jpg", ".jpeg", ".png", ".webp"}
 
 	for _, allowedExt := range allowedExts {
 		if ext == allowedExt {
-			return nil
+			return validateFileContent(fileHeader, []string{"image/jpeg", "image/png", "image/webp"}, message)
 		}
 	}
 
-	return NewUnsupportedMediaError("Разрешенные форматы изображений: JPEG, PNG, WebP")
+	return NewUnsupportedMediaError(message)
 }
 
 // ValidateVideoFile проверяет, что файл является видео
 func ValidateVideoFile(fileHeader *multipart.FileHeader) error {
+	message := "Разрешенные форматы видео: MP4, WebM"
 	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
 	allowedExts := []string{".mp4", ".webm"}
 
 	for _, allowedExt := range allowedExts {
 		if ext == allowedExt {
-			return nil
+			return validateFileContent(fileHeader, []string{"video/mp4", "video/webm"}, message)
 		}
 	}
 
-	return NewUnsupportedMediaError("Разрешенные форматы видео: MP4, WebM")
+	return NewUnsupportedMediaError(message)
 }
 
 // ValidateMediaFile проверяет, что файл является медиа (изображение или видео)
 func ValidateMediaFile(fileHeader *multipart.FileHeader) error {
+	message := "Разрешенные форматы медиа: JPEG, PNG, WebP, MP4, WebM"
 	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
 	allowedExts := []string{".jpg", ".jpeg", ".png", ".webp", ".mp4", ".webm"}
 
 	for _, allowedExt := range allowedExts {
 		if ext == allowedExt {
-			return nil
+			return validateFileContent(fileHeader, []string{"image/jpeg", "image/png", "image/webp", "video/mp4", "video/webm"}, message)
 		}
 	}
 
-	return NewUnsupportedMediaError("Разрешенные форматы медиа: JPEG, PNG, WebP, MP4, WebM")
+	return NewUnsupportedMediaError(message)
 }
 
 // ValidateDocumentFile проверяет, что файл является документом
 func ValidateDocumentFile(fileHeader *multipart.FileHeader) error {
+	message := "Разрешенные форматы документов: PDF, JPEG, PNG"
 	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
 	allowedExts := []string{".pdf", ".jpg", ".jpeg", ".png"}
 
 	for _, allowedExt := range allowedExts {
 		if ext == allowedExt {
-			return nil
+			return validateFileContent(fileHeader, []string{"application/pdf", "image/jpeg", "image/png"}, message)
 		}
 	}
 
-	return NewUnsupportedMediaError("Разрешенные форматы документов: PDF, JPEG, PNG")
+	return NewUnsupportedMediaError(message)
+}
+
+// validateFileContent проверяет настоящий тип файла по первым 512 байтам,
+// так как расширение можно подделать. Изображения дополнительно должны
+// декодироваться (проверяется заголовок с размерами).
+func validateFileContent(fileHeader *multipart.FileHeader, allowedTypes []string, message string) error {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return NewInternalError("Ошибка чтения файла")
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return NewUnsupportedMediaError(message)
+	}
+	contentType := http.DetectContentType(head[:n])
+
+	allowed := false
+	for _, allowedType := range allowedTypes {
+		if contentType == allowedType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return NewUnsupportedMediaError(message)
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return NewInternalError("Ошибка чтения файла")
+		}
+		if _, _, err := image.DecodeConfig(file); err != nil {
+			return NewUnsupportedMediaError(message)
+		}
+	}
+	return nil
 }
 
 // ValidateContentType проверяет Content-Type файла
@@ -162,3 +208,12 @@ func ValidatePhoneNumber(phone string) error {
 	}
 	return nil
 }
+
+// AgeAt возвращает полное число лет на дату now
+func AgeAt(birthDate, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
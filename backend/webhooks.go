@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"tmphackbackend/httpclient"
+)
+
+// webhookMaxBackoff верхняя граница паузы между повторами доставки
+const webhookMaxBackoff = 6 * time.Hour
+
+// WebhookDispatcher доставляет события из очереди webhook_deliveries в личные
+// интеграции пользователей и на вебхуки партнеров. Запускается периодической
+// задачей планировщика.
+// Повторы доставки выполняет сама очередь, поэтому клиенты создаются без повторов.
+type WebhookDispatcher struct {
+	db       *DB
+	cfg      WebhookConfig
+	client   *httpclient.Client
+	telegram *httpclient.Client
+}
+
+// NewWebhookDispatcher создает диспетчер. client отправляет вебхуки на адреса
+// пользователей, telegram - запросы к Bot API.
+func NewWebhookDispatcher(db *DB, cfg WebhookConfig, client, telegram *httpclient.Client) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		db:       db,
+		cfg:      cfg,
+		client:   client,
+		telegram: telegram,
+	}
+}
+
+// Run отправляет все готовые к доставке события
+func (d *WebhookDispatcher) Run(ctx context.Context) error {
+	// Пока пачка отправляется, ее события не выбираются повторно
+	lease := time.Duration(d.cfg.BatchSize+1) * d.cfg.Timeout
+
+	for {
+		deliveries, err := d.db.ClaimWebhookDeliveries(d.cfg.BatchSize, lease)
+		if err != nil {
+			return err
+		}
+
+		for _, delivery := range deliveries {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			d.deliver(ctx, delivery)
+		}
+
+		if len(deliveries) < d.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery WebhookDelivery) {
+	var err error
+	switch delivery.Integration.Type {
+	case IntegrationWebhook:
+		err = d.sendWebhook(ctx, delivery)
+	case IntegrationTelegram:
+		err = d.sendTelegram(ctx, delivery)
+	default:
+		err = fmt.Errorf("unknown integration type: %s", delivery.Integration.Type)
+	}
+
+	labels := map[string]string{"type": delivery.Integration.Type, "result": "delivered"}
+	if delivery.WebhookID != nil {
+		labels["type"] = "partner"
+	}
+	if err == nil {
+		if err := d.db.MarkWebhookDelivered(delivery.ID); err != nil {
+			log.Printf("Failed to mark webhook delivery %d: %v", delivery.ID, err)
+		}
+		// Доставлено событие, повторенное из очереди недоставленных
+		if delivery.Attempts >= d.cfg.MaxAttempts {
+			if err := d.db.ResolveWebhookDeadLetter(delivery.ID); err != nil {
+				log.Printf("Failed to resolve dead letter for webhook delivery %d: %v", delivery.ID, err)
+			}
+		}
+		metrics.Inc("webhook_deliveries_total", "Integration delivery attempts by result", labels)
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	var nextAttempt *time.Time
+	if attempts < d.cfg.MaxAttempts {
+		next := time.Now().Add(webhookBackoff(attempts))
+		nextAttempt = &next
+		labels["result"] = "retry"
+	} else {
+		labels["result"] = "failed"
+		log.Printf("Webhook delivery %d dropped after %d attempts: %v", delivery.ID, attempts, err)
+	}
+	metrics.Inc("webhook_deliveries_total", "Integration delivery attempts by result", labels)
+
+	if err := d.db.MarkWebhookFailed(delivery.ID, err.Error(), nextAttempt); err != nil {
+		log.Printf("Failed to mark webhook delivery %d: %v", delivery.ID, err)
+	}
+	if nextAttempt == nil {
+		d.deadLetter(delivery, attempts, err)
+	}
+}
+
+// deadLetter сохраняет событие с прекращенной доставкой в очередь недоставленных.
+// Тело события и секрет остаются в webhook_deliveries.
+func (d *WebhookDispatcher) deadLetter(delivery WebhookDelivery, attempts int, cause error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"event":          delivery.Event,
+		"type":           delivery.Integration.Type,
+		"integration_id": delivery.IntegrationID,
+		"webhook_id":     delivery.WebhookID,
+	})
+	if err != nil {
+		log.Printf("Failed to encode dead letter for webhook delivery %d: %v", delivery.ID, err)
+		return
+	}
+	letter := &DeadLetter{
+		Kind:        DeadLetterWebhook,
+		ReferenceID: &delivery.ID,
+		Payload:     payload,
+		Error:       cause.Error(),
+		Attempts:    attempts,
+	}
+	if err := d.db.RecordDeadLetter(letter); err != nil {
+		log.Printf("Failed to record dead letter for webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// sendWebhook отправляет событие POST запросом. Тело подписывается HMAC-SHA256
+// секретом интеграции, подпись передается в заголовке X-Webhook-Signature.
+func (d *WebhookDispatcher) sendWebhook(ctx context.Context, delivery WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Integration.Target, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(delivery.Integration.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendTelegram отправляет краткое описание события в чат через Bot API
+func (d *WebhookDispatcher) sendTelegram(ctx context.Context, delivery WebhookDelivery) error {
+	return sendTelegramMessage(ctx, d.telegram, d.cfg.TelegramBotToken, delivery.Integration.Target, delivery.Summary)
+}
+
+// sendTelegramMessage отправляет текст в чат через Bot API
+func sendTelegramMessage(ctx context.Context, client *httpclient.Client, botToken, chatID, text string) error {
+	if botToken == "" {
+		return fmt.Errorf("TELEGRAM_BOT_TOKEN is not configured")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"chat_id": chatID,
+		"text":    text,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Ошибка содержит URL с токеном бота, не сохраняем ее целиком
+		return fmt.Errorf("failed to send telegram message")
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode telegram response: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram error: %s", result.Description)
+	}
+	return nil
+}
+
+// notifyDonationIntegrations ставит событие о новом пожертвовании в очередь
+// доставки по интеграциям автора поста
+func notifyDonationIntegrations(db *DB, post *Post, donation *Donation) {
+	payload, err := json.Marshal(DonationWebhookPayload{
+		Event:      WebhookEventDonationCreated,
+		DonationID: donation.ID,
+		PostID:     post.ID,
+		PostTitle:  post.Title,
+		Amount:     donation.Amount,
+		CreatedAt:  donation.CreatedAt,
+	})
+	if err != nil {
+		log.Printf("Failed to encode donation webhook: %v", err)
+		return
+	}
+
+	summary := fmt.Sprintf("Новое пожертвование %.2f ₽ на сбор «%s»", donation.Amount, post.Title)
+	if err := db.EnqueueWebhookEvent(post.UserID, WebhookEventDonationCreated, payload, summary); err != nil {
+		log.Printf("Failed to enqueue donation webhook: %v", err)
+	}
+
+	emitPartnerWebhook(db, WebhookEventDonationCreated, map[string]interface{}{
+		"donation_id": donation.ID,
+		"post_id":     post.ID,
+		"amount":      donation.Amount,
+		"created_at":  donation.CreatedAt,
+	})
+}
+
+// emitPartnerWebhook ставит событие платформы в очередь доставки по вебхукам
+// партнеров. Персональные данные (паспорт, контакты, жертвователь) в события не попадают.
+func emitPartnerWebhook(db *DB, event string, data interface{}) {
+	payload, err := json.Marshal(PartnerWebhookPayload{
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("Failed to encode %s webhook: %v", event, err)
+		return
+	}
+	if err := db.EnqueuePartnerWebhookEvent(event, payload); err != nil {
+		log.Printf("Failed to enqueue %s webhook: %v", event, err)
+	}
+}
+
+// webhookBackoff пауза перед повтором: 30с, 1м, 2м, ... но не больше webhookMaxBackoff
+func webhookBackoff(attempts int) time.Duration {
+	backoff := 30 * time.Second
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+// SignWebhookPayload возвращает HMAC-SHA256 подпись тела события в hex
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateWebhookSecret генерирует секрет для подписи событий интеграции
+func GenerateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ========== Webhook targets ==========
+
+// errNonPublicAddress соединение с внутренним адресом отклонено
+var errNonPublicAddress = errors.New("non-public address")
+
+// nonPublicPrefixes диапазоны вне публичного интернета, которые не покрывают методы netip.Addr:
+// "этот" сеть, CGNAT, служебные IETF, тестовые и зарезервированные
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// internalHostSuffixes зоны локальных и внутренних сетей
+var internalHostSuffixes = []string{".localhost", ".local", ".localdomain", ".internal", ".lan", ".home.arpa"}
+
+// isPublicAddr сообщает, относится ли адрес к публичному интернету: loopback, частные,
+// link-local, multicast и служебные адреса вебхукам недоступны
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// isInternalHostname сообщает, что имя указывает на локальную или внутреннюю сеть:
+// localhost, имена без точки (сервисы docker compose) и внутренние зоны
+func isInternalHostname(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range internalHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWebhookHost проверяет хост вебхука при сохранении: IP адрес или имя должны быть
+// публичными, как и все адреса, в которые имя разрешается сейчас. Запись DNS может
+// измениться позже, поэтому адрес еще раз проверяет publicOnlyTransport при соединении.
+func checkWebhookHost(ctx context.Context, host string) error {
+	internal := NewValidationError("Webhook не может указывать на внутренний адрес", map[string]interface{}{"field": "target"})
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublicAddr(addr) {
+			return internal
+		}
+		return nil
+	}
+	if isInternalHostname(host) {
+		return internal
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return NewValidationError("Хост webhook не найден", map[string]interface{}{"field": "target"})
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return internal
+		}
+	}
+	return nil
+}
+
+// publicOnlyTransport HTTP транспорт вебхуков, который соединяется только с публичными
+// адресами. Адрес проверяется после разрешения имени, поэтому ни смена записи DNS после
+// сохранения вебхука, ни перенаправление на внутренний адрес не открывают доступ к
+// внутренней сети. Прокси из окружения не используется: через него проверка не работает.
+func publicOnlyTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errNonPublicAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}